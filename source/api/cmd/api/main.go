@@ -11,10 +11,13 @@ import (
 
 	"github.com/condominio/backend/internal/config"
 	"github.com/condominio/backend/internal/database"
+	"github.com/condominio/backend/internal/models"
 	"github.com/condominio/backend/internal/router"
+	"github.com/condominio/backend/internal/scheduler"
 	"github.com/condominio/backend/internal/services"
 	"github.com/condominio/backend/pkg/email"
 	"github.com/condominio/backend/pkg/jwt"
+	"github.com/condominio/backend/pkg/money"
 	"github.com/condominio/backend/pkg/oauth"
 )
 
@@ -29,6 +32,12 @@ func main() {
 		)
 	}
 
+	moneyFormat, err := money.ParseJSONFormat(cfg.MoneyJSONFormat)
+	if err != nil {
+		log.Fatalf("Invalid MONEY_JSON_FORMAT: %v", err)
+	}
+	money.SetJSONFormat(moneyFormat)
+
 	// Connect to database
 	db, err := database.New(cfg.DatabaseURL())
 	if err != nil {
@@ -73,23 +82,97 @@ func main() {
 		log.Println("Email service disabled (SMTP not configured)")
 	}
 
+	// Bank transfer details printed on avisos de cobro
+	datosBancarios := models.DatosBancarios{
+		Banco:        cfg.BancoNombre,
+		TipoCuenta:   cfg.BancoTipoCuenta,
+		NumeroCuenta: cfg.BancoNumeroCuenta,
+		Titular:      cfg.BancoTitular,
+		RUT:          cfg.BancoRUT,
+		Email:        cfg.BancoEmail,
+	}
+
+	recordatorios := models.RecordatorioConfig{
+		Emision:     cfg.RecordatoriosEmision,
+		DiasAntes:   cfg.RecordatoriosDiasAntes,
+		Vencido:     cfg.RecordatoriosVencido,
+		FrontendURL: cfg.FrontendURL,
+	}
+
+	votaciones := models.VotacionConfig{
+		MaxPoderes:        cfg.PoderesMaximoPorPersona,
+		RecordatorioHoras: cfg.VotacionesRecordatorioHoras,
+		FrontendURL:       cfg.FrontendURL,
+	}
+
 	// Initialize services
 	svc := &router.Services{
 		Auth:         services.NewAuthService(db, jwtManager),
 		Comunicado:   services.NewComunicadoService(db),
 		Evento:       services.NewEventoService(db),
 		Tesoreria:    services.NewTesoreriaService(db),
+		Proveedor:    services.NewProveedorService(db),
 		Acta:         services.NewActaService(db),
 		Documento:    services.NewDocumentoService(db),
 		Emergencia:   services.NewEmergenciaService(db),
-		Votacion:     services.NewVotacionService(db),
+		Votacion:     services.NewVotacionService(db, emailSvc, votaciones),
 		GastoComun:   services.NewGastoComunService(db),
+		Convenio:     services.NewConvenioService(db),
+		Cobranza:     services.NewCobranzaService(db, emailSvc, datosBancarios),
+		Recordatorio: services.NewRecordatorioService(db, emailSvc, recordatorios),
+		Importacion:  services.NewImportacionBancoService(db),
+		UF:           services.NewUFService(db),
 		Contacto:     services.NewContactoService(db, emailSvc),
 		Galeria:      services.NewGaleriaService(db),
 		Mapa:         services.NewMapaService(db),
 		Notificacion: services.NewNotificacionService(db, emailSvc),
 	}
 
+	// Background jobs
+	sched := scheduler.New()
+	if cfg.RecordatoriosEnabled {
+		intervalo := time.Duration(cfg.RecordatoriosIntervaloMinutos) * time.Minute
+		if intervalo <= 0 {
+			intervalo = time.Hour
+		}
+		sched.Every("recordatorios-gastos", intervalo, func(ctx context.Context) error {
+			// Overdue notices go out right after gastos are marked as vencidos
+			if _, err := svc.GastoComun.MarcarVencidos(ctx); err != nil {
+				return err
+			}
+			_, err := svc.Recordatorio.ProcesarRecordatorios(ctx)
+			return err
+		})
+	}
+	// Next month's periodo is generated once the configured lead time is reached
+	sched.Every("periodos-automaticos", time.Hour, func(ctx context.Context) error {
+		_, err := svc.GastoComun.GenerarPeriodoSiguiente(ctx)
+		return err
+	})
+	// Cuotas of convenios de pago fall due by day: fulfilled convenios are
+	// closed and those with too many missed cuotas broken
+	sched.Every("convenios-cumplimiento", 24*time.Hour, func(ctx context.Context) error {
+		_, err := svc.Convenio.ProcesarConvenios(ctx)
+		return err
+	})
+	// Daily posting of pagos: each day is posted once it is over. Does nothing
+	// unless tesorería is configured with contabilizacion_pagos = diaria.
+	sched.Every("contabilizar-pagos", time.Hour, func(ctx context.Context) error {
+		y, m, d := time.Now().Date()
+		_, err := svc.Tesoreria.ContabilizarPagosPendientes(ctx, time.Date(y, m, d-1, 0, 0, 0, 0, time.UTC), "")
+		return err
+	})
+	// Scheduled votaciones open and close on their dates, with their notices
+	intervaloVotaciones := time.Duration(cfg.VotacionesIntervaloMinutos) * time.Minute
+	if intervaloVotaciones <= 0 {
+		intervaloVotaciones = 5 * time.Minute
+	}
+	sched.Every("votaciones-calendario", intervaloVotaciones, func(ctx context.Context) error {
+		_, err := svc.Votacion.ProcesarVotaciones(ctx)
+		return err
+	})
+	sched.Start()
+
 	// Initialize Google OAuth service
 	googleSvc := oauth.NewGoogleService(oauth.GoogleConfig{
 		ClientID:     cfg.GoogleClientID,
@@ -137,6 +220,8 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	sched.Stop()
+
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/condominio/backend/internal/config"
+	"github.com/condominio/backend/internal/database"
+	"github.com/condominio/backend/internal/services"
+)
+
+// check-pagos reports gastos comunes whose monto_pagado disagrees with the sum
+// of their approved pagos. It exits with status 1 when inconsistencies exist so
+// it can be used from cron or CI.
+func main() {
+	cfg := config.Load()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	db, err := database.New(cfg.DatabaseURL())
+	if err != nil {
+		log.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	svc := services.NewGastoComunService(db)
+
+	inconsistencias, err := svc.VerificarConsistencia(ctx)
+	if err != nil {
+		log.Fatalf("Failed to check pagos: %v", err)
+	}
+
+	if len(inconsistencias) == 0 {
+		log.Println("OK: monto_pagado matches approved pagos for every gasto")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PERIODO\tPARCELA\tSTATUS\tMONTO\tMONTO_PAGADO\tPAGOS_APROBADOS\tDIFERENCIA\tGASTO_ID")
+	for _, i := range inconsistencias {
+		fmt.Fprintf(w, "%04d-%02d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			i.Year, i.Month, i.ParcelaNumero, i.Status,
+			i.Monto, i.MontoPagado, i.MontoLedger, i.Diferencia, i.GastoComunID)
+	}
+	w.Flush()
+
+	log.Printf("Found %d gastos with inconsistent monto_pagado", len(inconsistencias))
+	os.Exit(1)
+}
@@ -43,14 +43,44 @@ func main() {
 	// Clear existing data
 	log.Println("Clearing existing data...")
 	clearTables := []string{
+		"config_tesoreria",
+		"conciliaciones_bancarias",
+		"cartola_lineas",
+		"cartolas_bancarias",
+		"pagos_tesoreria",
+		"reglas_aprobacion_facturas",
+		"facturas_adjuntos",
+		"factura_aprobaciones",
+		"facturas",
+		"proveedores",
+		"presupuesto_items",
+		"presupuestos",
+		"movimientos_adjuntos",
+		"valores_uf",
+		"importaciones_banco_filas",
+		"importaciones_banco",
+		"formatos_importacion",
+		"convenio_cuotas",
+		"convenio_gastos",
+		"convenios_pago",
+		"auditoria_financiera",
+		"creditos_parcela",
+		"cargos_parcela",
+		"saldos_iniciales",
+		"recordatorios_gasto",
+		"recibos",
+		"correlativos",
 		"pagos",
 		"gastos_comunes",
 		"periodos_gasto",
+		"config_periodos",
+		"preferencias_notificacion",
 		"notificaciones",
 		"mensajes_contacto",
 		"galeria_items",
 		"galerias",
 		"votos",
+		"poderes",
 		"votacion_opciones",
 		"votaciones",
 		"emergencias",
@@ -59,6 +89,8 @@ func main() {
 		"documentos",
 		"actas",
 		"movimientos_tesoreria",
+		"categorias_tesoreria",
+		"cuentas_tesoreria",
 		"eventos",
 		"comunicados",
 		"users",
@@ -186,6 +218,43 @@ func main() {
 	// ============================================
 	// MOVIMIENTOS TESORERIA
 	// ============================================
+	log.Println("Inserting cuentas tesoreria...")
+	_, err = pool.Exec(ctx, `
+		INSERT INTO cuentas_tesoreria (id, codigo, nombre, tipo, principal) VALUES
+		('c1000000-0000-0000-0000-000000000001', 'cuenta_corriente', 'Cuenta corriente', 'corriente', TRUE),
+		('c1000000-0000-0000-0000-000000000002', 'caja', 'Caja chica', 'caja', FALSE),
+		('c1000000-0000-0000-0000-000000000003', 'fondo_reserva', 'Fondo de reserva', 'fondo_reserva', FALSE)
+	`)
+	if err != nil {
+		log.Printf("Warning inserting cuentas: %v", err)
+	}
+	_, err = pool.Exec(ctx, `
+		INSERT INTO config_tesoreria (porcentaje_fondo_reserva, cuenta_reserva_id)
+		VALUES (5, 'c1000000-0000-0000-0000-000000000003')
+	`)
+	if err != nil {
+		log.Printf("Warning inserting config tesoreria: %v", err)
+	}
+
+	log.Println("Inserting categorias tesoreria...")
+	_, err = pool.Exec(ctx, `
+		INSERT INTO categorias_tesoreria (codigo, nombre, tipo) VALUES
+		('gastos_comunes', 'Gastos comunes', 'ingreso'),
+		('arriendos', 'Arriendos', 'ingreso'),
+		('multas', 'Multas', 'ingreso'),
+		('servicios', 'Servicios', 'egreso'),
+		('servicios_basicos', 'Servicios básicos', 'egreso'),
+		('mantencion', 'Mantención', 'egreso'),
+		('reparaciones', 'Reparaciones', 'egreso'),
+		('eventos', 'Eventos', 'egreso'),
+		('administracion', 'Administración', 'egreso'),
+		('seguros', 'Seguros', 'egreso'),
+		('reverso_pagos', 'Reverso de pagos', 'egreso')
+	`)
+	if err != nil {
+		log.Printf("Warning inserting categorias: %v", err)
+	}
+
 	log.Println("Inserting movimientos tesoreria...")
 	movimientos := []struct {
 		desc     string
@@ -214,15 +283,71 @@ func main() {
 		{"Servicio de aseo áreas comunes - Enero", 450000, "egreso", "servicios", "2026-01-05"},
 	}
 
-	for _, m := range movimientos {
+	for i, m := range movimientos {
 		_, err = pool.Exec(ctx, `
-			INSERT INTO movimientos_tesoreria (description, amount, type, category, date, created_by)
-			VALUES ($1, $2, $3, $4, $5, 'a0000000-0000-0000-0000-000000000003')
-		`, m.desc, m.amount, m.tipo, m.category, m.date)
+			INSERT INTO movimientos_tesoreria (comprobante, cuenta_id, description, amount, type, category, date, created_by)
+			VALUES ($1, 'c1000000-0000-0000-0000-000000000001', $2, $3, $4, $5, $6, 'a0000000-0000-0000-0000-000000000003')
+		`, i+1, m.desc, m.amount, m.tipo, m.category, m.date)
 		if err != nil {
 			log.Printf("Warning inserting movimiento: %v", err)
 		}
 	}
+	_, err = pool.Exec(ctx, `INSERT INTO correlativos (tipo, ultimo) VALUES ('comprobante_tesoreria', $1)`, len(movimientos))
+	if err != nil {
+		log.Printf("Warning inserting correlativo: %v", err)
+	}
+
+	log.Println("Inserting presupuesto 2026...")
+	_, err = pool.Exec(ctx, `
+		WITH p AS (
+			INSERT INTO presupuestos (year, descripcion, umbral_alerta, created_by)
+			VALUES (2026, 'Presupuesto aprobado en asamblea ordinaria', 90, 'a0000000-0000-0000-0000-000000000003')
+			RETURNING id
+		)
+		INSERT INTO presupuesto_items (presupuesto_id, categoria, monto_anual)
+		SELECT p.id, i.categoria, i.monto FROM p, (VALUES
+			('gastos_comunes', 55200000), ('arriendos', 1200000), ('multas', 600000),
+			('servicios', 5400000), ('servicios_basicos', 5600000), ('mantencion', 3600000),
+			('reparaciones', 4000000), ('eventos', 1500000), ('administracion', 3600000),
+			('seguros', 1200000)
+		) AS i(categoria, monto)
+	`)
+	if err != nil {
+		log.Printf("Warning inserting presupuesto: %v", err)
+	}
+
+	log.Println("Inserting proveedores y facturas...")
+	_, err = pool.Exec(ctx, `
+		INSERT INTO proveedores (id, nombre, rut, email, telefono, banco, tipo_cuenta, numero_cuenta, categoria, created_by) VALUES
+		('f1000000-0000-0000-0000-000000000001', 'Jardines del Valle SpA', '761234560', 'contacto@jardinesdelvalle.cl', '+56 9 8765 4321', 'Banco de Chile', 'corriente', '00-123-45678-09', 'mantencion', 'a0000000-0000-0000-0000-000000000003'),
+		('f1000000-0000-0000-0000-000000000002', 'Seguridad Integral Ltda.', '779876543', 'facturacion@seguridadintegral.cl', '+56 2 2345 6789', 'Banco Santander', 'corriente', '0-000-7654321-0', 'servicios', 'a0000000-0000-0000-0000-000000000003'),
+		('f1000000-0000-0000-0000-000000000003', 'Electricidad Rural EIRL', '965432108', NULL, '+56 9 1234 5678', 'BancoEstado', 'vista', '12345678', 'reparaciones', 'a0000000-0000-0000-0000-000000000003')
+	`)
+	if err != nil {
+		log.Printf("Warning inserting proveedores: %v", err)
+	}
+	_, err = pool.Exec(ctx, `
+		INSERT INTO reglas_aprobacion_facturas (monto_desde, aprobaciones) VALUES (0, 1), (500000, 2)
+	`)
+	if err != nil {
+		log.Printf("Warning inserting reglas aprobacion: %v", err)
+	}
+	_, err = pool.Exec(ctx, `
+		INSERT INTO facturas (id, proveedor_id, numero, descripcion, categoria, monto, fecha_emision, fecha_vencimiento, estado, aprobaciones_requeridas, created_by) VALUES
+		('f2000000-0000-0000-0000-000000000001', 'f1000000-0000-0000-0000-000000000001', '4521', 'Mantención áreas verdes febrero', 'mantencion', 350000, '2026-02-01', '2026-03-02', 'aprobada', 1, 'a0000000-0000-0000-0000-000000000003'),
+		('f2000000-0000-0000-0000-000000000002', 'f1000000-0000-0000-0000-000000000003', '118', 'Cambio de luminarias camino principal', 'reparaciones', 1250000, '2026-02-05', '2026-03-07', 'pendiente', 2, 'a0000000-0000-0000-0000-000000000003')
+	`)
+	if err != nil {
+		log.Printf("Warning inserting facturas: %v", err)
+	}
+	_, err = pool.Exec(ctx, `
+		INSERT INTO factura_aprobaciones (factura_id, user_id, aprobada) VALUES
+		('f2000000-0000-0000-0000-000000000001', 'a0000000-0000-0000-0000-000000000002', true),
+		('f2000000-0000-0000-0000-000000000002', 'a0000000-0000-0000-0000-000000000002', true)
+	`)
+	if err != nil {
+		log.Printf("Warning inserting aprobaciones: %v", err)
+	}
 
 	// ============================================
 	// GASTOS COMUNES - PERIODOS + GASTOS + PAGOS
@@ -306,12 +431,14 @@ func main() {
 
 		('91000000-0000-0000-0000-000000000201', 68000, 'transferencia', 'TRX-2025-12-0001', 'approved', '{"banco":"Banco Estado"}'::jsonb),
 		('91000000-0000-0000-0000-000000000202', 68000, 'transferencia', 'TRX-2025-12-0002', 'approved', '{"banco":"Banco Estado"}'::jsonb),
+		('91000000-0000-0000-0000-000000000203', 34000, 'transferencia', 'TRX-2025-12-0003', 'approved', '{"banco":"Banco Estado"}'::jsonb),
 		('91000000-0000-0000-0000-000000000205', 68000, 'transferencia', 'TRX-2025-12-0005', 'approved', '{"banco":"Banco Estado"}'::jsonb),
 		('91000000-0000-0000-0000-000000000207', 68000, 'transferencia', 'TRX-2025-12-0007', 'approved', '{"banco":"Banco Estado"}'::jsonb),
 		('91000000-0000-0000-0000-000000000209', 68000, 'transferencia', 'TRX-2025-12-0009', 'approved', '{"banco":"Banco Estado"}'::jsonb),
 
 		('91000000-0000-0000-0000-000000000301', 70000, 'transferencia', 'TRX-2026-01-0001', 'approved', '{"banco":"Banco Estado"}'::jsonb),
 		('91000000-0000-0000-0000-000000000304', 70000, 'transferencia', 'TRX-2026-01-0004', 'approved', '{"banco":"Banco Estado"}'::jsonb),
+		('91000000-0000-0000-0000-000000000307', 35000, 'transferencia', 'TRX-2026-01-0007', 'approved', '{"banco":"Banco Estado"}'::jsonb),
 		('91000000-0000-0000-0000-000000000309', 70000, 'transferencia', 'TRX-2026-01-0009', 'approved', '{"banco":"Banco Estado"}'::jsonb)
 	`)
 	if err != nil {
@@ -451,12 +578,12 @@ func main() {
 
 	// Votos (para la votación cerrada)
 	_, err = pool.Exec(ctx, `
-		INSERT INTO votos (votacion_id, user_id, opcion_id, is_abstention, voted_at) VALUES
-		('b0000000-0000-0000-0000-000000000003', 'a0000000-0000-0000-0000-000000000005', 'c0000000-0000-0000-0000-000000000007', false, NOW() - INTERVAL '25 days'),
-		('b0000000-0000-0000-0000-000000000003', 'a0000000-0000-0000-0000-000000000006', 'c0000000-0000-0000-0000-000000000007', false, NOW() - INTERVAL '24 days'),
-		('b0000000-0000-0000-0000-000000000003', 'a0000000-0000-0000-0000-000000000007', 'c0000000-0000-0000-0000-000000000008', false, NOW() - INTERVAL '23 days'),
-		('b0000000-0000-0000-0000-000000000003', 'a0000000-0000-0000-0000-000000000008', 'c0000000-0000-0000-0000-000000000007', false, NOW() - INTERVAL '22 days'),
-		('b0000000-0000-0000-0000-000000000003', 'a0000000-0000-0000-0000-000000000009', NULL, true, NOW() - INTERVAL '21 days')
+		INSERT INTO votos (votacion_id, user_id, parcela_id, opcion_id, is_abstention, voted_at) VALUES
+		('b0000000-0000-0000-0000-000000000003', 'a0000000-0000-0000-0000-000000000005', 4, 'c0000000-0000-0000-0000-000000000007', false, NOW() - INTERVAL '25 days'),
+		('b0000000-0000-0000-0000-000000000003', 'a0000000-0000-0000-0000-000000000006', 5, 'c0000000-0000-0000-0000-000000000007', false, NOW() - INTERVAL '24 days'),
+		('b0000000-0000-0000-0000-000000000003', 'a0000000-0000-0000-0000-000000000007', 6, 'c0000000-0000-0000-0000-000000000008', false, NOW() - INTERVAL '23 days'),
+		('b0000000-0000-0000-0000-000000000003', 'a0000000-0000-0000-0000-000000000008', 7, 'c0000000-0000-0000-0000-000000000007', false, NOW() - INTERVAL '22 days'),
+		('b0000000-0000-0000-0000-000000000003', 'a0000000-0000-0000-0000-000000000009', 8, NULL, true, NOW() - INTERVAL '21 days')
 	`)
 	if err != nil {
 		log.Printf("Warning inserting votos: %v", err)
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -39,6 +40,34 @@ type Config struct {
 	GoogleClientSecret string
 	GoogleRedirectURL  string
 	FrontendURL        string
+
+	// Datos de transferencia impresos en los avisos de cobro
+	BancoNombre       string
+	BancoTipoCuenta   string
+	BancoNumeroCuenta string
+	BancoTitular      string
+	BancoRUT          string
+	BancoEmail        string
+
+	// Recordatorios de gastos comunes
+	RecordatoriosEnabled          bool
+	RecordatoriosIntervaloMinutos int
+	RecordatoriosDiasAntes        []int
+	RecordatoriosEmision          bool
+	RecordatoriosVencido          bool
+
+	// Poderes que una misma persona puede ejercer en una votación o asamblea
+	// (reglamento de copropiedad); 0 = sin límite
+	PoderesMaximoPorPersona int
+
+	// Apertura y cierre automático de votaciones, con aviso de apertura,
+	// recordatorio a quienes no han votado (horas antes del cierre; 0 = sin
+	// recordatorio) y resultados
+	VotacionesIntervaloMinutos  int
+	VotacionesRecordatorioHoras int
+
+	// Montos en JSON: "number" (compatible con clientes existentes) o "string"
+	MoneyJSONFormat string
 }
 
 func Load() *Config {
@@ -67,6 +96,25 @@ func Load() *Config {
 		GoogleClientSecret:    getEnv("GOOGLE_CLIENT_SECRET", ""),
 		GoogleRedirectURL:     getEnv("GOOGLE_REDIRECT_URL", "http://localhost:8080/auth/google/callback"),
 		FrontendURL:           getEnv("FRONTEND_URL", "http://localhost:3000"),
+		BancoNombre:           getEnv("BANCO_NOMBRE", ""),
+		BancoTipoCuenta:       getEnv("BANCO_TIPO_CUENTA", ""),
+		BancoNumeroCuenta:     getEnv("BANCO_NUMERO_CUENTA", ""),
+		BancoTitular:          getEnv("BANCO_TITULAR", ""),
+		BancoRUT:              getEnv("BANCO_RUT", ""),
+		BancoEmail:            getEnv("BANCO_EMAIL", ""),
+
+		RecordatoriosEnabled:          getEnvBool("RECORDATORIOS_ENABLED", true),
+		RecordatoriosIntervaloMinutos: getEnvInt("RECORDATORIOS_INTERVALO_MINUTOS", 60),
+		RecordatoriosDiasAntes:        getEnvIntList("RECORDATORIOS_DIAS_ANTES", []int{7, 1}),
+		RecordatoriosEmision:          getEnvBool("RECORDATORIOS_EMISION", true),
+		RecordatoriosVencido:          getEnvBool("RECORDATORIOS_VENCIDO", true),
+
+		PoderesMaximoPorPersona: getEnvInt("PODERES_MAXIMO_POR_PERSONA", 2),
+
+		VotacionesIntervaloMinutos:  getEnvInt("VOTACIONES_INTERVALO_MINUTOS", 5),
+		VotacionesRecordatorioHoras: getEnvInt("VOTACIONES_RECORDATORIO_HORAS", 24),
+
+		MoneyJSONFormat: getEnv("MONEY_JSON_FORMAT", "number"),
 	}
 }
 
@@ -107,3 +155,19 @@ func getEnvBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+// getEnvIntList parses a comma separated list such as "7,3,1". An empty
+// variable yields an empty list.
+func getEnvIntList(key string, defaultValue []int) []int {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultValue
+	}
+	list := []int{}
+	for _, part := range strings.Split(value, ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(part)); err == nil {
+			list = append(list, n)
+		}
+	}
+	return list
+}
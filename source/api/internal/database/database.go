@@ -123,6 +123,28 @@ func (db *DB) RunMigrations(ctx context.Context) error {
 		migrationMapaPuntos,
 		migrationMensajesContacto,
 		migrationNotificaciones,
+		migrationPagosLedger,
+		migrationCreditosParcela,
+		migrationCuentaCorriente,
+		migrationConvenios,
+		migrationRecibos,
+		migrationRecordatorios,
+		migrationPeriodosAutomaticos,
+		migrationPeriodosEstado,
+		migrationImportacionesBanco,
+		migrationValoresUF,
+		migrationTesoreriaComprobantes,
+		migrationPresupuestos,
+		migrationCuentasTesoreria,
+		migrationPagosTesoreria,
+		migrationProveedores,
+		migrationConciliacionBancaria,
+		migrationVotacionParcelas,
+		migrationPoderes,
+		migrationVotacionSecreta,
+		migrationTiposVotacion,
+		migrationAvisosVotacion,
+		migrationCambioVoto,
 	}
 
 	for i, migration := range migrations {
@@ -131,9 +153,61 @@ func (db *DB) RunMigrations(ctx context.Context) error {
 		}
 	}
 
+	if _, err := db.Pool.Exec(ctx, migrationMigracionesUnicas); err != nil {
+		return fmt.Errorf("failed to create migraciones_unicas: %w", err)
+	}
+	for _, m := range migracionesUnicas {
+		if err := db.runOnce(ctx, m.nombre, m.sql); err != nil {
+			return fmt.Errorf("failed to run one-time migration %s: %w", m.nombre, err)
+		}
+	}
+
 	return nil
 }
 
+// migracionesUnicas are backfills and seeds that must run a single time, not
+// on every boot: running them again would undo later changes. Each is
+// recorded in migraciones_unicas by name; never rename one.
+var migracionesUnicas = []struct {
+	nombre string
+	sql    string
+}{
+	{"cuentas_tesoreria_iniciales", migracionCuentasTesoreriaIniciales},
+	{"reglas_aprobacion_iniciales", migracionReglasAprobacionIniciales},
+	{"avisos_votacion_previos", migracionAvisosVotacionPrevios},
+}
+
+// runOnce runs sql and records nombre in the same transaction, unless it was
+// already recorded.
+func (db *DB) runOnce(ctx context.Context, nombre, sql string) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO migraciones_unicas (nombre) VALUES ($1)
+		ON CONFLICT (nombre) DO NOTHING`, nombre)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+const migrationMigracionesUnicas = `
+CREATE TABLE IF NOT EXISTS migraciones_unicas (
+    nombre VARCHAR(100) PRIMARY KEY,
+    applied_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+`
+
 const migrationUsers = `
 CREATE EXTENSION IF NOT EXISTS "pgcrypto";
 
@@ -465,3 +539,799 @@ CREATE TABLE IF NOT EXISTS notificaciones (
 
 CREATE INDEX IF NOT EXISTS idx_notificaciones_user ON notificaciones(user_id, is_read);
 `
+
+const migrationPagosLedger = `
+-- Idempotency key sent by clients (Idempotency-Key header) so retried requests never double-charge
+ALTER TABLE pagos ADD COLUMN IF NOT EXISTS idempotency_key VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pagos_idempotency_key ON pagos(idempotency_key) WHERE idempotency_key IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_pagos_gasto_estado ON pagos(gasto_comun_id, estado);
+`
+
+const migrationCreditosParcela = `
+-- Pagos can be reversed (never deleted)
+ALTER TABLE pagos DROP CONSTRAINT IF EXISTS pagos_estado_check;
+ALTER TABLE pagos ADD CONSTRAINT pagos_estado_check CHECK (estado IN ('pending', 'approved', 'rejected', 'reversed'));
+ALTER TABLE pagos ADD COLUMN IF NOT EXISTS reversed_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE pagos ADD COLUMN IF NOT EXISTS reversed_by UUID REFERENCES users(id);
+ALTER TABLE pagos ADD COLUMN IF NOT EXISTS motivo_reverso TEXT;
+
+-- Per-parcela credit ledger: the balance is SUM(monto)
+CREATE TABLE IF NOT EXISTS creditos_parcela (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    parcela_id INTEGER NOT NULL REFERENCES parcelas(id) ON DELETE CASCADE,
+    monto DECIMAL(12,2) NOT NULL,
+    tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('sobrepago', 'aplicacion', 'reverso', 'reembolso')),
+    pago_id UUID REFERENCES pagos(id) ON DELETE SET NULL,
+    gasto_comun_id UUID REFERENCES gastos_comunes(id) ON DELETE SET NULL,
+    descripcion TEXT,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_creditos_parcela ON creditos_parcela(parcela_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_creditos_pago ON creditos_parcela(pago_id);
+
+-- Audit trail for financial corrections (reversals, refunds, voids)
+CREATE TABLE IF NOT EXISTS auditoria_financiera (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    entidad VARCHAR(50) NOT NULL,
+    entidad_id VARCHAR(100) NOT NULL,
+    accion VARCHAR(50) NOT NULL,
+    motivo TEXT,
+    datos JSONB,
+    user_id UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_auditoria_entidad ON auditoria_financiera(entidad, entidad_id);
+`
+
+const migrationCuentaCorriente = `
+-- Extra charges posted to a parcela's account (interest, fines, manual adjustments)
+CREATE TABLE IF NOT EXISTS cargos_parcela (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    parcela_id INTEGER NOT NULL REFERENCES parcelas(id) ON DELETE CASCADE,
+    gasto_comun_id UUID REFERENCES gastos_comunes(id) ON DELETE SET NULL,
+    tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('interes', 'multa', 'ajuste')),
+    monto DECIMAL(12,2) NOT NULL,
+    fecha DATE NOT NULL,
+    descripcion TEXT,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_cargos_parcela ON cargos_parcela(parcela_id, fecha);
+
+-- Opening balance for history imported from before the system existed
+CREATE TABLE IF NOT EXISTS saldos_iniciales (
+    parcela_id INTEGER PRIMARY KEY REFERENCES parcelas(id) ON DELETE CASCADE,
+    monto DECIMAL(12,2) NOT NULL,
+    fecha DATE NOT NULL,
+    descripcion TEXT,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+`
+
+const migrationConvenios = `
+-- Gastos grouped into a payment plan stop counting as overdue while the plan is active
+ALTER TABLE gastos_comunes DROP CONSTRAINT IF EXISTS gastos_comunes_status_check;
+ALTER TABLE gastos_comunes ADD CONSTRAINT gastos_comunes_status_check CHECK (status IN ('pending', 'paid', 'overdue', 'cancelled', 'convenio'));
+
+CREATE TABLE IF NOT EXISTS convenios_pago (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    parcela_id INTEGER NOT NULL REFERENCES parcelas(id) ON DELETE CASCADE,
+    estado VARCHAR(20) NOT NULL DEFAULT 'activo' CHECK (estado IN ('activo', 'cumplido', 'roto', 'anulado')),
+    monto_total DECIMAL(12,2) NOT NULL,
+    num_cuotas INTEGER NOT NULL CHECK (num_cuotas > 0),
+    max_cuotas_impagas INTEGER NOT NULL DEFAULT 2 CHECK (max_cuotas_impagas > 0),
+    congelar_intereses BOOLEAN NOT NULL DEFAULT false,
+    intereses_congelados DECIMAL(12,2) NOT NULL DEFAULT 0,
+    observaciones TEXT,
+    motivo_termino TEXT,
+    terminado_at TIMESTAMP WITH TIME ZONE,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Gastos included in a convenio, with what was already paid when it was agreed
+CREATE TABLE IF NOT EXISTS convenio_gastos (
+    convenio_id UUID NOT NULL REFERENCES convenios_pago(id) ON DELETE CASCADE,
+    gasto_comun_id UUID NOT NULL REFERENCES gastos_comunes(id) ON DELETE CASCADE,
+    monto_pendiente DECIMAL(12,2) NOT NULL,
+    monto_pagado_inicial DECIMAL(12,2) NOT NULL DEFAULT 0,
+    PRIMARY KEY (convenio_id, gasto_comun_id)
+);
+
+CREATE TABLE IF NOT EXISTS convenio_cuotas (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    convenio_id UUID NOT NULL REFERENCES convenios_pago(id) ON DELETE CASCADE,
+    numero INTEGER NOT NULL,
+    monto DECIMAL(12,2) NOT NULL,
+    fecha_vencimiento DATE NOT NULL,
+    periodo_id UUID REFERENCES periodos_gasto(id) ON DELETE SET NULL,
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente' CHECK (estado IN ('pendiente', 'pagada', 'vencida')),
+    pagada_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE(convenio_id, numero)
+);
+
+CREATE INDEX IF NOT EXISTS idx_convenios_parcela ON convenios_pago(parcela_id, estado);
+CREATE INDEX IF NOT EXISTS idx_convenio_gastos_gasto ON convenio_gastos(gasto_comun_id);
+CREATE INDEX IF NOT EXISTS idx_convenio_cuotas_vencimiento ON convenio_cuotas(fecha_vencimiento) WHERE estado <> 'pagada';
+`
+
+const migrationRecibos = `
+-- Gapless counters for numbered documents (receipts, vouchers...). Numbers are
+-- taken inside the issuing transaction, so they never repeat.
+CREATE TABLE IF NOT EXISTS correlativos (
+    tipo VARCHAR(50) PRIMARY KEY,
+    ultimo BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS recibos (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    numero BIGINT NOT NULL UNIQUE,
+    pago_id UUID NOT NULL UNIQUE REFERENCES pagos(id) ON DELETE RESTRICT,
+    parcela_id INTEGER NOT NULL REFERENCES parcelas(id),
+    monto DECIMAL(12,2) NOT NULL,
+    emitido_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    anulado_at TIMESTAMP WITH TIME ZONE,
+    motivo_anulacion TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_recibos_parcela ON recibos(parcela_id);
+`
+
+const migrationRecordatorios = `
+-- One row per reminder sent for a gasto. The unique key is what guarantees a
+-- reminder is never sent twice, even with several API instances running.
+CREATE TABLE IF NOT EXISTS recordatorios_gasto (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    gasto_comun_id UUID NOT NULL REFERENCES gastos_comunes(id) ON DELETE CASCADE,
+    tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('emision', 'previo', 'vencido')),
+    dias INTEGER NOT NULL DEFAULT 0,
+    notificaciones INTEGER NOT NULL DEFAULT 0,
+    emails INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(gasto_comun_id, tipo, dias)
+);
+
+-- Per-user opt-out of gasto reminders; users without a row get everything
+CREATE TABLE IF NOT EXISTS preferencias_notificacion (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    email_gastos BOOLEAN NOT NULL DEFAULT TRUE,
+    app_gastos BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+`
+
+const migrationPeriodosAutomaticos = `
+ALTER TABLE periodos_gasto ADD COLUMN IF NOT EXISTS estado VARCHAR(20) NOT NULL DEFAULT 'abierto';
+ALTER TABLE periodos_gasto DROP CONSTRAINT IF EXISTS periodos_gasto_estado_check;
+-- Single definition of the periodo lifecycle: borrador -> abierto -> cerrado
+ALTER TABLE periodos_gasto ADD CONSTRAINT periodos_gasto_estado_check CHECK (estado IN ('borrador', 'abierto', 'cerrado'));
+ALTER TABLE periodos_gasto ADD COLUMN IF NOT EXISTS generado_automaticamente BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE periodos_gasto ADD COLUMN IF NOT EXISTS aprobado_by UUID REFERENCES users(id);
+ALTER TABLE periodos_gasto ADD COLUMN IF NOT EXISTS aprobado_at TIMESTAMP WITH TIME ZONE;
+
+-- Single-row recurrence settings used to generate the next periodo
+CREATE TABLE IF NOT EXISTS config_periodos (
+    id INTEGER PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    activa BOOLEAN NOT NULL DEFAULT FALSE,
+    modo VARCHAR(20) NOT NULL DEFAULT 'monto' CHECK (modo IN ('monto', 'presupuesto')),
+    monto_base DECIMAL(12,2) NOT NULL DEFAULT 0,
+    presupuesto_mensual DECIMAL(12,2) NOT NULL DEFAULT 0,
+    dia_vencimiento INTEGER NOT NULL DEFAULT 10 CHECK (dia_vencimiento BETWEEN 1 AND 31),
+    ajuste_dia_habil VARCHAR(20) NOT NULL DEFAULT 'siguiente' CHECK (ajuste_dia_habil IN ('ninguno', 'siguiente', 'anterior')),
+    descripcion_plantilla TEXT NOT NULL DEFAULT 'Gasto común {periodo}',
+    dias_anticipacion INTEGER NOT NULL DEFAULT 5 CHECK (dias_anticipacion BETWEEN 0 AND 28),
+    requiere_aprobacion BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_by UUID REFERENCES users(id),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+`
+
+const migrationPeriodosEstado = `
+-- 'cerrado' is part of periodos_gasto_estado_check in migrationPeriodosAutomaticos
+ALTER TABLE periodos_gasto ADD COLUMN IF NOT EXISTS cerrado_by UUID REFERENCES users(id);
+ALTER TABLE periodos_gasto ADD COLUMN IF NOT EXISTS cerrado_at TIMESTAMP WITH TIME ZONE;
+`
+
+const migrationImportacionesBanco = `
+-- CSV layouts of the bank exports
+CREATE TABLE IF NOT EXISTS formatos_importacion (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    nombre VARCHAR(100) NOT NULL UNIQUE,
+    separador VARCHAR(1) NOT NULL DEFAULT ';',
+    tiene_encabezado BOOLEAN NOT NULL DEFAULT TRUE,
+    filas_omitir INTEGER NOT NULL DEFAULT 0 CHECK (filas_omitir >= 0),
+    columna_fecha VARCHAR(100) NOT NULL,
+    columna_monto VARCHAR(100) NOT NULL,
+    columna_rut VARCHAR(100) NOT NULL DEFAULT '',
+    columna_nombre VARCHAR(100) NOT NULL DEFAULT '',
+    columna_descripcion VARCHAR(100) NOT NULL DEFAULT '',
+    columna_referencia VARCHAR(100) NOT NULL DEFAULT '',
+    formato_fecha VARCHAR(20) NOT NULL DEFAULT 'DD/MM/YYYY',
+    separador_decimal VARCHAR(1) NOT NULL DEFAULT ',' CHECK (separador_decimal IN (',', '.')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Staged bank imports, confirmed as a whole
+CREATE TABLE IF NOT EXISTS importaciones_banco (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    formato_id UUID REFERENCES formatos_importacion(id) ON DELETE SET NULL,
+    archivo_nombre VARCHAR(255) NOT NULL,
+    estado VARCHAR(20) NOT NULL DEFAULT 'staging' CHECK (estado IN ('staging', 'confirmada', 'descartada')),
+    created_by UUID REFERENCES users(id),
+    confirmada_by UUID REFERENCES users(id),
+    confirmada_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS importaciones_banco_filas (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    importacion_id UUID NOT NULL REFERENCES importaciones_banco(id) ON DELETE CASCADE,
+    linea INTEGER NOT NULL,
+    fecha DATE,
+    monto DECIMAL(12,2) NOT NULL DEFAULT 0,
+    rut VARCHAR(20) NOT NULL DEFAULT '',
+    nombre VARCHAR(255) NOT NULL DEFAULT '',
+    descripcion TEXT NOT NULL DEFAULT '',
+    referencia VARCHAR(255) NOT NULL DEFAULT '',
+    huella VARCHAR(64) NOT NULL,
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente'
+        CHECK (estado IN ('pendiente', 'ignorada', 'duplicada', 'error', 'importada')),
+    parcela_id INTEGER REFERENCES parcelas(id),
+    gasto_comun_id UUID REFERENCES gastos_comunes(id) ON DELETE SET NULL,
+    match VARCHAR(20) NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    pago_id UUID REFERENCES pagos(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_importaciones_filas_importacion ON importaciones_banco_filas(importacion_id);
+CREATE INDEX IF NOT EXISTS idx_importaciones_filas_rut ON importaciones_banco_filas(rut) WHERE estado = 'importada';
+-- A bank movement is imported at most once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_importaciones_filas_huella ON importaciones_banco_filas(huella) WHERE estado = 'importada';
+`
+
+const migrationValoresUF = `
+-- Daily UF values, maintained locally
+CREATE TABLE IF NOT EXISTS valores_uf (
+    fecha DATE PRIMARY KEY,
+    valor DECIMAL(10,2) NOT NULL CHECK (valor > 0),
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Periodos defined in UF keep the amount and the value used to convert it
+ALTER TABLE periodos_gasto ADD COLUMN IF NOT EXISTS moneda VARCHAR(3) NOT NULL DEFAULT 'CLP';
+ALTER TABLE periodos_gasto DROP CONSTRAINT IF EXISTS periodos_gasto_moneda_check;
+ALTER TABLE periodos_gasto ADD CONSTRAINT periodos_gasto_moneda_check CHECK (moneda IN ('CLP', 'UF'));
+ALTER TABLE periodos_gasto ADD COLUMN IF NOT EXISTS monto_base_uf DECIMAL(10,4);
+ALTER TABLE periodos_gasto ADD COLUMN IF NOT EXISTS valor_uf DECIMAL(10,2);
+ALTER TABLE periodos_gasto ADD COLUMN IF NOT EXISTS fecha_uf DATE;
+
+ALTER TABLE config_periodos ADD COLUMN IF NOT EXISTS monto_base_uf DECIMAL(10,4) NOT NULL DEFAULT 0;
+ALTER TABLE config_periodos DROP CONSTRAINT IF EXISTS config_periodos_modo_check;
+ALTER TABLE config_periodos ADD CONSTRAINT config_periodos_modo_check CHECK (modo IN ('monto', 'presupuesto', 'uf'));
+`
+
+const migrationTesoreriaComprobantes = `
+-- Voucher number, void and correction tracking for treasury movimientos
+ALTER TABLE movimientos_tesoreria ADD COLUMN IF NOT EXISTS comprobante BIGINT;
+ALTER TABLE movimientos_tesoreria ADD COLUMN IF NOT EXISTS anulado_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE movimientos_tesoreria ADD COLUMN IF NOT EXISTS anulado_by UUID REFERENCES users(id);
+ALTER TABLE movimientos_tesoreria ADD COLUMN IF NOT EXISTS motivo_anulacion TEXT;
+ALTER TABLE movimientos_tesoreria ADD COLUMN IF NOT EXISTS reverso_de UUID REFERENCES movimientos_tesoreria(id);
+ALTER TABLE movimientos_tesoreria ADD COLUMN IF NOT EXISTS updated_by UUID REFERENCES users(id);
+
+-- Existing movimientos are numbered in date order after any number already used
+WITH numerados AS (
+    SELECT id, ROW_NUMBER() OVER (ORDER BY date, created_at, id)
+        + (SELECT COALESCE(MAX(comprobante), 0) FROM movimientos_tesoreria) AS numero
+    FROM movimientos_tesoreria
+    WHERE comprobante IS NULL
+)
+UPDATE movimientos_tesoreria m SET comprobante = n.numero
+FROM numerados n WHERE m.id = n.id;
+
+INSERT INTO correlativos (tipo, ultimo)
+SELECT 'comprobante_tesoreria', COALESCE(MAX(comprobante), 0) FROM movimientos_tesoreria
+ON CONFLICT (tipo) DO UPDATE SET ultimo = GREATEST(correlativos.ultimo, EXCLUDED.ultimo);
+
+ALTER TABLE movimientos_tesoreria ALTER COLUMN comprobante SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tesoreria_comprobante ON movimientos_tesoreria(comprobante);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tesoreria_reverso ON movimientos_tesoreria(reverso_de) WHERE reverso_de IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_tesoreria_category ON movimientos_tesoreria(category);
+
+CREATE TABLE IF NOT EXISTS movimientos_adjuntos (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    movimiento_id UUID NOT NULL REFERENCES movimientos_tesoreria(id),
+    nombre_archivo VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    tamano INTEGER NOT NULL,
+    contenido BYTEA NOT NULL,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_movimientos_adjuntos_movimiento ON movimientos_adjuntos(movimiento_id);
+`
+
+const migrationPresupuestos = `
+-- Managed catalogue of treasury categories; movimientos keep the codigo
+CREATE TABLE IF NOT EXISTS categorias_tesoreria (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    codigo VARCHAR(100) NOT NULL UNIQUE,
+    nombre VARCHAR(150) NOT NULL,
+    tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('ingreso', 'egreso')),
+    activa BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- The free-text categories already in use become the initial catalogue
+UPDATE movimientos_tesoreria SET category = NULL WHERE TRIM(category) = '';
+INSERT INTO categorias_tesoreria (codigo, nombre, tipo)
+SELECT category, INITCAP(REPLACE(category, '_', ' ')),
+       CASE WHEN BOOL_OR(type = 'egreso') THEN 'egreso' ELSE 'ingreso' END
+FROM movimientos_tesoreria
+WHERE category IS NOT NULL
+GROUP BY category
+ON CONFLICT (codigo) DO NOTHING;
+
+ALTER TABLE movimientos_tesoreria DROP CONSTRAINT IF EXISTS movimientos_tesoreria_category_fkey;
+ALTER TABLE movimientos_tesoreria ADD CONSTRAINT movimientos_tesoreria_category_fkey
+    FOREIGN KEY (category) REFERENCES categorias_tesoreria(codigo);
+
+CREATE TABLE IF NOT EXISTS presupuestos (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    year INTEGER NOT NULL UNIQUE,
+    descripcion TEXT,
+    umbral_alerta DECIMAL(5,2) NOT NULL DEFAULT 90 CHECK (umbral_alerta > 0),
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- alertado_at marks that the directiva was notified the threshold was crossed
+CREATE TABLE IF NOT EXISTS presupuesto_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    presupuesto_id UUID NOT NULL REFERENCES presupuestos(id) ON DELETE CASCADE,
+    categoria VARCHAR(100) NOT NULL REFERENCES categorias_tesoreria(codigo),
+    monto_anual DECIMAL(12,2) NOT NULL CHECK (monto_anual >= 0),
+    umbral_alerta DECIMAL(5,2) CHECK (umbral_alerta > 0),
+    alertado_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (presupuesto_id, categoria)
+);
+`
+
+const migrationCuentasTesoreria = `
+-- Treasury accounts; existing movimientos go to the principal one
+CREATE TABLE IF NOT EXISTS cuentas_tesoreria (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    codigo VARCHAR(50) NOT NULL UNIQUE,
+    nombre VARCHAR(150) NOT NULL,
+    tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('corriente', 'caja', 'fondo_reserva', 'otra')),
+    principal BOOLEAN NOT NULL DEFAULT FALSE,
+    activa BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cuentas_tesoreria_principal ON cuentas_tesoreria(principal) WHERE principal;
+
+INSERT INTO cuentas_tesoreria (codigo, nombre, tipo, principal)
+SELECT 'cuenta_corriente', 'Cuenta corriente', 'corriente', TRUE
+WHERE NOT EXISTS (SELECT 1 FROM cuentas_tesoreria WHERE principal);
+
+ALTER TABLE movimientos_tesoreria ADD COLUMN IF NOT EXISTS cuenta_id UUID REFERENCES cuentas_tesoreria(id);
+UPDATE movimientos_tesoreria SET cuenta_id = (SELECT id FROM cuentas_tesoreria WHERE principal)
+WHERE cuenta_id IS NULL;
+ALTER TABLE movimientos_tesoreria ALTER COLUMN cuenta_id SET NOT NULL;
+
+-- Both entries of a transfer share transferencia_id; pago_id marks the
+-- reserve fund allocation of a pago
+ALTER TABLE movimientos_tesoreria ADD COLUMN IF NOT EXISTS transferencia_id UUID;
+ALTER TABLE movimientos_tesoreria ADD COLUMN IF NOT EXISTS pago_id UUID REFERENCES pagos(id);
+CREATE INDEX IF NOT EXISTS idx_tesoreria_cuenta ON movimientos_tesoreria(cuenta_id);
+CREATE INDEX IF NOT EXISTS idx_tesoreria_transferencia ON movimientos_tesoreria(transferencia_id) WHERE transferencia_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_tesoreria_pago ON movimientos_tesoreria(pago_id) WHERE pago_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS config_tesoreria (
+    id INTEGER PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    porcentaje_fondo_reserva DECIMAL(5,2) NOT NULL DEFAULT 0 CHECK (porcentaje_fondo_reserva BETWEEN 0 AND 100),
+    cuenta_reserva_id UUID REFERENCES cuentas_tesoreria(id),
+    updated_by UUID REFERENCES users(id),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+`
+
+// Petty cash and reserve fund accounts of a new install. Runs once so the
+// directiva's later changes to them are kept.
+const migracionCuentasTesoreriaIniciales = `
+INSERT INTO cuentas_tesoreria (codigo, nombre, tipo)
+VALUES ('caja', 'Caja chica', 'caja'), ('fondo_reserva', 'Fondo de reserva', 'fondo_reserva')
+ON CONFLICT (codigo) DO NOTHING;
+`
+
+const migrationPagosTesoreria = `
+-- How approved pagos are posted to the treasury ledger
+ALTER TABLE config_tesoreria ADD COLUMN IF NOT EXISTS contabilizacion_pagos VARCHAR(20) NOT NULL DEFAULT 'individual';
+ALTER TABLE config_tesoreria DROP CONSTRAINT IF EXISTS config_tesoreria_contabilizacion_pagos_check;
+ALTER TABLE config_tesoreria ADD CONSTRAINT config_tesoreria_contabilizacion_pagos_check
+    CHECK (contabilizacion_pagos IN ('desactivada', 'individual', 'diaria'));
+
+INSERT INTO categorias_tesoreria (codigo, nombre, tipo)
+VALUES ('gastos_comunes', 'Gastos comunes', 'ingreso'), ('reverso_pagos', 'Reverso de pagos', 'egreso'),
+       ('reembolso_creditos', 'Reembolso de saldos a favor', 'egreso')
+ON CONFLICT (codigo) DO NOTHING;
+
+-- One row per pago posted (ingreso) and per reversal posted (reverso).
+-- movimiento_id stays NULL while a pago waits for the daily posting.
+CREATE TABLE IF NOT EXISTS pagos_tesoreria (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    pago_id UUID NOT NULL REFERENCES pagos(id),
+    tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('ingreso', 'reverso')),
+    monto DECIMAL(12,2) NOT NULL,
+    fecha DATE NOT NULL,
+    movimiento_id UUID REFERENCES movimientos_tesoreria(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (pago_id, tipo)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pagos_tesoreria_movimiento ON pagos_tesoreria(movimiento_id);
+CREATE INDEX IF NOT EXISTS idx_pagos_tesoreria_pendientes ON pagos_tesoreria(fecha) WHERE movimiento_id IS NULL;
+
+-- Egreso posting a refund of credit to the parcela
+ALTER TABLE creditos_parcela ADD COLUMN IF NOT EXISTS movimiento_id UUID REFERENCES movimientos_tesoreria(id);
+`
+
+const migrationProveedores = `
+CREATE TABLE IF NOT EXISTS proveedores (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    nombre VARCHAR(255) NOT NULL,
+    rut VARCHAR(20) NOT NULL UNIQUE,
+    email VARCHAR(255),
+    telefono VARCHAR(50),
+    banco VARCHAR(100),
+    tipo_cuenta VARCHAR(50),
+    numero_cuenta VARCHAR(50),
+    categoria VARCHAR(100) REFERENCES categorias_tesoreria(codigo) ON UPDATE CASCADE,
+    notas TEXT,
+    activo BOOLEAN NOT NULL DEFAULT true,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS facturas (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    proveedor_id UUID NOT NULL REFERENCES proveedores(id),
+    numero VARCHAR(50) NOT NULL,
+    descripcion TEXT NOT NULL,
+    categoria VARCHAR(100) REFERENCES categorias_tesoreria(codigo) ON UPDATE CASCADE,
+    monto DECIMAL(12,2) NOT NULL CHECK (monto > 0),
+    fecha_emision DATE NOT NULL,
+    fecha_vencimiento DATE,
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente'
+        CHECK (estado IN ('pendiente', 'aprobada', 'rechazada', 'pagada', 'anulada')),
+    aprobaciones_requeridas INTEGER NOT NULL DEFAULT 1,
+    movimiento_id UUID REFERENCES movimientos_tesoreria(id),
+    pagada_at TIMESTAMP WITH TIME ZONE,
+    pagada_by UUID REFERENCES users(id),
+    motivo_anulacion TEXT,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (proveedor_id, numero)
+);
+
+CREATE INDEX IF NOT EXISTS idx_facturas_estado ON facturas(estado);
+CREATE INDEX IF NOT EXISTS idx_facturas_movimiento ON facturas(movimiento_id);
+
+-- One signature per directiva member and factura; aprobada = false rejects it
+CREATE TABLE IF NOT EXISTS factura_aprobaciones (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    factura_id UUID NOT NULL REFERENCES facturas(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id),
+    aprobada BOOLEAN NOT NULL,
+    comentario TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (factura_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS facturas_adjuntos (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    factura_id UUID NOT NULL REFERENCES facturas(id),
+    nombre_archivo VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    tamano INTEGER NOT NULL,
+    contenido BYTEA NOT NULL,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_facturas_adjuntos_factura ON facturas_adjuntos(factura_id);
+
+-- Approval chain: facturas of monto_desde or more need that many signatures
+CREATE TABLE IF NOT EXISTS reglas_aprobacion_facturas (
+    monto_desde DECIMAL(12,2) PRIMARY KEY CHECK (monto_desde >= 0),
+    aprobaciones INTEGER NOT NULL CHECK (aprobaciones BETWEEN 1 AND 10)
+);
+`
+
+// Default approval chain. Runs once, and only on an empty table: the
+// directiva replaces the rules as a whole, and removed thresholds must not
+// come back on the next boot.
+const migracionReglasAprobacionIniciales = `
+INSERT INTO reglas_aprobacion_facturas (monto_desde, aprobaciones)
+SELECT monto_desde, aprobaciones FROM (VALUES (0, 1), (500000, 2)) AS r(monto_desde, aprobaciones)
+WHERE NOT EXISTS (SELECT 1 FROM reglas_aprobacion_facturas);
+`
+
+const migrationConciliacionBancaria = `
+-- Bank statements (cartolas) uploaded per cuenta, in CSV or OFX
+CREATE TABLE IF NOT EXISTS cartolas_bancarias (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    cuenta_id UUID NOT NULL REFERENCES cuentas_tesoreria(id),
+    archivo_nombre VARCHAR(255) NOT NULL,
+    formato VARCHAR(10) NOT NULL CHECK (formato IN ('csv', 'ofx')),
+    formato_id UUID REFERENCES formatos_importacion(id) ON DELETE SET NULL,
+    desde DATE,
+    hasta DATE,
+    saldo_final DECIMAL(12,2),
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_cartolas_bancarias_cuenta ON cartolas_bancarias(cuenta_id);
+
+-- Statement lines: monto is positive for deposits and negative for charges.
+-- huella identifies a bank movement so overlapping statements are not loaded
+-- twice; movimiento_id is the ledger entry the line is reconciled with.
+CREATE TABLE IF NOT EXISTS cartola_lineas (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    cartola_id UUID NOT NULL REFERENCES cartolas_bancarias(id) ON DELETE CASCADE,
+    cuenta_id UUID NOT NULL REFERENCES cuentas_tesoreria(id),
+    linea INTEGER NOT NULL,
+    fecha DATE NOT NULL,
+    monto DECIMAL(12,2) NOT NULL CHECK (monto <> 0),
+    descripcion TEXT,
+    referencia VARCHAR(255),
+    huella VARCHAR(64) NOT NULL,
+    movimiento_id UUID REFERENCES movimientos_tesoreria(id),
+    match VARCHAR(20) CHECK (match IN ('referencia', 'monto_fecha', 'manual')),
+    conciliada_at TIMESTAMP WITH TIME ZONE,
+    conciliada_by UUID REFERENCES users(id),
+    UNIQUE (cuenta_id, huella)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cartola_lineas_cartola ON cartola_lineas(cartola_id);
+CREATE INDEX IF NOT EXISTS idx_cartola_lineas_fecha ON cartola_lineas(cuenta_id, fecha);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cartola_lineas_movimiento ON cartola_lineas(movimiento_id) WHERE movimiento_id IS NOT NULL;
+
+-- Period closing: the bank and book balances at fecha_corte, what was left
+-- unreconciled and the difference those items do not explain
+CREATE TABLE IF NOT EXISTS conciliaciones_bancarias (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    cuenta_id UUID NOT NULL REFERENCES cuentas_tesoreria(id),
+    fecha_corte DATE NOT NULL,
+    saldo_banco DECIMAL(12,2) NOT NULL,
+    saldo_libro DECIMAL(12,2) NOT NULL,
+    diferencia DECIMAL(12,2) NOT NULL,
+    lineas_pendientes INTEGER NOT NULL,
+    monto_lineas_pendientes DECIMAL(12,2) NOT NULL,
+    movimientos_pendientes INTEGER NOT NULL,
+    monto_movimientos_pendientes DECIMAL(12,2) NOT NULL,
+    diferencia_no_explicada DECIMAL(12,2) NOT NULL,
+    observaciones TEXT,
+    firmado_by UUID NOT NULL REFERENCES users(id),
+    firmado_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (cuenta_id, fecha_corte)
+);
+`
+
+const migrationVotacionParcelas = `
+-- Voting weight of each parcela (coefficient); 1 means one parcela, one vote
+ALTER TABLE parcelas ADD COLUMN IF NOT EXISTS coeficiente DECIMAL(9,6) NOT NULL DEFAULT 1;
+ALTER TABLE parcelas DROP CONSTRAINT IF EXISTS parcelas_coeficiente_check;
+ALTER TABLE parcelas ADD CONSTRAINT parcelas_coeficiente_check CHECK (coeficiente > 0);
+
+-- Weighted votaciones decide quorum and majorities by coefficient instead of
+-- by number of parcelas
+ALTER TABLE votaciones ADD COLUMN IF NOT EXISTS ponderada BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- The ballot belongs to the parcela, with the coefficient it had when cast
+ALTER TABLE votos ADD COLUMN IF NOT EXISTS parcela_id INTEGER REFERENCES parcelas(id);
+ALTER TABLE votos ADD COLUMN IF NOT EXISTS peso DECIMAL(9,6) NOT NULL DEFAULT 1;
+
+-- Existing votes: the first one of each parcela keeps counting; later votes of
+-- the same parcela (and votes of users without parcela) stay as history only
+UPDATE votos v SET parcela_id = p.parcela_id, peso = p.coeficiente
+FROM (
+    SELECT DISTINCT ON (vo.votacion_id, u.parcela_id) vo.id, u.parcela_id, pa.coeficiente
+    FROM votos vo
+    JOIN users u ON u.id = vo.user_id
+    JOIN parcelas pa ON pa.id = u.parcela_id
+    WHERE vo.parcela_id IS NULL
+      AND NOT EXISTS (SELECT 1 FROM votos o WHERE o.votacion_id = vo.votacion_id AND o.parcela_id = u.parcela_id)
+    ORDER BY vo.votacion_id, u.parcela_id, vo.voted_at, vo.id
+) p
+WHERE v.id = p.id;
+
+ALTER TABLE votos DROP CONSTRAINT IF EXISTS votos_votacion_id_user_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_votos_parcela ON votos(votacion_id, parcela_id);
+`
+
+const migrationPoderes = `
+-- Votaciones held during an asamblea, so a poder for the asamblea covers them
+ALTER TABLE votaciones ADD COLUMN IF NOT EXISTS evento_id UUID REFERENCES eventos(id) ON DELETE SET NULL;
+
+-- Written proxies: a parcela lets a neighbour vote on its behalf in one
+-- votacion or in every votacion of an asamblea. The directiva validates them
+-- against the signed document before they can be used.
+CREATE TABLE IF NOT EXISTS poderes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    parcela_id INTEGER NOT NULL REFERENCES parcelas(id),
+    apoderado_id UUID NOT NULL REFERENCES users(id),
+    votacion_id UUID REFERENCES votaciones(id) ON DELETE CASCADE,
+    evento_id UUID REFERENCES eventos(id) ON DELETE CASCADE,
+    valido_desde TIMESTAMP WITH TIME ZONE NOT NULL,
+    valido_hasta TIMESTAMP WITH TIME ZONE NOT NULL,
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente' CHECK (estado IN ('pendiente', 'validado', 'rechazado', 'revocado')),
+    documento_nombre VARCHAR(255),
+    documento_content_type VARCHAR(100),
+    documento_tamano INTEGER,
+    documento BYTEA,
+    motivo_rechazo TEXT,
+    revisado_by UUID REFERENCES users(id),
+    revisado_at TIMESTAMP WITH TIME ZONE,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK ((votacion_id IS NULL) <> (evento_id IS NULL)),
+    CHECK (valido_hasta > valido_desde)
+);
+
+CREATE INDEX IF NOT EXISTS idx_poderes_apoderado ON poderes(apoderado_id);
+CREATE INDEX IF NOT EXISTS idx_poderes_parcela ON poderes(parcela_id);
+-- One live poder per parcela and scope
+CREATE UNIQUE INDEX IF NOT EXISTS idx_poderes_votacion ON poderes(parcela_id, votacion_id) WHERE estado IN ('pendiente', 'validado') AND votacion_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_poderes_evento ON poderes(parcela_id, evento_id) WHERE estado IN ('pendiente', 'validado') AND evento_id IS NOT NULL;
+
+-- Ballots cast by an apoderado on behalf of the parcela
+ALTER TABLE votos ADD COLUMN IF NOT EXISTS poder_id UUID REFERENCES poderes(id);
+`
+
+const migrationVotacionSecreta = `
+ALTER TABLE votaciones ADD COLUMN IF NOT EXISTS secreta BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Secret ballots. In secret votaciones votos only records who voted (no
+-- opcion); the contents go here, with nothing linking them to the voter: no
+-- user, no parcela, no timestamp. recibo is the SHA-256 of the code handed to
+-- the voter, so each voter can find their ballot in the published list.
+CREATE TABLE IF NOT EXISTS boletas (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    votacion_id UUID NOT NULL REFERENCES votaciones(id) ON DELETE CASCADE,
+    opcion_id UUID REFERENCES votacion_opciones(id) ON DELETE CASCADE,
+    is_abstention BOOLEAN NOT NULL DEFAULT FALSE,
+    peso DECIMAL(9,6) NOT NULL DEFAULT 1,
+    recibo VARCHAR(64) NOT NULL UNIQUE,
+    CHECK (is_abstention OR opcion_id IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_boletas_votacion ON boletas(votacion_id);
+
+-- Ballot box of open secret votaciones. Each ballot is written in the same
+-- transaction as its votos row and moves to boletas in one shuffled batch at
+-- closing, so the published boletas keep no order of casting. While open, the
+-- urna can be paired with votos by whoever reads the database directly.
+CREATE TABLE IF NOT EXISTS urna_boletas (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    votacion_id UUID NOT NULL REFERENCES votaciones(id) ON DELETE CASCADE,
+    opcion_id UUID REFERENCES votacion_opciones(id) ON DELETE CASCADE,
+    is_abstention BOOLEAN NOT NULL DEFAULT FALSE,
+    peso DECIMAL(9,6) NOT NULL DEFAULT 1,
+    recibo VARCHAR(64) NOT NULL UNIQUE,
+    CHECK (is_abstention OR opcion_id IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_urna_boletas_votacion ON urna_boletas(votacion_id);
+
+-- A second fingerprint of the receipt code, kept with who voted, so only the
+-- holder of the code can change that parcela's ballot. Without the code it
+-- cannot be matched with the published recibo.
+ALTER TABLE votos ADD COLUMN IF NOT EXISTS llave_recibo VARCHAR(64);
+`
+
+const migrationTiposVotacion = `
+-- simple: one opcion; multiple: approve between min and max opciones;
+-- preferencial: rank between min and max opciones, counted by instant-runoff
+-- (irv) or Borda. max_selecciones 0 means up to every opcion.
+ALTER TABLE votaciones ADD COLUMN IF NOT EXISTS tipo VARCHAR(20) NOT NULL DEFAULT 'simple';
+ALTER TABLE votaciones ADD COLUMN IF NOT EXISTS min_selecciones INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE votaciones ADD COLUMN IF NOT EXISTS max_selecciones INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE votaciones ADD COLUMN IF NOT EXISTS metodo_conteo VARCHAR(20) NOT NULL DEFAULT 'irv';
+ALTER TABLE votaciones DROP CONSTRAINT IF EXISTS votaciones_tipo_check;
+ALTER TABLE votaciones ADD CONSTRAINT votaciones_tipo_check
+    CHECK (tipo IN ('simple', 'multiple', 'preferencial'));
+ALTER TABLE votaciones DROP CONSTRAINT IF EXISTS votaciones_selecciones_check;
+ALTER TABLE votaciones ADD CONSTRAINT votaciones_selecciones_check
+    CHECK (min_selecciones >= 1 AND (max_selecciones = 0 OR max_selecciones >= min_selecciones));
+ALTER TABLE votaciones DROP CONSTRAINT IF EXISTS votaciones_metodo_conteo_check;
+ALTER TABLE votaciones ADD CONSTRAINT votaciones_metodo_conteo_check
+    CHECK (metodo_conteo IN ('irv', 'borda'));
+
+-- Selected opciones of multiple and preferencial ballots, in order of
+-- preference; opcion_id stays for simple ones
+ALTER TABLE votos ADD COLUMN IF NOT EXISTS opciones UUID[];
+ALTER TABLE boletas ADD COLUMN IF NOT EXISTS opciones UUID[];
+ALTER TABLE boletas DROP CONSTRAINT IF EXISTS boletas_check;
+ALTER TABLE boletas DROP CONSTRAINT IF EXISTS boletas_contenido_check;
+ALTER TABLE boletas ADD CONSTRAINT boletas_contenido_check
+    CHECK (is_abstention OR opcion_id IS NOT NULL OR cardinality(opciones) > 0);
+ALTER TABLE urna_boletas ADD COLUMN IF NOT EXISTS opciones UUID[];
+ALTER TABLE urna_boletas DROP CONSTRAINT IF EXISTS urna_boletas_check;
+ALTER TABLE urna_boletas DROP CONSTRAINT IF EXISTS urna_boletas_contenido_check;
+ALTER TABLE urna_boletas ADD CONSTRAINT urna_boletas_contenido_check
+    CHECK (is_abstention OR opcion_id IS NOT NULL OR cardinality(opciones) > 0);
+`
+
+const migrationAvisosVotacion = `
+-- Notices of the votaciones job: opening, reminder to parcelas that have not
+-- voted, and results. One per votacion and tipo, recorded before it goes out
+-- so it is never sent twice.
+CREATE TABLE IF NOT EXISTS avisos_votacion (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    votacion_id UUID NOT NULL REFERENCES votaciones(id) ON DELETE CASCADE,
+    tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('apertura', 'recordatorio', 'resultados')),
+    notificaciones INTEGER NOT NULL DEFAULT 0,
+    emails INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (votacion_id, tipo)
+);
+`
+
+// Votaciones opened or closed before the job existed are not announced
+// again. Runs once: on later boots it would swallow the notices of
+// votaciones opened or closed since the last run of the job.
+const migracionAvisosVotacionPrevios = `
+INSERT INTO avisos_votacion (votacion_id, tipo)
+SELECT id, 'apertura' FROM votaciones WHERE status IN ('active', 'closed')
+ON CONFLICT DO NOTHING;
+INSERT INTO avisos_votacion (votacion_id, tipo)
+SELECT id, 'resultados' FROM votaciones WHERE status = 'closed'
+ON CONFLICT DO NOTHING;
+`
+
+const migrationCambioVoto = `
+ALTER TABLE votaciones ADD COLUMN IF NOT EXISTS allow_vote_change BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Ballots replaced by a change of vote, with who had cast them and who
+-- replaced them. The contents of secret ballots are not kept: opcion_id,
+-- opciones and is_abstention stay NULL.
+CREATE TABLE IF NOT EXISTS votos_historial (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    votacion_id UUID NOT NULL REFERENCES votaciones(id) ON DELETE CASCADE,
+    parcela_id INTEGER NOT NULL REFERENCES parcelas(id),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    poder_id UUID REFERENCES poderes(id),
+    opcion_id UUID REFERENCES votacion_opciones(id) ON DELETE SET NULL,
+    opciones UUID[],
+    is_abstention BOOLEAN,
+    peso DECIMAL(9,6) NOT NULL,
+    voted_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    cambiado_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    cambiado_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_votos_historial_votacion ON votos_historial(votacion_id, parcela_id);
+`
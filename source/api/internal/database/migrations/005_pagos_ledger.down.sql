@@ -0,0 +1,8 @@
+-- ============================================
+-- ROLLBACK 005: Ledger de Pagos
+-- ============================================
+
+DROP INDEX IF EXISTS idx_pagos_gasto_estado;
+DROP INDEX IF EXISTS idx_pagos_idempotency_key;
+
+ALTER TABLE pagos DROP COLUMN IF EXISTS idempotency_key;
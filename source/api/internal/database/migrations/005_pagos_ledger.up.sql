@@ -0,0 +1,9 @@
+-- ============================================
+-- MIGRACIÓN 005: Ledger de Pagos
+-- Llave de idempotencia para registro de pagos
+-- ============================================
+
+ALTER TABLE pagos ADD COLUMN idempotency_key VARCHAR(255);
+
+CREATE UNIQUE INDEX idx_pagos_idempotency_key ON pagos(idempotency_key) WHERE idempotency_key IS NOT NULL;
+CREATE INDEX idx_pagos_gasto_estado ON pagos(gasto_comun_id, estado);
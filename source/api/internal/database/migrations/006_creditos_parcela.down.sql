@@ -0,0 +1,12 @@
+-- ============================================
+-- ROLLBACK 006: Reversos de Pago y Saldo a Favor
+-- ============================================
+
+DROP TABLE IF EXISTS auditoria_financiera;
+DROP TABLE IF EXISTS creditos_parcela;
+
+DROP TYPE IF EXISTS credito_tipo;
+
+ALTER TABLE pagos DROP COLUMN IF EXISTS motivo_reverso;
+ALTER TABLE pagos DROP COLUMN IF EXISTS reversed_by;
+ALTER TABLE pagos DROP COLUMN IF EXISTS reversed_at;
@@ -0,0 +1,39 @@
+-- ============================================
+-- MIGRACIÓN 006: Reversos de Pago y Saldo a Favor
+-- ============================================
+
+ALTER TABLE pagos ADD COLUMN reversed_at TIMESTAMPTZ;
+ALTER TABLE pagos ADD COLUMN reversed_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE pagos ADD COLUMN motivo_reverso TEXT;
+
+CREATE TYPE credito_tipo AS ENUM ('sobrepago', 'aplicacion', 'reverso', 'reembolso');
+
+-- Saldo a favor por parcela (el saldo es SUM(monto))
+CREATE TABLE creditos_parcela (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    parcela_id INTEGER NOT NULL REFERENCES parcelas(id) ON DELETE CASCADE,
+    monto DECIMAL(12, 2) NOT NULL,
+    tipo credito_tipo NOT NULL,
+    pago_id UUID REFERENCES pagos(id) ON DELETE SET NULL,
+    gasto_comun_id UUID REFERENCES gastos_comunes(id) ON DELETE SET NULL,
+    descripcion TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_creditos_parcela ON creditos_parcela(parcela_id, created_at);
+CREATE INDEX idx_creditos_pago ON creditos_parcela(pago_id);
+
+-- Auditoría de correcciones financieras
+CREATE TABLE auditoria_financiera (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    entidad VARCHAR(50) NOT NULL, -- 'pago', 'credito', 'movimiento', etc.
+    entidad_id VARCHAR(100) NOT NULL,
+    accion VARCHAR(50) NOT NULL,
+    motivo TEXT,
+    datos JSONB,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_auditoria_entidad ON auditoria_financiera(entidad, entidad_id);
@@ -0,0 +1,10 @@
+-- ============================================
+-- ROLLBACK 007: Cuenta Corriente por Parcela
+-- ============================================
+
+DROP TRIGGER IF EXISTS update_saldos_iniciales_updated_at ON saldos_iniciales;
+
+DROP TABLE IF EXISTS saldos_iniciales;
+DROP TABLE IF EXISTS cargos_parcela;
+
+DROP TYPE IF EXISTS cargo_tipo;
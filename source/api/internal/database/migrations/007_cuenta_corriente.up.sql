@@ -0,0 +1,35 @@
+-- ============================================
+-- MIGRACIÓN 007: Cuenta Corriente por Parcela
+-- Cargos adicionales y saldo inicial
+-- ============================================
+
+CREATE TYPE cargo_tipo AS ENUM ('interes', 'multa', 'ajuste');
+
+-- Cargos adicionales (intereses, multas, ajustes manuales)
+CREATE TABLE cargos_parcela (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    parcela_id INTEGER NOT NULL REFERENCES parcelas(id) ON DELETE CASCADE,
+    gasto_comun_id UUID REFERENCES gastos_comunes(id) ON DELETE SET NULL,
+    tipo cargo_tipo NOT NULL,
+    monto DECIMAL(12, 2) NOT NULL, -- negativo = abono/condonación
+    fecha DATE NOT NULL,
+    descripcion TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_cargos_parcela ON cargos_parcela(parcela_id, fecha);
+
+-- Saldo inicial para historia importada
+CREATE TABLE saldos_iniciales (
+    parcela_id INTEGER PRIMARY KEY REFERENCES parcelas(id) ON DELETE CASCADE,
+    monto DECIMAL(12, 2) NOT NULL,
+    fecha DATE NOT NULL,
+    descripcion TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER update_saldos_iniciales_updated_at BEFORE UPDATE ON saldos_iniciales
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
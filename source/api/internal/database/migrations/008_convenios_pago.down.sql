@@ -0,0 +1,15 @@
+-- ============================================
+-- ROLLBACK 008: Convenios de Pago
+-- ============================================
+
+-- Los gastos en convenio vuelven a quedar morosos
+UPDATE gastos_comunes SET status = 'overdue' WHERE status = 'convenio';
+
+DROP TABLE IF EXISTS convenio_cuotas;
+DROP TABLE IF EXISTS convenio_gastos;
+DROP TABLE IF EXISTS convenios_pago;
+
+DROP TYPE IF EXISTS cuota_estado;
+DROP TYPE IF EXISTS convenio_estado;
+
+-- PostgreSQL no permite quitar valores de un ENUM; 'convenio' queda en pago_status sin uso
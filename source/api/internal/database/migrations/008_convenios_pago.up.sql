@@ -0,0 +1,56 @@
+-- ============================================
+-- MIGRACIÓN 008: Convenios de Pago
+-- Repactación de deuda morosa en cuotas
+-- ============================================
+
+ALTER TYPE pago_status ADD VALUE IF NOT EXISTS 'convenio';
+
+CREATE TYPE convenio_estado AS ENUM ('activo', 'cumplido', 'roto', 'anulado');
+CREATE TYPE cuota_estado AS ENUM ('pendiente', 'pagada', 'vencida');
+
+-- Convenios de pago
+CREATE TABLE convenios_pago (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    parcela_id INTEGER NOT NULL REFERENCES parcelas(id) ON DELETE CASCADE,
+    estado convenio_estado NOT NULL DEFAULT 'activo',
+    monto_total DECIMAL(12, 2) NOT NULL,
+    num_cuotas INTEGER NOT NULL CHECK (num_cuotas > 0),
+    max_cuotas_impagas INTEGER NOT NULL DEFAULT 2 CHECK (max_cuotas_impagas > 0),
+    congelar_intereses BOOLEAN NOT NULL DEFAULT false,
+    intereses_congelados DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    observaciones TEXT,
+    motivo_termino TEXT,
+    terminado_at TIMESTAMPTZ,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Gastos incluidos en el convenio
+CREATE TABLE convenio_gastos (
+    convenio_id UUID NOT NULL REFERENCES convenios_pago(id) ON DELETE CASCADE,
+    gasto_comun_id UUID NOT NULL REFERENCES gastos_comunes(id) ON DELETE CASCADE,
+    monto_pendiente DECIMAL(12, 2) NOT NULL,
+    monto_pagado_inicial DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    PRIMARY KEY (convenio_id, gasto_comun_id)
+);
+
+-- Cuotas del convenio
+CREATE TABLE convenio_cuotas (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    convenio_id UUID NOT NULL REFERENCES convenios_pago(id) ON DELETE CASCADE,
+    numero INTEGER NOT NULL,
+    monto DECIMAL(12, 2) NOT NULL,
+    fecha_vencimiento DATE NOT NULL,
+    periodo_id UUID REFERENCES periodos_gasto(id) ON DELETE SET NULL,
+    estado cuota_estado NOT NULL DEFAULT 'pendiente',
+    pagada_at TIMESTAMPTZ,
+    UNIQUE(convenio_id, numero)
+);
+
+CREATE INDEX idx_convenios_parcela ON convenios_pago(parcela_id, estado);
+CREATE INDEX idx_convenio_gastos_gasto ON convenio_gastos(gasto_comun_id);
+CREATE INDEX idx_convenio_cuotas_vencimiento ON convenio_cuotas(fecha_vencimiento) WHERE estado <> 'pagada';
+
+CREATE TRIGGER update_convenios_pago_updated_at BEFORE UPDATE ON convenios_pago
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,6 @@
+-- ============================================
+-- ROLLBACK 009: Recibos de Pago
+-- ============================================
+
+DROP TABLE IF EXISTS recibos;
+DROP TABLE IF EXISTS correlativos;
@@ -0,0 +1,24 @@
+-- ============================================
+-- MIGRACIÓN 009: Recibos de Pago
+-- Numeración correlativa de documentos
+-- ============================================
+
+-- Contadores correlativos sin saltos (recibos, comprobantes)
+CREATE TABLE correlativos (
+    tipo VARCHAR(50) PRIMARY KEY,
+    ultimo BIGINT NOT NULL DEFAULT 0
+);
+
+-- Recibos emitidos por pagos aprobados
+CREATE TABLE recibos (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    numero BIGINT NOT NULL UNIQUE,
+    pago_id UUID NOT NULL UNIQUE REFERENCES pagos(id) ON DELETE RESTRICT,
+    parcela_id INTEGER NOT NULL REFERENCES parcelas(id),
+    monto DECIMAL(12, 2) NOT NULL,
+    emitido_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    anulado_at TIMESTAMPTZ,
+    motivo_anulacion TEXT
+);
+
+CREATE INDEX idx_recibos_parcela ON recibos(parcela_id);
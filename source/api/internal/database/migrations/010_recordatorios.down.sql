@@ -0,0 +1,6 @@
+-- ============================================
+-- ROLLBACK 010: Recordatorios de Gastos Comunes
+-- ============================================
+
+DROP TABLE IF EXISTS preferencias_notificacion;
+DROP TABLE IF EXISTS recordatorios_gasto;
@@ -0,0 +1,27 @@
+-- ============================================
+-- MIGRACIÓN 010: Recordatorios de Gastos Comunes
+-- Registro de avisos enviados y preferencias
+-- ============================================
+
+-- Un registro por recordatorio enviado; la clave única evita duplicados
+CREATE TABLE recordatorios_gasto (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    gasto_comun_id UUID NOT NULL REFERENCES gastos_comunes(id) ON DELETE CASCADE,
+    tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('emision', 'previo', 'vencido')),
+    dias INTEGER NOT NULL DEFAULT 0,
+    notificaciones INTEGER NOT NULL DEFAULT 0,
+    emails INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (gasto_comun_id, tipo, dias)
+);
+
+-- Preferencias por usuario; sin registro se envía todo
+CREATE TABLE preferencias_notificacion (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    email_gastos BOOLEAN NOT NULL DEFAULT TRUE,
+    app_gastos BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER update_preferencias_notificacion_updated_at BEFORE UPDATE ON preferencias_notificacion
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
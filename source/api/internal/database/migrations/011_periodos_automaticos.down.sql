@@ -0,0 +1,10 @@
+-- ============================================
+-- ROLLBACK 011: Periodos Automáticos
+-- ============================================
+
+DROP TABLE IF EXISTS config_periodos;
+
+ALTER TABLE periodos_gasto DROP COLUMN IF EXISTS aprobado_at;
+ALTER TABLE periodos_gasto DROP COLUMN IF EXISTS aprobado_by;
+ALTER TABLE periodos_gasto DROP COLUMN IF EXISTS generado_automaticamente;
+ALTER TABLE periodos_gasto DROP COLUMN IF EXISTS estado;
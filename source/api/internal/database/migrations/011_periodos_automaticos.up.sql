@@ -0,0 +1,29 @@
+-- ============================================
+-- MIGRACIÓN 011: Periodos Automáticos
+-- Generación mensual de periodos y borradores
+-- ============================================
+
+ALTER TABLE periodos_gasto ADD COLUMN estado VARCHAR(20) NOT NULL DEFAULT 'abierto'
+    CHECK (estado IN ('borrador', 'abierto'));
+ALTER TABLE periodos_gasto ADD COLUMN generado_automaticamente BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE periodos_gasto ADD COLUMN aprobado_by UUID REFERENCES users(id);
+ALTER TABLE periodos_gasto ADD COLUMN aprobado_at TIMESTAMPTZ;
+
+-- Configuración de recurrencia (una sola fila)
+CREATE TABLE config_periodos (
+    id INTEGER PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    activa BOOLEAN NOT NULL DEFAULT FALSE,
+    modo VARCHAR(20) NOT NULL DEFAULT 'monto' CHECK (modo IN ('monto', 'presupuesto')),
+    monto_base DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    presupuesto_mensual DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    dia_vencimiento INTEGER NOT NULL DEFAULT 10 CHECK (dia_vencimiento BETWEEN 1 AND 31),
+    ajuste_dia_habil VARCHAR(20) NOT NULL DEFAULT 'siguiente' CHECK (ajuste_dia_habil IN ('ninguno', 'siguiente', 'anterior')),
+    descripcion_plantilla TEXT NOT NULL DEFAULT 'Gasto común {periodo}',
+    dias_anticipacion INTEGER NOT NULL DEFAULT 5 CHECK (dias_anticipacion BETWEEN 0 AND 28),
+    requiere_aprobacion BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_by UUID REFERENCES users(id),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER update_config_periodos_updated_at BEFORE UPDATE ON config_periodos
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
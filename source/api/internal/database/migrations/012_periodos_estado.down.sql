@@ -0,0 +1,11 @@
+-- ============================================
+-- ROLLBACK 012: Estado de Periodos
+-- ============================================
+
+UPDATE periodos_gasto SET estado = 'abierto' WHERE estado = 'cerrado';
+
+ALTER TABLE periodos_gasto DROP COLUMN IF EXISTS cerrado_at;
+ALTER TABLE periodos_gasto DROP COLUMN IF EXISTS cerrado_by;
+ALTER TABLE periodos_gasto DROP CONSTRAINT IF EXISTS periodos_gasto_estado_check;
+ALTER TABLE periodos_gasto ADD CONSTRAINT periodos_gasto_estado_check
+    CHECK (estado IN ('borrador', 'abierto'));
@@ -0,0 +1,10 @@
+-- ============================================
+-- MIGRACIÓN 012: Estado de Periodos
+-- Ciclo borrador / abierto / cerrado
+-- ============================================
+
+ALTER TABLE periodos_gasto DROP CONSTRAINT IF EXISTS periodos_gasto_estado_check;
+ALTER TABLE periodos_gasto ADD CONSTRAINT periodos_gasto_estado_check
+    CHECK (estado IN ('borrador', 'abierto', 'cerrado'));
+ALTER TABLE periodos_gasto ADD COLUMN cerrado_by UUID REFERENCES users(id);
+ALTER TABLE periodos_gasto ADD COLUMN cerrado_at TIMESTAMPTZ;
@@ -0,0 +1,7 @@
+-- ============================================
+-- ROLLBACK 013: Importación de Pagos Bancarios
+-- ============================================
+
+DROP TABLE IF EXISTS importaciones_banco_filas;
+DROP TABLE IF EXISTS importaciones_banco;
+DROP TABLE IF EXISTS formatos_importacion;
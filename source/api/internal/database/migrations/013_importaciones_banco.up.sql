@@ -0,0 +1,67 @@
+-- ============================================
+-- MIGRACIÓN 013: Importación de Pagos Bancarios
+-- Formatos CSV, staging y confirmación de pagos
+-- ============================================
+
+CREATE TABLE formatos_importacion (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    nombre VARCHAR(100) NOT NULL UNIQUE,
+    separador VARCHAR(1) NOT NULL DEFAULT ';',
+    tiene_encabezado BOOLEAN NOT NULL DEFAULT TRUE,
+    filas_omitir INTEGER NOT NULL DEFAULT 0 CHECK (filas_omitir >= 0),
+    columna_fecha VARCHAR(100) NOT NULL,
+    columna_monto VARCHAR(100) NOT NULL,
+    columna_rut VARCHAR(100) NOT NULL DEFAULT '',
+    columna_nombre VARCHAR(100) NOT NULL DEFAULT '',
+    columna_descripcion VARCHAR(100) NOT NULL DEFAULT '',
+    columna_referencia VARCHAR(100) NOT NULL DEFAULT '',
+    formato_fecha VARCHAR(20) NOT NULL DEFAULT 'DD/MM/YYYY',
+    separador_decimal VARCHAR(1) NOT NULL DEFAULT ',' CHECK (separador_decimal IN (',', '.')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE importaciones_banco (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    formato_id UUID REFERENCES formatos_importacion(id) ON DELETE SET NULL,
+    archivo_nombre VARCHAR(255) NOT NULL,
+    estado VARCHAR(20) NOT NULL DEFAULT 'staging' CHECK (estado IN ('staging', 'confirmada', 'descartada')),
+    created_by UUID REFERENCES users(id),
+    confirmada_by UUID REFERENCES users(id),
+    confirmada_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE importaciones_banco_filas (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    importacion_id UUID NOT NULL REFERENCES importaciones_banco(id) ON DELETE CASCADE,
+    linea INTEGER NOT NULL,
+    fecha DATE,
+    monto DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    rut VARCHAR(20) NOT NULL DEFAULT '',
+    nombre VARCHAR(255) NOT NULL DEFAULT '',
+    descripcion TEXT NOT NULL DEFAULT '',
+    referencia VARCHAR(255) NOT NULL DEFAULT '',
+    huella VARCHAR(64) NOT NULL,
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente'
+        CHECK (estado IN ('pendiente', 'ignorada', 'duplicada', 'error', 'importada')),
+    parcela_id INTEGER REFERENCES parcelas(id),
+    gasto_comun_id UUID REFERENCES gastos_comunes(id) ON DELETE SET NULL,
+    match VARCHAR(20) NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    pago_id UUID REFERENCES pagos(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_importaciones_filas_importacion ON importaciones_banco_filas(importacion_id);
+CREATE INDEX idx_importaciones_filas_rut ON importaciones_banco_filas(rut) WHERE estado = 'importada';
+-- Un movimiento bancario se importa una sola vez
+CREATE UNIQUE INDEX idx_importaciones_filas_huella ON importaciones_banco_filas(huella) WHERE estado = 'importada';
+
+CREATE TRIGGER update_formatos_importacion_updated_at BEFORE UPDATE ON formatos_importacion
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_importaciones_banco_updated_at BEFORE UPDATE ON importaciones_banco
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_importaciones_banco_filas_updated_at BEFORE UPDATE ON importaciones_banco_filas
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
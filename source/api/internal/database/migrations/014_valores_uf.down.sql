@@ -0,0 +1,16 @@
+-- ============================================
+-- ROLLBACK 014: Valores UF
+-- ============================================
+
+UPDATE config_periodos SET modo = 'monto' WHERE modo = 'uf';
+ALTER TABLE config_periodos DROP CONSTRAINT IF EXISTS config_periodos_modo_check;
+ALTER TABLE config_periodos ADD CONSTRAINT config_periodos_modo_check
+    CHECK (modo IN ('monto', 'presupuesto'));
+ALTER TABLE config_periodos DROP COLUMN IF EXISTS monto_base_uf;
+
+ALTER TABLE periodos_gasto DROP COLUMN IF EXISTS fecha_uf;
+ALTER TABLE periodos_gasto DROP COLUMN IF EXISTS valor_uf;
+ALTER TABLE periodos_gasto DROP COLUMN IF EXISTS monto_base_uf;
+ALTER TABLE periodos_gasto DROP COLUMN IF EXISTS moneda;
+
+DROP TABLE IF EXISTS valores_uf;
@@ -0,0 +1,27 @@
+-- ============================================
+-- MIGRACIÓN 014: Valores UF
+-- Tabla local de UF y periodos definidos en UF
+-- ============================================
+
+CREATE TABLE valores_uf (
+    fecha DATE PRIMARY KEY,
+    valor DECIMAL(10, 2) NOT NULL CHECK (valor > 0),
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER update_valores_uf_updated_at BEFORE UPDATE ON valores_uf
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- monto_base sigue en CLP; monto_base_uf y valor_uf registran la conversión
+ALTER TABLE periodos_gasto ADD COLUMN moneda VARCHAR(3) NOT NULL DEFAULT 'CLP'
+    CHECK (moneda IN ('CLP', 'UF'));
+ALTER TABLE periodos_gasto ADD COLUMN monto_base_uf DECIMAL(10, 4);
+ALTER TABLE periodos_gasto ADD COLUMN valor_uf DECIMAL(10, 2);
+ALTER TABLE periodos_gasto ADD COLUMN fecha_uf DATE;
+
+ALTER TABLE config_periodos ADD COLUMN monto_base_uf DECIMAL(10, 4) NOT NULL DEFAULT 0;
+ALTER TABLE config_periodos DROP CONSTRAINT IF EXISTS config_periodos_modo_check;
+ALTER TABLE config_periodos ADD CONSTRAINT config_periodos_modo_check
+    CHECK (modo IN ('monto', 'presupuesto', 'uf'));
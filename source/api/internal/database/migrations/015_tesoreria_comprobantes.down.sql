@@ -0,0 +1,20 @@
+-- ============================================
+-- ROLLBACK 015: Comprobantes de tesorería
+-- ============================================
+
+DROP TABLE IF EXISTS movimientos_adjuntos;
+
+-- Los asientos de reverso solo existen por la anulación
+DELETE FROM movimientos_tesoreria WHERE reverso_de IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_tesoreria_category;
+DROP INDEX IF EXISTS idx_tesoreria_reverso;
+DROP INDEX IF EXISTS idx_tesoreria_comprobante;
+ALTER TABLE movimientos_tesoreria DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE movimientos_tesoreria DROP COLUMN IF EXISTS reverso_de;
+ALTER TABLE movimientos_tesoreria DROP COLUMN IF EXISTS motivo_anulacion;
+ALTER TABLE movimientos_tesoreria DROP COLUMN IF EXISTS anulado_by;
+ALTER TABLE movimientos_tesoreria DROP COLUMN IF EXISTS anulado_at;
+ALTER TABLE movimientos_tesoreria DROP COLUMN IF EXISTS comprobante;
+
+DELETE FROM correlativos WHERE tipo = 'comprobante_tesoreria';
@@ -0,0 +1,43 @@
+-- ============================================
+-- MIGRACIÓN 015: Comprobantes de tesorería
+-- Numeración, corrección, anulación por reverso y adjuntos
+-- ============================================
+
+ALTER TABLE movimientos_tesoreria ADD COLUMN comprobante BIGINT;
+ALTER TABLE movimientos_tesoreria ADD COLUMN anulado_at TIMESTAMPTZ;
+ALTER TABLE movimientos_tesoreria ADD COLUMN anulado_by UUID REFERENCES users(id);
+ALTER TABLE movimientos_tesoreria ADD COLUMN motivo_anulacion TEXT;
+-- Asiento de reverso: mismo tipo, monto con signo contrario
+ALTER TABLE movimientos_tesoreria ADD COLUMN reverso_de UUID REFERENCES movimientos_tesoreria(id);
+ALTER TABLE movimientos_tesoreria ADD COLUMN updated_by UUID REFERENCES users(id);
+
+-- Los movimientos existentes se numeran por fecha
+WITH numerados AS (
+    SELECT id, ROW_NUMBER() OVER (ORDER BY date, created_at, id) AS numero
+    FROM movimientos_tesoreria
+)
+UPDATE movimientos_tesoreria m SET comprobante = n.numero
+FROM numerados n WHERE m.id = n.id;
+
+INSERT INTO correlativos (tipo, ultimo)
+SELECT 'comprobante_tesoreria', COALESCE(MAX(comprobante), 0) FROM movimientos_tesoreria
+ON CONFLICT (tipo) DO UPDATE SET ultimo = GREATEST(correlativos.ultimo, EXCLUDED.ultimo);
+
+ALTER TABLE movimientos_tesoreria ALTER COLUMN comprobante SET NOT NULL;
+CREATE UNIQUE INDEX idx_tesoreria_comprobante ON movimientos_tesoreria(comprobante);
+CREATE UNIQUE INDEX idx_tesoreria_reverso ON movimientos_tesoreria(reverso_de) WHERE reverso_de IS NOT NULL;
+CREATE INDEX idx_tesoreria_category ON movimientos_tesoreria(category);
+
+-- Boletas y facturas escaneadas
+CREATE TABLE movimientos_adjuntos (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    movimiento_id UUID NOT NULL REFERENCES movimientos_tesoreria(id),
+    nombre_archivo VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    tamano INTEGER NOT NULL,
+    contenido BYTEA NOT NULL,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_movimientos_adjuntos_movimiento ON movimientos_adjuntos(movimiento_id);
@@ -0,0 +1,10 @@
+-- ============================================
+-- ROLLBACK 016: Presupuesto anual y catálogo de categorías
+-- ============================================
+
+DROP TABLE IF EXISTS presupuesto_items;
+DROP TABLE IF EXISTS presupuestos;
+
+-- Los movimientos conservan el código como texto libre
+ALTER TABLE movimientos_tesoreria DROP CONSTRAINT IF EXISTS movimientos_tesoreria_category_fkey;
+DROP TABLE IF EXISTS categorias_tesoreria;
@@ -0,0 +1,49 @@
+-- ============================================
+-- MIGRACIÓN 016: Presupuesto anual y catálogo de categorías
+-- ============================================
+
+-- Catálogo de categorías de tesorería; los movimientos guardan el código
+CREATE TABLE IF NOT EXISTS categorias_tesoreria (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    codigo VARCHAR(100) NOT NULL UNIQUE,
+    nombre VARCHAR(150) NOT NULL,
+    tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('ingreso', 'egreso')),
+    activa BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Las categorías de texto libre ya usadas forman el catálogo inicial
+UPDATE movimientos_tesoreria SET category = NULL WHERE TRIM(category) = '';
+INSERT INTO categorias_tesoreria (codigo, nombre, tipo)
+SELECT category, INITCAP(REPLACE(category, '_', ' ')),
+       CASE WHEN BOOL_OR(type = 'egreso') THEN 'egreso' ELSE 'ingreso' END
+FROM movimientos_tesoreria
+WHERE category IS NOT NULL
+GROUP BY category
+ON CONFLICT (codigo) DO NOTHING;
+
+ALTER TABLE movimientos_tesoreria DROP CONSTRAINT IF EXISTS movimientos_tesoreria_category_fkey;
+ALTER TABLE movimientos_tesoreria ADD CONSTRAINT movimientos_tesoreria_category_fkey
+    FOREIGN KEY (category) REFERENCES categorias_tesoreria(codigo);
+
+CREATE TABLE IF NOT EXISTS presupuestos (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    year INTEGER NOT NULL UNIQUE,
+    descripcion TEXT,
+    umbral_alerta DECIMAL(5,2) NOT NULL DEFAULT 90 CHECK (umbral_alerta > 0),
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- alertado_at: la directiva ya fue notificada de que se superó el umbral
+CREATE TABLE IF NOT EXISTS presupuesto_items (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    presupuesto_id UUID NOT NULL REFERENCES presupuestos(id) ON DELETE CASCADE,
+    categoria VARCHAR(100) NOT NULL REFERENCES categorias_tesoreria(codigo),
+    monto_anual DECIMAL(12,2) NOT NULL CHECK (monto_anual >= 0),
+    umbral_alerta DECIMAL(5,2) CHECK (umbral_alerta > 0),
+    alertado_at TIMESTAMPTZ,
+    UNIQUE (presupuesto_id, categoria)
+);
@@ -0,0 +1,18 @@
+-- ============================================
+-- ROLLBACK 017: Cuentas de tesorería y fondo de reserva
+-- ============================================
+
+DROP TABLE IF EXISTS config_tesoreria;
+
+-- Las transferencias solo existen entre cuentas
+DELETE FROM movimientos_tesoreria WHERE transferencia_id IS NOT NULL AND reverso_de IS NOT NULL;
+DELETE FROM movimientos_tesoreria WHERE transferencia_id IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_tesoreria_pago;
+DROP INDEX IF EXISTS idx_tesoreria_transferencia;
+DROP INDEX IF EXISTS idx_tesoreria_cuenta;
+ALTER TABLE movimientos_tesoreria DROP COLUMN IF EXISTS pago_id;
+ALTER TABLE movimientos_tesoreria DROP COLUMN IF EXISTS transferencia_id;
+ALTER TABLE movimientos_tesoreria DROP COLUMN IF EXISTS cuenta_id;
+
+DROP TABLE IF EXISTS cuentas_tesoreria;
@@ -0,0 +1,45 @@
+-- ============================================
+-- MIGRACIÓN 017: Cuentas de tesorería y fondo de reserva
+-- ============================================
+
+-- Cuentas de tesorería; los movimientos existentes quedan en la principal
+CREATE TABLE IF NOT EXISTS cuentas_tesoreria (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    codigo VARCHAR(50) NOT NULL UNIQUE,
+    nombre VARCHAR(150) NOT NULL,
+    tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('corriente', 'caja', 'fondo_reserva', 'otra')),
+    principal BOOLEAN NOT NULL DEFAULT FALSE,
+    activa BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cuentas_tesoreria_principal ON cuentas_tesoreria(principal) WHERE principal;
+
+INSERT INTO cuentas_tesoreria (codigo, nombre, tipo, principal)
+SELECT 'cuenta_corriente', 'Cuenta corriente', 'corriente', TRUE
+WHERE NOT EXISTS (SELECT 1 FROM cuentas_tesoreria WHERE principal);
+INSERT INTO cuentas_tesoreria (codigo, nombre, tipo)
+VALUES ('caja', 'Caja chica', 'caja'), ('fondo_reserva', 'Fondo de reserva', 'fondo_reserva')
+ON CONFLICT (codigo) DO NOTHING;
+
+ALTER TABLE movimientos_tesoreria ADD COLUMN IF NOT EXISTS cuenta_id UUID REFERENCES cuentas_tesoreria(id);
+UPDATE movimientos_tesoreria SET cuenta_id = (SELECT id FROM cuentas_tesoreria WHERE principal)
+WHERE cuenta_id IS NULL;
+ALTER TABLE movimientos_tesoreria ALTER COLUMN cuenta_id SET NOT NULL;
+
+-- Los dos asientos de una transferencia comparten transferencia_id; pago_id
+-- marca el aporte al fondo de reserva de un pago
+ALTER TABLE movimientos_tesoreria ADD COLUMN IF NOT EXISTS transferencia_id UUID;
+ALTER TABLE movimientos_tesoreria ADD COLUMN IF NOT EXISTS pago_id UUID REFERENCES pagos(id);
+CREATE INDEX IF NOT EXISTS idx_tesoreria_cuenta ON movimientos_tesoreria(cuenta_id);
+CREATE INDEX IF NOT EXISTS idx_tesoreria_transferencia ON movimientos_tesoreria(transferencia_id) WHERE transferencia_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_tesoreria_pago ON movimientos_tesoreria(pago_id) WHERE pago_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS config_tesoreria (
+    id INTEGER PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    porcentaje_fondo_reserva DECIMAL(5,2) NOT NULL DEFAULT 0 CHECK (porcentaje_fondo_reserva BETWEEN 0 AND 100),
+    cuenta_reserva_id UUID REFERENCES cuentas_tesoreria(id),
+    updated_by UUID REFERENCES users(id),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
@@ -0,0 +1,10 @@
+-- ============================================
+-- ROLLBACK 018: Contabilización de pagos en tesorería
+-- ============================================
+
+-- Los movimientos ya contabilizados quedan en tesorería como ingresos normales
+DROP TABLE IF EXISTS pagos_tesoreria;
+ALTER TABLE creditos_parcela DROP COLUMN IF EXISTS movimiento_id;
+
+ALTER TABLE config_tesoreria DROP CONSTRAINT IF EXISTS config_tesoreria_contabilizacion_pagos_check;
+ALTER TABLE config_tesoreria DROP COLUMN IF EXISTS contabilizacion_pagos;
@@ -0,0 +1,33 @@
+-- ============================================
+-- MIGRACIÓN 018: Contabilización de pagos en tesorería
+-- ============================================
+
+-- Forma de contabilizar los pagos aprobados en tesorería
+ALTER TABLE config_tesoreria ADD COLUMN IF NOT EXISTS contabilizacion_pagos VARCHAR(20) NOT NULL DEFAULT 'individual';
+ALTER TABLE config_tesoreria DROP CONSTRAINT IF EXISTS config_tesoreria_contabilizacion_pagos_check;
+ALTER TABLE config_tesoreria ADD CONSTRAINT config_tesoreria_contabilizacion_pagos_check
+    CHECK (contabilizacion_pagos IN ('desactivada', 'individual', 'diaria'));
+
+INSERT INTO categorias_tesoreria (codigo, nombre, tipo)
+VALUES ('gastos_comunes', 'Gastos comunes', 'ingreso'), ('reverso_pagos', 'Reverso de pagos', 'egreso'),
+       ('reembolso_creditos', 'Reembolso de saldos a favor', 'egreso')
+ON CONFLICT (codigo) DO NOTHING;
+
+-- Una fila por pago contabilizado (ingreso) y por reverso (reverso).
+-- movimiento_id queda NULL mientras el pago espera la contabilización diaria.
+CREATE TABLE IF NOT EXISTS pagos_tesoreria (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    pago_id UUID NOT NULL REFERENCES pagos(id),
+    tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('ingreso', 'reverso')),
+    monto DECIMAL(12,2) NOT NULL,
+    fecha DATE NOT NULL,
+    movimiento_id UUID REFERENCES movimientos_tesoreria(id),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (pago_id, tipo)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pagos_tesoreria_movimiento ON pagos_tesoreria(movimiento_id);
+CREATE INDEX IF NOT EXISTS idx_pagos_tesoreria_pendientes ON pagos_tesoreria(fecha) WHERE movimiento_id IS NULL;
+
+-- Egreso que contabiliza un reembolso de saldo a favor a la parcela
+ALTER TABLE creditos_parcela ADD COLUMN IF NOT EXISTS movimiento_id UUID REFERENCES movimientos_tesoreria(id);
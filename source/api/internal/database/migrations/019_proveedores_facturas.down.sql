@@ -0,0 +1,10 @@
+-- ============================================
+-- ROLLBACK 019: Proveedores, facturas y cadena de aprobación
+-- ============================================
+
+-- Los egresos de facturas pagadas quedan en tesorería
+DROP TABLE IF EXISTS reglas_aprobacion_facturas;
+DROP TABLE IF EXISTS facturas_adjuntos;
+DROP TABLE IF EXISTS factura_aprobaciones;
+DROP TABLE IF EXISTS facturas;
+DROP TABLE IF EXISTS proveedores;
@@ -0,0 +1,79 @@
+-- ============================================
+-- MIGRACIÓN 019: Proveedores, facturas y cadena de aprobación
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS proveedores (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    nombre VARCHAR(255) NOT NULL,
+    rut VARCHAR(20) NOT NULL UNIQUE,
+    email VARCHAR(255),
+    telefono VARCHAR(50),
+    banco VARCHAR(100),
+    tipo_cuenta VARCHAR(50),
+    numero_cuenta VARCHAR(50),
+    categoria VARCHAR(100) REFERENCES categorias_tesoreria(codigo) ON UPDATE CASCADE,
+    notas TEXT,
+    activo BOOLEAN NOT NULL DEFAULT true,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS facturas (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    proveedor_id UUID NOT NULL REFERENCES proveedores(id),
+    numero VARCHAR(50) NOT NULL,
+    descripcion TEXT NOT NULL,
+    categoria VARCHAR(100) REFERENCES categorias_tesoreria(codigo) ON UPDATE CASCADE,
+    monto DECIMAL(12,2) NOT NULL CHECK (monto > 0),
+    fecha_emision DATE NOT NULL,
+    fecha_vencimiento DATE,
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente'
+        CHECK (estado IN ('pendiente', 'aprobada', 'rechazada', 'pagada', 'anulada')),
+    aprobaciones_requeridas INTEGER NOT NULL DEFAULT 1,
+    movimiento_id UUID REFERENCES movimientos_tesoreria(id),
+    pagada_at TIMESTAMPTZ,
+    pagada_by UUID REFERENCES users(id),
+    motivo_anulacion TEXT,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (proveedor_id, numero)
+);
+
+CREATE INDEX IF NOT EXISTS idx_facturas_estado ON facturas(estado);
+CREATE INDEX IF NOT EXISTS idx_facturas_movimiento ON facturas(movimiento_id);
+
+-- Una firma por miembro de la directiva y factura; aprobada = false la rechaza
+CREATE TABLE IF NOT EXISTS factura_aprobaciones (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    factura_id UUID NOT NULL REFERENCES facturas(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id),
+    aprobada BOOLEAN NOT NULL,
+    comentario TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (factura_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS facturas_adjuntos (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    factura_id UUID NOT NULL REFERENCES facturas(id),
+    nombre_archivo VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    tamano INTEGER NOT NULL,
+    contenido BYTEA NOT NULL,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_facturas_adjuntos_factura ON facturas_adjuntos(factura_id);
+
+-- Cadena de aprobación: facturas desde monto_desde requieren esa cantidad de firmas
+CREATE TABLE IF NOT EXISTS reglas_aprobacion_facturas (
+    monto_desde DECIMAL(12,2) PRIMARY KEY CHECK (monto_desde >= 0),
+    aprobaciones INTEGER NOT NULL CHECK (aprobaciones BETWEEN 1 AND 10)
+);
+
+INSERT INTO reglas_aprobacion_facturas (monto_desde, aprobaciones)
+VALUES (0, 1), (500000, 2)
+ON CONFLICT (monto_desde) DO NOTHING;
@@ -0,0 +1,7 @@
+-- ============================================
+-- ROLLBACK 020: Conciliación bancaria
+-- ============================================
+
+DROP TABLE IF EXISTS conciliaciones_bancarias;
+DROP TABLE IF EXISTS cartola_lineas;
+DROP TABLE IF EXISTS cartolas_bancarias;
@@ -0,0 +1,63 @@
+-- ============================================
+-- MIGRACIÓN 020: Conciliación bancaria
+-- ============================================
+
+-- Cartolas bancarias cargadas por cuenta, en CSV u OFX
+CREATE TABLE IF NOT EXISTS cartolas_bancarias (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    cuenta_id UUID NOT NULL REFERENCES cuentas_tesoreria(id),
+    archivo_nombre VARCHAR(255) NOT NULL,
+    formato VARCHAR(10) NOT NULL CHECK (formato IN ('csv', 'ofx')),
+    formato_id UUID REFERENCES formatos_importacion(id) ON DELETE SET NULL,
+    desde DATE,
+    hasta DATE,
+    saldo_final DECIMAL(12,2),
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_cartolas_bancarias_cuenta ON cartolas_bancarias(cuenta_id);
+
+-- Líneas de la cartola: monto positivo para abonos y negativo para cargos.
+-- La huella identifica el movimiento del banco para no cargar dos veces
+-- cartolas que se traslapan; movimiento_id es el asiento conciliado.
+CREATE TABLE IF NOT EXISTS cartola_lineas (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    cartola_id UUID NOT NULL REFERENCES cartolas_bancarias(id) ON DELETE CASCADE,
+    cuenta_id UUID NOT NULL REFERENCES cuentas_tesoreria(id),
+    linea INTEGER NOT NULL,
+    fecha DATE NOT NULL,
+    monto DECIMAL(12,2) NOT NULL CHECK (monto <> 0),
+    descripcion TEXT,
+    referencia VARCHAR(255),
+    huella VARCHAR(64) NOT NULL,
+    movimiento_id UUID REFERENCES movimientos_tesoreria(id),
+    match VARCHAR(20) CHECK (match IN ('referencia', 'monto_fecha', 'manual')),
+    conciliada_at TIMESTAMPTZ,
+    conciliada_by UUID REFERENCES users(id),
+    UNIQUE (cuenta_id, huella)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cartola_lineas_cartola ON cartola_lineas(cartola_id);
+CREATE INDEX IF NOT EXISTS idx_cartola_lineas_fecha ON cartola_lineas(cuenta_id, fecha);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cartola_lineas_movimiento ON cartola_lineas(movimiento_id) WHERE movimiento_id IS NOT NULL;
+
+-- Cierre de período: saldos del banco y del libro a la fecha de corte, lo que
+-- quedó sin conciliar y la diferencia que esas partidas no explican
+CREATE TABLE IF NOT EXISTS conciliaciones_bancarias (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    cuenta_id UUID NOT NULL REFERENCES cuentas_tesoreria(id),
+    fecha_corte DATE NOT NULL,
+    saldo_banco DECIMAL(12,2) NOT NULL,
+    saldo_libro DECIMAL(12,2) NOT NULL,
+    diferencia DECIMAL(12,2) NOT NULL,
+    lineas_pendientes INTEGER NOT NULL,
+    monto_lineas_pendientes DECIMAL(12,2) NOT NULL,
+    movimientos_pendientes INTEGER NOT NULL,
+    monto_movimientos_pendientes DECIMAL(12,2) NOT NULL,
+    diferencia_no_explicada DECIMAL(12,2) NOT NULL,
+    observaciones TEXT,
+    firmado_by UUID NOT NULL REFERENCES users(id),
+    firmado_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (cuenta_id, fecha_corte)
+);
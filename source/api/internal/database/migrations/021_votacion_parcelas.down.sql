@@ -0,0 +1,12 @@
+-- ============================================
+-- ROLLBACK 021: Votación por parcela con coeficiente
+-- ============================================
+
+-- Falla si un mismo usuario quedó con más de un voto en una votación
+DROP INDEX IF EXISTS idx_votos_parcela;
+ALTER TABLE votos ADD CONSTRAINT votos_votacion_id_user_id_key UNIQUE (votacion_id, user_id);
+ALTER TABLE votos DROP COLUMN IF EXISTS peso;
+ALTER TABLE votos DROP COLUMN IF EXISTS parcela_id;
+ALTER TABLE votaciones DROP COLUMN IF EXISTS ponderada;
+ALTER TABLE parcelas DROP CONSTRAINT IF EXISTS parcelas_coeficiente_check;
+ALTER TABLE parcelas DROP COLUMN IF EXISTS coeficiente;
@@ -0,0 +1,33 @@
+-- ============================================
+-- MIGRACIÓN 021: Votación por parcela con coeficiente
+-- ============================================
+
+-- Peso de cada parcela en las votaciones; 1 equivale a una parcela, un voto
+ALTER TABLE parcelas ADD COLUMN IF NOT EXISTS coeficiente DECIMAL(9,6) NOT NULL DEFAULT 1;
+ALTER TABLE parcelas DROP CONSTRAINT IF EXISTS parcelas_coeficiente_check;
+ALTER TABLE parcelas ADD CONSTRAINT parcelas_coeficiente_check CHECK (coeficiente > 0);
+
+-- Las votaciones ponderadas deciden quórum y mayorías por coeficiente
+ALTER TABLE votaciones ADD COLUMN IF NOT EXISTS ponderada BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- El voto pertenece a la parcela, con el coeficiente vigente al votar
+ALTER TABLE votos ADD COLUMN IF NOT EXISTS parcela_id INTEGER REFERENCES parcelas(id);
+ALTER TABLE votos ADD COLUMN IF NOT EXISTS peso DECIMAL(9,6) NOT NULL DEFAULT 1;
+
+-- Votos existentes: cuenta el primero de cada parcela; los demás votos de la
+-- misma parcela (y los de usuarios sin parcela) quedan solo como historial
+UPDATE votos v SET parcela_id = p.parcela_id, peso = p.coeficiente
+FROM (
+    SELECT DISTINCT ON (vo.votacion_id, u.parcela_id) vo.id, u.parcela_id, pa.coeficiente
+    FROM votos vo
+    JOIN users u ON u.id = vo.user_id
+    JOIN parcelas pa ON pa.id = u.parcela_id
+    WHERE vo.parcela_id IS NULL
+      AND NOT EXISTS (SELECT 1 FROM votos o WHERE o.votacion_id = vo.votacion_id AND o.parcela_id = u.parcela_id)
+    ORDER BY vo.votacion_id, u.parcela_id, vo.voted_at, vo.id
+) p
+WHERE v.id = p.id;
+
+-- Un voto por parcela, sin importar cuántos residentes tenga
+ALTER TABLE votos DROP CONSTRAINT IF EXISTS votos_votacion_id_user_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_votos_parcela ON votos(votacion_id, parcela_id);
@@ -0,0 +1,7 @@
+-- ============================================
+-- ROLLBACK 022: Poderes para votaciones y asambleas
+-- ============================================
+
+ALTER TABLE votos DROP COLUMN IF EXISTS poder_id;
+DROP TABLE IF EXISTS poderes;
+ALTER TABLE votaciones DROP COLUMN IF EXISTS evento_id;
@@ -0,0 +1,41 @@
+-- ============================================
+-- MIGRACIÓN 022: Poderes para votaciones y asambleas
+-- ============================================
+
+-- Votaciones realizadas en una asamblea; un poder para la asamblea las cubre
+ALTER TABLE votaciones ADD COLUMN IF NOT EXISTS evento_id UUID REFERENCES eventos(id) ON DELETE SET NULL;
+
+-- Poderes: una parcela autoriza a un vecino a votar en su nombre en una
+-- votación o en todas las votaciones de una asamblea. La directiva los valida
+-- contra el documento firmado antes de que puedan usarse.
+CREATE TABLE IF NOT EXISTS poderes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    parcela_id INTEGER NOT NULL REFERENCES parcelas(id),
+    apoderado_id UUID NOT NULL REFERENCES users(id),
+    votacion_id UUID REFERENCES votaciones(id) ON DELETE CASCADE,
+    evento_id UUID REFERENCES eventos(id) ON DELETE CASCADE,
+    valido_desde TIMESTAMPTZ NOT NULL,
+    valido_hasta TIMESTAMPTZ NOT NULL,
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente' CHECK (estado IN ('pendiente', 'validado', 'rechazado', 'revocado')),
+    documento_nombre VARCHAR(255),
+    documento_content_type VARCHAR(100),
+    documento_tamano INTEGER,
+    documento BYTEA,
+    motivo_rechazo TEXT,
+    revisado_by UUID REFERENCES users(id),
+    revisado_at TIMESTAMPTZ,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    CHECK ((votacion_id IS NULL) <> (evento_id IS NULL)),
+    CHECK (valido_hasta > valido_desde)
+);
+
+CREATE INDEX IF NOT EXISTS idx_poderes_apoderado ON poderes(apoderado_id);
+CREATE INDEX IF NOT EXISTS idx_poderes_parcela ON poderes(parcela_id);
+-- Un poder vigente por parcela y alcance
+CREATE UNIQUE INDEX IF NOT EXISTS idx_poderes_votacion ON poderes(parcela_id, votacion_id) WHERE estado IN ('pendiente', 'validado') AND votacion_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_poderes_evento ON poderes(parcela_id, evento_id) WHERE estado IN ('pendiente', 'validado') AND evento_id IS NOT NULL;
+
+-- Votos emitidos por un apoderado en nombre de la parcela
+ALTER TABLE votos ADD COLUMN IF NOT EXISTS poder_id UUID REFERENCES poderes(id);
@@ -0,0 +1,8 @@
+-- ============================================
+-- ROLLBACK 023: Votación secreta con recibos verificables
+-- ============================================
+
+ALTER TABLE votos DROP COLUMN IF EXISTS llave_recibo;
+DROP TABLE IF EXISTS urna_boletas;
+DROP TABLE IF EXISTS boletas;
+ALTER TABLE votaciones DROP COLUMN IF EXISTS secreta;
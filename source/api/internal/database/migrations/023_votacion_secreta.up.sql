@@ -0,0 +1,44 @@
+-- ============================================
+-- MIGRACIÓN 023: Votación secreta con recibos verificables
+-- ============================================
+
+ALTER TABLE votaciones ADD COLUMN IF NOT EXISTS secreta BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Boletas secretas. En las votaciones secretas, votos solo registra quién
+-- votó (sin opción); el contenido queda aquí, sin nada que lo vincule al
+-- votante: ni usuario, ni parcela, ni fecha. recibo es el SHA-256 del código
+-- entregado al votante, para que cada uno encuentre su boleta en la lista
+-- publicada.
+CREATE TABLE IF NOT EXISTS boletas (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    votacion_id UUID NOT NULL REFERENCES votaciones(id) ON DELETE CASCADE,
+    opcion_id UUID REFERENCES votacion_opciones(id) ON DELETE CASCADE,
+    is_abstention BOOLEAN NOT NULL DEFAULT FALSE,
+    peso DECIMAL(9,6) NOT NULL DEFAULT 1,
+    recibo VARCHAR(64) NOT NULL UNIQUE,
+    CHECK (is_abstention OR opcion_id IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_boletas_votacion ON boletas(votacion_id);
+
+-- Urna de las votaciones secretas abiertas. Cada boleta se escribe en la
+-- misma transacción que su fila de votos y pasa a boletas en un solo lote
+-- desordenado al cierre, para que las boletas publicadas no guarden el orden
+-- en que se emitieron. Mientras está abierta, quien lea la base de datos
+-- directamente puede emparejar la urna con votos.
+CREATE TABLE IF NOT EXISTS urna_boletas (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    votacion_id UUID NOT NULL REFERENCES votaciones(id) ON DELETE CASCADE,
+    opcion_id UUID REFERENCES votacion_opciones(id) ON DELETE CASCADE,
+    is_abstention BOOLEAN NOT NULL DEFAULT FALSE,
+    peso DECIMAL(9,6) NOT NULL DEFAULT 1,
+    recibo VARCHAR(64) NOT NULL UNIQUE,
+    CHECK (is_abstention OR opcion_id IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_urna_boletas_votacion ON urna_boletas(votacion_id);
+
+-- Segunda huella del código del recibo, guardada con quién votó, para que
+-- solo quien tiene el código pueda cambiar la boleta de esa parcela. Sin el
+-- código no se puede cruzar con el recibo publicado.
+ALTER TABLE votos ADD COLUMN IF NOT EXISTS llave_recibo VARCHAR(64);
@@ -0,0 +1,19 @@
+-- ============================================
+-- ROLLBACK 024: Votaciones de selección múltiple y preferenciales
+-- ============================================
+
+-- Falla si quedan boletas múltiples o preferenciales
+ALTER TABLE boletas DROP CONSTRAINT IF EXISTS boletas_contenido_check;
+ALTER TABLE boletas ADD CONSTRAINT boletas_check CHECK (is_abstention OR opcion_id IS NOT NULL);
+ALTER TABLE boletas DROP COLUMN IF EXISTS opciones;
+ALTER TABLE urna_boletas DROP CONSTRAINT IF EXISTS urna_boletas_contenido_check;
+ALTER TABLE urna_boletas ADD CONSTRAINT urna_boletas_check CHECK (is_abstention OR opcion_id IS NOT NULL);
+ALTER TABLE urna_boletas DROP COLUMN IF EXISTS opciones;
+ALTER TABLE votos DROP COLUMN IF EXISTS opciones;
+ALTER TABLE votaciones DROP CONSTRAINT IF EXISTS votaciones_metodo_conteo_check;
+ALTER TABLE votaciones DROP CONSTRAINT IF EXISTS votaciones_selecciones_check;
+ALTER TABLE votaciones DROP CONSTRAINT IF EXISTS votaciones_tipo_check;
+ALTER TABLE votaciones DROP COLUMN IF EXISTS metodo_conteo;
+ALTER TABLE votaciones DROP COLUMN IF EXISTS max_selecciones;
+ALTER TABLE votaciones DROP COLUMN IF EXISTS min_selecciones;
+ALTER TABLE votaciones DROP COLUMN IF EXISTS tipo;
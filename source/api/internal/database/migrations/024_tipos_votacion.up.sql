@@ -0,0 +1,29 @@
+-- ============================================
+-- MIGRACIÓN 024: Votaciones de selección múltiple y preferenciales
+-- ============================================
+
+-- simple: una opción; multiple: aprobar entre min y max opciones;
+-- preferencial: ordenar entre min y max opciones, contadas por segunda vuelta
+-- instantánea (irv) o Borda. max_selecciones 0 permite todas las opciones.
+ALTER TABLE votaciones ADD COLUMN IF NOT EXISTS tipo VARCHAR(20) NOT NULL DEFAULT 'simple';
+ALTER TABLE votaciones ADD COLUMN IF NOT EXISTS min_selecciones INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE votaciones ADD COLUMN IF NOT EXISTS max_selecciones INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE votaciones ADD COLUMN IF NOT EXISTS metodo_conteo VARCHAR(20) NOT NULL DEFAULT 'irv';
+ALTER TABLE votaciones ADD CONSTRAINT votaciones_tipo_check
+    CHECK (tipo IN ('simple', 'multiple', 'preferencial'));
+ALTER TABLE votaciones ADD CONSTRAINT votaciones_selecciones_check
+    CHECK (min_selecciones >= 1 AND (max_selecciones = 0 OR max_selecciones >= min_selecciones));
+ALTER TABLE votaciones ADD CONSTRAINT votaciones_metodo_conteo_check
+    CHECK (metodo_conteo IN ('irv', 'borda'));
+
+-- Opciones elegidas en boletas múltiples y preferenciales, en orden de
+-- preferencia; opcion_id se mantiene para las simples
+ALTER TABLE votos ADD COLUMN IF NOT EXISTS opciones UUID[];
+ALTER TABLE boletas ADD COLUMN IF NOT EXISTS opciones UUID[];
+ALTER TABLE boletas DROP CONSTRAINT IF EXISTS boletas_check;
+ALTER TABLE boletas ADD CONSTRAINT boletas_contenido_check
+    CHECK (is_abstention OR opcion_id IS NOT NULL OR cardinality(opciones) > 0);
+ALTER TABLE urna_boletas ADD COLUMN IF NOT EXISTS opciones UUID[];
+ALTER TABLE urna_boletas DROP CONSTRAINT IF EXISTS urna_boletas_check;
+ALTER TABLE urna_boletas ADD CONSTRAINT urna_boletas_contenido_check
+    CHECK (is_abstention OR opcion_id IS NOT NULL OR cardinality(opciones) > 0);
@@ -0,0 +1,5 @@
+-- ============================================
+-- ROLLBACK 025: Apertura y cierre automático de votaciones con avisos
+-- ============================================
+
+DROP TABLE IF EXISTS avisos_votacion;
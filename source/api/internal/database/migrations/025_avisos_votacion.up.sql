@@ -0,0 +1,25 @@
+-- ============================================
+-- MIGRACIÓN 025: Apertura y cierre automático de votaciones con avisos
+-- ============================================
+
+-- Avisos del proceso de votaciones: apertura, recordatorio a las parcelas que
+-- no han votado y resultados. Uno por votación y tipo, registrado antes de
+-- enviarse para no repetirlo nunca.
+CREATE TABLE IF NOT EXISTS avisos_votacion (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    votacion_id UUID NOT NULL REFERENCES votaciones(id) ON DELETE CASCADE,
+    tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('apertura', 'recordatorio', 'resultados')),
+    notificaciones INTEGER NOT NULL DEFAULT 0,
+    emails INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (votacion_id, tipo)
+);
+
+-- Las votaciones abiertas o cerradas antes de este proceso no se vuelven a
+-- anunciar
+INSERT INTO avisos_votacion (votacion_id, tipo)
+SELECT id, 'apertura' FROM votaciones WHERE status IN ('active', 'closed')
+ON CONFLICT DO NOTHING;
+INSERT INTO avisos_votacion (votacion_id, tipo)
+SELECT id, 'resultados' FROM votaciones WHERE status = 'closed'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,6 @@
+-- ============================================
+-- ROLLBACK 026: Cambio de voto con historial
+-- ============================================
+
+DROP TABLE IF EXISTS votos_historial;
+ALTER TABLE votaciones DROP COLUMN IF EXISTS allow_vote_change;
@@ -0,0 +1,25 @@
+-- ============================================
+-- MIGRACIÓN 026: Cambio de voto con historial
+-- ============================================
+
+ALTER TABLE votaciones ADD COLUMN IF NOT EXISTS allow_vote_change BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Boletas reemplazadas por un cambio de voto, con quién las había emitido y
+-- quién las reemplazó. El contenido de las boletas secretas no se guarda:
+-- opcion_id, opciones e is_abstention quedan en NULL.
+CREATE TABLE IF NOT EXISTS votos_historial (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    votacion_id UUID NOT NULL REFERENCES votaciones(id) ON DELETE CASCADE,
+    parcela_id INTEGER NOT NULL REFERENCES parcelas(id),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    poder_id UUID REFERENCES poderes(id),
+    opcion_id UUID REFERENCES votacion_opciones(id) ON DELETE SET NULL,
+    opciones UUID[],
+    is_abstention BOOLEAN,
+    peso DECIMAL(9,6) NOT NULL,
+    voted_at TIMESTAMPTZ NOT NULL,
+    cambiado_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    cambiado_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_votos_historial_votacion ON votos_historial(votacion_id, parcela_id);
@@ -0,0 +1,144 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/pdf"
+)
+
+var meses = [...]string{"", "Enero", "Febrero", "Marzo", "Abril", "Mayo", "Junio", "Julio",
+	"Agosto", "Septiembre", "Octubre", "Noviembre", "Diciembre"}
+
+// NombreMes returns the Spanish name of a month ("Marzo").
+func NombreMes(month int) string {
+	if month < 1 || month > 12 {
+		return fmt.Sprintf("%02d", month)
+	}
+	return meses[month]
+}
+
+// NombrePeriodo returns "Marzo 2026".
+func NombrePeriodo(year, month int) string {
+	if month < 1 || month > 12 {
+		return fmt.Sprintf("%02d/%d", month, year)
+	}
+	return fmt.Sprintf("%s %d", meses[month], year)
+}
+
+// AvisoCobroPDF writes the collection notice of a parcela for a periodo.
+func AvisoCobroPDF(w io.Writer, a *models.AvisoCobro) error {
+	doc := pdf.New()
+	doc.Title = "Aviso de cobro " + NombrePeriodo(a.Year, a.Month) + " - Parcela " + a.ParcelaNumero
+	doc.Footer = "Comunidad Viña Pelvin - Parcelas de Agrado"
+
+	doc.Heading("Comunidad Viña Pelvin", 18)
+	doc.Heading("Aviso de cobro - "+NombrePeriodo(a.Year, a.Month), 13)
+	doc.MoveDown(4)
+
+	doc.KeyValue("Parcela", a.ParcelaNumero)
+	if a.Propietario != "" {
+		doc.KeyValue("Propietario", a.Propietario)
+	}
+	if a.Direccion != "" {
+		doc.KeyValue("Dirección", a.Direccion)
+	}
+	doc.KeyValue("Fecha de emisión", formatDate(a.FechaEmision))
+	doc.KeyValue("Fecha de vencimiento", formatDate(a.FechaVencimiento))
+	if a.MontoBaseUF != nil && a.ValorUF != nil && a.FechaUF != nil {
+		doc.KeyValue("Gasto común en UF", FormatUF(*a.MontoBaseUF)+" (UF al "+formatDate(*a.FechaUF)+": "+
+			formatValorUF(*a.ValorUF)+")")
+	}
+	doc.MoveDown(8)
+
+	cols := []pdf.Column{
+		{Header: "Detalle", Width: 395},
+		{Header: "Monto", Width: 120, Align: pdf.AlignRight},
+	}
+	rows := make([][]string, 0, len(a.Lineas)+6)
+	for _, l := range a.Lineas {
+		rows = append(rows, []string{l.Descripcion, FormatCLP(l.Monto.Float64())})
+	}
+	rows = append(rows, []string{"**Total del periodo", FormatCLP(a.TotalPeriodo.Float64())})
+	if a.AbonosPeriodo != 0 {
+		rows = append(rows, []string{"Abonos recibidos del periodo", FormatCLP(-a.AbonosPeriodo.Float64())})
+	}
+	if a.DeudaAnterior != 0 {
+		rows = append(rows, []string{"Deuda anterior", FormatCLP(a.DeudaAnterior.Float64())})
+	}
+	if a.SaldoFavor != 0 {
+		rows = append(rows, []string{"Saldo a favor", FormatCLP(-a.SaldoFavor.Float64())})
+	}
+	rows = append(rows, []string{"**TOTAL A PAGAR", FormatCLP(a.TotalAPagar.Float64())})
+	doc.Table(cols, rows)
+
+	b := a.DatosBancarios
+	if b.Banco != "" || b.NumeroCuenta != "" {
+		doc.MoveDown(6)
+		doc.Heading("Datos para transferencia", 12)
+		for _, kv := range [][2]string{
+			{"Banco", b.Banco},
+			{"Tipo de cuenta", b.TipoCuenta},
+			{"Número de cuenta", b.NumeroCuenta},
+			{"Titular", b.Titular},
+			{"RUT", b.RUT},
+			{"Email comprobantes", b.Email},
+		} {
+			if kv[1] != "" {
+				doc.KeyValue(kv[0], kv[1])
+			}
+		}
+	}
+
+	doc.MoveDown(10)
+	doc.SetFont(false, 9)
+	doc.Paragraph("Indique el número de parcela en el comentario de la transferencia.")
+	doc.Paragraph("Los pagos posteriores al vencimiento pueden generar intereses y multas según reglamento.")
+
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+// ReciboPDF writes the numbered receipt of a pago.
+func ReciboPDF(w io.Writer, r *models.Recibo) error {
+	doc := pdf.New()
+	doc.Title = fmt.Sprintf("Recibo N° %06d", r.Numero)
+	doc.Footer = "Comunidad Viña Pelvin - Parcelas de Agrado"
+
+	doc.Heading("Comunidad Viña Pelvin", 18)
+	doc.Heading(fmt.Sprintf("Recibo de pago N° %06d", r.Numero), 14)
+	doc.MoveDown(4)
+
+	doc.KeyValue("Fecha de emisión", formatDate(r.EmitidoAt))
+	doc.KeyValue("Parcela", r.ParcelaNumero)
+	if r.Pagador != "" {
+		doc.KeyValue("Recibimos de", r.Pagador)
+	}
+	doc.KeyValue("Concepto", "Gasto común "+NombrePeriodo(r.Year, r.Month))
+	doc.KeyValue("Fecha de pago", formatDate(r.FechaPago))
+	doc.KeyValue("Medio de pago", r.Metodo)
+	if r.Referencia != "" {
+		doc.KeyValue("Referencia", r.Referencia)
+	}
+	doc.MoveDown(6)
+
+	doc.SetFont(true, 16)
+	doc.Text(pdf.Margin, doc.Y()+16, "Monto: "+FormatCLP(r.Monto.Float64()))
+	doc.MoveDown(30)
+
+	if r.AnuladoAt != nil {
+		doc.SetFont(true, 28)
+		doc.Text(pdf.Margin, doc.Y()+28, "ANULADO")
+		doc.MoveDown(36)
+		doc.SetFont(false, 10)
+		doc.Paragraph("Anulado el " + formatDate(*r.AnuladoAt) + ". " + r.MotivoAnulacion)
+	}
+
+	doc.SetFont(false, 9)
+	doc.Paragraph("Documento generado el " + time.Now().Format("02-01-2006 15:04") + ".")
+
+	_, err := doc.WriteTo(w)
+	return err
+}
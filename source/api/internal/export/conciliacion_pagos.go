@@ -0,0 +1,36 @@
+package export
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/condominio/backend/internal/models"
+)
+
+// ConciliacionPagosCSV writes the pagos / tesorería reconciliation as CSV,
+// one row per difference followed by the totals.
+func ConciliacionPagosCSV(w io.Writer, c *models.ConciliacionPagos) error {
+	cw, err := newCSVWriter(w)
+	if err != nil {
+		return err
+	}
+
+	cw.Write([]string{"Tipo", "Fecha", "Parcela", "Pago", "Comprobante", "Monto pago", "Monto contabilizado", "Detalle"})
+	for _, it := range c.Items {
+		pago, comprobante := "", ""
+		if it.PagoID != nil {
+			pago = *it.PagoID
+		}
+		if it.Comprobante != nil {
+			comprobante = strconv.FormatInt(*it.Comprobante, 10)
+		}
+		cw.Write([]string{
+			string(it.Tipo), formatDate(it.Fecha), it.ParcelaNumero, pago, comprobante,
+			formatAmount(it.Monto), formatAmount(it.MontoContabilizado), it.Detalle,
+		})
+	}
+	cw.Write([]string{"TOTAL", formatDate(c.Desde) + " al " + formatDate(c.Hasta), "", "", "",
+		formatAmount(c.TotalPagos), formatAmount(c.TotalContabilizado), "Diferencia " + formatAmount(c.Diferencia)})
+	cw.Flush()
+	return cw.Error()
+}
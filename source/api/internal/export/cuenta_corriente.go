@@ -0,0 +1,88 @@
+package export
+
+import (
+	"io"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/pdf"
+)
+
+// CuentaCorrienteCSV writes the ledger as CSV, one row per movement.
+func CuentaCorrienteCSV(w io.Writer, cc *models.CuentaCorriente) error {
+	cw, err := newCSVWriter(w)
+	if err != nil {
+		return err
+	}
+
+	cw.Write([]string{"fecha", "tipo", "descripcion", "referencia", "cargo", "abono", "saldo"})
+	if cc.Desde != nil {
+		cw.Write([]string{cc.Desde.Format("2006-01-02"), "saldo_anterior", "Saldo anterior", "", "", "", formatAmount(cc.SaldoAnterior)})
+	}
+	for _, m := range cc.Movimientos {
+		cw.Write([]string{
+			m.Fecha.Format("2006-01-02"),
+			string(m.Tipo),
+			m.Descripcion,
+			m.Referencia,
+			formatAmount(m.Cargo),
+			formatAmount(m.Abono),
+			formatAmount(m.Saldo),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// CuentaCorrientePDF writes the ledger as a printable statement.
+func CuentaCorrientePDF(w io.Writer, cc *models.CuentaCorriente) error {
+	doc := pdf.New()
+	doc.Title = "Cuenta corriente parcela " + cc.ParcelaNumero
+	doc.Footer = "Comunidad Viña Pelvin - Cuenta corriente parcela " + cc.ParcelaNumero
+
+	doc.Heading("Cuenta corriente", 16)
+	doc.KeyValue("Parcela", cc.ParcelaNumero)
+	periodo := "Todo el historial"
+	if cc.Desde != nil || cc.Hasta != nil {
+		desde, hasta := "inicio", "hoy"
+		if cc.Desde != nil {
+			desde = formatDate(*cc.Desde)
+		}
+		if cc.Hasta != nil {
+			hasta = formatDate(*cc.Hasta)
+		}
+		periodo = desde + " al " + hasta
+	}
+	doc.KeyValue("Periodo", periodo)
+	doc.KeyValue("Saldo anterior", FormatCLP(cc.SaldoAnterior.Float64()))
+	doc.KeyValue("Total cargos", FormatCLP(cc.TotalCargos.Float64()))
+	doc.KeyValue("Total abonos", FormatCLP(cc.TotalAbonos.Float64()))
+	doc.KeyValue("Saldo final", FormatCLP(cc.SaldoFinal.Float64()))
+	doc.MoveDown(8)
+
+	cols := []pdf.Column{
+		{Header: "Fecha", Width: 62},
+		{Header: "Descripción", Width: 223},
+		{Header: "Cargo", Width: 75, Align: pdf.AlignRight},
+		{Header: "Abono", Width: 75, Align: pdf.AlignRight},
+		{Header: "Saldo", Width: 80, Align: pdf.AlignRight},
+	}
+	rows := make([][]string, 0, len(cc.Movimientos)+2)
+	if cc.Desde != nil {
+		rows = append(rows, []string{formatDate(*cc.Desde), "Saldo anterior", "", "", FormatCLP(cc.SaldoAnterior.Float64())})
+	}
+	for _, m := range cc.Movimientos {
+		cargo, abono := "", ""
+		if m.Cargo != 0 {
+			cargo = FormatCLP(m.Cargo.Float64())
+		}
+		if m.Abono != 0 {
+			abono = FormatCLP(m.Abono.Float64())
+		}
+		rows = append(rows, []string{formatDate(m.Fecha), m.Descripcion, cargo, abono, FormatCLP(m.Saldo.Float64())})
+	}
+	rows = append(rows, []string{"**", "Totales", FormatCLP(cc.TotalCargos.Float64()), FormatCLP(cc.TotalAbonos.Float64()), FormatCLP(cc.SaldoFinal.Float64())})
+	doc.Table(cols, rows)
+
+	_, err := doc.WriteTo(w)
+	return err
+}
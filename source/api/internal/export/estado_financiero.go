@@ -0,0 +1,235 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/money"
+	"github.com/condominio/backend/pkg/pdf"
+	"github.com/condominio/backend/pkg/xlsx"
+)
+
+// TituloEstadoFinanciero names the statement: "Estado financiero Septiembre
+// 2026" or "Estado financiero 2026".
+func TituloEstadoFinanciero(e *models.EstadoFinanciero) string {
+	if e.Month == 0 {
+		return "Estado financiero " + strconv.Itoa(e.Year)
+	}
+	return "Estado financiero " + NombrePeriodo(e.Year, e.Month)
+}
+
+// EstadoFinancieroCSV writes the statement as CSV, one section after the
+// other with the section name in the first column.
+func EstadoFinancieroCSV(w io.Writer, e *models.EstadoFinanciero) error {
+	cw, err := newCSVWriter(w)
+	if err != nil {
+		return err
+	}
+
+	cw.Write([]string{"seccion", "concepto", "detalle", "monto"})
+	cw.Write([]string{"resumen", "saldo_inicial", e.Desde.Format("2006-01-02"), formatAmount(e.SaldoInicial)})
+	for _, l := range e.Ingresos {
+		cw.Write([]string{"ingresos", l.Categoria, l.CategoriaNombre, formatAmount(l.Monto)})
+	}
+	cw.Write([]string{"resumen", "total_ingresos", "", formatAmount(e.TotalIngresos)})
+	for _, l := range e.Egresos {
+		cw.Write([]string{"egresos", l.Categoria, l.CategoriaNombre, formatAmount(l.Monto)})
+	}
+	cw.Write([]string{"resumen", "total_egresos", "", formatAmount(e.TotalEgresos)})
+	cw.Write([]string{"resumen", "resultado", "", formatAmount(e.Resultado)})
+	cw.Write([]string{"resumen", "saldo_final", e.Hasta.Format("2006-01-02"), formatAmount(e.SaldoFinal)})
+	for _, c := range e.Cuentas {
+		cw.Write([]string{"cuentas", c.Nombre, "saldo_inicial", formatAmount(c.SaldoInicial)})
+		cw.Write([]string{"cuentas", c.Nombre, "ingresos", formatAmount(c.Ingresos)})
+		cw.Write([]string{"cuentas", c.Nombre, "egresos", formatAmount(c.Egresos)})
+		cw.Write([]string{"cuentas", c.Nombre, "saldo_final", formatAmount(c.SaldoFinal)})
+	}
+	for _, f := range e.FlujoCaja {
+		mes := fmt.Sprintf("%04d-%02d", f.Year, f.Month)
+		cw.Write([]string{"flujo_caja", mes, "ingresos", formatAmount(f.Ingresos)})
+		cw.Write([]string{"flujo_caja", mes, "egresos", formatAmount(f.Egresos)})
+		cw.Write([]string{"flujo_caja", mes, "saldo_final", formatAmount(f.SaldoFinal)})
+	}
+	for _, r := range e.Recaudacion {
+		mes := fmt.Sprintf("%04d-%02d", r.Year, r.Month)
+		cw.Write([]string{"recaudacion", mes, "emitido", formatAmount(r.Emitido)})
+		cw.Write([]string{"recaudacion", mes, "recaudado", formatAmount(r.Recaudado)})
+		cw.Write([]string{"recaudacion", mes, "tasa_%", formatPorcentaje(r.Tasa)})
+	}
+	cw.Write([]string{"resumen", "tasa_recaudacion_%", "", formatPorcentaje(e.TasaRecaudacion)})
+	for _, d := range e.Deudores {
+		cw.Write([]string{"deudores", d.ParcelaNumero, "", formatAmount(d.Deuda)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// EstadoFinancieroXLSX writes the statement as a workbook with a sheet per
+// section.
+func EstadoFinancieroXLSX(w io.Writer, e *models.EstadoFinanciero) error {
+	wb := xlsx.New()
+	pesos := func(v money.Amount, style xlsx.Style) xlsx.Cell { return xlsx.Cell{Value: v.Float64(), Style: style} }
+	porcentaje := func(v *float64) interface{} {
+		if v == nil {
+			return nil
+		}
+		return xlsx.Cell{Value: *v / 100, Style: xlsx.StylePercent}
+	}
+
+	sh := wb.AddSheet("Resumen")
+	sh.SetWidths(32, 32, 16)
+	sh.AddRow(xlsx.Cell{Value: TituloEstadoFinanciero(e), Style: xlsx.StyleBold})
+	sh.AddRow("Periodo", e.Desde, e.Hasta)
+	sh.AddRow()
+	sh.AddRow(xlsx.Cell{Value: "Saldo inicial", Style: xlsx.StyleBold}, nil, pesos(e.SaldoInicial, xlsx.StyleBoldMoney))
+	sh.AddHeader("Ingresos", "Categoría", "Monto")
+	for _, l := range e.Ingresos {
+		sh.AddRow(nil, l.CategoriaNombre, pesos(l.Monto, xlsx.StyleMoney))
+	}
+	sh.AddRow(xlsx.Cell{Value: "Total ingresos", Style: xlsx.StyleBold}, nil, pesos(e.TotalIngresos, xlsx.StyleBoldMoney))
+	sh.AddHeader("Egresos", "Categoría", "Monto")
+	for _, l := range e.Egresos {
+		sh.AddRow(nil, l.CategoriaNombre, pesos(l.Monto, xlsx.StyleMoney))
+	}
+	sh.AddRow(xlsx.Cell{Value: "Total egresos", Style: xlsx.StyleBold}, nil, pesos(e.TotalEgresos, xlsx.StyleBoldMoney))
+	sh.AddRow(xlsx.Cell{Value: "Resultado del periodo", Style: xlsx.StyleBold}, nil, pesos(e.Resultado, xlsx.StyleBoldMoney))
+	sh.AddRow(xlsx.Cell{Value: "Saldo final", Style: xlsx.StyleBold}, nil, pesos(e.SaldoFinal, xlsx.StyleBoldMoney))
+	sh.AddRow(xlsx.Cell{Value: "Tasa de recaudación", Style: xlsx.StyleBold}, nil, porcentaje(e.TasaRecaudacion))
+
+	sh = wb.AddSheet("Cuentas")
+	sh.SetWidths(28, 16, 16, 16, 16)
+	sh.AddHeader("Cuenta", "Saldo inicial", "Ingresos", "Egresos", "Saldo final")
+	for _, c := range e.Cuentas {
+		sh.AddRow(c.Nombre, pesos(c.SaldoInicial, xlsx.StyleMoney), pesos(c.Ingresos, xlsx.StyleMoney),
+			pesos(c.Egresos, xlsx.StyleMoney), pesos(c.SaldoFinal, xlsx.StyleMoney))
+	}
+
+	sh = wb.AddSheet("Flujo de caja")
+	sh.SetWidths(18, 16, 16, 16, 16)
+	sh.AddHeader("Mes", "Saldo inicial", "Ingresos", "Egresos", "Saldo final")
+	for _, f := range e.FlujoCaja {
+		sh.AddRow(NombrePeriodo(f.Year, f.Month), pesos(f.SaldoInicial, xlsx.StyleMoney), pesos(f.Ingresos, xlsx.StyleMoney),
+			pesos(f.Egresos, xlsx.StyleMoney), pesos(f.SaldoFinal, xlsx.StyleMoney))
+	}
+
+	sh = wb.AddSheet("Recaudación")
+	sh.SetWidths(18, 16, 16, 12, 10, 10)
+	sh.AddHeader("Mes", "Emitido", "Recaudado", "Tasa", "Parcelas", "Al día")
+	for _, r := range e.Recaudacion {
+		sh.AddRow(NombrePeriodo(r.Year, r.Month), pesos(r.Emitido, xlsx.StyleMoney), pesos(r.Recaudado, xlsx.StyleMoney),
+			porcentaje(r.Tasa), r.Parcelas, r.ParcelasAlDia)
+	}
+
+	if !e.Publico {
+		sh = wb.AddSheet("Deudores")
+		sh.SetWidths(14, 16)
+		sh.AddHeader("Parcela", "Deuda")
+		for _, d := range e.Deudores {
+			sh.AddRow(d.ParcelaNumero, pesos(d.Deuda, xlsx.StyleMoney))
+		}
+	}
+
+	_, err := wb.WriteTo(w)
+	return err
+}
+
+// EstadoFinancieroPDF writes the printable statement for the directiva or,
+// in its public version, for the residents.
+func EstadoFinancieroPDF(w io.Writer, e *models.EstadoFinanciero) error {
+	titulo := TituloEstadoFinanciero(e)
+	doc := pdf.New()
+	doc.Title = titulo
+	doc.Footer = "Comunidad Viña Pelvin - " + titulo
+
+	doc.Heading(titulo, 16)
+	doc.KeyValue("Periodo", formatDate(e.Desde)+" al "+formatDate(e.Hasta))
+	doc.KeyValue("Generado", formatDate(e.GeneradoAt))
+	if e.Publico {
+		doc.Paragraph("Versión de transparencia para residentes: no incluye datos por parcela.")
+	}
+	doc.MoveDown(8)
+
+	clp := func(v money.Amount) string { return FormatCLP(v.Float64()) }
+	porcentaje := func(v *float64) string {
+		if v == nil {
+			return "-"
+		}
+		return formatPorcentaje(v) + "%"
+	}
+
+	cols := []pdf.Column{
+		{Header: "Concepto", Width: 335},
+		{Header: "Monto", Width: 180, Align: pdf.AlignRight},
+	}
+	rows := [][]string{{"**Saldo inicial", clp(e.SaldoInicial)}}
+	for _, l := range e.Ingresos {
+		rows = append(rows, []string{"Ingreso: " + l.CategoriaNombre, clp(l.Monto)})
+	}
+	rows = append(rows, []string{"**Total ingresos", clp(e.TotalIngresos)})
+	for _, l := range e.Egresos {
+		rows = append(rows, []string{"Egreso: " + l.CategoriaNombre, clp(l.Monto)})
+	}
+	rows = append(rows,
+		[]string{"**Total egresos", clp(e.TotalEgresos)},
+		[]string{"**Resultado del periodo", clp(e.Resultado)},
+		[]string{"**Saldo final", clp(e.SaldoFinal)},
+	)
+	doc.Table(cols, rows)
+
+	doc.Heading("Saldos por cuenta", 12)
+	cols = []pdf.Column{
+		{Header: "Cuenta", Width: 155},
+		{Header: "Saldo inicial", Width: 90, Align: pdf.AlignRight},
+		{Header: "Ingresos", Width: 90, Align: pdf.AlignRight},
+		{Header: "Egresos", Width: 90, Align: pdf.AlignRight},
+		{Header: "Saldo final", Width: 90, Align: pdf.AlignRight},
+	}
+	rows = rows[:0]
+	for _, c := range e.Cuentas {
+		rows = append(rows, []string{c.Nombre, clp(c.SaldoInicial), clp(c.Ingresos), clp(c.Egresos), clp(c.SaldoFinal)})
+	}
+	doc.Table(cols, rows)
+
+	doc.Heading("Flujo de caja", 12)
+	cols[0].Header = "Mes"
+	rows = rows[:0]
+	for _, f := range e.FlujoCaja {
+		rows = append(rows, []string{NombrePeriodo(f.Year, f.Month), clp(f.SaldoInicial), clp(f.Ingresos), clp(f.Egresos), clp(f.SaldoFinal)})
+	}
+	doc.Table(cols, rows)
+
+	doc.Heading("Recaudación de gastos comunes", 12)
+	cols = []pdf.Column{
+		{Header: "Mes", Width: 135},
+		{Header: "Emitido", Width: 95, Align: pdf.AlignRight},
+		{Header: "Recaudado", Width: 95, Align: pdf.AlignRight},
+		{Header: "Tasa", Width: 70, Align: pdf.AlignRight},
+		{Header: "Parcelas al día", Width: 120, Align: pdf.AlignRight},
+	}
+	rows = rows[:0]
+	for _, r := range e.Recaudacion {
+		rows = append(rows, []string{NombrePeriodo(r.Year, r.Month), clp(r.Emitido), clp(r.Recaudado), porcentaje(r.Tasa),
+			fmt.Sprintf("%d de %d", r.ParcelasAlDia, r.Parcelas)})
+	}
+	doc.Table(cols, rows)
+	doc.KeyValue("Tasa de recaudación", porcentaje(e.TasaRecaudacion))
+
+	if !e.Publico && len(e.Deudores) > 0 {
+		doc.MoveDown(8)
+		doc.Heading("Parcelas con deuda", 12)
+		cols = []pdf.Column{
+			{Header: "Parcela", Width: 335},
+			{Header: "Deuda", Width: 180, Align: pdf.AlignRight},
+		}
+		rows = rows[:0]
+		for _, d := range e.Deudores {
+			rows = append(rows, []string{d.ParcelaNumero, clp(d.Deuda)})
+		}
+		doc.Table(cols, rows)
+	}
+
+	_, err := doc.WriteTo(w)
+	return err
+}
@@ -0,0 +1,68 @@
+// Package export renders API reports as downloadable files (CSV, PDF).
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/condominio/backend/pkg/money"
+)
+
+// utf8BOM makes Excel detect UTF-8 when opening the CSV files.
+const utf8BOM = "\xef\xbb\xbf"
+
+func newCSVWriter(w io.Writer) (*csv.Writer, error) {
+	if _, err := io.WriteString(w, utf8BOM); err != nil {
+		return nil, err
+	}
+	return csv.NewWriter(w), nil
+}
+
+// FormatCLP formats an amount in pesos as "$1.234.567" (negative: "-$1.234").
+func FormatCLP(v float64) string {
+	neg := v < 0
+	n := int64(math.Round(math.Abs(v)))
+	s := strconv.FormatInt(n, 10)
+
+	var b strings.Builder
+	if neg {
+		b.WriteByte('-')
+	}
+	b.WriteByte('$')
+	for i, c := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// FormatUF formats an amount in UF as "1,2500 UF", trimming zero decimals
+// beyond the second ("2,50 UF").
+func FormatUF(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 4, 64)
+	for strings.HasSuffix(s, "0") && len(s)-strings.IndexByte(s, '.') > 3 {
+		s = s[:len(s)-1]
+	}
+	return strings.Replace(s, ".", ",", 1) + " UF"
+}
+
+// formatValorUF formats the CLP value of a UF with its cents: "$39.485,65".
+func formatValorUF(v float64) string {
+	entero, cents, _ := strings.Cut(strconv.FormatFloat(v, 'f', 2, 64), ".")
+	n, _ := strconv.ParseFloat(entero, 64)
+	return FormatCLP(n) + "," + cents
+}
+
+func formatAmount(v money.Amount) string {
+	return v.String()
+}
+
+func formatDate(t time.Time) string {
+	return t.Format("02-01-2006")
+}
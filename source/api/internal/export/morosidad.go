@@ -0,0 +1,134 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/money"
+	"github.com/condominio/backend/pkg/xlsx"
+)
+
+var morosidadHeader = []string{
+	"Parcela", "Direccion", "Contacto", "Email", "Deuda total", "Deuda (UF)",
+	"0-30 dias", "31-60 dias", "61-90 dias", "90+ dias",
+	"Meses adeudados", "Dias max. atraso", "Ultimo pago", "En convenio",
+}
+
+// MorosidadCSV writes the delinquency report as CSV.
+func MorosidadCSV(w io.Writer, r *models.ReporteMorosidad) error {
+	cw, err := newCSVWriter(w)
+	if err != nil {
+		return err
+	}
+
+	cw.Write(morosidadHeader)
+	for _, m := range r.Parcelas {
+		ultimoPago := ""
+		if m.UltimoPago != nil {
+			ultimoPago = m.UltimoPago.Format("2006-01-02")
+		}
+		convenio := "no"
+		if m.EnConvenio {
+			convenio = "si"
+		}
+		cw.Write([]string{
+			m.ParcelaNumero, m.Direccion, m.Contacto, m.Email,
+			formatAmount(m.DeudaTotal), formatUFOpcional(m.DeudaTotalUF),
+			formatAmount(m.Tramo0a30), formatAmount(m.Tramo31a60),
+			formatAmount(m.Tramo61a90), formatAmount(m.TramoMas90),
+			strconv.Itoa(m.MesesAdeudados), strconv.Itoa(m.DiasMaxAtraso),
+			ultimoPago, convenio,
+		})
+	}
+	cw.Write([]string{
+		"TOTAL", "", "", "",
+		formatAmount(r.DeudaTotal), formatUFOpcional(r.DeudaTotalUF),
+		formatAmount(r.Tramo0a30), formatAmount(r.Tramo31a60),
+		formatAmount(r.Tramo61a90), formatAmount(r.TramoMas90),
+		"", "", "", "",
+	})
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatUFOpcional(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', 4, 64)
+}
+
+// MorosidadXLSX writes the delinquency report as a spreadsheet.
+func MorosidadXLSX(w io.Writer, r *models.ReporteMorosidad) error {
+	wb := xlsx.New()
+	sh := wb.AddSheet("Morosidad " + r.FechaCorte.Format("2006-01-02"))
+	sh.SetWidths(10, 28, 28, 30, 14, 12, 12, 12, 12, 12, 10, 10, 12, 10)
+	sh.AddHeader(morosidadHeader...)
+
+	pesos := func(v money.Amount) xlsx.Cell { return xlsx.Cell{Value: v.Float64(), Style: xlsx.StyleMoney} }
+	uf := func(v *float64, style xlsx.Style) interface{} {
+		if v == nil {
+			return nil
+		}
+		return xlsx.Cell{Value: *v, Style: style}
+	}
+	for _, m := range r.Parcelas {
+		convenio := "No"
+		if m.EnConvenio {
+			convenio = "Si"
+		}
+		sh.AddRow(
+			m.ParcelaNumero, m.Direccion, m.Contacto, m.Email,
+			pesos(m.DeudaTotal), uf(m.DeudaTotalUF, xlsx.StyleDecimal),
+			pesos(m.Tramo0a30), pesos(m.Tramo31a60), pesos(m.Tramo61a90), pesos(m.TramoMas90),
+			m.MesesAdeudados, m.DiasMaxAtraso, m.UltimoPago, convenio,
+		)
+	}
+
+	bold := func(v money.Amount) xlsx.Cell { return xlsx.Cell{Value: v.Float64(), Style: xlsx.StyleBoldMoney} }
+	sh.AddRow(
+		xlsx.Cell{Value: "TOTAL", Style: xlsx.StyleBold}, nil, nil, nil,
+		bold(r.DeudaTotal), uf(r.DeudaTotalUF, xlsx.StyleBoldDecimal),
+		bold(r.Tramo0a30), bold(r.Tramo31a60), bold(r.Tramo61a90), bold(r.TramoMas90),
+	)
+
+	_, err := wb.WriteTo(w)
+	return err
+}
+
+// TendenciaMorosidadCSV writes the monthly delinquency trend as CSV.
+func TendenciaMorosidadCSV(w io.Writer, tendencia []models.MorosidadMes) error {
+	cw, err := newCSVWriter(w)
+	if err != nil {
+		return err
+	}
+
+	cw.Write([]string{"year", "month", "fecha_corte", "deuda_total", "parcelas_morosas"})
+	for _, m := range tendencia {
+		cw.Write([]string{
+			strconv.Itoa(m.Year), strconv.Itoa(m.Month), m.FechaCorte.Format("2006-01-02"),
+			formatAmount(m.DeudaTotal), strconv.Itoa(m.ParcelasMorosas),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// TendenciaMorosidadXLSX writes the monthly delinquency trend as a spreadsheet.
+func TendenciaMorosidadXLSX(w io.Writer, tendencia []models.MorosidadMes) error {
+	wb := xlsx.New()
+	sh := wb.AddSheet("Tendencia morosidad")
+	sh.SetWidths(10, 14, 16, 18)
+	sh.AddHeader("Mes", "Fecha corte", "Deuda total", "Parcelas morosas")
+	for _, m := range tendencia {
+		sh.AddRow(
+			fmt.Sprintf("%04d-%02d", m.Year, m.Month), m.FechaCorte,
+			xlsx.Cell{Value: m.DeudaTotal.Float64(), Style: xlsx.StyleMoney}, m.ParcelasMorosas,
+		)
+	}
+
+	_, err := wb.WriteTo(w)
+	return err
+}
@@ -0,0 +1,100 @@
+package export
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/money"
+	"github.com/condominio/backend/pkg/xlsx"
+)
+
+var ejecucionHeader = []string{
+	"Categoria", "Nombre", "Tipo", "Presupuesto anual", "Presupuestado mes", "Ejecutado mes",
+	"Presupuestado al mes", "Ejecutado al mes", "Diferencia", "% ejecutado", "Alerta",
+}
+
+// EjecucionPresupuestoCSV writes the budget variance report as CSV.
+func EjecucionPresupuestoCSV(w io.Writer, e *models.EjecucionPresupuesto) error {
+	cw, err := newCSVWriter(w)
+	if err != nil {
+		return err
+	}
+
+	cw.Write(ejecucionHeader)
+	for _, c := range e.Categorias {
+		alerta := "no"
+		if c.Alerta {
+			alerta = "si"
+		}
+		cw.Write([]string{
+			c.Categoria, c.CategoriaNombre, string(c.Tipo), formatAmount(c.Presupuestado),
+			formatAmount(c.PresupuestadoMes), formatAmount(c.EjecutadoMes),
+			formatAmount(c.PresupuestadoAlMes), formatAmount(c.EjecutadoAlMes),
+			formatAmount(c.Diferencia), formatPorcentaje(c.PorcentajeEjecutado), alerta,
+		})
+	}
+	for _, t := range []struct {
+		nombre string
+		tot    models.EjecucionTotales
+	}{{"TOTAL INGRESOS", e.Ingresos}, {"TOTAL EGRESOS", e.Egresos}} {
+		cw.Write([]string{
+			t.nombre, "", "", formatAmount(t.tot.Presupuestado), "", "",
+			formatAmount(t.tot.PresupuestadoAlMes), formatAmount(t.tot.EjecutadoAlMes),
+			formatAmount(t.tot.Diferencia), formatPorcentaje(t.tot.PorcentajeEjecutado), "",
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatPorcentaje(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', 2, 64)
+}
+
+// EjecucionPresupuestoXLSX writes the budget variance report as a spreadsheet
+// for the annual assembly.
+func EjecucionPresupuestoXLSX(w io.Writer, e *models.EjecucionPresupuesto) error {
+	wb := xlsx.New()
+	sh := wb.AddSheet("Presupuesto " + NombrePeriodo(e.Year, e.Month))
+	sh.SetWidths(18, 28, 10, 16, 16, 16, 18, 18, 16, 12, 8)
+	sh.AddHeader(ejecucionHeader...)
+
+	pesos := func(v money.Amount, style xlsx.Style) xlsx.Cell { return xlsx.Cell{Value: v.Float64(), Style: style} }
+	porcentaje := func(v *float64, style xlsx.Style) interface{} {
+		if v == nil {
+			return nil
+		}
+		return xlsx.Cell{Value: *v / 100, Style: style}
+	}
+	for _, c := range e.Categorias {
+		alerta := "No"
+		if c.Alerta {
+			alerta = "Si"
+		}
+		sh.AddRow(
+			c.Categoria, c.CategoriaNombre, string(c.Tipo),
+			pesos(c.Presupuestado, xlsx.StyleMoney), pesos(c.PresupuestadoMes, xlsx.StyleMoney),
+			pesos(c.EjecutadoMes, xlsx.StyleMoney), pesos(c.PresupuestadoAlMes, xlsx.StyleMoney),
+			pesos(c.EjecutadoAlMes, xlsx.StyleMoney), pesos(c.Diferencia, xlsx.StyleMoney),
+			porcentaje(c.PorcentajeEjecutado, xlsx.StylePercent), alerta,
+		)
+	}
+	for _, t := range []struct {
+		nombre string
+		tot    models.EjecucionTotales
+	}{{"TOTAL INGRESOS", e.Ingresos}, {"TOTAL EGRESOS", e.Egresos}} {
+		sh.AddRow(
+			xlsx.Cell{Value: t.nombre, Style: xlsx.StyleBold}, nil, nil,
+			pesos(t.tot.Presupuestado, xlsx.StyleBoldMoney), nil, nil,
+			pesos(t.tot.PresupuestadoAlMes, xlsx.StyleBoldMoney), pesos(t.tot.EjecutadoAlMes, xlsx.StyleBoldMoney),
+			pesos(t.tot.Diferencia, xlsx.StyleBoldMoney), porcentaje(t.tot.PorcentajeEjecutado, xlsx.StylePercent),
+		)
+	}
+
+	_, err := wb.WriteTo(w)
+	return err
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/condominio/backend/internal/export"
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/internal/services"
+)
+
+type CobranzaHandler struct {
+	service *services.CobranzaService
+}
+
+func NewCobranzaHandler(service *services.CobranzaService) *CobranzaHandler {
+	return &CobranzaHandler{service: service}
+}
+
+// ============================================
+// RECIBOS
+// ============================================
+
+func (h *CobranzaHandler) GetRecibo(w http.ResponseWriter, r *http.Request) {
+	recibo, err := h.service.GetRecibo(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeReciboError(w, err)
+		return
+	}
+	writeRecibo(w, r, recibo)
+}
+
+func (h *CobranzaHandler) GetMiRecibo(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	recibo, err := h.service.GetMiRecibo(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeReciboError(w, err)
+		return
+	}
+	writeRecibo(w, r, recibo)
+}
+
+func (h *CobranzaHandler) EnviarRecibo(w http.ResponseWriter, r *http.Request) {
+	recibo, err := h.service.EnviarRecibo(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, services.ErrSinDestinatarios) {
+			writeError(w, http.StatusBadRequest, "Parcela has no residents with email")
+			return
+		}
+		writeReciboError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, recibo)
+}
+
+func writeReciboError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNoParcela):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrPagoNotFound):
+		writeError(w, http.StatusNotFound, "Pago not found")
+	case errors.Is(err, services.ErrReciboNoDisponible):
+		writeError(w, http.StatusBadRequest, "Receipts are only issued for approved pagos")
+	default:
+		log.Printf("Recibo failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to get recibo")
+	}
+}
+
+func writeRecibo(w http.ResponseWriter, r *http.Request, recibo *models.Recibo) {
+	var buf bytes.Buffer
+	switch r.URL.Query().Get("format") {
+	case "", "pdf":
+		if err := export.ReciboPDF(&buf, recibo); err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to export recibo")
+			return
+		}
+		writeFile(w, "application/pdf", fmt.Sprintf("recibo-%06d.pdf", recibo.Numero), buf.Bytes())
+	case "json":
+		writeJSON(w, http.StatusOK, recibo)
+	default:
+		writeError(w, http.StatusBadRequest, "format must be pdf or json")
+	}
+}
+
+// ============================================
+// AVISOS DE COBRO
+// ============================================
+
+func (h *CobranzaHandler) GetAvisoCobro(w http.ResponseWriter, r *http.Request) {
+	parcelaID, err := strconv.Atoi(chi.URLParam(r, "parcelaId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid parcela id")
+		return
+	}
+
+	aviso, err := h.service.GetAvisoCobro(r.Context(), chi.URLParam(r, "id"), parcelaID)
+	if err != nil {
+		writeAvisoError(w, err)
+		return
+	}
+	writeAvisoCobro(w, r, aviso)
+}
+
+func (h *CobranzaHandler) GetMiAvisoCobro(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	aviso, err := h.service.GetMiAvisoCobro(r.Context(), userID, chi.URLParam(r, "periodoId"))
+	if err != nil {
+		writeAvisoError(w, err)
+		return
+	}
+	writeAvisoCobro(w, r, aviso)
+}
+
+func (h *CobranzaHandler) EnviarAvisos(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.EnviarAvisos(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeAvisoError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func writeAvisoError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNoParcela):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrPeriodoNotFound):
+		writeError(w, http.StatusNotFound, "Periodo not found")
+	case errors.Is(err, services.ErrParcelaNotFound):
+		writeError(w, http.StatusNotFound, "Parcela not found")
+	default:
+		log.Printf("Aviso de cobro failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to get aviso de cobro")
+	}
+}
+
+func writeAvisoCobro(w http.ResponseWriter, r *http.Request, aviso *models.AvisoCobro) {
+	var buf bytes.Buffer
+	switch r.URL.Query().Get("format") {
+	case "", "pdf":
+		if err := export.AvisoCobroPDF(&buf, aviso); err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to export aviso de cobro")
+			return
+		}
+		filename := fmt.Sprintf("aviso-%d-%02d-parcela-%s.pdf", aviso.Year, aviso.Month, aviso.ParcelaNumero)
+		writeFile(w, "application/pdf", filename, buf.Bytes())
+	case "json":
+		writeJSON(w, http.StatusOK, aviso)
+	default:
+		writeError(w, http.StatusBadRequest, "format must be pdf or json")
+	}
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/internal/services"
+)
+
+func writeConciliacionError(w http.ResponseWriter, err error, op string) {
+	switch {
+	case errors.Is(err, services.ErrCuentaNotFound):
+		writeError(w, http.StatusNotFound, "Cuenta not found")
+	case errors.Is(err, services.ErrFormatoNotFound):
+		writeError(w, http.StatusNotFound, "Import format not found")
+	case errors.Is(err, services.ErrInvalidFormato):
+		writeError(w, http.StatusBadRequest, "formato must be 'csv' (with formato_id) or 'ofx'")
+	case errors.Is(err, services.ErrArchivoInvalido):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrInvalidRangoFechas):
+		writeError(w, http.StatusBadRequest, "desde must not be after hasta")
+	case errors.Is(err, services.ErrLineaCartolaNotFound):
+		writeError(w, http.StatusNotFound, "Statement line not found")
+	case errors.Is(err, services.ErrMovimientoNotFound):
+		writeError(w, http.StatusBadRequest, "Movimiento not found")
+	case errors.Is(err, services.ErrMovimientoNoConciliable):
+		writeError(w, http.StatusBadRequest, "The movimiento must be a live entry of the same cuenta and amount as the line")
+	case errors.Is(err, services.ErrLineaConciliada),
+		errors.Is(err, services.ErrLineaNoConciliada),
+		errors.Is(err, services.ErrMovimientoConciliado),
+		errors.Is(err, services.ErrPeriodoConciliado),
+		errors.Is(err, services.ErrCierreAnterior):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrInvalidCierre):
+		writeError(w, http.StatusBadRequest, "fecha_corte is required and cannot be in the future")
+	case errors.Is(err, services.ErrSaldoBancoRequerido):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("%s failed: %v", op, err)
+		writeError(w, http.StatusInternalServerError, "Failed to process bank reconciliation")
+	}
+}
+
+// CreateCartola uploads a bank statement: multipart form with archivo,
+// formato (csv|ofx, detected when empty) and formato_id for CSV files.
+func (h *TesoreriaHandler) CreateCartola(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxArchivoBanco)
+	if err := r.ParseMultipartForm(maxArchivoBanco); err != nil {
+		writeError(w, http.StatusBadRequest, "Expected multipart form with archivo (max 5 MB)")
+		return
+	}
+	file, header, err := r.FormFile("archivo")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "archivo is required")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Failed to read archivo")
+		return
+	}
+	if len(data) == 0 {
+		writeError(w, http.StatusBadRequest, "archivo is empty")
+		return
+	}
+
+	cartola, err := h.service.CreateCartola(r.Context(), chi.URLParam(r, "id"),
+		models.CartolaFormato(r.FormValue("formato")), r.FormValue("formato_id"),
+		nombreAdjunto(header.Filename, ".txt"), data, userID)
+	if err != nil {
+		writeConciliacionError(w, err, "CreateCartola")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, cartola)
+}
+
+func (h *TesoreriaHandler) ListCartolas(w http.ResponseWriter, r *http.Request) {
+	cartolas, err := h.service.ListCartolas(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeConciliacionError(w, err, "ListCartolas")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cartolas)
+}
+
+// GetConciliacionBancaria is the reconciliation screen of a cuenta between
+// ?desde= and ?hasta= (default: the current month).
+func (h *TesoreriaHandler) GetConciliacionBancaria(w http.ResponseWriter, r *http.Request) {
+	y, m, d := time.Now().Date()
+	desde := time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	hasta := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	if v := r.URL.Query().Get("desde"); v != "" {
+		fecha, ok := parseFechaParam(w, v, "desde")
+		if !ok {
+			return
+		}
+		desde = fecha
+	}
+	if v := r.URL.Query().Get("hasta"); v != "" {
+		fecha, ok := parseFechaParam(w, v, "hasta")
+		if !ok {
+			return
+		}
+		hasta = fecha
+	}
+
+	conciliacion, err := h.service.GetConciliacionBancaria(r.Context(), chi.URLParam(r, "id"), desde, hasta)
+	if err != nil {
+		writeConciliacionError(w, err, "GetConciliacionBancaria")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, conciliacion)
+}
+
+func (h *TesoreriaHandler) ConciliarAutomatico(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	result, err := h.service.ConciliarAutomatico(r.Context(), chi.URLParam(r, "id"), userID)
+	if err != nil {
+		writeConciliacionError(w, err, "ConciliarAutomatico")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *TesoreriaHandler) ConciliarLinea(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	var req models.ConciliarLineaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.MovimientoID == "" {
+		writeError(w, http.StatusBadRequest, "movimiento_id is required")
+		return
+	}
+
+	linea, err := h.service.ConciliarLinea(r.Context(), chi.URLParam(r, "id"), req.MovimientoID, userID)
+	if err != nil {
+		writeConciliacionError(w, err, "ConciliarLinea")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, linea)
+}
+
+func (h *TesoreriaHandler) DesconciliarLinea(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	linea, err := h.service.DesconciliarLinea(r.Context(), chi.URLParam(r, "id"), userID)
+	if err != nil {
+		writeConciliacionError(w, err, "DesconciliarLinea")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, linea)
+}
+
+// CreateCierre signs the reconciliation of a cuenta at fecha_corte.
+func (h *TesoreriaHandler) CreateCierre(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	var req models.CreateCierreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	cierre, err := h.service.CreateCierre(r.Context(), chi.URLParam(r, "id"), &req, userID)
+	if err != nil {
+		writeConciliacionError(w, err, "CreateCierre")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, cierre)
+}
+
+func (h *TesoreriaHandler) ListCierres(w http.ResponseWriter, r *http.Request) {
+	cierres, err := h.service.ListCierres(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeConciliacionError(w, err, "ListCierres")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cierres)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/condominio/backend/internal/export"
+	"github.com/condominio/backend/internal/services"
+)
+
+// ContabilizarPagos posts the pagos waiting for the daily posting up to
+// ?hasta= (default: today).
+func (h *TesoreriaHandler) ContabilizarPagos(w http.ResponseWriter, r *http.Request) {
+	y, m, d := time.Now().Date()
+	hasta := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	if v := r.URL.Query().Get("hasta"); v != "" {
+		fecha, ok := parseFechaParam(w, v, "hasta")
+		if !ok {
+			return
+		}
+		hasta = fecha
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	result, err := h.service.ContabilizarPagosPendientes(r.Context(), hasta, userID)
+	if err != nil {
+		log.Printf("ContabilizarPagosPendientes failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to post pagos")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// GetConciliacionPagos cross-checks pagos against treasury between ?desde=
+// and ?hasta= (default: the current month).
+func (h *TesoreriaHandler) GetConciliacionPagos(w http.ResponseWriter, r *http.Request) {
+	y, m, d := time.Now().Date()
+	desde := time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	hasta := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	if v := r.URL.Query().Get("desde"); v != "" {
+		fecha, ok := parseFechaParam(w, v, "desde")
+		if !ok {
+			return
+		}
+		desde = fecha
+	}
+	if v := r.URL.Query().Get("hasta"); v != "" {
+		fecha, ok := parseFechaParam(w, v, "hasta")
+		if !ok {
+			return
+		}
+		hasta = fecha
+	}
+
+	conciliacion, err := h.service.GetConciliacionPagos(r.Context(), desde, hasta)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRangoFechas) {
+			writeError(w, http.StatusBadRequest, "desde must not be after hasta")
+			return
+		}
+		log.Printf("GetConciliacionPagos failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to build reconciliation")
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		writeJSON(w, http.StatusOK, conciliacion)
+	case "csv":
+		var buf bytes.Buffer
+		if err := export.ConciliacionPagosCSV(&buf, conciliacion); err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to export reconciliation")
+			return
+		}
+		filename := "conciliacion-pagos-" + desde.Format("2006-01-02") + "-" + hasta.Format("2006-01-02") + ".csv"
+		writeFile(w, "text/csv; charset=utf-8", filename, buf.Bytes())
+	default:
+		writeError(w, http.StatusBadRequest, "format must be json or csv")
+	}
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/internal/services"
+)
+
+type ConvenioHandler struct {
+	service *services.ConvenioService
+}
+
+func NewConvenioHandler(service *services.ConvenioService) *ConvenioHandler {
+	return &ConvenioHandler{service: service}
+}
+
+func (h *ConvenioHandler) List(w http.ResponseWriter, r *http.Request) {
+	filter := models.ConvenioFilter{
+		Page:    1,
+		PerPage: 20,
+	}
+
+	if page := r.URL.Query().Get("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil {
+			filter.Page = p
+		}
+	}
+	if perPage := r.URL.Query().Get("per_page"); perPage != "" {
+		if pp, err := strconv.Atoi(perPage); err == nil {
+			filter.PerPage = pp
+		}
+	}
+	if parcelaID := r.URL.Query().Get("parcela_id"); parcelaID != "" {
+		if pid, err := strconv.Atoi(parcelaID); err == nil {
+			filter.ParcelaID = pid
+		}
+	}
+	if estado := r.URL.Query().Get("estado"); estado != "" {
+		filter.Estado = models.ConvenioEstado(estado)
+	}
+
+	resp, err := h.service.ListConvenios(r.Context(), filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to list convenios")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *ConvenioHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	convenio, err := h.service.GetConvenio(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, services.ErrConvenioNotFound) {
+			writeError(w, http.StatusNotFound, "Convenio not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to get convenio")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, convenio)
+}
+
+func (h *ConvenioHandler) GetMisConvenios(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	convenios, err := h.service.GetMisConvenios(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNoParcela) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to get convenios")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, convenios)
+}
+
+func (h *ConvenioHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateConvenioRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.ParcelaID <= 0 {
+		writeError(w, http.StatusBadRequest, "parcela_id is required")
+		return
+	}
+	if len(req.GastoIDs) == 0 {
+		writeError(w, http.StatusBadRequest, "gasto_ids is required")
+		return
+	}
+	seen := make(map[string]bool, len(req.GastoIDs))
+	for _, id := range req.GastoIDs {
+		if seen[id] {
+			writeError(w, http.StatusBadRequest, "gasto_ids contains duplicates")
+			return
+		}
+		seen[id] = true
+	}
+	if req.NumCuotas < 1 || req.NumCuotas > 60 {
+		writeError(w, http.StatusBadRequest, "num_cuotas must be between 1 and 60")
+		return
+	}
+	if req.FechaPrimeraCuota == "" {
+		writeError(w, http.StatusBadRequest, "fecha_primera_cuota is required")
+		return
+	}
+	if req.MaxCuotasImpagas < 0 {
+		writeError(w, http.StatusBadRequest, "max_cuotas_impagas must be positive")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	convenio, err := h.service.CreateConvenio(r.Context(), &req, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidFecha):
+			writeError(w, http.StatusBadRequest, "Invalid fecha_primera_cuota format, expected YYYY-MM-DD")
+		case errors.Is(err, services.ErrGastoComunNotFound):
+			writeError(w, http.StatusNotFound, "Gasto not found")
+		case errors.Is(err, services.ErrConvenioGastoInvalido):
+			writeError(w, http.StatusBadRequest, "All gastos must be overdue and belong to the parcela")
+		case errors.Is(err, services.ErrConvenioSinDeuda):
+			writeError(w, http.StatusBadRequest, "Selected gastos have no pending balance")
+		default:
+			log.Printf("CreateConvenio failed: %v", err)
+			writeError(w, http.StatusInternalServerError, "Failed to create convenio")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, convenio)
+}
+
+func (h *ConvenioHandler) Pagar(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req models.RegistrarPagoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Monto <= 0 {
+		writeError(w, http.StatusBadRequest, "monto must be positive")
+		return
+	}
+	if req.Metodo == "" {
+		writeError(w, http.StatusBadRequest, "metodo is required")
+		return
+	}
+	if req.Metodo == models.MetodoPagoCredito {
+		writeError(w, http.StatusBadRequest, "Use aplicar-credito to pay with credit balance")
+		return
+	}
+
+	convenio, err := h.service.PagarConvenio(r.Context(), id, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrConvenioNotFound):
+			writeError(w, http.StatusNotFound, "Convenio not found")
+		case errors.Is(err, services.ErrConvenioNoActivo):
+			writeError(w, http.StatusBadRequest, "Convenio is not active")
+		case errors.Is(err, services.ErrGastoAlreadyPaid):
+			writeError(w, http.StatusBadRequest, "Convenio has no pending balance")
+		default:
+			log.Printf("PagarConvenio failed: %v", err)
+			writeError(w, http.StatusInternalServerError, "Failed to register payment")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, convenio)
+}
+
+func (h *ConvenioHandler) Anular(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req models.TerminarConvenioRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Motivo == "" {
+		writeError(w, http.StatusBadRequest, "motivo is required")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	convenio, err := h.service.AnularConvenio(r.Context(), id, req.Motivo, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrConvenioNotFound):
+			writeError(w, http.StatusNotFound, "Convenio not found")
+		case errors.Is(err, services.ErrConvenioNoActivo):
+			writeError(w, http.StatusBadRequest, "Convenio is not active")
+		default:
+			log.Printf("AnularConvenio failed: %v", err)
+			writeError(w, http.StatusInternalServerError, "Failed to cancel convenio")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, convenio)
+}
+
+func (h *ConvenioHandler) Procesar(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.ProcesarConvenios(r.Context())
+	if err != nil {
+		log.Printf("ProcesarConvenios failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to process convenios")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
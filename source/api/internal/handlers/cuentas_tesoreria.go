@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/internal/services"
+)
+
+// ============================================
+// CUENTAS
+// ============================================
+
+func writeCuentaError(w http.ResponseWriter, err error, op string) {
+	switch {
+	case errors.Is(err, services.ErrCuentaNotFound):
+		writeError(w, http.StatusNotFound, "Cuenta not found")
+	case errors.Is(err, services.ErrCuentaExists):
+		writeError(w, http.StatusConflict, "A cuenta with this codigo already exists")
+	case errors.Is(err, services.ErrInvalidCuenta):
+		writeError(w, http.StatusBadRequest, "codigo (lowercase letters, digits and _), nombre and tipo 'corriente', 'caja', 'fondo_reserva' or 'otra' are required")
+	case errors.Is(err, services.ErrCuentaPrincipal):
+		writeError(w, http.StatusConflict, "The principal cuenta cannot be deactivated")
+	default:
+		log.Printf("%s failed: %v", op, err)
+		writeError(w, http.StatusInternalServerError, "Failed to save cuenta")
+	}
+}
+
+func (h *TesoreriaHandler) ListCuentas(w http.ResponseWriter, r *http.Request) {
+	cuentas, err := h.service.ListCuentas(r.Context(), r.URL.Query().Get("activas") == "true")
+	if err != nil {
+		log.Printf("ListCuentas failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to list cuentas")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cuentas)
+}
+
+func (h *TesoreriaHandler) CreateCuenta(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateCuentaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	cuenta, err := h.service.CreateCuenta(r.Context(), &req, userID)
+	if err != nil {
+		writeCuentaError(w, err, "CreateCuenta")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, cuenta)
+}
+
+func (h *TesoreriaHandler) UpdateCuenta(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req models.UpdateCuentaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	cuenta, err := h.service.UpdateCuenta(r.Context(), id, &req, userID)
+	if err != nil {
+		writeCuentaError(w, err, "UpdateCuenta")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cuenta)
+}
+
+// ============================================
+// TRANSFERENCIAS
+// ============================================
+
+func (h *TesoreriaHandler) CreateTransferencia(w http.ResponseWriter, r *http.Request) {
+	var req models.TransferenciaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	transferencia, err := h.service.Transferir(r.Context(), &req, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidTransferencia):
+			writeError(w, http.StatusBadRequest, "Two different cuentas and a positive monto are required")
+		case errors.Is(err, services.ErrCuentaInvalida):
+			writeError(w, http.StatusBadRequest, "Both cuentas must exist and be active")
+		default:
+			log.Printf("Transferir failed: %v", err)
+			writeError(w, http.StatusInternalServerError, "Failed to create transferencia")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, transferencia)
+}
+
+func (h *TesoreriaHandler) GetTransferencia(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	transferencia, err := h.service.GetTransferencia(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, services.ErrTransferenciaNotFound) {
+			writeError(w, http.StatusNotFound, "Transferencia not found")
+			return
+		}
+		log.Printf("GetTransferencia failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to get transferencia")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, transferencia)
+}
+
+// ============================================
+// CONFIGURACION
+// ============================================
+
+func (h *TesoreriaHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	cfg, err := h.service.GetConfigTesoreria(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to get tesoreria configuration")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cfg)
+}
+
+func (h *TesoreriaHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateConfigTesoreriaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	cfg, err := h.service.UpdateConfigTesoreria(r.Context(), &req, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidConfigTesoreria) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("UpdateConfigTesoreria failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to update tesoreria configuration")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cfg)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/condominio/backend/internal/export"
+	"github.com/condominio/backend/internal/services"
+)
+
+// GetEstadoFinanciero is the full statement for the directiva:
+// ?month= (omit for the whole year) &format=json|csv|xlsx|pdf.
+func (h *TesoreriaHandler) GetEstadoFinanciero(w http.ResponseWriter, r *http.Request) {
+	h.estadoFinanciero(w, r, false)
+}
+
+// GetEstadoFinancieroPublico is the transparency version residents can
+// download, without per-parcela data.
+func (h *TesoreriaHandler) GetEstadoFinancieroPublico(w http.ResponseWriter, r *http.Request) {
+	h.estadoFinanciero(w, r, true)
+}
+
+func (h *TesoreriaHandler) estadoFinanciero(w http.ResponseWriter, r *http.Request, publico bool) {
+	year, ok := parseYearParam(w, r)
+	if !ok {
+		return
+	}
+	month := 0
+	if m := r.URL.Query().Get("month"); m != "" {
+		v, err := strconv.Atoi(m)
+		if err != nil || v < 1 || v > 12 {
+			writeError(w, http.StatusBadRequest, "month must be between 1 and 12")
+			return
+		}
+		month = v
+	}
+
+	estado, err := h.service.GetEstadoFinanciero(r.Context(), year, month, publico)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidEstadoFinanciero) {
+			writeError(w, http.StatusBadRequest, "The period has not started yet")
+			return
+		}
+		log.Printf("GetEstadoFinanciero failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to build financial statement")
+		return
+	}
+
+	filename := fmt.Sprintf("estado-financiero-%04d", year)
+	if month > 0 {
+		filename += fmt.Sprintf("-%02d", month)
+	}
+	if publico {
+		filename += "-publico"
+	}
+
+	var buf bytes.Buffer
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		writeJSON(w, http.StatusOK, estado)
+	case "csv":
+		if err := export.EstadoFinancieroCSV(&buf, estado); err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to export financial statement")
+			return
+		}
+		writeFile(w, "text/csv; charset=utf-8", filename+".csv", buf.Bytes())
+	case "xlsx":
+		if err := export.EstadoFinancieroXLSX(&buf, estado); err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to export financial statement")
+			return
+		}
+		writeFile(w, contentTypeXLSX, filename+".xlsx", buf.Bytes())
+	case "pdf":
+		if err := export.EstadoFinancieroPDF(&buf, estado); err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to export financial statement")
+			return
+		}
+		writeFile(w, "application/pdf", filename+".pdf", buf.Bytes())
+	default:
+		writeError(w, http.StatusBadRequest, "format must be json, csv, xlsx or pdf")
+	}
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/internal/services"
+)
+
+func writeFacturaError(w http.ResponseWriter, err error, op string) {
+	switch {
+	case errors.Is(err, services.ErrFacturaNotFound):
+		writeError(w, http.StatusNotFound, "Factura not found")
+	case errors.Is(err, services.ErrProveedorNotFound):
+		writeError(w, http.StatusBadRequest, "Proveedor not found")
+	case errors.Is(err, services.ErrProveedorInactivo):
+		writeError(w, http.StatusBadRequest, "Proveedor is inactive")
+	case errors.Is(err, services.ErrFacturaExists):
+		writeError(w, http.StatusConflict, "This proveedor already has a factura with this numero")
+	case errors.Is(err, services.ErrInvalidFactura):
+		writeError(w, http.StatusBadRequest, "numero, descripcion, a positive monto and fecha_emision (not after fecha_vencimiento) are required")
+	case errors.Is(err, services.ErrCategoriaInvalida):
+		writeError(w, http.StatusBadRequest, "categoria must be an active egreso category")
+	case errors.Is(err, services.ErrFacturaEstado):
+		writeError(w, http.StatusConflict, "The factura is not in a state that allows this")
+	case errors.Is(err, services.ErrFacturaYaFirmada):
+		writeError(w, http.StatusConflict, "You already signed this factura")
+	case errors.Is(err, services.ErrFacturaPropia):
+		writeError(w, http.StatusConflict, "You registered this factura; another directiva member must approve it")
+	case errors.Is(err, services.ErrCuentaInvalida):
+		writeError(w, http.StatusBadRequest, "cuenta_id must be an active cuenta")
+	case errors.Is(err, services.ErrAdjuntoNotFound):
+		writeError(w, http.StatusNotFound, "Attachment not found")
+	default:
+		log.Printf("%s failed: %v", op, err)
+		writeError(w, http.StatusInternalServerError, "Failed to process factura")
+	}
+}
+
+// ListFacturas supports ?proveedor_id=&estado=&vencidas=true&page=&per_page=.
+func (h *ProveedorHandler) ListFacturas(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.FacturaFilter{
+		ProveedorID: q.Get("proveedor_id"),
+		Estado:      models.FacturaEstado(q.Get("estado")),
+		Vencidas:    q.Get("vencidas") == "true",
+	}
+	filter.Page, _ = strconv.Atoi(q.Get("page"))
+	filter.PerPage, _ = strconv.Atoi(q.Get("per_page"))
+
+	facturas, err := h.service.ListFacturas(r.Context(), filter)
+	if err != nil {
+		writeFacturaError(w, err, "ListFacturas")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, facturas)
+}
+
+func (h *ProveedorHandler) GetFactura(w http.ResponseWriter, r *http.Request) {
+	factura, err := h.service.GetFactura(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeFacturaError(w, err, "GetFactura")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, factura)
+}
+
+func (h *ProveedorHandler) CreateFactura(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateFacturaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	factura, err := h.service.CreateFactura(r.Context(), &req, userID)
+	if err != nil {
+		writeFacturaError(w, err, "CreateFactura")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, factura)
+}
+
+func (h *ProveedorHandler) UpdateFactura(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateFacturaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	factura, err := h.service.UpdateFactura(r.Context(), chi.URLParam(r, "id"), &req, userID)
+	if err != nil {
+		writeFacturaError(w, err, "UpdateFactura")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, factura)
+}
+
+func (h *ProveedorHandler) AprobarFactura(w http.ResponseWriter, r *http.Request) {
+	var req models.AprobarFacturaRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	factura, err := h.service.AprobarFactura(r.Context(), chi.URLParam(r, "id"), req.Comentario, userID)
+	if err != nil {
+		writeFacturaError(w, err, "AprobarFactura")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, factura)
+}
+
+func (h *ProveedorHandler) RechazarFactura(w http.ResponseWriter, r *http.Request) {
+	var req models.RechazarFacturaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Motivo) == "" {
+		writeError(w, http.StatusBadRequest, "motivo is required")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	factura, err := h.service.RechazarFactura(r.Context(), chi.URLParam(r, "id"), req.Motivo, userID)
+	if err != nil {
+		writeFacturaError(w, err, "RechazarFactura")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, factura)
+}
+
+func (h *ProveedorHandler) AnularFactura(w http.ResponseWriter, r *http.Request) {
+	var req models.AnularMovimientoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Motivo) == "" {
+		writeError(w, http.StatusBadRequest, "motivo is required")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	factura, err := h.service.AnularFactura(r.Context(), chi.URLParam(r, "id"), req.Motivo, userID)
+	if err != nil {
+		writeFacturaError(w, err, "AnularFactura")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, factura)
+}
+
+// PagarFactura books the egreso of an approved factura in tesorería.
+func (h *ProveedorHandler) PagarFactura(w http.ResponseWriter, r *http.Request) {
+	var req models.PagarFacturaRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	factura, err := h.service.PagarFactura(r.Context(), chi.URLParam(r, "id"), &req, userID)
+	if err != nil {
+		writeFacturaError(w, err, "PagarFactura")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, factura)
+}
+
+// AddAdjunto uploads the invoice document (multipart archivo field).
+func (h *ProveedorHandler) AddAdjunto(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	data, nombre, contentType, ok := leerArchivoAdjunto(w, r)
+	if !ok {
+		return
+	}
+
+	adjunto, err := h.service.AddAdjunto(r.Context(), chi.URLParam(r, "id"), &models.AdjuntoFactura{
+		NombreArchivo: nombre,
+		ContentType:   contentType,
+		Contenido:     data,
+	}, userID)
+	if err != nil {
+		writeFacturaError(w, err, "AddAdjunto factura")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, adjunto)
+}
+
+func (h *ProveedorHandler) GetAdjunto(w http.ResponseWriter, r *http.Request) {
+	adjunto, err := h.service.GetAdjunto(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "adjuntoId"))
+	if err != nil {
+		writeFacturaError(w, err, "GetAdjunto factura")
+		return
+	}
+
+	writeFile(w, adjunto.ContentType, adjunto.NombreArchivo, adjunto.Contenido)
+}
+
+// ============================================
+// CADENA DE APROBACION
+// ============================================
+
+func (h *ProveedorHandler) GetReglasAprobacion(w http.ResponseWriter, r *http.Request) {
+	reglas, err := h.service.GetReglasAprobacion(r.Context())
+	if err != nil {
+		log.Printf("GetReglasAprobacion failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to get approval rules")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, reglas)
+}
+
+func (h *ProveedorHandler) SetReglasAprobacion(w http.ResponseWriter, r *http.Request) {
+	var req models.SetReglasAprobacionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	reglas, err := h.service.SetReglasAprobacion(r.Context(), &req, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidReglaAprobacion) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("SetReglasAprobacion failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to update approval rules")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, reglas)
+}
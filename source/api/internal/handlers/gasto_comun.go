@@ -1,24 +1,28 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/condominio/backend/internal/export"
 	"github.com/condominio/backend/internal/models"
 	"github.com/condominio/backend/internal/services"
 )
 
 type GastoComunHandler struct {
-	service *services.GastoComunService
+	service       *services.GastoComunService
+	recordatorios *services.RecordatorioService
 }
 
-func NewGastoComunHandler(service *services.GastoComunService) *GastoComunHandler {
-	return &GastoComunHandler{service: service}
+func NewGastoComunHandler(service *services.GastoComunService, recordatorios *services.RecordatorioService) *GastoComunHandler {
+	return &GastoComunHandler{service: service, recordatorios: recordatorios}
 }
 
 // ============================================
@@ -46,6 +50,10 @@ func (h *GastoComunHandler) ListPeriodos(w http.ResponseWriter, r *http.Request)
 			filter.Year = y
 		}
 	}
+	// Draft periodos are only visible to the directiva
+	if role, _ := r.Context().Value("user_role").(string); role == "directiva" {
+		filter.IncluirBorradores = true
+	}
 
 	resp, err := h.service.ListPeriodos(r.Context(), filter)
 	if err != nil {
@@ -69,6 +77,10 @@ func (h *GastoComunHandler) GetPeriodo(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, "Failed to get periodo")
 		return
 	}
+	if role, _ := r.Context().Value("user_role").(string); periodo.Estado == models.PeriodoBorrador && role != "directiva" {
+		writeError(w, http.StatusNotFound, "Periodo not found")
+		return
+	}
 
 	writeJSON(w, http.StatusOK, periodo)
 }
@@ -102,8 +114,8 @@ func (h *GastoComunHandler) CreatePeriodo(w http.ResponseWriter, r *http.Request
 		writeError(w, http.StatusBadRequest, "Invalid month")
 		return
 	}
-	if req.MontoBase <= 0 {
-		writeError(w, http.StatusBadRequest, "monto_base must be positive")
+	if req.MontoBase < 0 || req.MontoBaseUF < 0 || (req.MontoBase > 0) == (req.MontoBaseUF > 0) {
+		writeError(w, http.StatusBadRequest, "Exactly one of monto_base or monto_base_uf must be positive")
 		return
 	}
 	if req.FechaVencimiento == "" {
@@ -113,13 +125,19 @@ func (h *GastoComunHandler) CreatePeriodo(w http.ResponseWriter, r *http.Request
 
 	periodo, err := h.service.CreatePeriodo(r.Context(), &req)
 	if err != nil {
-		if errors.Is(err, services.ErrPeriodoExists) {
+		switch {
+		case errors.Is(err, services.ErrPeriodoExists):
 			writeError(w, http.StatusConflict, "Periodo already exists for this year/month")
-			return
+		case errors.Is(err, services.ErrValorUFNoDisponible):
+			writeError(w, http.StatusConflict, err.Error())
+		default:
+			writeError(w, http.StatusInternalServerError, err.Error())
 		}
-		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	if periodo.Estado == models.PeriodoAbierto {
+		h.notificarApertura(r, periodo.ID)
+	}
 
 	writeJSON(w, http.StatusCreated, periodo)
 }
@@ -135,11 +153,20 @@ func (h *GastoComunHandler) UpdatePeriodo(w http.ResponseWriter, r *http.Request
 
 	periodo, err := h.service.UpdatePeriodo(r.Context(), id, &req)
 	if err != nil {
-		if errors.Is(err, services.ErrPeriodoNotFound) {
+		switch {
+		case errors.Is(err, services.ErrPeriodoNotFound):
 			writeError(w, http.StatusNotFound, "Periodo not found")
-			return
+		case errors.Is(err, services.ErrPeriodoCerrado):
+			writeError(w, http.StatusConflict, "Periodo is closed")
+		case errors.Is(err, services.ErrPeriodoMontoCongelado):
+			writeError(w, http.StatusConflict, "monto_base cannot change once the periodo is open")
+		case errors.Is(err, services.ErrInvalidValorUF):
+			writeError(w, http.StatusBadRequest, "monto_base_uf must be positive")
+		case errors.Is(err, services.ErrValorUFNoDisponible):
+			writeError(w, http.StatusConflict, err.Error())
+		default:
+			writeError(w, http.StatusInternalServerError, err.Error())
 		}
-		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
@@ -248,8 +275,19 @@ func (h *GastoComunHandler) RegistrarPago(w http.ResponseWriter, r *http.Request
 		writeError(w, http.StatusBadRequest, "metodo is required")
 		return
 	}
+	if req.Metodo == models.MetodoPagoCredito {
+		writeError(w, http.StatusBadRequest, "Use aplicar-credito to pay with credit balance")
+		return
+	}
 
-	gasto, err := h.service.RegistrarPago(r.Context(), id, &req)
+	// Optional: lets clients retry a payment without double-charging
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if len(idempotencyKey) > 255 {
+		writeError(w, http.StatusBadRequest, "Idempotency-Key is too long")
+		return
+	}
+
+	gasto, err := h.service.RegistrarPago(r.Context(), id, &req, idempotencyKey)
 	if err != nil {
 		switch {
 		case errors.Is(err, services.ErrGastoComunNotFound):
@@ -258,7 +296,12 @@ func (h *GastoComunHandler) RegistrarPago(w http.ResponseWriter, r *http.Request
 			writeError(w, http.StatusBadRequest, "Gasto already paid")
 		case errors.Is(err, services.ErrInvalidPaymentAmount):
 			writeError(w, http.StatusBadRequest, "Invalid payment amount")
+		case errors.Is(err, services.ErrIdempotencyKeyReused):
+			writeError(w, http.StatusConflict, "Idempotency-Key already used for another gasto")
+		case errors.Is(err, services.ErrPeriodoCerrado):
+			writeError(w, http.StatusConflict, "Periodo is closed; payments for it go through a convenio")
 		default:
+			log.Printf("RegistrarPago failed: %v", err)
 			writeError(w, http.StatusInternalServerError, "Failed to register payment")
 		}
 		return
@@ -267,6 +310,350 @@ func (h *GastoComunHandler) RegistrarPago(w http.ResponseWriter, r *http.Request
 	writeJSON(w, http.StatusOK, gasto)
 }
 
+func (h *GastoComunHandler) ListPagos(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if _, err := h.service.GetGasto(r.Context(), id); err != nil {
+		writeError(w, http.StatusNotFound, "Gasto not found")
+		return
+	}
+
+	pagos, err := h.service.ListPagos(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to list pagos")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pagos": pagos,
+		"total": len(pagos),
+	})
+}
+
+func (h *GastoComunHandler) ReversarPago(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req models.ReversarPagoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Motivo == "" {
+		writeError(w, http.StatusBadRequest, "motivo is required")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	gasto, err := h.service.ReversarPago(r.Context(), id, req.Motivo, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPagoNotFound):
+			writeError(w, http.StatusNotFound, "Pago not found")
+		case errors.Is(err, services.ErrPagoNotReversible):
+			writeError(w, http.StatusBadRequest, "Only approved pagos can be reversed")
+		case errors.Is(err, services.ErrCreditoYaAplicado):
+			writeError(w, http.StatusConflict, "Overpayment credit was already applied; reverse those pagos first")
+		default:
+			log.Printf("ReversarPago failed: %v", err)
+			writeError(w, http.StatusInternalServerError, "Failed to reverse pago")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, gasto)
+}
+
+func (h *GastoComunHandler) AplicarCredito(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	userID := r.Context().Value("user_id").(string)
+
+	gasto, err := h.service.AplicarCredito(r.Context(), id, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrGastoComunNotFound):
+			writeError(w, http.StatusNotFound, "Gasto not found")
+		case errors.Is(err, services.ErrGastoAlreadyPaid):
+			writeError(w, http.StatusBadRequest, "Gasto already paid")
+		case errors.Is(err, services.ErrCreditoInsuficiente):
+			writeError(w, http.StatusBadRequest, "Parcela has no credit balance")
+		case errors.Is(err, services.ErrPeriodoCerrado):
+			writeError(w, http.StatusConflict, "Periodo is closed")
+		default:
+			log.Printf("AplicarCredito failed: %v", err)
+			writeError(w, http.StatusInternalServerError, "Failed to apply credit")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, gasto)
+}
+
+func (h *GastoComunHandler) GetSaldoCredito(w http.ResponseWriter, r *http.Request) {
+	parcelaID, err := strconv.Atoi(chi.URLParam(r, "parcelaId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid parcela id")
+		return
+	}
+
+	saldo, err := h.service.GetSaldoCredito(r.Context(), parcelaID)
+	if err != nil {
+		if errors.Is(err, services.ErrParcelaNotFound) {
+			writeError(w, http.StatusNotFound, "Parcela not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to get credit balance")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, saldo)
+}
+
+func (h *GastoComunHandler) ReembolsarCredito(w http.ResponseWriter, r *http.Request) {
+	parcelaID, err := strconv.Atoi(chi.URLParam(r, "parcelaId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid parcela id")
+		return
+	}
+
+	var req models.ReembolsoCreditoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Monto <= 0 {
+		writeError(w, http.StatusBadRequest, "monto must be positive")
+		return
+	}
+	if req.Metodo == "" {
+		writeError(w, http.StatusBadRequest, "metodo is required")
+		return
+	}
+	if req.Motivo == "" {
+		writeError(w, http.StatusBadRequest, "motivo is required")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	saldo, err := h.service.ReembolsarCredito(r.Context(), parcelaID, &req, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrParcelaNotFound):
+			writeError(w, http.StatusNotFound, "Parcela not found")
+		case errors.Is(err, services.ErrCreditoInsuficiente):
+			writeError(w, http.StatusBadRequest, "Refund exceeds credit balance")
+		default:
+			log.Printf("ReembolsarCredito failed: %v", err)
+			writeError(w, http.StatusInternalServerError, "Failed to refund credit")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, saldo)
+}
+
+// ============================================
+// CUENTA CORRIENTE
+// ============================================
+
+func (h *GastoComunHandler) GetCuentaCorriente(w http.ResponseWriter, r *http.Request) {
+	parcelaID, err := strconv.Atoi(chi.URLParam(r, "parcelaId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid parcela id")
+		return
+	}
+
+	desde, hasta, ok := parseRangoFechas(w, r)
+	if !ok {
+		return
+	}
+
+	cc, err := h.service.GetCuentaCorriente(r.Context(), models.CuentaCorrienteFilter{
+		ParcelaID: parcelaID,
+		Desde:     desde,
+		Hasta:     hasta,
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrParcelaNotFound) {
+			writeError(w, http.StatusNotFound, "Parcela not found")
+			return
+		}
+		log.Printf("GetCuentaCorriente failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to get cuenta corriente")
+		return
+	}
+
+	writeCuentaCorriente(w, r, cc)
+}
+
+func (h *GastoComunHandler) GetMiCuentaCorriente(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	desde, hasta, ok := parseRangoFechas(w, r)
+	if !ok {
+		return
+	}
+
+	cc, err := h.service.GetMiCuentaCorriente(r.Context(), userID, desde, hasta)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNoParcela) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("GetMiCuentaCorriente failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to get cuenta corriente")
+		return
+	}
+
+	writeCuentaCorriente(w, r, cc)
+}
+
+// writeCuentaCorriente renders the ledger in the format requested with
+// ?format=json|csv|pdf (default json).
+func writeCuentaCorriente(w http.ResponseWriter, r *http.Request, cc *models.CuentaCorriente) {
+	filename := "cuenta-corriente-parcela-" + cc.ParcelaNumero
+
+	var buf bytes.Buffer
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		writeJSON(w, http.StatusOK, cc)
+	case "csv":
+		if err := export.CuentaCorrienteCSV(&buf, cc); err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to export cuenta corriente")
+			return
+		}
+		writeFile(w, "text/csv; charset=utf-8", filename+".csv", buf.Bytes())
+	case "pdf":
+		if err := export.CuentaCorrientePDF(&buf, cc); err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to export cuenta corriente")
+			return
+		}
+		writeFile(w, "application/pdf", filename+".pdf", buf.Bytes())
+	default:
+		writeError(w, http.StatusBadRequest, "format must be json, csv or pdf")
+	}
+}
+
+// parseRangoFechas reads the optional desde/hasta (YYYY-MM-DD) query params.
+// It writes the error response itself and returns ok=false on bad input.
+func parseRangoFechas(w http.ResponseWriter, r *http.Request) (desde, hasta *time.Time, ok bool) {
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"desde", &desde}, {"hasta", &hasta}} {
+		v := r.URL.Query().Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid "+p.name+" date, expected YYYY-MM-DD")
+			return nil, nil, false
+		}
+		*p.dst = &t
+	}
+	if desde != nil && hasta != nil && hasta.Before(*desde) {
+		writeError(w, http.StatusBadRequest, "hasta must not be before desde")
+		return nil, nil, false
+	}
+	return desde, hasta, true
+}
+
+func (h *GastoComunHandler) CreateCargo(w http.ResponseWriter, r *http.Request) {
+	parcelaID, err := strconv.Atoi(chi.URLParam(r, "parcelaId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid parcela id")
+		return
+	}
+
+	var req models.CreateCargoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if !req.Tipo.IsValid() {
+		writeError(w, http.StatusBadRequest, "tipo must be interes, multa or ajuste")
+		return
+	}
+	if req.Monto == 0 {
+		writeError(w, http.StatusBadRequest, "monto is required")
+		return
+	}
+	if req.Monto < 0 && req.Tipo != models.CargoAjuste {
+		writeError(w, http.StatusBadRequest, "Only ajuste cargos can be negative")
+		return
+	}
+	if req.Fecha == "" {
+		writeError(w, http.StatusBadRequest, "fecha is required")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	cargo, err := h.service.CreateCargo(r.Context(), parcelaID, &req, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCargo):
+			writeError(w, http.StatusBadRequest, "Invalid cargo")
+		case errors.Is(err, services.ErrInteresesCongelados):
+			writeError(w, http.StatusConflict, "Interest is frozen by an active convenio")
+		case errors.Is(err, services.ErrParcelaNotFound):
+			writeError(w, http.StatusNotFound, "Parcela not found")
+		case errors.Is(err, services.ErrCargoGasto):
+			writeError(w, http.StatusBadRequest, "gasto_comun_id is not a gasto of this parcela")
+		case errors.Is(err, services.ErrInvalidFecha):
+			writeError(w, http.StatusBadRequest, "Invalid fecha format, expected YYYY-MM-DD")
+		default:
+			log.Printf("CreateCargo failed: %v", err)
+			writeError(w, http.StatusInternalServerError, "Failed to create cargo")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, cargo)
+}
+
+func (h *GastoComunHandler) SetSaldoInicial(w http.ResponseWriter, r *http.Request) {
+	parcelaID, err := strconv.Atoi(chi.URLParam(r, "parcelaId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid parcela id")
+		return
+	}
+
+	var req models.SaldoInicialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Fecha == "" {
+		writeError(w, http.StatusBadRequest, "fecha is required")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	saldo, err := h.service.SetSaldoInicial(r.Context(), parcelaID, &req, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrParcelaNotFound):
+			writeError(w, http.StatusNotFound, "Parcela not found")
+		case errors.Is(err, services.ErrInvalidFecha):
+			writeError(w, http.StatusBadRequest, "Invalid fecha format, expected YYYY-MM-DD")
+		default:
+			log.Printf("SetSaldoInicial failed: %v", err)
+			writeError(w, http.StatusInternalServerError, "Failed to set saldo inicial")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, saldo)
+}
+
 func (h *GastoComunHandler) MarcarVencidos(w http.ResponseWriter, r *http.Request) {
 	count, err := h.service.MarcarVencidos(r.Context())
 	if err != nil {
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/internal/services"
+)
+
+// Bank exports are small text files; anything larger is not one.
+const maxArchivoBanco = 5 << 20
+
+type ImportacionBancoHandler struct {
+	service *services.ImportacionBancoService
+}
+
+func NewImportacionBancoHandler(service *services.ImportacionBancoService) *ImportacionBancoHandler {
+	return &ImportacionBancoHandler{service: service}
+}
+
+// ============================================
+// FORMATOS CSV
+// ============================================
+
+func (h *ImportacionBancoHandler) ListFormatos(w http.ResponseWriter, r *http.Request) {
+	formatos, err := h.service.ListFormatos(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to list import formats")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, formatos)
+}
+
+func (h *ImportacionBancoHandler) CreateFormato(w http.ResponseWriter, r *http.Request) {
+	var req models.FormatoImportacionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	formato, err := h.service.CreateFormato(r.Context(), &req)
+	if err != nil {
+		writeFormatoError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, formato)
+}
+
+func (h *ImportacionBancoHandler) UpdateFormato(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req models.FormatoImportacionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	formato, err := h.service.UpdateFormato(r.Context(), id, &req)
+	if err != nil {
+		writeFormatoError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, formato)
+}
+
+func (h *ImportacionBancoHandler) DeleteFormato(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if err := h.service.DeleteFormato(r.Context(), id); err != nil {
+		writeFormatoError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Import format deleted"})
+}
+
+func writeFormatoError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrFormatoNotFound):
+		writeError(w, http.StatusNotFound, "Import format not found")
+	case errors.Is(err, services.ErrFormatoExists):
+		writeError(w, http.StatusConflict, "An import format with that name already exists")
+	case errors.Is(err, services.ErrInvalidFormato):
+		writeError(w, http.StatusBadRequest, "Invalid format: nombre, columna_fecha and columna_monto are required, separador is one character, formato_fecha like DD/MM/YYYY, separador_decimal , or . and columns are 1-based positions when there is no header")
+	default:
+		log.Printf("Import format operation failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to save import format")
+	}
+}
+
+// ============================================
+// IMPORTACIONES
+// ============================================
+
+func (h *ImportacionBancoHandler) List(w http.ResponseWriter, r *http.Request) {
+	filter := models.ImportacionFilter{
+		Page:    1,
+		PerPage: 20,
+	}
+
+	if page := r.URL.Query().Get("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil {
+			filter.Page = p
+		}
+	}
+	if perPage := r.URL.Query().Get("per_page"); perPage != "" {
+		if pp, err := strconv.Atoi(perPage); err == nil {
+			filter.PerPage = pp
+		}
+	}
+	if estado := r.URL.Query().Get("estado"); estado != "" {
+		filter.Estado = models.ImportacionEstado(estado)
+	}
+
+	resp, err := h.service.ListImportaciones(r.Context(), filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to list imports")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *ImportacionBancoHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	imp, err := h.service.GetImportacion(r.Context(), id)
+	if err != nil {
+		writeImportacionError(w, err, "GetImportacion")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, imp)
+}
+
+// Create stages a bank CSV sent as multipart/form-data with fields archivo
+// and formato_id.
+func (h *ImportacionBancoHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxArchivoBanco)
+	if err := r.ParseMultipartForm(maxArchivoBanco); err != nil {
+		writeError(w, http.StatusBadRequest, "Expected multipart form with archivo (max 5 MB) and formato_id")
+		return
+	}
+
+	formatoID := r.FormValue("formato_id")
+	if formatoID == "" {
+		writeError(w, http.StatusBadRequest, "formato_id is required")
+		return
+	}
+	file, header, err := r.FormFile("archivo")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "archivo is required")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Failed to read archivo")
+		return
+	}
+
+	imp, err := h.service.CreateImportacion(r.Context(), formatoID, header.Filename, data, userID)
+	if err != nil {
+		writeImportacionError(w, err, "CreateImportacion")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, imp)
+}
+
+func (h *ImportacionBancoHandler) UpdateFila(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	filaID := chi.URLParam(r, "filaId")
+
+	var req models.UpdateFilaImportacionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	imp, err := h.service.UpdateFila(r.Context(), id, filaID, &req)
+	if err != nil {
+		writeImportacionError(w, err, "UpdateFila")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, imp)
+}
+
+// Confirmar registers the pagos of every pending row in one transaction.
+func (h *ImportacionBancoHandler) Confirmar(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	userID := r.Context().Value("user_id").(string)
+
+	imp, err := h.service.ConfirmarImportacion(r.Context(), id, userID)
+	if err != nil {
+		writeImportacionError(w, err, "ConfirmarImportacion")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, imp)
+}
+
+func (h *ImportacionBancoHandler) Descartar(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	imp, err := h.service.DescartarImportacion(r.Context(), id)
+	if err != nil {
+		writeImportacionError(w, err, "DescartarImportacion")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, imp)
+}
+
+func writeImportacionError(w http.ResponseWriter, err error, op string) {
+	switch {
+	case errors.Is(err, services.ErrImportacionNotFound):
+		writeError(w, http.StatusNotFound, "Import not found")
+	case errors.Is(err, services.ErrFilaNotFound):
+		writeError(w, http.StatusNotFound, "Import row not found")
+	case errors.Is(err, services.ErrFormatoNotFound):
+		writeError(w, http.StatusNotFound, "Import format not found")
+	case errors.Is(err, services.ErrParcelaNotFound):
+		writeError(w, http.StatusNotFound, "Parcela not found")
+	case errors.Is(err, services.ErrArchivoInvalido):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrFilaGastoInvalido):
+		writeError(w, http.StatusBadRequest, "Gasto does not belong to the parcela, has no balance or its periodo is not open")
+	case errors.Is(err, services.ErrImportacionNoStaging),
+		errors.Is(err, services.ErrFilaNoEditable),
+		errors.Is(err, services.ErrImportacionIncompleta),
+		errors.Is(err, services.ErrFilaSinGasto),
+		errors.Is(err, services.ErrFilaYaImportada),
+		errors.Is(err, services.ErrPeriodoCerrado),
+		errors.Is(err, services.ErrGastoAlreadyPaid):
+		// The message names the offending line
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		log.Printf("%s failed: %v", op, err)
+		writeError(w, http.StatusInternalServerError, "Failed to process import")
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/condominio/backend/internal/export"
+	"github.com/condominio/backend/internal/models"
+)
+
+const contentTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// ============================================
+// MOROSIDAD
+// ============================================
+
+func (h *GastoComunHandler) GetReporteMorosidad(w http.ResponseWriter, r *http.Request) {
+	filter := models.MorosidadFilter{
+		Sort:  r.URL.Query().Get("sort"),
+		Order: r.URL.Query().Get("order"),
+	}
+
+	reporte, err := h.service.GetReporteMorosidad(r.Context(), filter)
+	if err != nil {
+		log.Printf("GetReporteMorosidad failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to get morosidad report")
+		return
+	}
+
+	filename := "morosidad-" + time.Now().Format("2006-01-02")
+
+	var buf bytes.Buffer
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		writeJSON(w, http.StatusOK, reporte)
+	case "csv":
+		if err := export.MorosidadCSV(&buf, reporte); err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to export morosidad report")
+			return
+		}
+		writeFile(w, "text/csv; charset=utf-8", filename+".csv", buf.Bytes())
+	case "xlsx":
+		if err := export.MorosidadXLSX(&buf, reporte); err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to export morosidad report")
+			return
+		}
+		writeFile(w, contentTypeXLSX, filename+".xlsx", buf.Bytes())
+	default:
+		writeError(w, http.StatusBadRequest, "format must be json, csv or xlsx")
+	}
+}
+
+func (h *GastoComunHandler) GetTendenciaMorosidad(w http.ResponseWriter, r *http.Request) {
+	meses := 12
+	if m := r.URL.Query().Get("meses"); m != "" {
+		v, err := strconv.Atoi(m)
+		if err != nil || v < 1 || v > 120 {
+			writeError(w, http.StatusBadRequest, "meses must be between 1 and 120")
+			return
+		}
+		meses = v
+	}
+
+	tendencia, err := h.service.GetTendenciaMorosidad(r.Context(), meses)
+	if err != nil {
+		log.Printf("GetTendenciaMorosidad failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to get morosidad trend")
+		return
+	}
+
+	filename := "tendencia-morosidad-" + time.Now().Format("2006-01-02")
+
+	var buf bytes.Buffer
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"meses": tendencia,
+			"total": len(tendencia),
+		})
+	case "csv":
+		if err := export.TendenciaMorosidadCSV(&buf, tendencia); err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to export morosidad trend")
+			return
+		}
+		writeFile(w, "text/csv; charset=utf-8", filename+".csv", buf.Bytes())
+	case "xlsx":
+		if err := export.TendenciaMorosidadXLSX(&buf, tendencia); err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to export morosidad trend")
+			return
+		}
+		writeFile(w, contentTypeXLSX, filename+".xlsx", buf.Bytes())
+	default:
+		writeError(w, http.StatusBadRequest, "format must be json, csv or xlsx")
+	}
+}
@@ -274,3 +274,35 @@ func (h *NotificacionHandler) CreateBroadcast(w http.ResponseWriter, r *http.Req
 		"count":   count,
 	})
 }
+
+// GetPreferencias returns the user's gasto reminder preferences
+func (h *NotificacionHandler) GetPreferencias(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	prefs, err := h.service.GetPreferencias(r.Context(), userID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to get preferences")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, prefs)
+}
+
+// UpdatePreferencias changes the user's gasto reminder preferences
+func (h *NotificacionHandler) UpdatePreferencias(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	var req models.UpdatePreferenciasRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	prefs, err := h.service.UpdatePreferencias(r.Context(), userID, &req)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to update preferences")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, prefs)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/internal/services"
+)
+
+// ============================================
+// RECURRENCIA DE PERIODOS
+// ============================================
+
+func (h *GastoComunHandler) GetConfigPeriodos(w http.ResponseWriter, r *http.Request) {
+	cfg, err := h.service.GetConfigPeriodos(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to get recurrence settings")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cfg)
+}
+
+func (h *GastoComunHandler) UpdateConfigPeriodos(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateConfigPeriodosRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	cfg, err := h.service.UpdateConfigPeriodos(r.Context(), &req, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidConfigPeriodos) {
+			writeError(w, http.StatusBadRequest, "Invalid settings: modo must be monto, presupuesto or uf with a positive amount, dia_vencimiento 1-31, ajuste_dia_habil ninguno|siguiente|anterior, dias_anticipacion 0-28")
+			return
+		}
+		log.Printf("UpdateConfigPeriodos failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to update recurrence settings")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cfg)
+}
+
+// GenerarPeriodo runs the monthly generation now instead of waiting for the
+// scheduler. The same lead-time rule applies.
+func (h *GastoComunHandler) GenerarPeriodo(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.GenerarPeriodoSiguiente(r.Context())
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidConfigPeriodos) {
+			writeError(w, http.StatusBadRequest, "Recurrence settings produce no amount to charge")
+			return
+		}
+		if errors.Is(err, services.ErrValorUFNoDisponible) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		log.Printf("GenerarPeriodoSiguiente failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to generate periodo")
+		return
+	}
+
+	status := http.StatusOK
+	if result.Generado {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, result)
+}
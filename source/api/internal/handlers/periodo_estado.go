@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/internal/services"
+)
+
+// ============================================
+// CICLO DE VIDA DE PERIODOS
+// ============================================
+
+func writePeriodoEstadoError(w http.ResponseWriter, err error, op string) {
+	switch {
+	case errors.Is(err, services.ErrPeriodoNotFound):
+		writeError(w, http.StatusNotFound, "Periodo not found")
+	case errors.Is(err, services.ErrPeriodoNoBorrador):
+		writeError(w, http.StatusConflict, "Periodo is not a draft")
+	case errors.Is(err, services.ErrPeriodoNoAbierto):
+		writeError(w, http.StatusConflict, "Periodo is not open")
+	case errors.Is(err, services.ErrPeriodoConDeuda):
+		writeError(w, http.StatusConflict, "Periodo has gastos with pending balance; settle them or move them to a convenio first")
+	case errors.Is(err, services.ErrInvalidConfigPeriodos):
+		writeError(w, http.StatusBadRequest, "Recurrence settings produce no amount to charge")
+	case errors.Is(err, services.ErrValorUFNoDisponible):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		log.Printf("%s failed: %v", op, err)
+		writeError(w, http.StatusInternalServerError, "Failed to update periodo")
+	}
+}
+
+// notificarApertura tells residents about a periodo that just opened.
+// Failures are only logged: the periodo is open either way and the scheduler
+// picks up any notice left unsent.
+func (h *GastoComunHandler) notificarApertura(r *http.Request, periodoID string) {
+	if h.recordatorios == nil {
+		return
+	}
+	if _, err := h.recordatorios.NotificarEmision(r.Context(), periodoID); err != nil {
+		log.Printf("NotificarEmision %s failed: %v", periodoID, err)
+	}
+}
+
+// AbrirPeriodo opens a draft: gastos are generated, amounts are frozen and
+// residents are notified.
+func (h *GastoComunHandler) AbrirPeriodo(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	userID := r.Context().Value("user_id").(string)
+
+	periodo, err := h.service.AbrirPeriodo(r.Context(), id, userID)
+	if err != nil {
+		writePeriodoEstadoError(w, err, "AbrirPeriodo")
+		return
+	}
+	h.notificarApertura(r, id)
+
+	writeJSON(w, http.StatusOK, periodo)
+}
+
+// RegenerarPeriodo recomputes a draft from the recurrence settings.
+func (h *GastoComunHandler) RegenerarPeriodo(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	userID := r.Context().Value("user_id").(string)
+
+	periodo, err := h.service.RegenerarPeriodo(r.Context(), id, userID)
+	if err != nil {
+		writePeriodoEstadoError(w, err, "RegenerarPeriodo")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, periodo)
+}
+
+func (h *GastoComunHandler) DeletePeriodo(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	userID := r.Context().Value("user_id").(string)
+
+	if err := h.service.DeletePeriodo(r.Context(), id, userID); err != nil {
+		writePeriodoEstadoError(w, err, "DeletePeriodo")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Periodo deleted"})
+}
+
+// CerrarPeriodo locks an open periodo; afterwards its gastos and pagos only
+// change through reversals and convenio payments.
+func (h *GastoComunHandler) CerrarPeriodo(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	userID := r.Context().Value("user_id").(string)
+
+	var req models.CerrarPeriodoRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	periodo, err := h.service.CerrarPeriodo(r.Context(), id, req.Motivo, userID)
+	if err != nil {
+		writePeriodoEstadoError(w, err, "CerrarPeriodo")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, periodo)
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/internal/services"
+)
+
+func writePoderError(w http.ResponseWriter, err error, op string) {
+	switch {
+	case errors.Is(err, services.ErrPoderNotFound):
+		writeError(w, http.StatusNotFound, "Poder not found")
+	case errors.Is(err, services.ErrVotacionNotFound):
+		writeError(w, http.StatusNotFound, "Votacion not found")
+	case errors.Is(err, services.ErrParcelaNotFound):
+		writeError(w, http.StatusNotFound, "Parcela not found")
+	case errors.Is(err, services.ErrPoderAcceso):
+		writeError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrUserNoParcela):
+		writeError(w, http.StatusForbidden, "Para otorgar un poder debe tener una parcela asociada")
+	case errors.Is(err, services.ErrInvalidPoder),
+		errors.Is(err, services.ErrInvalidVigenciaPoder),
+		errors.Is(err, services.ErrInvalidEvento),
+		errors.Is(err, services.ErrApoderadoInvalido),
+		errors.Is(err, services.ErrVotacionNotActive):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrPoderDuplicado),
+		errors.Is(err, services.ErrLimitePoderes),
+		errors.Is(err, services.ErrPoderNoPendiente),
+		errors.Is(err, services.ErrPoderSinDocumento),
+		errors.Is(err, services.ErrPoderUsado):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		log.Printf("%s failed: %v", op, err)
+		writeError(w, http.StatusInternalServerError, "Failed to process poder")
+	}
+}
+
+// esDirectiva tells whether the caller manages poderes of any parcela.
+func esDirectiva(r *http.Request) bool {
+	role, _ := r.Context().Value("user_role").(string)
+	return role == "directiva" || role == "admin"
+}
+
+// ListPoderes lists poderes for the directiva: ?estado=&votacion_id=&evento_id=.
+func (h *VotacionHandler) ListPoderes(w http.ResponseWriter, r *http.Request) {
+	filter := models.PoderFilter{
+		Estado:     models.PoderEstado(r.URL.Query().Get("estado")),
+		VotacionID: r.URL.Query().Get("votacion_id"),
+		EventoID:   r.URL.Query().Get("evento_id"),
+	}
+	if filter.Estado != "" && !filter.Estado.IsValid() {
+		writeError(w, http.StatusBadRequest, "estado must be pendiente, validado, rechazado or revocado")
+		return
+	}
+
+	poderes, err := h.service.ListPoderes(r.Context(), filter)
+	if err != nil {
+		writePoderError(w, err, "ListPoderes")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, poderes)
+}
+
+// ListMisPoderes lists the poderes granted by the user's parcela and those the
+// user holds.
+func (h *VotacionHandler) ListMisPoderes(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	poderes, err := h.service.ListMisPoderes(r.Context(), userID)
+	if err != nil {
+		writePoderError(w, err, "ListMisPoderes")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, poderes)
+}
+
+func (h *VotacionHandler) GetPoder(w http.ResponseWriter, r *http.Request) {
+	poder, err := h.service.GetPoder(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writePoderError(w, err, "GetPoder")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, poder)
+}
+
+func (h *VotacionHandler) CreatePoder(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	var req models.CreatePoderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.ApoderadoID == "" {
+		writeError(w, http.StatusBadRequest, "apoderado_id is required")
+		return
+	}
+	if req.ValidoHasta.IsZero() {
+		writeError(w, http.StatusBadRequest, "valido_hasta is required")
+		return
+	}
+
+	poder, err := h.service.CreatePoder(r.Context(), &req, userID, esDirectiva(r))
+	if err != nil {
+		writePoderError(w, err, "CreatePoder")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, poder)
+}
+
+// SetDocumentoPoder uploads the signed poder as multipart/form-data in the
+// archivo field (PDF, JPEG, PNG or WebP).
+func (h *VotacionHandler) SetDocumentoPoder(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	data, nombre, contentType, ok := leerArchivoAdjunto(w, r)
+	if !ok {
+		return
+	}
+
+	poder, err := h.service.SetDocumentoPoder(r.Context(), chi.URLParam(r, "id"), &models.DocumentoPoder{
+		NombreArchivo: nombre,
+		ContentType:   contentType,
+		Contenido:     data,
+	}, userID, esDirectiva(r))
+	if err != nil {
+		writePoderError(w, err, "SetDocumentoPoder")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, poder)
+}
+
+func (h *VotacionHandler) GetDocumentoPoder(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	doc, err := h.service.GetDocumentoPoder(r.Context(), chi.URLParam(r, "id"), userID, esDirectiva(r))
+	if err != nil {
+		writePoderError(w, err, "GetDocumentoPoder")
+		return
+	}
+
+	writeFile(w, doc.ContentType, doc.NombreArchivo, doc.Contenido)
+}
+
+func (h *VotacionHandler) ValidarPoder(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	poder, err := h.service.ValidarPoder(r.Context(), chi.URLParam(r, "id"), userID)
+	if err != nil {
+		writePoderError(w, err, "ValidarPoder")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, poder)
+}
+
+func (h *VotacionHandler) RechazarPoder(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	var req models.RechazarPoderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Motivo) == "" {
+		writeError(w, http.StatusBadRequest, "motivo is required")
+		return
+	}
+
+	poder, err := h.service.RechazarPoder(r.Context(), chi.URLParam(r, "id"), req.Motivo, userID)
+	if err != nil {
+		writePoderError(w, err, "RechazarPoder")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, poder)
+}
+
+// RevocarPoder withdraws an unused poder: the granting parcela revokes it,
+// the apoderado renounces it.
+func (h *VotacionHandler) RevocarPoder(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	poder, err := h.service.RevocarPoder(r.Context(), chi.URLParam(r, "id"), userID, esDirectiva(r))
+	if err != nil {
+		writePoderError(w, err, "RevocarPoder")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, poder)
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/condominio/backend/internal/export"
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/internal/services"
+)
+
+// ============================================
+// CATEGORIAS
+// ============================================
+
+func (h *TesoreriaHandler) ListCategorias(w http.ResponseWriter, r *http.Request) {
+	tipo := models.MovimientoType(r.URL.Query().Get("tipo"))
+	soloActivas := r.URL.Query().Get("activas") == "true"
+
+	categorias, err := h.service.ListCategorias(r.Context(), tipo, soloActivas)
+	if err != nil {
+		log.Printf("ListCategorias failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to list categories")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, categorias)
+}
+
+func writeCategoriaError(w http.ResponseWriter, err error, op string) {
+	switch {
+	case errors.Is(err, services.ErrCategoriaNotFound):
+		writeError(w, http.StatusNotFound, "Category not found")
+	case errors.Is(err, services.ErrCategoriaExists):
+		writeError(w, http.StatusConflict, "A category with this codigo already exists")
+	case errors.Is(err, services.ErrInvalidCategoria):
+		writeError(w, http.StatusBadRequest, "codigo (lowercase letters, digits and _), nombre and tipo 'ingreso' or 'egreso' are required")
+	default:
+		log.Printf("%s failed: %v", op, err)
+		writeError(w, http.StatusInternalServerError, "Failed to save category")
+	}
+}
+
+func (h *TesoreriaHandler) CreateCategoria(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateCategoriaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	categoria, err := h.service.CreateCategoria(r.Context(), &req, userID)
+	if err != nil {
+		writeCategoriaError(w, err, "CreateCategoria")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, categoria)
+}
+
+func (h *TesoreriaHandler) UpdateCategoria(w http.ResponseWriter, r *http.Request) {
+	codigo := chi.URLParam(r, "codigo")
+
+	var req models.UpdateCategoriaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	categoria, err := h.service.UpdateCategoria(r.Context(), codigo, &req, userID)
+	if err != nil {
+		writeCategoriaError(w, err, "UpdateCategoria")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, categoria)
+}
+
+// ============================================
+// PRESUPUESTOS
+// ============================================
+
+func parseYearParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	year, err := strconv.Atoi(chi.URLParam(r, "year"))
+	if err != nil || year < 2000 || year > 2100 {
+		writeError(w, http.StatusBadRequest, "Invalid year")
+		return 0, false
+	}
+	return year, true
+}
+
+func writePresupuestoError(w http.ResponseWriter, err error, op string) {
+	switch {
+	case errors.Is(err, services.ErrPresupuestoNotFound):
+		writeError(w, http.StatusNotFound, "No presupuesto for this year")
+	case errors.Is(err, services.ErrInvalidPresupuesto):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("%s failed: %v", op, err)
+		writeError(w, http.StatusInternalServerError, "Failed to process presupuesto")
+	}
+}
+
+func (h *TesoreriaHandler) ListPresupuestos(w http.ResponseWriter, r *http.Request) {
+	presupuestos, err := h.service.ListPresupuestos(r.Context())
+	if err != nil {
+		writePresupuestoError(w, err, "ListPresupuestos")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, presupuestos)
+}
+
+func (h *TesoreriaHandler) GetPresupuesto(w http.ResponseWriter, r *http.Request) {
+	year, ok := parseYearParam(w, r)
+	if !ok {
+		return
+	}
+
+	presupuesto, err := h.service.GetPresupuesto(r.Context(), year)
+	if err != nil {
+		writePresupuestoError(w, err, "GetPresupuesto")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, presupuesto)
+}
+
+func (h *TesoreriaHandler) SetPresupuesto(w http.ResponseWriter, r *http.Request) {
+	year, ok := parseYearParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.SetPresupuestoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	presupuesto, err := h.service.SetPresupuesto(r.Context(), year, &req, userID)
+	if err != nil {
+		writePresupuestoError(w, err, "SetPresupuesto")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, presupuesto)
+}
+
+func (h *TesoreriaHandler) DeletePresupuesto(w http.ResponseWriter, r *http.Request) {
+	year, ok := parseYearParam(w, r)
+	if !ok {
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	if err := h.service.DeletePresupuesto(r.Context(), year, userID); err != nil {
+		writePresupuestoError(w, err, "DeletePresupuesto")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Presupuesto deleted"})
+}
+
+// GetEjecucion returns the budget variance up to ?month= (default: the
+// current month for the current year, December for past years).
+func (h *TesoreriaHandler) GetEjecucion(w http.ResponseWriter, r *http.Request) {
+	year, ok := parseYearParam(w, r)
+	if !ok {
+		return
+	}
+
+	month := 12
+	if now := time.Now(); year == now.Year() {
+		month = int(now.Month())
+	}
+	if m := r.URL.Query().Get("month"); m != "" {
+		v, err := strconv.Atoi(m)
+		if err != nil || v < 1 || v > 12 {
+			writeError(w, http.StatusBadRequest, "month must be between 1 and 12")
+			return
+		}
+		month = v
+	}
+
+	ejecucion, err := h.service.GetEjecucion(r.Context(), year, month)
+	if err != nil {
+		writePresupuestoError(w, err, "GetEjecucion")
+		return
+	}
+
+	filename := fmt.Sprintf("presupuesto-%04d-%02d", year, month)
+
+	var buf bytes.Buffer
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		writeJSON(w, http.StatusOK, ejecucion)
+	case "csv":
+		if err := export.EjecucionPresupuestoCSV(&buf, ejecucion); err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to export presupuesto")
+			return
+		}
+		writeFile(w, "text/csv; charset=utf-8", filename+".csv", buf.Bytes())
+	case "xlsx":
+		if err := export.EjecucionPresupuestoXLSX(&buf, ejecucion); err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to export presupuesto")
+			return
+		}
+		writeFile(w, contentTypeXLSX, filename+".xlsx", buf.Bytes())
+	default:
+		writeError(w, http.StatusBadRequest, "format must be json, csv or xlsx")
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/internal/services"
+)
+
+type ProveedorHandler struct {
+	service *services.ProveedorService
+}
+
+func NewProveedorHandler(service *services.ProveedorService) *ProveedorHandler {
+	return &ProveedorHandler{service: service}
+}
+
+func writeProveedorError(w http.ResponseWriter, err error, op string) {
+	switch {
+	case errors.Is(err, services.ErrProveedorNotFound):
+		writeError(w, http.StatusNotFound, "Proveedor not found")
+	case errors.Is(err, services.ErrProveedorExists):
+		writeError(w, http.StatusConflict, "A proveedor with this RUT already exists")
+	case errors.Is(err, services.ErrInvalidRUT):
+		writeError(w, http.StatusBadRequest, "rut is not a valid RUT")
+	case errors.Is(err, services.ErrInvalidProveedor):
+		writeError(w, http.StatusBadRequest, "nombre is required")
+	case errors.Is(err, services.ErrCategoriaInvalida):
+		writeError(w, http.StatusBadRequest, "categoria must be an active egreso category")
+	default:
+		log.Printf("%s failed: %v", op, err)
+		writeError(w, http.StatusInternalServerError, "Failed to process proveedor")
+	}
+}
+
+// List returns the proveedores; ?q= searches name or RUT and ?todos=true
+// includes inactive ones.
+func (h *ProveedorHandler) List(w http.ResponseWriter, r *http.Request) {
+	proveedores, err := h.service.List(r.Context(), r.URL.Query().Get("q"), r.URL.Query().Get("todos") == "true")
+	if err != nil {
+		writeProveedorError(w, err, "List proveedores")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, proveedores)
+}
+
+func (h *ProveedorHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	proveedor, err := h.service.GetByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeProveedorError(w, err, "Get proveedor")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, proveedor)
+}
+
+func (h *ProveedorHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateProveedorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	proveedor, err := h.service.Create(r.Context(), &req, userID)
+	if err != nil {
+		writeProveedorError(w, err, "Create proveedor")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, proveedor)
+}
+
+func (h *ProveedorHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateProveedorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	proveedor, err := h.service.Update(r.Context(), chi.URLParam(r, "id"), &req, userID)
+	if err != nil {
+		writeProveedorError(w, err, "Update proveedor")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, proveedor)
+}
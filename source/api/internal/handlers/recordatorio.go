@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/condominio/backend/internal/services"
+)
+
+type RecordatorioHandler struct {
+	service *services.RecordatorioService
+}
+
+func NewRecordatorioHandler(service *services.RecordatorioService) *RecordatorioHandler {
+	return &RecordatorioHandler{service: service}
+}
+
+// Procesar sends the pending reminders right away instead of waiting for the
+// scheduler.
+func (h *RecordatorioHandler) Procesar(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.ProcesarRecordatorios(r.Context())
+	if err != nil {
+		log.Printf("ProcesarRecordatorios failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to process reminders")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *RecordatorioHandler) List(w http.ResponseWriter, r *http.Request) {
+	recordatorios, err := h.service.ListRecordatorios(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to list reminders")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, recordatorios)
+}
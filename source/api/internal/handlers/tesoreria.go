@@ -2,13 +2,31 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
+	"log"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
 
 	"github.com/condominio/backend/internal/models"
 	"github.com/condominio/backend/internal/services"
+	"github.com/condominio/backend/pkg/money"
 )
 
+// Scanned boletas and facturas; a phone photo of a page fits well below this.
+const maxArchivoAdjunto = 10 << 20
+
+var tiposAdjunto = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+}
+
 type TesoreriaHandler struct {
 	service *services.TesoreriaService
 }
@@ -46,12 +64,45 @@ func (h *TesoreriaHandler) List(w http.ResponseWriter, r *http.Request) {
 			filter.Month = m
 		}
 	}
+	filter.CuentaID = r.URL.Query().Get("cuenta_id")
+	filter.Category = r.URL.Query().Get("category")
+	if v := r.URL.Query().Get("monto_min"); v != "" {
+		monto, err := money.Parse(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid monto_min")
+			return
+		}
+		filter.MontoMin = &monto
+	}
+	if v := r.URL.Query().Get("monto_max"); v != "" {
+		monto, err := money.Parse(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid monto_max")
+			return
+		}
+		filter.MontoMax = &monto
+	}
+	if v := r.URL.Query().Get("con_adjunto"); v != "" {
+		conAdjunto, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "con_adjunto must be true or false")
+			return
+		}
+		filter.ConAdjunto = &conAdjunto
+	}
 
 	resp, err := h.service.List(r.Context(), filter)
 	if err != nil {
+		log.Printf("List movimientos failed: %v", err)
 		writeError(w, http.StatusInternalServerError, "Failed to list movimientos")
 		return
 	}
+	// Pagos of each parcela are only visible to the directiva
+	if role, _ := r.Context().Value("user_role").(string); role != "directiva" {
+		for i := range resp.Movimientos {
+			resp.Movimientos[i].OcultarPago()
+		}
+	}
 
 	writeJSON(w, http.StatusOK, resp)
 }
@@ -86,9 +137,216 @@ func (h *TesoreriaHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	movimiento, err := h.service.Create(r.Context(), &req, createdBy)
 	if err != nil {
+		if errors.Is(err, services.ErrCategoriaInvalida) {
+			writeError(w, http.StatusBadRequest, "category must be an active category of the same type")
+			return
+		}
+		if errors.Is(err, services.ErrCuentaInvalida) {
+			writeError(w, http.StatusBadRequest, "cuenta_id must be an active cuenta")
+			return
+		}
+		log.Printf("Create movimiento failed: %v", err)
 		writeError(w, http.StatusInternalServerError, "Failed to create movimiento")
 		return
 	}
 
 	writeJSON(w, http.StatusCreated, movimiento)
 }
+
+func writeMovimientoError(w http.ResponseWriter, err error, op string) {
+	switch {
+	case errors.Is(err, services.ErrMovimientoNotFound):
+		writeError(w, http.StatusNotFound, "Movimiento not found")
+	case errors.Is(err, services.ErrMovimientoAnulado):
+		writeError(w, http.StatusConflict, "Movimiento is void")
+	case errors.Is(err, services.ErrMovimientoReverso):
+		writeError(w, http.StatusConflict, "Reverse entries cannot be changed; void the original instead")
+	case errors.Is(err, services.ErrInvalidMovimiento):
+		writeError(w, http.StatusBadRequest, "Description, positive amount and type 'ingreso' or 'egreso' are required")
+	case errors.Is(err, services.ErrMovimientoTransferencia):
+		writeError(w, http.StatusConflict, "Transfer entries cannot be edited; void the transfer instead")
+	case errors.Is(err, services.ErrMovimientoVinculado):
+		writeError(w, http.StatusConflict, "Movimientos posted from a pago or factura cannot be edited; void it and post it again from its source")
+	case errors.Is(err, services.ErrMovimientoEnCierre):
+		writeError(w, http.StatusConflict, "The movimiento is in a signed reconciliation period; its amount, type, cuenta and date cannot change. Void it instead")
+	case errors.Is(err, services.ErrCuentaInvalida):
+		writeError(w, http.StatusBadRequest, "cuenta_id must be an active cuenta")
+	case errors.Is(err, services.ErrCategoriaInvalida):
+		writeError(w, http.StatusBadRequest, "category must be an active category of the same type")
+	case errors.Is(err, services.ErrAdjuntoNotFound):
+		writeError(w, http.StatusNotFound, "Attachment not found")
+	default:
+		log.Printf("%s failed: %v", op, err)
+		writeError(w, http.StatusInternalServerError, "Failed to update movimiento")
+	}
+}
+
+func (h *TesoreriaHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	movimiento, err := h.service.GetByID(r.Context(), id)
+	if err != nil {
+		writeMovimientoError(w, err, "GetByID movimiento")
+		return
+	}
+	if role, _ := r.Context().Value("user_role").(string); role != "directiva" {
+		movimiento.OcultarPago()
+	}
+
+	writeJSON(w, http.StatusOK, movimiento)
+}
+
+func (h *TesoreriaHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req models.UpdateMovimientoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Motivo) == "" {
+		writeError(w, http.StatusBadRequest, "motivo is required")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	movimiento, err := h.service.Update(r.Context(), id, &req, userID)
+	if err != nil {
+		writeMovimientoError(w, err, "Update movimiento")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, movimiento)
+}
+
+// Anular voids a movimiento by booking a reverse entry.
+func (h *TesoreriaHandler) Anular(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req models.AnularMovimientoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Motivo) == "" {
+		writeError(w, http.StatusBadRequest, "motivo is required")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	movimiento, err := h.service.Anular(r.Context(), id, req.Motivo, userID)
+	if err != nil {
+		writeMovimientoError(w, err, "Anular movimiento")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, movimiento)
+}
+
+// AddAdjunto uploads a receipt sent as multipart/form-data in the archivo
+// field. PDF, JPEG, PNG and WebP files are accepted.
+func (h *TesoreriaHandler) AddAdjunto(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	userID := r.Context().Value("user_id").(string)
+
+	data, nombre, contentType, ok := leerArchivoAdjunto(w, r)
+	if !ok {
+		return
+	}
+
+	adjunto, err := h.service.AddAdjunto(r.Context(), id, &models.AdjuntoMovimiento{
+		NombreArchivo: nombre,
+		ContentType:   contentType,
+		Contenido:     data,
+	}, userID)
+	if err != nil {
+		writeMovimientoError(w, err, "AddAdjunto")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, adjunto)
+}
+
+// leerArchivoAdjunto reads the archivo field of a multipart upload and checks
+// it is a PDF or image. On failure the error response is already written.
+func leerArchivoAdjunto(w http.ResponseWriter, r *http.Request) (data []byte, nombre, contentType string, ok bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxArchivoAdjunto)
+	if err := r.ParseMultipartForm(maxArchivoAdjunto); err != nil {
+		writeError(w, http.StatusBadRequest, "Expected multipart form with archivo (max 10 MB)")
+		return nil, "", "", false
+	}
+	file, header, err := r.FormFile("archivo")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "archivo is required")
+		return nil, "", "", false
+	}
+	defer file.Close()
+
+	data, err = io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Failed to read archivo")
+		return nil, "", "", false
+	}
+	if len(data) == 0 {
+		writeError(w, http.StatusBadRequest, "archivo is empty")
+		return nil, "", "", false
+	}
+
+	// The declared type is not trusted; the content decides
+	contentType, _, _ = strings.Cut(http.DetectContentType(data), ";")
+	ext, ok := tiposAdjunto[contentType]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "archivo must be a PDF, JPEG, PNG or WebP file")
+		return nil, "", "", false
+	}
+	return data, nombreAdjunto(header.Filename, ext), contentType, true
+}
+
+// nombreAdjunto keeps the base name of an upload, without characters that
+// would break the Content-Disposition header on download.
+func nombreAdjunto(nombre, ext string) string {
+	nombre = filepath.Base(strings.ReplaceAll(nombre, "\\", "/"))
+	nombre = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == '"' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, nombre)
+	if nombre == "" || nombre == "." || nombre == "/" {
+		nombre = "adjunto" + ext
+	}
+	if r := []rune(nombre); len(r) > 255 {
+		nombre = string(r[len(r)-255:])
+	}
+	return nombre
+}
+
+func (h *TesoreriaHandler) GetAdjunto(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	adjuntoID := chi.URLParam(r, "adjuntoId")
+
+	adjunto, err := h.service.GetAdjunto(r.Context(), id, adjuntoID)
+	if err != nil {
+		writeMovimientoError(w, err, "GetAdjunto")
+		return
+	}
+
+	writeFile(w, adjunto.ContentType, adjunto.NombreArchivo, adjunto.Contenido)
+}
+
+// DeleteAdjunto removes an attachment; ?motivo= goes to the audit trail.
+func (h *TesoreriaHandler) DeleteAdjunto(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	adjuntoID := chi.URLParam(r, "adjuntoId")
+	userID := r.Context().Value("user_id").(string)
+
+	err := h.service.DeleteAdjunto(r.Context(), id, adjuntoID, r.URL.Query().Get("motivo"), userID)
+	if err != nil {
+		writeMovimientoError(w, err, "DeleteAdjunto")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Attachment deleted"})
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/internal/services"
+)
+
+// A year of daily UF values fits in a few KB.
+const maxArchivoUF = 1 << 20
+
+type UFHandler struct {
+	service *services.UFService
+}
+
+func NewUFHandler(service *services.UFService) *UFHandler {
+	return &UFHandler{service: service}
+}
+
+func parseFechaParam(w http.ResponseWriter, v, campo string) (time.Time, bool) {
+	fecha, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, campo+" must be YYYY-MM-DD")
+		return time.Time{}, false
+	}
+	return fecha, true
+}
+
+func (h *UFHandler) List(w http.ResponseWriter, r *http.Request) {
+	var filter models.ValorUFFilter
+	if v := r.URL.Query().Get("desde"); v != "" {
+		fecha, ok := parseFechaParam(w, v, "desde")
+		if !ok {
+			return
+		}
+		filter.Desde = &fecha
+	}
+	if v := r.URL.Query().Get("hasta"); v != "" {
+		fecha, ok := parseFechaParam(w, v, "hasta")
+		if !ok {
+			return
+		}
+		filter.Hasta = &fecha
+	}
+
+	valores, err := h.service.ListValoresUF(r.Context(), filter)
+	if err != nil {
+		log.Printf("ListValoresUF failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to list UF values")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, valores)
+}
+
+func (h *UFHandler) Get(w http.ResponseWriter, r *http.Request) {
+	fecha, ok := parseFechaParam(w, chi.URLParam(r, "fecha"), "fecha")
+	if !ok {
+		return
+	}
+
+	valor, err := h.service.GetValorUF(r.Context(), fecha)
+	if err != nil {
+		if errors.Is(err, services.ErrValorUFNotFound) {
+			writeError(w, http.StatusNotFound, "No UF value for this date")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to get UF value")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, valor)
+}
+
+func (h *UFHandler) Set(w http.ResponseWriter, r *http.Request) {
+	fecha, ok := parseFechaParam(w, chi.URLParam(r, "fecha"), "fecha")
+	if !ok {
+		return
+	}
+
+	var req models.SetValorUFRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	valor, err := h.service.SetValorUF(r.Context(), fecha, req.Valor, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidValorUF) {
+			writeError(w, http.StatusBadRequest, "valor must be positive")
+			return
+		}
+		log.Printf("SetValorUF failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to save UF value")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, valor)
+}
+
+func (h *UFHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	fecha, ok := parseFechaParam(w, chi.URLParam(r, "fecha"), "fecha")
+	if !ok {
+		return
+	}
+
+	userID := r.Context().Value("user_id").(string)
+
+	if err := h.service.DeleteValorUF(r.Context(), fecha, userID); err != nil {
+		if errors.Is(err, services.ErrValorUFNotFound) {
+			writeError(w, http.StatusNotFound, "No UF value for this date")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to delete UF value")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "UF value deleted"})
+}
+
+// Importar loads a CSV of fecha,valor lines sent as multipart/form-data in
+// the archivo field.
+func (h *UFHandler) Importar(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxArchivoUF)
+	if err := r.ParseMultipartForm(maxArchivoUF); err != nil {
+		writeError(w, http.StatusBadRequest, "Expected multipart form with archivo (max 1 MB)")
+		return
+	}
+	file, _, err := r.FormFile("archivo")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "archivo is required")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Failed to read archivo")
+		return
+	}
+
+	result, err := h.service.ImportarValoresUF(r.Context(), data, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrArchivoInvalido) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("ImportarValoresUF failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to import UF values")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
@@ -113,6 +114,12 @@ func (h *VotacionHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	votacion, err := h.service.Create(r.Context(), &req, createdBy)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidEvento) ||
+			errors.Is(err, services.ErrInvalidTipoVotacion) ||
+			errors.Is(err, services.ErrInvalidSelecciones) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		writeError(w, http.StatusInternalServerError, "Failed to create votacion")
 		return
 	}
@@ -135,6 +142,12 @@ func (h *VotacionHandler) Update(w http.ResponseWriter, r *http.Request) {
 			writeError(w, http.StatusNotFound, "Votacion not found")
 			return
 		}
+		if errors.Is(err, services.ErrInvalidEvento) ||
+			errors.Is(err, services.ErrInvalidTipoVotacion) ||
+			errors.Is(err, services.ErrInvalidSelecciones) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -207,14 +220,14 @@ func (h *VotacionHandler) EmitirVoto(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !req.IsAbstention && req.OpcionID == nil {
-		writeError(w, http.StatusBadRequest, "opcion_id is required unless abstaining")
+	if !req.IsAbstention && req.OpcionID == nil && len(req.Opciones) == 0 {
+		writeError(w, http.StatusBadRequest, "opcion_id or opciones is required unless abstaining")
 		return
 	}
 
 	userID := r.Context().Value("user_id").(string)
 
-	err := h.service.EmitirVoto(r.Context(), id, userID, &req)
+	recibo, err := h.service.EmitirVoto(r.Context(), id, userID, &req)
 	if err != nil {
 		switch {
 		case errors.Is(err, services.ErrVotacionNotFound):
@@ -222,19 +235,35 @@ func (h *VotacionHandler) EmitirVoto(w http.ResponseWriter, r *http.Request) {
 		case errors.Is(err, services.ErrVotacionNotActive):
 			writeError(w, http.StatusBadRequest, "Votacion is not active")
 		case errors.Is(err, services.ErrAlreadyVoted):
-			writeError(w, http.StatusConflict, "You have already voted")
+			writeError(w, http.StatusConflict, "Your parcela has already voted")
 		case errors.Is(err, services.ErrInvalidOpcion):
 			writeError(w, http.StatusBadRequest, "Invalid option")
+		case errors.Is(err, services.ErrInvalidSeleccion),
+			errors.Is(err, services.ErrReciboRequerido):
+			writeError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrReciboNotFound):
+			writeError(w, http.StatusNotFound, "No ballot matches the receipt")
 		case errors.Is(err, services.ErrAbstentionNotAllowed):
 			writeError(w, http.StatusBadRequest, "Abstention is not allowed")
 		case errors.Is(err, services.ErrUserNoParcela):
 			writeError(w, http.StatusForbidden, "Para votar debe tener una parcela asociada")
+		case errors.Is(err, services.ErrPoderNoVigente):
+			writeError(w, http.StatusForbidden, "No tiene un poder validado y vigente de esa parcela para esta votación")
 		default:
 			writeError(w, http.StatusInternalServerError, "Failed to emit vote")
 		}
 		return
 	}
 
+	if recibo != nil {
+		// Secret ballot: the receipt code is shown to the voter and never stored
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"message": "Vote registered successfully",
+			"recibo":  recibo,
+		})
+		return
+	}
+
 	writeJSON(w, http.StatusOK, map[string]string{"message": "Vote registered successfully"})
 }
 
@@ -265,3 +294,117 @@ func (h *VotacionHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// ListBoletas publishes the anonymous ballots of a secret votacion.
+func (h *VotacionHandler) ListBoletas(w http.ResponseWriter, r *http.Request) {
+	boletas, err := h.service.ListBoletas(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrVotacionNotFound):
+			writeError(w, http.StatusNotFound, "Votacion not found")
+		case errors.Is(err, services.ErrVotacionNoSecreta):
+			writeError(w, http.StatusBadRequest, "Votacion is not secret")
+		default:
+			log.Printf("ListBoletas failed: %v", err)
+			writeError(w, http.StatusInternalServerError, "Failed to list ballots")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, boletas)
+}
+
+// VerificarRecibo checks a receipt code against the published ballots.
+func (h *VotacionHandler) VerificarRecibo(w http.ResponseWriter, r *http.Request) {
+	var req models.VerificarReciboRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Codigo == "" {
+		writeError(w, http.StatusBadRequest, "codigo is required")
+		return
+	}
+
+	boleta, err := h.service.VerificarRecibo(r.Context(), chi.URLParam(r, "id"), req.Codigo)
+	if err != nil {
+		if errors.Is(err, services.ErrReciboNotFound) {
+			writeError(w, http.StatusNotFound, "No ballot matches the receipt")
+			return
+		}
+		log.Printf("VerificarRecibo failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to verify receipt")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, boleta)
+}
+
+// ListCoeficientes lists the voting weight of every parcela.
+func (h *VotacionHandler) ListCoeficientes(w http.ResponseWriter, r *http.Request) {
+	coeficientes, err := h.service.ListCoeficientes(r.Context())
+	if err != nil {
+		log.Printf("ListCoeficientes failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to list coeficientes")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, coeficientes)
+}
+
+func (h *VotacionHandler) SetCoeficiente(w http.ResponseWriter, r *http.Request) {
+	parcelaID, err := strconv.Atoi(chi.URLParam(r, "parcelaId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid parcela id")
+		return
+	}
+
+	var req models.SetCoeficienteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	coeficiente, err := h.service.SetCoeficiente(r.Context(), parcelaID, req.Coeficiente)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrParcelaNotFound):
+			writeError(w, http.StatusNotFound, "Parcela not found")
+		case errors.Is(err, services.ErrInvalidCoeficiente):
+			writeError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrCoeficienteEnUso):
+			writeError(w, http.StatusConflict, err.Error())
+		default:
+			log.Printf("SetCoeficiente failed: %v", err)
+			writeError(w, http.StatusInternalServerError, "Failed to update coeficiente")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, coeficiente)
+}
+
+// ProcesarVotaciones opens and closes the votaciones that are due and sends
+// their notices right away instead of waiting for the scheduler.
+func (h *VotacionHandler) ProcesarVotaciones(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.ProcesarVotaciones(r.Context())
+	if err != nil {
+		log.Printf("ProcesarVotaciones failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to process votaciones")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ListAvisos lists the notices sent for a votacion.
+func (h *VotacionHandler) ListAvisos(w http.ResponseWriter, r *http.Request) {
+	avisos, err := h.service.ListAvisos(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		log.Printf("ListAvisos failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to list avisos")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, avisos)
+}
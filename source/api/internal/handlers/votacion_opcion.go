@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/internal/services"
+)
+
+func writeOpcionError(w http.ResponseWriter, err error, op string) {
+	switch {
+	case errors.Is(err, services.ErrVotacionNotFound):
+		writeError(w, http.StatusNotFound, "Votacion not found")
+	case errors.Is(err, services.ErrOpcionNotFound):
+		writeError(w, http.StatusNotFound, "Opcion not found")
+	case errors.Is(err, services.ErrInvalidOpcionLabel),
+		errors.Is(err, services.ErrInvalidOrdenOpciones):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrOpcionesNoEditables):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		log.Printf("%s failed: %v", op, err)
+		writeError(w, http.StatusInternalServerError, "Failed to process opcion")
+	}
+}
+
+func (h *VotacionHandler) AddOpcion(w http.ResponseWriter, r *http.Request) {
+	var req models.AddOpcionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	opcion, err := h.service.AddOpcion(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeOpcionError(w, err, "AddOpcion")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, opcion)
+}
+
+func (h *VotacionHandler) UpdateOpcion(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateOpcionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	opcion, err := h.service.UpdateOpcion(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "opcionId"), &req)
+	if err != nil {
+		writeOpcionError(w, err, "UpdateOpcion")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, opcion)
+}
+
+func (h *VotacionHandler) DeleteOpcion(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteOpcion(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "opcionId")); err != nil {
+		writeOpcionError(w, err, "DeleteOpcion")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReordenarOpciones sets the order of the options from the full list of
+// their ids.
+func (h *VotacionHandler) ReordenarOpciones(w http.ResponseWriter, r *http.Request) {
+	var req models.ReordenarOpcionesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	opciones, err := h.service.ReordenarOpciones(r.Context(), chi.URLParam(r, "id"), req.Opciones)
+	if err != nil {
+		writeOpcionError(w, err, "ReordenarOpciones")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, opciones)
+}
+
+// ListHistorialVotos lists the ballots replaced by changes of vote.
+func (h *VotacionHandler) ListHistorialVotos(w http.ResponseWriter, r *http.Request) {
+	historial, err := h.service.ListHistorialVotos(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, services.ErrVotacionNotFound) {
+			writeError(w, http.StatusNotFound, "Votacion not found")
+			return
+		}
+		log.Printf("ListHistorialVotos failed: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to list vote history")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, historial)
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+type AvisoVotacionTipo string
+
+const (
+	AvisoApertura     AvisoVotacionTipo = "apertura"
+	AvisoRecordatorio AvisoVotacionTipo = "recordatorio" // to parcelas that have not voted
+	AvisoResultados   AvisoVotacionTipo = "resultados"
+)
+
+// VotacionConfig holds the settings of votaciones. RecordatorioHoras is how
+// long before end_date the parcelas that have not voted are reminded; 0
+// disables the reminder.
+type VotacionConfig struct {
+	MaxPoderes        int // poderes per apoderado and scope; 0 means no limit
+	RecordatorioHoras int
+	FrontendURL       string
+}
+
+// AvisoVotacion is a notice already sent for a votacion.
+type AvisoVotacion struct {
+	ID             string            `json:"id"`
+	VotacionID     string            `json:"votacion_id"`
+	Tipo           AvisoVotacionTipo `json:"tipo"`
+	Notificaciones int               `json:"notificaciones"`
+	Emails         int               `json:"emails"`
+	CreatedAt      time.Time         `json:"created_at"`
+}
+
+// ProcesarVotacionesResult sums up a run of the votaciones job.
+type ProcesarVotacionesResult struct {
+	Abiertas       int `json:"abiertas"`
+	Cerradas       int `json:"cerradas"`
+	Aperturas      int `json:"aperturas"`
+	Recordatorios  int `json:"recordatorios"`
+	Resultados     int `json:"resultados"`
+	Notificaciones int `json:"notificaciones"`
+	Emails         int `json:"emails"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"github.com/condominio/backend/pkg/money"
+)
+
+// DatosBancarios are the transfer details printed on avisos de cobro.
+type DatosBancarios struct {
+	Banco        string `json:"banco,omitempty"`
+	TipoCuenta   string `json:"tipo_cuenta,omitempty"`
+	NumeroCuenta string `json:"numero_cuenta,omitempty"`
+	Titular      string `json:"titular,omitempty"`
+	RUT          string `json:"rut,omitempty"`
+	Email        string `json:"email,omitempty"`
+}
+
+type LineaAviso struct {
+	Descripcion string       `json:"descripcion"`
+	Monto       money.Amount `json:"monto"`
+}
+
+// AvisoCobro is the collection notice of a parcela for a periodo.
+type AvisoCobro struct {
+	PeriodoID        string         `json:"periodo_id"`
+	Year             int            `json:"year"`
+	Month            int            `json:"month"`
+	FechaVencimiento time.Time      `json:"fecha_vencimiento"`
+	FechaEmision     time.Time      `json:"fecha_emision"`
+	ParcelaID        int            `json:"parcela_id"`
+	ParcelaNumero    string         `json:"parcela_numero"`
+	Direccion        string         `json:"direccion,omitempty"`
+	Propietario      string         `json:"propietario,omitempty"`
+	Emails           []string       `json:"-"`
+	Lineas           []LineaAviso   `json:"lineas"`
+	MontoBaseUF      *float64       `json:"monto_base_uf,omitempty"` // periodo defined in UF
+	ValorUF          *float64       `json:"valor_uf,omitempty"`
+	FechaUF          *time.Time     `json:"fecha_uf,omitempty"`
+	TotalPeriodo     money.Amount   `json:"total_periodo"`
+	AbonosPeriodo    money.Amount   `json:"abonos_periodo"`
+	DeudaAnterior    money.Amount   `json:"deuda_anterior"`
+	SaldoFavor       money.Amount   `json:"saldo_favor"`
+	TotalAPagar      money.Amount   `json:"total_a_pagar"`
+	DatosBancarios   DatosBancarios `json:"datos_bancarios"`
+}
+
+// Recibo is the numbered receipt issued for an approved pago.
+type Recibo struct {
+	ID              string       `json:"id"`
+	Numero          int64        `json:"numero"`
+	PagoID          string       `json:"pago_id"`
+	GastoComunID    string       `json:"gasto_comun_id"`
+	ParcelaID       int          `json:"parcela_id"`
+	ParcelaNumero   string       `json:"parcela_numero"`
+	Pagador         string       `json:"pagador,omitempty"`
+	Emails          []string     `json:"-"`
+	Year            int          `json:"year"`
+	Month           int          `json:"month"`
+	Monto           money.Amount `json:"monto"`
+	Metodo          string       `json:"metodo"`
+	Referencia      string       `json:"referencia,omitempty"`
+	FechaPago       time.Time    `json:"fecha_pago"`
+	EmitidoAt       time.Time    `json:"emitido_at"`
+	AnuladoAt       *time.Time   `json:"anulado_at,omitempty"`
+	MotivoAnulacion string       `json:"motivo_anulacion,omitempty"`
+}
+
+type EnvioAvisosResult struct {
+	Avisos   int `json:"avisos"`
+	Enviados int `json:"enviados"`
+	SinEmail int `json:"sin_email"`
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"time"
+
+	"github.com/condominio/backend/pkg/money"
+)
+
+type CartolaFormato string
+
+const (
+	CartolaCSV CartolaFormato = "csv" // read with a formato_importacion layout
+	CartolaOFX CartolaFormato = "ofx"
+)
+
+type MatchCartola string
+
+const (
+	// Same amount and the bank reference found in the pago or description
+	MatchCartolaReferencia MatchCartola = "referencia"
+	// The only pending movimiento with the same amount and date
+	MatchCartolaMontoFecha MatchCartola = "monto_fecha"
+	MatchCartolaManual     MatchCartola = "manual"
+)
+
+// CartolaBancaria is a bank statement uploaded for a treasury cuenta.
+type CartolaBancaria struct {
+	ID            string         `json:"id"`
+	CuentaID      string         `json:"cuenta_id"`
+	CuentaNombre  string         `json:"cuenta_nombre"`
+	ArchivoNombre string         `json:"archivo_nombre"`
+	Formato       CartolaFormato `json:"formato"`
+	FormatoID     *string        `json:"formato_id,omitempty"`
+	Desde         *time.Time     `json:"desde,omitempty"`
+	Hasta         *time.Time     `json:"hasta,omitempty"`
+	SaldoFinal    *money.Amount  `json:"saldo_final,omitempty"` // closing balance stated by the bank
+	TotalLineas   int            `json:"total_lineas"`
+	Conciliadas   int            `json:"conciliadas"`
+	CreatedBy     *string        `json:"created_by,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	// Upload result only: lines already loaded by an earlier statement and
+	// lines that could not be read
+	Duplicadas int            `json:"duplicadas,omitempty"`
+	Omitidas   []LineaOmitida `json:"omitidas,omitempty"`
+}
+
+type LineaOmitida struct {
+	Linea  int    `json:"linea"`
+	Motivo string `json:"motivo"`
+}
+
+// LineaCartola is a statement line. Monto is positive for deposits and
+// negative for charges, the same sign an ingreso and an egreso have on the
+// cuenta balance.
+type LineaCartola struct {
+	ID           string        `json:"id"`
+	CartolaID    string        `json:"cartola_id"`
+	Linea        int           `json:"linea"`
+	Fecha        time.Time     `json:"fecha"`
+	Monto        money.Amount  `json:"monto"`
+	Descripcion  string        `json:"descripcion,omitempty"`
+	Referencia   string        `json:"referencia,omitempty"`
+	MovimientoID *string       `json:"movimiento_id,omitempty"`
+	Match        *MatchCartola `json:"match,omitempty"`
+	ConciliadaAt *time.Time    `json:"conciliada_at,omitempty"`
+	ConciliadaBy *string       `json:"conciliada_by,omitempty"`
+	// Related data
+	Movimiento  *Movimiento  `json:"movimiento,omitempty"`
+	Sugerencias []Movimiento `json:"sugerencias,omitempty"`
+}
+
+// ConciliacionBancaria is the reconciliation screen of a cuenta between two
+// dates: matched pairs, statement lines without a movimiento (with
+// candidates) and movimientos the bank has not shown yet.
+type ConciliacionBancaria struct {
+	CuentaID              string              `json:"cuenta_id"`
+	CuentaNombre          string              `json:"cuenta_nombre"`
+	Desde                 time.Time           `json:"desde"`
+	Hasta                 time.Time           `json:"hasta"`
+	Conciliadas           []LineaCartola      `json:"conciliadas"`
+	LineasPendientes      []LineaCartola      `json:"lineas_pendientes"`
+	MovimientosPendientes []Movimiento        `json:"movimientos_pendientes"`
+	SaldoLibro            money.Amount        `json:"saldo_libro"` // at hasta
+	UltimoCierre          *CierreConciliacion `json:"ultimo_cierre,omitempty"`
+}
+
+type ConciliarLineaRequest struct {
+	MovimientoID string `json:"movimiento_id"`
+}
+
+type ConciliacionAutomaticaResult struct {
+	Conciliadas int `json:"conciliadas"`
+	Pendientes  int `json:"pendientes"`
+}
+
+// CierreConciliacion closes a period of a cuenta. Diferencia is the bank
+// balance minus the book balance; what the pending lines and movimientos do
+// not account for is DiferenciaNoExplicada, which should be zero.
+type CierreConciliacion struct {
+	ID                         string       `json:"id"`
+	CuentaID                   string       `json:"cuenta_id"`
+	FechaCorte                 time.Time    `json:"fecha_corte"`
+	SaldoBanco                 money.Amount `json:"saldo_banco"`
+	SaldoLibro                 money.Amount `json:"saldo_libro"`
+	Diferencia                 money.Amount `json:"diferencia"`
+	LineasPendientes           int          `json:"lineas_pendientes"`
+	MontoLineasPendientes      money.Amount `json:"monto_lineas_pendientes"`
+	MovimientosPendientes      int          `json:"movimientos_pendientes"`
+	MontoMovimientosPendientes money.Amount `json:"monto_movimientos_pendientes"`
+	DiferenciaNoExplicada      money.Amount `json:"diferencia_no_explicada"`
+	Observaciones              string       `json:"observaciones,omitempty"`
+	FirmadoBy                  string       `json:"firmado_by"`
+	FirmadoPor                 string       `json:"firmado_por"`
+	FirmadoAt                  time.Time    `json:"firmado_at"`
+}
+
+// CreateCierreRequest signs the reconciliation at FechaCorte. SaldoBanco
+// defaults to the closing balance of a statement ending that day.
+type CreateCierreRequest struct {
+	FechaCorte    time.Time     `json:"fecha_corte"`
+	SaldoBanco    *money.Amount `json:"saldo_banco,omitempty"`
+	Observaciones string        `json:"observaciones"`
+}
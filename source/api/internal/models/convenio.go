@@ -0,0 +1,103 @@
+package models
+
+import (
+	"time"
+
+	"github.com/condominio/backend/pkg/money"
+)
+
+type ConvenioEstado string
+
+const (
+	ConvenioActivo   ConvenioEstado = "activo"
+	ConvenioCumplido ConvenioEstado = "cumplido"
+	ConvenioRoto     ConvenioEstado = "roto"
+	ConvenioAnulado  ConvenioEstado = "anulado"
+)
+
+type CuotaEstado string
+
+const (
+	CuotaPendiente CuotaEstado = "pendiente"
+	CuotaPagada    CuotaEstado = "pagada"
+	CuotaVencida   CuotaEstado = "vencida"
+)
+
+type ConvenioPago struct {
+	ID                  string         `json:"id"`
+	ParcelaID           int            `json:"parcela_id"`
+	ParcelaNumero       string         `json:"parcela_numero,omitempty"`
+	Estado              ConvenioEstado `json:"estado"`
+	MontoTotal          money.Amount   `json:"monto_total"`
+	NumCuotas           int            `json:"num_cuotas"`
+	MaxCuotasImpagas    int            `json:"max_cuotas_impagas"`
+	CongelarIntereses   bool           `json:"congelar_intereses"`
+	InteresesCongelados money.Amount   `json:"intereses_congelados"`
+	Observaciones       string         `json:"observaciones,omitempty"`
+	MotivoTermino       string         `json:"motivo_termino,omitempty"`
+	TerminadoAt         *time.Time     `json:"terminado_at,omitempty"`
+	CreatedBy           *string        `json:"created_by,omitempty"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	// Computed fields
+	MontoPagado    money.Amount `json:"monto_pagado"`
+	CuotasPagadas  int          `json:"cuotas_pagadas"`
+	CuotasVencidas int          `json:"cuotas_vencidas"`
+	// Related data
+	Gastos []ConvenioGasto `json:"gastos,omitempty"`
+	Cuotas []ConvenioCuota `json:"cuotas,omitempty"`
+}
+
+type ConvenioGasto struct {
+	GastoComunID       string       `json:"gasto_comun_id"`
+	Year               int          `json:"year"`
+	Month              int          `json:"month"`
+	MontoPendiente     money.Amount `json:"monto_pendiente"`
+	MontoPagadoInicial money.Amount `json:"monto_pagado_inicial"`
+	Status             PagoStatus   `json:"status"`
+}
+
+type ConvenioCuota struct {
+	ID               string       `json:"id"`
+	Numero           int          `json:"numero"`
+	Monto            money.Amount `json:"monto"`
+	FechaVencimiento time.Time    `json:"fecha_vencimiento"`
+	PeriodoID        *string      `json:"periodo_id,omitempty"`
+	Estado           CuotaEstado  `json:"estado"`
+	PagadaAt         *time.Time   `json:"pagada_at,omitempty"`
+}
+
+type CreateConvenioRequest struct {
+	ParcelaID         int      `json:"parcela_id"`
+	GastoIDs          []string `json:"gasto_ids"`
+	NumCuotas         int      `json:"num_cuotas"`
+	FechaPrimeraCuota string   `json:"fecha_primera_cuota"`          // YYYY-MM-DD, following cuotas are monthly
+	MaxCuotasImpagas  int      `json:"max_cuotas_impagas,omitempty"` // default 2
+	CongelarIntereses bool     `json:"congelar_intereses"`
+	Observaciones     string   `json:"observaciones,omitempty"`
+}
+
+type TerminarConvenioRequest struct {
+	Motivo string `json:"motivo"`
+}
+
+type ConvenioFilter struct {
+	ParcelaID int
+	Estado    ConvenioEstado
+	Page      int
+	PerPage   int
+}
+
+type ConvenioListResponse struct {
+	Convenios []ConvenioPago `json:"convenios"`
+	Total     int            `json:"total"`
+	Page      int            `json:"page"`
+	PerPage   int            `json:"per_page"`
+}
+
+// ProcesarConveniosResult summarizes a compliance check run.
+type ProcesarConveniosResult struct {
+	CuotasVencidas     int `json:"cuotas_vencidas"`
+	ConveniosRotos     int `json:"convenios_rotos"`
+	ConveniosCumplidos int `json:"convenios_cumplidos"`
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"time"
+
+	"github.com/condominio/backend/pkg/money"
+)
+
+type MovimientoCuentaTipo string
+
+const (
+	MovimientoCuentaSaldoInicial MovimientoCuentaTipo = "saldo_inicial"
+	MovimientoCuentaCargo        MovimientoCuentaTipo = "cargo"
+	MovimientoCuentaInteres      MovimientoCuentaTipo = "interes"
+	MovimientoCuentaMulta        MovimientoCuentaTipo = "multa"
+	MovimientoCuentaAjuste       MovimientoCuentaTipo = "ajuste"
+	MovimientoCuentaPago         MovimientoCuentaTipo = "pago"
+	MovimientoCuentaCredito      MovimientoCuentaTipo = "credito"
+	MovimientoCuentaReembolso    MovimientoCuentaTipo = "reembolso"
+	MovimientoCuentaReverso      MovimientoCuentaTipo = "reverso"
+)
+
+// MovimientoCuenta is a line of a parcela's cuenta corriente. Saldo is the
+// running balance after the line: positive means the parcela owes money,
+// negative means it has credit in its favour.
+type MovimientoCuenta struct {
+	Fecha       time.Time            `json:"fecha"`
+	Tipo        MovimientoCuentaTipo `json:"tipo"`
+	Descripcion string               `json:"descripcion"`
+	Referencia  string               `json:"referencia,omitempty"`
+	Cargo       money.Amount         `json:"cargo"`
+	Abono       money.Amount         `json:"abono"`
+	Saldo       money.Amount         `json:"saldo"`
+}
+
+type CuentaCorriente struct {
+	ParcelaID     int                `json:"parcela_id"`
+	ParcelaNumero string             `json:"parcela_numero"`
+	Desde         *time.Time         `json:"desde,omitempty"`
+	Hasta         *time.Time         `json:"hasta,omitempty"`
+	SaldoAnterior money.Amount       `json:"saldo_anterior"`
+	TotalCargos   money.Amount       `json:"total_cargos"`
+	TotalAbonos   money.Amount       `json:"total_abonos"`
+	SaldoFinal    money.Amount       `json:"saldo_final"`
+	Movimientos   []MovimientoCuenta `json:"movimientos"`
+}
+
+type CuentaCorrienteFilter struct {
+	ParcelaID int
+	Desde     *time.Time // inclusive
+	Hasta     *time.Time // inclusive
+}
+
+type CargoTipo string
+
+const (
+	CargoInteres CargoTipo = "interes"
+	CargoMulta   CargoTipo = "multa"
+	CargoAjuste  CargoTipo = "ajuste"
+)
+
+func (t CargoTipo) IsValid() bool {
+	switch t {
+	case CargoInteres, CargoMulta, CargoAjuste:
+		return true
+	}
+	return false
+}
+
+type CargoParcela struct {
+	ID           string       `json:"id"`
+	ParcelaID    int          `json:"parcela_id"`
+	GastoComunID *string      `json:"gasto_comun_id,omitempty"`
+	Tipo         CargoTipo    `json:"tipo"`
+	Monto        money.Amount `json:"monto"`
+	Fecha        time.Time    `json:"fecha"`
+	Descripcion  string       `json:"descripcion,omitempty"`
+	CreatedBy    *string      `json:"created_by,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+type CreateCargoRequest struct {
+	Tipo         CargoTipo    `json:"tipo"`
+	Monto        money.Amount `json:"monto"` // negative for condonations/adjustments in favour of the parcela
+	Fecha        string       `json:"fecha"` // YYYY-MM-DD
+	Descripcion  string       `json:"descripcion,omitempty"`
+	GastoComunID *string      `json:"gasto_comun_id,omitempty"`
+}
+
+type SaldoInicial struct {
+	ParcelaID   int          `json:"parcela_id"`
+	Monto       money.Amount `json:"monto"`
+	Fecha       time.Time    `json:"fecha"`
+	Descripcion string       `json:"descripcion,omitempty"`
+	CreatedBy   *string      `json:"created_by,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+type SaldoInicialRequest struct {
+	Monto       money.Amount `json:"monto"` // positive = debt, negative = credit
+	Fecha       string       `json:"fecha"` // YYYY-MM-DD
+	Descripcion string       `json:"descripcion,omitempty"`
+}
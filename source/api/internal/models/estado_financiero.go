@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/condominio/backend/pkg/money"
+)
+
+// EstadoFinanciero is the treasury statement of a month or, with Month 0, of
+// a year. Income and expenses exclude transfers between cuentas, which net
+// out across the community; voids net out with their reverse entries.
+//
+// The public version (Publico) is the transparency report residents can
+// download: it carries no per-parcela data.
+type EstadoFinanciero struct {
+	Year            int                 `json:"year"`
+	Month           int                 `json:"month,omitempty"`
+	Desde           time.Time           `json:"desde"`
+	Hasta           time.Time           `json:"hasta"`
+	Publico         bool                `json:"publico"`
+	SaldoInicial    money.Amount        `json:"saldo_inicial"`
+	Ingresos        []LineaEstado       `json:"ingresos"`
+	Egresos         []LineaEstado       `json:"egresos"`
+	TotalIngresos   money.Amount        `json:"total_ingresos"`
+	TotalEgresos    money.Amount        `json:"total_egresos"`
+	Resultado       money.Amount        `json:"resultado"`
+	SaldoFinal      money.Amount        `json:"saldo_final"`
+	Cuentas         []SaldoCuentaEstado `json:"cuentas"`
+	FlujoCaja       []FlujoCajaMes      `json:"flujo_caja"` // January up to the statement month
+	Recaudacion     []RecaudacionMes    `json:"recaudacion"`
+	TasaRecaudacion *float64            `json:"tasa_recaudacion,omitempty"` // % of the period's gastos comunes collected
+	Deudores        []DeudorEstado      `json:"deudores,omitempty"`         // private version only
+	GeneradoAt      time.Time           `json:"generado_at"`
+}
+
+// LineaEstado is the total of a category in the period.
+type LineaEstado struct {
+	Categoria       string       `json:"categoria"`
+	CategoriaNombre string       `json:"categoria_nombre"`
+	Monto           money.Amount `json:"monto"`
+}
+
+type SaldoCuentaEstado struct {
+	CuentaID     string       `json:"cuenta_id"`
+	Nombre       string       `json:"nombre"`
+	SaldoInicial money.Amount `json:"saldo_inicial"`
+	Ingresos     money.Amount `json:"ingresos"` // transfers included
+	Egresos      money.Amount `json:"egresos"`
+	SaldoFinal   money.Amount `json:"saldo_final"`
+}
+
+type FlujoCajaMes struct {
+	Year         int          `json:"year"`
+	Month        int          `json:"month"`
+	SaldoInicial money.Amount `json:"saldo_inicial"`
+	Ingresos     money.Amount `json:"ingresos"`
+	Egresos      money.Amount `json:"egresos"`
+	SaldoFinal   money.Amount `json:"saldo_final"`
+}
+
+// RecaudacionMes is the collection of the gastos comunes issued for a month,
+// as of today.
+type RecaudacionMes struct {
+	Year          int          `json:"year"`
+	Month         int          `json:"month"`
+	Emitido       money.Amount `json:"emitido"`
+	Recaudado     money.Amount `json:"recaudado"`
+	Tasa          *float64     `json:"tasa,omitempty"`
+	Parcelas      int          `json:"parcelas"`
+	ParcelasAlDia int          `json:"parcelas_al_dia"`
+}
+
+// DeudorEstado is a parcela owing gastos comunes issued up to the end of the
+// period.
+type DeudorEstado struct {
+	ParcelaNumero string       `json:"parcela_numero"`
+	Deuda         money.Amount `json:"deuda"`
+}
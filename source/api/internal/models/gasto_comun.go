@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/condominio/backend/pkg/money"
+)
 
 type PagoStatus string
 
@@ -9,83 +13,176 @@ const (
 	PagoStatusPaid      PagoStatus = "paid"
 	PagoStatusOverdue   PagoStatus = "overdue"
 	PagoStatusCancelled PagoStatus = "cancelled"
+	PagoStatusConvenio  PagoStatus = "convenio" // included in an active payment plan
 )
 
 func (s PagoStatus) IsValid() bool {
 	switch s {
-	case PagoStatusPending, PagoStatusPaid, PagoStatusOverdue, PagoStatusCancelled:
+	case PagoStatusPending, PagoStatusPaid, PagoStatusOverdue, PagoStatusCancelled, PagoStatusConvenio:
 		return true
 	}
 	return false
 }
 
+type PeriodoEstado string
+
+const (
+	PeriodoBorrador PeriodoEstado = "borrador" // generated, waiting for directiva approval
+	PeriodoAbierto  PeriodoEstado = "abierto"
+	PeriodoCerrado  PeriodoEstado = "cerrado" // gastos and pagos only change through reversals
+)
+
 type PeriodoGasto struct {
-	ID               string    `json:"id"`
-	Year             int       `json:"year"`
-	Month            int       `json:"month"`
-	MontoBase        float64   `json:"monto_base"`
-	FechaVencimiento time.Time `json:"fecha_vencimiento"`
-	Descripcion      string    `json:"descripcion,omitempty"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	ID               string        `json:"id"`
+	Year             int           `json:"year"`
+	Month            int           `json:"month"`
+	MontoBase        money.Amount  `json:"monto_base"` // CLP; for UF periodos, converted at FechaUF
+	FechaVencimiento time.Time     `json:"fecha_vencimiento"`
+	Descripcion      string        `json:"descripcion,omitempty"`
+	Estado           PeriodoEstado `json:"estado"`
+	Moneda           string        `json:"moneda"` // CLP or UF
+	MontoBaseUF      *float64      `json:"monto_base_uf,omitempty"`
+	ValorUF          *float64      `json:"valor_uf,omitempty"` // provisional while the periodo is a draft
+	FechaUF          *time.Time    `json:"fecha_uf,omitempty"`
+	CerradoAt        *time.Time    `json:"cerrado_at,omitempty"`
+	CreatedAt        time.Time     `json:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at"`
 	// Computed fields
-	TotalParcelas   int     `json:"total_parcelas,omitempty"`
-	TotalPagados    int     `json:"total_pagados,omitempty"`
-	TotalPendientes int     `json:"total_pendientes,omitempty"`
-	MontoRecaudado  float64 `json:"monto_recaudado,omitempty"`
-	MontoPendiente  float64 `json:"monto_pendiente,omitempty"`
+	TotalParcelas    int          `json:"total_parcelas,omitempty"`
+	TotalPagados     int          `json:"total_pagados,omitempty"`
+	TotalPendientes  int          `json:"total_pendientes,omitempty"`
+	MontoRecaudado   money.Amount `json:"monto_recaudado,omitempty"`
+	MontoPendiente   money.Amount `json:"monto_pendiente,omitempty"`
+	MontoRecaudadoUF *float64     `json:"monto_recaudado_uf,omitempty"`
+	MontoPendienteUF *float64     `json:"monto_pendiente_uf,omitempty"`
 }
 
 type GastoComun struct {
-	ID             string     `json:"id"`
-	PeriodoID      string     `json:"periodo_id"`
-	ParcelaID      int        `json:"parcela_id"`
-	ParcelaNumero  string     `json:"parcela_numero,omitempty"`
-	UserID         *string    `json:"user_id,omitempty"`
-	UserName       string     `json:"user_name,omitempty"`
-	Monto          float64    `json:"monto"`
-	MontoPagado    float64    `json:"monto_pagado"`
-	Status         PagoStatus `json:"status"`
-	FechaPago      *time.Time `json:"fecha_pago,omitempty"`
-	MetodoPago     string     `json:"metodo_pago,omitempty"`
-	ReferenciaPago string     `json:"referencia_pago,omitempty"`
-	CreatedAt      time.Time  `json:"created_at"`
-	UpdatedAt      time.Time  `json:"updated_at"`
+	ID             string       `json:"id"`
+	PeriodoID      string       `json:"periodo_id"`
+	ParcelaID      int          `json:"parcela_id"`
+	ParcelaNumero  string       `json:"parcela_numero,omitempty"`
+	UserID         *string      `json:"user_id,omitempty"`
+	UserName       string       `json:"user_name,omitempty"`
+	Monto          money.Amount `json:"monto"`
+	MontoPagado    money.Amount `json:"monto_pagado"`
+	Status         PagoStatus   `json:"status"`
+	FechaPago      *time.Time   `json:"fecha_pago,omitempty"`
+	MetodoPago     string       `json:"metodo_pago,omitempty"`
+	ReferenciaPago string       `json:"referencia_pago,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
 	// Related data
 	Periodo *PeriodoGasto `json:"periodo,omitempty"`
 }
 
+const (
+	PagoEstadoPending  = "pending"
+	PagoEstadoApproved = "approved"
+	PagoEstadoRejected = "rejected"
+	PagoEstadoReversed = "reversed"
+)
+
+// MetodoPagoCredito marks pagos funded from the parcela's credit balance
+const MetodoPagoCredito = "credito"
+
 type Pago struct {
-	ID                string    `json:"id"`
-	GastoComunID      string    `json:"gasto_comun_id"`
-	Monto             float64   `json:"monto"`
-	Metodo            string    `json:"metodo"` // transbank, mercadopago, transferencia, efectivo
-	ReferenciaExterna string    `json:"referencia_externa,omitempty"`
-	Estado            string    `json:"estado"` // pending, approved, rejected
-	Detalles          string    `json:"detalles,omitempty"`
-	CreatedAt         time.Time `json:"created_at"`
+	ID                string       `json:"id"`
+	GastoComunID      string       `json:"gasto_comun_id"`
+	Monto             money.Amount `json:"monto"`
+	Metodo            string       `json:"metodo"` // transbank, mercadopago, transferencia, efectivo, credito
+	ReferenciaExterna string       `json:"referencia_externa,omitempty"`
+	Estado            string       `json:"estado"` // pending, approved, rejected, reversed
+	Detalles          string       `json:"detalles,omitempty"`
+	ReversedAt        *time.Time   `json:"reversed_at,omitempty"`
+	ReversedBy        *string      `json:"reversed_by,omitempty"`
+	MotivoReverso     string       `json:"motivo_reverso,omitempty"`
+	CreatedAt         time.Time    `json:"created_at"`
+}
+
+type CreditoTipo string
+
+const (
+	CreditoSobrepago  CreditoTipo = "sobrepago"  // excess of a pago over the pending balance
+	CreditoAplicacion CreditoTipo = "aplicacion" // credit used to pay a gasto
+	CreditoReverso    CreditoTipo = "reverso"    // undo of a sobrepago/aplicacion when its pago is reversed
+	CreditoReembolso  CreditoTipo = "reembolso"  // credit returned to the neighbour
+)
+
+// MovimientoCredito is an entry of a parcela's credit ledger. Positive amounts
+// add credit, negative amounts consume it.
+type MovimientoCredito struct {
+	ID           string       `json:"id"`
+	ParcelaID    int          `json:"parcela_id"`
+	Monto        money.Amount `json:"monto"`
+	Tipo         CreditoTipo  `json:"tipo"`
+	PagoID       *string      `json:"pago_id,omitempty"`
+	GastoComunID *string      `json:"gasto_comun_id,omitempty"`
+	Descripcion  string       `json:"descripcion,omitempty"`
+	CreatedBy    *string      `json:"created_by,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+type SaldoCredito struct {
+	ParcelaID     int                 `json:"parcela_id"`
+	ParcelaNumero string              `json:"parcela_numero"`
+	Saldo         money.Amount        `json:"saldo"`
+	Movimientos   []MovimientoCredito `json:"movimientos"`
 }
 
 // Request/Response types
 
 type CreatePeriodoRequest struct {
-	Year             int     `json:"year"`
-	Month            int     `json:"month"`
-	MontoBase        float64 `json:"monto_base"`
-	FechaVencimiento string  `json:"fecha_vencimiento"` // YYYY-MM-DD
-	Descripcion      string  `json:"descripcion,omitempty"`
+	Year             int          `json:"year"`
+	Month            int          `json:"month"`
+	MontoBase        money.Amount `json:"monto_base"`
+	MontoBaseUF      float64      `json:"monto_base_uf,omitempty"` // instead of monto_base, converted at the issue date
+	FechaVencimiento string       `json:"fecha_vencimiento"`       // YYYY-MM-DD
+	Descripcion      string       `json:"descripcion,omitempty"`
+	Borrador         bool         `json:"borrador,omitempty"` // create as draft, without gastos
 }
 
 type UpdatePeriodoRequest struct {
-	MontoBase        *float64 `json:"monto_base,omitempty"`
-	FechaVencimiento *string  `json:"fecha_vencimiento,omitempty"`
-	Descripcion      *string  `json:"descripcion,omitempty"`
+	MontoBase        *money.Amount `json:"monto_base,omitempty"`
+	MontoBaseUF      *float64      `json:"monto_base_uf,omitempty"`
+	FechaVencimiento *string       `json:"fecha_vencimiento,omitempty"`
+	Descripcion      *string       `json:"descripcion,omitempty"`
 }
 
 type RegistrarPagoRequest struct {
-	Monto             float64 `json:"monto"`
-	Metodo            string  `json:"metodo"` // transbank, mercadopago, transferencia, efectivo
-	ReferenciaExterna string  `json:"referencia_externa,omitempty"`
+	Monto             money.Amount `json:"monto"`
+	Metodo            string       `json:"metodo"` // transbank, mercadopago, transferencia, efectivo
+	ReferenciaExterna string       `json:"referencia_externa,omitempty"`
+}
+
+// InconsistenciaPago is a gasto whose monto_pagado disagrees with the sum of
+// its approved pagos
+type InconsistenciaPago struct {
+	GastoComunID  string       `json:"gasto_comun_id"`
+	PeriodoID     string       `json:"periodo_id"`
+	Year          int          `json:"year"`
+	Month         int          `json:"month"`
+	ParcelaID     int          `json:"parcela_id"`
+	ParcelaNumero string       `json:"parcela_numero"`
+	Status        PagoStatus   `json:"status"`
+	Monto         money.Amount `json:"monto"`
+	MontoPagado   money.Amount `json:"monto_pagado"`
+	MontoLedger   money.Amount `json:"monto_ledger"`
+	Diferencia    money.Amount `json:"diferencia"`
+}
+
+type ReversarPagoRequest struct {
+	Motivo string `json:"motivo"`
+}
+
+type CerrarPeriodoRequest struct {
+	Motivo string `json:"motivo,omitempty"`
+}
+
+type ReembolsoCreditoRequest struct {
+	Monto  money.Amount `json:"monto"`
+	Metodo string       `json:"metodo"` // transferencia, efectivo
+	Motivo string       `json:"motivo"`
 }
 
 type PeriodoListResponse struct {
@@ -103,9 +200,10 @@ type GastoComunListResponse struct {
 }
 
 type PeriodoFilter struct {
-	Year    int
-	Page    int
-	PerPage int
+	Year              int
+	IncluirBorradores bool
+	Page              int
+	PerPage           int
 }
 
 type GastoComunFilter struct {
@@ -117,24 +215,26 @@ type GastoComunFilter struct {
 }
 
 type ResumenGastos struct {
-	Periodo            PeriodoGasto `json:"periodo"`
-	TotalParcelas      int          `json:"total_parcelas"`
-	TotalPagados       int          `json:"total_pagados"`
-	TotalPendientes    int          `json:"total_pendientes"`
-	TotalVencidos      int          `json:"total_vencidos"`
-	MontoTotal         float64      `json:"monto_total"`
-	MontoRecaudado     float64      `json:"monto_recaudado"`
-	MontoPendiente     float64      `json:"monto_pendiente"`
-	PorcentajeRecaudo  float64      `json:"porcentaje_recaudo"`
+	Periodo           PeriodoGasto `json:"periodo"`
+	TotalParcelas     int          `json:"total_parcelas"`
+	TotalPagados      int          `json:"total_pagados"`
+	TotalPendientes   int          `json:"total_pendientes"`
+	TotalVencidos     int          `json:"total_vencidos"`
+	MontoTotal        money.Amount `json:"monto_total"`
+	MontoRecaudado    money.Amount `json:"monto_recaudado"`
+	MontoPendiente    money.Amount `json:"monto_pendiente"`
+	PorcentajeRecaudo float64      `json:"porcentaje_recaudo"`
+	MontoTotalUF      *float64     `json:"monto_total_uf,omitempty"`
 }
 
 type MiEstadoCuenta struct {
-	HasParcela      bool           `json:"has_parcela"`
-	Message         string         `json:"message,omitempty"`
-	ParcelaID      int            `json:"parcela_id"`
-	ParcelaNumero  string         `json:"parcela_numero"`
+	HasParcela       bool         `json:"has_parcela"`
+	Message          string       `json:"message,omitempty"`
+	ParcelaID        int          `json:"parcela_id"`
+	ParcelaNumero    string       `json:"parcela_numero"`
 	GastosPendientes []GastoComun `json:"gastos_pendientes"`
 	GastosPagados    []GastoComun `json:"gastos_pagados"`
-	TotalPendiente   float64      `json:"total_pendiente"`
-	TotalPagado      float64      `json:"total_pagado"`
+	TotalPendiente   money.Amount `json:"total_pendiente"`
+	TotalPagado      money.Amount `json:"total_pagado"`
+	SaldoCredito     money.Amount `json:"saldo_credito"`
 }
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"time"
+
+	"github.com/condominio/backend/pkg/money"
+)
+
+// FormatoImportacion describes the CSV layout of a bank export. Columns are
+// header names when TieneEncabezado is set, otherwise 1-based positions.
+type FormatoImportacion struct {
+	ID                string    `json:"id"`
+	Nombre            string    `json:"nombre"`
+	Separador         string    `json:"separador"`
+	TieneEncabezado   bool      `json:"tiene_encabezado"`
+	FilasOmitir       int       `json:"filas_omitir"`
+	ColumnaFecha      string    `json:"columna_fecha"`
+	ColumnaMonto      string    `json:"columna_monto"`
+	ColumnaRUT        string    `json:"columna_rut,omitempty"`
+	ColumnaNombre     string    `json:"columna_nombre,omitempty"`
+	ColumnaDesc       string    `json:"columna_descripcion,omitempty"`
+	ColumnaReferencia string    `json:"columna_referencia,omitempty"`
+	FormatoFecha      string    `json:"formato_fecha"`     // DD/MM/YYYY, YYYY-MM-DD, ...
+	SeparadorDecimal  string    `json:"separador_decimal"` // "," or "."
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type FormatoImportacionRequest struct {
+	Nombre            string `json:"nombre"`
+	Separador         string `json:"separador"`
+	TieneEncabezado   bool   `json:"tiene_encabezado"`
+	FilasOmitir       int    `json:"filas_omitir"`
+	ColumnaFecha      string `json:"columna_fecha"`
+	ColumnaMonto      string `json:"columna_monto"`
+	ColumnaRUT        string `json:"columna_rut,omitempty"`
+	ColumnaNombre     string `json:"columna_nombre,omitempty"`
+	ColumnaDesc       string `json:"columna_descripcion,omitempty"`
+	ColumnaReferencia string `json:"columna_referencia,omitempty"`
+	FormatoFecha      string `json:"formato_fecha"`
+	SeparadorDecimal  string `json:"separador_decimal"`
+}
+
+type ImportacionEstado string
+
+const (
+	ImportacionStaging    ImportacionEstado = "staging"
+	ImportacionConfirmada ImportacionEstado = "confirmada"
+	ImportacionDescartada ImportacionEstado = "descartada"
+)
+
+type FilaImportacionEstado string
+
+const (
+	FilaPendiente FilaImportacionEstado = "pendiente" // waiting for the treasurer
+	FilaIgnorada  FilaImportacionEstado = "ignorada"  // not a gasto payment
+	FilaDuplicada FilaImportacionEstado = "duplicada" // already imported before
+	FilaError     FilaImportacionEstado = "error"     // could not be parsed
+	FilaImportada FilaImportacionEstado = "importada" // pago registered
+)
+
+// Why a row was matched to a parcela
+const (
+	MatchReferencia = "referencia" // description mentions the parcela number
+	MatchRUT        = "rut"        // RUT paid this parcela in earlier imports
+	MatchNombre     = "nombre"     // payer name is a resident of the parcela
+	MatchMonto      = "monto"      // only one parcela owes exactly this amount
+	MatchManual     = "manual"     // set by the treasurer
+)
+
+type ImportacionBanco struct {
+	ID            string            `json:"id"`
+	FormatoID     *string           `json:"formato_id,omitempty"`
+	ArchivoNombre string            `json:"archivo_nombre"`
+	Estado        ImportacionEstado `json:"estado"`
+	CreatedBy     *string           `json:"created_by,omitempty"`
+	ConfirmadaBy  *string           `json:"confirmada_by,omitempty"`
+	ConfirmadaAt  *time.Time        `json:"confirmada_at,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	// Computed fields
+	TotalFilas     int          `json:"total_filas"`
+	FilasPendiente int          `json:"filas_pendiente"`
+	FilasSinMatch  int          `json:"filas_sin_match"`
+	FilasImportada int          `json:"filas_importada"`
+	MontoPendiente money.Amount `json:"monto_pendiente"`
+	// Related data
+	Filas []FilaImportacion `json:"filas,omitempty"`
+}
+
+type FilaImportacion struct {
+	ID            string                `json:"id"`
+	Linea         int                   `json:"linea"`
+	Fecha         *time.Time            `json:"fecha,omitempty"`
+	Monto         money.Amount          `json:"monto"`
+	RUT           string                `json:"rut,omitempty"`
+	Nombre        string                `json:"nombre,omitempty"`
+	Descripcion   string                `json:"descripcion,omitempty"`
+	Referencia    string                `json:"referencia,omitempty"`
+	Estado        FilaImportacionEstado `json:"estado"`
+	ParcelaID     *int                  `json:"parcela_id,omitempty"`
+	ParcelaNumero string                `json:"parcela_numero,omitempty"`
+	GastoComunID  *string               `json:"gasto_comun_id,omitempty"`
+	Match         string                `json:"match,omitempty"`
+	Error         string                `json:"error,omitempty"`
+	PagoID        *string               `json:"pago_id,omitempty"`
+}
+
+// UpdateFilaImportacionRequest corrects the proposed match of a row. Setting
+// Ignorar excludes it from the import; a parcela without gasto uses its
+// oldest gasto with balance.
+type UpdateFilaImportacionRequest struct {
+	ParcelaID    *int    `json:"parcela_id,omitempty"`
+	GastoComunID *string `json:"gasto_comun_id,omitempty"`
+	Ignorar      bool    `json:"ignorar"`
+}
+
+type ImportacionFilter struct {
+	Estado  ImportacionEstado
+	Page    int
+	PerPage int
+}
+
+type ImportacionListResponse struct {
+	Importaciones []ImportacionBanco `json:"importaciones"`
+	Total         int                `json:"total"`
+	Page          int                `json:"page"`
+	PerPage       int                `json:"per_page"`
+}
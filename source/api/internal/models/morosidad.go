@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/condominio/backend/pkg/money"
+)
+
+// MorosidadParcela is a row of the delinquency report: what a parcela owes on
+// gastos past their due date, split by how many days overdue each one is.
+type MorosidadParcela struct {
+	ParcelaID      int          `json:"parcela_id"`
+	ParcelaNumero  string       `json:"parcela_numero"`
+	Direccion      string       `json:"direccion,omitempty"`
+	Contacto       string       `json:"contacto,omitempty"`
+	Email          string       `json:"email,omitempty"`
+	DeudaTotal     money.Amount `json:"deuda_total"`
+	DeudaTotalUF   *float64     `json:"deuda_total_uf,omitempty"`
+	Tramo0a30      money.Amount `json:"tramo_0_30"`
+	Tramo31a60     money.Amount `json:"tramo_31_60"`
+	Tramo61a90     money.Amount `json:"tramo_61_90"`
+	TramoMas90     money.Amount `json:"tramo_90_mas"`
+	MesesAdeudados int          `json:"meses_adeudados"`
+	DiasMaxAtraso  int          `json:"dias_max_atraso"`
+	UltimoPago     *time.Time   `json:"ultimo_pago,omitempty"`
+	EnConvenio     bool         `json:"en_convenio"`
+}
+
+type MorosidadFilter struct {
+	Sort  string // parcela, deuda, atraso, meses, ultimo_pago
+	Order string // asc, desc
+}
+
+type ReporteMorosidad struct {
+	FechaCorte    time.Time          `json:"fecha_corte"`
+	Parcelas      []MorosidadParcela `json:"parcelas"`
+	TotalParcelas int                `json:"total_parcelas"`
+	DeudaTotal    money.Amount       `json:"deuda_total"`
+	ValorUF       *float64           `json:"valor_uf,omitempty"` // latest UF value at fecha_corte
+	DeudaTotalUF  *float64           `json:"deuda_total_uf,omitempty"`
+	Tramo0a30     money.Amount       `json:"tramo_0_30"`
+	Tramo31a60    money.Amount       `json:"tramo_31_60"`
+	Tramo61a90    money.Amount       `json:"tramo_61_90"`
+	TramoMas90    money.Amount       `json:"tramo_90_mas"`
+}
+
+// MorosidadMes is the delinquency at the end of a month, as it stood then.
+type MorosidadMes struct {
+	Year            int          `json:"year"`
+	Month           int          `json:"month"`
+	FechaCorte      time.Time    `json:"fecha_corte"`
+	DeudaTotal      money.Amount `json:"deuda_total"`
+	ParcelasMorosas int          `json:"parcelas_morosas"`
+}
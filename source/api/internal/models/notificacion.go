@@ -14,6 +14,7 @@ const (
 	NotificationTypeActa        NotificationType = "acta"
 	NotificationTypeContacto    NotificationType = "contacto"
 	NotificationTypeGastoComun  NotificationType = "gasto_comun"
+	NotificationTypeTesoreria   NotificationType = "tesoreria"
 	NotificationTypeSistema     NotificationType = "sistema"
 )
 
@@ -83,8 +84,21 @@ func (nt NotificationType) IsValid() bool {
 	case NotificationTypeComunicado, NotificationTypeEmergencia, NotificationTypeVotacion,
 		NotificationTypePago, NotificationTypeEvento, NotificationTypeDocumento,
 		NotificationTypeActa, NotificationTypeContacto, NotificationTypeGastoComun,
-		NotificationTypeSistema:
+		NotificationTypeTesoreria, NotificationTypeSistema:
 		return true
 	}
 	return false
 }
+
+// PreferenciasNotificacion controls which automatic gasto reminders a user gets
+type PreferenciasNotificacion struct {
+	UserID      string    `json:"user_id"`
+	EmailGastos bool      `json:"email_gastos"`
+	AppGastos   bool      `json:"app_gastos"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type UpdatePreferenciasRequest struct {
+	EmailGastos *bool `json:"email_gastos,omitempty"`
+	AppGastos   *bool `json:"app_gastos,omitempty"`
+}
@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+type PoderEstado string
+
+const (
+	PoderPendiente PoderEstado = "pendiente"
+	PoderValidado  PoderEstado = "validado"
+	PoderRechazado PoderEstado = "rechazado"
+	PoderRevocado  PoderEstado = "revocado"
+)
+
+// Poder is the written proxy a parcela gives a neighbour (the apoderado) to
+// vote on its behalf, either in one votacion or in every votacion of an
+// asamblea. It can only be used once the directiva has validated it against
+// the signed document, and within its validity dates.
+type Poder struct {
+	ID              string          `json:"id"`
+	ParcelaID       int             `json:"parcela_id"`
+	ParcelaNumero   string          `json:"parcela_numero"`
+	ApoderadoID     string          `json:"apoderado_id"`
+	ApoderadoNombre string          `json:"apoderado_nombre"`
+	VotacionID      *string         `json:"votacion_id,omitempty"`
+	VotacionTitle   string          `json:"votacion_title,omitempty"`
+	EventoID        *string         `json:"evento_id,omitempty"` // asamblea
+	EventoTitle     string          `json:"evento_title,omitempty"`
+	ValidoDesde     time.Time       `json:"valido_desde"`
+	ValidoHasta     time.Time       `json:"valido_hasta"`
+	Estado          PoderEstado     `json:"estado"`
+	Documento       *DocumentoPoder `json:"documento,omitempty"`
+	MotivoRechazo   string          `json:"motivo_rechazo,omitempty"`
+	RevisadoBy      *string         `json:"revisado_by,omitempty"` // validated or rejected by
+	RevisadoAt      *time.Time      `json:"revisado_at,omitempty"`
+	Usado           bool            `json:"usado"` // a ballot was cast with it
+	CreatedBy       *string         `json:"created_by,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+// DocumentoPoder is the signed poder, a PDF or a picture of it.
+type DocumentoPoder struct {
+	NombreArchivo string `json:"nombre_archivo"`
+	ContentType   string `json:"content_type"`
+	Tamano        int    `json:"tamano"`
+	Contenido     []byte `json:"-"`
+}
+
+// CreatePoderRequest registers a poder. Residents grant it for their own
+// parcela; the directiva may register paper poderes for any parcela_id.
+// Exactly one of votacion_id and evento_id sets the scope.
+type CreatePoderRequest struct {
+	ParcelaID   *int       `json:"parcela_id,omitempty"`
+	ApoderadoID string     `json:"apoderado_id"`
+	VotacionID  *string    `json:"votacion_id,omitempty"`
+	EventoID    *string    `json:"evento_id,omitempty"`
+	ValidoDesde *time.Time `json:"valido_desde,omitempty"` // default: now
+	ValidoHasta time.Time  `json:"valido_hasta"`
+}
+
+type RechazarPoderRequest struct {
+	Motivo string `json:"motivo"`
+}
+
+type PoderFilter struct {
+	Estado     PoderEstado
+	VotacionID string
+	EventoID   string
+}
+
+// PoderVoto is a parcela the user may vote for in a votacion through a
+// validated poder.
+type PoderVoto struct {
+	PoderID       string `json:"poder_id"`
+	ParcelaID     int    `json:"parcela_id"`
+	ParcelaNumero string `json:"parcela_numero"`
+	HasVoted      bool   `json:"has_voted"`
+}
+
+// VotoPoder is a ballot cast by an apoderado, listed in the results.
+type VotoPoder struct {
+	PoderID       string    `json:"poder_id"`
+	ParcelaNumero string    `json:"parcela_numero"`
+	Apoderado     string    `json:"apoderado"`
+	VotedAt       time.Time `json:"voted_at"`
+}
+
+func (e PoderEstado) IsValid() bool {
+	switch e {
+	case PoderPendiente, PoderValidado, PoderRechazado, PoderRevocado:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"time"
+
+	"github.com/condominio/backend/pkg/money"
+)
+
+// CategoriaTesoreria is an entry of the catalogue movimientos are classified
+// with. The codigo is what movimientos store; categories are deactivated,
+// never deleted, so past movimientos keep theirs.
+type CategoriaTesoreria struct {
+	ID        string         `json:"id"`
+	Codigo    string         `json:"codigo"`
+	Nombre    string         `json:"nombre"`
+	Tipo      MovimientoType `json:"tipo"`
+	Activa    bool           `json:"activa"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+type CreateCategoriaRequest struct {
+	Codigo string         `json:"codigo"`
+	Nombre string         `json:"nombre"`
+	Tipo   MovimientoType `json:"tipo"`
+}
+
+type UpdateCategoriaRequest struct {
+	Nombre *string `json:"nombre,omitempty"`
+	Activa *bool   `json:"activa,omitempty"`
+}
+
+// Presupuesto is the budget approved by the assembly for a year.
+// UmbralAlerta is the percentage of a category's annual amount that, once
+// executed, notifies the directiva; an item may override it.
+type Presupuesto struct {
+	ID            string            `json:"id"`
+	Year          int               `json:"year"`
+	Descripcion   string            `json:"descripcion,omitempty"`
+	UmbralAlerta  float64           `json:"umbral_alerta"`
+	TotalIngresos money.Amount      `json:"total_ingresos"`
+	TotalEgresos  money.Amount      `json:"total_egresos"`
+	CreatedBy     *string           `json:"created_by,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	Items         []PresupuestoItem `json:"items,omitempty"`
+}
+
+type PresupuestoItem struct {
+	ID              string         `json:"id"`
+	Categoria       string         `json:"categoria"`
+	CategoriaNombre string         `json:"categoria_nombre"`
+	Tipo            MovimientoType `json:"tipo"`
+	MontoAnual      money.Amount   `json:"monto_anual"`
+	UmbralAlerta    *float64       `json:"umbral_alerta,omitempty"`
+	AlertadoAt      *time.Time     `json:"alertado_at,omitempty"`
+}
+
+// SetPresupuestoRequest creates or replaces the budget of a year; the items
+// sent replace the previous ones.
+type SetPresupuestoRequest struct {
+	Descripcion  string                   `json:"descripcion"`
+	UmbralAlerta *float64                 `json:"umbral_alerta,omitempty"`
+	Items        []PresupuestoItemRequest `json:"items"`
+}
+
+type PresupuestoItemRequest struct {
+	Categoria    string       `json:"categoria"`
+	MontoAnual   money.Amount `json:"monto_anual"`
+	UmbralAlerta *float64     `json:"umbral_alerta,omitempty"`
+}
+
+// EjecucionPresupuesto compares the budget of a year with the movimientos
+// booked up to the end of Month. Budgeted amounts to date assume an even
+// distribution of the annual amount over the twelve months.
+type EjecucionPresupuesto struct {
+	Year       int                  `json:"year"`
+	Month      int                  `json:"month"`
+	Categorias []EjecucionCategoria `json:"categorias"`
+	Ingresos   EjecucionTotales     `json:"ingresos"`
+	Egresos    EjecucionTotales     `json:"egresos"`
+	Alertas    int                  `json:"alertas"`
+}
+
+type EjecucionCategoria struct {
+	Categoria           string         `json:"categoria"`
+	CategoriaNombre     string         `json:"categoria_nombre"`
+	Tipo                MovimientoType `json:"tipo"`
+	Presupuestado       money.Amount   `json:"presupuestado"` // annual
+	PresupuestadoMes    money.Amount   `json:"presupuestado_mes"`
+	EjecutadoMes        money.Amount   `json:"ejecutado_mes"`
+	PresupuestadoAlMes  money.Amount   `json:"presupuestado_al_mes"`
+	EjecutadoAlMes      money.Amount   `json:"ejecutado_al_mes"`
+	Diferencia          money.Amount   `json:"diferencia"`           // presupuestado_al_mes - ejecutado_al_mes
+	PorcentajeEjecutado *float64       `json:"porcentaje_ejecutado"` // of the annual amount; nil without budget
+	UmbralAlerta        float64        `json:"umbral_alerta"`
+	Alerta              bool           `json:"alerta"`
+	SinPresupuesto      bool           `json:"sin_presupuesto,omitempty"`
+}
+
+type EjecucionTotales struct {
+	Presupuestado       money.Amount `json:"presupuestado"`
+	PresupuestadoAlMes  money.Amount `json:"presupuestado_al_mes"`
+	EjecutadoAlMes      money.Amount `json:"ejecutado_al_mes"`
+	Diferencia          money.Amount `json:"diferencia"`
+	PorcentajeEjecutado *float64     `json:"porcentaje_ejecutado"`
+}
@@ -0,0 +1,183 @@
+package models
+
+import (
+	"time"
+
+	"github.com/condominio/backend/pkg/money"
+)
+
+// Proveedor is a supplier the community pays. RUT is stored normalized
+// (12345678K); bank data is what the treasurer needs to transfer to it.
+type Proveedor struct {
+	ID              string    `json:"id"`
+	Nombre          string    `json:"nombre"`
+	RUT             string    `json:"rut"`
+	Email           string    `json:"email,omitempty"`
+	Telefono        string    `json:"telefono,omitempty"`
+	Banco           string    `json:"banco,omitempty"`
+	TipoCuenta      string    `json:"tipo_cuenta,omitempty"`
+	NumeroCuenta    string    `json:"numero_cuenta,omitempty"`
+	Categoria       string    `json:"categoria,omitempty"` // default category of its facturas
+	CategoriaNombre string    `json:"categoria_nombre,omitempty"`
+	Notas           string    `json:"notas,omitempty"`
+	Activo          bool      `json:"activo"`
+	FacturasPend    int       `json:"facturas_pendientes"`
+	CreatedBy       *string   `json:"created_by,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type CreateProveedorRequest struct {
+	Nombre       string `json:"nombre"`
+	RUT          string `json:"rut"`
+	Email        string `json:"email"`
+	Telefono     string `json:"telefono"`
+	Banco        string `json:"banco"`
+	TipoCuenta   string `json:"tipo_cuenta"`
+	NumeroCuenta string `json:"numero_cuenta"`
+	Categoria    string `json:"categoria"`
+	Notas        string `json:"notas"`
+}
+
+type UpdateProveedorRequest struct {
+	Nombre       *string `json:"nombre,omitempty"`
+	RUT          *string `json:"rut,omitempty"`
+	Email        *string `json:"email,omitempty"`
+	Telefono     *string `json:"telefono,omitempty"`
+	Banco        *string `json:"banco,omitempty"`
+	TipoCuenta   *string `json:"tipo_cuenta,omitempty"`
+	NumeroCuenta *string `json:"numero_cuenta,omitempty"`
+	Categoria    *string `json:"categoria,omitempty"`
+	Notas        *string `json:"notas,omitempty"`
+	Activo       *bool   `json:"activo,omitempty"`
+}
+
+// ============================================
+// FACTURAS
+// ============================================
+
+type FacturaEstado string
+
+const (
+	// Waiting for the approvals its amount requires
+	FacturaPendiente FacturaEstado = "pendiente"
+	// Approved and ready to be paid
+	FacturaAprobada  FacturaEstado = "aprobada"
+	FacturaRechazada FacturaEstado = "rechazada"
+	// Paid: the tesorería egreso is MovimientoID
+	FacturaPagada  FacturaEstado = "pagada"
+	FacturaAnulada FacturaEstado = "anulada"
+)
+
+// Factura is a supplier invoice. It is paid only once the number of
+// directiva approvals required by its amount is reached; paying it books the
+// egreso in tesorería.
+type Factura struct {
+	ID                     string              `json:"id"`
+	ProveedorID            string              `json:"proveedor_id"`
+	ProveedorNombre        string              `json:"proveedor_nombre"`
+	ProveedorRUT           string              `json:"proveedor_rut"`
+	Numero                 string              `json:"numero"`
+	Descripcion            string              `json:"descripcion"`
+	Categoria              string              `json:"categoria,omitempty"`
+	Monto                  money.Amount        `json:"monto"`
+	FechaEmision           time.Time           `json:"fecha_emision"`
+	FechaVencimiento       *time.Time          `json:"fecha_vencimiento,omitempty"`
+	Vencida                bool                `json:"vencida"`
+	Estado                 FacturaEstado       `json:"estado"`
+	AprobacionesRequeridas int                 `json:"aprobaciones_requeridas"`
+	TotalAprobaciones      int                 `json:"total_aprobaciones"`
+	MovimientoID           *string             `json:"movimiento_id,omitempty"`
+	PagadaAt               *time.Time          `json:"pagada_at,omitempty"`
+	PagadaBy               *string             `json:"pagada_by,omitempty"`
+	MotivoAnulacion        string              `json:"motivo_anulacion,omitempty"`
+	TotalAdjuntos          int                 `json:"total_adjuntos"`
+	CreatedBy              *string             `json:"created_by,omitempty"`
+	CreatorName            string              `json:"creator_name,omitempty"`
+	CreatedAt              time.Time           `json:"created_at"`
+	UpdatedAt              time.Time           `json:"updated_at"`
+	Aprobaciones           []FacturaAprobacion `json:"aprobaciones,omitempty"`
+	Adjuntos               []AdjuntoFactura    `json:"adjuntos,omitempty"`
+}
+
+// FacturaAprobacion is the signature of a directiva member on a factura.
+type FacturaAprobacion struct {
+	UserID     string    `json:"user_id"`
+	UserName   string    `json:"user_name"`
+	Aprobada   bool      `json:"aprobada"` // false: rejected
+	Comentario string    `json:"comentario,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type AdjuntoFactura struct {
+	ID            string    `json:"id"`
+	FacturaID     string    `json:"factura_id"`
+	NombreArchivo string    `json:"nombre_archivo"`
+	ContentType   string    `json:"content_type"`
+	Tamano        int       `json:"tamano"`
+	CreatedBy     *string   `json:"created_by,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	Contenido     []byte    `json:"-"`
+}
+
+type CreateFacturaRequest struct {
+	ProveedorID      string       `json:"proveedor_id"`
+	Numero           string       `json:"numero"`
+	Descripcion      string       `json:"descripcion"`
+	Categoria        string       `json:"categoria"` // default: the proveedor's
+	Monto            money.Amount `json:"monto"`
+	FechaEmision     time.Time    `json:"fecha_emision"`
+	FechaVencimiento *time.Time   `json:"fecha_vencimiento,omitempty"`
+}
+
+// UpdateFacturaRequest corrects a factura still pending; its approvals are
+// discarded because they were given to the previous data.
+type UpdateFacturaRequest struct {
+	Numero           *string       `json:"numero,omitempty"`
+	Descripcion      *string       `json:"descripcion,omitempty"`
+	Categoria        *string       `json:"categoria,omitempty"`
+	Monto            *money.Amount `json:"monto,omitempty"`
+	FechaEmision     *time.Time    `json:"fecha_emision,omitempty"`
+	FechaVencimiento *time.Time    `json:"fecha_vencimiento,omitempty"`
+}
+
+type AprobarFacturaRequest struct {
+	Comentario string `json:"comentario"`
+}
+
+type RechazarFacturaRequest struct {
+	Motivo string `json:"motivo"`
+}
+
+// PagarFacturaRequest books the egreso of an approved factura.
+type PagarFacturaRequest struct {
+	CuentaID string     `json:"cuenta_id"` // default: the principal cuenta
+	Fecha    *time.Time `json:"fecha,omitempty"`
+}
+
+type FacturaFilter struct {
+	ProveedorID string
+	Estado      FacturaEstado
+	Vencidas    bool
+	Page        int
+	PerPage     int
+}
+
+type FacturaListResponse struct {
+	Facturas []Factura `json:"facturas"`
+	Total    int       `json:"total"`
+	Page     int       `json:"page"`
+	PerPage  int       `json:"per_page"`
+}
+
+// ReglaAprobacion requires Aprobaciones distinct directiva signatures for
+// facturas of MontoDesde or more. The rule with the highest MontoDesde not
+// above the amount applies.
+type ReglaAprobacion struct {
+	MontoDesde   money.Amount `json:"monto_desde"`
+	Aprobaciones int          `json:"aprobaciones"`
+}
+
+type SetReglasAprobacionRequest struct {
+	Reglas []ReglaAprobacion `json:"reglas"`
+}
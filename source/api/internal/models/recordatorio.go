@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+type RecordatorioTipo string
+
+const (
+	RecordatorioEmision RecordatorioTipo = "emision"
+	RecordatorioPrevio  RecordatorioTipo = "previo"
+	RecordatorioVencido RecordatorioTipo = "vencido"
+)
+
+// RecordatorioConfig selects which reminders are sent. DiasAntes lists the
+// days before fecha_vencimiento at which a reminder goes out, e.g. [7, 1].
+type RecordatorioConfig struct {
+	Emision     bool
+	DiasAntes   []int
+	Vencido     bool
+	FrontendURL string
+}
+
+// RecordatorioGasto is a reminder already sent for a gasto.
+type RecordatorioGasto struct {
+	ID             string           `json:"id"`
+	GastoComunID   string           `json:"gasto_comun_id"`
+	Tipo           RecordatorioTipo `json:"tipo"`
+	Dias           int              `json:"dias"`
+	Notificaciones int              `json:"notificaciones"`
+	Emails         int              `json:"emails"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
+
+type ProcesarRecordatoriosResult struct {
+	Emision        int `json:"emision"`
+	Previos        int `json:"previos"`
+	Vencidos       int `json:"vencidos"`
+	Notificaciones int `json:"notificaciones"`
+	Emails         int `json:"emails"`
+}
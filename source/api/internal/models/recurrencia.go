@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/condominio/backend/pkg/money"
+)
+
+const (
+	RecurrenciaModoMonto       = "monto"       // the same monto_base every month
+	RecurrenciaModoPresupuesto = "presupuesto" // monthly budget split among parcelas
+	RecurrenciaModoUF          = "uf"          // the same amount in UF every month
+
+	AjusteDiaHabilNinguno   = "ninguno"
+	AjusteDiaHabilSiguiente = "siguiente"
+	AjusteDiaHabilAnterior  = "anterior"
+)
+
+// ConfigPeriodos is the recurrence used to generate the next periodo. The
+// description template accepts {mes}, {anio} and {periodo}.
+type ConfigPeriodos struct {
+	Activa               bool         `json:"activa"`
+	Modo                 string       `json:"modo"`
+	MontoBase            money.Amount `json:"monto_base"`
+	MontoBaseUF          float64      `json:"monto_base_uf"`
+	PresupuestoMensual   money.Amount `json:"presupuesto_mensual"`
+	DiaVencimiento       int          `json:"dia_vencimiento"`
+	AjusteDiaHabil       string       `json:"ajuste_dia_habil"`
+	DescripcionPlantilla string       `json:"descripcion_plantilla"`
+	DiasAnticipacion     int          `json:"dias_anticipacion"`
+	RequiereAprobacion   bool         `json:"requiere_aprobacion"`
+	UpdatedBy            *string      `json:"updated_by,omitempty"`
+	UpdatedAt            *time.Time   `json:"updated_at,omitempty"`
+}
+
+type UpdateConfigPeriodosRequest struct {
+	Activa               *bool         `json:"activa,omitempty"`
+	Modo                 *string       `json:"modo,omitempty"`
+	MontoBase            *money.Amount `json:"monto_base,omitempty"`
+	MontoBaseUF          *float64      `json:"monto_base_uf,omitempty"`
+	PresupuestoMensual   *money.Amount `json:"presupuesto_mensual,omitempty"`
+	DiaVencimiento       *int          `json:"dia_vencimiento,omitempty"`
+	AjusteDiaHabil       *string       `json:"ajuste_dia_habil,omitempty"`
+	DescripcionPlantilla *string       `json:"descripcion_plantilla,omitempty"`
+	DiasAnticipacion     *int          `json:"dias_anticipacion,omitempty"`
+	RequiereAprobacion   *bool         `json:"requiere_aprobacion,omitempty"`
+}
+
+type GenerarPeriodoResult struct {
+	Generado bool          `json:"generado"`
+	Motivo   string        `json:"motivo,omitempty"`
+	Periodo  *PeriodoGasto `json:"periodo,omitempty"`
+}
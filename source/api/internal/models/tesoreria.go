@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/condominio/backend/pkg/money"
+)
 
 type MovimientoType string
 
@@ -9,27 +13,92 @@ const (
 	MovimientoEgreso  MovimientoType = "egreso"
 )
 
+// Movimiento is an entry of the treasury ledger. Entries are never deleted:
+// a void keeps the original, marked as anulado, and adds a reverse entry of
+// the same type with the opposite amount, so totals net out. A transfer
+// between cuentas is a pair of entries sharing TransferenciaID.
 type Movimiento struct {
-	ID          string         `json:"id"`
-	Description string         `json:"description"`
-	Amount      float64        `json:"amount"`
-	Type        MovimientoType `json:"type"`
-	Category    string         `json:"category,omitempty"`
-	Date        time.Time      `json:"date"`
-	CreatedBy   *string        `json:"created_by,omitempty"`
-	CreatorName string         `json:"creator_name,omitempty"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
+	ID              string         `json:"id"`
+	Comprobante     int64          `json:"comprobante"`
+	CuentaID        string         `json:"cuenta_id"`
+	CuentaNombre    string         `json:"cuenta_nombre"`
+	Description     string         `json:"description"`
+	Amount          money.Amount   `json:"amount"`
+	Type            MovimientoType `json:"type"`
+	Category        string         `json:"category,omitempty"`
+	Date            time.Time      `json:"date"`
+	AnuladoAt       *time.Time     `json:"anulado_at,omitempty"`
+	AnuladoBy       *string        `json:"anulado_by,omitempty"`
+	MotivoAnulacion string         `json:"motivo_anulacion,omitempty"`
+	ReversoDe       *string        `json:"reverso_de,omitempty"` // set on the reverse entry of a void
+	TransferenciaID *string        `json:"transferencia_id,omitempty"`
+	PagoID          *string        `json:"pago_id,omitempty"` // reserve fund allocation of a pago
+	TotalAdjuntos   int            `json:"total_adjuntos"`
+	CreatedBy       *string        `json:"created_by,omitempty"`
+	CreatorName     string         `json:"creator_name,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	// Related data
+	Adjuntos []AdjuntoMovimiento `json:"adjuntos,omitempty"`
+}
+
+// OcultarPago drops what ties an entry posted from a pago, or its void, to a
+// parcela: the description names the parcela and the reversal motivo. Reserve
+// fund transfers are already generic and stay as they are.
+func (m *Movimiento) OcultarPago() {
+	if m.PagoID == nil || m.TransferenciaID != nil {
+		return
+	}
+	switch {
+	case m.ReversoDe != nil:
+		m.Description = "Anulación de contabilización de pago de gasto común"
+	case m.Type == MovimientoEgreso:
+		m.Description = "Reverso pago gasto común"
+	default:
+		m.Description = "Pago gasto común"
+	}
+	m.PagoID = nil
+	m.MotivoAnulacion = ""
+}
+
+// AdjuntoMovimiento is a scanned boleta or factura backing a movimiento. The
+// file itself is only returned by the download endpoint.
+type AdjuntoMovimiento struct {
+	ID            string    `json:"id"`
+	MovimientoID  string    `json:"movimiento_id"`
+	NombreArchivo string    `json:"nombre_archivo"`
+	ContentType   string    `json:"content_type"`
+	Tamano        int       `json:"tamano"`
+	CreatedBy     *string   `json:"created_by,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	Contenido     []byte    `json:"-"`
 }
 
 type CreateMovimientoRequest struct {
+	CuentaID    string         `json:"cuenta_id"` // default: the principal cuenta
 	Description string         `json:"description"`
-	Amount      float64        `json:"amount"`
+	Amount      money.Amount   `json:"amount"`
 	Type        MovimientoType `json:"type"`
 	Category    string         `json:"category"`
 	Date        time.Time      `json:"date"`
 }
 
+// UpdateMovimientoRequest corrects a movimiento; the reason is mandatory and
+// recorded in the audit trail with the previous values.
+type UpdateMovimientoRequest struct {
+	CuentaID    *string         `json:"cuenta_id,omitempty"`
+	Description *string         `json:"description,omitempty"`
+	Amount      *money.Amount   `json:"amount,omitempty"`
+	Type        *MovimientoType `json:"type,omitempty"`
+	Category    *string         `json:"category,omitempty"`
+	Date        *time.Time      `json:"date,omitempty"`
+	Motivo      string          `json:"motivo"`
+}
+
+type AnularMovimientoRequest struct {
+	Motivo string `json:"motivo"`
+}
+
 type MovimientoListResponse struct {
 	Movimientos []Movimiento `json:"movimientos"`
 	Total       int          `json:"total"`
@@ -37,16 +106,189 @@ type MovimientoListResponse struct {
 	PerPage     int          `json:"per_page"`
 }
 
+// ResumenTesoreria totals exclude transfers between cuentas, which only move
+// money around; the per-cuenta figures include them.
 type ResumenTesoreria struct {
-	TotalIngresos float64 `json:"total_ingresos"`
-	TotalEgresos  float64 `json:"total_egresos"`
-	Balance       float64 `json:"balance"`
+	TotalIngresos money.Amount  `json:"total_ingresos"`
+	TotalEgresos  money.Amount  `json:"total_egresos"`
+	Balance       money.Amount  `json:"balance"`
+	Cuentas       []SaldoCuenta `json:"cuentas"`
+}
+
+type SaldoCuenta struct {
+	CuentaID string       `json:"cuenta_id"`
+	Codigo   string       `json:"codigo"`
+	Nombre   string       `json:"nombre"`
+	Tipo     CuentaTipo   `json:"tipo"`
+	Ingresos money.Amount `json:"ingresos"`
+	Egresos  money.Amount `json:"egresos"`
+	Saldo    money.Amount `json:"saldo"`
 }
 
 type MovimientoFilter struct {
-	Type    MovimientoType
-	Year    int
-	Month   int
-	Page    int
-	PerPage int
+	CuentaID   string
+	Type       MovimientoType
+	Year       int
+	Month      int
+	Category   string
+	MontoMin   *money.Amount
+	MontoMax   *money.Amount
+	ConAdjunto *bool
+	Page       int
+	PerPage    int
+}
+
+// ============================================
+// CUENTAS
+// ============================================
+
+type CuentaTipo string
+
+const (
+	CuentaTipoCorriente    CuentaTipo = "corriente"
+	CuentaTipoCaja         CuentaTipo = "caja"
+	CuentaTipoFondoReserva CuentaTipo = "fondo_reserva"
+	CuentaTipoOtra         CuentaTipo = "otra"
+)
+
+func (t CuentaTipo) IsValid() bool {
+	switch t {
+	case CuentaTipoCorriente, CuentaTipoCaja, CuentaTipoFondoReserva, CuentaTipoOtra:
+		return true
+	}
+	return false
+}
+
+// CuentaTesoreria is where the money of a movimiento sits: the bank current
+// account, the cash box, the legal reserve fund... Movimientos created
+// without a cuenta go to the principal one.
+type CuentaTesoreria struct {
+	ID        string       `json:"id"`
+	Codigo    string       `json:"codigo"`
+	Nombre    string       `json:"nombre"`
+	Tipo      CuentaTipo   `json:"tipo"`
+	Principal bool         `json:"principal"`
+	Activa    bool         `json:"activa"`
+	Saldo     money.Amount `json:"saldo"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+type CreateCuentaRequest struct {
+	Codigo string     `json:"codigo"`
+	Nombre string     `json:"nombre"`
+	Tipo   CuentaTipo `json:"tipo"`
+}
+
+type UpdateCuentaRequest struct {
+	Nombre    *string `json:"nombre,omitempty"`
+	Activa    *bool   `json:"activa,omitempty"`
+	Principal *bool   `json:"principal,omitempty"` // only true is accepted: it moves the flag
+}
+
+// TransferenciaRequest moves money between two cuentas: an egreso in the
+// origin and an ingreso in the destination, each with its comprobante.
+type TransferenciaRequest struct {
+	CuentaOrigenID  string       `json:"cuenta_origen_id"`
+	CuentaDestinoID string       `json:"cuenta_destino_id"`
+	Monto           money.Amount `json:"monto"`
+	Date            *time.Time   `json:"date,omitempty"` // default: today
+	Description     string       `json:"description"`
+}
+
+type Transferencia struct {
+	ID      string     `json:"id"`
+	Origen  Movimiento `json:"origen"`
+	Destino Movimiento `json:"destino"`
+}
+
+// ContabilizacionPagos selects how approved gasto común pagos reach the
+// treasury ledger.
+type ContabilizacionPagos string
+
+const (
+	// ContabilizacionDesactivada leaves pagos out of the ledger.
+	ContabilizacionDesactivada ContabilizacionPagos = "desactivada"
+	// ContabilizacionIndividual posts one ingreso per pago as it is approved.
+	ContabilizacionIndividual ContabilizacionPagos = "individual"
+	// ContabilizacionDiaria posts one ingreso per day with all of its pagos.
+	ContabilizacionDiaria ContabilizacionPagos = "diaria"
+)
+
+func (c ContabilizacionPagos) IsValid() bool {
+	switch c {
+	case ContabilizacionDesactivada, ContabilizacionIndividual, ContabilizacionDiaria:
+		return true
+	}
+	return false
+}
+
+// ConfigTesoreria holds the reserve fund rule: PorcentajeFondoReserva of
+// every approved gasto común pago is transferred from the principal cuenta to
+// CuentaReservaID. Zero disables it. ContabilizacionPagos controls how pagos
+// are posted as ingresos of the principal cuenta.
+type ConfigTesoreria struct {
+	PorcentajeFondoReserva float64              `json:"porcentaje_fondo_reserva"`
+	CuentaReservaID        *string              `json:"cuenta_reserva_id"`
+	ContabilizacionPagos   ContabilizacionPagos `json:"contabilizacion_pagos"`
+	UpdatedBy              *string              `json:"updated_by,omitempty"`
+	UpdatedAt              *time.Time           `json:"updated_at,omitempty"`
+}
+
+type UpdateConfigTesoreriaRequest struct {
+	PorcentajeFondoReserva *float64              `json:"porcentaje_fondo_reserva,omitempty"`
+	CuentaReservaID        *string               `json:"cuenta_reserva_id,omitempty"`
+	ContabilizacionPagos   *ContabilizacionPagos `json:"contabilizacion_pagos,omitempty"`
+}
+
+type ContabilizarPagosResult struct {
+	Movimientos int          `json:"movimientos"`
+	Pagos       int          `json:"pagos"`
+	Monto       money.Amount `json:"monto"` // ingresos minus reversals
+}
+
+// ============================================
+// CONCILIACION PAGOS / TESORERIA
+// ============================================
+
+type ConciliacionTipo string
+
+const (
+	// An approved pago (outside credit applications) with no ingreso
+	ConciliacionPagoSinAsiento ConciliacionTipo = "pago_sin_asiento"
+	// A pago waiting for the daily posting
+	ConciliacionPagoPendiente ConciliacionTipo = "pago_pendiente"
+	// A reversed pago whose ingreso was posted but not its reversal
+	ConciliacionReversoSinAsiento ConciliacionTipo = "reverso_sin_asiento"
+	// The posted amount differs from the pago (or the movimiento was edited)
+	ConciliacionMontoDistinto ConciliacionTipo = "monto_distinto"
+	// The movimiento posting the pago was voided
+	ConciliacionAsientoAnulado ConciliacionTipo = "asiento_anulado"
+	// A gasto común ingreso entered by hand, not backed by any pago
+	ConciliacionAsientoSinPago ConciliacionTipo = "asiento_sin_pago"
+	// A credit refund whose egreso is missing or was voided
+	ConciliacionReembolsoSinAsiento ConciliacionTipo = "reembolso_sin_asiento"
+)
+
+type ConciliacionItem struct {
+	Tipo               ConciliacionTipo `json:"tipo"`
+	PagoID             *string          `json:"pago_id,omitempty"`
+	ParcelaNumero      string           `json:"parcela_numero,omitempty"`
+	MovimientoID       *string          `json:"movimiento_id,omitempty"`
+	Comprobante        *int64           `json:"comprobante,omitempty"`
+	Fecha              time.Time        `json:"fecha"`
+	Monto              money.Amount     `json:"monto"`
+	MontoContabilizado money.Amount     `json:"monto_contabilizado"`
+	Detalle            string           `json:"detalle"`
+}
+
+// ConciliacionPagos cross-checks pagos against treasury ingresos for a date
+// range. Credit applications are not cash and are left out.
+type ConciliacionPagos struct {
+	Desde              time.Time          `json:"desde"`
+	Hasta              time.Time          `json:"hasta"`
+	TotalPagos         money.Amount       `json:"total_pagos"`
+	TotalContabilizado money.Amount       `json:"total_contabilizado"`
+	Diferencia         money.Amount       `json:"diferencia"`
+	Items              []ConciliacionItem `json:"items"`
 }
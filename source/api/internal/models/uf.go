@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+const (
+	MonedaCLP = "CLP"
+	MonedaUF  = "UF" // Unidad de Fomento, converted to CLP when the periodo is issued
+)
+
+// ValorUF is the CLP value of one UF on a given day.
+type ValorUF struct {
+	Fecha     time.Time `json:"fecha"`
+	Valor     float64   `json:"valor"`
+	CreatedBy *string   `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type SetValorUFRequest struct {
+	Valor float64 `json:"valor"`
+}
+
+type ValorUFFilter struct {
+	Desde *time.Time
+	Hasta *time.Time
+}
+
+type ImportarUFResult struct {
+	Insertados   int      `json:"insertados"`
+	Actualizados int      `json:"actualizados"`
+	Errores      []string `json:"errores"`
+}
@@ -11,59 +11,106 @@ const (
 	VotacionStatusCancelled VotacionStatus = "cancelled"
 )
 
+type VotacionTipo string
+
+const (
+	VotacionSimple       VotacionTipo = "simple"       // one opcion
+	VotacionMultiple     VotacionTipo = "multiple"     // approve several opciones
+	VotacionPreferencial VotacionTipo = "preferencial" // rank opciones
+)
+
+// MetodoConteo tallies preferencial votaciones.
+type MetodoConteo string
+
+const (
+	ConteoIRV   MetodoConteo = "irv"   // instant-runoff
+	ConteoBorda MetodoConteo = "borda" // points by position
+)
+
 type Votacion struct {
-	ID               string         `json:"id"`
-	Title            string         `json:"title"`
-	Description      string         `json:"description,omitempty"`
-	Status           VotacionStatus `json:"status"`
-	StartDate        *time.Time     `json:"start_date,omitempty"`
-	EndDate          *time.Time     `json:"end_date,omitempty"`
-	RequiresQuorum   bool           `json:"requires_quorum"`
-	QuorumPercentage int            `json:"quorum_percentage"`
-	AllowAbstention  bool           `json:"allow_abstention"`
+	ID               string           `json:"id"`
+	Title            string           `json:"title"`
+	Description      string           `json:"description,omitempty"`
+	Status           VotacionStatus   `json:"status"`
+	StartDate        *time.Time       `json:"start_date,omitempty"`
+	EndDate          *time.Time       `json:"end_date,omitempty"`
+	RequiresQuorum   bool             `json:"requires_quorum"`
+	QuorumPercentage int              `json:"quorum_percentage"`
+	AllowAbstention  bool             `json:"allow_abstention"`
+	Ponderada        bool             `json:"ponderada"`           // quorum and majorities by parcela coefficient
+	EventoID         *string          `json:"evento_id,omitempty"` // asamblea where it is held
+	Secreta          bool             `json:"secreta"`             // ballots stored apart from who voted
+	Tipo             VotacionTipo     `json:"tipo"`
+	MinSelecciones   int              `json:"min_selecciones"`
+	MaxSelecciones   int              `json:"max_selecciones"`         // 0: up to every opcion
+	MetodoConteo     MetodoConteo     `json:"metodo_conteo,omitempty"` // preferencial only
+	AllowVoteChange  bool             `json:"allow_vote_change"`       // ballots can be replaced until closing
 	Opciones         []VotacionOpcion `json:"opciones,omitempty"`
-	CreatedBy        *string        `json:"created_by,omitempty"`
-	CreatorName      string         `json:"creator_name,omitempty"`
-	CreatedAt        time.Time      `json:"created_at"`
-	UpdatedAt        time.Time      `json:"updated_at"`
+	CreatedBy        *string          `json:"created_by,omitempty"`
+	CreatorName      string           `json:"creator_name,omitempty"`
+	CreatedAt        time.Time        `json:"created_at"`
+	UpdatedAt        time.Time        `json:"updated_at"`
 	// Computed fields
-	TotalVotos       int            `json:"total_votos,omitempty"`
-	HasVoted         bool           `json:"has_voted,omitempty"`
+	TotalVotos int         `json:"total_votos,omitempty"`
+	HasVoted   bool        `json:"has_voted,omitempty"`
+	Poderes    []PoderVoto `json:"poderes,omitempty"` // parcelas the user represents
 }
 
 type VotacionOpcion struct {
-	ID          string `json:"id"`
-	VotacionID  string `json:"votacion_id"`
-	Label       string `json:"label"`
-	Description string `json:"description,omitempty"`
-	OrderIndex  int    `json:"order_index"`
-	VotosCount  int    `json:"votos_count,omitempty"`
+	ID          string  `json:"id"`
+	VotacionID  string  `json:"votacion_id"`
+	Label       string  `json:"label"`
+	Description string  `json:"description,omitempty"`
+	OrderIndex  int     `json:"order_index"`
+	VotosCount  int     `json:"votos_count,omitempty"`
+	Peso        float64 `json:"peso,omitempty"` // sum of the coefficients of its votes
+	VotosPoder  int     `json:"votos_poder,omitempty"`
 }
 
 type Voto struct {
-	ID          string    `json:"id"`
-	VotacionID  string    `json:"votacion_id"`
-	UserID      string    `json:"user_id"`
-	OpcionID    *string   `json:"opcion_id,omitempty"`
-	IsAbstention bool     `json:"is_abstention"`
-	VotedAt     time.Time `json:"voted_at"`
+	ID           string    `json:"id"`
+	VotacionID   string    `json:"votacion_id"`
+	UserID       string    `json:"user_id"`
+	ParcelaID    *int      `json:"parcela_id,omitempty"`
+	Peso         float64   `json:"peso"`
+	PoderID      *string   `json:"poder_id,omitempty"` // cast by an apoderado
+	OpcionID     *string   `json:"opcion_id,omitempty"`
+	Opciones     []string  `json:"opciones,omitempty"` // multiple and preferencial, in order of preference
+	IsAbstention bool      `json:"is_abstention"`
+	VotedAt      time.Time `json:"voted_at"`
 }
 
 type CreateVotacionRequest struct {
-	Title            string   `json:"title"`
-	Description      string   `json:"description"`
-	RequiresQuorum   bool     `json:"requires_quorum"`
-	QuorumPercentage int      `json:"quorum_percentage"`
-	AllowAbstention  bool     `json:"allow_abstention"`
-	Opciones         []string `json:"opciones"` // Labels for options
+	Title            string       `json:"title"`
+	Description      string       `json:"description"`
+	RequiresQuorum   bool         `json:"requires_quorum"`
+	QuorumPercentage int          `json:"quorum_percentage"`
+	AllowAbstention  bool         `json:"allow_abstention"`
+	Ponderada        bool         `json:"ponderada"`
+	Secreta          bool         `json:"secreta"`
+	EventoID         *string      `json:"evento_id,omitempty"`
+	Tipo             VotacionTipo `json:"tipo,omitempty"` // default: simple
+	MinSelecciones   int          `json:"min_selecciones,omitempty"`
+	MaxSelecciones   int          `json:"max_selecciones,omitempty"`
+	MetodoConteo     MetodoConteo `json:"metodo_conteo,omitempty"` // default: irv
+	AllowVoteChange  bool         `json:"allow_vote_change"`
+	Opciones         []string     `json:"opciones"` // Labels for options
 }
 
 type UpdateVotacionRequest struct {
-	Title            *string `json:"title,omitempty"`
-	Description      *string `json:"description,omitempty"`
-	RequiresQuorum   *bool   `json:"requires_quorum,omitempty"`
-	QuorumPercentage *int    `json:"quorum_percentage,omitempty"`
-	AllowAbstention  *bool   `json:"allow_abstention,omitempty"`
+	Title            *string       `json:"title,omitempty"`
+	Description      *string       `json:"description,omitempty"`
+	RequiresQuorum   *bool         `json:"requires_quorum,omitempty"`
+	QuorumPercentage *int          `json:"quorum_percentage,omitempty"`
+	AllowAbstention  *bool         `json:"allow_abstention,omitempty"`
+	Ponderada        *bool         `json:"ponderada,omitempty"`
+	Secreta          *bool         `json:"secreta,omitempty"`
+	EventoID         *string       `json:"evento_id,omitempty"` // "" detaches it from the asamblea
+	Tipo             *VotacionTipo `json:"tipo,omitempty"`
+	MinSelecciones   *int          `json:"min_selecciones,omitempty"`
+	MaxSelecciones   *int          `json:"max_selecciones,omitempty"`
+	MetodoConteo     *MetodoConteo `json:"metodo_conteo,omitempty"`
+	AllowVoteChange  *bool         `json:"allow_vote_change,omitempty"`
 }
 
 type AddOpcionRequest struct {
@@ -71,9 +118,49 @@ type AddOpcionRequest struct {
 	Description string `json:"description,omitempty"`
 }
 
+type UpdateOpcionRequest struct {
+	Label       *string `json:"label,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+// ReordenarOpcionesRequest lists every opcion_id of the votacion in the new
+// order.
+type ReordenarOpcionesRequest struct {
+	Opciones []string `json:"opciones"`
+}
+
+// EmitirVotoRequest casts the ballot of the user's parcela or, with
+// parcela_id, of a parcela the user represents through a poder. Simple
+// votaciones take opcion_id; multiple and preferencial ones take opciones,
+// the latter ordered from most to least preferred. When the votacion allows
+// vote changes, voting again replaces the parcela's ballot; in secret
+// votaciones recibo must then carry the receipt code of that ballot.
 type EmitirVotoRequest struct {
-	OpcionID     *string `json:"opcion_id,omitempty"`
-	IsAbstention bool    `json:"is_abstention"`
+	OpcionID     *string  `json:"opcion_id,omitempty"`
+	Opciones     []string `json:"opciones,omitempty"`
+	Recibo       string   `json:"recibo,omitempty"`
+	IsAbstention bool     `json:"is_abstention"`
+	ParcelaID    *int     `json:"parcela_id,omitempty"`
+}
+
+// VotoHistorial is a ballot replaced by a change of vote. Secret ballots show
+// who changed them and when, but not their contents.
+type VotoHistorial struct {
+	ID             string    `json:"id"`
+	VotacionID     string    `json:"votacion_id"`
+	ParcelaID      int       `json:"parcela_id"`
+	ParcelaNumero  string    `json:"parcela_numero"`
+	UserID         *string   `json:"user_id,omitempty"` // who had cast it
+	UserName       string    `json:"user_name,omitempty"`
+	PoderID        *string   `json:"poder_id,omitempty"`
+	OpcionID       *string   `json:"opcion_id,omitempty"`
+	Opciones       []string  `json:"opciones,omitempty"`
+	IsAbstention   *bool     `json:"is_abstention,omitempty"`
+	Peso           float64   `json:"peso"`
+	VotedAt        time.Time `json:"voted_at"`
+	CambiadoBy     *string   `json:"cambiado_by,omitempty"`
+	CambiadoByName string    `json:"cambiado_by_name,omitempty"`
+	CambiadoAt     time.Time `json:"cambiado_at"`
 }
 
 type VotacionListResponse struct {
@@ -83,21 +170,95 @@ type VotacionListResponse struct {
 	PerPage    int        `json:"per_page"`
 }
 
+// VotacionResultado counts one ballot per parcela. Headcount fields count
+// parcelas; the Peso fields add up their coefficients. Quorum uses the
+// weighted participation when the votacion is Ponderada. In multiple
+// votaciones each opcion counts the ballots approving it; in preferencial
+// ones it counts first preferences, and Rondas, Puntos and Ganador come from
+// the instant-runoff and Borda counts.
 type VotacionResultado struct {
-	Votacion         Votacion           `json:"votacion"`
-	TotalVotos       int                `json:"total_votos"`
-	TotalAbstenciones int               `json:"total_abstenciones"`
-	Resultados       []OpcionResultado  `json:"resultados"`
-	QuorumAlcanzado  bool               `json:"quorum_alcanzado"`
-	TotalVecinos     int                `json:"total_vecinos"`
-	Participacion    float64            `json:"participacion"`
+	Votacion               Votacion          `json:"votacion"`
+	TotalVotos             int               `json:"total_votos"`
+	TotalAbstenciones      int               `json:"total_abstenciones"`
+	Resultados             []OpcionResultado `json:"resultados"`
+	QuorumAlcanzado        bool              `json:"quorum_alcanzado"`
+	TotalVecinos           int               `json:"total_vecinos"` // voting parcelas
+	Participacion          float64           `json:"participacion"`
+	PesoTotal              float64           `json:"peso_total"`
+	PesoVotos              float64           `json:"peso_votos"`
+	PesoAbstenciones       float64           `json:"peso_abstenciones"`
+	ParticipacionPonderada float64           `json:"participacion_ponderada"`
+	VotosPoder             int               `json:"votos_poder"` // ballots cast by apoderados
+	PesoPoder              float64           `json:"peso_poder"`
+	VotosPorPoder          []VotoPoder       `json:"votos_por_poder"`
+	Rondas                 []RondaConteo     `json:"rondas,omitempty"`  // instant-runoff, preferencial only
+	Ganador                *string           `json:"ganador,omitempty"` // opcion_id, preferencial only
+	Empate                 bool              `json:"empate,omitempty"`
+	EscrutinioPendiente    bool              `json:"escrutinio_pendiente,omitempty"` // secret and not closed: only turnout is known
 }
 
 type OpcionResultado struct {
+	OpcionID            string   `json:"opcion_id"`
+	Label               string   `json:"label"`
+	Count               int      `json:"count"`
+	Percentage          float64  `json:"percentage"`
+	Peso                float64  `json:"peso"`
+	PercentagePonderado float64  `json:"percentage_ponderado"`
+	CountPoder          int      `json:"count_poder"`      // of Count, cast by apoderados; 0 in secret votaciones
+	Puntos              *float64 `json:"puntos,omitempty"` // Borda points, preferencial only
+}
+
+// RondaConteo is a round of the instant-runoff count. Each ballot counts for
+// its most preferred opcion still in the race; Agotadas are the ballots with
+// none left. By coefficient when the votacion is Ponderada.
+type RondaConteo struct {
+	Ronda     int            `json:"ronda"`
+	Conteos   []ConteoOpcion `json:"conteos"`
+	Agotadas  float64        `json:"agotadas"`
+	Eliminada *string        `json:"eliminada,omitempty"` // opcion_id dropped after the round
+	Ganador   *string        `json:"ganador,omitempty"`
+}
+
+type ConteoOpcion struct {
 	OpcionID   string  `json:"opcion_id"`
 	Label      string  `json:"label"`
-	Count      int     `json:"count"`
-	Percentage float64 `json:"percentage"`
+	Votos      float64 `json:"votos"`
+	Percentage float64 `json:"percentage"` // of the ballots not exhausted
+}
+
+// ReciboVoto is handed to the voter of a secret votacion. Only the voter
+// knows Codigo; the published boletas show its Huella (SHA-256), so the voter
+// can check the ballot was counted as cast without revealing who cast it.
+type ReciboVoto struct {
+	VotacionID string `json:"votacion_id"`
+	Codigo     string `json:"codigo"`
+	Huella     string `json:"huella"`
+}
+
+// Boleta is a published ballot of a secret votacion.
+type Boleta struct {
+	Huella       string   `json:"huella"`
+	OpcionID     *string  `json:"opcion_id,omitempty"`
+	Opcion       string   `json:"opcion,omitempty"`
+	Opciones     []string `json:"opciones,omitempty"` // labels, multiple and preferencial
+	IsAbstention bool     `json:"is_abstention"`
+	Peso         float64  `json:"peso"`
+}
+
+type VerificarReciboRequest struct {
+	Codigo string `json:"codigo"`
+}
+
+// CoeficienteParcela is the voting weight of a parcela.
+type CoeficienteParcela struct {
+	ParcelaID   int     `json:"parcela_id"`
+	Numero      string  `json:"numero"`
+	Coeficiente float64 `json:"coeficiente"`
+	Residentes  int     `json:"residentes"`
+}
+
+type SetCoeficienteRequest struct {
+	Coeficiente float64 `json:"coeficiente"`
 }
 
 type VotacionFilter struct {
@@ -115,6 +276,33 @@ func (s VotacionStatus) IsValid() bool {
 	return false
 }
 
+func (t VotacionTipo) IsValid() bool {
+	switch t {
+	case VotacionSimple, VotacionMultiple, VotacionPreferencial:
+		return true
+	}
+	return false
+}
+
+func (m MetodoConteo) IsValid() bool {
+	switch m {
+	case ConteoIRV, ConteoBorda:
+		return true
+	}
+	return false
+}
+
+// LimiteSelecciones is the most opciones a ballot may select or rank.
+func (v *Votacion) LimiteSelecciones() int {
+	if v.Tipo == VotacionSimple {
+		return 1
+	}
+	if v.MaxSelecciones == 0 || v.MaxSelecciones > len(v.Opciones) {
+		return len(v.Opciones)
+	}
+	return v.MaxSelecciones
+}
+
 func (v *Votacion) CanVote() bool {
 	if v.Status != VotacionStatusActive {
 		return false
@@ -16,11 +16,17 @@ type Services struct {
 	Comunicado   *services.ComunicadoService
 	Evento       *services.EventoService
 	Tesoreria    *services.TesoreriaService
+	Proveedor    *services.ProveedorService
 	Acta         *services.ActaService
 	Documento    *services.DocumentoService
 	Emergencia   *services.EmergenciaService
 	Votacion     *services.VotacionService
 	GastoComun   *services.GastoComunService
+	Convenio     *services.ConvenioService
+	Cobranza     *services.CobranzaService
+	Recordatorio *services.RecordatorioService
+	Importacion  *services.ImportacionBancoService
+	UF           *services.UFService
 	Contacto     *services.ContactoService
 	Galeria      *services.GaleriaService
 	Mapa         *services.MapaService
@@ -55,11 +61,17 @@ func New(svc *Services, jwtManager *jwt.JWTManager, oauthCfg *OAuthConfig) *chi.
 	comunicadoHandler := handlers.NewComunicadoHandler(svc.Comunicado)
 	eventoHandler := handlers.NewEventoHandler(svc.Evento)
 	tesoreriaHandler := handlers.NewTesoreriaHandler(svc.Tesoreria)
+	proveedorHandler := handlers.NewProveedorHandler(svc.Proveedor)
 	actaHandler := handlers.NewActaHandler(svc.Acta)
 	documentoHandler := handlers.NewDocumentoHandler(svc.Documento)
 	emergenciaHandler := handlers.NewEmergenciaHandler(svc.Emergencia)
 	votacionHandler := handlers.NewVotacionHandler(svc.Votacion)
-	gastoComunHandler := handlers.NewGastoComunHandler(svc.GastoComun)
+	gastoComunHandler := handlers.NewGastoComunHandler(svc.GastoComun, svc.Recordatorio)
+	convenioHandler := handlers.NewConvenioHandler(svc.Convenio)
+	cobranzaHandler := handlers.NewCobranzaHandler(svc.Cobranza)
+	recordatorioHandler := handlers.NewRecordatorioHandler(svc.Recordatorio)
+	importacionHandler := handlers.NewImportacionBancoHandler(svc.Importacion)
+	ufHandler := handlers.NewUFHandler(svc.UF)
 	contactoHandler := handlers.NewContactoHandler(svc.Contacto)
 	galeriaHandler := handlers.NewGaleriaHandler(svc.Galeria)
 	mapaHandler := handlers.NewMapaHandler(svc.Mapa)
@@ -122,13 +134,75 @@ func New(svc *Services, jwtManager *jwt.JWTManager, oauthCfg *OAuthConfig) *chi.
 
 			r.Get("/", tesoreriaHandler.List)
 			r.Get("/resumen", tesoreriaHandler.GetResumen)
+			r.Get("/cuentas", tesoreriaHandler.ListCuentas)
+			r.Get("/transferencias/{id}", tesoreriaHandler.GetTransferencia)
+			r.Get("/categorias", tesoreriaHandler.ListCategorias)
+			r.Get("/presupuestos", tesoreriaHandler.ListPresupuestos)
+			r.Get("/presupuestos/{year}", tesoreriaHandler.GetPresupuesto)
+			r.Get("/presupuestos/{year}/ejecucion", tesoreriaHandler.GetEjecucion)
+			r.Get("/estados-financieros/{year}/publico", tesoreriaHandler.GetEstadoFinancieroPublico)
+			r.Get("/{id}", tesoreriaHandler.GetByID)
+			r.Get("/{id}/adjuntos/{adjuntoId}", tesoreriaHandler.GetAdjunto)
 
 			r.Group(func(r chi.Router) {
 				r.Use(authMiddleware.RequireRole("directiva"))
 				r.Post("/", tesoreriaHandler.Create)
+				r.Put("/{id}", tesoreriaHandler.Update)
+				r.Post("/{id}/anular", tesoreriaHandler.Anular)
+				r.Post("/{id}/adjuntos", tesoreriaHandler.AddAdjunto)
+				r.Delete("/{id}/adjuntos/{adjuntoId}", tesoreriaHandler.DeleteAdjunto)
+				r.Post("/cuentas", tesoreriaHandler.CreateCuenta)
+				r.Put("/cuentas/{id}", tesoreriaHandler.UpdateCuenta)
+				r.Post("/transferencias", tesoreriaHandler.CreateTransferencia)
+				r.Get("/config", tesoreriaHandler.GetConfig)
+				r.Put("/config", tesoreriaHandler.UpdateConfig)
+				r.Post("/pagos/contabilizar", tesoreriaHandler.ContabilizarPagos)
+				r.Get("/conciliacion-pagos", tesoreriaHandler.GetConciliacionPagos)
+				r.Get("/estados-financieros/{year}", tesoreriaHandler.GetEstadoFinanciero)
+				r.Get("/cuentas/{id}/cartolas", tesoreriaHandler.ListCartolas)
+				r.Post("/cuentas/{id}/cartolas", tesoreriaHandler.CreateCartola)
+				r.Get("/cuentas/{id}/conciliacion", tesoreriaHandler.GetConciliacionBancaria)
+				r.Post("/cuentas/{id}/conciliar-automatico", tesoreriaHandler.ConciliarAutomatico)
+				r.Get("/cuentas/{id}/cierres", tesoreriaHandler.ListCierres)
+				r.Post("/cuentas/{id}/cierres", tesoreriaHandler.CreateCierre)
+				r.Post("/cartolas/lineas/{id}/conciliar", tesoreriaHandler.ConciliarLinea)
+				r.Delete("/cartolas/lineas/{id}/conciliar", tesoreriaHandler.DesconciliarLinea)
+				r.Post("/categorias", tesoreriaHandler.CreateCategoria)
+				r.Put("/categorias/{codigo}", tesoreriaHandler.UpdateCategoria)
+				r.Put("/presupuestos/{year}", tesoreriaHandler.SetPresupuesto)
+				r.Delete("/presupuestos/{year}", tesoreriaHandler.DeletePresupuesto)
 			})
 		})
 
+		// Proveedores y facturas - directiva (datos bancarios de terceros)
+		r.Route("/proveedores", func(r chi.Router) {
+			r.Use(authMiddleware.Authenticate)
+			r.Use(authMiddleware.RequireRole("directiva"))
+
+			r.Get("/", proveedorHandler.List)
+			r.Post("/", proveedorHandler.Create)
+			r.Get("/{id}", proveedorHandler.GetByID)
+			r.Put("/{id}", proveedorHandler.Update)
+		})
+
+		r.Route("/facturas", func(r chi.Router) {
+			r.Use(authMiddleware.Authenticate)
+			r.Use(authMiddleware.RequireRole("directiva"))
+
+			r.Get("/", proveedorHandler.ListFacturas)
+			r.Post("/", proveedorHandler.CreateFactura)
+			r.Get("/reglas-aprobacion", proveedorHandler.GetReglasAprobacion)
+			r.Put("/reglas-aprobacion", proveedorHandler.SetReglasAprobacion)
+			r.Get("/{id}", proveedorHandler.GetFactura)
+			r.Put("/{id}", proveedorHandler.UpdateFactura)
+			r.Post("/{id}/aprobar", proveedorHandler.AprobarFactura)
+			r.Post("/{id}/rechazar", proveedorHandler.RechazarFactura)
+			r.Post("/{id}/anular", proveedorHandler.AnularFactura)
+			r.Post("/{id}/pagar", proveedorHandler.PagarFactura)
+			r.Post("/{id}/adjuntos", proveedorHandler.AddAdjunto)
+			r.Get("/{id}/adjuntos/{adjuntoId}", proveedorHandler.GetAdjunto)
+		})
+
 		// Actas - protected (vecino+)
 		r.Route("/actas", func(r chi.Router) {
 			r.Use(authMiddleware.Authenticate)
@@ -186,9 +260,18 @@ func New(svc *Services, jwtManager *jwt.JWTManager, oauthCfg *OAuthConfig) *chi.
 				r.Get("/active", votacionHandler.GetActive)
 				r.Get("/{id}", votacionHandler.GetByID)
 				r.Get("/{id}/resultados", votacionHandler.GetResultados)
+				r.Get("/{id}/boletas", votacionHandler.ListBoletas)
+				r.Post("/{id}/verificar-recibo", votacionHandler.VerificarRecibo)
 
 				// Vote endpoint: allowed roles, but parcela is enforced at service level
 				r.Post("/{id}/votar", votacionHandler.EmitirVoto)
+
+				// Poderes: granted by a parcela, or held by the user
+				r.Get("/poderes/mios", votacionHandler.ListMisPoderes)
+				r.Post("/poderes", votacionHandler.CreatePoder)
+				r.Put("/poderes/{id}/documento", votacionHandler.SetDocumentoPoder)
+				r.Get("/poderes/{id}/documento", votacionHandler.GetDocumentoPoder)
+				r.Post("/poderes/{id}/revocar", votacionHandler.RevocarPoder)
 			})
 
 			// Admin endpoints (directiva only)
@@ -200,6 +283,27 @@ func New(svc *Services, jwtManager *jwt.JWTManager, oauthCfg *OAuthConfig) *chi.
 				r.Post("/{id}/close", votacionHandler.Close)
 				r.Post("/{id}/cancel", votacionHandler.Cancel)
 				r.Delete("/{id}", votacionHandler.Delete)
+				r.Get("/{id}/avisos", votacionHandler.ListAvisos)
+				r.Get("/{id}/historial", votacionHandler.ListHistorialVotos)
+
+				// Options, while the votacion is a draft
+				r.Post("/{id}/opciones", votacionHandler.AddOpcion)
+				r.Put("/{id}/opciones/orden", votacionHandler.ReordenarOpciones)
+				r.Put("/{id}/opciones/{opcionId}", votacionHandler.UpdateOpcion)
+				r.Delete("/{id}/opciones/{opcionId}", votacionHandler.DeleteOpcion)
+
+				// Scheduled opening and closing, normally run by the scheduler
+				r.Post("/calendario/procesar", votacionHandler.ProcesarVotaciones)
+
+				// Voting weight of each parcela
+				r.Get("/coeficientes", votacionHandler.ListCoeficientes)
+				r.Put("/coeficientes/{parcelaId}", votacionHandler.SetCoeficiente)
+
+				// Poderes review
+				r.Get("/poderes", votacionHandler.ListPoderes)
+				r.Get("/poderes/{id}", votacionHandler.GetPoder)
+				r.Post("/poderes/{id}/validar", votacionHandler.ValidarPoder)
+				r.Post("/poderes/{id}/rechazar", votacionHandler.RechazarPoder)
 			})
 		})
 
@@ -215,6 +319,12 @@ func New(svc *Services, jwtManager *jwt.JWTManager, oauthCfg *OAuthConfig) *chi.
 			r.Get("/periodos/{id}/resumen", gastoComunHandler.GetResumen)
 			r.Get("/periodos/{id}/gastos", gastoComunHandler.ListGastos)
 			r.Get("/mi-cuenta", gastoComunHandler.GetMiEstadoCuenta)
+			r.Get("/mi-cuenta/movimientos", gastoComunHandler.GetMiCuentaCorriente)
+			r.Get("/mi-cuenta/convenios", convenioHandler.GetMisConvenios)
+			r.Get("/mi-cuenta/pagos/{id}/recibo", cobranzaHandler.GetMiRecibo)
+			r.Get("/mi-cuenta/avisos/{periodoId}", cobranzaHandler.GetMiAvisoCobro)
+			r.Get("/uf", ufHandler.List)
+			r.Get("/uf/{fecha}", ufHandler.Get)
 			r.Get("/{id}", gastoComunHandler.GetGasto)
 
 			// Admin endpoints (directiva only)
@@ -222,8 +332,60 @@ func New(svc *Services, jwtManager *jwt.JWTManager, oauthCfg *OAuthConfig) *chi.
 				r.Use(authMiddleware.RequireRole("directiva"))
 				r.Post("/periodos", gastoComunHandler.CreatePeriodo)
 				r.Put("/periodos/{id}", gastoComunHandler.UpdatePeriodo)
+				r.Delete("/periodos/{id}", gastoComunHandler.DeletePeriodo)
+				r.Post("/periodos/{id}/regenerar", gastoComunHandler.RegenerarPeriodo)
+				r.Post("/periodos/{id}/abrir", gastoComunHandler.AbrirPeriodo)
+				r.Post("/periodos/{id}/cerrar", gastoComunHandler.CerrarPeriodo)
+				r.Get("/recurrencia", gastoComunHandler.GetConfigPeriodos)
+				r.Put("/recurrencia", gastoComunHandler.UpdateConfigPeriodos)
+				r.Post("/recurrencia/generar", gastoComunHandler.GenerarPeriodo)
 				r.Post("/{id}/pago", gastoComunHandler.RegistrarPago)
+				r.Get("/{id}/pagos", gastoComunHandler.ListPagos)
+				r.Post("/{id}/aplicar-credito", gastoComunHandler.AplicarCredito)
+				r.Post("/pagos/{id}/reversar", gastoComunHandler.ReversarPago)
+				r.Get("/parcelas/{parcelaId}/credito", gastoComunHandler.GetSaldoCredito)
+				r.Post("/parcelas/{parcelaId}/credito/reembolso", gastoComunHandler.ReembolsarCredito)
+				r.Get("/parcelas/{parcelaId}/cuenta-corriente", gastoComunHandler.GetCuentaCorriente)
+				r.Post("/parcelas/{parcelaId}/cargos", gastoComunHandler.CreateCargo)
+				r.Put("/parcelas/{parcelaId}/saldo-inicial", gastoComunHandler.SetSaldoInicial)
 				r.Post("/marcar-vencidos", gastoComunHandler.MarcarVencidos)
+				r.Get("/morosidad", gastoComunHandler.GetReporteMorosidad)
+				r.Get("/morosidad/tendencia", gastoComunHandler.GetTendenciaMorosidad)
+
+				// Convenios de pago
+				r.Get("/convenios", convenioHandler.List)
+				r.Post("/convenios", convenioHandler.Create)
+				r.Post("/convenios/procesar", convenioHandler.Procesar)
+				r.Get("/convenios/{id}", convenioHandler.Get)
+				r.Post("/convenios/{id}/pago", convenioHandler.Pagar)
+				r.Post("/convenios/{id}/anular", convenioHandler.Anular)
+
+				// Avisos de cobro y recibos
+				r.Get("/pagos/{id}/recibo", cobranzaHandler.GetRecibo)
+				r.Post("/pagos/{id}/recibo/enviar", cobranzaHandler.EnviarRecibo)
+				r.Get("/periodos/{id}/avisos/{parcelaId}", cobranzaHandler.GetAvisoCobro)
+				r.Post("/periodos/{id}/avisos/enviar", cobranzaHandler.EnviarAvisos)
+
+				// Recordatorios automaticos
+				r.Post("/recordatorios/procesar", recordatorioHandler.Procesar)
+				r.Get("/{id}/recordatorios", recordatorioHandler.List)
+
+				// Importacion de pagos desde cartola bancaria (CSV)
+				r.Get("/importaciones/formatos", importacionHandler.ListFormatos)
+				r.Post("/importaciones/formatos", importacionHandler.CreateFormato)
+				r.Put("/importaciones/formatos/{id}", importacionHandler.UpdateFormato)
+				r.Delete("/importaciones/formatos/{id}", importacionHandler.DeleteFormato)
+				r.Get("/importaciones", importacionHandler.List)
+				r.Post("/importaciones", importacionHandler.Create)
+				r.Get("/importaciones/{id}", importacionHandler.Get)
+				r.Put("/importaciones/{id}/filas/{filaId}", importacionHandler.UpdateFila)
+				r.Post("/importaciones/{id}/confirmar", importacionHandler.Confirmar)
+				r.Post("/importaciones/{id}/descartar", importacionHandler.Descartar)
+
+				// Valores UF
+				r.Post("/uf/importar", ufHandler.Importar)
+				r.Put("/uf/{fecha}", ufHandler.Set)
+				r.Delete("/uf/{fecha}", ufHandler.Delete)
 			})
 		})
 
@@ -317,6 +479,8 @@ func New(svc *Services, jwtManager *jwt.JWTManager, oauthCfg *OAuthConfig) *chi.
 			// User endpoints
 			r.Get("/", notificacionHandler.List)
 			r.Get("/stats", notificacionHandler.GetStats)
+			r.Get("/preferencias", notificacionHandler.GetPreferencias)
+			r.Put("/preferencias", notificacionHandler.UpdatePreferencias)
 			r.Get("/{id}", notificacionHandler.GetByID)
 			r.Post("/{id}/read", notificacionHandler.MarkAsRead)
 			r.Post("/read-all", notificacionHandler.MarkAllAsRead)
@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job is a unit of background work. Errors are logged and the job runs again
+// on the next tick.
+type Job func(ctx context.Context) error
+
+type job struct {
+	name     string
+	interval time.Duration
+	fn       Job
+}
+
+// Scheduler runs registered jobs periodically until stopped. Jobs must be safe
+// to run from several API instances at once; they rely on the database to
+// avoid doing the same work twice.
+type Scheduler struct {
+	jobs   []job
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func New() *Scheduler {
+	return &Scheduler{}
+}
+
+// Every registers a job that runs at startup and then once per interval.
+func (s *Scheduler) Every(name string, interval time.Duration, fn Job) {
+	s.jobs = append(s.jobs, job{name: name, interval: interval, fn: fn})
+}
+
+// Start launches every registered job in its own goroutine.
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go func(j job) {
+			defer s.wg.Done()
+			log.Printf("[SCHEDULER] %s every %s", j.name, j.interval)
+
+			ticker := time.NewTicker(j.interval)
+			defer ticker.Stop()
+			for {
+				s.run(ctx, j)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(j)
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, j job) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[SCHEDULER] %s panicked: %v", j.name, r)
+		}
+	}()
+
+	if err := j.fn(ctx); err != nil && ctx.Err() == nil {
+		log.Printf("[SCHEDULER] %s failed: %v", j.name, err)
+	}
+}
+
+// Stop cancels running jobs and waits for them to return.
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// querier is satisfied by both *pgxpool.Pool and pgx.Tx, so ledger helpers can
+// run inside or outside a transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// registrarAuditoria records a financial correction (reversal, refund, void...)
+// in auditoria_financiera. datos is stored as JSONB and may be nil.
+func registrarAuditoria(ctx context.Context, q querier, entidad, entidadID, accion, motivo, userID string, datos interface{}) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO auditoria_financiera (entidad, entidad_id, accion, motivo, datos, user_id)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, NULLIF($6, '')::uuid)`,
+		entidad, entidadID, accion, motivo, datos, userID)
+	return err
+}
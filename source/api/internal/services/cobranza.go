@@ -0,0 +1,496 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/condominio/backend/internal/database"
+	"github.com/condominio/backend/internal/export"
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/email"
+	"github.com/condominio/backend/pkg/money"
+)
+
+var (
+	ErrReciboNoDisponible = errors.New("receipt only available for approved pagos")
+	ErrSinDestinatarios   = errors.New("parcela has no email recipients")
+)
+
+const correlativoRecibo = "recibo"
+
+type CobranzaService struct {
+	db    *database.DB
+	email *email.Service
+	banco models.DatosBancarios
+}
+
+func NewCobranzaService(db *database.DB, emailSvc *email.Service, banco models.DatosBancarios) *CobranzaService {
+	return &CobranzaService{db: db, email: emailSvc, banco: banco}
+}
+
+// siguienteCorrelativo takes the next number of a document series. The
+// counter row stays locked until the transaction ends, so numbers are never
+// repeated and a rolled back transaction does not leave gaps.
+func siguienteCorrelativo(ctx context.Context, tx pgx.Tx, tipo string) (int64, error) {
+	var numero int64
+	err := tx.QueryRow(ctx, `
+		INSERT INTO correlativos (tipo, ultimo) VALUES ($1, 1)
+		ON CONFLICT (tipo) DO UPDATE SET ultimo = correlativos.ultimo + 1
+		RETURNING ultimo`, tipo).Scan(&numero)
+	return numero, err
+}
+
+// emitirRecibo issues the receipt of a pago if it does not have one yet. The
+// amount includes the overpayment that went to credit, so the receipt shows
+// what the resident actually paid.
+func emitirRecibo(ctx context.Context, tx pgx.Tx, pagoID string) error {
+	var exists bool
+	err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM recibos WHERE pago_id = $1)`, pagoID).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+
+	var parcelaID int
+	var monto money.Amount
+	err = tx.QueryRow(ctx, `
+		SELECT g.parcela_id,
+		       pa.monto + COALESCE((SELECT SUM(cr.monto) FROM creditos_parcela cr
+		                            WHERE cr.pago_id = pa.id AND cr.tipo = 'sobrepago'), 0)
+		FROM pagos pa
+		JOIN gastos_comunes g ON pa.gasto_comun_id = g.id
+		WHERE pa.id = $1`, pagoID).Scan(&parcelaID, &monto)
+	if err != nil {
+		return err
+	}
+
+	numero, err := siguienteCorrelativo(ctx, tx, correlativoRecibo)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO recibos (numero, pago_id, parcela_id, monto)
+		VALUES ($1, $2, $3, $4)`,
+		numero, pagoID, parcelaID, monto)
+	return err
+}
+
+// ============================================
+// RECIBOS
+// ============================================
+
+// GetRecibo returns the receipt of a pago. Pagos registered before receipts
+// existed get their number the first time the receipt is requested.
+func (s *CobranzaService) GetRecibo(ctx context.Context, pagoID string) (*models.Recibo, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var estado, metodo, motivoReverso string
+	err = tx.QueryRow(ctx, `
+		SELECT estado, metodo, COALESCE(motivo_reverso, '') FROM pagos WHERE id = $1 FOR UPDATE`,
+		pagoID).Scan(&estado, &metodo, &motivoReverso)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPagoNotFound
+		}
+		return nil, err
+	}
+	// Credit applications move money that already has a receipt
+	if metodo == models.MetodoPagoCredito ||
+		(estado != models.PagoEstadoApproved && estado != models.PagoEstadoReversed) {
+		return nil, ErrReciboNoDisponible
+	}
+
+	if err = emitirRecibo(ctx, tx, pagoID); err != nil {
+		return nil, err
+	}
+	if estado == models.PagoEstadoReversed {
+		_, err = tx.Exec(ctx, `
+			UPDATE recibos r SET anulado_at = COALESCE(pa.reversed_at, NOW()), motivo_anulacion = $1
+			FROM pagos pa
+			WHERE r.pago_id = pa.id AND r.pago_id = $2 AND r.anulado_at IS NULL`,
+			motivoReverso, pagoID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.getRecibo(ctx, pagoID)
+}
+
+// GetMiRecibo returns a receipt only if the pago belongs to the user's parcela.
+func (s *CobranzaService) GetMiRecibo(ctx context.Context, userID, pagoID string) (*models.Recibo, error) {
+	parcelaID, err := s.parcelaUsuario(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var pagoParcela int
+	err = s.db.Pool.QueryRow(ctx, `
+		SELECT g.parcela_id FROM pagos pa
+		JOIN gastos_comunes g ON pa.gasto_comun_id = g.id
+		WHERE pa.id = $1`, pagoID).Scan(&pagoParcela)
+	if err != nil || pagoParcela != parcelaID {
+		return nil, ErrPagoNotFound
+	}
+
+	return s.GetRecibo(ctx, pagoID)
+}
+
+func (s *CobranzaService) getRecibo(ctx context.Context, pagoID string) (*models.Recibo, error) {
+	var r models.Recibo
+	var userID *string
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT r.id, r.numero, r.pago_id, pa.gasto_comun_id, r.parcela_id, p.numero, g.user_id,
+		       pg.year, pg.month, r.monto, pa.metodo, COALESCE(pa.referencia_externa, ''), pa.created_at,
+		       r.emitido_at, r.anulado_at, COALESCE(r.motivo_anulacion, '')
+		FROM recibos r
+		JOIN pagos pa ON r.pago_id = pa.id
+		JOIN gastos_comunes g ON pa.gasto_comun_id = g.id
+		JOIN periodos_gasto pg ON g.periodo_id = pg.id
+		JOIN parcelas p ON r.parcela_id = p.id
+		WHERE r.pago_id = $1`, pagoID).Scan(
+		&r.ID, &r.Numero, &r.PagoID, &r.GastoComunID, &r.ParcelaID, &r.ParcelaNumero, &userID,
+		&r.Year, &r.Month, &r.Monto, &r.Metodo, &r.Referencia, &r.FechaPago,
+		&r.EmitidoAt, &r.AnuladoAt, &r.MotivoAnulacion)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPagoNotFound
+		}
+		return nil, err
+	}
+
+	nombres, emails, err := s.contactosParcela(ctx, r.ParcelaID)
+	if err != nil {
+		return nil, err
+	}
+	r.Emails = emails
+	if userID != nil {
+		s.db.Pool.QueryRow(ctx, `SELECT name FROM users WHERE id = $1`, *userID).Scan(&r.Pagador)
+	}
+	if r.Pagador == "" && len(nombres) > 0 {
+		r.Pagador = nombres[0]
+	}
+
+	return &r, nil
+}
+
+// EnviarRecibo emails the receipt PDF to the residents of the parcela.
+func (s *CobranzaService) EnviarRecibo(ctx context.Context, pagoID string) (*models.Recibo, error) {
+	recibo, err := s.GetRecibo(ctx, pagoID)
+	if err != nil {
+		return nil, err
+	}
+	if len(recibo.Emails) == 0 {
+		return nil, ErrSinDestinatarios
+	}
+
+	var buf bytes.Buffer
+	if err := export.ReciboPDF(&buf, recibo); err != nil {
+		return nil, err
+	}
+
+	if s.email != nil {
+		numero := fmt.Sprintf("%06d", recibo.Numero)
+		go func() {
+			err := s.email.Send(email.Email{
+				To:       recibo.Emails,
+				Subject:  "Recibo de Pago N° " + numero + " - Comunidad Viña Pelvin",
+				Template: email.TemplatePagoRecibido,
+				Data: map[string]string{
+					"Numero":    numero,
+					"Nombre":    recibo.Pagador,
+					"Monto":     export.FormatCLP(recibo.Monto.Float64()),
+					"Parcela":   recibo.ParcelaNumero,
+					"Concepto":  "Gasto común " + export.NombrePeriodo(recibo.Year, recibo.Month),
+					"FechaPago": recibo.FechaPago.Format("02-01-2006"),
+					"Metodo":    recibo.Metodo,
+				},
+				Attachments: []email.Attachment{{
+					Filename:    "recibo-" + numero + ".pdf",
+					ContentType: "application/pdf",
+					Data:        buf.Bytes(),
+				}},
+			})
+			if err != nil {
+				log.Printf("[EMAIL] Failed to send recibo %s: %v", numero, err)
+			}
+		}()
+	}
+
+	return recibo, nil
+}
+
+// ============================================
+// AVISOS DE COBRO
+// ============================================
+
+// GetAvisoCobro builds the collection notice of a parcela for a periodo: the
+// charges of the month, convenio cuotas falling in it, unpaid debt from
+// earlier periodos and the available credit.
+func (s *CobranzaService) GetAvisoCobro(ctx context.Context, periodoID string, parcelaID int) (*models.AvisoCobro, error) {
+	a := &models.AvisoCobro{
+		PeriodoID:      periodoID,
+		ParcelaID:      parcelaID,
+		FechaEmision:   time.Now(),
+		Lineas:         []models.LineaAviso{},
+		DatosBancarios: s.banco,
+	}
+
+	var descripcion string
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT year, month, fecha_vencimiento, COALESCE(descripcion, ''), monto_base_uf, valor_uf, fecha_uf
+		FROM periodos_gasto WHERE id = $1`,
+		periodoID).Scan(&a.Year, &a.Month, &a.FechaVencimiento, &descripcion, &a.MontoBaseUF, &a.ValorUF, &a.FechaUF)
+	if err != nil {
+		return nil, ErrPeriodoNotFound
+	}
+
+	err = s.db.Pool.QueryRow(ctx, `
+		SELECT numero, COALESCE(direccion, '') FROM parcelas WHERE id = $1`,
+		parcelaID).Scan(&a.ParcelaNumero, &a.Direccion)
+	if err != nil {
+		return nil, ErrParcelaNotFound
+	}
+
+	var gastoID *string
+	var monto, montoPagado money.Amount
+	err = s.db.Pool.QueryRow(ctx, `
+		SELECT id, monto, monto_pagado FROM gastos_comunes
+		WHERE periodo_id = $1 AND parcela_id = $2 AND status <> 'cancelled'`,
+		periodoID, parcelaID).Scan(&gastoID, &monto, &montoPagado)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if gastoID != nil {
+		concepto := "Gasto común " + export.NombrePeriodo(a.Year, a.Month)
+		if descripcion != "" {
+			concepto += " - " + descripcion
+		}
+		a.Lineas = append(a.Lineas, models.LineaAviso{Descripcion: concepto, Monto: monto})
+		a.AbonosPeriodo = montoPagado
+	}
+
+	// Interest, fines and adjustments of the month or tied to this gasto
+	desde := time.Date(a.Year, time.Month(a.Month), 1, 0, 0, 0, 0, time.UTC)
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT COALESCE(NULLIF(descripcion, ''), tipo), monto FROM cargos_parcela
+		WHERE parcela_id = $1
+		  AND (gasto_comun_id = $2 OR (gasto_comun_id IS NULL AND fecha >= $3 AND fecha < $4))
+		ORDER BY fecha, created_at`,
+		parcelaID, gastoID, desde, desde.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var l models.LineaAviso
+		if err := rows.Scan(&l.Descripcion, &l.Monto); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		a.Lineas = append(a.Lineas, l)
+	}
+	rows.Close()
+
+	rows, err = s.db.Pool.Query(ctx, `
+		SELECT cc.numero, c.num_cuotas, cc.monto FROM convenio_cuotas cc
+		JOIN convenios_pago c ON cc.convenio_id = c.id
+		WHERE c.parcela_id = $1 AND cc.periodo_id = $2 AND c.estado = 'activo' AND cc.estado <> 'pagada'
+		ORDER BY c.created_at, cc.numero`,
+		parcelaID, periodoID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var numero, total int
+		var l models.LineaAviso
+		if err := rows.Scan(&numero, &total, &l.Monto); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		l.Descripcion = fmt.Sprintf("Cuota %d/%d convenio de pago", numero, total)
+		a.Lineas = append(a.Lineas, l)
+	}
+	rows.Close()
+
+	for _, l := range a.Lineas {
+		a.TotalPeriodo += l.Monto
+	}
+
+	// Debt under a convenio is billed through its cuotas
+	err = s.db.Pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(g.monto - g.monto_pagado), 0)
+		FROM gastos_comunes g
+		JOIN periodos_gasto pg ON g.periodo_id = pg.id
+		WHERE g.parcela_id = $1 AND g.status IN ('pending', 'overdue')
+		  AND (pg.year, pg.month) < ($2, $3)`,
+		parcelaID, a.Year, a.Month).Scan(&a.DeudaAnterior)
+	if err != nil {
+		return nil, err
+	}
+
+	saldo, err := saldoCredito(ctx, s.db.Pool, parcelaID)
+	if err != nil {
+		return nil, err
+	}
+	a.SaldoFavor = saldo
+
+	a.TotalAPagar = max(0, a.TotalPeriodo-a.AbonosPeriodo+a.DeudaAnterior-a.SaldoFavor)
+
+	nombres, emails, err := s.contactosParcela(ctx, parcelaID)
+	if err != nil {
+		return nil, err
+	}
+	a.Emails = emails
+	if len(nombres) > 0 {
+		a.Propietario = nombres[0]
+	}
+
+	return a, nil
+}
+
+// GetMiAvisoCobro returns the aviso de cobro of the user's parcela.
+func (s *CobranzaService) GetMiAvisoCobro(ctx context.Context, userID, periodoID string) (*models.AvisoCobro, error) {
+	parcelaID, err := s.parcelaUsuario(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetAvisoCobro(ctx, periodoID, parcelaID)
+}
+
+// EnviarAvisos emails the aviso de cobro PDF to every parcela billed in the
+// periodo. The notices are built up front and sent in the background.
+func (s *CobranzaService) EnviarAvisos(ctx context.Context, periodoID string) (*models.EnvioAvisosResult, error) {
+	var exists bool
+	if err := s.db.Pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM periodos_gasto WHERE id = $1)`, periodoID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrPeriodoNotFound
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT parcela_id FROM gastos_comunes WHERE periodo_id = $1 AND status <> 'cancelled'
+		UNION
+		SELECT c.parcela_id FROM convenio_cuotas cc
+		JOIN convenios_pago c ON cc.convenio_id = c.id
+		WHERE cc.periodo_id = $1 AND c.estado = 'activo'
+		ORDER BY 1`, periodoID)
+	if err != nil {
+		return nil, err
+	}
+	var parcelas []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		parcelas = append(parcelas, id)
+	}
+	rows.Close()
+
+	result := &models.EnvioAvisosResult{}
+	var envios []email.Email
+	for _, parcelaID := range parcelas {
+		aviso, err := s.GetAvisoCobro(ctx, periodoID, parcelaID)
+		if err != nil {
+			return nil, err
+		}
+		result.Avisos++
+		if len(aviso.Emails) == 0 {
+			result.SinEmail++
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := export.AvisoCobroPDF(&buf, aviso); err != nil {
+			return nil, err
+		}
+		periodo := export.NombrePeriodo(aviso.Year, aviso.Month)
+		envios = append(envios, email.Email{
+			To:       aviso.Emails,
+			Subject:  "Aviso de Cobro " + periodo + " - Parcela " + aviso.ParcelaNumero,
+			Template: email.TemplateGastoComun,
+			Data: map[string]string{
+				"Nombre":           aviso.Propietario,
+				"Periodo":          periodo,
+				"Monto":            strings.TrimPrefix(export.FormatCLP(aviso.TotalAPagar.Float64()), "$"),
+				"Parcela":          aviso.ParcelaNumero,
+				"FechaVencimiento": aviso.FechaVencimiento.Format("02-01-2006"),
+			},
+			Attachments: []email.Attachment{{
+				Filename:    fmt.Sprintf("aviso-%d-%02d-parcela-%s.pdf", aviso.Year, aviso.Month, aviso.ParcelaNumero),
+				ContentType: "application/pdf",
+				Data:        buf.Bytes(),
+			}},
+		})
+		result.Enviados++
+	}
+
+	if s.email != nil && len(envios) > 0 {
+		go func() {
+			for _, e := range envios {
+				if err := s.email.Send(e); err != nil {
+					log.Printf("[EMAIL] Failed to send aviso de cobro to %v: %v", e.To, err)
+				}
+			}
+		}()
+	}
+
+	return result, nil
+}
+
+// ============================================
+// HELPERS
+// ============================================
+
+func (s *CobranzaService) parcelaUsuario(ctx context.Context, userID string) (int, error) {
+	var parcelaID *int
+	err := s.db.Pool.QueryRow(ctx, `SELECT parcela_id FROM users WHERE id = $1`, userID).Scan(&parcelaID)
+	if err != nil {
+		return 0, err
+	}
+	if parcelaID == nil {
+		return 0, ErrUserNoParcela
+	}
+	return *parcelaID, nil
+}
+
+// contactosParcela returns the names and emails of the residents of a parcela,
+// oldest account first.
+func (s *CobranzaService) contactosParcela(ctx context.Context, parcelaID int) ([]string, []string, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT name, email FROM users
+		WHERE parcela_id = $1 AND role IN ('vecino', 'directiva')
+		ORDER BY created_at`, parcelaID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var nombres, emails []string
+	for rows.Next() {
+		var nombre, correo string
+		if err := rows.Scan(&nombre, &correo); err != nil {
+			return nil, nil, err
+		}
+		nombres = append(nombres, nombre)
+		emails = append(emails, correo)
+	}
+	return nombres, emails, rows.Err()
+}
@@ -0,0 +1,982 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/money"
+	"github.com/condominio/backend/pkg/ofx"
+)
+
+var (
+	ErrLineaCartolaNotFound    = errors.New("statement line not found")
+	ErrLineaConciliada         = errors.New("statement line is already reconciled")
+	ErrLineaNoConciliada       = errors.New("statement line is not reconciled")
+	ErrMovimientoConciliado    = errors.New("movimiento is already reconciled with another statement line")
+	ErrMovimientoNoConciliable = errors.New("movimiento must be of the same cuenta and amount as the statement line")
+	ErrPeriodoConciliado       = errors.New("the line belongs to a closed reconciliation period")
+	ErrMovimientoEnCierre      = errors.New("movimiento belongs to a closed reconciliation period")
+	ErrInvalidCierre           = errors.New("invalid fecha_corte")
+	ErrCierreAnterior          = errors.New("the cuenta is already reconciled up to a later date")
+	ErrSaldoBancoRequerido     = errors.New("saldo_banco is required: no statement ends on fecha_corte")
+)
+
+// Days around a statement line in which a movimiento of the same amount is
+// offered as a candidate: deposits and cheques take a few days to show up.
+const diasSugerenciaConciliacion = 5
+
+// lineaBanco is a statement line read from a file, before it is stored.
+type lineaBanco struct {
+	linea       int
+	fecha       time.Time
+	monto       money.Amount
+	descripcion string
+	referencia  string
+	huella      string
+}
+
+// montoLibro is the effect of a movimiento on its cuenta balance, with the
+// sign a statement line has.
+const montoLibro = `CASE m.type WHEN 'ingreso' THEN m.amount ELSE -m.amount END`
+
+// movimientoPendiente selects the movimientos of a cuenta ($1) that can still
+// be reconciled. Voids and their reverse entries net out and are left aside.
+const movimientoPendiente = `
+	m.cuenta_id = $1 AND m.anulado_at IS NULL AND m.reverso_de IS NULL
+	AND NOT EXISTS (SELECT 1 FROM cartola_lineas l WHERE l.movimiento_id = m.id)`
+
+func signoLibro(m *models.Movimiento) money.Amount {
+	if m.Type == models.MovimientoEgreso {
+		return -m.Amount
+	}
+	return m.Amount
+}
+
+// ============================================
+// CARTOLAS
+// ============================================
+
+// CreateCartola loads a bank statement for a cuenta. CSV files are read with
+// an import layout (formatoID); OFX files carry their own structure. Lines
+// already loaded by an overlapping statement are skipped, and the new lines
+// are matched against the ledger right away.
+func (s *TesoreriaService) CreateCartola(ctx context.Context, cuentaID string, formato models.CartolaFormato, formatoID, archivoNombre string, data []byte, userID string) (*models.CartolaBancaria, error) {
+	if _, err := s.GetCuenta(ctx, cuentaID); err != nil {
+		return nil, err
+	}
+	if formato == "" {
+		formato = models.CartolaCSV
+		ext := strings.ToLower(filepath.Ext(archivoNombre))
+		if ext == ".ofx" || ext == ".qfx" || strings.Contains(strings.ToUpper(string(data[:min(len(data), 4096)])), "<OFX>") {
+			formato = models.CartolaOFX
+		}
+	}
+
+	c := &models.CartolaBancaria{CuentaID: cuentaID, ArchivoNombre: truncar(archivoNombre, 255), Formato: formato}
+	var lineas []lineaBanco
+	var err error
+	switch formato {
+	case models.CartolaCSV:
+		if formatoID == "" {
+			return nil, ErrInvalidFormato
+		}
+		f, err := scanFormato(s.db.Pool.QueryRow(ctx, selectFormato+` WHERE id::text = $1`, formatoID))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrFormatoNotFound
+			}
+			return nil, err
+		}
+		c.FormatoID = &f.ID
+		if lineas, c.Omitidas, err = lineasCSV(f, data); err != nil {
+			return nil, err
+		}
+	case models.CartolaOFX:
+		if lineas, c.Omitidas, err = lineasOFX(c, data); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrInvalidFormato
+	}
+	if len(lineas) == 0 {
+		return nil, fmt.Errorf("%w: no readable lines", ErrArchivoInvalido)
+	}
+	// The period stated by the file, or else the one its lines cover
+	desde, hasta := lineas[0].fecha, lineas[0].fecha
+	for _, l := range lineas {
+		if l.fecha.Before(desde) {
+			desde = l.fecha
+		}
+		if l.fecha.After(hasta) {
+			hasta = l.fecha
+		}
+	}
+	if c.Desde == nil {
+		c.Desde = &desde
+	}
+	if c.Hasta == nil {
+		c.Hasta = &hasta
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO cartolas_bancarias (cuenta_id, archivo_nombre, formato, formato_id, desde, hasta, saldo_final, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id`,
+		cuentaID, c.ArchivoNombre, c.Formato, c.FormatoID, c.Desde, c.Hasta, c.SaldoFinal, userID).Scan(&c.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range lineas {
+		result, err := tx.Exec(ctx, `
+			INSERT INTO cartola_lineas (cartola_id, cuenta_id, linea, fecha, monto, descripcion, referencia, huella)
+			VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8)
+			ON CONFLICT (cuenta_id, huella) DO NOTHING`,
+			c.ID, cuentaID, l.linea, l.fecha, l.monto, l.descripcion, truncar(l.referencia, 255), l.huella)
+		if err != nil {
+			return nil, err
+		}
+		if result.RowsAffected() == 0 {
+			c.Duplicadas++
+		}
+	}
+
+	if _, err = conciliarAutomatico(ctx, tx, cuentaID, userID); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	cartola, err := s.getCartola(ctx, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	cartola.Duplicadas, cartola.Omitidas = c.Duplicadas, c.Omitidas
+	return cartola, nil
+}
+
+// lineasCSV keeps the rows of a CSV statement that can be reconciled. Unlike
+// the pago import, charges (negative amounts) are statement lines too.
+func lineasCSV(f *models.FormatoImportacion, data []byte) ([]lineaBanco, []models.LineaOmitida, error) {
+	filas, err := leerCSVBanco(f, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lineas := []lineaBanco{}
+	omitidas := []models.LineaOmitida{}
+	for _, fila := range filas {
+		monto := fila.monto
+		switch {
+		case fila.estado == models.FilaError:
+			omitidas = append(omitidas, models.LineaOmitida{Linea: fila.linea, Motivo: fila.err})
+			continue
+		case fila.fecha == nil:
+			omitidas = append(omitidas, models.LineaOmitida{Linea: fila.linea, Motivo: "Fecha inválida"})
+			continue
+		case monto == 0:
+			omitidas = append(omitidas, models.LineaOmitida{Linea: fila.linea, Motivo: "Monto cero"})
+			continue
+		}
+		lineas = append(lineas, lineaBanco{
+			linea:       fila.linea,
+			fecha:       *fila.fecha,
+			monto:       monto,
+			descripcion: strings.TrimSpace(fila.nombre + " " + fila.descripcion),
+			referencia:  fila.referencia,
+			huella:      fila.huella,
+		})
+	}
+	return lineas, omitidas, nil
+}
+
+// lineasOFX reads the transactions of an OFX statement, and its period and
+// closing balance into c.
+func lineasOFX(c *models.CartolaBancaria, data []byte) ([]lineaBanco, []models.LineaOmitida, error) {
+	st, err := ofx.Parse(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrArchivoInvalido, err)
+	}
+	if len(st.Transactions) > maxFilasImportacion {
+		return nil, nil, fmt.Errorf("%w: more than %d rows", ErrArchivoInvalido, maxFilasImportacion)
+	}
+	if !st.Start.IsZero() && !st.End.IsZero() {
+		c.Desde, c.Hasta = &st.Start, &st.End
+	}
+	if st.Balance != nil {
+		if saldo, err := montoOFX(*st.Balance); err == nil {
+			c.SaldoFinal = &saldo
+		}
+	}
+
+	lineas := []lineaBanco{}
+	omitidas := []models.LineaOmitida{}
+	ocurrencias := map[string]int{}
+	for i, t := range st.Transactions {
+		n := i + 1
+		monto, err := montoOFX(t.Amount)
+		switch {
+		case t.Posted.IsZero():
+			omitidas = append(omitidas, models.LineaOmitida{Linea: n, Motivo: "Fecha inválida"})
+			continue
+		case err != nil:
+			omitidas = append(omitidas, models.LineaOmitida{Linea: n, Motivo: "Monto inválido: " + t.Amount})
+			continue
+		case monto == 0:
+			omitidas = append(omitidas, models.LineaOmitida{Linea: n, Motivo: "Monto cero"})
+			continue
+		}
+
+		// FITID is the bank's own id of the transaction; files without it
+		// fall back to the content, as the CSV import does
+		clave := "ofx|" + t.FITID
+		if t.FITID == "" {
+			clave = strings.Join([]string{t.Posted.Format("2006-01-02"), monto.String(), t.Name, t.Memo, t.Ref}, "|")
+			ocurrencias[clave]++
+			clave += "|" + strconv.Itoa(ocurrencias[clave])
+		}
+		sum := sha256.Sum256([]byte(clave))
+
+		referencia := t.Ref
+		if referencia == "" {
+			referencia = t.FITID
+		}
+		lineas = append(lineas, lineaBanco{
+			linea:       n,
+			fecha:       t.Posted,
+			monto:       monto,
+			descripcion: strings.TrimSpace(t.Name + " " + t.Memo),
+			referencia:  referencia,
+			huella:      hex.EncodeToString(sum[:]),
+		})
+	}
+	return lineas, omitidas, nil
+}
+
+// montoOFX reads a TRNAMT or BALAMT; some banks write a decimal comma or
+// more than two decimals.
+func montoOFX(v string) (money.Amount, error) {
+	v = strings.ReplaceAll(strings.TrimSpace(v), ",", ".")
+	if a, err := money.Parse(v); err == nil {
+		return a, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, money.ErrInvalid
+	}
+	return money.FromFloat(f), nil
+}
+
+const cartolaColumns = `
+	ca.id, ca.cuenta_id, cu.nombre, ca.archivo_nombre, ca.formato, ca.formato_id, ca.desde, ca.hasta,
+	ca.saldo_final,
+	(SELECT COUNT(*) FROM cartola_lineas l WHERE l.cartola_id = ca.id),
+	(SELECT COUNT(*) FROM cartola_lineas l WHERE l.cartola_id = ca.id AND l.movimiento_id IS NOT NULL),
+	ca.created_by, ca.created_at
+	FROM cartolas_bancarias ca
+	JOIN cuentas_tesoreria cu ON cu.id = ca.cuenta_id`
+
+func scanCartola(row pgx.Row, c *models.CartolaBancaria) error {
+	return row.Scan(&c.ID, &c.CuentaID, &c.CuentaNombre, &c.ArchivoNombre, &c.Formato, &c.FormatoID, &c.Desde, &c.Hasta,
+		&c.SaldoFinal, &c.TotalLineas, &c.Conciliadas, &c.CreatedBy, &c.CreatedAt)
+}
+
+func (s *TesoreriaService) getCartola(ctx context.Context, id string) (*models.CartolaBancaria, error) {
+	var c models.CartolaBancaria
+	if err := scanCartola(s.db.Pool.QueryRow(ctx, `SELECT `+cartolaColumns+` WHERE ca.id = $1`, id), &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ListCartolas returns the statements of a cuenta, latest period first.
+func (s *TesoreriaService) ListCartolas(ctx context.Context, cuentaID string) ([]models.CartolaBancaria, error) {
+	if _, err := s.GetCuenta(ctx, cuentaID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `SELECT `+cartolaColumns+`
+		WHERE ca.cuenta_id = $1
+		ORDER BY ca.hasta DESC NULLS LAST, ca.created_at DESC`, cuentaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cartolas := []models.CartolaBancaria{}
+	for rows.Next() {
+		var c models.CartolaBancaria
+		if err := scanCartola(rows, &c); err != nil {
+			return nil, err
+		}
+		cartolas = append(cartolas, c)
+	}
+	return cartolas, rows.Err()
+}
+
+// ============================================
+// CONCILIACIÓN
+// ============================================
+
+const lineaCartolaColumns = `
+	l.id, l.cartola_id, l.linea, l.fecha, l.monto, COALESCE(l.descripcion, ''), COALESCE(l.referencia, ''),
+	l.movimiento_id, l.match, l.conciliada_at, l.conciliada_by
+	FROM cartola_lineas l`
+
+func scanLineaCartola(row pgx.Row, l *models.LineaCartola) error {
+	return row.Scan(&l.ID, &l.CartolaID, &l.Linea, &l.Fecha, &l.Monto, &l.Descripcion, &l.Referencia,
+		&l.MovimientoID, &l.Match, &l.ConciliadaAt, &l.ConciliadaBy)
+}
+
+// GetConciliacionBancaria builds the reconciliation screen of a cuenta
+// between desde and hasta.
+func (s *TesoreriaService) GetConciliacionBancaria(ctx context.Context, cuentaID string, desde, hasta time.Time) (*models.ConciliacionBancaria, error) {
+	if desde.After(hasta) {
+		return nil, ErrInvalidRangoFechas
+	}
+	cuenta, err := s.GetCuenta(ctx, cuentaID)
+	if err != nil {
+		return nil, err
+	}
+	c := &models.ConciliacionBancaria{
+		CuentaID:              cuenta.ID,
+		CuentaNombre:          cuenta.Nombre,
+		Desde:                 desde,
+		Hasta:                 hasta,
+		Conciliadas:           []models.LineaCartola{},
+		LineasPendientes:      []models.LineaCartola{},
+		MovimientosPendientes: []models.Movimiento{},
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `SELECT `+lineaCartolaColumns+`
+		WHERE l.cuenta_id = $1 AND l.fecha BETWEEN $2 AND $3
+		ORDER BY l.fecha, l.cartola_id, l.linea`, cuenta.ID, desde, hasta)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conciliadosIDs []string
+	for rows.Next() {
+		var l models.LineaCartola
+		if err := scanLineaCartola(rows, &l); err != nil {
+			return nil, err
+		}
+		if l.MovimientoID != nil {
+			conciliadosIDs = append(conciliadosIDs, *l.MovimientoID)
+			c.Conciliadas = append(c.Conciliadas, l)
+		} else {
+			c.LineasPendientes = append(c.LineasPendientes, l)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Matched movimientos may fall outside the range
+	conciliados, err := s.movimientosPorID(ctx, conciliadosIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range c.Conciliadas {
+		c.Conciliadas[i].Movimiento = conciliados[*c.Conciliadas[i].MovimientoID]
+	}
+
+	// Pending movimientos around the range are candidates for its lines
+	margen := diasSugerenciaConciliacion * 24 * time.Hour
+	rows, err = s.db.Pool.Query(ctx, `SELECT `+movimientoColumns+movimientoJoins+`
+		WHERE `+movimientoPendiente+` AND m.date BETWEEN $2 AND $3
+		ORDER BY m.date, m.comprobante`, cuenta.ID, desde.Add(-margen), hasta.Add(margen))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidatos []models.Movimiento
+	for rows.Next() {
+		var m models.Movimiento
+		if err := scanMovimiento(rows, &m); err != nil {
+			return nil, err
+		}
+		candidatos = append(candidatos, m)
+		if !m.Date.Before(desde) && !m.Date.After(hasta) {
+			c.MovimientosPendientes = append(c.MovimientosPendientes, m)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range c.LineasPendientes {
+		l := &c.LineasPendientes[i]
+		for _, m := range candidatos {
+			if signoLibro(&m) == l.Monto && diasEntre(m.Date, l.Fecha) <= diasSugerenciaConciliacion {
+				l.Sugerencias = append(l.Sugerencias, m)
+			}
+		}
+		sort.SliceStable(l.Sugerencias, func(a, b int) bool {
+			return diasEntre(l.Sugerencias[a].Date, l.Fecha) < diasEntre(l.Sugerencias[b].Date, l.Fecha)
+		})
+	}
+
+	if c.SaldoLibro, err = saldoLibro(ctx, s.db.Pool, cuenta.ID, hasta); err != nil {
+		return nil, err
+	}
+	if c.UltimoCierre, err = s.ultimoCierre(ctx, cuenta.ID); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *TesoreriaService) movimientosPorID(ctx context.Context, ids []string) (map[string]*models.Movimiento, error) {
+	movimientos := map[string]*models.Movimiento{}
+	if len(ids) == 0 {
+		return movimientos, nil
+	}
+	rows, err := s.db.Pool.Query(ctx, `SELECT `+movimientoColumns+movimientoJoins+` WHERE m.id = ANY($1::uuid[])`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.Movimiento
+		if err := scanMovimiento(rows, &m); err != nil {
+			return nil, err
+		}
+		movimientos[m.ID] = &m
+	}
+	return movimientos, rows.Err()
+}
+
+func diasEntre(a, b time.Time) int {
+	d := int(a.Sub(b).Hours() / 24)
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// saldoLibro is the ledger balance of a cuenta at the end of fecha. Voids
+// count both entries, which net out once the reverse entry is booked.
+func saldoLibro(ctx context.Context, q querier, cuentaID string, fecha time.Time) (money.Amount, error) {
+	var saldo money.Amount
+	err := q.QueryRow(ctx, `
+		SELECT COALESCE(SUM(`+montoLibro+`), 0)
+		FROM movimientos_tesoreria m
+		WHERE m.cuenta_id = $1 AND m.date <= $2`, cuentaID, fecha).Scan(&saldo)
+	return saldo, err
+}
+
+// ConciliarAutomatico matches the pending statement lines of a cuenta.
+func (s *TesoreriaService) ConciliarAutomatico(ctx context.Context, cuentaID, userID string) (*models.ConciliacionAutomaticaResult, error) {
+	if _, err := s.GetCuenta(ctx, cuentaID); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := conciliarAutomatico(ctx, tx, cuentaID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type lineaPendiente struct {
+	id         string
+	fecha      time.Time
+	monto      money.Amount
+	referencia string
+	conciliada bool
+}
+
+type candidatoConciliacion struct {
+	id          string
+	fecha       time.Time
+	monto       money.Amount
+	descripcion string
+	referencias []string // of the pagos and facturas behind the movimiento
+	usado       bool
+}
+
+// conciliarAutomatico matches pending statement lines with pending
+// movimientos of the same amount. A line is matched when the bank reference
+// is the one of the pago or factura behind a movimiento, or appears in its
+// description; otherwise only when a single line and a single movimiento
+// share amount and date, so nothing is guessed.
+func conciliarAutomatico(ctx context.Context, tx pgx.Tx, cuentaID, userID string) (*models.ConciliacionAutomaticaResult, error) {
+	// Serializes concurrent runs on the same cuenta
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM cuentas_tesoreria WHERE id = $1 FOR UPDATE`, cuentaID); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT l.id, l.fecha, l.monto, COALESCE(l.referencia, '')
+		FROM cartola_lineas l
+		WHERE l.cuenta_id = $1 AND l.movimiento_id IS NULL
+		ORDER BY l.fecha, l.linea`, cuentaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lineas []*lineaPendiente
+	for rows.Next() {
+		var l lineaPendiente
+		if err := rows.Scan(&l.id, &l.fecha, &l.monto, &l.referencia); err != nil {
+			return nil, err
+		}
+		lineas = append(lineas, &l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	result := &models.ConciliacionAutomaticaResult{}
+	if len(lineas) == 0 {
+		return result, nil
+	}
+
+	margen := diasSugerenciaConciliacion * 24 * time.Hour
+	rows, err = tx.Query(ctx, `
+		SELECT m.id, m.date, `+montoLibro+`, m.description,
+		       ARRAY(SELECT pa.referencia_externa FROM pagos_tesoreria pt JOIN pagos pa ON pa.id = pt.pago_id
+		             WHERE pt.movimiento_id = m.id AND pa.referencia_externa IS NOT NULL
+		             UNION SELECT f.numero FROM facturas f WHERE f.movimiento_id = m.id)
+		FROM movimientos_tesoreria m
+		WHERE `+movimientoPendiente+` AND m.date BETWEEN $2 AND $3
+		ORDER BY m.date, m.comprobante`,
+		cuentaID, lineas[0].fecha.Add(-margen), lineas[len(lineas)-1].fecha.Add(margen))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidatos []*candidatoConciliacion
+	for rows.Next() {
+		var c candidatoConciliacion
+		if err := rows.Scan(&c.id, &c.fecha, &c.monto, &c.descripcion, &c.referencias); err != nil {
+			return nil, err
+		}
+		candidatos = append(candidatos, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	asignar := func(l *lineaPendiente, c *candidatoConciliacion, match models.MatchCartola) error {
+		l.conciliada, c.usado = true, true
+		result.Conciliadas++
+		_, err := tx.Exec(ctx, `
+			UPDATE cartola_lineas
+			SET movimiento_id = $1, match = $2, conciliada_at = NOW(), conciliada_by = NULLIF($3, '')::uuid
+			WHERE id = $4`, c.id, match, userID, l.id)
+		return err
+	}
+
+	// By reference: the closest in date wins
+	for _, l := range lineas {
+		if l.referencia == "" {
+			continue
+		}
+		var mejor *candidatoConciliacion
+		for _, c := range candidatos {
+			if c.usado || c.monto != l.monto || !c.tieneReferencia(l.referencia) {
+				continue
+			}
+			if mejor == nil || diasEntre(c.fecha, l.fecha) < diasEntre(mejor.fecha, l.fecha) {
+				mejor = c
+			}
+		}
+		if mejor != nil {
+			if err := asignar(l, mejor, models.MatchCartolaReferencia); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// By amount and date, only when unambiguous on both sides
+	clave := func(fecha time.Time, monto money.Amount) string {
+		return fecha.Format("2006-01-02") + "|" + monto.String()
+	}
+	lineasPorClave := map[string]int{}
+	for _, l := range lineas {
+		if !l.conciliada {
+			lineasPorClave[clave(l.fecha, l.monto)]++
+		}
+	}
+	movimientosPorClave := map[string][]*candidatoConciliacion{}
+	for _, c := range candidatos {
+		if !c.usado {
+			k := clave(c.fecha, c.monto)
+			movimientosPorClave[k] = append(movimientosPorClave[k], c)
+		}
+	}
+	for _, l := range lineas {
+		if l.conciliada {
+			continue
+		}
+		k := clave(l.fecha, l.monto)
+		if lineasPorClave[k] == 1 && len(movimientosPorClave[k]) == 1 {
+			if err := asignar(l, movimientosPorClave[k][0], models.MatchCartolaMontoFecha); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result.Pendientes = len(lineas) - result.Conciliadas
+	return result, nil
+}
+
+func (c *candidatoConciliacion) tieneReferencia(ref string) bool {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	for _, r := range c.referencias {
+		if strings.ToLower(strings.TrimSpace(r)) == ref {
+			return true
+		}
+	}
+	// Short references (a cheque number) would match any description
+	return len(ref) >= 4 && strings.Contains(strings.ToLower(c.descripcion), ref)
+}
+
+// ConciliarLinea matches a statement line with a movimiento by hand.
+func (s *TesoreriaService) ConciliarLinea(ctx context.Context, lineaID, movimientoID, userID string) (*models.LineaCartola, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	l, err := lockLineaCartola(ctx, tx, lineaID)
+	if err != nil {
+		return nil, err
+	}
+	if l.MovimientoID != nil {
+		return nil, ErrLineaConciliada
+	}
+	m, err := lockMovimiento(ctx, tx, movimientoID)
+	if err != nil {
+		if errors.Is(err, ErrMovimientoAnulado) || errors.Is(err, ErrMovimientoReverso) {
+			return nil, ErrMovimientoNoConciliable
+		}
+		return nil, err
+	}
+	var cuentaID string
+	if err = tx.QueryRow(ctx, `SELECT cuenta_id FROM cartola_lineas WHERE id = $1`, l.ID).Scan(&cuentaID); err != nil {
+		return nil, err
+	}
+	if m.CuentaID != cuentaID || signoLibro(m) != l.Monto {
+		return nil, ErrMovimientoNoConciliable
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE cartola_lineas
+		SET movimiento_id = $1, match = $2, conciliada_at = NOW(), conciliada_by = $3
+		WHERE id = $4`, m.ID, models.MatchCartolaManual, userID, l.ID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+			return nil, ErrMovimientoConciliado
+		}
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.getLineaCartola(ctx, l.ID)
+}
+
+// DesconciliarLinea undoes a match. Lines of a period already closed keep
+// their match: the signed record relies on them.
+func (s *TesoreriaService) DesconciliarLinea(ctx context.Context, lineaID, userID string) (*models.LineaCartola, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	l, err := lockLineaCartola(ctx, tx, lineaID)
+	if err != nil {
+		return nil, err
+	}
+	if l.MovimientoID == nil {
+		return nil, ErrLineaNoConciliada
+	}
+	var cerrado bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM conciliaciones_bancarias cb
+		               JOIN cartola_lineas cl ON cl.cuenta_id = cb.cuenta_id
+		               WHERE cl.id = $1 AND cb.fecha_corte >= cl.fecha)`, l.ID).Scan(&cerrado)
+	if err != nil {
+		return nil, err
+	}
+	if cerrado {
+		return nil, ErrPeriodoConciliado
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE cartola_lineas SET movimiento_id = NULL, match = NULL, conciliada_at = NULL, conciliada_by = NULL
+		WHERE id = $1`, l.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = registrarAuditoria(ctx, tx, "cartola_linea", l.ID, "desconciliar", "", userID, map[string]interface{}{
+		"movimiento_id": *l.MovimientoID,
+		"match":         l.Match,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.getLineaCartola(ctx, l.ID)
+}
+
+func lockLineaCartola(ctx context.Context, tx pgx.Tx, id string) (*models.LineaCartola, error) {
+	var l models.LineaCartola
+	err := scanLineaCartola(tx.QueryRow(ctx, `SELECT `+lineaCartolaColumns+` WHERE l.id::text = $1 FOR UPDATE`, id), &l)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrLineaCartolaNotFound
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (s *TesoreriaService) getLineaCartola(ctx context.Context, id string) (*models.LineaCartola, error) {
+	var l models.LineaCartola
+	if err := scanLineaCartola(s.db.Pool.QueryRow(ctx, `SELECT `+lineaCartolaColumns+` WHERE l.id = $1`, id), &l); err != nil {
+		return nil, err
+	}
+	if l.MovimientoID != nil {
+		m, err := s.GetByID(ctx, *l.MovimientoID)
+		if err != nil {
+			return nil, err
+		}
+		l.Movimiento = m
+	}
+	return &l, nil
+}
+
+// liberarLineaCartola unmatches the statement line of a movimiento that was
+// voided or whose amount or cuenta changed. Lines of a closed period keep
+// their match: the signed record relies on them.
+func liberarLineaCartola(ctx context.Context, tx pgx.Tx, movimientoID string) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE cartola_lineas cl
+		SET movimiento_id = NULL, match = NULL, conciliada_at = NULL, conciliada_by = NULL
+		WHERE cl.movimiento_id = $1
+		  AND NOT EXISTS (SELECT 1 FROM conciliaciones_bancarias cb
+		                  WHERE cb.cuenta_id = cl.cuenta_id AND cb.fecha_corte >= cl.fecha)`, movimientoID)
+	return err
+}
+
+// movimientoEnCierre reports whether a movimiento dated fecha in the cuenta,
+// or the statement line it is reconciled with, falls in a closed period.
+func movimientoEnCierre(ctx context.Context, q querier, movimientoID, cuentaID string, fecha time.Time) (bool, error) {
+	var cerrado bool
+	err := q.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM conciliaciones_bancarias WHERE cuenta_id = $2 AND fecha_corte >= $3)
+		    OR EXISTS (SELECT 1 FROM cartola_lineas cl
+		               JOIN conciliaciones_bancarias cb ON cb.cuenta_id = cl.cuenta_id
+		               WHERE cl.movimiento_id = $1 AND cb.fecha_corte >= cl.fecha)`,
+		movimientoID, cuentaID, fecha).Scan(&cerrado)
+	return cerrado, err
+}
+
+// ============================================
+// CIERRES
+// ============================================
+
+const cierreColumns = `
+	cb.id, cb.cuenta_id, cb.fecha_corte, cb.saldo_banco, cb.saldo_libro, cb.diferencia,
+	cb.lineas_pendientes, cb.monto_lineas_pendientes, cb.movimientos_pendientes,
+	cb.monto_movimientos_pendientes, cb.diferencia_no_explicada, COALESCE(cb.observaciones, ''),
+	cb.firmado_by, COALESCE(u.name, ''), cb.firmado_at
+	FROM conciliaciones_bancarias cb
+	LEFT JOIN users u ON u.id = cb.firmado_by`
+
+func scanCierre(row pgx.Row, c *models.CierreConciliacion) error {
+	return row.Scan(&c.ID, &c.CuentaID, &c.FechaCorte, &c.SaldoBanco, &c.SaldoLibro, &c.Diferencia,
+		&c.LineasPendientes, &c.MontoLineasPendientes, &c.MovimientosPendientes,
+		&c.MontoMovimientosPendientes, &c.DiferenciaNoExplicada, &c.Observaciones,
+		&c.FirmadoBy, &c.FirmadoPor, &c.FirmadoAt)
+}
+
+// CreateCierre closes the reconciliation of a cuenta at req.FechaCorte,
+// signed by userID. It states the bank and book balances, the items still
+// unreconciled on each side and the difference they do not explain.
+// Movimientos dated before the first statement line are taken as already
+// reflected in the bank balance.
+func (s *TesoreriaService) CreateCierre(ctx context.Context, cuentaID string, req *models.CreateCierreRequest, userID string) (*models.CierreConciliacion, error) {
+	cuenta, err := s.GetCuenta(ctx, cuentaID)
+	if err != nil {
+		return nil, err
+	}
+	if req.FechaCorte.IsZero() {
+		return nil, ErrInvalidCierre
+	}
+	y, m, d := req.FechaCorte.Date()
+	req.FechaCorte = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	if req.FechaCorte.After(fechaHoy()) {
+		return nil, ErrInvalidCierre
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, `SELECT 1 FROM cuentas_tesoreria WHERE id = $1 FOR UPDATE`, cuenta.ID); err != nil {
+		return nil, err
+	}
+	var posterior bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM conciliaciones_bancarias WHERE cuenta_id = $1 AND fecha_corte >= $2)`,
+		cuenta.ID, req.FechaCorte).Scan(&posterior)
+	if err != nil {
+		return nil, err
+	}
+	if posterior {
+		return nil, ErrCierreAnterior
+	}
+
+	c := &models.CierreConciliacion{CuentaID: cuenta.ID, FechaCorte: req.FechaCorte, Observaciones: strings.TrimSpace(req.Observaciones)}
+	if req.SaldoBanco != nil {
+		c.SaldoBanco = *req.SaldoBanco
+	} else {
+		err = tx.QueryRow(ctx, `
+			SELECT saldo_final FROM cartolas_bancarias
+			WHERE cuenta_id = $1 AND hasta = $2 AND saldo_final IS NOT NULL
+			ORDER BY created_at DESC LIMIT 1`, cuenta.ID, req.FechaCorte).Scan(&c.SaldoBanco)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSaldoBancoRequerido
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.SaldoLibro, err = saldoLibro(ctx, tx, cuenta.ID, req.FechaCorte); err != nil {
+		return nil, err
+	}
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(monto), 0) FROM cartola_lineas
+		WHERE cuenta_id = $1 AND fecha <= $2 AND movimiento_id IS NULL`,
+		cuenta.ID, req.FechaCorte).Scan(&c.LineasPendientes, &c.MontoLineasPendientes)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(`+montoLibro+`), 0)
+		FROM movimientos_tesoreria m
+		WHERE `+movimientoPendiente+` AND m.date <= $2
+		  AND m.date >= (SELECT MIN(fecha) FROM cartola_lineas WHERE cuenta_id = $1)`,
+		cuenta.ID, req.FechaCorte).Scan(&c.MovimientosPendientes, &c.MontoMovimientosPendientes)
+	if err != nil {
+		return nil, err
+	}
+	// Bank = book + lines not booked yet - movimientos the bank has not shown
+	c.Diferencia = c.SaldoBanco - c.SaldoLibro
+	c.DiferenciaNoExplicada = c.Diferencia - c.MontoLineasPendientes + c.MontoMovimientosPendientes
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO conciliaciones_bancarias (cuenta_id, fecha_corte, saldo_banco, saldo_libro, diferencia,
+		                                      lineas_pendientes, monto_lineas_pendientes, movimientos_pendientes,
+		                                      monto_movimientos_pendientes, diferencia_no_explicada, observaciones,
+		                                      firmado_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), $12)
+		RETURNING id`,
+		c.CuentaID, c.FechaCorte, c.SaldoBanco, c.SaldoLibro, c.Diferencia, c.LineasPendientes,
+		c.MontoLineasPendientes, c.MovimientosPendientes, c.MontoMovimientosPendientes,
+		c.DiferenciaNoExplicada, c.Observaciones, userID).Scan(&c.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = registrarAuditoria(ctx, tx, "conciliacion_bancaria", c.ID, "cierre", c.Observaciones, userID, map[string]interface{}{
+		"cuenta_id":               c.CuentaID,
+		"fecha_corte":             c.FechaCorte.Format("2006-01-02"),
+		"saldo_banco":             c.SaldoBanco,
+		"saldo_libro":             c.SaldoLibro,
+		"diferencia_no_explicada": c.DiferenciaNoExplicada,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	if err = scanCierre(s.db.Pool.QueryRow(ctx, `SELECT `+cierreColumns+` WHERE cb.id = $1`, c.ID), c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *TesoreriaService) ListCierres(ctx context.Context, cuentaID string) ([]models.CierreConciliacion, error) {
+	if _, err := s.GetCuenta(ctx, cuentaID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `SELECT `+cierreColumns+`
+		WHERE cb.cuenta_id = $1
+		ORDER BY cb.fecha_corte DESC`, cuentaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cierres := []models.CierreConciliacion{}
+	for rows.Next() {
+		var c models.CierreConciliacion
+		if err := scanCierre(rows, &c); err != nil {
+			return nil, err
+		}
+		cierres = append(cierres, c)
+	}
+	return cierres, rows.Err()
+}
+
+func (s *TesoreriaService) ultimoCierre(ctx context.Context, cuentaID string) (*models.CierreConciliacion, error) {
+	var c models.CierreConciliacion
+	err := scanCierre(s.db.Pool.QueryRow(ctx, `SELECT `+cierreColumns+`
+		WHERE cb.cuenta_id = $1
+		ORDER BY cb.fecha_corte DESC LIMIT 1`, cuentaID), &c)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,474 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/money"
+)
+
+const (
+	categoriaPagos        = "gastos_comunes"
+	categoriaReversoPagos = "reverso_pagos"
+	categoriaReembolsos   = "reembolso_creditos"
+)
+
+var ErrInvalidRangoFechas = errors.New("desde must not be after hasta")
+
+// ============================================
+// CONTABILIZACION DE PAGOS
+// ============================================
+
+// contabilizarPago posts an approved pago to the principal cuenta, either
+// right away or as a pending entry for the daily posting. The amount includes
+// the overpayment that went to credit: it is cash that came in. Credit
+// applications are skipped, their money was posted when it was paid.
+func contabilizarPago(ctx context.Context, tx pgx.Tx, pagoID string) error {
+	cfg, err := getConfigTesoreria(ctx, tx)
+	if err != nil || cfg.ContabilizacionPagos == models.ContabilizacionDesactivada {
+		return err
+	}
+
+	var metodo, parcela string
+	var monto money.Amount
+	err = tx.QueryRow(ctx, `
+		SELECT pa.metodo, p.numero,
+		       pa.monto + COALESCE((SELECT SUM(cr.monto) FROM creditos_parcela cr
+		                            WHERE cr.pago_id = pa.id AND cr.tipo = 'sobrepago'), 0)
+		FROM pagos pa
+		JOIN gastos_comunes g ON pa.gasto_comun_id = g.id
+		JOIN parcelas p ON g.parcela_id = p.id
+		WHERE pa.id = $1`, pagoID).Scan(&metodo, &parcela, &monto)
+	if err != nil {
+		return err
+	}
+	if metodo == models.MetodoPagoCredito || monto <= 0 {
+		return nil
+	}
+
+	fecha := fechaHoy()
+	var movimientoID *string
+	if cfg.ContabilizacionPagos == models.ContabilizacionIndividual {
+		id, err := insertarMovimientoPagos(ctx, tx, models.MovimientoIngreso, categoriaPagos, monto, fecha,
+			fmt.Sprintf("Pago gasto común parcela %s", parcela), &pagoID, "")
+		if err != nil {
+			return err
+		}
+		movimientoID = &id
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO pagos_tesoreria (pago_id, tipo, monto, fecha, movimiento_id)
+		VALUES ($1, 'ingreso', $2, $3, $4)
+		ON CONFLICT (pago_id, tipo) DO NOTHING`,
+		pagoID, monto, fecha, movimientoID)
+	return err
+}
+
+// contabilizarReversoPago posts the egreso matching the ingreso of a reversed
+// pago. A pago still waiting for the daily posting is simply dropped from it.
+func contabilizarReversoPago(ctx context.Context, tx pgx.Tx, pagoID, motivo, userID string) error {
+	var ingresoID string
+	var movimientoID *string
+	var monto money.Amount
+	err := tx.QueryRow(ctx, `
+		SELECT id, movimiento_id, monto FROM pagos_tesoreria
+		WHERE pago_id = $1 AND tipo = 'ingreso'
+		FOR UPDATE`, pagoID).Scan(&ingresoID, &movimientoID, &monto)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if movimientoID == nil {
+		_, err = tx.Exec(ctx, `DELETE FROM pagos_tesoreria WHERE id = $1`, ingresoID)
+		return err
+	}
+
+	cfg, err := getConfigTesoreria(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	fecha := fechaHoy()
+	var reversoID *string
+	if cfg.ContabilizacionPagos != models.ContabilizacionDiaria {
+		var parcela string
+		err = tx.QueryRow(ctx, `
+			SELECT p.numero FROM pagos pa
+			JOIN gastos_comunes g ON pa.gasto_comun_id = g.id
+			JOIN parcelas p ON g.parcela_id = p.id
+			WHERE pa.id = $1`, pagoID).Scan(&parcela)
+		if err != nil {
+			return err
+		}
+		id, err := insertarMovimientoPagos(ctx, tx, models.MovimientoEgreso, categoriaReversoPagos, monto, fecha,
+			truncar(fmt.Sprintf("Reverso pago gasto común parcela %s: %s", parcela, motivo), 255), &pagoID, userID)
+		if err != nil {
+			return err
+		}
+		reversoID = &id
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO pagos_tesoreria (pago_id, tipo, monto, fecha, movimiento_id)
+		VALUES ($1, 'reverso', $2, $3, $4)
+		ON CONFLICT (pago_id, tipo) DO NOTHING`,
+		pagoID, monto, fecha, reversoID)
+	return err
+}
+
+// insertarMovimientoPagos books an ingreso (pagos) or egreso (reversals and
+// refunds) in the principal cuenta and returns its id.
+func insertarMovimientoPagos(ctx context.Context, tx pgx.Tx, tipo models.MovimientoType, categoria string, monto money.Amount, fecha time.Time, descripcion string, pagoID *string, userID string) (string, error) {
+	cuentaID, err := cuentaMovimiento(ctx, tx, "")
+	if err != nil {
+		return "", err
+	}
+
+	comprobante, err := siguienteCorrelativo(ctx, tx, correlativoComprobante)
+	if err != nil {
+		return "", err
+	}
+
+	var id string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO movimientos_tesoreria (comprobante, cuenta_id, description, amount, type, category, date,
+		                                   pago_id, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, '')::uuid)
+		RETURNING id`,
+		comprobante, cuentaID, descripcion, monto, tipo, categoria, fecha, pagoID, userID).Scan(&id)
+	if err != nil {
+		return "", err
+	}
+
+	if err = revisarAlertaPresupuesto(ctx, tx, fecha.Year(), categoria); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// contabilizarReembolso posts the egreso of a credit refund and links it to
+// the credit entry. Refunds are few and are posted right away, also with the
+// daily posting.
+func contabilizarReembolso(ctx context.Context, tx pgx.Tx, creditoID string, monto money.Amount, metodo, userID string) error {
+	cfg, err := getConfigTesoreria(ctx, tx)
+	if err != nil || cfg.ContabilizacionPagos == models.ContabilizacionDesactivada {
+		return err
+	}
+
+	movimientoID, err := insertarMovimientoPagos(ctx, tx, models.MovimientoEgreso, categoriaReembolsos, monto, fechaHoy(),
+		fmt.Sprintf("Reembolso de saldo a favor de gasto común (%s)", metodo), nil, userID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `UPDATE creditos_parcela SET movimiento_id = $2 WHERE id = $1`, creditoID, movimientoID)
+	return err
+}
+
+// liberarPagosContabilizados puts the pagos posted by a voided movimiento
+// back in the daily posting, so they are posted again with their amounts.
+func liberarPagosContabilizados(ctx context.Context, tx pgx.Tx, movimientoID string) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE pagos_tesoreria SET movimiento_id = NULL WHERE movimiento_id = $1`, movimientoID)
+	return err
+}
+
+// ContabilizarPagosPendientes posts the pagos and reversals waiting for the
+// daily posting up to hasta: one movimiento per day and kind.
+func (s *TesoreriaService) ContabilizarPagosPendientes(ctx context.Context, hasta time.Time, userID string) (*models.ContabilizarPagosResult, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the pending rows so a concurrent run does not post them twice
+	_, err = tx.Exec(ctx, `
+		SELECT id FROM pagos_tesoreria WHERE movimiento_id IS NULL AND fecha <= $1 FOR UPDATE`, hasta)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT fecha, tipo, SUM(monto), COUNT(*) FROM pagos_tesoreria
+		WHERE movimiento_id IS NULL AND fecha <= $1
+		GROUP BY fecha, tipo
+		ORDER BY fecha, tipo`, hasta)
+	if err != nil {
+		return nil, err
+	}
+	type grupo struct {
+		fecha time.Time
+		tipo  string
+		monto money.Amount
+		pagos int
+	}
+	grupos := []grupo{}
+	for rows.Next() {
+		var g grupo
+		if err := rows.Scan(&g.fecha, &g.tipo, &g.monto, &g.pagos); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		grupos = append(grupos, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &models.ContabilizarPagosResult{}
+	for _, g := range grupos {
+		tipo, categoria := models.MovimientoIngreso, categoriaPagos
+		descripcion := fmt.Sprintf("Pagos de gastos comunes del %s (%d)", g.fecha.Format("02/01/2006"), g.pagos)
+		if g.tipo == "reverso" {
+			tipo, categoria = models.MovimientoEgreso, categoriaReversoPagos
+			descripcion = fmt.Sprintf("Reversos de pagos de gastos comunes del %s (%d)", g.fecha.Format("02/01/2006"), g.pagos)
+		}
+
+		movimientoID, err := insertarMovimientoPagos(ctx, tx, tipo, categoria, g.monto, g.fecha, descripcion, nil, userID)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE pagos_tesoreria SET movimiento_id = $1
+			WHERE movimiento_id IS NULL AND fecha = $2 AND tipo = $3`,
+			movimientoID, g.fecha, g.tipo)
+		if err != nil {
+			return nil, err
+		}
+
+		err = registrarAuditoria(ctx, tx, "movimiento_tesoreria", movimientoID, "contabilizar_pagos", "", userID,
+			map[string]interface{}{"fecha": g.fecha.Format("2006-01-02"), "tipo": g.tipo, "pagos": g.pagos, "monto": g.monto})
+		if err != nil {
+			return nil, err
+		}
+
+		result.Movimientos++
+		result.Pagos += g.pagos
+		if tipo == models.MovimientoIngreso {
+			result.Monto += g.monto
+		} else {
+			result.Monto -= g.monto
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ============================================
+// CONCILIACION PAGOS / TESORERIA
+// ============================================
+
+// GetConciliacionPagos lists the pagos and credit refunds of [desde, hasta]
+// that are not correctly reflected in treasury, and the gasto común ingresos
+// of the range that no pago backs.
+func (s *TesoreriaService) GetConciliacionPagos(ctx context.Context, desde, hasta time.Time) (*models.ConciliacionPagos, error) {
+	if desde.After(hasta) {
+		return nil, ErrInvalidRangoFechas
+	}
+	c := &models.ConciliacionPagos{Desde: desde, Hasta: hasta, Items: []models.ConciliacionItem{}}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		WITH pg AS (
+			SELECT pa.id, pa.estado, p.numero, pa.created_at::date AS fecha,
+			       pa.monto + COALESCE((SELECT SUM(cr.monto) FROM creditos_parcela cr
+			                            WHERE cr.pago_id = pa.id AND cr.tipo = 'sobrepago'), 0) AS monto
+			FROM pagos pa
+			JOIN gastos_comunes g ON pa.gasto_comun_id = g.id
+			JOIN parcelas p ON g.parcela_id = p.id
+			WHERE pa.metodo <> 'credito' AND pa.estado IN ('approved', 'reversed')
+			  AND pa.created_at::date BETWEEN $1 AND $2
+		)
+		SELECT pg.id, pg.estado, pg.numero, pg.fecha, pg.monto,
+		       i.id IS NOT NULL, i.monto, i.movimiento_id, mi.comprobante, mi.anulado_at IS NOT NULL,
+		       mi.pago_id IS NOT NULL AND mi.amount <> i.monto,
+		       r.id IS NOT NULL, r.movimiento_id
+		FROM pg
+		LEFT JOIN pagos_tesoreria i ON i.pago_id = pg.id AND i.tipo = 'ingreso'
+		LEFT JOIN movimientos_tesoreria mi ON mi.id = i.movimiento_id
+		LEFT JOIN pagos_tesoreria r ON r.pago_id = pg.id AND r.tipo = 'reverso'
+		ORDER BY pg.fecha, pg.numero`, desde, hasta)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pagoID, estado, parcela string
+		var fecha time.Time
+		var monto money.Amount
+		var tieneIngreso, anulado, editado, tieneReverso bool
+		var montoIngreso *money.Amount
+		var movimientoID, reversoID *string
+		var comprobante *int64
+		err := rows.Scan(&pagoID, &estado, &parcela, &fecha, &monto,
+			&tieneIngreso, &montoIngreso, &movimientoID, &comprobante, &anulado, &editado,
+			&tieneReverso, &reversoID)
+		if err != nil {
+			return nil, err
+		}
+
+		item := models.ConciliacionItem{
+			PagoID:        &pagoID,
+			ParcelaNumero: parcela,
+			MovimientoID:  movimientoID,
+			Comprobante:   comprobante,
+			Fecha:         fecha,
+			Monto:         monto,
+		}
+		if montoIngreso != nil {
+			item.MontoContabilizado = *montoIngreso
+		}
+
+		switch {
+		case !tieneIngreso:
+			// A pago reversed before being posted nets to zero
+			if estado != models.PagoEstadoApproved {
+				continue
+			}
+			item.Tipo = models.ConciliacionPagoSinAsiento
+			item.Detalle = "Pago aprobado sin ingreso en tesorería"
+		case movimientoID == nil:
+			item.Tipo = models.ConciliacionPagoPendiente
+			item.Detalle = "Pago pendiente de la contabilización diaria"
+		case anulado:
+			item.Tipo = models.ConciliacionAsientoAnulado
+			item.Detalle = "El ingreso del pago fue anulado"
+		case *montoIngreso != monto || editado:
+			item.Tipo = models.ConciliacionMontoDistinto
+			item.Detalle = "El monto contabilizado no coincide con el pago"
+		case estado == models.PagoEstadoReversed && !tieneReverso:
+			item.Tipo = models.ConciliacionReversoSinAsiento
+			item.Detalle = "Pago reversado sin egreso en tesorería"
+		case estado == models.PagoEstadoReversed && reversoID == nil:
+			item.Tipo = models.ConciliacionPagoPendiente
+			item.Detalle = "Reverso pendiente de la contabilización diaria"
+		default:
+			continue
+		}
+		c.Items = append(c.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// Daily movimientos whose amount no longer matches the pagos they post,
+	// and gasto común ingresos entered by hand
+	rows, err = s.db.Pool.Query(ctx, `
+		SELECT m.id, m.comprobante, m.date, m.amount,
+		       COALESCE((SELECT SUM(pt.monto) FROM pagos_tesoreria pt WHERE pt.movimiento_id = m.id), 0),
+		       EXISTS(SELECT 1 FROM pagos_tesoreria pt WHERE pt.movimiento_id = m.id)
+		FROM movimientos_tesoreria m
+		WHERE m.type = 'ingreso' AND m.category = $3
+		  AND m.pago_id IS NULL AND m.transferencia_id IS NULL
+		  AND m.anulado_at IS NULL AND m.reverso_de IS NULL
+		  AND m.date BETWEEN $1 AND $2
+		ORDER BY m.date, m.comprobante`, desde, hasta, categoriaPagos)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var comprobante int64
+		var fecha time.Time
+		var monto, montoPagos money.Amount
+		var conPagos bool
+		if err := rows.Scan(&id, &comprobante, &fecha, &monto, &montoPagos, &conPagos); err != nil {
+			return nil, err
+		}
+
+		item := models.ConciliacionItem{
+			MovimientoID:       &id,
+			Comprobante:        &comprobante,
+			Fecha:              fecha,
+			Monto:              montoPagos,
+			MontoContabilizado: monto,
+		}
+		switch {
+		case !conPagos:
+			item.Tipo = models.ConciliacionAsientoSinPago
+			item.Detalle = "Ingreso de gastos comunes sin pago asociado"
+		case monto != montoPagos:
+			item.Tipo = models.ConciliacionMontoDistinto
+			item.Detalle = "El monto del ingreso diario no coincide con sus pagos"
+		default:
+			continue
+		}
+		c.Items = append(c.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Credit refunds paid out without a standing egreso
+	rows, err = s.db.Pool.Query(ctx, `
+		SELECT p.numero, cr.created_at::date, -cr.monto, cr.movimiento_id, m.comprobante, m.anulado_at IS NOT NULL
+		FROM creditos_parcela cr
+		JOIN parcelas p ON p.id = cr.parcela_id
+		LEFT JOIN movimientos_tesoreria m ON m.id = cr.movimiento_id
+		WHERE cr.tipo = 'reembolso' AND cr.created_at::date BETWEEN $1 AND $2
+		  AND (m.id IS NULL OR m.anulado_at IS NOT NULL)
+		ORDER BY cr.created_at`, desde, hasta)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item := models.ConciliacionItem{Tipo: models.ConciliacionReembolsoSinAsiento}
+		var anulado bool
+		err := rows.Scan(&item.ParcelaNumero, &item.Fecha, &item.Monto, &item.MovimientoID, &item.Comprobante, &anulado)
+		if err != nil {
+			return nil, err
+		}
+		item.Detalle = "Reembolso de saldo a favor sin egreso en tesorería"
+		if anulado {
+			item.Detalle = "El egreso del reembolso fue anulado"
+		}
+		c.Items = append(c.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Totals: cash received net of reversals in the range, against gasto
+	// común ingresos net of reversal egresos booked in the range
+	err = s.db.Pool.QueryRow(ctx, `
+		SELECT
+			COALESCE((SELECT SUM(pa.monto + COALESCE((SELECT SUM(cr.monto) FROM creditos_parcela cr
+			                                          WHERE cr.pago_id = pa.id AND cr.tipo = 'sobrepago'), 0))
+			          FROM pagos pa
+			          WHERE pa.metodo <> 'credito' AND pa.estado IN ('approved', 'reversed')
+			            AND pa.created_at::date BETWEEN $1 AND $2), 0)
+			- COALESCE((SELECT SUM(pa.monto + COALESCE((SELECT SUM(cr.monto) FROM creditos_parcela cr
+			                                            WHERE cr.pago_id = pa.id AND cr.tipo = 'sobrepago'), 0))
+			            FROM pagos pa
+			            WHERE pa.metodo <> 'credito' AND pa.estado = 'reversed'
+			              AND pa.reversed_at::date BETWEEN $1 AND $2), 0),
+			COALESCE((SELECT SUM(CASE WHEN m.type = 'ingreso' THEN m.amount ELSE -m.amount END)
+			          FROM movimientos_tesoreria m
+			          WHERE ((m.type = 'ingreso' AND m.category = $3) OR (m.type = 'egreso' AND m.category = $4))
+			            AND m.transferencia_id IS NULL AND m.anulado_at IS NULL AND m.reverso_de IS NULL
+			            AND m.date BETWEEN $1 AND $2), 0)`,
+		desde, hasta, categoriaPagos, categoriaReversoPagos).Scan(&c.TotalPagos, &c.TotalContabilizado)
+	if err != nil {
+		return nil, err
+	}
+	c.Diferencia = c.TotalPagos - c.TotalContabilizado
+
+	return c, nil
+}
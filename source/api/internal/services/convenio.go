@@ -0,0 +1,771 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/condominio/backend/internal/database"
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/money"
+)
+
+var (
+	ErrConvenioNotFound      = errors.New("convenio not found")
+	ErrConvenioNoActivo      = errors.New("convenio is not active")
+	ErrConvenioGastoInvalido = errors.New("gastos must be overdue and belong to the parcela")
+	ErrConvenioSinDeuda      = errors.New("selected gastos have no pending balance")
+	ErrInteresesCongelados   = errors.New("interest is frozen by an active convenio")
+)
+
+type ConvenioService struct {
+	db *database.DB
+}
+
+func NewConvenioService(db *database.DB) *ConvenioService {
+	return &ConvenioService{db: db}
+}
+
+// ============================================
+// CONVENIOS DE PAGO
+// ============================================
+
+// CreateConvenio groups overdue gastos of a parcela into a payment plan. The
+// gastos move to status convenio, the pending balance is split into monthly
+// cuotas (the last one absorbs rounding) and, when requested, interest already
+// charged on those gastos is frozen with an offsetting adjustment that is
+// reverted if the plan is broken.
+func (s *ConvenioService) CreateConvenio(ctx context.Context, req *models.CreateConvenioRequest, createdBy string) (*models.ConvenioPago, error) {
+	primeraCuota, err := time.Parse("2006-01-02", req.FechaPrimeraCuota)
+	if err != nil {
+		return nil, ErrInvalidFecha
+	}
+	if req.MaxCuotasImpagas <= 0 {
+		req.MaxCuotasImpagas = 2
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, parcela_id, status, monto, monto_pagado
+		FROM gastos_comunes
+		WHERE id = ANY($1::uuid[])
+		ORDER BY id
+		FOR UPDATE`, req.GastoIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	type gastoConvenio struct {
+		id          string
+		pendiente   money.Amount
+		montoPagado money.Amount
+	}
+	gastos := []gastoConvenio{}
+	var total money.Amount
+	for rows.Next() {
+		var g gastoConvenio
+		var parcelaID int
+		var status models.PagoStatus
+		var monto money.Amount
+		if err := rows.Scan(&g.id, &parcelaID, &status, &monto, &g.montoPagado); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if parcelaID != req.ParcelaID || status != models.PagoStatusOverdue {
+			rows.Close()
+			return nil, ErrConvenioGastoInvalido
+		}
+		g.pendiente = monto - g.montoPagado
+		total += g.pendiente
+		gastos = append(gastos, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(gastos) != len(req.GastoIDs) {
+		return nil, ErrGastoComunNotFound
+	}
+	if total <= 0 {
+		return nil, ErrConvenioSinDeuda
+	}
+
+	var convenioID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO convenios_pago (parcela_id, monto_total, num_cuotas, max_cuotas_impagas, congelar_intereses, observaciones, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`,
+		req.ParcelaID, total, req.NumCuotas, req.MaxCuotasImpagas, req.CongelarIntereses, req.Observaciones, createdBy).Scan(&convenioID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, g := range gastos {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO convenio_gastos (convenio_id, gasto_comun_id, monto_pendiente, monto_pagado_inicial)
+			VALUES ($1, $2, $3, $4)`,
+			convenioID, g.id, g.pendiente, g.montoPagado)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE gastos_comunes SET status = 'convenio', updated_at = NOW()
+		WHERE id = ANY($1::uuid[])`, req.GastoIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cuotas in whole pesos; the last one takes the remainder, centavos
+	// included
+	base := money.FromPesos(total.Pesos() / int64(req.NumCuotas))
+	for i := 0; i < req.NumCuotas; i++ {
+		monto := base
+		if i == req.NumCuotas-1 {
+			monto = total - base.Mul(req.NumCuotas-1)
+		}
+		fecha := addMonths(primeraCuota, i)
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO convenio_cuotas (convenio_id, numero, monto, fecha_vencimiento, periodo_id)
+			VALUES ($1, $2, $3, $4,
+			        (SELECT id FROM periodos_gasto WHERE year = $5 AND month = $6))`,
+			convenioID, i+1, monto, fecha, fecha.Year(), int(fecha.Month()))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.CongelarIntereses {
+		var intereses money.Amount
+		err = tx.QueryRow(ctx, `
+			SELECT COALESCE(SUM(monto), 0) FROM cargos_parcela
+			WHERE gasto_comun_id = ANY($1::uuid[]) AND tipo = 'interes'`, req.GastoIDs).Scan(&intereses)
+		if err != nil {
+			return nil, err
+		}
+		if intereses > 0 {
+			_, err = tx.Exec(ctx, `
+				INSERT INTO cargos_parcela (parcela_id, tipo, monto, fecha, descripcion, created_by)
+				VALUES ($1, 'ajuste', $2, CURRENT_DATE, 'Congelamiento de intereses por convenio de pago', $3)`,
+				req.ParcelaID, -intereses, createdBy)
+			if err != nil {
+				return nil, err
+			}
+			_, err = tx.Exec(ctx, `UPDATE convenios_pago SET intereses_congelados = $1 WHERE id = $2`,
+				intereses, convenioID)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err = registrarAuditoria(ctx, tx, "convenio", convenioID, "crear", req.Observaciones, createdBy, map[string]interface{}{
+		"parcela_id": req.ParcelaID,
+		"gasto_ids":  req.GastoIDs,
+		"monto":      total,
+		"num_cuotas": req.NumCuotas,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.GetConvenio(ctx, convenioID)
+}
+
+// GetConvenio returns a convenio with its gastos and cuotas. Cuota states are
+// derived from what has been paid on the gastos since the plan was agreed, so
+// they are accurate even for payments registered directly on a gasto.
+func (s *ConvenioService) GetConvenio(ctx context.Context, id string) (*models.ConvenioPago, error) {
+	var c models.ConvenioPago
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT c.id, c.parcela_id, p.numero, c.estado, c.monto_total, c.num_cuotas,
+		       c.max_cuotas_impagas, c.congelar_intereses, c.intereses_congelados,
+		       COALESCE(c.observaciones, ''), COALESCE(c.motivo_termino, ''), c.terminado_at,
+		       c.created_by, c.created_at, c.updated_at
+		FROM convenios_pago c
+		JOIN parcelas p ON c.parcela_id = p.id
+		WHERE c.id = $1`, id).Scan(
+		&c.ID, &c.ParcelaID, &c.ParcelaNumero, &c.Estado, &c.MontoTotal, &c.NumCuotas,
+		&c.MaxCuotasImpagas, &c.CongelarIntereses, &c.InteresesCongelados,
+		&c.Observaciones, &c.MotivoTermino, &c.TerminadoAt,
+		&c.CreatedBy, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, ErrConvenioNotFound
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT cg.gasto_comun_id, pg.year, pg.month, cg.monto_pendiente, cg.monto_pagado_inicial, g.status
+		FROM convenio_gastos cg
+		JOIN gastos_comunes g ON cg.gasto_comun_id = g.id
+		JOIN periodos_gasto pg ON g.periodo_id = pg.id
+		WHERE cg.convenio_id = $1
+		ORDER BY pg.year, pg.month`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	c.Gastos = []models.ConvenioGasto{}
+	for rows.Next() {
+		var g models.ConvenioGasto
+		if err := rows.Scan(&g.GastoComunID, &g.Year, &g.Month, &g.MontoPendiente, &g.MontoPagadoInicial, &g.Status); err != nil {
+			return nil, err
+		}
+		c.Gastos = append(c.Gastos, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	cuotas, err := loadCuotas(ctx, s.db.Pool, id)
+	if err != nil {
+		return nil, err
+	}
+	pagado, _, err := pagadoConvenio(ctx, s.db.Pool, id)
+	if err != nil {
+		return nil, err
+	}
+
+	c.MontoPagado = pagado
+	c.Cuotas = cuotas
+	if c.Estado == models.ConvenioActivo {
+		c.CuotasPagadas, c.CuotasVencidas = evaluarCuotas(c.Cuotas, pagado, time.Now())
+	} else {
+		for _, q := range c.Cuotas {
+			switch q.Estado {
+			case models.CuotaPagada:
+				c.CuotasPagadas++
+			case models.CuotaVencida:
+				c.CuotasVencidas++
+			}
+		}
+	}
+
+	return &c, nil
+}
+
+func (s *ConvenioService) ListConvenios(ctx context.Context, filter models.ConvenioFilter) (*models.ConvenioListResponse, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 || filter.PerPage > 100 {
+		filter.PerPage = 20
+	}
+	offset := (filter.Page - 1) * filter.PerPage
+
+	where := ` WHERE 1=1`
+	args := []interface{}{}
+	argCount := 0
+
+	if filter.ParcelaID > 0 {
+		argCount++
+		where += ` AND c.parcela_id = $` + strconv.Itoa(argCount)
+		args = append(args, filter.ParcelaID)
+	}
+	if filter.Estado != "" {
+		argCount++
+		where += ` AND c.estado = $` + strconv.Itoa(argCount)
+		args = append(args, filter.Estado)
+	}
+
+	var total int
+	err := s.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM convenios_pago c`+where, args...).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT c.id, c.parcela_id, p.numero, c.estado, c.monto_total, c.num_cuotas,
+		       c.max_cuotas_impagas, c.congelar_intereses, c.intereses_congelados,
+		       COALESCE(c.observaciones, ''), COALESCE(c.motivo_termino, ''), c.terminado_at,
+		       c.created_by, c.created_at, c.updated_at,
+		       COALESCE((SELECT SUM(g.monto_pagado - cg.monto_pagado_inicial)
+		                 FROM convenio_gastos cg JOIN gastos_comunes g ON cg.gasto_comun_id = g.id
+		                 WHERE cg.convenio_id = c.id), 0)
+		FROM convenios_pago c
+		JOIN parcelas p ON c.parcela_id = p.id` + where +
+		fmt.Sprintf(` ORDER BY c.created_at DESC LIMIT $%d OFFSET $%d`, argCount+1, argCount+2)
+	args = append(args, filter.PerPage, offset)
+
+	rows, err := s.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	convenios := []models.ConvenioPago{}
+	for rows.Next() {
+		var c models.ConvenioPago
+		err := rows.Scan(
+			&c.ID, &c.ParcelaID, &c.ParcelaNumero, &c.Estado, &c.MontoTotal, &c.NumCuotas,
+			&c.MaxCuotasImpagas, &c.CongelarIntereses, &c.InteresesCongelados,
+			&c.Observaciones, &c.MotivoTermino, &c.TerminadoAt,
+			&c.CreatedBy, &c.CreatedAt, &c.UpdatedAt,
+			&c.MontoPagado)
+		if err != nil {
+			return nil, err
+		}
+		convenios = append(convenios, c)
+	}
+
+	return &models.ConvenioListResponse{
+		Convenios: convenios,
+		Total:     total,
+		Page:      filter.Page,
+		PerPage:   filter.PerPage,
+	}, rows.Err()
+}
+
+// GetMisConvenios returns every convenio of the user's parcela.
+func (s *ConvenioService) GetMisConvenios(ctx context.Context, userID string) ([]models.ConvenioPago, error) {
+	var parcelaID *int
+	err := s.db.Pool.QueryRow(ctx, `SELECT parcela_id FROM users WHERE id = $1`, userID).Scan(&parcelaID)
+	if err != nil {
+		return nil, err
+	}
+	if parcelaID == nil {
+		return nil, ErrUserNoParcela
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT id FROM convenios_pago WHERE parcela_id = $1 ORDER BY created_at DESC`, *parcelaID)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	convenios := []models.ConvenioPago{}
+	for _, id := range ids {
+		c, err := s.GetConvenio(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		convenios = append(convenios, *c)
+	}
+	return convenios, nil
+}
+
+// PagarConvenio registers a cuota payment. The amount is applied to the
+// convenio's gastos oldest first; anything above the remaining debt becomes
+// credit in favour of the parcela, as with RegistrarPago.
+func (s *ConvenioService) PagarConvenio(ctx context.Context, id string, req *models.RegistrarPagoRequest) (*models.ConvenioPago, error) {
+	if req.Monto <= 0 {
+		return nil, ErrInvalidPaymentAmount
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock order: convenio, gastos, parcela (credit)
+	var estado models.ConvenioEstado
+	var parcelaID int
+	err = tx.QueryRow(ctx, `SELECT estado, parcela_id FROM convenios_pago WHERE id = $1 FOR UPDATE`, id).Scan(&estado, &parcelaID)
+	if err != nil {
+		return nil, ErrConvenioNotFound
+	}
+	if estado != models.ConvenioActivo {
+		return nil, ErrConvenioNoActivo
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT g.id, g.monto
+		FROM convenio_gastos cg
+		JOIN gastos_comunes g ON cg.gasto_comun_id = g.id
+		JOIN periodos_gasto pg ON g.periodo_id = pg.id
+		WHERE cg.convenio_id = $1
+		ORDER BY pg.year, pg.month
+		FOR UPDATE OF g`, id)
+	if err != nil {
+		return nil, err
+	}
+	type gastoMonto struct {
+		id    string
+		monto money.Amount
+	}
+	gastos := []gastoMonto{}
+	for rows.Next() {
+		var g gastoMonto
+		if err := rows.Scan(&g.id, &g.monto); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		gastos = append(gastos, g)
+	}
+	rows.Close()
+
+	restante := req.Monto
+	pagoIDs := []string{}
+	var ultimoPagoID, ultimoGastoID string
+	for _, g := range gastos {
+		if restante <= 0 {
+			break
+		}
+		montoPagado, err := sumPagosAprobados(ctx, tx, g.id)
+		if err != nil {
+			return nil, err
+		}
+		aplicar := min(restante, g.monto-montoPagado)
+		if aplicar <= 0 {
+			continue
+		}
+
+		err = tx.QueryRow(ctx, `
+			INSERT INTO pagos (gasto_comun_id, monto, metodo, referencia_externa, estado)
+			VALUES ($1, $2, $3, $4, 'approved')
+			RETURNING id`,
+			g.id, aplicar, req.Metodo, req.ReferenciaExterna).Scan(&ultimoPagoID)
+		if err != nil {
+			return nil, err
+		}
+		if err = asignarFondoReserva(ctx, tx, ultimoPagoID, aplicar); err != nil {
+			return nil, err
+		}
+		pagoIDs = append(pagoIDs, ultimoPagoID)
+		ultimoGastoID = g.id
+		restante -= aplicar
+
+		if err := recalcularGasto(ctx, tx, g.id, req.Metodo, req.ReferenciaExterna); err != nil {
+			return nil, err
+		}
+	}
+
+	if ultimoPagoID == "" {
+		return nil, ErrGastoAlreadyPaid
+	}
+
+	if restante > 0 {
+		if err := lockCreditoParcela(ctx, tx, parcelaID); err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO creditos_parcela (parcela_id, monto, tipo, pago_id, gasto_comun_id, descripcion)
+			VALUES ($1, $2, 'sobrepago', $3, $4, 'Excedente de pago de convenio')`,
+			parcelaID, restante, ultimoPagoID, ultimoGastoID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Receipts and treasury entries go after the credit row so the last one
+	// includes the excess
+	for _, pagoID := range pagoIDs {
+		if err := emitirRecibo(ctx, tx, pagoID); err != nil {
+			return nil, err
+		}
+		if err := contabilizarPago(ctx, tx, pagoID); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := actualizarConvenio(ctx, tx, id); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.GetConvenio(ctx, id)
+}
+
+// AnularConvenio cancels an active convenio (e.g. created by mistake). The
+// gastos and any frozen interest are restored as if the plan never existed.
+func (s *ConvenioService) AnularConvenio(ctx context.Context, id string, motivo string, userID string) (*models.ConvenioPago, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var estado models.ConvenioEstado
+	err = tx.QueryRow(ctx, `SELECT estado FROM convenios_pago WHERE id = $1 FOR UPDATE`, id).Scan(&estado)
+	if err != nil {
+		return nil, ErrConvenioNotFound
+	}
+	if estado != models.ConvenioActivo {
+		return nil, ErrConvenioNoActivo
+	}
+
+	if err := terminarConvenio(ctx, tx, id, models.ConvenioAnulado, motivo, userID); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.GetConvenio(ctx, id)
+}
+
+// ProcesarConvenios refreshes cuota states of every active convenio, closing
+// the fulfilled ones and breaking those with too many missed cuotas. The
+// convenios-cumplimiento job runs it daily.
+func (s *ConvenioService) ProcesarConvenios(ctx context.Context) (*models.ProcesarConveniosResult, error) {
+	rows, err := s.db.Pool.Query(ctx, `SELECT id FROM convenios_pago WHERE estado = 'activo'`)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	result := &models.ProcesarConveniosResult{}
+	for _, id := range ids {
+		tx, err := s.db.Pool.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := actualizarConvenio(ctx, tx, id)
+		if err == nil {
+			err = tx.Commit(ctx)
+		}
+		tx.Rollback(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		result.CuotasVencidas += r.CuotasVencidas
+		result.ConveniosRotos += r.ConveniosRotos
+		result.ConveniosCumplidos += r.ConveniosCumplidos
+	}
+	return result, nil
+}
+
+// actualizarConvenio persists cuota states of an active convenio and closes it
+// when fulfilled or broken. It takes the convenio row lock.
+func actualizarConvenio(ctx context.Context, tx pgx.Tx, id string) (*models.ProcesarConveniosResult, error) {
+	result := &models.ProcesarConveniosResult{}
+
+	var estado models.ConvenioEstado
+	var maxImpagas int
+	err := tx.QueryRow(ctx, `
+		SELECT estado, max_cuotas_impagas FROM convenios_pago WHERE id = $1 FOR UPDATE`, id).Scan(&estado, &maxImpagas)
+	if err != nil {
+		return nil, ErrConvenioNotFound
+	}
+	if estado != models.ConvenioActivo {
+		return result, nil
+	}
+
+	cuotas, err := loadCuotas(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	anteriores := make(map[string]models.CuotaEstado, len(cuotas))
+	for _, q := range cuotas {
+		anteriores[q.ID] = q.Estado
+	}
+
+	pagado, gastosImpagos, err := pagadoConvenio(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	_, vencidas := evaluarCuotas(cuotas, pagado, time.Now())
+
+	// Every gasto paid: the plan is fulfilled regardless of rounding in cuotas
+	if gastosImpagos == 0 {
+		for i := range cuotas {
+			cuotas[i].Estado = models.CuotaPagada
+		}
+	}
+
+	for _, q := range cuotas {
+		if anteriores[q.ID] == q.Estado {
+			continue
+		}
+		if q.Estado == models.CuotaVencida {
+			result.CuotasVencidas++
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE convenio_cuotas
+			SET estado = $1, pagada_at = CASE WHEN $1 = 'pagada' THEN NOW() ELSE NULL END
+			WHERE id = $2`, q.Estado, q.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case gastosImpagos == 0:
+		_, err = tx.Exec(ctx, `
+			UPDATE convenios_pago SET estado = 'cumplido', terminado_at = NOW(), updated_at = NOW()
+			WHERE id = $1`, id)
+		if err != nil {
+			return nil, err
+		}
+		result.ConveniosCumplidos++
+	case vencidas >= maxImpagas:
+		motivo := fmt.Sprintf("%d cuotas impagas", vencidas)
+		if err := terminarConvenio(ctx, tx, id, models.ConvenioRoto, motivo, ""); err != nil {
+			return nil, err
+		}
+		result.ConveniosRotos++
+	}
+
+	return result, nil
+}
+
+// terminarConvenio breaks or cancels a convenio: gastos still under the plan
+// return to pending/overdue with their original debt and frozen interest is
+// charged again.
+func terminarConvenio(ctx context.Context, tx pgx.Tx, id string, estado models.ConvenioEstado, motivo, userID string) error {
+	var parcelaID int
+	var intereses money.Amount
+	err := tx.QueryRow(ctx, `
+		SELECT parcela_id, intereses_congelados FROM convenios_pago WHERE id = $1`, id).Scan(&parcelaID, &intereses)
+	if err != nil {
+		return ErrConvenioNotFound
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE gastos_comunes g
+		SET status = CASE WHEN p.fecha_vencimiento < CURRENT_DATE THEN 'overdue' ELSE 'pending' END,
+		    updated_at = NOW()
+		FROM convenio_gastos cg, periodos_gasto p
+		WHERE cg.convenio_id = $1 AND cg.gasto_comun_id = g.id
+		  AND g.periodo_id = p.id AND g.status = 'convenio'`, id)
+	if err != nil {
+		return err
+	}
+
+	if intereses > 0 {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO cargos_parcela (parcela_id, tipo, monto, fecha, descripcion, created_by)
+			VALUES ($1, 'interes', $2, CURRENT_DATE, 'Restitucion de intereses por termino de convenio', NULLIF($3, '')::uuid)`,
+			parcelaID, intereses, userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE convenios_pago
+		SET estado = $1, motivo_termino = $2, terminado_at = NOW(), updated_at = NOW()
+		WHERE id = $3`, estado, motivo, id)
+	if err != nil {
+		return err
+	}
+
+	return registrarAuditoria(ctx, tx, "convenio", id, string(estado), motivo, userID, map[string]interface{}{
+		"parcela_id":            parcelaID,
+		"intereses_restituidos": intereses,
+	})
+}
+
+// vincularCuotasPeriodo links the cuotas falling due in a newly created
+// periodo, so they are billed together with that month's gastos.
+func vincularCuotasPeriodo(ctx context.Context, tx pgx.Tx, periodoID string, year, month int) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE convenio_cuotas SET periodo_id = $1
+		WHERE periodo_id IS NULL
+		  AND EXTRACT(YEAR FROM fecha_vencimiento) = $2
+		  AND EXTRACT(MONTH FROM fecha_vencimiento) = $3`,
+		periodoID, year, month)
+	return err
+}
+
+func loadCuotas(ctx context.Context, q querier, convenioID string) ([]models.ConvenioCuota, error) {
+	rows, err := q.Query(ctx, `
+		SELECT id, numero, monto, fecha_vencimiento, periodo_id, estado, pagada_at
+		FROM convenio_cuotas
+		WHERE convenio_id = $1
+		ORDER BY numero`, convenioID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cuotas := []models.ConvenioCuota{}
+	for rows.Next() {
+		var c models.ConvenioCuota
+		if err := rows.Scan(&c.ID, &c.Numero, &c.Monto, &c.FechaVencimiento, &c.PeriodoID, &c.Estado, &c.PagadaAt); err != nil {
+			return nil, err
+		}
+		cuotas = append(cuotas, c)
+	}
+	return cuotas, rows.Err()
+}
+
+// pagadoConvenio returns how much has been paid on the convenio's gastos since
+// it was agreed, and how many of them are not fully paid yet.
+func pagadoConvenio(ctx context.Context, q querier, convenioID string) (money.Amount, int, error) {
+	var pagado money.Amount
+	var impagos int
+	err := q.QueryRow(ctx, `
+		SELECT COALESCE(SUM(g.monto_pagado - cg.monto_pagado_inicial), 0),
+		       COUNT(*) FILTER (WHERE g.status <> 'paid')
+		FROM convenio_gastos cg
+		JOIN gastos_comunes g ON cg.gasto_comun_id = g.id
+		WHERE cg.convenio_id = $1`, convenioID).Scan(&pagado, &impagos)
+	return pagado, impagos, err
+}
+
+// evaluarCuotas assigns payments to cuotas in order: a cuota is paid once the
+// accumulated payments cover it and every previous one. Unpaid cuotas past
+// their due date are overdue. Returns paid and overdue counts.
+func evaluarCuotas(cuotas []models.ConvenioCuota, pagado money.Amount, now time.Time) (pagadas, vencidas int) {
+	hoy := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var acumulado money.Amount
+	for i := range cuotas {
+		acumulado += cuotas[i].Monto
+		switch {
+		case pagado >= acumulado:
+			cuotas[i].Estado = models.CuotaPagada
+			pagadas++
+		case cuotas[i].FechaVencimiento.Before(hoy):
+			cuotas[i].Estado = models.CuotaVencida
+			vencidas++
+		default:
+			cuotas[i].Estado = models.CuotaPendiente
+		}
+	}
+	return pagadas, vencidas
+}
+
+// addMonths adds n months keeping the day, clamped to the end of shorter
+// months (Jan 31 + 1 month = Feb 28/29).
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, 0, 0, 0, 0, t.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, t.Location())
+}
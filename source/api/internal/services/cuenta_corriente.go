@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/money"
+)
+
+var (
+	ErrInvalidCargo = errors.New("invalid cargo")
+	ErrInvalidFecha = errors.New("invalid fecha format")
+	ErrCargoGasto   = errors.New("gasto_comun_id is not a gasto of this parcela")
+)
+
+// ============================================
+// CUENTA CORRIENTE
+// ============================================
+
+// cuentaCorrienteQuery merges every source of money movements of a parcela into
+// one chronological list. Credit applications and pagos funded from credit are
+// internal transfers and are left out: the money was already counted when the
+// overpayment was received.
+const cuentaCorrienteQuery = `
+	SELECT fecha, tipo, descripcion, referencia, cargo, abono FROM (
+		SELECT s.fecha::timestamptz AS fecha, 0 AS orden, 'saldo_inicial' AS tipo,
+		       COALESCE(NULLIF(s.descripcion, ''), 'Saldo inicial') AS descripcion, '' AS referencia,
+		       GREATEST(s.monto, 0) AS cargo, GREATEST(-s.monto, 0) AS abono
+		FROM saldos_iniciales s
+		WHERE s.parcela_id = $1
+
+		UNION ALL
+		SELECT make_date(pg.year, pg.month, 1)::timestamptz, 1, 'cargo',
+		       COALESCE(NULLIF(pg.descripcion, ''), 'Gasto comun ' || LPAD(pg.month::text, 2, '0') || '/' || pg.year),
+		       g.id::text, g.monto, 0
+		FROM gastos_comunes g
+		JOIN periodos_gasto pg ON g.periodo_id = pg.id
+		WHERE g.parcela_id = $1 AND g.status <> 'cancelled'
+
+		UNION ALL
+		SELECT c.fecha::timestamptz, 1, c.tipo, COALESCE(NULLIF(c.descripcion, ''), c.tipo),
+		       c.id::text, GREATEST(c.monto, 0), GREATEST(-c.monto, 0)
+		FROM cargos_parcela c
+		WHERE c.parcela_id = $1
+
+		UNION ALL
+		SELECT pa.created_at, 2, 'pago', 'Pago ' || pa.metodo || COALESCE(' ' || NULLIF(pa.referencia_externa, ''), ''),
+		       pa.id::text, 0, pa.monto
+		FROM pagos pa
+		JOIN gastos_comunes g ON pa.gasto_comun_id = g.id
+		WHERE g.parcela_id = $1 AND pa.metodo <> 'credito' AND pa.estado IN ('approved', 'reversed')
+
+		UNION ALL
+		SELECT pa.reversed_at, 3, 'reverso', 'Reverso de pago' || COALESCE(': ' || NULLIF(pa.motivo_reverso, ''), ''),
+		       pa.id::text, pa.monto, 0
+		FROM pagos pa
+		JOIN gastos_comunes g ON pa.gasto_comun_id = g.id
+		WHERE g.parcela_id = $1 AND pa.metodo <> 'credito' AND pa.estado = 'reversed'
+
+		UNION ALL
+		SELECT cr.created_at, 2, CASE cr.tipo WHEN 'sobrepago' THEN 'credito' ELSE cr.tipo END,
+		       COALESCE(NULLIF(cr.descripcion, ''), cr.tipo), COALESCE(cr.pago_id::text, cr.id::text),
+		       GREATEST(-cr.monto, 0), GREATEST(cr.monto, 0)
+		FROM creditos_parcela cr
+		WHERE cr.parcela_id = $1
+		  AND (cr.tipo IN ('sobrepago', 'reembolso') OR (cr.tipo = 'reverso' AND cr.monto < 0))
+	) m
+	ORDER BY fecha, orden`
+
+// GetCuentaCorriente returns the chronological ledger of a parcela with a
+// running balance. With a date range, everything before Desde is summarized in
+// SaldoAnterior.
+func (s *GastoComunService) GetCuentaCorriente(ctx context.Context, filter models.CuentaCorrienteFilter) (*models.CuentaCorriente, error) {
+	cc := &models.CuentaCorriente{
+		ParcelaID:   filter.ParcelaID,
+		Desde:       filter.Desde,
+		Hasta:       filter.Hasta,
+		Movimientos: []models.MovimientoCuenta{},
+	}
+	err := s.db.Pool.QueryRow(ctx, `SELECT numero FROM parcelas WHERE id = $1`, filter.ParcelaID).Scan(&cc.ParcelaNumero)
+	if err != nil {
+		return nil, ErrParcelaNotFound
+	}
+
+	rows, err := s.db.Pool.Query(ctx, cuentaCorrienteQuery, filter.ParcelaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hastaExclusive time.Time
+	if filter.Hasta != nil {
+		hastaExclusive = filter.Hasta.AddDate(0, 0, 1)
+	}
+
+	var saldo money.Amount
+	for rows.Next() {
+		var m models.MovimientoCuenta
+		if err := rows.Scan(&m.Fecha, &m.Tipo, &m.Descripcion, &m.Referencia, &m.Cargo, &m.Abono); err != nil {
+			return nil, err
+		}
+		saldo += m.Cargo - m.Abono
+		m.Saldo = saldo
+
+		if filter.Desde != nil && m.Fecha.Before(*filter.Desde) {
+			cc.SaldoAnterior = saldo
+			continue
+		}
+		if filter.Hasta != nil && !m.Fecha.Before(hastaExclusive) {
+			continue
+		}
+		cc.TotalCargos += m.Cargo
+		cc.TotalAbonos += m.Abono
+		cc.Movimientos = append(cc.Movimientos, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	cc.SaldoFinal = cc.SaldoAnterior + cc.TotalCargos - cc.TotalAbonos
+	return cc, nil
+}
+
+// GetMiCuentaCorriente returns the cuenta corriente of the user's parcela.
+func (s *GastoComunService) GetMiCuentaCorriente(ctx context.Context, userID string, desde, hasta *time.Time) (*models.CuentaCorriente, error) {
+	var parcelaID *int
+	err := s.db.Pool.QueryRow(ctx, `SELECT parcela_id FROM users WHERE id = $1`, userID).Scan(&parcelaID)
+	if err != nil {
+		return nil, err
+	}
+	if parcelaID == nil {
+		return nil, ErrUserNoParcela
+	}
+
+	return s.GetCuentaCorriente(ctx, models.CuentaCorrienteFilter{
+		ParcelaID: *parcelaID,
+		Desde:     desde,
+		Hasta:     hasta,
+	})
+}
+
+// CreateCargo posts an interest charge, fine or manual adjustment to a parcela,
+// optionally tied to one of its gastos.
+func (s *GastoComunService) CreateCargo(ctx context.Context, parcelaID int, req *models.CreateCargoRequest, createdBy string) (*models.CargoParcela, error) {
+	fecha, err := time.Parse("2006-01-02", req.Fecha)
+	if err != nil {
+		return nil, ErrInvalidFecha
+	}
+	if !req.Tipo.IsValid() || req.Monto == 0 {
+		return nil, ErrInvalidCargo
+	}
+	// Only adjustments can be in favour of the parcela
+	if req.Monto < 0 && req.Tipo != models.CargoAjuste {
+		return nil, ErrInvalidCargo
+	}
+
+	var exists bool
+	if err := s.db.Pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM parcelas WHERE id = $1)`, parcelaID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrParcelaNotFound
+	}
+	if req.GastoComunID != nil {
+		err = s.db.Pool.QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM gastos_comunes WHERE id::text = $1 AND parcela_id = $2)`,
+			*req.GastoComunID, parcelaID).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrCargoGasto
+		}
+	}
+
+	if req.Tipo == models.CargoInteres && req.GastoComunID != nil {
+		var congelado bool
+		err = s.db.Pool.QueryRow(ctx, `
+			SELECT EXISTS(
+				SELECT 1 FROM convenio_gastos cg
+				JOIN convenios_pago c ON cg.convenio_id = c.id
+				WHERE cg.gasto_comun_id = $1 AND c.estado = 'activo' AND c.congelar_intereses)`,
+			*req.GastoComunID).Scan(&congelado)
+		if err != nil {
+			return nil, err
+		}
+		if congelado {
+			return nil, ErrInteresesCongelados
+		}
+	}
+
+	var c models.CargoParcela
+	err = s.db.Pool.QueryRow(ctx, `
+		INSERT INTO cargos_parcela (parcela_id, gasto_comun_id, tipo, monto, fecha, descripcion, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, parcela_id, gasto_comun_id, tipo, monto, fecha, COALESCE(descripcion, ''), created_by, created_at`,
+		parcelaID, req.GastoComunID, req.Tipo, req.Monto, fecha, req.Descripcion, createdBy).Scan(
+		&c.ID, &c.ParcelaID, &c.GastoComunID, &c.Tipo, &c.Monto, &c.Fecha, &c.Descripcion, &c.CreatedBy, &c.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := registrarAuditoria(ctx, s.db.Pool, "cargo_parcela", c.ID, "crear", c.Descripcion, createdBy, c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// SetSaldoInicial sets (or replaces) the opening balance of a parcela.
+func (s *GastoComunService) SetSaldoInicial(ctx context.Context, parcelaID int, req *models.SaldoInicialRequest, createdBy string) (*models.SaldoInicial, error) {
+	fecha, err := time.Parse("2006-01-02", req.Fecha)
+	if err != nil {
+		return nil, ErrInvalidFecha
+	}
+
+	var exists bool
+	if err := s.db.Pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM parcelas WHERE id = $1)`, parcelaID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrParcelaNotFound
+	}
+
+	var si models.SaldoInicial
+	err = s.db.Pool.QueryRow(ctx, `
+		INSERT INTO saldos_iniciales (parcela_id, monto, fecha, descripcion, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (parcela_id) DO UPDATE
+		SET monto = EXCLUDED.monto, fecha = EXCLUDED.fecha, descripcion = EXCLUDED.descripcion,
+		    created_by = EXCLUDED.created_by, updated_at = NOW()
+		RETURNING parcela_id, monto, fecha, COALESCE(descripcion, ''), created_by, created_at, updated_at`,
+		parcelaID, req.Monto, fecha, req.Descripcion, createdBy).Scan(
+		&si.ParcelaID, &si.Monto, &si.Fecha, &si.Descripcion, &si.CreatedBy, &si.CreatedAt, &si.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := registrarAuditoria(ctx, s.db.Pool, "saldo_inicial", strconv.Itoa(parcelaID), "fijar", si.Descripcion, createdBy, si); err != nil {
+		return nil, err
+	}
+	return &si, nil
+}
@@ -0,0 +1,396 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/money"
+)
+
+var (
+	ErrCuentaNotFound         = errors.New("cuenta not found")
+	ErrCuentaExists           = errors.New("cuenta already exists")
+	ErrInvalidCuenta          = errors.New("invalid cuenta")
+	ErrCuentaInvalida         = errors.New("cuenta does not exist or is inactive")
+	ErrCuentaPrincipal        = errors.New("the principal cuenta cannot be deactivated")
+	ErrInvalidTransferencia   = errors.New("invalid transferencia")
+	ErrInvalidConfigTesoreria = errors.New("invalid tesoreria configuration")
+	ErrTransferenciaNotFound  = errors.New("transferencia not found")
+)
+
+var codigoCuentaRe = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+// ============================================
+// CUENTAS
+// ============================================
+
+const cuentaColumns = `
+	c.id, c.codigo, c.nombre, c.tipo, c.principal, c.activa,
+	COALESCE((SELECT SUM(CASE WHEN m.type = 'ingreso' THEN m.amount ELSE -m.amount END)
+	          FROM movimientos_tesoreria m WHERE m.cuenta_id = c.id), 0),
+	c.created_at, c.updated_at`
+
+func scanCuenta(row pgx.Row, c *models.CuentaTesoreria) error {
+	return row.Scan(&c.ID, &c.Codigo, &c.Nombre, &c.Tipo, &c.Principal, &c.Activa, &c.Saldo,
+		&c.CreatedAt, &c.UpdatedAt)
+}
+
+func (s *TesoreriaService) ListCuentas(ctx context.Context, soloActivas bool) ([]models.CuentaTesoreria, error) {
+	query := `SELECT ` + cuentaColumns + ` FROM cuentas_tesoreria c`
+	if soloActivas {
+		query += ` WHERE c.activa`
+	}
+	query += ` ORDER BY c.principal DESC, c.nombre`
+
+	rows, err := s.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cuentas := []models.CuentaTesoreria{}
+	for rows.Next() {
+		var c models.CuentaTesoreria
+		if err := scanCuenta(rows, &c); err != nil {
+			return nil, err
+		}
+		cuentas = append(cuentas, c)
+	}
+	return cuentas, rows.Err()
+}
+
+func (s *TesoreriaService) GetCuenta(ctx context.Context, id string) (*models.CuentaTesoreria, error) {
+	var c models.CuentaTesoreria
+	err := scanCuenta(s.db.Pool.QueryRow(ctx, `SELECT `+cuentaColumns+` FROM cuentas_tesoreria c WHERE c.id::text = $1`, id), &c)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCuentaNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (s *TesoreriaService) CreateCuenta(ctx context.Context, req *models.CreateCuentaRequest, userID string) (*models.CuentaTesoreria, error) {
+	req.Codigo = strings.TrimSpace(req.Codigo)
+	req.Nombre = strings.TrimSpace(req.Nombre)
+	if !codigoCuentaRe.MatchString(req.Codigo) || req.Nombre == "" || !req.Tipo.IsValid() {
+		return nil, ErrInvalidCuenta
+	}
+
+	var id string
+	err := s.db.Pool.QueryRow(ctx, `
+		INSERT INTO cuentas_tesoreria (codigo, nombre, tipo) VALUES ($1, $2, $3) RETURNING id`,
+		req.Codigo, req.Nombre, req.Tipo).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrCuentaExists
+		}
+		return nil, err
+	}
+
+	if err = registrarAuditoria(ctx, s.db.Pool, "cuenta_tesoreria", id, "crear", "", userID, req); err != nil {
+		return nil, err
+	}
+	return s.GetCuenta(ctx, id)
+}
+
+// UpdateCuenta renames, (de)activates or makes a cuenta the principal one.
+// Movimientos stay in deactivated cuentas, but new ones cannot be added.
+func (s *TesoreriaService) UpdateCuenta(ctx context.Context, id string, req *models.UpdateCuentaRequest, userID string) (*models.CuentaTesoreria, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var c models.CuentaTesoreria
+	err = tx.QueryRow(ctx, `
+		SELECT id, nombre, principal, activa FROM cuentas_tesoreria WHERE id::text = $1 FOR UPDATE`, id).Scan(
+		&c.ID, &c.Nombre, &c.Principal, &c.Activa)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCuentaNotFound
+		}
+		return nil, err
+	}
+
+	if req.Nombre != nil {
+		c.Nombre = strings.TrimSpace(*req.Nombre)
+	}
+	if req.Activa != nil {
+		c.Activa = *req.Activa
+	}
+	if c.Nombre == "" || (req.Principal != nil && !*req.Principal) {
+		return nil, ErrInvalidCuenta
+	}
+	hacerPrincipal := req.Principal != nil && *req.Principal && !c.Principal
+	if (c.Principal || hacerPrincipal) && !c.Activa {
+		return nil, ErrCuentaPrincipal
+	}
+
+	if hacerPrincipal {
+		if _, err = tx.Exec(ctx, `UPDATE cuentas_tesoreria SET principal = FALSE, updated_at = NOW() WHERE principal`); err != nil {
+			return nil, err
+		}
+		c.Principal = true
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE cuentas_tesoreria SET nombre = $1, activa = $2, principal = $3, updated_at = NOW() WHERE id = $4`,
+		c.Nombre, c.Activa, c.Principal, c.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = registrarAuditoria(ctx, tx, "cuenta_tesoreria", c.ID, "actualizar", "", userID, req); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetCuenta(ctx, c.ID)
+}
+
+// ============================================
+// TRANSFERENCIAS
+// ============================================
+
+// Transferir moves money between two active cuentas.
+func (s *TesoreriaService) Transferir(ctx context.Context, req *models.TransferenciaRequest, userID string) (*models.Transferencia, error) {
+	if req.Monto <= 0 || req.CuentaOrigenID == "" || req.CuentaOrigenID == req.CuentaDestinoID {
+		return nil, ErrInvalidTransferencia
+	}
+	fecha := fechaHoy()
+	if req.Date != nil {
+		fecha = *req.Date
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var origen, destino string
+	for _, c := range []struct {
+		id     string
+		nombre *string
+	}{{req.CuentaOrigenID, &origen}, {req.CuentaDestinoID, &destino}} {
+		err = tx.QueryRow(ctx, `SELECT nombre FROM cuentas_tesoreria WHERE id::text = $1 AND activa`, c.id).Scan(c.nombre)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrCuentaInvalida
+			}
+			return nil, err
+		}
+	}
+
+	descripcion := strings.TrimSpace(req.Description)
+	if descripcion == "" {
+		descripcion = fmt.Sprintf("Transferencia de %s a %s", origen, destino)
+	}
+
+	transferenciaID, err := transferir(ctx, tx, req.CuentaOrigenID, req.CuentaDestinoID, req.Monto, fecha,
+		truncar(descripcion, 255), nil, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = registrarAuditoria(ctx, tx, "transferencia_tesoreria", transferenciaID, "crear", "", userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetTransferencia(ctx, transferenciaID)
+}
+
+// GetTransferencia returns both entries of a transfer.
+func (s *TesoreriaService) GetTransferencia(ctx context.Context, id string) (*models.Transferencia, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT `+movimientoColumns+movimientoJoins+`
+		WHERE m.transferencia_id::text = $1 AND m.reverso_de IS NULL`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	t := &models.Transferencia{ID: id}
+	encontrados := 0
+	for rows.Next() {
+		var m models.Movimiento
+		if err := scanMovimiento(rows, &m); err != nil {
+			return nil, err
+		}
+		if m.Type == models.MovimientoEgreso {
+			t.Origen = m
+		} else {
+			t.Destino = m
+		}
+		encontrados++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if encontrados == 0 {
+		return nil, ErrTransferenciaNotFound
+	}
+	return t, nil
+}
+
+// ============================================
+// FONDO DE RESERVA
+// ============================================
+
+func (s *TesoreriaService) GetConfigTesoreria(ctx context.Context) (*models.ConfigTesoreria, error) {
+	return getConfigTesoreria(ctx, s.db.Pool)
+}
+
+func getConfigTesoreria(ctx context.Context, q querier) (*models.ConfigTesoreria, error) {
+	c := &models.ConfigTesoreria{ContabilizacionPagos: models.ContabilizacionIndividual}
+	err := q.QueryRow(ctx, `
+		SELECT porcentaje_fondo_reserva::float8, cuenta_reserva_id, contabilizacion_pagos, updated_by, updated_at
+		FROM config_tesoreria WHERE id = 1`).Scan(
+		&c.PorcentajeFondoReserva, &c.CuentaReservaID, &c.ContabilizacionPagos, &c.UpdatedBy, &c.UpdatedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *TesoreriaService) UpdateConfigTesoreria(ctx context.Context, req *models.UpdateConfigTesoreriaRequest, userID string) (*models.ConfigTesoreria, error) {
+	c, err := s.GetConfigTesoreria(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.PorcentajeFondoReserva != nil {
+		c.PorcentajeFondoReserva = *req.PorcentajeFondoReserva
+	}
+	if req.CuentaReservaID != nil {
+		c.CuentaReservaID = req.CuentaReservaID
+		if *req.CuentaReservaID == "" {
+			c.CuentaReservaID = nil
+		}
+	}
+	if req.ContabilizacionPagos != nil {
+		c.ContabilizacionPagos = *req.ContabilizacionPagos
+	}
+
+	if c.PorcentajeFondoReserva < 0 || c.PorcentajeFondoReserva > 100 {
+		return nil, fmt.Errorf("%w: porcentaje_fondo_reserva must be between 0 and 100", ErrInvalidConfigTesoreria)
+	}
+	if c.PorcentajeFondoReserva > 0 && c.CuentaReservaID == nil {
+		return nil, fmt.Errorf("%w: cuenta_reserva_id is required", ErrInvalidConfigTesoreria)
+	}
+	if !c.ContabilizacionPagos.IsValid() {
+		return nil, fmt.Errorf("%w: contabilizacion_pagos must be desactivada, individual or diaria", ErrInvalidConfigTesoreria)
+	}
+	if c.CuentaReservaID != nil {
+		var principal, activa bool
+		err = s.db.Pool.QueryRow(ctx, `SELECT principal, activa FROM cuentas_tesoreria WHERE id::text = $1`,
+			*c.CuentaReservaID).Scan(&principal, &activa)
+		if errors.Is(err, pgx.ErrNoRows) || (err == nil && (principal || !activa)) {
+			return nil, fmt.Errorf("%w: the reserve cuenta must be active and not the principal one", ErrInvalidConfigTesoreria)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = s.db.Pool.Exec(ctx, `
+		INSERT INTO config_tesoreria (id, porcentaje_fondo_reserva, cuenta_reserva_id, contabilizacion_pagos, updated_by)
+		VALUES (1, $1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE
+		SET porcentaje_fondo_reserva = EXCLUDED.porcentaje_fondo_reserva,
+		    cuenta_reserva_id = EXCLUDED.cuenta_reserva_id,
+		    contabilizacion_pagos = EXCLUDED.contabilizacion_pagos,
+		    updated_by = EXCLUDED.updated_by, updated_at = NOW()`,
+		c.PorcentajeFondoReserva, c.CuentaReservaID, c.ContabilizacionPagos, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := registrarAuditoria(ctx, s.db.Pool, "config_tesoreria", "1", "actualizar", "", userID, c); err != nil {
+		return nil, err
+	}
+	return s.GetConfigTesoreria(ctx)
+}
+
+// asignarFondoReserva transfers the configured share of an approved gasto
+// común pago from the principal cuenta to the reserve fund, rounded to whole
+// pesos. Credit applications count as well: the overpayment that created the
+// credit was not allocated when it came in.
+func asignarFondoReserva(ctx context.Context, tx pgx.Tx, pagoID string, monto money.Amount) error {
+	cfg, err := getConfigTesoreria(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if cfg.PorcentajeFondoReserva <= 0 || cfg.CuentaReservaID == nil {
+		return nil
+	}
+	aporte := monto.Mul(int(math.Round(cfg.PorcentajeFondoReserva * 100))).Div(10000).RoundPesos()
+	if aporte <= 0 {
+		return nil
+	}
+
+	var principalID string
+	err = tx.QueryRow(ctx, `SELECT id FROM cuentas_tesoreria WHERE principal`).Scan(&principalID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if principalID == *cfg.CuentaReservaID {
+		return nil
+	}
+
+	descripcion := fmt.Sprintf("Aporte fondo de reserva (%s%%) de pago de gasto común",
+		strconv.FormatFloat(cfg.PorcentajeFondoReserva, 'f', -1, 64))
+	_, err = transferir(ctx, tx, principalID, *cfg.CuentaReservaID, aporte, fechaHoy(), descripcion, &pagoID, "")
+	return err
+}
+
+// revertirFondoReserva voids the reserve fund allocation of a pago that is
+// being reversed.
+func revertirFondoReserva(ctx context.Context, tx pgx.Tx, pagoID, motivo, userID string) error {
+	rows, err := tx.Query(ctx, `
+		SELECT DISTINCT transferencia_id::text FROM movimientos_tesoreria
+		WHERE pago_id = $1 AND transferencia_id IS NOT NULL
+		  AND reverso_de IS NULL AND anulado_at IS NULL`, pagoID)
+	if err != nil {
+		return err
+	}
+	var transferencias []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		transferencias = append(transferencias, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range transferencias {
+		if err := anularTransferencia(ctx, tx, id, "Reverso de pago: "+motivo, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
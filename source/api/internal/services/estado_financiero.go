@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/money"
+)
+
+var ErrInvalidEstadoFinanciero = errors.New("invalid statement period")
+
+// GetEstadoFinanciero builds the treasury statement of a month (1-12) or of
+// the whole year (month 0). The public version leaves out the parcelas that
+// owe gastos comunes.
+func (s *TesoreriaService) GetEstadoFinanciero(ctx context.Context, year, month int, publico bool) (*models.EstadoFinanciero, error) {
+	if month < 0 || month > 12 {
+		return nil, ErrInvalidEstadoFinanciero
+	}
+	desde := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	hasta := desde.AddDate(1, 0, 0) // exclusive
+	if month > 0 {
+		desde = time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+		hasta = desde.AddDate(0, 1, 0)
+	}
+	if desde.After(fechaHoy()) {
+		return nil, ErrInvalidEstadoFinanciero
+	}
+
+	e := &models.EstadoFinanciero{
+		Year:        year,
+		Month:       month,
+		Desde:       desde,
+		Hasta:       hasta.AddDate(0, 0, -1),
+		Publico:     publico,
+		Ingresos:    []models.LineaEstado{},
+		Egresos:     []models.LineaEstado{},
+		Cuentas:     []models.SaldoCuentaEstado{},
+		FlujoCaja:   []models.FlujoCajaMes{},
+		Recaudacion: []models.RecaudacionMes{},
+		GeneradoAt:  time.Now(),
+	}
+
+	// Transfers net out over all cuentas, so the opening balance includes
+	// them and the period totals do not
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(`+montoLibro+`), 0) FROM movimientos_tesoreria m WHERE m.date < $1`,
+		desde).Scan(&e.SaldoInicial)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT m.type, COALESCE(m.category, ''), COALESCE(c.nombre, 'Sin categoría'), SUM(m.amount)
+		FROM movimientos_tesoreria m
+		LEFT JOIN categorias_tesoreria c ON c.codigo = m.category
+		WHERE m.date >= $1 AND m.date < $2 AND m.transferencia_id IS NULL
+		GROUP BY m.type, m.category, c.nombre
+		HAVING SUM(m.amount) <> 0
+		ORDER BY SUM(m.amount) DESC`, desde, hasta)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tipo models.MovimientoType
+		var l models.LineaEstado
+		if err := rows.Scan(&tipo, &l.Categoria, &l.CategoriaNombre, &l.Monto); err != nil {
+			return nil, err
+		}
+		if tipo == models.MovimientoIngreso {
+			e.Ingresos = append(e.Ingresos, l)
+			e.TotalIngresos += l.Monto
+		} else {
+			e.Egresos = append(e.Egresos, l)
+			e.TotalEgresos += l.Monto
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	e.Resultado = e.TotalIngresos - e.TotalEgresos
+	e.SaldoFinal = e.SaldoInicial + e.Resultado
+
+	rows, err = s.db.Pool.Query(ctx, `
+		SELECT c.id, c.nombre,
+		       COALESCE(SUM(`+montoLibro+`) FILTER (WHERE m.date < $1), 0),
+		       COALESCE(SUM(m.amount) FILTER (WHERE m.type = 'ingreso' AND m.date >= $1 AND m.date < $2), 0),
+		       COALESCE(SUM(m.amount) FILTER (WHERE m.type = 'egreso' AND m.date >= $1 AND m.date < $2), 0)
+		FROM cuentas_tesoreria c
+		LEFT JOIN movimientos_tesoreria m ON m.cuenta_id = c.id AND m.date < $2
+		GROUP BY c.id
+		HAVING c.activa OR COUNT(m.id) > 0
+		ORDER BY c.principal DESC, c.nombre`, desde, hasta)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c models.SaldoCuentaEstado
+		if err := rows.Scan(&c.CuentaID, &c.Nombre, &c.SaldoInicial, &c.Ingresos, &c.Egresos); err != nil {
+			return nil, err
+		}
+		c.SaldoFinal = c.SaldoInicial + c.Ingresos - c.Egresos
+		e.Cuentas = append(e.Cuentas, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.flujoCaja(ctx, e, hasta); err != nil {
+		return nil, err
+	}
+	if err := s.recaudacion(ctx, e, desde, hasta); err != nil {
+		return nil, err
+	}
+
+	if !publico {
+		e.Deudores = []models.DeudorEstado{}
+		rows, err = s.db.Pool.Query(ctx, `
+			SELECT pa.numero, SUM(g.monto - g.monto_pagado)
+			FROM gastos_comunes g
+			JOIN periodos_gasto p ON p.id = g.periodo_id
+			JOIN parcelas pa ON pa.id = g.parcela_id
+			WHERE g.status <> 'cancelled' AND g.monto > g.monto_pagado
+			  AND make_date(p.year, p.month, 1) < $1
+			GROUP BY pa.id, pa.numero
+			ORDER BY SUM(g.monto - g.monto_pagado) DESC, pa.numero`, hasta)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var d models.DeudorEstado
+			if err := rows.Scan(&d.ParcelaNumero, &d.Deuda); err != nil {
+				return nil, err
+			}
+			e.Deudores = append(e.Deudores, d)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// flujoCaja fills the month by month cash flow from January up to the end
+// of the statement, transfers excluded.
+func (s *TesoreriaService) flujoCaja(ctx context.Context, e *models.EstadoFinanciero, hasta time.Time) error {
+	inicio := time.Date(e.Year, 1, 1, 0, 0, 0, 0, time.UTC)
+	if hasta.After(fechaHoy()) {
+		hasta = fechaHoy().AddDate(0, 0, 1)
+	}
+
+	var saldo money.Amount
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(`+montoLibro+`), 0) FROM movimientos_tesoreria m WHERE m.date < $1`,
+		inicio).Scan(&saldo)
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT EXTRACT(MONTH FROM m.date)::int,
+		       COALESCE(SUM(m.amount) FILTER (WHERE m.type = 'ingreso'), 0),
+		       COALESCE(SUM(m.amount) FILTER (WHERE m.type = 'egreso'), 0)
+		FROM movimientos_tesoreria m
+		WHERE m.date >= $1 AND m.date < $2 AND m.transferencia_id IS NULL
+		GROUP BY 1`, inicio, hasta)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	porMes := map[int][2]money.Amount{}
+	for rows.Next() {
+		var mes int
+		var ingresos, egresos money.Amount
+		if err := rows.Scan(&mes, &ingresos, &egresos); err != nil {
+			return err
+		}
+		porMes[mes] = [2]money.Amount{ingresos, egresos}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for mes := 1; time.Date(e.Year, time.Month(mes), 1, 0, 0, 0, 0, time.UTC).Before(hasta); mes++ {
+		f := models.FlujoCajaMes{Year: e.Year, Month: mes, SaldoInicial: saldo,
+			Ingresos: porMes[mes][0], Egresos: porMes[mes][1]}
+		f.SaldoFinal = f.SaldoInicial + f.Ingresos - f.Egresos
+		saldo = f.SaldoFinal
+		e.FlujoCaja = append(e.FlujoCaja, f)
+	}
+	return nil
+}
+
+// recaudacion fills the collection of the gastos comunes issued for the
+// months of the statement.
+func (s *TesoreriaService) recaudacion(ctx context.Context, e *models.EstadoFinanciero, desde, hasta time.Time) error {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT p.year, p.month, COALESCE(SUM(g.monto), 0), COALESCE(SUM(LEAST(g.monto_pagado, g.monto)), 0),
+		       COUNT(g.id), COUNT(g.id) FILTER (WHERE g.monto_pagado >= g.monto)
+		FROM periodos_gasto p
+		JOIN gastos_comunes g ON g.periodo_id = p.id AND g.status <> 'cancelled'
+		WHERE make_date(p.year, p.month, 1) >= $1 AND make_date(p.year, p.month, 1) < $2
+		GROUP BY p.year, p.month
+		ORDER BY p.year, p.month`, desde, hasta)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var emitido, recaudado money.Amount
+	for rows.Next() {
+		var r models.RecaudacionMes
+		if err := rows.Scan(&r.Year, &r.Month, &r.Emitido, &r.Recaudado, &r.Parcelas, &r.ParcelasAlDia); err != nil {
+			return err
+		}
+		r.Tasa = porcentajeEjecutado(r.Emitido, r.Recaudado)
+		emitido += r.Emitido
+		recaudado += r.Recaudado
+		e.Recaudacion = append(e.Recaudacion, r)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	e.TasaRecaudacion = porcentajeEjecutado(emitido, recaudado)
+	return nil
+}
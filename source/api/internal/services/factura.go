@@ -0,0 +1,728 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/condominio/backend/internal/export"
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/money"
+)
+
+var (
+	ErrFacturaNotFound        = errors.New("factura not found")
+	ErrFacturaExists          = errors.New("this proveedor already has a factura with this numero")
+	ErrInvalidFactura         = errors.New("invalid factura")
+	ErrFacturaEstado          = errors.New("the factura is not in a state that allows this")
+	ErrFacturaYaFirmada       = errors.New("you already signed this factura")
+	ErrFacturaPropia          = errors.New("the factura cannot be approved by whoever registered it")
+	ErrProveedorInactivo      = errors.New("proveedor is inactive")
+	ErrInvalidReglaAprobacion = errors.New("invalid approval rules")
+)
+
+// ============================================
+// FACTURAS
+// ============================================
+
+const facturaColumns = `
+	f.id, f.proveedor_id, p.nombre, p.rut, f.numero, f.descripcion, COALESCE(f.categoria, ''), f.monto,
+	f.fecha_emision, f.fecha_vencimiento,
+	f.estado IN ('pendiente', 'aprobada') AND f.fecha_vencimiento < CURRENT_DATE,
+	f.estado, f.aprobaciones_requeridas,
+	(SELECT COUNT(*) FROM factura_aprobaciones a WHERE a.factura_id = f.id AND a.aprobada),
+	f.movimiento_id, f.pagada_at, f.pagada_by, COALESCE(f.motivo_anulacion, ''),
+	(SELECT COUNT(*) FROM facturas_adjuntos a WHERE a.factura_id = f.id),
+	f.created_by, COALESCE(u.name, ''), f.created_at, f.updated_at`
+
+const facturaJoins = `
+	FROM facturas f
+	JOIN proveedores p ON p.id = f.proveedor_id
+	LEFT JOIN users u ON u.id = f.created_by`
+
+func scanFactura(row pgx.Row, f *models.Factura) error {
+	return row.Scan(&f.ID, &f.ProveedorID, &f.ProveedorNombre, &f.ProveedorRUT, &f.Numero, &f.Descripcion,
+		&f.Categoria, &f.Monto,
+		&f.FechaEmision, &f.FechaVencimiento,
+		&f.Vencida,
+		&f.Estado, &f.AprobacionesRequeridas,
+		&f.TotalAprobaciones,
+		&f.MovimientoID, &f.PagadaAt, &f.PagadaBy, &f.MotivoAnulacion,
+		&f.TotalAdjuntos,
+		&f.CreatedBy, &f.CreatorName, &f.CreatedAt, &f.UpdatedAt)
+}
+
+func (s *ProveedorService) ListFacturas(ctx context.Context, filter models.FacturaFilter) (*models.FacturaListResponse, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 || filter.PerPage > 100 {
+		filter.PerPage = 20
+	}
+	offset := (filter.Page - 1) * filter.PerPage
+
+	where := ` WHERE 1=1`
+	args := []interface{}{}
+	argCount := 0
+
+	if filter.ProveedorID != "" {
+		argCount++
+		where += ` AND f.proveedor_id::text = $` + strconv.Itoa(argCount)
+		args = append(args, filter.ProveedorID)
+	}
+	if filter.Estado != "" {
+		argCount++
+		where += ` AND f.estado = $` + strconv.Itoa(argCount)
+		args = append(args, filter.Estado)
+	}
+	if filter.Vencidas {
+		where += ` AND f.estado IN ('pendiente', 'aprobada') AND f.fecha_vencimiento < CURRENT_DATE`
+	}
+
+	var total int
+	if err := s.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM facturas f`+where, args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + facturaColumns + facturaJoins + where +
+		` ORDER BY f.fecha_vencimiento NULLS LAST, f.fecha_emision, f.created_at` +
+		` LIMIT $` + strconv.Itoa(argCount+1) + ` OFFSET $` + strconv.Itoa(argCount+2)
+	args = append(args, filter.PerPage, offset)
+
+	rows, err := s.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facturas := []models.Factura{}
+	for rows.Next() {
+		var f models.Factura
+		if err := scanFactura(rows, &f); err != nil {
+			return nil, err
+		}
+		facturas = append(facturas, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &models.FacturaListResponse{
+		Facturas: facturas,
+		Total:    total,
+		Page:     filter.Page,
+		PerPage:  filter.PerPage,
+	}, nil
+}
+
+// GetFactura returns a factura with its signatures and attachments.
+func (s *ProveedorService) GetFactura(ctx context.Context, id string) (*models.Factura, error) {
+	var f models.Factura
+	err := scanFactura(s.db.Pool.QueryRow(ctx, `SELECT `+facturaColumns+facturaJoins+` WHERE f.id::text = $1`, id), &f)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrFacturaNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT a.user_id, COALESCE(u.name, ''), a.aprobada, COALESCE(a.comentario, ''), a.created_at
+		FROM factura_aprobaciones a
+		LEFT JOIN users u ON u.id = a.user_id
+		WHERE a.factura_id = $1
+		ORDER BY a.created_at`, f.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a models.FacturaAprobacion
+		if err := rows.Scan(&a.UserID, &a.UserName, &a.Aprobada, &a.Comentario, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		f.Aprobaciones = append(f.Aprobaciones, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	rows, err = s.db.Pool.Query(ctx, `
+		SELECT id, factura_id, nombre_archivo, content_type, tamano, created_by, created_at
+		FROM facturas_adjuntos WHERE factura_id = $1 ORDER BY created_at`, f.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a models.AdjuntoFactura
+		if err := rows.Scan(&a.ID, &a.FacturaID, &a.NombreArchivo, &a.ContentType, &a.Tamano, &a.CreatedBy, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		f.Adjuntos = append(f.Adjuntos, a)
+	}
+	return &f, rows.Err()
+}
+
+// CreateFactura registers a supplier invoice pending approval and lets the
+// directiva know it needs their signature.
+func (s *ProveedorService) CreateFactura(ctx context.Context, req *models.CreateFacturaRequest, userID string) (*models.Factura, error) {
+	req.Numero = strings.TrimSpace(req.Numero)
+	req.Descripcion = strings.TrimSpace(req.Descripcion)
+	if req.Numero == "" || req.Descripcion == "" || req.Monto <= 0 || req.FechaEmision.IsZero() ||
+		(req.FechaVencimiento != nil && req.FechaVencimiento.Before(req.FechaEmision)) {
+		return nil, ErrInvalidFactura
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var proveedor, categoria string
+	var activo bool
+	err = tx.QueryRow(ctx, `SELECT nombre, COALESCE(categoria, ''), activo FROM proveedores WHERE id::text = $1`,
+		req.ProveedorID).Scan(&proveedor, &categoria, &activo)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProveedorNotFound
+		}
+		return nil, err
+	}
+	if !activo {
+		return nil, ErrProveedorInactivo
+	}
+	if req.Categoria != "" {
+		categoria = req.Categoria
+	}
+	if err = validarCategoria(ctx, tx, categoria, models.MovimientoEgreso, true); err != nil {
+		return nil, err
+	}
+
+	requeridas, err := aprobacionesRequeridas(ctx, tx, req.Monto)
+	if err != nil {
+		return nil, err
+	}
+
+	var id string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO facturas (proveedor_id, numero, descripcion, categoria, monto, fecha_emision, fecha_vencimiento,
+		                      aprobaciones_requeridas, created_by)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9)
+		RETURNING id`,
+		req.ProveedorID, req.Numero, req.Descripcion, categoria, req.Monto, req.FechaEmision, req.FechaVencimiento,
+		requeridas, userID).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+			return nil, ErrFacturaExists
+		}
+		return nil, err
+	}
+
+	if err = registrarAuditoria(ctx, tx, "factura", id, "crear", "", userID, req); err != nil {
+		return nil, err
+	}
+
+	titulo := fmt.Sprintf("Factura %s de %s por aprobar", req.Numero, proveedor)
+	cuerpo := fmt.Sprintf("%s por %s. Requiere %d aprobación(es) de la directiva.",
+		req.Descripcion, export.FormatCLP(req.Monto.Float64()), requeridas)
+	_, err = tx.Exec(ctx, `
+		INSERT INTO notificaciones (user_id, title, body, type, reference_id)
+		SELECT id, $1, $2, $3, $4 FROM users WHERE role = 'directiva' AND id::text <> $5`,
+		titulo, cuerpo, models.NotificationTypeTesoreria, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetFactura(ctx, id)
+}
+
+// lockFactura locks a factura for a state change.
+func lockFactura(ctx context.Context, tx pgx.Tx, id string) (*models.Factura, error) {
+	var f models.Factura
+	err := tx.QueryRow(ctx, `
+		SELECT id, proveedor_id, numero, descripcion, COALESCE(categoria, ''), monto, fecha_emision,
+		       fecha_vencimiento, estado, aprobaciones_requeridas, created_by
+		FROM facturas WHERE id::text = $1
+		FOR UPDATE`, id).Scan(
+		&f.ID, &f.ProveedorID, &f.Numero, &f.Descripcion, &f.Categoria, &f.Monto, &f.FechaEmision,
+		&f.FechaVencimiento, &f.Estado, &f.AprobacionesRequeridas, &f.CreatedBy)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrFacturaNotFound
+		}
+		return nil, err
+	}
+	return &f, nil
+}
+
+// UpdateFactura corrects a pending factura. Signatures already given are
+// discarded: they approved different data.
+func (s *ProveedorService) UpdateFactura(ctx context.Context, id string, req *models.UpdateFacturaRequest, userID string) (*models.Factura, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	f, err := lockFactura(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if f.Estado != models.FacturaPendiente {
+		return nil, ErrFacturaEstado
+	}
+	anterior := *f
+
+	if req.Numero != nil {
+		f.Numero = strings.TrimSpace(*req.Numero)
+	}
+	if req.Descripcion != nil {
+		f.Descripcion = strings.TrimSpace(*req.Descripcion)
+	}
+	if req.Categoria != nil {
+		f.Categoria = *req.Categoria
+		if err = validarCategoria(ctx, tx, f.Categoria, models.MovimientoEgreso, true); err != nil {
+			return nil, err
+		}
+	}
+	if req.Monto != nil {
+		f.Monto = *req.Monto
+	}
+	if req.FechaEmision != nil {
+		f.FechaEmision = *req.FechaEmision
+	}
+	if req.FechaVencimiento != nil {
+		f.FechaVencimiento = req.FechaVencimiento
+	}
+	if f.Numero == "" || f.Descripcion == "" || f.Monto <= 0 ||
+		(f.FechaVencimiento != nil && f.FechaVencimiento.Before(f.FechaEmision)) {
+		return nil, ErrInvalidFactura
+	}
+
+	if f.AprobacionesRequeridas, err = aprobacionesRequeridas(ctx, tx, f.Monto); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE facturas
+		SET numero = $1, descripcion = $2, categoria = NULLIF($3, ''), monto = $4, fecha_emision = $5,
+		    fecha_vencimiento = $6, aprobaciones_requeridas = $7, updated_at = NOW()
+		WHERE id = $8`,
+		f.Numero, f.Descripcion, f.Categoria, f.Monto, f.FechaEmision, f.FechaVencimiento,
+		f.AprobacionesRequeridas, f.ID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+			return nil, ErrFacturaExists
+		}
+		return nil, err
+	}
+	if _, err = tx.Exec(ctx, `DELETE FROM factura_aprobaciones WHERE factura_id = $1`, f.ID); err != nil {
+		return nil, err
+	}
+
+	err = registrarAuditoria(ctx, tx, "factura", f.ID, "actualizar", "", userID, map[string]interface{}{
+		"anterior": anterior,
+		"cambios":  req,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetFactura(ctx, f.ID)
+}
+
+// AprobarFactura adds the signature of a directiva member other than the one
+// who registered the factura. Once the number of signatures the amount
+// requires is reached the factura can be paid.
+func (s *ProveedorService) AprobarFactura(ctx context.Context, id, comentario, userID string) (*models.Factura, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	f, err := lockFactura(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if f.Estado != models.FacturaPendiente {
+		return nil, ErrFacturaEstado
+	}
+	if f.CreatedBy != nil && *f.CreatedBy == userID {
+		return nil, ErrFacturaPropia
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO factura_aprobaciones (factura_id, user_id, aprobada, comentario)
+		VALUES ($1, $2, true, NULLIF($3, ''))`, f.ID, userID, strings.TrimSpace(comentario))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+			return nil, ErrFacturaYaFirmada
+		}
+		return nil, err
+	}
+
+	var firmas int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM factura_aprobaciones WHERE factura_id = $1 AND aprobada`, f.ID).Scan(&firmas)
+	if err != nil {
+		return nil, err
+	}
+	if firmas >= f.AprobacionesRequeridas {
+		_, err = tx.Exec(ctx, `UPDATE facturas SET estado = 'aprobada', updated_at = NOW() WHERE id = $1`, f.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = registrarAuditoria(ctx, tx, "factura", f.ID, "aprobar", comentario, userID, map[string]interface{}{
+		"firmas":     firmas,
+		"requeridas": f.AprobacionesRequeridas,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetFactura(ctx, f.ID)
+}
+
+// RechazarFactura rejects a factura not yet paid. A member who had approved
+// it may change their signature into a rejection.
+func (s *ProveedorService) RechazarFactura(ctx context.Context, id, motivo, userID string) (*models.Factura, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	f, err := lockFactura(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if f.Estado != models.FacturaPendiente && f.Estado != models.FacturaAprobada {
+		return nil, ErrFacturaEstado
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO factura_aprobaciones (factura_id, user_id, aprobada, comentario)
+		VALUES ($1, $2, false, $3)
+		ON CONFLICT (factura_id, user_id) DO UPDATE
+		SET aprobada = false, comentario = EXCLUDED.comentario, created_at = NOW()`,
+		f.ID, userID, motivo)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `UPDATE facturas SET estado = 'rechazada', updated_at = NOW() WHERE id = $1`, f.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = registrarAuditoria(ctx, tx, "factura", f.ID, "rechazar", motivo, userID, nil); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetFactura(ctx, f.ID)
+}
+
+// AnularFactura cancels a factura that will not be paid (duplicated,
+// replaced by a credit note...). Paid facturas are voided from tesorería.
+func (s *ProveedorService) AnularFactura(ctx context.Context, id, motivo, userID string) (*models.Factura, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	f, err := lockFactura(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if f.Estado == models.FacturaPagada || f.Estado == models.FacturaAnulada {
+		return nil, ErrFacturaEstado
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE facturas SET estado = 'anulada', motivo_anulacion = $1, updated_at = NOW()
+		WHERE id = $2`, motivo, f.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = registrarAuditoria(ctx, tx, "factura", f.ID, "anulacion", motivo, userID, map[string]interface{}{
+		"estado_anterior": f.Estado,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetFactura(ctx, f.ID)
+}
+
+// PagarFactura marks an approved factura as paid and books its egreso in
+// tesorería. Voiding that egreso puts the factura back to aprobada.
+func (s *ProveedorService) PagarFactura(ctx context.Context, id string, req *models.PagarFacturaRequest, userID string) (*models.Factura, error) {
+	fecha := fechaHoy()
+	if req.Fecha != nil {
+		fecha = *req.Fecha
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	f, err := lockFactura(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if f.Estado != models.FacturaAprobada {
+		return nil, ErrFacturaEstado
+	}
+
+	cuentaID, err := cuentaMovimiento(ctx, tx, req.CuentaID)
+	if err != nil {
+		return nil, err
+	}
+	var proveedor string
+	if err = tx.QueryRow(ctx, `SELECT nombre FROM proveedores WHERE id = $1`, f.ProveedorID).Scan(&proveedor); err != nil {
+		return nil, err
+	}
+
+	comprobante, err := siguienteCorrelativo(ctx, tx, correlativoComprobante)
+	if err != nil {
+		return nil, err
+	}
+	var movimientoID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO movimientos_tesoreria (comprobante, cuenta_id, description, amount, type, category, date, created_by)
+		VALUES ($1, $2, $3, $4, 'egreso', NULLIF($5, ''), $6, $7)
+		RETURNING id`,
+		comprobante, cuentaID, truncar(fmt.Sprintf("Factura N° %s %s: %s", f.Numero, proveedor, f.Descripcion), 255),
+		f.Monto, f.Categoria, fecha, userID).Scan(&movimientoID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE facturas
+		SET estado = 'pagada', movimiento_id = $1, pagada_at = NOW(), pagada_by = $2, updated_at = NOW()
+		WHERE id = $3`, movimientoID, userID, f.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = revisarAlertaPresupuesto(ctx, tx, fecha.Year(), f.Categoria); err != nil {
+		return nil, err
+	}
+
+	err = registrarAuditoria(ctx, tx, "factura", f.ID, "pagar", "", userID, map[string]interface{}{
+		"movimiento_id": movimientoID,
+		"comprobante":   comprobante,
+		"monto":         f.Monto,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetFactura(ctx, f.ID)
+}
+
+// liberarFacturaPagada puts back to aprobada the factura paid by a voided
+// movimiento, so it can be paid again.
+func liberarFacturaPagada(ctx context.Context, tx pgx.Tx, movimientoID string) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE facturas
+		SET estado = 'aprobada', movimiento_id = NULL, pagada_at = NULL, pagada_by = NULL, updated_at = NOW()
+		WHERE movimiento_id = $1`, movimientoID)
+	return err
+}
+
+// ============================================
+// ADJUNTOS
+// ============================================
+
+func (s *ProveedorService) AddAdjunto(ctx context.Context, facturaID string, a *models.AdjuntoFactura, userID string) (*models.AdjuntoFactura, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = lockFactura(ctx, tx, facturaID); err != nil {
+		return nil, err
+	}
+
+	a.FacturaID = facturaID
+	a.Tamano = len(a.Contenido)
+	err = tx.QueryRow(ctx, `
+		INSERT INTO facturas_adjuntos (factura_id, nombre_archivo, content_type, tamano, contenido, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_by, created_at`,
+		facturaID, a.NombreArchivo, a.ContentType, a.Tamano, a.Contenido, userID).Scan(
+		&a.ID, &a.CreatedBy, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = registrarAuditoria(ctx, tx, "factura", facturaID, "adjuntar", "", userID, map[string]interface{}{
+		"adjunto_id": a.ID,
+		"archivo":    a.NombreArchivo,
+		"tamano":     a.Tamano,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *ProveedorService) GetAdjunto(ctx context.Context, facturaID, adjuntoID string) (*models.AdjuntoFactura, error) {
+	var a models.AdjuntoFactura
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT id, factura_id, nombre_archivo, content_type, tamano, contenido, created_by, created_at
+		FROM facturas_adjuntos
+		WHERE id::text = $1 AND factura_id::text = $2`, adjuntoID, facturaID).Scan(
+		&a.ID, &a.FacturaID, &a.NombreArchivo, &a.ContentType, &a.Tamano, &a.Contenido, &a.CreatedBy, &a.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAdjuntoNotFound
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+// ============================================
+// CADENA DE APROBACION
+// ============================================
+
+// aprobacionesRequeridas returns the signatures a factura of monto needs.
+func aprobacionesRequeridas(ctx context.Context, q querier, monto money.Amount) (int, error) {
+	var n int
+	err := q.QueryRow(ctx, `
+		SELECT COALESCE((SELECT aprobaciones FROM reglas_aprobacion_facturas
+		                 WHERE monto_desde <= $1 ORDER BY monto_desde DESC LIMIT 1), 1)`, monto).Scan(&n)
+	return n, err
+}
+
+func (s *ProveedorService) GetReglasAprobacion(ctx context.Context) ([]models.ReglaAprobacion, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT monto_desde, aprobaciones FROM reglas_aprobacion_facturas ORDER BY monto_desde`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reglas := []models.ReglaAprobacion{}
+	for rows.Next() {
+		var r models.ReglaAprobacion
+		if err := rows.Scan(&r.MontoDesde, &r.Aprobaciones); err != nil {
+			return nil, err
+		}
+		reglas = append(reglas, r)
+	}
+	return reglas, rows.Err()
+}
+
+// SetReglasAprobacion replaces the approval chain. Pending facturas take the
+// new requirement; those that already have enough signatures become
+// approved.
+func (s *ProveedorService) SetReglasAprobacion(ctx context.Context, req *models.SetReglasAprobacionRequest, userID string) ([]models.ReglaAprobacion, error) {
+	var directiva int
+	if err := s.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE role = 'directiva'`).Scan(&directiva); err != nil {
+		return nil, err
+	}
+
+	if len(req.Reglas) == 0 {
+		return nil, fmt.Errorf("%w: at least one rule is required", ErrInvalidReglaAprobacion)
+	}
+	montos := map[money.Amount]bool{}
+	for _, r := range req.Reglas {
+		if r.MontoDesde < 0 || r.Aprobaciones < 1 || r.Aprobaciones > 10 || montos[r.MontoDesde] {
+			return nil, fmt.Errorf("%w: monto_desde must be unique and not negative, aprobaciones between 1 and 10", ErrInvalidReglaAprobacion)
+		}
+		if r.Aprobaciones > directiva {
+			return nil, fmt.Errorf("%w: the directiva has only %d members", ErrInvalidReglaAprobacion, directiva)
+		}
+		montos[r.MontoDesde] = true
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, `DELETE FROM reglas_aprobacion_facturas`); err != nil {
+		return nil, err
+	}
+	for _, r := range req.Reglas {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO reglas_aprobacion_facturas (monto_desde, aprobaciones) VALUES ($1, $2)`,
+			r.MontoDesde, r.Aprobaciones)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE facturas f
+		SET aprobaciones_requeridas = COALESCE((SELECT r.aprobaciones FROM reglas_aprobacion_facturas r
+		                                        WHERE r.monto_desde <= f.monto
+		                                        ORDER BY r.monto_desde DESC LIMIT 1), 1),
+		    updated_at = NOW()
+		WHERE f.estado = 'pendiente'`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE facturas f SET estado = 'aprobada', updated_at = NOW()
+		WHERE f.estado = 'pendiente'
+		  AND (SELECT COUNT(*) FROM factura_aprobaciones a WHERE a.factura_id = f.id AND a.aprobada)
+		      >= f.aprobaciones_requeridas`)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = registrarAuditoria(ctx, tx, "reglas_aprobacion_facturas", "1", "actualizar", "", userID, req); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetReglasAprobacion(ctx)
+}
@@ -5,8 +5,12 @@ import (
 	"errors"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
 	"github.com/condominio/backend/internal/database"
 	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/money"
 )
 
 var (
@@ -15,6 +19,12 @@ var (
 	ErrGastoComunNotFound  = errors.New("gasto comun not found")
 	ErrGastoAlreadyPaid    = errors.New("gasto already paid")
 	ErrInvalidPaymentAmount = errors.New("invalid payment amount")
+	ErrIdempotencyKeyReused = errors.New("idempotency key already used for another pago")
+	ErrPagoNotFound         = errors.New("pago not found")
+	ErrPagoNotReversible    = errors.New("only approved pagos can be reversed")
+	ErrParcelaNotFound      = errors.New("parcela not found")
+	ErrCreditoInsuficiente  = errors.New("insufficient credit balance")
+	ErrCreditoYaAplicado    = errors.New("overpayment credit has already been applied to other gastos")
 )
 
 type GastoComunService struct {
@@ -40,10 +50,11 @@ func (s *GastoComunService) ListPeriodos(ctx context.Context, filter models.Peri
 
 	query := `
 		SELECT p.id, p.year, p.month, p.monto_base, p.fecha_vencimiento,
-		       COALESCE(p.descripcion, ''), p.created_at, p.updated_at,
+		       COALESCE(p.descripcion, ''), p.estado, p.moneda, p.monto_base_uf, p.valor_uf, p.fecha_uf,
+		       p.cerrado_at, p.created_at, p.updated_at,
 		       COUNT(g.id) as total_parcelas,
 		       COUNT(g.id) FILTER (WHERE g.status = 'paid') as total_pagados,
-		       COUNT(g.id) FILTER (WHERE g.status IN ('pending', 'overdue')) as total_pendientes,
+		       COUNT(g.id) FILTER (WHERE g.status IN ('pending', 'overdue', 'convenio')) as total_pendientes,
 		       COALESCE(SUM(g.monto_pagado), 0) as monto_recaudado,
 		       COALESCE(SUM(g.monto) - SUM(g.monto_pagado), 0) as monto_pendiente
 		FROM periodos_gasto p
@@ -53,6 +64,11 @@ func (s *GastoComunService) ListPeriodos(ctx context.Context, filter models.Peri
 	args := []interface{}{}
 	argCount := 0
 
+	if !filter.IncluirBorradores {
+		query += ` AND p.estado <> 'borrador'`
+		countQuery += ` AND estado <> 'borrador'`
+	}
+
 	if filter.Year > 0 {
 		argCount++
 		query += ` AND p.year = $` + string(rune('0'+argCount))
@@ -86,12 +102,14 @@ func (s *GastoComunService) ListPeriodos(ctx context.Context, filter models.Peri
 		var p models.PeriodoGasto
 		err := rows.Scan(
 			&p.ID, &p.Year, &p.Month, &p.MontoBase, &p.FechaVencimiento,
-			&p.Descripcion, &p.CreatedAt, &p.UpdatedAt,
+			&p.Descripcion, &p.Estado, &p.Moneda, &p.MontoBaseUF, &p.ValorUF, &p.FechaUF,
+			&p.CerradoAt, &p.CreatedAt, &p.UpdatedAt,
 			&p.TotalParcelas, &p.TotalPagados, &p.TotalPendientes,
 			&p.MontoRecaudado, &p.MontoPendiente)
 		if err != nil {
 			return nil, err
 		}
+		completarUF(&p)
 		periodos = append(periodos, p)
 	}
 
@@ -107,10 +125,11 @@ func (s *GastoComunService) GetPeriodo(ctx context.Context, id string) (*models.
 	var p models.PeriodoGasto
 	err := s.db.Pool.QueryRow(ctx, `
 		SELECT p.id, p.year, p.month, p.monto_base, p.fecha_vencimiento,
-		       COALESCE(p.descripcion, ''), p.created_at, p.updated_at,
+		       COALESCE(p.descripcion, ''), p.estado, p.moneda, p.monto_base_uf, p.valor_uf, p.fecha_uf,
+		       p.cerrado_at, p.created_at, p.updated_at,
 		       COUNT(g.id) as total_parcelas,
 		       COUNT(g.id) FILTER (WHERE g.status = 'paid') as total_pagados,
-		       COUNT(g.id) FILTER (WHERE g.status IN ('pending', 'overdue')) as total_pendientes,
+		       COUNT(g.id) FILTER (WHERE g.status IN ('pending', 'overdue', 'convenio')) as total_pendientes,
 		       COALESCE(SUM(g.monto_pagado), 0) as monto_recaudado,
 		       COALESCE(SUM(g.monto) - SUM(g.monto_pagado), 0) as monto_pendiente
 		FROM periodos_gasto p
@@ -118,12 +137,14 @@ func (s *GastoComunService) GetPeriodo(ctx context.Context, id string) (*models.
 		WHERE p.id = $1
 		GROUP BY p.id`, id).Scan(
 		&p.ID, &p.Year, &p.Month, &p.MontoBase, &p.FechaVencimiento,
-		&p.Descripcion, &p.CreatedAt, &p.UpdatedAt,
+		&p.Descripcion, &p.Estado, &p.Moneda, &p.MontoBaseUF, &p.ValorUF, &p.FechaUF,
+			&p.CerradoAt, &p.CreatedAt, &p.UpdatedAt,
 		&p.TotalParcelas, &p.TotalPagados, &p.TotalPendientes,
 		&p.MontoRecaudado, &p.MontoPendiente)
 	if err != nil {
 		return nil, ErrPeriodoNotFound
 	}
+	completarUF(&p)
 	return &p, nil
 }
 
@@ -132,23 +153,26 @@ func (s *GastoComunService) GetPeriodoActual(ctx context.Context) (*models.Perio
 	var p models.PeriodoGasto
 	err := s.db.Pool.QueryRow(ctx, `
 		SELECT p.id, p.year, p.month, p.monto_base, p.fecha_vencimiento,
-		       COALESCE(p.descripcion, ''), p.created_at, p.updated_at,
+		       COALESCE(p.descripcion, ''), p.estado, p.moneda, p.monto_base_uf, p.valor_uf, p.fecha_uf,
+		       p.cerrado_at, p.created_at, p.updated_at,
 		       COUNT(g.id) as total_parcelas,
 		       COUNT(g.id) FILTER (WHERE g.status = 'paid') as total_pagados,
-		       COUNT(g.id) FILTER (WHERE g.status IN ('pending', 'overdue')) as total_pendientes,
+		       COUNT(g.id) FILTER (WHERE g.status IN ('pending', 'overdue', 'convenio')) as total_pendientes,
 		       COALESCE(SUM(g.monto_pagado), 0) as monto_recaudado,
 		       COALESCE(SUM(g.monto) - SUM(g.monto_pagado), 0) as monto_pendiente
 		FROM periodos_gasto p
 		LEFT JOIN gastos_comunes g ON p.id = g.periodo_id
-		WHERE p.year = $1 AND p.month = $2
+		WHERE p.year = $1 AND p.month = $2 AND p.estado <> 'borrador'
 		GROUP BY p.id`, now.Year(), int(now.Month())).Scan(
 		&p.ID, &p.Year, &p.Month, &p.MontoBase, &p.FechaVencimiento,
-		&p.Descripcion, &p.CreatedAt, &p.UpdatedAt,
+		&p.Descripcion, &p.Estado, &p.Moneda, &p.MontoBaseUF, &p.ValorUF, &p.FechaUF,
+			&p.CerradoAt, &p.CreatedAt, &p.UpdatedAt,
 		&p.TotalParcelas, &p.TotalPagados, &p.TotalPendientes,
 		&p.MontoRecaudado, &p.MontoPendiente)
 	if err != nil {
 		return nil, ErrPeriodoNotFound
 	}
+	completarUF(&p)
 	return &p, nil
 }
 
@@ -175,33 +199,83 @@ func (s *GastoComunService) CreatePeriodo(ctx context.Context, req *models.Creat
 		return nil, ErrPeriodoExists
 	}
 
-	// Create periodo
+	// Create periodo; drafts get their gastos when they are opened
+	estado := models.PeriodoAbierto
+	if req.Borrador {
+		estado = models.PeriodoBorrador
+	}
+
+	// UF amounts are converted with the value of the issue date
+	monto := req.MontoBase
+	var uf *conversionUF
+	if req.MontoBaseUF > 0 {
+		uf, err = convertirUF(ctx, tx, req.MontoBaseUF, fechaHoy(), estado == models.PeriodoAbierto)
+		if err != nil {
+			return nil, err
+		}
+		monto = uf.clp
+	}
+
 	var periodoID string
 	err = tx.QueryRow(ctx, `
-		INSERT INTO periodos_gasto (year, month, monto_base, fecha_vencimiento, descripcion)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO periodos_gasto (year, month, monto_base, fecha_vencimiento, descripcion, estado)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id`,
-		req.Year, req.Month, req.MontoBase, fechaVenc, req.Descripcion).Scan(&periodoID)
+		req.Year, req.Month, monto, fechaVenc, req.Descripcion, estado).Scan(&periodoID)
 	if err != nil {
 		return nil, err
 	}
+	if err = guardarMonedaPeriodo(ctx, tx, periodoID, uf); err != nil {
+		return nil, err
+	}
 
-	// Generate gastos_comunes for all parcelas
-	_, err = tx.Exec(ctx, `
+	if estado == models.PeriodoAbierto {
+		if err = generarGastosPeriodo(ctx, tx, periodoID, req.Year, req.Month, monto); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.GetPeriodo(ctx, periodoID)
+}
+
+// guardarMonedaPeriodo records the currency a periodo was defined in; a nil
+// conversion means plain CLP.
+func guardarMonedaPeriodo(ctx context.Context, q querier, periodoID string, uf *conversionUF) error {
+	if uf == nil {
+		_, err := q.Exec(ctx, `
+			UPDATE periodos_gasto SET moneda = 'CLP', monto_base_uf = NULL, valor_uf = NULL, fecha_uf = NULL
+			WHERE id = $1`, periodoID)
+		return err
+	}
+	_, err := q.Exec(ctx, `
+		UPDATE periodos_gasto SET moneda = 'UF', monto_base = $1, monto_base_uf = $2, valor_uf = $3, fecha_uf = $4
+		WHERE id = $5`, uf.clp, uf.montoUF, uf.valor, uf.fecha, periodoID)
+	return err
+}
+
+// generarGastosPeriodo creates the gasto of every parcela for an open periodo,
+// applies available credit and links convenio cuotas due in the month.
+func generarGastosPeriodo(ctx context.Context, tx pgx.Tx, periodoID string, year, month int, montoBase money.Amount) error {
+	_, err := tx.Exec(ctx, `
 		INSERT INTO gastos_comunes (periodo_id, parcela_id, user_id, monto, status)
 		SELECT $1, p.id, u.id, $2, 'pending'
 		FROM parcelas p
 		LEFT JOIN users u ON u.parcela_id = p.id AND u.role IN ('vecino', 'directiva')`,
-		periodoID, req.MontoBase)
+		periodoID, montoBase)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if err = tx.Commit(ctx); err != nil {
-		return nil, err
+	// Prepayments and overpayments are applied to the new gastos right away
+	if err = aplicarCreditosPeriodo(ctx, tx, periodoID); err != nil {
+		return err
 	}
 
-	return s.GetPeriodo(ctx, periodoID)
+	return vincularCuotasPeriodo(ctx, tx, periodoID, year, month)
 }
 
 func (s *GastoComunService) UpdatePeriodo(ctx context.Context, id string, req *models.UpdatePeriodoRequest) (*models.PeriodoGasto, error) {
@@ -210,9 +284,35 @@ func (s *GastoComunService) UpdatePeriodo(ctx context.Context, id string, req *m
 		return nil, err
 	}
 
+	switch current.Estado {
+	case models.PeriodoCerrado:
+		return nil, ErrPeriodoCerrado
+	case models.PeriodoAbierto:
+		// Amounts are frozen once residents can pay them
+		if req.MontoBase != nil && *req.MontoBase != current.MontoBase {
+			return nil, ErrPeriodoMontoCongelado
+		}
+		if req.MontoBaseUF != nil && (current.MontoBaseUF == nil || *req.MontoBaseUF != *current.MontoBaseUF) {
+			return nil, ErrPeriodoMontoCongelado
+		}
+	}
+
+	// A draft switches currency with the amount it is given
+	var uf *conversionUF
+	cambiaMoneda := current.Estado == models.PeriodoBorrador && (req.MontoBase != nil || req.MontoBaseUF != nil)
 	if req.MontoBase != nil {
 		current.MontoBase = *req.MontoBase
 	}
+	if cambiaMoneda && req.MontoBaseUF != nil {
+		if *req.MontoBaseUF <= 0 {
+			return nil, ErrInvalidValorUF
+		}
+		uf, err = convertirUF(ctx, s.db.Pool, *req.MontoBaseUF, fechaHoy(), false)
+		if err != nil {
+			return nil, err
+		}
+		current.MontoBase = uf.clp
+	}
 	if req.FechaVencimiento != nil {
 		fechaVenc, err := time.Parse("2006-01-02", *req.FechaVencimiento)
 		if err != nil {
@@ -224,14 +324,23 @@ func (s *GastoComunService) UpdatePeriodo(ctx context.Context, id string, req *m
 		current.Descripcion = *req.Descripcion
 	}
 
-	_, err = s.db.Pool.Exec(ctx, `
+	// The estado guard keeps a concurrent close from being overwritten
+	result, err := s.db.Pool.Exec(ctx, `
 		UPDATE periodos_gasto
 		SET monto_base = $1, fecha_vencimiento = $2, descripcion = $3, updated_at = NOW()
-		WHERE id = $4`,
-		current.MontoBase, current.FechaVencimiento, current.Descripcion, id)
+		WHERE id = $4 AND estado = $5`,
+		current.MontoBase, current.FechaVencimiento, current.Descripcion, id, current.Estado)
 	if err != nil {
 		return nil, err
 	}
+	if result.RowsAffected() == 0 {
+		return nil, ErrPeriodoCerrado
+	}
+	if cambiaMoneda {
+		if err = guardarMonedaPeriodo(ctx, s.db.Pool, id, uf); err != nil {
+			return nil, err
+		}
+	}
 
 	return s.GetPeriodo(ctx, id)
 }
@@ -250,13 +359,13 @@ func (s *GastoComunService) GetResumen(ctx context.Context, periodoID string) (*
 		return nil, err
 	}
 
-	montoTotal := float64(periodo.TotalParcelas) * periodo.MontoBase
+	montoTotal := periodo.MontoBase.Mul(periodo.TotalParcelas)
 	porcentaje := float64(0)
 	if montoTotal > 0 {
-		porcentaje = (periodo.MontoRecaudado / montoTotal) * 100
+		porcentaje = (periodo.MontoRecaudado.Float64() / montoTotal.Float64()) * 100
 	}
 
-	return &models.ResumenGastos{
+	resumen := &models.ResumenGastos{
 		Periodo:           *periodo,
 		TotalParcelas:     periodo.TotalParcelas,
 		TotalPagados:      periodo.TotalPagados,
@@ -266,7 +375,12 @@ func (s *GastoComunService) GetResumen(ctx context.Context, periodoID string) (*
 		MontoRecaudado:    periodo.MontoRecaudado,
 		MontoPendiente:    periodo.MontoPendiente,
 		PorcentajeRecaudo: porcentaje,
-	}, nil
+	}
+	if periodo.ValorUF != nil && *periodo.ValorUF > 0 {
+		totalUF := enUF(montoTotal, *periodo.ValorUF)
+		resumen.MontoTotalUF = &totalUF
+	}
+	return resumen, nil
 }
 
 // ============================================
@@ -416,7 +530,7 @@ func (s *GastoComunService) GetMiEstadoCuenta(ctx context.Context, userID string
 		FROM gastos_comunes g
 		JOIN parcelas p ON g.parcela_id = p.id
 		JOIN periodos_gasto pg ON g.periodo_id = pg.id
-		WHERE g.parcela_id = $1 AND g.status IN ('pending', 'overdue')
+		WHERE g.parcela_id = $1 AND g.status IN ('pending', 'overdue', 'convenio')
 		ORDER BY pg.year DESC, pg.month DESC`, parcelaID)
 	if err != nil {
 		return nil, err
@@ -424,7 +538,7 @@ func (s *GastoComunService) GetMiEstadoCuenta(ctx context.Context, userID string
 	defer rowsPendientes.Close()
 
 	gastosPendientes := []models.GastoComun{}
-	var totalPendiente float64
+	var totalPendiente money.Amount
 	for rowsPendientes.Next() {
 		var g models.GastoComun
 		var year, month int
@@ -463,7 +577,7 @@ func (s *GastoComunService) GetMiEstadoCuenta(ctx context.Context, userID string
 	defer rowsPagados.Close()
 
 	gastosPagados := []models.GastoComun{}
-	var totalPagado float64
+	var totalPagado money.Amount
 	for rowsPagados.Next() {
 		var g models.GastoComun
 		var year, month int
@@ -482,6 +596,11 @@ func (s *GastoComunService) GetMiEstadoCuenta(ctx context.Context, userID string
 		totalPagado += g.MontoPagado
 	}
 
+	saldo, err := saldoCredito(ctx, s.db.Pool, parcelaID)
+	if err != nil {
+		return nil, err
+	}
+
 	return &models.MiEstadoCuenta{
 		HasParcela:       true,
 		ParcelaID:        parcelaID,
@@ -490,61 +609,346 @@ func (s *GastoComunService) GetMiEstadoCuenta(ctx context.Context, userID string
 		GastosPagados:    gastosPagados,
 		TotalPendiente:   totalPendiente,
 		TotalPagado:      totalPagado,
+		SaldoCredito:     saldo,
 	}, nil
 }
 
-func (s *GastoComunService) RegistrarPago(ctx context.Context, gastoID string, req *models.RegistrarPagoRequest) (*models.GastoComun, error) {
-	gasto, err := s.GetGasto(ctx, gastoID)
+// RegistrarPago registers an approved pago against a gasto. The gasto row is
+// locked for the duration of the transaction and monto_pagado is recomputed from
+// the pagos ledger, so concurrent payments can neither overwrite each other nor
+// overpay. Any amount above the pending balance is kept as credit in favour of
+// the parcela. When idempotencyKey is set, a retried request returns the gasto
+// as it was left by the original payment instead of charging twice.
+func (s *GastoComunService) RegistrarPago(ctx context.Context, gastoID string, req *models.RegistrarPagoRequest, idempotencyKey string) (*models.GastoComun, error) {
+	if req.Monto <= 0 {
+		return nil, ErrInvalidPaymentAmount
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback(ctx)
 
-	if gasto.Status == models.PagoStatusPaid {
-		return nil, ErrGastoAlreadyPaid
+	if _, err = registrarPago(ctx, tx, gastoID, req, idempotencyKey); err != nil {
+		return nil, err
 	}
 
-	pendiente := gasto.Monto - gasto.MontoPagado
-	if req.Monto <= 0 || req.Monto > pendiente {
-		return nil, ErrInvalidPaymentAmount
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.GetGasto(ctx, gastoID)
+}
+
+// registrarPago registers an approved pago inside tx and returns its id. A
+// replayed idempotency key returns the original pago without changes.
+func registrarPago(ctx context.Context, tx pgx.Tx, gastoID string, req *models.RegistrarPagoRequest, idempotencyKey string) (string, error) {
+	// Lock the gasto row: concurrent payments on the same gasto are serialized here.
+	// The share lock on the periodo keeps it from being closed meanwhile.
+	var monto money.Amount
+	var parcelaID int
+	var status models.PagoStatus
+	var estadoPeriodo models.PeriodoEstado
+	err := tx.QueryRow(ctx, `
+		SELECT g.monto, g.parcela_id, g.status, p.estado
+		FROM gastos_comunes g
+		JOIN periodos_gasto p ON g.periodo_id = p.id
+		WHERE g.id = $1
+		FOR UPDATE OF g FOR SHARE OF p`,
+		gastoID).Scan(&monto, &parcelaID, &status, &estadoPeriodo)
+	if err != nil {
+		return "", ErrGastoComunNotFound
+	}
+
+	// Replayed request: the lock above guarantees the original pago is visible
+	if idempotencyKey != "" {
+		var existingID, existingGastoID string
+		err = tx.QueryRow(ctx, `SELECT id, gasto_comun_id FROM pagos WHERE idempotency_key = $1`,
+			idempotencyKey).Scan(&existingID, &existingGastoID)
+		if err == nil {
+			if existingGastoID != gastoID {
+				return "", ErrIdempotencyKeyReused
+			}
+			return existingID, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return "", err
+		}
+	}
+
+	if err = admitePago(estadoPeriodo, status); err != nil {
+		return "", err
+	}
+
+	montoPagado, err := sumPagosAprobados(ctx, tx, gastoID)
+	if err != nil {
+		return "", err
+	}
+
+	// Overpayment: the excess becomes credit in favour of the parcela
+	aplicado := req.Monto
+	var excedente money.Amount
+	if pendiente := monto - montoPagado; req.Monto > pendiente {
+		aplicado = pendiente
+		excedente = req.Monto - pendiente
+	}
+
+	// Register payment
+	var pagoID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO pagos (gasto_comun_id, monto, metodo, referencia_externa, estado, idempotency_key)
+		VALUES ($1, $2, $3, $4, 'approved', NULLIF($5, ''))
+		RETURNING id`,
+		gastoID, aplicado, req.Metodo, req.ReferenciaExterna, idempotencyKey).Scan(&pagoID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+			return "", ErrIdempotencyKeyReused
+		}
+		return "", err
 	}
 
+	if err = asignarFondoReserva(ctx, tx, pagoID, aplicado); err != nil {
+		return "", err
+	}
+
+	if excedente > 0 {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO creditos_parcela (parcela_id, monto, tipo, pago_id, gasto_comun_id, descripcion)
+			VALUES ($1, $2, 'sobrepago', $3, $4, 'Excedente de pago')`,
+			parcelaID, excedente, pagoID, gastoID)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if err = emitirRecibo(ctx, tx, pagoID); err != nil {
+		return "", err
+	}
+	if err = contabilizarPago(ctx, tx, pagoID); err != nil {
+		return "", err
+	}
+
+	if err = recalcularGasto(ctx, tx, gastoID, req.Metodo, req.ReferenciaExterna); err != nil {
+		return "", err
+	}
+
+	return pagoID, nil
+}
+
+// ListPagos returns every pago registered for a gasto, including reversed ones.
+func (s *GastoComunService) ListPagos(ctx context.Context, gastoID string) ([]models.Pago, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT id, gasto_comun_id, monto, metodo, COALESCE(referencia_externa, ''), estado,
+		       COALESCE(detalles::text, ''), reversed_at, reversed_by, COALESCE(motivo_reverso, ''),
+		       created_at
+		FROM pagos
+		WHERE gasto_comun_id = $1
+		ORDER BY created_at`, gastoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pagos := []models.Pago{}
+	for rows.Next() {
+		var p models.Pago
+		err := rows.Scan(&p.ID, &p.GastoComunID, &p.Monto, &p.Metodo, &p.ReferenciaExterna, &p.Estado,
+			&p.Detalles, &p.ReversedAt, &p.ReversedBy, &p.MotivoReverso,
+			&p.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		pagos = append(pagos, p)
+	}
+	return pagos, nil
+}
+
+// ReversarPago reverses an approved pago and reopens its gasto. Pagos funded
+// from credit give that credit back; credit created by an overpayment is
+// withdrawn, which fails if it has already been used on another gasto.
+func (s *GastoComunService) ReversarPago(ctx context.Context, pagoID string, motivo string, userID string) (*models.GastoComun, error) {
 	tx, err := s.db.Pool.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback(ctx)
 
-	// Register payment
-	_, err = tx.Exec(ctx, `
-		INSERT INTO pagos (gasto_comun_id, monto, metodo, referencia_externa, estado)
-		VALUES ($1, $2, $3, $4, 'approved')`,
-		gastoID, req.Monto, req.Metodo, req.ReferenciaExterna)
+	var gastoID string
+	err = tx.QueryRow(ctx, `SELECT gasto_comun_id FROM pagos WHERE id = $1`, pagoID).Scan(&gastoID)
+	if err != nil {
+		return nil, ErrPagoNotFound
+	}
+
+	// Same lock order as RegistrarPago: gasto, then parcela credit
+	var parcelaID int
+	err = tx.QueryRow(ctx, `SELECT parcela_id FROM gastos_comunes WHERE id = $1 FOR UPDATE`,
+		gastoID).Scan(&parcelaID)
 	if err != nil {
+		return nil, ErrGastoComunNotFound
+	}
+	if err = lockCreditoParcela(ctx, tx, parcelaID); err != nil {
 		return nil, err
 	}
 
-	// Update gasto_comun
-	newMontoPagado := gasto.MontoPagado + req.Monto
-	newStatus := models.PagoStatusPending
-	var fechaPago *time.Time
-	var metodoPago, referenciaPago string
+	var monto money.Amount
+	var metodo, estado string
+	err = tx.QueryRow(ctx, `SELECT monto, metodo, estado FROM pagos WHERE id = $1 FOR UPDATE`,
+		pagoID).Scan(&monto, &metodo, &estado)
+	if err != nil {
+		return nil, ErrPagoNotFound
+	}
+	if estado != models.PagoEstadoApproved {
+		return nil, ErrPagoNotReversible
+	}
+
+	if metodo == models.MetodoPagoCredito {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO creditos_parcela (parcela_id, monto, tipo, pago_id, gasto_comun_id, descripcion, created_by)
+			VALUES ($1, $2, 'reverso', $3, $4, 'Reverso de aplicacion de saldo a favor', $5)`,
+			parcelaID, monto, pagoID, gastoID, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	if newMontoPagado >= gasto.Monto {
-		newStatus = models.PagoStatusPaid
-		now := time.Now()
-		fechaPago = &now
-		metodoPago = req.Metodo
-		referenciaPago = req.ReferenciaExterna
+	var excedente money.Amount
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(monto), 0) FROM creditos_parcela
+		WHERE pago_id = $1 AND tipo = 'sobrepago'`, pagoID).Scan(&excedente)
+	if err != nil {
+		return nil, err
+	}
+	if excedente > 0 {
+		saldo, err := saldoCredito(ctx, tx, parcelaID)
+		if err != nil {
+			return nil, err
+		}
+		if saldo < excedente {
+			return nil, ErrCreditoYaAplicado
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO creditos_parcela (parcela_id, monto, tipo, pago_id, gasto_comun_id, descripcion, created_by)
+			VALUES ($1, $2, 'reverso', $3, $4, 'Reverso de excedente de pago', $5)`,
+			parcelaID, -excedente, pagoID, gastoID, userID)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	_, err = tx.Exec(ctx, `
-		UPDATE gastos_comunes
-		SET monto_pagado = $1, status = $2, fecha_pago = $3, metodo_pago = $4, referencia_pago = $5, updated_at = NOW()
-		WHERE id = $6`,
-		newMontoPagado, newStatus, fechaPago, metodoPago, referenciaPago, gastoID)
+		UPDATE pagos
+		SET estado = 'reversed', reversed_at = NOW(), reversed_by = $1, motivo_reverso = $2
+		WHERE id = $3`, userID, motivo, pagoID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The receipt keeps its number but is marked as void
+	_, err = tx.Exec(ctx, `
+		UPDATE recibos SET anulado_at = NOW(), motivo_anulacion = $1 WHERE pago_id = $2`,
+		motivo, pagoID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = revertirFondoReserva(ctx, tx, pagoID, motivo, userID); err != nil {
+		return nil, err
+	}
+	if err = contabilizarReversoPago(ctx, tx, pagoID, motivo, userID); err != nil {
+		return nil, err
+	}
+
+	if err = recalcularGasto(ctx, tx, gastoID, "", ""); err != nil {
+		return nil, err
+	}
+
+	err = registrarAuditoria(ctx, tx, "pago", pagoID, "reverso", motivo, userID, map[string]interface{}{
+		"gasto_comun_id": gastoID,
+		"monto":          monto,
+		"metodo":         metodo,
+		"excedente":      excedente,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.GetGasto(ctx, gastoID)
+}
+
+// ============================================
+// SALDO A FAVOR (CREDITO)
+// ============================================
+
+func (s *GastoComunService) GetSaldoCredito(ctx context.Context, parcelaID int) (*models.SaldoCredito, error) {
+	saldo := &models.SaldoCredito{ParcelaID: parcelaID, Movimientos: []models.MovimientoCredito{}}
+	err := s.db.Pool.QueryRow(ctx, `SELECT numero FROM parcelas WHERE id = $1`, parcelaID).Scan(&saldo.ParcelaNumero)
+	if err != nil {
+		return nil, ErrParcelaNotFound
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT id, parcela_id, monto, tipo, pago_id, gasto_comun_id, COALESCE(descripcion, ''),
+		       created_by, created_at
+		FROM creditos_parcela
+		WHERE parcela_id = $1
+		ORDER BY created_at DESC`, parcelaID)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.MovimientoCredito
+		err := rows.Scan(&m.ID, &m.ParcelaID, &m.Monto, &m.Tipo, &m.PagoID, &m.GastoComunID, &m.Descripcion,
+			&m.CreatedBy, &m.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		saldo.Saldo += m.Monto
+		saldo.Movimientos = append(saldo.Movimientos, m)
+	}
+
+	return saldo, nil
+}
+
+// AplicarCredito pays the pending balance of a gasto with the parcela's credit.
+func (s *GastoComunService) AplicarCredito(ctx context.Context, gastoID string, userID string) (*models.GastoComun, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var parcelaID int
+	var status models.PagoStatus
+	var estadoPeriodo models.PeriodoEstado
+	err = tx.QueryRow(ctx, `
+		SELECT g.parcela_id, g.status, p.estado
+		FROM gastos_comunes g
+		JOIN periodos_gasto p ON g.periodo_id = p.id
+		WHERE g.id = $1
+		FOR UPDATE OF g FOR SHARE OF p`,
+		gastoID).Scan(&parcelaID, &status, &estadoPeriodo)
+	if err != nil {
+		return nil, ErrGastoComunNotFound
+	}
+	if err = admitePago(estadoPeriodo, status); err != nil {
+		return nil, err
+	}
+
+	aplicado, err := aplicarCredito(ctx, tx, gastoID, parcelaID, &userID)
+	if err != nil {
+		return nil, err
+	}
+	if aplicado == 0 {
+		return nil, ErrCreditoInsuficiente
+	}
 
 	if err = tx.Commit(ctx); err != nil {
 		return nil, err
@@ -553,6 +957,273 @@ func (s *GastoComunService) RegistrarPago(ctx context.Context, gastoID string, r
 	return s.GetGasto(ctx, gastoID)
 }
 
+// ReembolsarCredito returns part of a parcela's credit to the neighbour and
+// posts the egreso to tesorería.
+func (s *GastoComunService) ReembolsarCredito(ctx context.Context, parcelaID int, req *models.ReembolsoCreditoRequest, userID string) (*models.SaldoCredito, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockCreditoParcela(ctx, tx, parcelaID); err != nil {
+		return nil, err
+	}
+
+	saldo, err := saldoCredito(ctx, tx, parcelaID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Monto > saldo {
+		return nil, ErrCreditoInsuficiente
+	}
+
+	var creditoID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO creditos_parcela (parcela_id, monto, tipo, descripcion, created_by)
+		VALUES ($1, $2, 'reembolso', $3, $4)
+		RETURNING id`,
+		parcelaID, -req.Monto, "Reembolso ("+req.Metodo+")", userID).Scan(&creditoID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = contabilizarReembolso(ctx, tx, creditoID, req.Monto, req.Metodo, userID); err != nil {
+		return nil, err
+	}
+
+	err = registrarAuditoria(ctx, tx, "credito", creditoID, "reembolso", req.Motivo, userID, map[string]interface{}{
+		"parcela_id": parcelaID,
+		"monto":      req.Monto,
+		"metodo":     req.Metodo,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.GetSaldoCredito(ctx, parcelaID)
+}
+
+// aplicarCreditosPeriodo uses available credit to pay the gastos of a newly
+// generated periodo.
+func aplicarCreditosPeriodo(ctx context.Context, tx pgx.Tx, periodoID string) error {
+	rows, err := tx.Query(ctx, `
+		SELECT g.id, g.parcela_id
+		FROM gastos_comunes g
+		WHERE g.periodo_id = $1
+		  AND g.status IN ('pending', 'overdue')
+		  AND (SELECT COALESCE(SUM(c.monto), 0) FROM creditos_parcela c WHERE c.parcela_id = g.parcela_id) > 0`,
+		periodoID)
+	if err != nil {
+		return err
+	}
+
+	type gastoConCredito struct {
+		gastoID   string
+		parcelaID int
+	}
+	gastos := []gastoConCredito{}
+	for rows.Next() {
+		var g gastoConCredito
+		if err := rows.Scan(&g.gastoID, &g.parcelaID); err != nil {
+			rows.Close()
+			return err
+		}
+		gastos = append(gastos, g)
+	}
+	rows.Close()
+
+	for _, g := range gastos {
+		if _, err := aplicarCredito(ctx, tx, g.gastoID, g.parcelaID, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aplicarCredito pays as much of the gasto's pending balance as the parcela's
+// credit allows, returning the amount applied. The caller must hold the gasto
+// row lock.
+func aplicarCredito(ctx context.Context, tx pgx.Tx, gastoID string, parcelaID int, createdBy *string) (money.Amount, error) {
+	if err := lockCreditoParcela(ctx, tx, parcelaID); err != nil {
+		return 0, err
+	}
+
+	saldo, err := saldoCredito(ctx, tx, parcelaID)
+	if err != nil || saldo <= 0 {
+		return 0, err
+	}
+
+	var monto money.Amount
+	if err := tx.QueryRow(ctx, `SELECT monto FROM gastos_comunes WHERE id = $1`, gastoID).Scan(&monto); err != nil {
+		return 0, err
+	}
+	montoPagado, err := sumPagosAprobados(ctx, tx, gastoID)
+	if err != nil {
+		return 0, err
+	}
+
+	aplicar := min(saldo, monto-montoPagado)
+	if aplicar <= 0 {
+		return 0, nil
+	}
+
+	var pagoID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO pagos (gasto_comun_id, monto, metodo, estado)
+		VALUES ($1, $2, 'credito', 'approved')
+		RETURNING id`, gastoID, aplicar).Scan(&pagoID)
+	if err != nil {
+		return 0, err
+	}
+	if err = asignarFondoReserva(ctx, tx, pagoID, aplicar); err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO creditos_parcela (parcela_id, monto, tipo, pago_id, gasto_comun_id, descripcion, created_by)
+		VALUES ($1, $2, 'aplicacion', $3, $4, 'Aplicacion de saldo a favor', $5)`,
+		parcelaID, -aplicar, pagoID, gastoID, createdBy)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := recalcularGasto(ctx, tx, gastoID, models.MetodoPagoCredito, ""); err != nil {
+		return 0, err
+	}
+	return aplicar, nil
+}
+
+// lockCreditoParcela serializes changes to a parcela's credit balance.
+func lockCreditoParcela(ctx context.Context, tx pgx.Tx, parcelaID int) error {
+	var id int
+	err := tx.QueryRow(ctx, `SELECT id FROM parcelas WHERE id = $1 FOR UPDATE`, parcelaID).Scan(&id)
+	if err != nil {
+		return ErrParcelaNotFound
+	}
+	return nil
+}
+
+func saldoCredito(ctx context.Context, q querier, parcelaID int) (money.Amount, error) {
+	var saldo money.Amount
+	err := q.QueryRow(ctx, `
+		SELECT COALESCE(SUM(monto), 0) FROM creditos_parcela WHERE parcela_id = $1`, parcelaID).Scan(&saldo)
+	return saldo, err
+}
+
+// sumPagosAprobados returns the total of approved pagos for a gasto, which is
+// the source of truth for gastos_comunes.monto_pagado.
+func sumPagosAprobados(ctx context.Context, q querier, gastoID string) (money.Amount, error) {
+	var total money.Amount
+	err := q.QueryRow(ctx, `
+		SELECT COALESCE(SUM(monto), 0) FROM pagos
+		WHERE gasto_comun_id = $1 AND estado = 'approved'`, gastoID).Scan(&total)
+	return total, err
+}
+
+// recalcularGasto recomputes monto_pagado and status of a gasto from its pagos
+// ledger. metodo/referencia are recorded when the gasto becomes paid; a gasto
+// that is no longer fully paid goes back to pending, overdue or convenio.
+func recalcularGasto(ctx context.Context, tx pgx.Tx, gastoID string, metodo, referencia string) error {
+	var monto money.Amount
+	var status models.PagoStatus
+	var fechaVencimiento time.Time
+	err := tx.QueryRow(ctx, `
+		SELECT g.monto, g.status, p.fecha_vencimiento
+		FROM gastos_comunes g
+		JOIN periodos_gasto p ON g.periodo_id = p.id
+		WHERE g.id = $1`, gastoID).Scan(&monto, &status, &fechaVencimiento)
+	if err != nil {
+		return err
+	}
+
+	montoPagado, err := sumPagosAprobados(ctx, tx, gastoID)
+	if err != nil {
+		return err
+	}
+
+	if montoPagado >= monto {
+		if status == models.PagoStatusPaid {
+			_, err = tx.Exec(ctx, `
+				UPDATE gastos_comunes SET monto_pagado = $1, updated_at = NOW() WHERE id = $2`,
+				montoPagado, gastoID)
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE gastos_comunes
+			SET monto_pagado = $1, status = 'paid', fecha_pago = NOW(), metodo_pago = $2, referencia_pago = $3, updated_at = NOW()
+			WHERE id = $4`,
+			montoPagado, metodo, referencia, gastoID)
+		return err
+	}
+
+	newStatus := status
+	if status == models.PagoStatusPaid {
+		newStatus = models.PagoStatusPending
+		if fechaVencimiento.Before(time.Now()) {
+			newStatus = models.PagoStatusOverdue
+		}
+		// A gasto inside an active convenio stays under the plan
+		var enConvenio bool
+		err = tx.QueryRow(ctx, `
+			SELECT EXISTS(
+				SELECT 1 FROM convenio_gastos cg
+				JOIN convenios_pago c ON cg.convenio_id = c.id
+				WHERE cg.gasto_comun_id = $1 AND c.estado = 'activo')`, gastoID).Scan(&enConvenio)
+		if err != nil {
+			return err
+		}
+		if enConvenio {
+			newStatus = models.PagoStatusConvenio
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE gastos_comunes
+		SET monto_pagado = $1, status = $2, fecha_pago = NULL, metodo_pago = NULL, referencia_pago = NULL, updated_at = NOW()
+		WHERE id = $3`,
+		montoPagado, newStatus, gastoID)
+	return err
+}
+
+// VerificarConsistencia reports every gasto whose monto_pagado disagrees with
+// the sum of its approved pagos.
+func (s *GastoComunService) VerificarConsistencia(ctx context.Context) ([]models.InconsistenciaPago, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT g.id, g.periodo_id, pg.year, pg.month, g.parcela_id, p.numero,
+		       g.status, g.monto, g.monto_pagado,
+		       COALESCE(SUM(pa.monto) FILTER (WHERE pa.estado = 'approved'), 0) as monto_ledger
+		FROM gastos_comunes g
+		JOIN parcelas p ON g.parcela_id = p.id
+		JOIN periodos_gasto pg ON g.periodo_id = pg.id
+		LEFT JOIN pagos pa ON pa.gasto_comun_id = g.id
+		GROUP BY g.id, pg.year, pg.month, p.numero
+		HAVING g.monto_pagado <> COALESCE(SUM(pa.monto) FILTER (WHERE pa.estado = 'approved'), 0)
+		ORDER BY pg.year, pg.month, p.numero::int`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inconsistencias := []models.InconsistenciaPago{}
+	for rows.Next() {
+		var i models.InconsistenciaPago
+		err := rows.Scan(
+			&i.GastoComunID, &i.PeriodoID, &i.Year, &i.Month, &i.ParcelaID, &i.ParcelaNumero,
+			&i.Status, &i.Monto, &i.MontoPagado, &i.MontoLedger)
+		if err != nil {
+			return nil, err
+		}
+		i.Diferencia = i.MontoPagado - i.MontoLedger
+		inconsistencias = append(inconsistencias, i)
+	}
+	return inconsistencias, rows.Err()
+}
+
 func (s *GastoComunService) MarcarVencidos(ctx context.Context) (int, error) {
 	result, err := s.db.Pool.Exec(ctx, `
 		UPDATE gastos_comunes g
@@ -0,0 +1,878 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/condominio/backend/internal/database"
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/money"
+)
+
+var (
+	ErrFormatoNotFound       = errors.New("import format not found")
+	ErrInvalidFormato        = errors.New("invalid import format")
+	ErrFormatoExists         = errors.New("import format name already exists")
+	ErrArchivoInvalido       = errors.New("invalid bank file")
+	ErrImportacionNotFound   = errors.New("import not found")
+	ErrImportacionNoStaging  = errors.New("import is no longer in staging")
+	ErrImportacionIncompleta = errors.New("import has rows without parcela")
+	ErrFilaNotFound          = errors.New("import row not found")
+	ErrFilaNoEditable        = errors.New("import row cannot be changed")
+	ErrFilaGastoInvalido     = errors.New("gasto does not belong to the parcela or has no balance")
+	ErrFilaSinGasto          = errors.New("parcela has no gasto with balance")
+	ErrFilaYaImportada       = errors.New("bank movement already imported")
+)
+
+// Upper bound of rows per file; weekly exports are a few hundred at most.
+const maxFilasImportacion = 5000
+
+type ImportacionBancoService struct {
+	db *database.DB
+}
+
+func NewImportacionBancoService(db *database.DB) *ImportacionBancoService {
+	return &ImportacionBancoService{db: db}
+}
+
+// ============================================
+// FORMATOS CSV
+// ============================================
+
+const selectFormato = `
+	SELECT id, nombre, separador, tiene_encabezado, filas_omitir, columna_fecha, columna_monto,
+	       columna_rut, columna_nombre, columna_descripcion, columna_referencia, formato_fecha,
+	       separador_decimal, created_at, updated_at
+	FROM formatos_importacion`
+
+func scanFormato(row pgx.Row) (*models.FormatoImportacion, error) {
+	var f models.FormatoImportacion
+	err := row.Scan(&f.ID, &f.Nombre, &f.Separador, &f.TieneEncabezado, &f.FilasOmitir, &f.ColumnaFecha,
+		&f.ColumnaMonto, &f.ColumnaRUT, &f.ColumnaNombre, &f.ColumnaDesc, &f.ColumnaReferencia,
+		&f.FormatoFecha, &f.SeparadorDecimal, &f.CreatedAt, &f.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (s *ImportacionBancoService) ListFormatos(ctx context.Context) ([]models.FormatoImportacion, error) {
+	rows, err := s.db.Pool.Query(ctx, selectFormato+` ORDER BY nombre`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	formatos := []models.FormatoImportacion{}
+	for rows.Next() {
+		f, err := scanFormato(rows)
+		if err != nil {
+			return nil, err
+		}
+		formatos = append(formatos, *f)
+	}
+	return formatos, rows.Err()
+}
+
+func (s *ImportacionBancoService) GetFormato(ctx context.Context, id string) (*models.FormatoImportacion, error) {
+	f, err := scanFormato(s.db.Pool.QueryRow(ctx, selectFormato+` WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrFormatoNotFound
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+func (s *ImportacionBancoService) CreateFormato(ctx context.Context, req *models.FormatoImportacionRequest) (*models.FormatoImportacion, error) {
+	if err := validarFormato(req); err != nil {
+		return nil, err
+	}
+
+	var id string
+	err := s.db.Pool.QueryRow(ctx, `
+		INSERT INTO formatos_importacion (nombre, separador, tiene_encabezado, filas_omitir, columna_fecha,
+		                                  columna_monto, columna_rut, columna_nombre, columna_descripcion,
+		                                  columna_referencia, formato_fecha, separador_decimal)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id`,
+		req.Nombre, req.Separador, req.TieneEncabezado, req.FilasOmitir, req.ColumnaFecha, req.ColumnaMonto,
+		req.ColumnaRUT, req.ColumnaNombre, req.ColumnaDesc, req.ColumnaReferencia, req.FormatoFecha,
+		req.SeparadorDecimal).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+			return nil, ErrFormatoExists
+		}
+		return nil, err
+	}
+	return s.GetFormato(ctx, id)
+}
+
+func (s *ImportacionBancoService) UpdateFormato(ctx context.Context, id string, req *models.FormatoImportacionRequest) (*models.FormatoImportacion, error) {
+	if err := validarFormato(req); err != nil {
+		return nil, err
+	}
+
+	result, err := s.db.Pool.Exec(ctx, `
+		UPDATE formatos_importacion
+		SET nombre = $1, separador = $2, tiene_encabezado = $3, filas_omitir = $4, columna_fecha = $5,
+		    columna_monto = $6, columna_rut = $7, columna_nombre = $8, columna_descripcion = $9,
+		    columna_referencia = $10, formato_fecha = $11, separador_decimal = $12, updated_at = NOW()
+		WHERE id = $13`,
+		req.Nombre, req.Separador, req.TieneEncabezado, req.FilasOmitir, req.ColumnaFecha, req.ColumnaMonto,
+		req.ColumnaRUT, req.ColumnaNombre, req.ColumnaDesc, req.ColumnaReferencia, req.FormatoFecha,
+		req.SeparadorDecimal, id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+			return nil, ErrFormatoExists
+		}
+		return nil, err
+	}
+	if result.RowsAffected() == 0 {
+		return nil, ErrFormatoNotFound
+	}
+	return s.GetFormato(ctx, id)
+}
+
+func (s *ImportacionBancoService) DeleteFormato(ctx context.Context, id string) error {
+	result, err := s.db.Pool.Exec(ctx, `DELETE FROM formatos_importacion WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrFormatoNotFound
+	}
+	return nil
+}
+
+// validarFormato fills defaults and checks the layout can be applied.
+func validarFormato(req *models.FormatoImportacionRequest) error {
+	req.Nombre = strings.TrimSpace(req.Nombre)
+	if req.Separador == "" {
+		req.Separador = ";"
+	}
+	if req.FormatoFecha == "" {
+		req.FormatoFecha = "DD/MM/YYYY"
+	}
+	if req.SeparadorDecimal == "" {
+		req.SeparadorDecimal = ","
+	}
+
+	if req.Nombre == "" || len(req.Separador) != 1 || req.FilasOmitir < 0 {
+		return ErrInvalidFormato
+	}
+	if req.SeparadorDecimal != "," && req.SeparadorDecimal != "." {
+		return ErrInvalidFormato
+	}
+	if _, ok := layoutFecha(req.FormatoFecha); !ok {
+		return ErrInvalidFormato
+	}
+	if req.ColumnaFecha == "" || req.ColumnaMonto == "" {
+		return ErrInvalidFormato
+	}
+	// Without a header the columns are positions
+	if !req.TieneEncabezado {
+		for _, c := range []string{req.ColumnaFecha, req.ColumnaMonto, req.ColumnaRUT, req.ColumnaNombre,
+			req.ColumnaDesc, req.ColumnaReferencia} {
+			if c == "" {
+				continue
+			}
+			if n, err := strconv.Atoi(c); err != nil || n < 1 {
+				return ErrInvalidFormato
+			}
+		}
+	}
+	return nil
+}
+
+// layoutFecha turns a DD/MM/YYYY style pattern into a Go time layout.
+func layoutFecha(formato string) (string, bool) {
+	f := strings.ToUpper(formato)
+	if !strings.Contains(f, "DD") || !strings.Contains(f, "MM") || !strings.Contains(f, "YY") {
+		return "", false
+	}
+	layout := strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "01", "DD", "02").Replace(f)
+	return layout, true
+}
+
+// ============================================
+// LECTURA DEL ARCHIVO
+// ============================================
+
+// filaCSV is a parsed bank movement before it is stored.
+type filaCSV struct {
+	linea       int
+	fecha       *time.Time
+	monto       money.Amount
+	rut         string
+	nombre      string
+	descripcion string
+	referencia  string
+	huella      string
+	estado      models.FilaImportacionEstado
+	err         string
+}
+
+// leerCSVBanco applies a layout to a bank export. Rows that cannot be read
+// are kept with estado error so the treasurer sees every line of the file;
+// only a file the layout does not fit at all is rejected.
+func leerCSVBanco(f *models.FormatoImportacion, data []byte) ([]filaCSV, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // UTF-8 BOM from spreadsheet exports
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = rune(f.Separador[0])
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+
+	var registros [][]string
+	var lineas []int
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrArchivoInvalido, err)
+		}
+		linea, _ := r.FieldPos(0)
+		registros = append(registros, rec)
+		lineas = append(lineas, linea)
+	}
+
+	if f.FilasOmitir >= len(registros) {
+		return nil, fmt.Errorf("%w: file has no rows", ErrArchivoInvalido)
+	}
+	registros, lineas = registros[f.FilasOmitir:], lineas[f.FilasOmitir:]
+
+	var encabezado map[string]int
+	if f.TieneEncabezado {
+		encabezado = map[string]int{}
+		for i, h := range registros[0] {
+			encabezado[strings.ToLower(strings.TrimSpace(h))] = i
+		}
+		registros, lineas = registros[1:], lineas[1:]
+	}
+
+	columna := func(nombre string, requerida bool) (int, error) {
+		if nombre == "" {
+			return -1, nil
+		}
+		if encabezado != nil {
+			if i, ok := encabezado[strings.ToLower(strings.TrimSpace(nombre))]; ok {
+				return i, nil
+			}
+			if !requerida {
+				return -1, nil
+			}
+			return 0, fmt.Errorf("%w: column %q not found", ErrArchivoInvalido, nombre)
+		}
+		n, _ := strconv.Atoi(nombre)
+		return n - 1, nil
+	}
+	colFecha, err := columna(f.ColumnaFecha, true)
+	if err != nil {
+		return nil, err
+	}
+	colMonto, err := columna(f.ColumnaMonto, true)
+	if err != nil {
+		return nil, err
+	}
+	colRUT, _ := columna(f.ColumnaRUT, false)
+	colNombre, _ := columna(f.ColumnaNombre, false)
+	colDesc, _ := columna(f.ColumnaDesc, false)
+	colRef, _ := columna(f.ColumnaReferencia, false)
+
+	layout, _ := layoutFecha(f.FormatoFecha)
+	campo := func(rec []string, i int) string {
+		if i < 0 || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	filas := []filaCSV{}
+	ocurrencias := map[string]int{}
+	for n, rec := range registros {
+		if strings.TrimSpace(strings.Join(rec, "")) == "" {
+			continue
+		}
+		if len(filas) == maxFilasImportacion {
+			return nil, fmt.Errorf("%w: more than %d rows", ErrArchivoInvalido, maxFilasImportacion)
+		}
+
+		fila := filaCSV{
+			linea:       lineas[n],
+			rut:         normalizarRUT(campo(rec, colRUT)),
+			nombre:      campo(rec, colNombre),
+			descripcion: campo(rec, colDesc),
+			referencia:  campo(rec, colRef),
+			estado:      models.FilaPendiente,
+		}
+
+		fecha, errFecha := parseFechaBanco(campo(rec, colFecha), layout)
+		monto, errMonto := parseMontoBanco(campo(rec, colMonto), f.SeparadorDecimal)
+		switch {
+		case errFecha != nil:
+			fila.estado, fila.err = models.FilaError, "Fecha inválida: "+campo(rec, colFecha)
+		case errMonto != nil:
+			fila.estado, fila.err = models.FilaError, "Monto inválido: "+campo(rec, colMonto)
+		case monto <= 0:
+			fila.estado, fila.err = models.FilaIgnorada, "Cargo o monto sin abono"
+		}
+		if errFecha == nil {
+			fila.fecha = &fecha
+		}
+		fila.monto = monto
+
+		// The bank reference identifies a movement; without one, identical
+		// lines in the same file are told apart by their order.
+		clave := strings.Join([]string{campo(rec, colFecha), monto.String(), fila.rut}, "|")
+		if fila.referencia != "" {
+			clave += "|ref:" + fila.referencia
+		} else {
+			clave += "|" + fila.nombre + "|" + fila.descripcion
+			ocurrencias[clave]++
+			clave += "|" + strconv.Itoa(ocurrencias[clave])
+		}
+		sum := sha256.Sum256([]byte(clave))
+		fila.huella = hex.EncodeToString(sum[:])
+
+		filas = append(filas, fila)
+	}
+
+	if len(filas) == 0 {
+		return nil, fmt.Errorf("%w: file has no rows", ErrArchivoInvalido)
+	}
+	return filas, nil
+}
+
+func parseFechaBanco(v, layout string) (time.Time, error) {
+	// Some banks append the time of the transfer
+	if !strings.Contains(layout, " ") {
+		if i := strings.IndexAny(v, " T"); i > 0 {
+			v = v[:i]
+		}
+	}
+	return time.Parse(layout, v)
+}
+
+// parseMontoBanco reads amounts such as "$ 1.234.567", "-45.000" or
+// "1,234.50". The separator that is not decimal is taken as thousands.
+func parseMontoBanco(v, decimal string) (money.Amount, error) {
+	miles := "."
+	if decimal == "." {
+		miles = ","
+	}
+	v = strings.NewReplacer("$", "", " ", "", " ", "", miles, "").Replace(v)
+	v = strings.Replace(v, decimal, ".", 1)
+	if strings.HasPrefix(v, "(") && strings.HasSuffix(v, ")") { // accounting negatives
+		v = "-" + strings.Trim(v, "()")
+	}
+	return money.Parse(v)
+}
+
+// normalizarRUT keeps digits and verifier in a single comparable form
+// (12345678K), without dots, dash or leading zeros.
+func normalizarRUT(v string) string {
+	v = strings.ToUpper(strings.NewReplacer(".", "", "-", "", " ", "").Replace(v))
+	return strings.TrimLeft(v, "0")
+}
+
+// ============================================
+// STAGING
+// ============================================
+
+// CreateImportacion reads a bank export with the given layout and stores its
+// rows in staging, each with the proposed parcela and gasto. Nothing is
+// registered until the import is confirmed.
+func (s *ImportacionBancoService) CreateImportacion(ctx context.Context, formatoID, archivoNombre string, data []byte, userID string) (*models.ImportacionBanco, error) {
+	formato, err := s.GetFormato(ctx, formatoID)
+	if err != nil {
+		return nil, err
+	}
+
+	filas, err := leerCSVBanco(formato, data)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := s.cargarMatcher(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	huellas := make([]string, len(filas))
+	for i, f := range filas {
+		huellas[i] = f.huella
+	}
+	importadas := map[string]bool{}
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT huella FROM importaciones_banco_filas
+		WHERE estado = 'importada' AND huella = ANY($1)`, huellas)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var h string
+		if err := rows.Scan(&h); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		importadas[h] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO importaciones_banco (formato_id, archivo_nombre, created_by)
+		VALUES ($1, $2, NULLIF($3, '')::uuid)
+		RETURNING id`, formatoID, archivoNombre, userID).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range filas {
+		var parcelaID *int
+		var gastoID *string
+		match := ""
+		if importadas[f.huella] {
+			f.estado, f.err = models.FilaDuplicada, "Movimiento ya importado"
+		}
+		if f.estado == models.FilaPendiente {
+			if p, motivo := m.proponer(f); p != 0 {
+				parcelaID, match = &p, motivo
+				if g := m.gastoPara(p, f.monto); g != "" {
+					gastoID = &g
+				}
+			}
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO importaciones_banco_filas (importacion_id, linea, fecha, monto, rut, nombre, descripcion,
+			                                       referencia, huella, estado, parcela_id, gasto_comun_id, match, error)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+			id, f.linea, f.fecha, f.monto, f.rut, truncar(f.nombre, 255), f.descripcion,
+			truncar(f.referencia, 255), f.huella, f.estado, parcelaID, gastoID, match, f.err)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.GetImportacion(ctx, id)
+}
+
+func truncar(v string, n int) string {
+	r := []rune(v)
+	if len(r) <= n {
+		return v
+	}
+	return string(r[:n])
+}
+
+const selectImportacion = `
+	SELECT i.id, i.formato_id, i.archivo_nombre, i.estado, i.created_by, i.confirmada_by, i.confirmada_at,
+	       i.created_at, i.updated_at,
+	       COUNT(f.id),
+	       COUNT(f.id) FILTER (WHERE f.estado = 'pendiente'),
+	       COUNT(f.id) FILTER (WHERE f.estado = 'pendiente' AND f.parcela_id IS NULL),
+	       COUNT(f.id) FILTER (WHERE f.estado = 'importada'),
+	       COALESCE(SUM(f.monto) FILTER (WHERE f.estado = 'pendiente'), 0)
+	FROM importaciones_banco i
+	LEFT JOIN importaciones_banco_filas f ON f.importacion_id = i.id`
+
+func scanImportacion(row pgx.Row) (*models.ImportacionBanco, error) {
+	var i models.ImportacionBanco
+	err := row.Scan(&i.ID, &i.FormatoID, &i.ArchivoNombre, &i.Estado, &i.CreatedBy, &i.ConfirmadaBy,
+		&i.ConfirmadaAt, &i.CreatedAt, &i.UpdatedAt,
+		&i.TotalFilas, &i.FilasPendiente, &i.FilasSinMatch, &i.FilasImportada, &i.MontoPendiente)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// GetImportacion returns an import with all its rows.
+func (s *ImportacionBancoService) GetImportacion(ctx context.Context, id string) (*models.ImportacionBanco, error) {
+	imp, err := scanImportacion(s.db.Pool.QueryRow(ctx, selectImportacion+` WHERE i.id = $1 GROUP BY i.id`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrImportacionNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT f.id, f.linea, f.fecha, f.monto, f.rut, f.nombre, f.descripcion, f.referencia, f.estado,
+		       f.parcela_id, COALESCE(p.numero, ''), f.gasto_comun_id, f.match, f.error, f.pago_id
+		FROM importaciones_banco_filas f
+		LEFT JOIN parcelas p ON f.parcela_id = p.id
+		WHERE f.importacion_id = $1
+		ORDER BY f.linea`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	imp.Filas = []models.FilaImportacion{}
+	for rows.Next() {
+		var f models.FilaImportacion
+		err := rows.Scan(&f.ID, &f.Linea, &f.Fecha, &f.Monto, &f.RUT, &f.Nombre, &f.Descripcion, &f.Referencia,
+			&f.Estado, &f.ParcelaID, &f.ParcelaNumero, &f.GastoComunID, &f.Match, &f.Error, &f.PagoID)
+		if err != nil {
+			return nil, err
+		}
+		imp.Filas = append(imp.Filas, f)
+	}
+	return imp, rows.Err()
+}
+
+func (s *ImportacionBancoService) ListImportaciones(ctx context.Context, filter models.ImportacionFilter) (*models.ImportacionListResponse, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 || filter.PerPage > 100 {
+		filter.PerPage = 20
+	}
+	offset := (filter.Page - 1) * filter.PerPage
+
+	where := ` WHERE 1=1`
+	args := []interface{}{}
+	argCount := 0
+
+	if filter.Estado != "" {
+		argCount++
+		where += ` AND i.estado = $` + strconv.Itoa(argCount)
+		args = append(args, filter.Estado)
+	}
+
+	var total int
+	err := s.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM importaciones_banco i`+where, args...).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	query := selectImportacion + where +
+		fmt.Sprintf(` GROUP BY i.id ORDER BY i.created_at DESC LIMIT $%d OFFSET $%d`, argCount+1, argCount+2)
+	args = append(args, filter.PerPage, offset)
+
+	rows, err := s.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	importaciones := []models.ImportacionBanco{}
+	for rows.Next() {
+		imp, err := scanImportacion(rows)
+		if err != nil {
+			return nil, err
+		}
+		importaciones = append(importaciones, *imp)
+	}
+
+	return &models.ImportacionListResponse{
+		Importaciones: importaciones,
+		Total:         total,
+		Page:          filter.Page,
+		PerPage:       filter.PerPage,
+	}, rows.Err()
+}
+
+// lockImportacionStaging locks an import that can still be changed.
+func lockImportacionStaging(ctx context.Context, tx pgx.Tx, id string) error {
+	var estado models.ImportacionEstado
+	err := tx.QueryRow(ctx, `SELECT estado FROM importaciones_banco WHERE id = $1 FOR UPDATE`, id).Scan(&estado)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrImportacionNotFound
+		}
+		return err
+	}
+	if estado != models.ImportacionStaging {
+		return ErrImportacionNoStaging
+	}
+	return nil
+}
+
+// UpdateFila confirms or corrects the match of a staged row, or excludes it.
+func (s *ImportacionBancoService) UpdateFila(ctx context.Context, importacionID, filaID string, req *models.UpdateFilaImportacionRequest) (*models.ImportacionBanco, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockImportacionStaging(ctx, tx, importacionID); err != nil {
+		return nil, err
+	}
+
+	var estado models.FilaImportacionEstado
+	var monto money.Amount
+	err = tx.QueryRow(ctx, `
+		SELECT estado, monto FROM importaciones_banco_filas WHERE id = $1 AND importacion_id = $2`,
+		filaID, importacionID).Scan(&estado, &monto)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrFilaNotFound
+		}
+		return nil, err
+	}
+	if estado != models.FilaPendiente && estado != models.FilaIgnorada {
+		return nil, ErrFilaNoEditable
+	}
+	if !req.Ignorar && monto <= 0 {
+		return nil, ErrFilaNoEditable
+	}
+
+	if req.Ignorar {
+		_, err = tx.Exec(ctx, `
+			UPDATE importaciones_banco_filas SET estado = 'ignorada', updated_at = NOW() WHERE id = $1`, filaID)
+		if err != nil {
+			return nil, err
+		}
+		if err = tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+		return s.GetImportacion(ctx, importacionID)
+	}
+
+	var parcelaID *int
+	var gastoID *string
+	switch {
+	case req.GastoComunID != nil:
+		var p int
+		err = tx.QueryRow(ctx, `
+			SELECT g.parcela_id FROM gastos_comunes g
+			JOIN periodos_gasto pg ON g.periodo_id = pg.id
+			WHERE g.id = $1 AND g.status IN ('pending', 'overdue') AND pg.estado = 'abierto'`,
+			*req.GastoComunID).Scan(&p)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrFilaGastoInvalido
+			}
+			return nil, err
+		}
+		if req.ParcelaID != nil && *req.ParcelaID != p {
+			return nil, ErrFilaGastoInvalido
+		}
+		parcelaID, gastoID = &p, req.GastoComunID
+	case req.ParcelaID != nil:
+		var exists bool
+		if err = tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM parcelas WHERE id = $1)`, *req.ParcelaID).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrParcelaNotFound
+		}
+		parcelaID = req.ParcelaID
+		if g, err := gastoConSaldo(ctx, tx, *req.ParcelaID); err != nil {
+			return nil, err
+		} else if g != "" {
+			gastoID = &g
+		}
+	}
+
+	match := ""
+	if parcelaID != nil {
+		match = models.MatchManual
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE importaciones_banco_filas
+		SET estado = 'pendiente', parcela_id = $1, gasto_comun_id = $2, match = $3, updated_at = NOW()
+		WHERE id = $4`, parcelaID, gastoID, match, filaID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetImportacion(ctx, importacionID)
+}
+
+// gastoConSaldo returns the oldest gasto with balance of a parcela in an open
+// periodo, or "" if it owes nothing.
+func gastoConSaldo(ctx context.Context, q querier, parcelaID int) (string, error) {
+	var id string
+	err := q.QueryRow(ctx, `
+		SELECT g.id FROM gastos_comunes g
+		JOIN periodos_gasto pg ON g.periodo_id = pg.id
+		WHERE g.parcela_id = $1 AND g.status IN ('pending', 'overdue') AND pg.estado = 'abierto'
+		ORDER BY pg.year, pg.month
+		LIMIT 1`, parcelaID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return id, err
+}
+
+// ConfirmarImportacion registers a pago for every pending row in a single
+// transaction: either the whole file is imported or nothing is. Every
+// pending row must have a parcela; rows the treasurer does not want are
+// ignored first. A row whose gasto was paid meanwhile goes to the oldest
+// gasto with balance of the same parcela.
+func (s *ImportacionBancoService) ConfirmarImportacion(ctx context.Context, id string, userID string) (*models.ImportacionBanco, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockImportacionStaging(ctx, tx, id); err != nil {
+		return nil, err
+	}
+
+	type pendiente struct {
+		id, referencia, descripcion string
+		linea                       int
+		monto                       money.Amount
+		parcelaID                   *int
+		gastoID                     *string
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT id, linea, monto, referencia, descripcion, parcela_id, gasto_comun_id
+		FROM importaciones_banco_filas
+		WHERE importacion_id = $1 AND estado = 'pendiente'
+		ORDER BY linea`, id)
+	if err != nil {
+		return nil, err
+	}
+	var filas []pendiente
+	for rows.Next() {
+		var f pendiente
+		if err := rows.Scan(&f.id, &f.linea, &f.monto, &f.referencia, &f.descripcion, &f.parcelaID, &f.gastoID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		filas = append(filas, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var total money.Amount
+	for _, f := range filas {
+		if f.parcelaID == nil {
+			return nil, fmt.Errorf("%w (línea %d)", ErrImportacionIncompleta, f.linea)
+		}
+
+		gastoID := ""
+		if f.gastoID != nil {
+			var status models.PagoStatus
+			err = tx.QueryRow(ctx, `SELECT status FROM gastos_comunes WHERE id = $1`, *f.gastoID).Scan(&status)
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				return nil, err
+			}
+			if err == nil && (status == models.PagoStatusPending || status == models.PagoStatusOverdue) {
+				gastoID = *f.gastoID
+			}
+		}
+		if gastoID == "" {
+			if gastoID, err = gastoConSaldo(ctx, tx, *f.parcelaID); err != nil {
+				return nil, err
+			}
+			if gastoID == "" {
+				return nil, fmt.Errorf("%w (línea %d)", ErrFilaSinGasto, f.linea)
+			}
+		}
+
+		referencia := f.referencia
+		if referencia == "" {
+			referencia = truncar(f.descripcion, 255)
+		}
+		pagoID, err := registrarPago(ctx, tx, gastoID, &models.RegistrarPagoRequest{
+			Monto:             f.monto,
+			Metodo:            "transferencia",
+			ReferenciaExterna: referencia,
+		}, "")
+		if err != nil {
+			return nil, fmt.Errorf("línea %d: %w", f.linea, err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE importaciones_banco_filas
+			SET estado = 'importada', gasto_comun_id = $1, pago_id = $2, updated_at = NOW()
+			WHERE id = $3`, gastoID, pagoID, f.id)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation: another import took it
+				return nil, fmt.Errorf("%w (línea %d)", ErrFilaYaImportada, f.linea)
+			}
+			return nil, err
+		}
+		total += f.monto
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE importaciones_banco
+		SET estado = 'confirmada', confirmada_by = NULLIF($1, '')::uuid, confirmada_at = NOW(), updated_at = NOW()
+		WHERE id = $2`, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = registrarAuditoria(ctx, tx, "importacion_banco", id, "confirmar", "", userID, map[string]interface{}{
+		"pagos": len(filas), "monto": total,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetImportacion(ctx, id)
+}
+
+// DescartarImportacion drops a staged import without registering anything.
+func (s *ImportacionBancoService) DescartarImportacion(ctx context.Context, id string) (*models.ImportacionBanco, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = lockImportacionStaging(ctx, tx, id); err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE importaciones_banco SET estado = 'descartada', updated_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetImportacion(ctx, id)
+}
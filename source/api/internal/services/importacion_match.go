@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/money"
+)
+
+// ============================================
+// PROPUESTA DE MATCH
+// ============================================
+
+// matcher holds what is needed to propose a parcela for each bank row, loaded
+// once per import.
+type matcher struct {
+	parcelas  map[string]int       // normalized numero -> parcela
+	ruts      map[string]int       // RUT -> parcela it paid last
+	residente map[int][][]string   // parcela -> name tokens of its residents
+	deudas    map[int][]deudaGasto // parcela -> gastos with balance, oldest first
+}
+
+type deudaGasto struct {
+	id        string
+	pendiente money.Amount
+}
+
+// "Parcela 12", "parc. 12", "P-12", "#12", "lote 12"
+var reParcela = regexp.MustCompile(`(?i)(?:parcela|parc\.?|lote|\bp|#)\s*[-#:nº°]*\s*([0-9]+[a-z]?)\b`)
+
+func (s *ImportacionBancoService) cargarMatcher(ctx context.Context) (*matcher, error) {
+	m := &matcher{
+		parcelas:  map[string]int{},
+		ruts:      map[string]int{},
+		residente: map[int][][]string{},
+		deudas:    map[int][]deudaGasto{},
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `SELECT id, numero FROM parcelas`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int
+		var numero string
+		if err := rows.Scan(&id, &numero); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		m.parcelas[normalizarNumero(numero)] = id
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// History: the parcela each RUT paid in its latest confirmed import
+	rows, err = s.db.Pool.Query(ctx, `
+		SELECT DISTINCT ON (rut) rut, parcela_id
+		FROM importaciones_banco_filas
+		WHERE estado = 'importada' AND rut <> '' AND parcela_id IS NOT NULL
+		ORDER BY rut, updated_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var rut string
+		var parcelaID int
+		if err := rows.Scan(&rut, &parcelaID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		m.ruts[rut] = parcelaID
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.Pool.Query(ctx, `
+		SELECT parcela_id, name FROM users
+		WHERE parcela_id IS NOT NULL AND role IN ('vecino', 'directiva')`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var parcelaID int
+		var nombre string
+		if err := rows.Scan(&parcelaID, &nombre); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if tokens := tokensNombre(nombre); len(tokens) >= 2 {
+			m.residente[parcelaID] = append(m.residente[parcelaID], tokens)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.Pool.Query(ctx, `
+		SELECT g.parcela_id, g.id, g.monto - g.monto_pagado
+		FROM gastos_comunes g
+		JOIN periodos_gasto pg ON g.periodo_id = pg.id
+		WHERE g.status IN ('pending', 'overdue') AND pg.estado = 'abierto'
+		ORDER BY pg.year, pg.month`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var parcelaID int
+		var d deudaGasto
+		if err := rows.Scan(&parcelaID, &d.id, &d.pendiente); err != nil {
+			return nil, err
+		}
+		m.deudas[parcelaID] = append(m.deudas[parcelaID], d)
+	}
+	return m, rows.Err()
+}
+
+// proponer returns the most likely parcela of a row and why, or 0. Explicit
+// evidence wins over the amount: a reference in the description, then a RUT
+// seen before, then the payer's name, and only then a unique amount owed.
+func (m *matcher) proponer(f filaCSV) (int, string) {
+	for _, texto := range []string{f.referencia, f.descripcion} {
+		for _, sub := range reParcela.FindAllStringSubmatch(texto, -1) {
+			if p, ok := m.parcelas[normalizarNumero(sub[1])]; ok {
+				return p, models.MatchReferencia
+			}
+		}
+	}
+
+	if f.rut != "" {
+		if p, ok := m.ruts[f.rut]; ok {
+			return p, models.MatchRUT
+		}
+	}
+
+	if pagador := tokensNombre(f.nombre); len(pagador) > 0 {
+		encontrada := 0
+		for p, residentes := range m.residente {
+			for _, r := range residentes {
+				if contieneTokens(pagador, r) {
+					if encontrada != 0 && encontrada != p {
+						encontrada = -1 // same name in two parcelas
+					} else if encontrada == 0 {
+						encontrada = p
+					}
+				}
+			}
+		}
+		if encontrada > 0 {
+			return encontrada, models.MatchNombre
+		}
+	}
+
+	encontrada := 0
+	for p, deudas := range m.deudas {
+		var total money.Amount
+		coincide := false
+		for _, d := range deudas {
+			total += d.pendiente
+			coincide = coincide || d.pendiente == f.monto
+		}
+		if coincide || total == f.monto {
+			if encontrada != 0 {
+				return 0, "" // ambiguous
+			}
+			encontrada = p
+		}
+	}
+	if encontrada != 0 {
+		return encontrada, models.MatchMonto
+	}
+	return 0, ""
+}
+
+// gastoPara picks the gasto a payment of the parcela goes to: the oldest one
+// owing exactly that amount, else the oldest with balance.
+func (m *matcher) gastoPara(parcelaID int, monto money.Amount) string {
+	deudas := m.deudas[parcelaID]
+	for _, d := range deudas {
+		if d.pendiente == monto {
+			return d.id
+		}
+	}
+	if len(deudas) > 0 {
+		return deudas[0].id
+	}
+	return ""
+}
+
+func normalizarNumero(v string) string {
+	v = strings.ToLower(strings.TrimSpace(v))
+	if t := strings.TrimLeft(v, "0"); t != "" {
+		return t
+	}
+	return v
+}
+
+var quitarTildes = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n")
+
+func tokensNombre(v string) []string {
+	return strings.Fields(quitarTildes.Replace(strings.ToLower(v)))
+}
+
+// contieneTokens reports whether every token of the resident's name appears in
+// the payer's name; banks usually print the full legal name.
+func contieneTokens(pagador, residente []string) bool {
+	for _, r := range residente {
+		ok := false
+		for _, p := range pagador {
+			if p == r {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/condominio/backend/internal/models"
+)
+
+// ============================================
+// MOROSIDAD
+// ============================================
+
+// morosidadOrden maps the allowed sort keys to ORDER BY expressions.
+var morosidadOrden = map[string]string{
+	"parcela":     "pa.numero::int",
+	"deuda":       "deuda_total",
+	"atraso":      "dias_max_atraso",
+	"meses":       "meses_adeudados",
+	"ultimo_pago": "ultimo_pago",
+}
+
+// GetReporteMorosidad lists every parcela owing gastos past their due date,
+// aged into 0-30, 31-60, 61-90 and 90+ days buckets by each gasto's due date.
+// Gastos under a convenio still count as debt and are flagged.
+func (s *GastoComunService) GetReporteMorosidad(ctx context.Context, filter models.MorosidadFilter) (*models.ReporteMorosidad, error) {
+	orden, ok := morosidadOrden[filter.Sort]
+	if !ok {
+		orden = morosidadOrden["deuda"]
+	}
+	dir := "DESC"
+	if filter.Order == "asc" || (filter.Order == "" && filter.Sort == "parcela") {
+		dir = "ASC"
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		WITH deudas AS (
+			SELECT g.parcela_id, g.monto - g.monto_pagado AS pendiente,
+			       CURRENT_DATE - p.fecha_vencimiento AS dias, g.status
+			FROM gastos_comunes g
+			JOIN periodos_gasto p ON g.periodo_id = p.id
+			WHERE g.status IN ('pending', 'overdue', 'convenio')
+			  AND p.fecha_vencimiento < CURRENT_DATE
+			  AND g.monto > g.monto_pagado
+		)
+		SELECT pa.id, pa.numero, COALESCE(pa.direccion, ''),
+		       COALESCE((SELECT string_agg(u.name, ', ' ORDER BY u.name) FROM users u
+		                 WHERE u.parcela_id = pa.id AND u.role IN ('vecino', 'directiva')), ''),
+		       COALESCE((SELECT string_agg(u.email, ', ' ORDER BY u.name) FROM users u
+		                 WHERE u.parcela_id = pa.id AND u.role IN ('vecino', 'directiva')), ''),
+		       SUM(d.pendiente) AS deuda_total,
+		       COALESCE(SUM(d.pendiente) FILTER (WHERE d.dias <= 30), 0),
+		       COALESCE(SUM(d.pendiente) FILTER (WHERE d.dias BETWEEN 31 AND 60), 0),
+		       COALESCE(SUM(d.pendiente) FILTER (WHERE d.dias BETWEEN 61 AND 90), 0),
+		       COALESCE(SUM(d.pendiente) FILTER (WHERE d.dias > 90), 0),
+		       COUNT(*) AS meses_adeudados,
+		       MAX(d.dias) AS dias_max_atraso,
+		       (SELECT MAX(pg.created_at) FROM pagos pg
+		        JOIN gastos_comunes g2 ON pg.gasto_comun_id = g2.id
+		        WHERE g2.parcela_id = pa.id AND pg.estado = 'approved' AND pg.metodo <> 'credito') AS ultimo_pago,
+		       BOOL_OR(d.status = 'convenio')
+		FROM deudas d
+		JOIN parcelas pa ON d.parcela_id = pa.id
+		GROUP BY pa.id
+		ORDER BY `+orden+` `+dir+` NULLS LAST, pa.numero::int`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reporte := &models.ReporteMorosidad{
+		FechaCorte: time.Now(),
+		Parcelas:   []models.MorosidadParcela{},
+	}
+	for rows.Next() {
+		var m models.MorosidadParcela
+		err := rows.Scan(
+			&m.ParcelaID, &m.ParcelaNumero, &m.Direccion, &m.Contacto, &m.Email,
+			&m.DeudaTotal, &m.Tramo0a30, &m.Tramo31a60, &m.Tramo61a90, &m.TramoMas90,
+			&m.MesesAdeudados, &m.DiasMaxAtraso, &m.UltimoPago, &m.EnConvenio)
+		if err != nil {
+			return nil, err
+		}
+		reporte.Parcelas = append(reporte.Parcelas, m)
+		reporte.DeudaTotal += m.DeudaTotal
+		reporte.Tramo0a30 += m.Tramo0a30
+		reporte.Tramo31a60 += m.Tramo31a60
+		reporte.Tramo61a90 += m.Tramo61a90
+		reporte.TramoMas90 += m.TramoMas90
+	}
+	reporte.TotalParcelas = len(reporte.Parcelas)
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Debt is also shown in UF at the latest known value
+	valorUF, err := valorUFVigente(ctx, s.db.Pool, fechaHoy())
+	if err != nil {
+		return nil, err
+	}
+	if valorUF != nil {
+		reporte.ValorUF = valorUF
+		for i := range reporte.Parcelas {
+			deudaUF := enUF(reporte.Parcelas[i].DeudaTotal, *valorUF)
+			reporte.Parcelas[i].DeudaTotalUF = &deudaUF
+		}
+		totalUF := enUF(reporte.DeudaTotal, *valorUF)
+		reporte.DeudaTotalUF = &totalUF
+	}
+
+	return reporte, nil
+}
+
+// GetTendenciaMorosidad returns, for each of the last meses months, the debt
+// past due at the end of that month as it stood then: pagos registered later
+// are not counted and pagos reversed later still are.
+func (s *GastoComunService) GetTendenciaMorosidad(ctx context.Context, meses int) ([]models.MorosidadMes, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		WITH cortes AS (
+			SELECT LEAST(
+			           (date_trunc('month', CURRENT_DATE) - make_interval(months => n) + INTERVAL '1 month' - INTERVAL '1 day')::date,
+			           CURRENT_DATE) AS corte
+			FROM generate_series(0, $1 - 1) AS n
+		),
+		deudas AS (
+			SELECT c.corte, g.parcela_id,
+			       GREATEST(g.monto - COALESCE((
+			           SELECT SUM(pa.monto) FROM pagos pa
+			           WHERE pa.gasto_comun_id = g.id
+			             AND pa.created_at < c.corte + 1
+			             AND (pa.estado = 'approved' OR (pa.estado = 'reversed' AND pa.reversed_at >= c.corte + 1))
+			       ), 0), 0) AS deuda
+			FROM cortes c
+			JOIN periodos_gasto p ON p.fecha_vencimiento < c.corte
+			JOIN gastos_comunes g ON g.periodo_id = p.id
+			WHERE g.status <> 'cancelled'
+		)
+		SELECT c.corte, COALESCE(SUM(d.deuda), 0),
+		       COUNT(DISTINCT d.parcela_id) FILTER (WHERE d.deuda > 0)
+		FROM cortes c
+		LEFT JOIN deudas d ON d.corte = c.corte
+		GROUP BY c.corte
+		ORDER BY c.corte`, meses)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tendencia := []models.MorosidadMes{}
+	for rows.Next() {
+		var m models.MorosidadMes
+		if err := rows.Scan(&m.FechaCorte, &m.DeudaTotal, &m.ParcelasMorosas); err != nil {
+			return nil, err
+		}
+		m.Year = m.FechaCorte.Year()
+		m.Month = int(m.FechaCorte.Month())
+		tendencia = append(tendencia, m)
+	}
+	return tendencia, rows.Err()
+}
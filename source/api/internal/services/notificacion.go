@@ -8,6 +8,8 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+
 	"github.com/condominio/backend/internal/database"
 	"github.com/condominio/backend/internal/models"
 	"github.com/condominio/backend/pkg/email"
@@ -330,3 +332,43 @@ func (s *NotificacionService) DeleteRead(ctx context.Context, userID string) (in
 	}
 	return int(result.RowsAffected()), nil
 }
+
+// GetPreferencias returns the user's reminder preferences; users that never
+// changed them get everything enabled.
+func (s *NotificacionService) GetPreferencias(ctx context.Context, userID string) (*models.PreferenciasNotificacion, error) {
+	p := models.PreferenciasNotificacion{UserID: userID, EmailGastos: true, AppGastos: true}
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT email_gastos, app_gastos, updated_at
+		FROM preferencias_notificacion
+		WHERE user_id = $1`, userID).Scan(&p.EmailGastos, &p.AppGastos, &p.UpdatedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *NotificacionService) UpdatePreferencias(ctx context.Context, userID string, req *models.UpdatePreferenciasRequest) (*models.PreferenciasNotificacion, error) {
+	actual, err := s.GetPreferencias(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.EmailGastos != nil {
+		actual.EmailGastos = *req.EmailGastos
+	}
+	if req.AppGastos != nil {
+		actual.AppGastos = *req.AppGastos
+	}
+
+	var p models.PreferenciasNotificacion
+	err = s.db.Pool.QueryRow(ctx, `
+		INSERT INTO preferencias_notificacion (user_id, email_gastos, app_gastos)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET email_gastos = EXCLUDED.email_gastos, app_gastos = EXCLUDED.app_gastos, updated_at = NOW()
+		RETURNING user_id, email_gastos, app_gastos, updated_at`,
+		userID, actual.EmailGastos, actual.AppGastos).Scan(&p.UserID, &p.EmailGastos, &p.AppGastos, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
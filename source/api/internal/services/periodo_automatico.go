@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/condominio/backend/internal/export"
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/money"
+)
+
+var ErrInvalidConfigPeriodos = errors.New("invalid periodo recurrence settings")
+
+// ============================================
+// RECURRENCIA DE PERIODOS
+// ============================================
+
+func defaultConfigPeriodos() *models.ConfigPeriodos {
+	return &models.ConfigPeriodos{
+		Modo:                 models.RecurrenciaModoMonto,
+		DiaVencimiento:       10,
+		AjusteDiaHabil:       models.AjusteDiaHabilSiguiente,
+		DescripcionPlantilla: "Gasto común {periodo}",
+		DiasAnticipacion:     5,
+		RequiereAprobacion:   true,
+	}
+}
+
+func (s *GastoComunService) GetConfigPeriodos(ctx context.Context) (*models.ConfigPeriodos, error) {
+	c := defaultConfigPeriodos()
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT activa, modo, monto_base, monto_base_uf, presupuesto_mensual, dia_vencimiento, ajuste_dia_habil,
+		       descripcion_plantilla, dias_anticipacion, requiere_aprobacion, updated_by, updated_at
+		FROM config_periodos WHERE id = 1`).Scan(
+		&c.Activa, &c.Modo, &c.MontoBase, &c.MontoBaseUF, &c.PresupuestoMensual, &c.DiaVencimiento, &c.AjusteDiaHabil,
+		&c.DescripcionPlantilla, &c.DiasAnticipacion, &c.RequiereAprobacion, &c.UpdatedBy, &c.UpdatedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *GastoComunService) UpdateConfigPeriodos(ctx context.Context, req *models.UpdateConfigPeriodosRequest, userID string) (*models.ConfigPeriodos, error) {
+	c, err := s.GetConfigPeriodos(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Activa != nil {
+		c.Activa = *req.Activa
+	}
+	if req.Modo != nil {
+		c.Modo = *req.Modo
+	}
+	if req.MontoBase != nil {
+		c.MontoBase = *req.MontoBase
+	}
+	if req.MontoBaseUF != nil {
+		c.MontoBaseUF = *req.MontoBaseUF
+	}
+	if req.PresupuestoMensual != nil {
+		c.PresupuestoMensual = *req.PresupuestoMensual
+	}
+	if req.DiaVencimiento != nil {
+		c.DiaVencimiento = *req.DiaVencimiento
+	}
+	if req.AjusteDiaHabil != nil {
+		c.AjusteDiaHabil = *req.AjusteDiaHabil
+	}
+	if req.DescripcionPlantilla != nil {
+		c.DescripcionPlantilla = *req.DescripcionPlantilla
+	}
+	if req.DiasAnticipacion != nil {
+		c.DiasAnticipacion = *req.DiasAnticipacion
+	}
+	if req.RequiereAprobacion != nil {
+		c.RequiereAprobacion = *req.RequiereAprobacion
+	}
+
+	if err := validarConfigPeriodos(c); err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.Pool.Exec(ctx, `
+		INSERT INTO config_periodos (id, activa, modo, monto_base, presupuesto_mensual, dia_vencimiento,
+		                             ajuste_dia_habil, descripcion_plantilla, dias_anticipacion,
+		                             requiere_aprobacion, updated_by, monto_base_uf)
+		VALUES (1, $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (id) DO UPDATE
+		SET activa = EXCLUDED.activa, modo = EXCLUDED.modo, monto_base = EXCLUDED.monto_base,
+		    monto_base_uf = EXCLUDED.monto_base_uf, presupuesto_mensual = EXCLUDED.presupuesto_mensual, dia_vencimiento = EXCLUDED.dia_vencimiento,
+		    ajuste_dia_habil = EXCLUDED.ajuste_dia_habil, descripcion_plantilla = EXCLUDED.descripcion_plantilla,
+		    dias_anticipacion = EXCLUDED.dias_anticipacion, requiere_aprobacion = EXCLUDED.requiere_aprobacion,
+		    updated_by = EXCLUDED.updated_by, updated_at = NOW()`,
+		c.Activa, c.Modo, c.MontoBase, c.PresupuestoMensual, c.DiaVencimiento, c.AjusteDiaHabil,
+		c.DescripcionPlantilla, c.DiasAnticipacion, c.RequiereAprobacion, userID, c.MontoBaseUF)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := registrarAuditoria(ctx, s.db.Pool, "config_periodos", "1", "actualizar", "", userID, c); err != nil {
+		return nil, err
+	}
+	return s.GetConfigPeriodos(ctx)
+}
+
+func validarConfigPeriodos(c *models.ConfigPeriodos) error {
+	switch c.Modo {
+	case models.RecurrenciaModoMonto:
+		if c.Activa && c.MontoBase <= 0 {
+			return ErrInvalidConfigPeriodos
+		}
+	case models.RecurrenciaModoPresupuesto:
+		if c.Activa && c.PresupuestoMensual <= 0 {
+			return ErrInvalidConfigPeriodos
+		}
+	case models.RecurrenciaModoUF:
+		if c.Activa && c.MontoBaseUF <= 0 {
+			return ErrInvalidConfigPeriodos
+		}
+	default:
+		return ErrInvalidConfigPeriodos
+	}
+	switch c.AjusteDiaHabil {
+	case models.AjusteDiaHabilNinguno, models.AjusteDiaHabilSiguiente, models.AjusteDiaHabilAnterior:
+	default:
+		return ErrInvalidConfigPeriodos
+	}
+	if c.DiaVencimiento < 1 || c.DiaVencimiento > 31 || c.DiasAnticipacion < 0 || c.DiasAnticipacion > 28 {
+		return ErrInvalidConfigPeriodos
+	}
+	if c.MontoBase < 0 || c.MontoBaseUF < 0 || c.PresupuestoMensual < 0 {
+		return ErrInvalidConfigPeriodos
+	}
+	return nil
+}
+
+// fechaVencimientoPeriodo applies the due-day rule to a month. Days past the
+// end of the month fall on its last day, and weekends move to the next or
+// previous business day depending on the rule.
+func fechaVencimientoPeriodo(year, month, dia int, ajuste string) time.Time {
+	ultimo := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if dia > ultimo {
+		dia = ultimo
+	}
+	fecha := time.Date(year, time.Month(month), dia, 0, 0, 0, 0, time.UTC)
+
+	paso := 0
+	switch ajuste {
+	case models.AjusteDiaHabilSiguiente:
+		paso = 1
+	case models.AjusteDiaHabilAnterior:
+		paso = -1
+	}
+	for paso != 0 && (fecha.Weekday() == time.Saturday || fecha.Weekday() == time.Sunday) {
+		fecha = fecha.AddDate(0, 0, paso)
+	}
+	return fecha
+}
+
+func descripcionPeriodo(plantilla string, year, month int) string {
+	return strings.NewReplacer(
+		"{mes}", export.NombreMes(month),
+		"{anio}", strconv.Itoa(year),
+		"{periodo}", export.NombrePeriodo(year, month),
+	).Replace(plantilla)
+}
+
+// montoRecurrencia returns the monto_base per parcela the recurrence charges.
+// In UF mode the conversion is also returned; exacta asks for the value of
+// today, as needed to issue the periodo right away.
+func (s *GastoComunService) montoRecurrencia(ctx context.Context, cfg *models.ConfigPeriodos, exacta bool) (money.Amount, *conversionUF, error) {
+	monto := cfg.MontoBase
+	var uf *conversionUF
+	switch cfg.Modo {
+	case models.RecurrenciaModoPresupuesto:
+		var parcelas int
+		if err := s.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM parcelas`).Scan(&parcelas); err != nil {
+			return 0, nil, err
+		}
+		if parcelas == 0 {
+			return 0, nil, ErrInvalidConfigPeriodos
+		}
+		// Rounded up to whole pesos so the budget is fully covered
+		monto = cfg.PresupuestoMensual.DivUp(parcelas).CeilPesos()
+	case models.RecurrenciaModoUF:
+		if cfg.MontoBaseUF <= 0 {
+			return 0, nil, ErrInvalidConfigPeriodos
+		}
+		var err error
+		if uf, err = convertirUF(ctx, s.db.Pool, cfg.MontoBaseUF, fechaHoy(), exacta); err != nil {
+			return 0, nil, err
+		}
+		monto = uf.clp
+	}
+	if monto <= 0 {
+		return 0, nil, ErrInvalidConfigPeriodos
+	}
+	return monto, uf, nil
+}
+
+// GenerarPeriodoSiguiente creates next month's periodo once the configured
+// lead time is reached. It does nothing if the recurrence is inactive or the
+// periodo already exists, so it is safe to run repeatedly from the scheduler.
+// The directiva is notified so it can review (and approve) the new periodo.
+func (s *GastoComunService) GenerarPeriodoSiguiente(ctx context.Context) (*models.GenerarPeriodoResult, error) {
+	cfg, err := s.GetConfigPeriodos(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.Activa {
+		return &models.GenerarPeriodoResult{Motivo: "Recurrencia inactiva"}, nil
+	}
+
+	now := time.Now()
+	siguiente := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.Local)
+	year, month := siguiente.Year(), int(siguiente.Month())
+	nombre := export.NombrePeriodo(year, month)
+
+	if now.Before(siguiente.AddDate(0, 0, -cfg.DiasAnticipacion)) {
+		return &models.GenerarPeriodoResult{Motivo: "Aún no corresponde generar " + nombre}, nil
+	}
+
+	var exists bool
+	err = s.db.Pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM periodos_gasto WHERE year = $1 AND month = $2)`,
+		year, month).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return &models.GenerarPeriodoResult{Motivo: "El periodo " + nombre + " ya existe"}, nil
+	}
+
+	estado := models.PeriodoAbierto
+	if cfg.RequiereAprobacion {
+		estado = models.PeriodoBorrador
+	}
+	monto, uf, err := s.montoRecurrencia(ctx, cfg, estado == models.PeriodoAbierto)
+	if err != nil {
+		return nil, err
+	}
+	fechaVenc := fechaVencimientoPeriodo(year, month, cfg.DiaVencimiento, cfg.AjusteDiaHabil)
+	descripcion := descripcionPeriodo(cfg.DescripcionPlantilla, year, month)
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var periodoID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO periodos_gasto (year, month, monto_base, fecha_vencimiento, descripcion, estado, generado_automaticamente)
+		VALUES ($1, $2, $3, $4, $5, $6, TRUE)
+		RETURNING id`,
+		year, month, monto, fechaVenc, descripcion, estado).Scan(&periodoID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation: another instance won
+			return &models.GenerarPeriodoResult{Motivo: "El periodo " + nombre + " ya existe"}, nil
+		}
+		return nil, err
+	}
+	if err = guardarMonedaPeriodo(ctx, tx, periodoID, uf); err != nil {
+		return nil, err
+	}
+
+	titulo := "Periodo " + nombre + " generado"
+	montoTexto := export.FormatCLP(monto.Float64())
+	if uf != nil {
+		montoTexto = export.FormatUF(uf.montoUF) + " (" + montoTexto + " al " + uf.fecha.Format("02-01-2006") + ")"
+	}
+	cuerpo := "Se generó automáticamente el periodo " + nombre + " por " + montoTexto +
+		" por parcela, con vencimiento el " + fechaVenc.Format("02-01-2006") + "."
+	if estado == models.PeriodoBorrador {
+		cuerpo += " Queda en borrador hasta que la directiva lo apruebe."
+	} else {
+		if err = generarGastosPeriodo(ctx, tx, periodoID, year, month, monto); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO notificaciones (user_id, title, body, type, reference_id)
+		SELECT id, $1, $2, $3, $4 FROM users WHERE role = 'directiva'`,
+		titulo, cuerpo, models.NotificationTypeGastoComun, periodoID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = registrarAuditoria(ctx, tx, "periodo", periodoID, "generar", "", "", map[string]interface{}{
+		"year": year, "month": month, "monto_base": monto, "monto_base_uf": cfg.MontoBaseUF, "estado": estado,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	periodo, err := s.GetPeriodo(ctx, periodoID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.GenerarPeriodoResult{Generado: true, Periodo: periodo}, nil
+}
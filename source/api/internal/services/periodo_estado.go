@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/condominio/backend/internal/export"
+	"github.com/condominio/backend/internal/models"
+)
+
+var (
+	ErrPeriodoNoBorrador     = errors.New("periodo is not a draft")
+	ErrPeriodoNoAbierto      = errors.New("periodo is not open")
+	ErrPeriodoCerrado        = errors.New("periodo is closed")
+	ErrPeriodoMontoCongelado = errors.New("monto_base cannot change once the periodo is open")
+	ErrPeriodoConDeuda       = errors.New("periodo has gastos with pending balance")
+)
+
+// ============================================
+// CICLO DE VIDA DE PERIODOS
+// ============================================
+//
+// borrador: no gastos yet; amounts, dates and description can change freely.
+// abierto:  gastos exist and residents can pay; monto_base is frozen.
+// cerrado:  every gasto is settled or under a convenio. Nothing is edited any
+//           more; the only changes are reversals, convenio payments and
+//           payments of the gastos a broken convenio hands back.
+
+// admitePago reports why a gasto cannot take a pago or a credit application.
+// In a closed periodo only the gastos handed back by a broken convenio, pending
+// or overdue again, still take them.
+func admitePago(estadoPeriodo models.PeriodoEstado, status models.PagoStatus) error {
+	if estadoPeriodo == models.PeriodoCerrado &&
+		status != models.PagoStatusPending && status != models.PagoStatusOverdue {
+		return ErrPeriodoCerrado
+	}
+	if status == models.PagoStatusPaid {
+		return ErrGastoAlreadyPaid
+	}
+	return nil
+}
+
+// lockPeriodo locks a periodo row and returns it.
+func lockPeriodo(ctx context.Context, tx pgx.Tx, id string) (*models.PeriodoGasto, error) {
+	var p models.PeriodoGasto
+	err := tx.QueryRow(ctx, `
+		SELECT id, year, month, monto_base, fecha_vencimiento, COALESCE(descripcion, ''), estado,
+		       moneda, monto_base_uf
+		FROM periodos_gasto WHERE id = $1 FOR UPDATE`, id).Scan(
+		&p.ID, &p.Year, &p.Month, &p.MontoBase, &p.FechaVencimiento, &p.Descripcion, &p.Estado,
+		&p.Moneda, &p.MontoBaseUF)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPeriodoNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// AbrirPeriodo opens a draft periodo: its gastos are generated with the
+// current monto_base, which is frozen from then on. A periodo defined in UF is
+// converted with the value of the opening day.
+func (s *GastoComunService) AbrirPeriodo(ctx context.Context, id string, userID string) (*models.PeriodoGasto, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	p, err := lockPeriodo(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.Estado != models.PeriodoBorrador {
+		return nil, ErrPeriodoNoBorrador
+	}
+
+	if p.Moneda == models.MonedaUF && p.MontoBaseUF != nil {
+		uf, err := convertirUF(ctx, tx, *p.MontoBaseUF, fechaHoy(), true)
+		if err != nil {
+			return nil, err
+		}
+		if err = guardarMonedaPeriodo(ctx, tx, id, uf); err != nil {
+			return nil, err
+		}
+		p.MontoBase = uf.clp
+	}
+
+	if err = generarGastosPeriodo(ctx, tx, id, p.Year, p.Month, p.MontoBase); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE periodos_gasto
+		SET estado = 'abierto', aprobado_by = $1, aprobado_at = NOW(), updated_at = NOW()
+		WHERE id = $2`, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = registrarAuditoria(ctx, tx, "periodo", id, "abrir", "", userID, p); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.GetPeriodo(ctx, id)
+}
+
+// RegenerarPeriodo recomputes a draft from the current recurrence settings,
+// e.g. after the budget or the due-day rule changed.
+func (s *GastoComunService) RegenerarPeriodo(ctx context.Context, id string, userID string) (*models.PeriodoGasto, error) {
+	cfg, err := s.GetConfigPeriodos(ctx)
+	if err != nil {
+		return nil, err
+	}
+	monto, uf, err := s.montoRecurrencia(ctx, cfg, false)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	p, err := lockPeriodo(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.Estado != models.PeriodoBorrador {
+		return nil, ErrPeriodoNoBorrador
+	}
+
+	fechaVenc := fechaVencimientoPeriodo(p.Year, p.Month, cfg.DiaVencimiento, cfg.AjusteDiaHabil)
+	_, err = tx.Exec(ctx, `
+		UPDATE periodos_gasto
+		SET monto_base = $1, fecha_vencimiento = $2, descripcion = $3, updated_at = NOW()
+		WHERE id = $4`,
+		monto, fechaVenc, descripcionPeriodo(cfg.DescripcionPlantilla, p.Year, p.Month), id)
+	if err != nil {
+		return nil, err
+	}
+	if err = guardarMonedaPeriodo(ctx, tx, id, uf); err != nil {
+		return nil, err
+	}
+
+	if err = registrarAuditoria(ctx, tx, "periodo", id, "regenerar", "", userID, p); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.GetPeriodo(ctx, id)
+}
+
+// DeletePeriodo removes a draft. Open and closed periodos are history and
+// cannot be deleted.
+func (s *GastoComunService) DeletePeriodo(ctx context.Context, id string, userID string) error {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	p, err := lockPeriodo(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if p.Estado != models.PeriodoBorrador {
+		return ErrPeriodoNoBorrador
+	}
+
+	if _, err = tx.Exec(ctx, `DELETE FROM periodos_gasto WHERE id = $1`, id); err != nil {
+		return err
+	}
+
+	if err = registrarAuditoria(ctx, tx, "periodo", id, "eliminar", "", userID, p); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// CerrarPeriodo locks an open periodo. Every gasto must be paid, cancelled or
+// under a convenio: debt of a closed periodo is only collected through its
+// convenio, or directly once the convenio breaks.
+func (s *GastoComunService) CerrarPeriodo(ctx context.Context, id string, motivo string, userID string) (*models.PeriodoGasto, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	p, err := lockPeriodo(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.Estado != models.PeriodoAbierto {
+		return nil, ErrPeriodoNoAbierto
+	}
+
+	var conDeuda int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM gastos_comunes
+		WHERE periodo_id = $1 AND status IN ('pending', 'overdue')`, id).Scan(&conDeuda)
+	if err != nil {
+		return nil, err
+	}
+	if conDeuda > 0 {
+		return nil, ErrPeriodoConDeuda
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE periodos_gasto
+		SET estado = 'cerrado', cerrado_by = $1, cerrado_at = NOW(), updated_at = NOW()
+		WHERE id = $2`, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = registrarAuditoria(ctx, tx, "periodo", id, "cerrar", motivo, userID, map[string]interface{}{
+		"periodo": export.NombrePeriodo(p.Year, p.Month),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.GetPeriodo(ctx, id)
+}
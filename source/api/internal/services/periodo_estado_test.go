@@ -0,0 +1,34 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/condominio/backend/internal/models"
+)
+
+func TestAdmitePago(t *testing.T) {
+	tests := []struct {
+		name    string
+		periodo models.PeriodoEstado
+		status  models.PagoStatus
+		want    error
+	}{
+		{"open and pending", models.PeriodoAbierto, models.PagoStatusPending, nil},
+		{"open and overdue", models.PeriodoAbierto, models.PagoStatusOverdue, nil},
+		{"open and paid", models.PeriodoAbierto, models.PagoStatusPaid, ErrGastoAlreadyPaid},
+		{"closed and overdue after a broken convenio", models.PeriodoCerrado, models.PagoStatusOverdue, nil},
+		{"closed and pending after a broken convenio", models.PeriodoCerrado, models.PagoStatusPending, nil},
+		{"closed and under a convenio", models.PeriodoCerrado, models.PagoStatusConvenio, ErrPeriodoCerrado},
+		{"closed and paid", models.PeriodoCerrado, models.PagoStatusPaid, ErrPeriodoCerrado},
+		{"closed and cancelled", models.PeriodoCerrado, models.PagoStatusCancelled, ErrPeriodoCerrado},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := admitePago(tt.periodo, tt.status); !errors.Is(err, tt.want) {
+				t.Errorf("admitePago(%s, %s) = %v, want %v", tt.periodo, tt.status, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,487 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/condominio/backend/internal/models"
+)
+
+var (
+	ErrPoderNotFound        = errors.New("poder not found")
+	ErrInvalidPoder         = errors.New("poder must cover exactly one votacion or asamblea")
+	ErrInvalidVigenciaPoder = errors.New("valido_hasta must be in the future and after valido_desde")
+	ErrInvalidEvento        = errors.New("evento must be an asamblea")
+	ErrApoderadoInvalido    = errors.New("apoderado must be a vecino of another parcela")
+	ErrPoderAcceso          = errors.New("poder belongs to another parcela")
+	ErrPoderDuplicado       = errors.New("the parcela already has a poder for this votacion or asamblea")
+	ErrLimitePoderes        = errors.New("the apoderado already holds the maximum number of poderes")
+	ErrPoderNoPendiente     = errors.New("poder has already been reviewed")
+	ErrPoderSinDocumento    = errors.New("poder has no signed document")
+	ErrPoderNoVigente       = errors.New("no validated poder for this parcela and votacion")
+	ErrPoderUsado           = errors.New("poder has already been used to vote")
+)
+
+const poderColumns = `
+	po.id, po.parcela_id, pa.numero, po.apoderado_id, COALESCE(ua.name, ''),
+	po.votacion_id, COALESCE(v.title, ''), po.evento_id, COALESCE(e.title, ''),
+	po.valido_desde, po.valido_hasta, po.estado,
+	po.documento_nombre, po.documento_content_type, po.documento_tamano,
+	COALESCE(po.motivo_rechazo, ''), po.revisado_by, po.revisado_at,
+	EXISTS(SELECT 1 FROM votos vo WHERE vo.poder_id = po.id),
+	po.created_by, po.created_at, po.updated_at`
+
+const poderJoins = `
+	FROM poderes po
+	JOIN parcelas pa ON pa.id = po.parcela_id
+	LEFT JOIN users ua ON ua.id = po.apoderado_id
+	LEFT JOIN votaciones v ON v.id = po.votacion_id
+	LEFT JOIN eventos e ON e.id = po.evento_id`
+
+func scanPoder(row pgx.Row) (*models.Poder, error) {
+	var p models.Poder
+	var nombre, contentType *string
+	var tamano *int
+	err := row.Scan(
+		&p.ID, &p.ParcelaID, &p.ParcelaNumero, &p.ApoderadoID, &p.ApoderadoNombre,
+		&p.VotacionID, &p.VotacionTitle, &p.EventoID, &p.EventoTitle,
+		&p.ValidoDesde, &p.ValidoHasta, &p.Estado,
+		&nombre, &contentType, &tamano,
+		&p.MotivoRechazo, &p.RevisadoBy, &p.RevisadoAt,
+		&p.Usado,
+		&p.CreatedBy, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if nombre != nil {
+		p.Documento = &models.DocumentoPoder{NombreArchivo: *nombre}
+		if contentType != nil {
+			p.Documento.ContentType = *contentType
+		}
+		if tamano != nil {
+			p.Documento.Tamano = *tamano
+		}
+	}
+	return &p, nil
+}
+
+func (s *VotacionService) GetPoder(ctx context.Context, id string) (*models.Poder, error) {
+	p, err := scanPoder(s.db.Pool.QueryRow(ctx, `SELECT `+poderColumns+poderJoins+` WHERE po.id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPoderNotFound
+	}
+	return p, err
+}
+
+// ListPoderes lists the poderes for the directiva, pending ones first.
+func (s *VotacionService) ListPoderes(ctx context.Context, filter models.PoderFilter) ([]models.Poder, error) {
+	query := `SELECT ` + poderColumns + poderJoins + ` WHERE 1=1`
+	args := []interface{}{}
+
+	if filter.Estado != "" {
+		args = append(args, filter.Estado)
+		query += fmt.Sprintf(` AND po.estado = $%d`, len(args))
+	}
+	if filter.VotacionID != "" {
+		// Poderes of the votacion itself and of the asamblea it is held in
+		args = append(args, filter.VotacionID)
+		query += fmt.Sprintf(` AND (po.votacion_id = $%d
+			OR po.evento_id = (SELECT evento_id FROM votaciones WHERE id = $%d))`, len(args), len(args))
+	}
+	if filter.EventoID != "" {
+		args = append(args, filter.EventoID)
+		query += fmt.Sprintf(` AND po.evento_id = $%d`, len(args))
+	}
+	query += ` ORDER BY po.estado = 'pendiente' DESC, po.created_at DESC`
+
+	return s.listPoderes(ctx, query, args...)
+}
+
+// ListMisPoderes lists the poderes granted by the user's parcela and those
+// given to the user.
+func (s *VotacionService) ListMisPoderes(ctx context.Context, userID string) ([]models.Poder, error) {
+	return s.listPoderes(ctx, `SELECT `+poderColumns+poderJoins+`
+		WHERE po.apoderado_id = $1
+		   OR po.parcela_id = (SELECT parcela_id FROM users WHERE id = $1)
+		ORDER BY po.created_at DESC`, userID)
+}
+
+func (s *VotacionService) listPoderes(ctx context.Context, query string, args ...interface{}) ([]models.Poder, error) {
+	rows, err := s.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	poderes := []models.Poder{}
+	for rows.Next() {
+		p, err := scanPoder(rows)
+		if err != nil {
+			return nil, err
+		}
+		poderes = append(poderes, *p)
+	}
+	return poderes, rows.Err()
+}
+
+// CreatePoder registers a pending poder. Residents grant poderes for their
+// own parcela; the directiva can register them for any parcela.
+func (s *VotacionService) CreatePoder(ctx context.Context, req *models.CreatePoderRequest, userID string, directiva bool) (*models.Poder, error) {
+	var userParcela *int
+	if err := s.db.Pool.QueryRow(ctx, `SELECT parcela_id FROM users WHERE id = $1`, userID).Scan(&userParcela); err != nil {
+		return nil, err
+	}
+	switch {
+	case req.ParcelaID == nil && userParcela == nil:
+		return nil, ErrUserNoParcela
+	case req.ParcelaID == nil:
+		req.ParcelaID = userParcela
+	case !directiva && (userParcela == nil || *userParcela != *req.ParcelaID):
+		return nil, ErrPoderAcceso
+	}
+
+	if (req.VotacionID == nil) == (req.EventoID == nil) {
+		return nil, ErrInvalidPoder
+	}
+	desde := time.Now()
+	if req.ValidoDesde != nil {
+		desde = *req.ValidoDesde
+	}
+	if !req.ValidoHasta.After(desde) || !req.ValidoHasta.After(time.Now()) {
+		return nil, ErrInvalidVigenciaPoder
+	}
+
+	if req.VotacionID != nil {
+		var status models.VotacionStatus
+		err := s.db.Pool.QueryRow(ctx, `SELECT status FROM votaciones WHERE id = $1`, *req.VotacionID).Scan(&status)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrVotacionNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if status == models.VotacionStatusClosed || status == models.VotacionStatusCancelled {
+			return nil, ErrVotacionNotActive
+		}
+	} else if err := s.validarAsamblea(ctx, *req.EventoID); err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM users
+			WHERE id = $1 AND role IN ('vecino', 'directiva')
+			  AND parcela_id IS DISTINCT FROM $2)`, req.ApoderadoID, *req.ParcelaID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrApoderadoInvalido
+	}
+
+	excede, err := s.excedeLimitePoderes(ctx, s.db.Pool, req.ApoderadoID, req.VotacionID, req.EventoID, true)
+	if err != nil {
+		return nil, err
+	}
+	if excede {
+		return nil, ErrLimitePoderes
+	}
+
+	var id string
+	err = s.db.Pool.QueryRow(ctx, `
+		INSERT INTO poderes (parcela_id, apoderado_id, votacion_id, evento_id, valido_desde, valido_hasta, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`,
+		*req.ParcelaID, req.ApoderadoID, req.VotacionID, req.EventoID, desde, req.ValidoHasta, userID).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+			return nil, ErrPoderDuplicado
+		}
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			return nil, ErrParcelaNotFound
+		}
+		return nil, err
+	}
+
+	return s.GetPoder(ctx, id)
+}
+
+// validarAsamblea checks the evento exists and is an asamblea.
+func (s *VotacionService) validarAsamblea(ctx context.Context, eventoID string) error {
+	var tipo models.EventoType
+	err := s.db.Pool.QueryRow(ctx, `SELECT type FROM eventos WHERE id = $1`, eventoID).Scan(&tipo)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && tipo != models.EventoAsamblea) {
+		return ErrInvalidEvento
+	}
+	return err
+}
+
+// excedeLimitePoderes tells whether the apoderado already holds the maximum
+// number of poderes allowed by the reglamento. It counts every live poder
+// usable in the same votacion or asamblea; pending ones count too when a new
+// poder is registered, so requests cannot pile up beyond the limit.
+func (s *VotacionService) excedeLimitePoderes(ctx context.Context, q querier, apoderadoID string, votacionID, eventoID *string, conPendientes bool) (bool, error) {
+	if s.cfg.MaxPoderes <= 0 {
+		return false, nil
+	}
+	var n int
+	err := q.QueryRow(ctx, `
+		SELECT COUNT(*) FROM poderes
+		WHERE apoderado_id = $1
+		  AND (estado = 'validado' OR ($4 AND estado = 'pendiente'))
+		  AND valido_hasta > NOW()
+		  AND (votacion_id = $2 OR evento_id = $3
+		       OR votacion_id IN (SELECT id FROM votaciones WHERE evento_id = $3)
+		       OR evento_id = (SELECT evento_id FROM votaciones WHERE id = $2))`,
+		apoderadoID, votacionID, eventoID, conPendientes).Scan(&n)
+	return n >= s.cfg.MaxPoderes, err
+}
+
+// accesoPoder tells whether the user may act on the poder: the directiva,
+// the residents of the parcela that granted it and the apoderado.
+func (s *VotacionService) accesoPoder(ctx context.Context, p *models.Poder, userID string, directiva bool) error {
+	if directiva || p.ApoderadoID == userID {
+		return nil
+	}
+	var userParcela *int
+	if err := s.db.Pool.QueryRow(ctx, `SELECT parcela_id FROM users WHERE id = $1`, userID).Scan(&userParcela); err != nil {
+		return err
+	}
+	if userParcela == nil || *userParcela != p.ParcelaID {
+		return ErrPoderAcceso
+	}
+	return nil
+}
+
+// SetDocumentoPoder attaches the signed poder, replacing the previous file
+// while the poder is pending review.
+func (s *VotacionService) SetDocumentoPoder(ctx context.Context, id string, doc *models.DocumentoPoder, userID string, directiva bool) (*models.Poder, error) {
+	p, err := s.GetPoder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.accesoPoder(ctx, p, userID, directiva); err != nil {
+		return nil, err
+	}
+
+	tag, err := s.db.Pool.Exec(ctx, `
+		UPDATE poderes
+		SET documento_nombre = $1, documento_content_type = $2, documento_tamano = $3, documento = $4, updated_at = NOW()
+		WHERE id = $5 AND estado = 'pendiente'`,
+		doc.NombreArchivo, doc.ContentType, len(doc.Contenido), doc.Contenido, id)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrPoderNoPendiente
+	}
+
+	return s.GetPoder(ctx, id)
+}
+
+func (s *VotacionService) GetDocumentoPoder(ctx context.Context, id, userID string, directiva bool) (*models.DocumentoPoder, error) {
+	p, err := s.GetPoder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.accesoPoder(ctx, p, userID, directiva); err != nil {
+		return nil, err
+	}
+	if p.Documento == nil {
+		return nil, ErrPoderSinDocumento
+	}
+
+	doc := p.Documento
+	err = s.db.Pool.QueryRow(ctx, `SELECT documento FROM poderes WHERE id = $1`, id).Scan(&doc.Contenido)
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// ValidarPoder approves a pending poder once the directiva has checked the
+// signed document. The limit of poderes per apoderado is enforced here too,
+// under a lock on the apoderado, so concurrent validations cannot exceed it.
+func (s *VotacionService) ValidarPoder(ctx context.Context, id, userID string) (*models.Poder, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var estado models.PoderEstado
+	var apoderadoID string
+	var votacionID, eventoID *string
+	var validoHasta time.Time
+	var conDocumento bool
+	err = tx.QueryRow(ctx, `
+		SELECT estado, apoderado_id, votacion_id, evento_id, valido_hasta, documento IS NOT NULL
+		FROM poderes WHERE id = $1
+		FOR UPDATE`, id).Scan(&estado, &apoderadoID, &votacionID, &eventoID, &validoHasta, &conDocumento)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPoderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if estado != models.PoderPendiente {
+		return nil, ErrPoderNoPendiente
+	}
+	if !conDocumento {
+		return nil, ErrPoderSinDocumento
+	}
+	if !validoHasta.After(time.Now()) {
+		return nil, ErrInvalidVigenciaPoder
+	}
+
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, apoderadoID); err != nil {
+		return nil, err
+	}
+	excede, err := s.excedeLimitePoderes(ctx, tx, apoderadoID, votacionID, eventoID, false)
+	if err != nil {
+		return nil, err
+	}
+	if excede {
+		return nil, ErrLimitePoderes
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE poderes
+		SET estado = 'validado', revisado_by = $1, revisado_at = NOW(), updated_at = NOW()
+		WHERE id = $2`, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.GetPoder(ctx, id)
+}
+
+func (s *VotacionService) RechazarPoder(ctx context.Context, id, motivo, userID string) (*models.Poder, error) {
+	tag, err := s.db.Pool.Exec(ctx, `
+		UPDATE poderes
+		SET estado = 'rechazado', motivo_rechazo = $1, revisado_by = $2, revisado_at = NOW(), updated_at = NOW()
+		WHERE id = $3 AND estado = 'pendiente'`, truncar(strings.TrimSpace(motivo), 500), userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		if _, err := s.GetPoder(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrPoderNoPendiente
+	}
+
+	return s.GetPoder(ctx, id)
+}
+
+// RevocarPoder withdraws a poder that has not been used yet. The parcela can
+// revoke it and the apoderado can renounce it.
+func (s *VotacionService) RevocarPoder(ctx context.Context, id, userID string, directiva bool) (*models.Poder, error) {
+	p, err := s.GetPoder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.accesoPoder(ctx, p, userID, directiva); err != nil {
+		return nil, err
+	}
+	if p.Estado != models.PoderPendiente && p.Estado != models.PoderValidado {
+		return nil, ErrPoderNoPendiente
+	}
+
+	tag, err := s.db.Pool.Exec(ctx, `
+		UPDATE poderes SET estado = 'revocado', updated_at = NOW()
+		WHERE id = $1 AND estado IN ('pendiente', 'validado')
+		  AND NOT EXISTS (SELECT 1 FROM votos WHERE poder_id = $1)`, id)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrPoderUsado
+	}
+
+	return s.GetPoder(ctx, id)
+}
+
+// poderVigente returns the validated poder that lets the user vote for the
+// parcela in the votacion right now.
+func (s *VotacionService) poderVigente(ctx context.Context, votacion *models.Votacion, userID string, parcelaID int) (string, error) {
+	var id string
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT id FROM poderes
+		WHERE apoderado_id = $1 AND parcela_id = $2 AND estado = 'validado'
+		  AND (votacion_id = $3 OR evento_id = $4)
+		  AND NOW() >= valido_desde AND NOW() < valido_hasta
+		LIMIT 1`, userID, parcelaID, votacion.ID, votacion.EventoID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrPoderNoVigente
+	}
+	return id, err
+}
+
+// poderesVoto lists the parcelas the user can vote for in the votacion through
+// a poder, and whether each has voted already.
+func (s *VotacionService) poderesVoto(ctx context.Context, votacion *models.Votacion, userID string) ([]models.PoderVoto, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT po.id, po.parcela_id, pa.numero,
+		       EXISTS(SELECT 1 FROM votos vo WHERE vo.votacion_id = $2 AND vo.parcela_id = po.parcela_id)
+		FROM poderes po
+		JOIN parcelas pa ON pa.id = po.parcela_id
+		WHERE po.apoderado_id = $1 AND po.estado = 'validado'
+		  AND (po.votacion_id = $2 OR po.evento_id = $3)
+		  AND NOW() >= po.valido_desde AND NOW() < po.valido_hasta
+		ORDER BY pa.id`, userID, votacion.ID, votacion.EventoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var poderes []models.PoderVoto
+	for rows.Next() {
+		var p models.PoderVoto
+		if err := rows.Scan(&p.PoderID, &p.ParcelaID, &p.ParcelaNumero, &p.HasVoted); err != nil {
+			return nil, err
+		}
+		poderes = append(poderes, p)
+	}
+	return poderes, rows.Err()
+}
+
+// votosPorPoder lists the ballots of the votacion cast by apoderados, with
+// the coefficients they carried.
+func (s *VotacionService) votosPorPoder(ctx context.Context, votacionID string) ([]models.VotoPoder, float64, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT vo.poder_id, pa.numero, COALESCE(u.name, ''), vo.voted_at, vo.peso
+		FROM votos vo
+		JOIN parcelas pa ON pa.id = vo.parcela_id
+		LEFT JOIN users u ON u.id = vo.user_id
+		WHERE vo.votacion_id = $1 AND vo.poder_id IS NOT NULL
+		ORDER BY vo.voted_at`, votacionID)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	votos := []models.VotoPoder{}
+	var peso float64
+	for rows.Next() {
+		var v models.VotoPoder
+		var p float64
+		if err := rows.Scan(&v.PoderID, &v.ParcelaNumero, &v.Apoderado, &v.VotedAt, &p); err != nil {
+			return nil, 0, err
+		}
+		peso += p
+		votos = append(votos, v)
+	}
+	return votos, peso, rows.Err()
+}
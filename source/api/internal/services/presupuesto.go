@@ -0,0 +1,520 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/condominio/backend/internal/export"
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/money"
+)
+
+var (
+	ErrCategoriaNotFound   = errors.New("category not found")
+	ErrCategoriaExists     = errors.New("category already exists")
+	ErrInvalidCategoria    = errors.New("invalid category")
+	ErrCategoriaInvalida   = errors.New("category does not exist, is inactive or does not match the movimiento type")
+	ErrPresupuestoNotFound = errors.New("presupuesto not found")
+	ErrInvalidPresupuesto  = errors.New("invalid presupuesto")
+)
+
+var codigoCategoriaRe = regexp.MustCompile(`^[a-z0-9_]{1,100}$`)
+
+// ============================================
+// CATEGORIAS
+// ============================================
+
+func (s *TesoreriaService) ListCategorias(ctx context.Context, tipo models.MovimientoType, soloActivas bool) ([]models.CategoriaTesoreria, error) {
+	query := `SELECT id, codigo, nombre, tipo, activa, created_at, updated_at FROM categorias_tesoreria WHERE 1=1`
+	args := []interface{}{}
+	if tipo != "" {
+		args = append(args, tipo)
+		query += ` AND tipo = $` + strconv.Itoa(len(args))
+	}
+	if soloActivas {
+		query += ` AND activa`
+	}
+	query += ` ORDER BY tipo, nombre`
+
+	rows, err := s.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categorias := []models.CategoriaTesoreria{}
+	for rows.Next() {
+		var c models.CategoriaTesoreria
+		if err := rows.Scan(&c.ID, &c.Codigo, &c.Nombre, &c.Tipo, &c.Activa, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		categorias = append(categorias, c)
+	}
+	return categorias, rows.Err()
+}
+
+func (s *TesoreriaService) getCategoria(ctx context.Context, q querier, codigo string) (*models.CategoriaTesoreria, error) {
+	var c models.CategoriaTesoreria
+	err := q.QueryRow(ctx, `
+		SELECT id, codigo, nombre, tipo, activa, created_at, updated_at
+		FROM categorias_tesoreria WHERE codigo = $1`, codigo).Scan(
+		&c.ID, &c.Codigo, &c.Nombre, &c.Tipo, &c.Activa, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCategoriaNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CreateCategoria adds a category to the catalogue. The codigo is what
+// movimientos store, so it is a fixed lowercase slug; the nombre can change.
+func (s *TesoreriaService) CreateCategoria(ctx context.Context, req *models.CreateCategoriaRequest, userID string) (*models.CategoriaTesoreria, error) {
+	req.Codigo = strings.TrimSpace(req.Codigo)
+	req.Nombre = strings.TrimSpace(req.Nombre)
+	if !codigoCategoriaRe.MatchString(req.Codigo) || req.Nombre == "" ||
+		(req.Tipo != models.MovimientoIngreso && req.Tipo != models.MovimientoEgreso) {
+		return nil, ErrInvalidCategoria
+	}
+
+	_, err := s.db.Pool.Exec(ctx, `
+		INSERT INTO categorias_tesoreria (codigo, nombre, tipo) VALUES ($1, $2, $3)`,
+		req.Codigo, req.Nombre, req.Tipo)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrCategoriaExists
+		}
+		return nil, err
+	}
+
+	err = registrarAuditoria(ctx, s.db.Pool, "categoria_tesoreria", req.Codigo, "crear", "", userID, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.getCategoria(ctx, s.db.Pool, req.Codigo)
+}
+
+// UpdateCategoria renames or (de)activates a category. Inactive categories
+// stay on past movimientos and budgets but cannot be used for new ones.
+func (s *TesoreriaService) UpdateCategoria(ctx context.Context, codigo string, req *models.UpdateCategoriaRequest, userID string) (*models.CategoriaTesoreria, error) {
+	c, err := s.getCategoria(ctx, s.db.Pool, codigo)
+	if err != nil {
+		return nil, err
+	}
+	if req.Nombre != nil {
+		c.Nombre = strings.TrimSpace(*req.Nombre)
+	}
+	if req.Activa != nil {
+		c.Activa = *req.Activa
+	}
+	if c.Nombre == "" {
+		return nil, ErrInvalidCategoria
+	}
+
+	_, err = s.db.Pool.Exec(ctx, `
+		UPDATE categorias_tesoreria SET nombre = $1, activa = $2, updated_at = NOW() WHERE codigo = $3`,
+		c.Nombre, c.Activa, codigo)
+	if err != nil {
+		return nil, err
+	}
+
+	err = registrarAuditoria(ctx, s.db.Pool, "categoria_tesoreria", codigo, "actualizar", "", userID, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.getCategoria(ctx, s.db.Pool, codigo)
+}
+
+// validarCategoria checks the category of a movimiento against the catalogue.
+// An empty category is allowed. A category that was deactivated is only
+// accepted when the movimiento already had it.
+func validarCategoria(ctx context.Context, q querier, codigo string, tipo models.MovimientoType, nueva bool) error {
+	if codigo == "" {
+		return nil
+	}
+	var catTipo models.MovimientoType
+	var activa bool
+	err := q.QueryRow(ctx, `SELECT tipo, activa FROM categorias_tesoreria WHERE codigo = $1`, codigo).Scan(&catTipo, &activa)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrCategoriaInvalida
+		}
+		return err
+	}
+	if catTipo != tipo || (nueva && !activa) {
+		return ErrCategoriaInvalida
+	}
+	return nil
+}
+
+// ============================================
+// PRESUPUESTOS
+// ============================================
+
+const presupuestoColumns = `
+	p.id, p.year, COALESCE(p.descripcion, ''), p.umbral_alerta::float8,
+	COALESCE((SELECT SUM(pi.monto_anual) FROM presupuesto_items pi JOIN categorias_tesoreria c ON c.codigo = pi.categoria
+	          WHERE pi.presupuesto_id = p.id AND c.tipo = 'ingreso'), 0),
+	COALESCE((SELECT SUM(pi.monto_anual) FROM presupuesto_items pi JOIN categorias_tesoreria c ON c.codigo = pi.categoria
+	          WHERE pi.presupuesto_id = p.id AND c.tipo = 'egreso'), 0),
+	p.created_by, p.created_at, p.updated_at`
+
+func scanPresupuesto(row pgx.Row, p *models.Presupuesto) error {
+	return row.Scan(&p.ID, &p.Year, &p.Descripcion, &p.UmbralAlerta, &p.TotalIngresos, &p.TotalEgresos,
+		&p.CreatedBy, &p.CreatedAt, &p.UpdatedAt)
+}
+
+func (s *TesoreriaService) ListPresupuestos(ctx context.Context) ([]models.Presupuesto, error) {
+	rows, err := s.db.Pool.Query(ctx, `SELECT `+presupuestoColumns+` FROM presupuestos p ORDER BY p.year DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	presupuestos := []models.Presupuesto{}
+	for rows.Next() {
+		var p models.Presupuesto
+		if err := scanPresupuesto(rows, &p); err != nil {
+			return nil, err
+		}
+		presupuestos = append(presupuestos, p)
+	}
+	return presupuestos, rows.Err()
+}
+
+// GetPresupuesto returns the budget of a year with its items.
+func (s *TesoreriaService) GetPresupuesto(ctx context.Context, year int) (*models.Presupuesto, error) {
+	var p models.Presupuesto
+	err := scanPresupuesto(s.db.Pool.QueryRow(ctx, `SELECT `+presupuestoColumns+` FROM presupuestos p WHERE p.year = $1`, year), &p)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPresupuestoNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT pi.id, pi.categoria, c.nombre, c.tipo, pi.monto_anual, pi.umbral_alerta::float8, pi.alertado_at
+		FROM presupuesto_items pi
+		JOIN categorias_tesoreria c ON c.codigo = pi.categoria
+		WHERE pi.presupuesto_id = $1
+		ORDER BY c.tipo DESC, c.nombre`, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	p.Items = []models.PresupuestoItem{}
+	for rows.Next() {
+		var it models.PresupuestoItem
+		err := rows.Scan(&it.ID, &it.Categoria, &it.CategoriaNombre, &it.Tipo, &it.MontoAnual,
+			&it.UmbralAlerta, &it.AlertadoAt)
+		if err != nil {
+			return nil, err
+		}
+		p.Items = append(p.Items, it)
+	}
+	return &p, rows.Err()
+}
+
+// SetPresupuesto creates the budget of a year or replaces it with the items
+// sent. Alerts are evaluated again against the new amounts.
+func (s *TesoreriaService) SetPresupuesto(ctx context.Context, year int, req *models.SetPresupuestoRequest, userID string) (*models.Presupuesto, error) {
+	if year < 2000 || year > 2100 {
+		return nil, fmt.Errorf("%w: year out of range", ErrInvalidPresupuesto)
+	}
+	umbral := 90.0
+	if req.UmbralAlerta != nil {
+		umbral = *req.UmbralAlerta
+	}
+	if umbral <= 0 || umbral > 999 {
+		return nil, fmt.Errorf("%w: umbral_alerta must be between 0 and 999", ErrInvalidPresupuesto)
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	vistos := map[string]bool{}
+	for _, it := range req.Items {
+		if vistos[it.Categoria] {
+			return nil, fmt.Errorf("%w: category %q appears twice", ErrInvalidPresupuesto, it.Categoria)
+		}
+		vistos[it.Categoria] = true
+		if it.MontoAnual < 0 {
+			return nil, fmt.Errorf("%w: monto_anual of %q is negative", ErrInvalidPresupuesto, it.Categoria)
+		}
+		if it.UmbralAlerta != nil && (*it.UmbralAlerta <= 0 || *it.UmbralAlerta > 999) {
+			return nil, fmt.Errorf("%w: umbral_alerta of %q must be between 0 and 999", ErrInvalidPresupuesto, it.Categoria)
+		}
+		if _, err := s.getCategoria(ctx, tx, it.Categoria); err != nil {
+			if errors.Is(err, ErrCategoriaNotFound) {
+				return nil, fmt.Errorf("%w: unknown category %q", ErrInvalidPresupuesto, it.Categoria)
+			}
+			return nil, err
+		}
+	}
+
+	var presupuestoID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO presupuestos (year, descripcion, umbral_alerta, created_by)
+		VALUES ($1, NULLIF($2, ''), $3, $4)
+		ON CONFLICT (year) DO UPDATE
+		SET descripcion = EXCLUDED.descripcion, umbral_alerta = EXCLUDED.umbral_alerta, updated_at = NOW()
+		RETURNING id`, year, req.Descripcion, umbral, userID).Scan(&presupuestoID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.Exec(ctx, `DELETE FROM presupuesto_items WHERE presupuesto_id = $1`, presupuestoID); err != nil {
+		return nil, err
+	}
+	for _, it := range req.Items {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO presupuesto_items (presupuesto_id, categoria, monto_anual, umbral_alerta)
+			VALUES ($1, $2, $3, $4)`, presupuestoID, it.Categoria, it.MontoAnual, it.UmbralAlerta)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = registrarAuditoria(ctx, tx, "presupuesto", strconv.Itoa(year), "actualizar", "", userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, it := range req.Items {
+		if err = revisarAlertaPresupuesto(ctx, tx, year, it.Categoria); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetPresupuesto(ctx, year)
+}
+
+func (s *TesoreriaService) DeletePresupuesto(ctx context.Context, year int, userID string) error {
+	result, err := s.db.Pool.Exec(ctx, `DELETE FROM presupuestos WHERE year = $1`, year)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrPresupuestoNotFound
+	}
+	return registrarAuditoria(ctx, s.db.Pool, "presupuesto", strconv.Itoa(year), "eliminar", "", userID, nil)
+}
+
+// ============================================
+// EJECUCION
+// ============================================
+
+// porcentajeEjecutado is ejecutado over the annual amount, in percent with two
+// decimals; nil when nothing was budgeted.
+func porcentajeEjecutado(presupuestado, ejecutado money.Amount) *float64 {
+	if presupuestado <= 0 {
+		return nil
+	}
+	p := math.Round(float64(ejecutado)/float64(presupuestado)*10000) / 100
+	return &p
+}
+
+// superaUmbral tells whether spending on a budgeted category calls for an
+// alert. Any spending on a category budgeted at zero does.
+func superaUmbral(presupuestado, ejecutado money.Amount, umbral float64) bool {
+	if presupuestado <= 0 {
+		return ejecutado > 0
+	}
+	return *porcentajeEjecutado(presupuestado, ejecutado) >= umbral
+}
+
+// presupuestadoAlMes is the part of an annual amount budgeted up to the end
+// of month, spread evenly so that the twelve months add up exactly.
+func presupuestadoAlMes(anual money.Amount, month int) money.Amount {
+	return anual.Mul(month).Div(12)
+}
+
+// GetEjecucion compares the budget of a year with what was booked up to the
+// end of month. Categories with movimientos but no budget line are listed
+// too, as is spending without a category.
+func (s *TesoreriaService) GetEjecucion(ctx context.Context, year, month int) (*models.EjecucionPresupuesto, error) {
+	if month < 1 || month > 12 {
+		return nil, fmt.Errorf("%w: month must be between 1 and 12", ErrInvalidPresupuesto)
+	}
+	var presupuestoID string
+	var umbral float64
+	err := s.db.Pool.QueryRow(ctx, `SELECT id, umbral_alerta::float8 FROM presupuestos WHERE year = $1`, year).Scan(&presupuestoID, &umbral)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPresupuestoNotFound
+		}
+		return nil, err
+	}
+
+	desde := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	hasta := time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, time.UTC)
+	inicioMes := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT c.codigo, c.nombre, c.tipo, pi.id IS NOT NULL,
+		       COALESCE(pi.monto_anual, 0), COALESCE(pi.umbral_alerta, $2)::float8,
+		       COALESCE((SELECT SUM(m.amount) FROM movimientos_tesoreria m
+		                 WHERE m.category = c.codigo AND m.type = c.tipo AND m.date >= $5 AND m.date < $4), 0),
+		       COALESCE((SELECT SUM(m.amount) FROM movimientos_tesoreria m
+		                 WHERE m.category = c.codigo AND m.type = c.tipo AND m.date >= $3 AND m.date < $4), 0)
+		FROM categorias_tesoreria c
+		LEFT JOIN presupuesto_items pi ON pi.categoria = c.codigo AND pi.presupuesto_id = $1
+		WHERE pi.id IS NOT NULL OR EXISTS (
+		    SELECT 1 FROM movimientos_tesoreria m
+		    WHERE m.category = c.codigo AND m.type = c.tipo AND m.date >= $3 AND m.date < $4)
+		ORDER BY c.tipo DESC, c.nombre`,
+		presupuestoID, umbral, desde, hasta, inicioMes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	e := &models.EjecucionPresupuesto{Year: year, Month: month, Categorias: []models.EjecucionCategoria{}}
+	for rows.Next() {
+		var c models.EjecucionCategoria
+		var presupuestada bool
+		err := rows.Scan(&c.Categoria, &c.CategoriaNombre, &c.Tipo, &presupuestada,
+			&c.Presupuestado, &c.UmbralAlerta, &c.EjecutadoMes, &c.EjecutadoAlMes)
+		if err != nil {
+			return nil, err
+		}
+		c.SinPresupuesto = !presupuestada
+		e.Categorias = append(e.Categorias, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Movimientos without a category
+	rows, err = s.db.Pool.Query(ctx, `
+		SELECT type,
+		       COALESCE(SUM(amount) FILTER (WHERE date >= $3), 0),
+		       COALESCE(SUM(amount), 0)
+		FROM movimientos_tesoreria
+		WHERE category IS NULL AND transferencia_id IS NULL AND date >= $1 AND date < $2
+		GROUP BY type
+		ORDER BY type DESC`, desde, hasta, inicioMes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		c := models.EjecucionCategoria{CategoriaNombre: "Sin categoría", SinPresupuesto: true, UmbralAlerta: umbral}
+		if err := rows.Scan(&c.Tipo, &c.EjecutadoMes, &c.EjecutadoAlMes); err != nil {
+			return nil, err
+		}
+		if c.EjecutadoAlMes != 0 {
+			e.Categorias = append(e.Categorias, c)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range e.Categorias {
+		c := &e.Categorias[i]
+		c.PresupuestadoAlMes = presupuestadoAlMes(c.Presupuestado, month)
+		c.PresupuestadoMes = c.PresupuestadoAlMes - presupuestadoAlMes(c.Presupuestado, month-1)
+		c.Diferencia = c.PresupuestadoAlMes - c.EjecutadoAlMes
+		c.PorcentajeEjecutado = porcentajeEjecutado(c.Presupuestado, c.EjecutadoAlMes)
+		if c.Tipo == models.MovimientoEgreso && !c.SinPresupuesto {
+			c.Alerta = superaUmbral(c.Presupuestado, c.EjecutadoAlMes, c.UmbralAlerta)
+		}
+		if c.Alerta {
+			e.Alertas++
+		}
+
+		t := &e.Ingresos
+		if c.Tipo == models.MovimientoEgreso {
+			t = &e.Egresos
+		}
+		t.Presupuestado += c.Presupuestado
+		t.PresupuestadoAlMes += c.PresupuestadoAlMes
+		t.EjecutadoAlMes += c.EjecutadoAlMes
+	}
+	for _, t := range []*models.EjecucionTotales{&e.Ingresos, &e.Egresos} {
+		t.Diferencia = t.PresupuestadoAlMes - t.EjecutadoAlMes
+		t.PorcentajeEjecutado = porcentajeEjecutado(t.Presupuestado, t.EjecutadoAlMes)
+	}
+	return e, nil
+}
+
+// revisarAlertaPresupuesto checks the spending on a category against its
+// budget line for the year and notifies the directiva the first time it
+// reaches the threshold. If it falls back below (a void, a correction or a
+// bigger budget) the mark is cleared, so crossing again alerts again.
+func revisarAlertaPresupuesto(ctx context.Context, q querier, year int, categoria string) error {
+	if categoria == "" {
+		return nil
+	}
+	var itemID, presupuestoID, nombre string
+	var tipo models.MovimientoType
+	var monto, ejecutado money.Amount
+	var umbral float64
+	var alertado bool
+	err := q.QueryRow(ctx, `
+		SELECT pi.id, p.id, c.nombre, c.tipo, pi.monto_anual,
+		       COALESCE(pi.umbral_alerta, p.umbral_alerta)::float8, pi.alertado_at IS NOT NULL,
+		       COALESCE((SELECT SUM(m.amount) FROM movimientos_tesoreria m
+		                 WHERE m.category = c.codigo AND m.type = c.tipo
+		                   AND EXTRACT(YEAR FROM m.date) = p.year), 0)
+		FROM presupuesto_items pi
+		JOIN presupuestos p ON p.id = pi.presupuesto_id
+		JOIN categorias_tesoreria c ON c.codigo = pi.categoria
+		WHERE p.year = $1 AND pi.categoria = $2
+		FOR UPDATE OF pi`, year, categoria).Scan(
+		&itemID, &presupuestoID, &nombre, &tipo, &monto, &umbral, &alertado, &ejecutado)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if tipo != models.MovimientoEgreso {
+		return nil
+	}
+
+	supera := superaUmbral(monto, ejecutado, umbral)
+	switch {
+	case supera && !alertado:
+		titulo := fmt.Sprintf("Presupuesto %d: gasto en %s sin monto presupuestado", year, nombre)
+		if p := porcentajeEjecutado(monto, ejecutado); p != nil {
+			titulo = fmt.Sprintf("Presupuesto %d: %s ejecutado al %s%%", year, nombre, strconv.FormatFloat(*p, 'f', -1, 64))
+		}
+		cuerpo := fmt.Sprintf("Se han ejecutado %s de %s presupuestados para %s en %d (umbral de alerta: %s%%).",
+			export.FormatCLP(ejecutado.Float64()), export.FormatCLP(monto.Float64()), nombre, year,
+			strconv.FormatFloat(umbral, 'f', -1, 64))
+		_, err = q.Exec(ctx, `
+			INSERT INTO notificaciones (user_id, title, body, type, reference_id)
+			SELECT id, $1, $2, $3, $4 FROM users WHERE role = 'directiva'`,
+			titulo, cuerpo, models.NotificationTypeTesoreria, presupuestoID)
+		if err != nil {
+			return err
+		}
+		_, err = q.Exec(ctx, `UPDATE presupuesto_items SET alertado_at = NOW() WHERE id = $1`, itemID)
+		return err
+	case !supera && alertado:
+		_, err = q.Exec(ctx, `UPDATE presupuesto_items SET alertado_at = NULL WHERE id = $1`, itemID)
+		return err
+	}
+	return nil
+}
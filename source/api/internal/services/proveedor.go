@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/condominio/backend/internal/database"
+	"github.com/condominio/backend/internal/models"
+)
+
+var (
+	ErrProveedorNotFound = errors.New("proveedor not found")
+	ErrProveedorExists   = errors.New("a proveedor with this RUT already exists")
+	ErrInvalidProveedor  = errors.New("invalid proveedor")
+	ErrInvalidRUT        = errors.New("invalid RUT")
+)
+
+type ProveedorService struct {
+	db *database.DB
+}
+
+func NewProveedorService(db *database.DB) *ProveedorService {
+	return &ProveedorService{db: db}
+}
+
+// validarRUT normalizes a Chilean RUT and checks its verifier digit
+// (modulo 11).
+func validarRUT(v string) (string, error) {
+	rut := normalizarRUT(v)
+	if len(rut) < 2 {
+		return "", ErrInvalidRUT
+	}
+	cuerpo, dv := rut[:len(rut)-1], rut[len(rut)-1]
+	if _, err := strconv.Atoi(cuerpo); err != nil {
+		return "", ErrInvalidRUT
+	}
+
+	suma, factor := 0, 2
+	for i := len(cuerpo) - 1; i >= 0; i-- {
+		suma += int(cuerpo[i]-'0') * factor
+		if factor++; factor > 7 {
+			factor = 2
+		}
+	}
+	var esperado byte
+	switch r := 11 - suma%11; r {
+	case 11:
+		esperado = '0'
+	case 10:
+		esperado = 'K'
+	default:
+		esperado = byte('0' + r)
+	}
+	if dv != esperado {
+		return "", ErrInvalidRUT
+	}
+	return rut, nil
+}
+
+const proveedorColumns = `
+	p.id, p.nombre, p.rut, COALESCE(p.email, ''), COALESCE(p.telefono, ''), COALESCE(p.banco, ''),
+	COALESCE(p.tipo_cuenta, ''), COALESCE(p.numero_cuenta, ''), COALESCE(p.categoria, ''),
+	COALESCE(c.nombre, ''), COALESCE(p.notas, ''), p.activo,
+	(SELECT COUNT(*) FROM facturas f WHERE f.proveedor_id = p.id AND f.estado IN ('pendiente', 'aprobada')),
+	p.created_by, p.created_at, p.updated_at
+	FROM proveedores p
+	LEFT JOIN categorias_tesoreria c ON c.codigo = p.categoria`
+
+func scanProveedor(row pgx.Row, p *models.Proveedor) error {
+	return row.Scan(&p.ID, &p.Nombre, &p.RUT, &p.Email, &p.Telefono, &p.Banco,
+		&p.TipoCuenta, &p.NumeroCuenta, &p.Categoria,
+		&p.CategoriaNombre, &p.Notas, &p.Activo,
+		&p.FacturasPend,
+		&p.CreatedBy, &p.CreatedAt, &p.UpdatedAt)
+}
+
+// List returns the proveedores matching q (name or RUT), active ones only
+// unless todos is set.
+func (s *ProveedorService) List(ctx context.Context, q string, todos bool) ([]models.Proveedor, error) {
+	query := `SELECT ` + proveedorColumns + ` WHERE ($1 OR p.activo)`
+	args := []interface{}{todos}
+	if q = strings.TrimSpace(q); q != "" {
+		query += ` AND (p.nombre ILIKE '%' || $2 || '%' OR p.rut LIKE '%' || $3 || '%')`
+		args = append(args, q, normalizarRUT(q))
+	}
+	query += ` ORDER BY p.nombre`
+
+	rows, err := s.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	proveedores := []models.Proveedor{}
+	for rows.Next() {
+		var p models.Proveedor
+		if err := scanProveedor(rows, &p); err != nil {
+			return nil, err
+		}
+		proveedores = append(proveedores, p)
+	}
+	return proveedores, rows.Err()
+}
+
+func (s *ProveedorService) GetByID(ctx context.Context, id string) (*models.Proveedor, error) {
+	var p models.Proveedor
+	err := scanProveedor(s.db.Pool.QueryRow(ctx, `SELECT `+proveedorColumns+` WHERE p.id::text = $1`, id), &p)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProveedorNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *ProveedorService) Create(ctx context.Context, req *models.CreateProveedorRequest, userID string) (*models.Proveedor, error) {
+	p := &models.Proveedor{
+		Nombre:       strings.TrimSpace(req.Nombre),
+		Email:        strings.TrimSpace(req.Email),
+		Telefono:     strings.TrimSpace(req.Telefono),
+		Banco:        strings.TrimSpace(req.Banco),
+		TipoCuenta:   strings.TrimSpace(req.TipoCuenta),
+		NumeroCuenta: strings.TrimSpace(req.NumeroCuenta),
+		Categoria:    req.Categoria,
+		Notas:        req.Notas,
+	}
+	rut, err := validarRUT(req.RUT)
+	if err != nil {
+		return nil, err
+	}
+	p.RUT = rut
+	if err := s.validar(ctx, p, true); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO proveedores (nombre, rut, email, telefono, banco, tipo_cuenta, numero_cuenta, categoria, notas, created_by)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''),
+		        NULLIF($8, ''), NULLIF($9, ''), $10)
+		RETURNING id`,
+		p.Nombre, p.RUT, p.Email, p.Telefono, p.Banco, p.TipoCuenta, p.NumeroCuenta, p.Categoria, p.Notas,
+		userID).Scan(&p.ID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+			return nil, ErrProveedorExists
+		}
+		return nil, err
+	}
+
+	if err = registrarAuditoria(ctx, tx, "proveedor", p.ID, "crear", "", userID, req); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetByID(ctx, p.ID)
+}
+
+// Update changes a proveedor's data. Bank data changes are a classic fraud
+// vector, so the previous values go to the audit trail.
+func (s *ProveedorService) Update(ctx context.Context, id string, req *models.UpdateProveedorRequest, userID string) (*models.Proveedor, error) {
+	p, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	anterior := *p
+
+	if req.Nombre != nil {
+		p.Nombre = strings.TrimSpace(*req.Nombre)
+	}
+	if req.RUT != nil {
+		if p.RUT, err = validarRUT(*req.RUT); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range []struct {
+		dst *string
+		src *string
+	}{
+		{&p.Email, req.Email}, {&p.Telefono, req.Telefono}, {&p.Banco, req.Banco},
+		{&p.TipoCuenta, req.TipoCuenta}, {&p.NumeroCuenta, req.NumeroCuenta},
+	} {
+		if f.src != nil {
+			*f.dst = strings.TrimSpace(*f.src)
+		}
+	}
+	if req.Categoria != nil {
+		p.Categoria = *req.Categoria
+	}
+	if req.Notas != nil {
+		p.Notas = *req.Notas
+	}
+	if req.Activo != nil {
+		p.Activo = *req.Activo
+	}
+	if err := s.validar(ctx, p, req.Categoria != nil); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE proveedores
+		SET nombre = $1, rut = $2, email = NULLIF($3, ''), telefono = NULLIF($4, ''), banco = NULLIF($5, ''),
+		    tipo_cuenta = NULLIF($6, ''), numero_cuenta = NULLIF($7, ''), categoria = NULLIF($8, ''),
+		    notas = NULLIF($9, ''), activo = $10, updated_at = NOW()
+		WHERE id = $11`,
+		p.Nombre, p.RUT, p.Email, p.Telefono, p.Banco, p.TipoCuenta, p.NumeroCuenta, p.Categoria,
+		p.Notas, p.Activo, p.ID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+			return nil, ErrProveedorExists
+		}
+		return nil, err
+	}
+
+	err = registrarAuditoria(ctx, tx, "proveedor", p.ID, "actualizar", "", userID, map[string]interface{}{
+		"anterior": anterior,
+		"cambios":  req,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetByID(ctx, p.ID)
+}
+
+// validar checks the required data; a category being set must be an active
+// egreso one.
+func (s *ProveedorService) validar(ctx context.Context, p *models.Proveedor, nuevaCategoria bool) error {
+	if p.Nombre == "" || len(p.Nombre) > 255 {
+		return ErrInvalidProveedor
+	}
+	return validarCategoria(ctx, s.db.Pool, p.Categoria, models.MovimientoEgreso, nuevaCategoria)
+}
@@ -0,0 +1,342 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/condominio/backend/internal/database"
+	"github.com/condominio/backend/internal/export"
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/email"
+)
+
+// Overdue notices are only sent for gastos that fell due recently, so enabling
+// reminders does not flood residents about old debt.
+const maxDiasAvisoVencido = 30
+
+type RecordatorioService struct {
+	db    *database.DB
+	email *email.Service
+	cfg   models.RecordatorioConfig
+}
+
+func NewRecordatorioService(db *database.DB, emailSvc *email.Service, cfg models.RecordatorioConfig) *RecordatorioService {
+	dias := append([]int(nil), cfg.DiasAntes...)
+	sort.Ints(dias)
+	cfg.DiasAntes = dias
+	return &RecordatorioService{db: db, email: emailSvc, cfg: cfg}
+}
+
+// gastoRecordatorio is a gasto that may need a reminder.
+type gastoRecordatorio struct {
+	id               string
+	parcelaID        int
+	parcelaNumero    string
+	year             int
+	month            int
+	fechaVencimiento time.Time
+	pendiente        float64
+	diasRestantes    int
+}
+
+// ProcesarRecordatorios sends every reminder that is due: the notice of a new
+// periodo, the reminders before fecha_vencimiento and the overdue notice.
+// Parcelas already paid or under a convenio are skipped, and each reminder is
+// recorded before it goes out so it is never sent twice.
+func (s *RecordatorioService) ProcesarRecordatorios(ctx context.Context) (*models.ProcesarRecordatoriosResult, error) {
+	result := &models.ProcesarRecordatoriosResult{}
+	var envios []email.Email
+
+	procesar := func(gastos []gastoRecordatorio, tipo models.RecordatorioTipo, dias func(gastoRecordatorio) int, contador *int) error {
+		for _, g := range gastos {
+			notificaciones, correos, enviado, err := s.registrarRecordatorio(ctx, g, tipo, dias(g))
+			if err != nil {
+				return err
+			}
+			if !enviado {
+				continue
+			}
+			*contador++
+			result.Notificaciones += notificaciones
+			result.Emails += len(correos)
+			envios = append(envios, correos...)
+		}
+		return nil
+	}
+	sinDias := func(gastoRecordatorio) int { return 0 }
+
+	if s.cfg.Emision {
+		gastos, err := s.gastosPendientes(ctx, `
+			g.status = 'pending' AND pg.fecha_vencimiento >= CURRENT_DATE
+			AND NOT EXISTS (SELECT 1 FROM recordatorios_gasto r WHERE r.gasto_comun_id = g.id AND r.tipo = 'emision')`)
+		if err != nil {
+			return nil, err
+		}
+		if err := procesar(gastos, models.RecordatorioEmision, sinDias, &result.Emision); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(s.cfg.DiasAntes) > 0 {
+		maxDias := s.cfg.DiasAntes[len(s.cfg.DiasAntes)-1]
+		// A notice sent today already told the resident about the due date
+		gastos, err := s.gastosPendientes(ctx, fmt.Sprintf(`
+			g.status = 'pending' AND pg.fecha_vencimiento >= CURRENT_DATE
+			AND pg.fecha_vencimiento - CURRENT_DATE <= %d
+			AND NOT EXISTS (SELECT 1 FROM recordatorios_gasto r
+			                WHERE r.gasto_comun_id = g.id AND r.created_at >= CURRENT_DATE)`, maxDias))
+		if err != nil {
+			return nil, err
+		}
+		if err := procesar(gastos, models.RecordatorioPrevio, s.ventanaPrevio, &result.Previos); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.cfg.Vencido {
+		gastos, err := s.gastosPendientes(ctx, fmt.Sprintf(`
+			g.status = 'overdue' AND pg.fecha_vencimiento >= CURRENT_DATE - %d
+			AND NOT EXISTS (SELECT 1 FROM recordatorios_gasto r WHERE r.gasto_comun_id = g.id AND r.tipo = 'vencido')`,
+			maxDiasAvisoVencido))
+		if err != nil {
+			return nil, err
+		}
+		if err := procesar(gastos, models.RecordatorioVencido, sinDias, &result.Vencidos); err != nil {
+			return nil, err
+		}
+	}
+
+	s.enviarCorreos(envios)
+	return result, nil
+}
+
+func (s *RecordatorioService) enviarCorreos(envios []email.Email) {
+	if s.email == nil || len(envios) == 0 {
+		return
+	}
+	go func() {
+		for _, e := range envios {
+			if err := s.email.Send(e); err != nil {
+				log.Printf("[EMAIL] Failed to send recordatorio to %v: %v", e.To, err)
+			}
+		}
+	}()
+}
+
+// NotificarEmision sends the notice of a new periodo to every parcela with
+// balance, right when the periodo opens. It runs even if emission reminders
+// are disabled for the scheduler, and parcelas already notified are skipped.
+func (s *RecordatorioService) NotificarEmision(ctx context.Context, periodoID string) (*models.ProcesarRecordatoriosResult, error) {
+	gastos, err := s.gastosPendientes(ctx, `
+		g.status = 'pending' AND pg.id = $1
+		AND NOT EXISTS (SELECT 1 FROM recordatorios_gasto r WHERE r.gasto_comun_id = g.id AND r.tipo = 'emision')`,
+		periodoID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ProcesarRecordatoriosResult{}
+	var envios []email.Email
+	for _, g := range gastos {
+		notificaciones, correos, enviado, err := s.registrarRecordatorio(ctx, g, models.RecordatorioEmision, 0)
+		if err != nil {
+			return nil, err
+		}
+		if !enviado {
+			continue
+		}
+		result.Emision++
+		result.Notificaciones += notificaciones
+		result.Emails += len(correos)
+		envios = append(envios, correos...)
+	}
+
+	s.enviarCorreos(envios)
+	return result, nil
+}
+
+// ventanaPrevio returns the smallest configured day count that still covers
+// the days left, so each window produces one reminder even if a run is missed.
+func (s *RecordatorioService) ventanaPrevio(g gastoRecordatorio) int {
+	for _, d := range s.cfg.DiasAntes {
+		if g.diasRestantes <= d {
+			return d
+		}
+	}
+	return s.cfg.DiasAntes[len(s.cfg.DiasAntes)-1]
+}
+
+func (s *RecordatorioService) gastosPendientes(ctx context.Context, where string, args ...interface{}) ([]gastoRecordatorio, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT g.id, g.parcela_id, p.numero, pg.year, pg.month, pg.fecha_vencimiento,
+		       g.monto - g.monto_pagado, pg.fecha_vencimiento - CURRENT_DATE
+		FROM gastos_comunes g
+		JOIN periodos_gasto pg ON g.periodo_id = pg.id
+		JOIN parcelas p ON g.parcela_id = p.id
+		WHERE g.monto_pagado < g.monto AND `+where+`
+		ORDER BY pg.fecha_vencimiento, p.numero`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	gastos := []gastoRecordatorio{}
+	for rows.Next() {
+		var g gastoRecordatorio
+		err := rows.Scan(&g.id, &g.parcelaID, &g.parcelaNumero, &g.year, &g.month, &g.fechaVencimiento,
+			&g.pendiente, &g.diasRestantes)
+		if err != nil {
+			return nil, err
+		}
+		gastos = append(gastos, g)
+	}
+	return gastos, rows.Err()
+}
+
+// registrarRecordatorio records the reminder and creates the in-app
+// notifications in one transaction. It returns enviado=false when another run
+// already sent it. Emails are returned to be sent after the commit.
+func (s *RecordatorioService) registrarRecordatorio(ctx context.Context, g gastoRecordatorio, tipo models.RecordatorioTipo, dias int) (int, []email.Email, bool, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, nil, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var recordatorioID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO recordatorios_gasto (gasto_comun_id, tipo, dias)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (gasto_comun_id, tipo, dias) DO NOTHING
+		RETURNING id`, g.id, tipo, dias).Scan(&recordatorioID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil, false, nil
+		}
+		return 0, nil, false, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT u.id, u.name, u.email, COALESCE(pn.email_gastos, TRUE), COALESCE(pn.app_gastos, TRUE)
+		FROM users u
+		LEFT JOIN preferencias_notificacion pn ON pn.user_id = u.id
+		WHERE u.parcela_id = $1 AND u.role IN ('vecino', 'directiva')`, g.parcelaID)
+	if err != nil {
+		return 0, nil, false, err
+	}
+	type destinatario struct {
+		id, nombre, correo string
+		email, app         bool
+	}
+	var destinatarios []destinatario
+	for rows.Next() {
+		var d destinatario
+		if err := rows.Scan(&d.id, &d.nombre, &d.correo, &d.email, &d.app); err != nil {
+			rows.Close()
+			return 0, nil, false, err
+		}
+		destinatarios = append(destinatarios, d)
+	}
+	rows.Close()
+
+	titulo, cuerpo := mensajeRecordatorio(g, tipo)
+	periodo := export.NombrePeriodo(g.year, g.month)
+
+	notificaciones := 0
+	var correos []email.Email
+	for _, d := range destinatarios {
+		if d.app {
+			_, err = tx.Exec(ctx, `
+				INSERT INTO notificaciones (user_id, title, body, type, reference_id)
+				VALUES ($1, $2, $3, $4, $5)`,
+				d.id, titulo, cuerpo, models.NotificationTypeGastoComun, g.id)
+			if err != nil {
+				return 0, nil, false, err
+			}
+			notificaciones++
+		}
+		if d.email && d.correo != "" {
+			correos = append(correos, email.Email{
+				To:       []string{d.correo},
+				Subject:  titulo + " - Comunidad Viña Pelvin",
+				Template: email.TemplateGastoComun,
+				Data: map[string]string{
+					"Nombre":           d.nombre,
+					"Periodo":          periodo,
+					"Monto":            strings.TrimPrefix(export.FormatCLP(g.pendiente), "$"),
+					"Parcela":          g.parcelaNumero,
+					"FechaVencimiento": g.fechaVencimiento.Format("02-01-2006"),
+					"Descripcion":      cuerpo,
+					"URL":              s.cfg.FrontendURL,
+				},
+			})
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE recordatorios_gasto SET notificaciones = $1, emails = $2 WHERE id = $3`,
+		notificaciones, len(correos), recordatorioID)
+	if err != nil {
+		return 0, nil, false, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, nil, false, err
+	}
+	return notificaciones, correos, true, nil
+}
+
+func mensajeRecordatorio(g gastoRecordatorio, tipo models.RecordatorioTipo) (string, string) {
+	periodo := export.NombrePeriodo(g.year, g.month)
+	monto := export.FormatCLP(g.pendiente)
+	vence := g.fechaVencimiento.Format("02-01-2006")
+
+	switch tipo {
+	case models.RecordatorioEmision:
+		return "Gasto común " + periodo,
+			fmt.Sprintf("Se emitió el gasto común de %s por %s. Vence el %s.", periodo, monto, vence)
+	case models.RecordatorioPrevio:
+		cuando := fmt.Sprintf("vence en %d días (%s)", g.diasRestantes, vence)
+		switch g.diasRestantes {
+		case 0:
+			cuando = "vence hoy"
+		case 1:
+			cuando = "vence mañana"
+		}
+		return "Recordatorio gasto común " + periodo,
+			fmt.Sprintf("Su gasto común de %s %s. Saldo pendiente: %s.", periodo, cuando, monto)
+	default:
+		return "Gasto común " + periodo + " vencido",
+			fmt.Sprintf("Su gasto común de %s venció el %s. Saldo pendiente: %s.", periodo, vence, monto)
+	}
+}
+
+// ListRecordatorios returns the reminders sent for a gasto.
+func (s *RecordatorioService) ListRecordatorios(ctx context.Context, gastoID string) ([]models.RecordatorioGasto, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT id, gasto_comun_id, tipo, dias, notificaciones, emails, created_at
+		FROM recordatorios_gasto
+		WHERE gasto_comun_id = $1
+		ORDER BY created_at`, gastoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recordatorios := []models.RecordatorioGasto{}
+	for rows.Next() {
+		var r models.RecordatorioGasto
+		if err := rows.Scan(&r.ID, &r.GastoComunID, &r.Tipo, &r.Dias, &r.Notificaciones, &r.Emails, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		recordatorios = append(recordatorios, r)
+	}
+	return recordatorios, rows.Err()
+}
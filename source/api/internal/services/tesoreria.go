@@ -2,13 +2,30 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
 
 	"github.com/condominio/backend/internal/database"
 	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/money"
+)
+
+var (
+	ErrMovimientoNotFound      = errors.New("movimiento not found")
+	ErrMovimientoAnulado       = errors.New("movimiento is void")
+	ErrMovimientoReverso       = errors.New("reverse entries cannot be changed")
+	ErrInvalidMovimiento       = errors.New("invalid movimiento")
+	ErrAdjuntoNotFound         = errors.New("attachment not found")
+	ErrMovimientoTransferencia = errors.New("transfer entries cannot be edited; void the transfer instead")
+	ErrMovimientoVinculado     = errors.New("movimientos posted from a pago or factura cannot be edited; void it and post it again from its source")
 )
 
+const correlativoComprobante = "comprobante_tesoreria"
+
 type TesoreriaService struct {
 	db *database.DB
 }
@@ -17,6 +34,26 @@ func NewTesoreriaService(db *database.DB) *TesoreriaService {
 	return &TesoreriaService{db: db}
 }
 
+const movimientoColumns = `
+	m.id, m.comprobante, m.cuenta_id, c.nombre, m.description, m.amount, m.type, COALESCE(m.category, ''),
+	m.date, m.anulado_at, m.anulado_by, COALESCE(m.motivo_anulacion, ''), m.reverso_de,
+	m.transferencia_id, m.pago_id,
+	(SELECT COUNT(*) FROM movimientos_adjuntos a WHERE a.movimiento_id = m.id),
+	m.created_by, COALESCE(u.name, ''), m.created_at, m.updated_at`
+
+const movimientoJoins = `
+	FROM movimientos_tesoreria m
+	JOIN cuentas_tesoreria c ON c.id = m.cuenta_id
+	LEFT JOIN users u ON m.created_by = u.id`
+
+func scanMovimiento(row pgx.Row, m *models.Movimiento) error {
+	return row.Scan(&m.ID, &m.Comprobante, &m.CuentaID, &m.CuentaNombre, &m.Description, &m.Amount, &m.Type, &m.Category,
+		&m.Date, &m.AnuladoAt, &m.AnuladoBy, &m.MotivoAnulacion, &m.ReversoDe,
+		&m.TransferenciaID, &m.PagoID,
+		&m.TotalAdjuntos,
+		&m.CreatedBy, &m.CreatorName, &m.CreatedAt, &m.UpdatedAt)
+}
+
 func (s *TesoreriaService) List(ctx context.Context, filter models.MovimientoFilter) (*models.MovimientoListResponse, error) {
 	if filter.Page < 1 {
 		filter.Page = 1
@@ -26,44 +63,69 @@ func (s *TesoreriaService) List(ctx context.Context, filter models.MovimientoFil
 	}
 	offset := (filter.Page - 1) * filter.PerPage
 
-	query := `
-		SELECT m.id, m.description, m.amount, m.type, COALESCE(m.category, ''),
-		       m.date, m.created_by, COALESCE(u.name, ''), m.created_at, m.updated_at
-		FROM movimientos_tesoreria m
-		LEFT JOIN users u ON m.created_by = u.id
-		WHERE 1=1`
-	countQuery := `SELECT COUNT(*) FROM movimientos_tesoreria WHERE 1=1`
+	query := `SELECT ` + movimientoColumns + movimientoJoins + ` WHERE 1=1`
+	countQuery := `SELECT COUNT(*) FROM movimientos_tesoreria m WHERE 1=1`
+	where := ""
 	args := []interface{}{}
 	argCount := 0
 
+	if filter.CuentaID != "" {
+		argCount++
+		where += ` AND m.cuenta_id = $` + strconv.Itoa(argCount)
+		args = append(args, filter.CuentaID)
+	}
+
 	if filter.Type != "" {
 		argCount++
-		query += ` AND m.type = $` + strconv.Itoa(argCount)
-		countQuery += ` AND type = $` + strconv.Itoa(argCount)
+		where += ` AND m.type = $` + strconv.Itoa(argCount)
 		args = append(args, filter.Type)
 	}
 
 	if filter.Year > 0 {
 		argCount++
-		query += ` AND EXTRACT(YEAR FROM m.date) = $` + strconv.Itoa(argCount)
-		countQuery += ` AND EXTRACT(YEAR FROM date) = $` + strconv.Itoa(argCount)
+		where += ` AND EXTRACT(YEAR FROM m.date) = $` + strconv.Itoa(argCount)
 		args = append(args, filter.Year)
 	}
 
 	if filter.Month > 0 {
 		argCount++
-		query += ` AND EXTRACT(MONTH FROM m.date) = $` + strconv.Itoa(argCount)
-		countQuery += ` AND EXTRACT(MONTH FROM date) = $` + strconv.Itoa(argCount)
+		where += ` AND EXTRACT(MONTH FROM m.date) = $` + strconv.Itoa(argCount)
 		args = append(args, filter.Month)
 	}
 
+	if filter.Category != "" {
+		argCount++
+		where += ` AND m.category = $` + strconv.Itoa(argCount)
+		args = append(args, filter.Category)
+	}
+
+	if filter.MontoMin != nil {
+		argCount++
+		where += ` AND m.amount >= $` + strconv.Itoa(argCount)
+		args = append(args, *filter.MontoMin)
+	}
+
+	if filter.MontoMax != nil {
+		argCount++
+		where += ` AND m.amount <= $` + strconv.Itoa(argCount)
+		args = append(args, *filter.MontoMax)
+	}
+
+	if filter.ConAdjunto != nil {
+		cond := `EXISTS`
+		if !*filter.ConAdjunto {
+			cond = `NOT EXISTS`
+		}
+		where += ` AND ` + cond + ` (SELECT 1 FROM movimientos_adjuntos a WHERE a.movimiento_id = m.id)`
+	}
+
 	var total int
-	err := s.db.Pool.QueryRow(ctx, countQuery, args...).Scan(&total)
+	err := s.db.Pool.QueryRow(ctx, countQuery+where, args...).Scan(&total)
 	if err != nil {
 		return nil, err
 	}
 
-	query += ` ORDER BY m.date DESC`
+	query += where + ` ORDER BY m.date DESC, m.comprobante DESC`
 	argCount++
 	query += fmt.Sprintf(` LIMIT $%d`, argCount)
 	args = append(args, filter.PerPage)
@@ -80,9 +142,7 @@ func (s *TesoreriaService) List(ctx context.Context, filter models.MovimientoFil
 	movimientos := []models.Movimiento{}
 	for rows.Next() {
 		var m models.Movimiento
-		err := rows.Scan(&m.ID, &m.Description, &m.Amount, &m.Type, &m.Category,
-			&m.Date, &m.CreatedBy, &m.CreatorName, &m.CreatedAt, &m.UpdatedAt)
-		if err != nil {
+		if err := scanMovimiento(rows, &m); err != nil {
 			return nil, err
 		}
 		movimientos = append(movimientos, m)
@@ -93,41 +153,557 @@ func (s *TesoreriaService) List(ctx context.Context, filter models.MovimientoFil
 		Total:       total,
 		Page:        filter.Page,
 		PerPage:     filter.PerPage,
-	}, nil
+	}, rows.Err()
+}
+
+// GetByID returns a movimiento with the list of its attachments.
+func (s *TesoreriaService) GetByID(ctx context.Context, id string) (*models.Movimiento, error) {
+	var m models.Movimiento
+	err := scanMovimiento(s.db.Pool.QueryRow(ctx, `SELECT `+movimientoColumns+movimientoJoins+` WHERE m.id = $1`, id), &m)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMovimientoNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT id, movimiento_id, nombre_archivo, content_type, tamano, created_by, created_at
+		FROM movimientos_adjuntos
+		WHERE movimiento_id = $1
+		ORDER BY created_at`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	m.Adjuntos = []models.AdjuntoMovimiento{}
+	for rows.Next() {
+		var a models.AdjuntoMovimiento
+		err := rows.Scan(&a.ID, &a.MovimientoID, &a.NombreArchivo, &a.ContentType, &a.Tamano,
+			&a.CreatedBy, &a.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		m.Adjuntos = append(m.Adjuntos, a)
+	}
+	return &m, rows.Err()
 }
 
 func (s *TesoreriaService) GetResumen(ctx context.Context) (*models.ResumenTesoreria, error) {
-	var ingresos, egresos float64
+	var ingresos, egresos money.Amount
 
+	// Voided movimientos and their reverse entries cancel out in the sums;
+	// transfers only move money between cuentas
 	err := s.db.Pool.QueryRow(ctx,
-		`SELECT COALESCE(SUM(amount), 0) FROM movimientos_tesoreria WHERE type = 'ingreso'`).Scan(&ingresos)
+		`SELECT COALESCE(SUM(amount), 0) FROM movimientos_tesoreria WHERE type = 'ingreso' AND transferencia_id IS NULL`).Scan(&ingresos)
 	if err != nil {
 		return nil, err
 	}
 
 	err = s.db.Pool.QueryRow(ctx,
-		`SELECT COALESCE(SUM(amount), 0) FROM movimientos_tesoreria WHERE type = 'egreso'`).Scan(&egresos)
+		`SELECT COALESCE(SUM(amount), 0) FROM movimientos_tesoreria WHERE type = 'egreso' AND transferencia_id IS NULL`).Scan(&egresos)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT c.id, c.codigo, c.nombre, c.tipo,
+		       COALESCE(SUM(m.amount) FILTER (WHERE m.type = 'ingreso'), 0),
+		       COALESCE(SUM(m.amount) FILTER (WHERE m.type = 'egreso'), 0)
+		FROM cuentas_tesoreria c
+		LEFT JOIN movimientos_tesoreria m ON m.cuenta_id = c.id
+		GROUP BY c.id
+		HAVING c.activa OR COUNT(m.id) > 0
+		ORDER BY c.principal DESC, c.nombre`)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	cuentas := []models.SaldoCuenta{}
+	for rows.Next() {
+		var c models.SaldoCuenta
+		if err := rows.Scan(&c.CuentaID, &c.Codigo, &c.Nombre, &c.Tipo, &c.Ingresos, &c.Egresos); err != nil {
+			return nil, err
+		}
+		c.Saldo = c.Ingresos - c.Egresos
+		cuentas = append(cuentas, c)
+	}
 
 	return &models.ResumenTesoreria{
 		TotalIngresos: ingresos,
 		TotalEgresos:  egresos,
 		Balance:       ingresos - egresos,
-	}, nil
+		Cuentas:       cuentas,
+	}, rows.Err()
 }
 
 func (s *TesoreriaService) Create(ctx context.Context, req *models.CreateMovimientoRequest, createdBy string) (*models.Movimiento, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err = validarCategoria(ctx, tx, req.Category, req.Type, true); err != nil {
+		return nil, err
+	}
+	cuentaID, err := cuentaMovimiento(ctx, tx, req.CuentaID)
+	if err != nil {
+		return nil, err
+	}
+
+	comprobante, err := siguienteCorrelativo(ctx, tx, correlativoComprobante)
+	if err != nil {
+		return nil, err
+	}
+
+	var id string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO movimientos_tesoreria (comprobante, cuenta_id, description, amount, type, category, date, created_by)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8)
+		RETURNING id`,
+		comprobante, cuentaID, req.Description, req.Amount, req.Type, req.Category, req.Date, createdBy).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = revisarAlertaPresupuesto(ctx, tx, req.Date.Year(), req.Category); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetByID(ctx, id)
+}
+
+// lockMovimiento locks a movimiento that can still be changed: neither void
+// nor the reverse entry of one.
+func lockMovimiento(ctx context.Context, tx pgx.Tx, id string) (*models.Movimiento, error) {
 	var m models.Movimiento
-	err := s.db.Pool.QueryRow(ctx, `
-		INSERT INTO movimientos_tesoreria (description, amount, type, category, date, created_by)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, description, amount, type, category, date, created_by, created_at, updated_at`,
-		req.Description, req.Amount, req.Type, req.Category, req.Date, createdBy).Scan(
-		&m.ID, &m.Description, &m.Amount, &m.Type, &m.Category, &m.Date, &m.CreatedBy, &m.CreatedAt, &m.UpdatedAt)
+	err := tx.QueryRow(ctx, `
+		SELECT id, comprobante, cuenta_id, description, amount, type, COALESCE(category, ''), date,
+		       anulado_at, reverso_de, transferencia_id, pago_id
+		FROM movimientos_tesoreria
+		WHERE id = $1
+		FOR UPDATE`, id).Scan(
+		&m.ID, &m.Comprobante, &m.CuentaID, &m.Description, &m.Amount, &m.Type, &m.Category, &m.Date,
+		&m.AnuladoAt, &m.ReversoDe, &m.TransferenciaID, &m.PagoID)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMovimientoNotFound
+		}
 		return nil, err
 	}
+	if m.AnuladoAt != nil {
+		return nil, ErrMovimientoAnulado
+	}
+	if m.ReversoDe != nil {
+		return nil, ErrMovimientoReverso
+	}
 	return &m, nil
 }
+
+// movimientoVinculado reports whether a locked movimiento was posted from a
+// pago, directly or in the daily posting, from a credit refund or from the
+// payment of a factura.
+// Its amount must keep matching the source, so it is voided, not edited.
+func movimientoVinculado(ctx context.Context, tx pgx.Tx, m *models.Movimiento) (bool, error) {
+	if m.PagoID != nil {
+		return true, nil
+	}
+	var vinculado bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM pagos_tesoreria WHERE movimiento_id = $1)
+		    OR EXISTS(SELECT 1 FROM creditos_parcela WHERE movimiento_id = $1)
+		    OR EXISTS(SELECT 1 FROM facturas WHERE movimiento_id = $1)`, m.ID).Scan(&vinculado)
+	return vinculado, err
+}
+
+// Update corrects a movimiento in place. The previous values go to the audit
+// trail together with the reason, so the yearly review can see what changed.
+// Transfers and movimientos posted from a pago or factura cannot be edited,
+// and the amount, type, cuenta and date are frozen inside a closed
+// reconciliation period, on either side of the change.
+func (s *TesoreriaService) Update(ctx context.Context, id string, req *models.UpdateMovimientoRequest, userID string) (*models.Movimiento, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	m, err := lockMovimiento(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if m.TransferenciaID != nil {
+		return nil, ErrMovimientoTransferencia
+	}
+	vinculado, err := movimientoVinculado(ctx, tx, m)
+	if err != nil {
+		return nil, err
+	}
+	if vinculado {
+		return nil, ErrMovimientoVinculado
+	}
+	antes := *m
+
+	if req.CuentaID != nil && *req.CuentaID != m.CuentaID {
+		if m.CuentaID, err = cuentaMovimiento(ctx, tx, *req.CuentaID); err != nil {
+			return nil, err
+		}
+	}
+	if req.Description != nil {
+		m.Description = *req.Description
+	}
+	if req.Amount != nil {
+		m.Amount = *req.Amount
+	}
+	if req.Type != nil {
+		m.Type = *req.Type
+	}
+	if req.Category != nil {
+		m.Category = *req.Category
+	}
+	if req.Date != nil {
+		m.Date = *req.Date
+	}
+	if m.Description == "" || m.Amount <= 0 ||
+		(m.Type != models.MovimientoIngreso && m.Type != models.MovimientoEgreso) {
+		return nil, ErrInvalidMovimiento
+	}
+	if m.Category != antes.Category || m.Type != antes.Type {
+		if err = validarCategoria(ctx, tx, m.Category, m.Type, m.Category != antes.Category); err != nil {
+			return nil, err
+		}
+	}
+	if m.Amount != antes.Amount || m.Type != antes.Type || m.CuentaID != antes.CuentaID || !m.Date.Equal(antes.Date) {
+		for _, c := range []*models.Movimiento{&antes, m} {
+			cerrado, err := movimientoEnCierre(ctx, tx, id, c.CuentaID, c.Date)
+			if err != nil {
+				return nil, err
+			}
+			if cerrado {
+				return nil, ErrMovimientoEnCierre
+			}
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE movimientos_tesoreria
+		SET description = $1, amount = $2, type = $3, category = NULLIF($4, ''), date = $5,
+		    cuenta_id = $6, updated_by = $7, updated_at = NOW()
+		WHERE id = $8`,
+		m.Description, m.Amount, m.Type, m.Category, m.Date, m.CuentaID, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	// The bank line it was reconciled with no longer fits
+	if m.Amount != antes.Amount || m.Type != antes.Type || m.CuentaID != antes.CuentaID {
+		if err = liberarLineaCartola(ctx, tx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	err = registrarAuditoria(ctx, tx, "movimiento_tesoreria", id, "correccion", req.Motivo, userID, map[string]interface{}{
+		"comprobante": m.Comprobante,
+		"antes": map[string]interface{}{
+			"cuenta_id": antes.CuentaID, "description": antes.Description, "amount": antes.Amount, "type": antes.Type,
+			"category": antes.Category, "date": antes.Date.Format("2006-01-02"),
+		},
+		"despues": map[string]interface{}{
+			"cuenta_id": m.CuentaID, "description": m.Description, "amount": m.Amount, "type": m.Type,
+			"category": m.Category, "date": m.Date.Format("2006-01-02"),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = revisarAlertaPresupuesto(ctx, tx, antes.Date.Year(), antes.Category); err != nil {
+		return nil, err
+	}
+	if m.Date.Year() != antes.Date.Year() || m.Category != antes.Category {
+		if err = revisarAlertaPresupuesto(ctx, tx, m.Date.Year(), m.Category); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetByID(ctx, id)
+}
+
+// Anular voids a movimiento with a reverse entry: a new movimiento with its
+// own comprobante, the same type and the opposite amount. The original stays
+// in the ledger marked as void. Voiding either entry of a transfer voids both.
+func (s *TesoreriaService) Anular(ctx context.Context, id string, motivo string, userID string) (*models.Movimiento, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	m, err := lockMovimiento(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if m.TransferenciaID != nil {
+		err = anularTransferencia(ctx, tx, *m.TransferenciaID, motivo, userID)
+	} else {
+		err = anularMovimiento(ctx, tx, m, motivo, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.GetByID(ctx, id)
+}
+
+// anularMovimiento books the reverse entry of a locked movimiento and marks it
+// as void. The reverse entry stays in the same cuenta and transfer.
+func anularMovimiento(ctx context.Context, tx pgx.Tx, m *models.Movimiento, motivo, userID string) error {
+	comprobante, err := siguienteCorrelativo(ctx, tx, correlativoComprobante)
+	if err != nil {
+		return err
+	}
+
+	var reversoID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO movimientos_tesoreria (comprobante, cuenta_id, description, amount, type, category, date,
+		                                   reverso_de, transferencia_id, pago_id, created_by)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), CURRENT_DATE, $7, $8, $9, NULLIF($10, '')::uuid)
+		RETURNING id`,
+		comprobante, m.CuentaID, fmt.Sprintf("Anulación comprobante N° %d: %s", m.Comprobante, truncar(m.Description, 200)),
+		-m.Amount, m.Type, m.Category, m.ID, m.TransferenciaID, m.PagoID, userID).Scan(&reversoID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE movimientos_tesoreria
+		SET anulado_at = NOW(), anulado_by = NULLIF($1, '')::uuid, motivo_anulacion = $2, updated_at = NOW()
+		WHERE id = $3`, userID, motivo, m.ID)
+	if err != nil {
+		return err
+	}
+
+	err = registrarAuditoria(ctx, tx, "movimiento_tesoreria", m.ID, "anulacion", motivo, userID, map[string]interface{}{
+		"comprobante":         m.Comprobante,
+		"amount":              m.Amount,
+		"type":                m.Type,
+		"reverso_id":          reversoID,
+		"comprobante_reverso": comprobante,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = liberarFacturaPagada(ctx, tx, m.ID); err != nil {
+		return err
+	}
+	if err = liberarPagosContabilizados(ctx, tx, m.ID); err != nil {
+		return err
+	}
+	if err = liberarLineaCartola(ctx, tx, m.ID); err != nil {
+		return err
+	}
+
+	// The reverse entry is booked today, which may fall in another budget year
+	for _, year := range []int{m.Date.Year(), fechaHoy().Year()} {
+		if err = revisarAlertaPresupuesto(ctx, tx, year, m.Category); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ============================================
+// CUENTAS Y TRANSFERENCIAS
+// ============================================
+
+// cuentaMovimiento resolves the cuenta of a new movimiento: the one given,
+// which must be active, or the principal one.
+func cuentaMovimiento(ctx context.Context, q querier, cuentaID string) (string, error) {
+	var id string
+	var err error
+	if cuentaID == "" {
+		err = q.QueryRow(ctx, `SELECT id FROM cuentas_tesoreria WHERE principal`).Scan(&id)
+	} else {
+		err = q.QueryRow(ctx, `SELECT id FROM cuentas_tesoreria WHERE id::text = $1 AND activa`, cuentaID).Scan(&id)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrCuentaInvalida
+	}
+	return id, err
+}
+
+// transferir books a transfer inside tx: an egreso in the origin and an
+// ingreso in the destination sharing a transferencia_id. pagoID is set for
+// reserve fund allocations.
+func transferir(ctx context.Context, tx pgx.Tx, origenID, destinoID string, monto money.Amount, fecha time.Time, descripcion string, pagoID *string, userID string) (string, error) {
+	var transferenciaID string
+	if err := tx.QueryRow(ctx, `SELECT gen_random_uuid()::text`).Scan(&transferenciaID); err != nil {
+		return "", err
+	}
+	for _, e := range []struct {
+		cuentaID string
+		tipo     models.MovimientoType
+	}{{origenID, models.MovimientoEgreso}, {destinoID, models.MovimientoIngreso}} {
+		comprobante, err := siguienteCorrelativo(ctx, tx, correlativoComprobante)
+		if err != nil {
+			return "", err
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO movimientos_tesoreria (comprobante, cuenta_id, description, amount, type, date,
+			                                   transferencia_id, pago_id, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, '')::uuid)`,
+			comprobante, e.cuentaID, descripcion, monto, e.tipo, fecha, transferenciaID, pagoID, userID)
+		if err != nil {
+			return "", err
+		}
+	}
+	return transferenciaID, nil
+}
+
+// anularTransferencia voids every entry of a transfer still standing.
+func anularTransferencia(ctx context.Context, tx pgx.Tx, transferenciaID, motivo, userID string) error {
+	rows, err := tx.Query(ctx, `
+		SELECT id FROM movimientos_tesoreria
+		WHERE transferencia_id = $1 AND reverso_de IS NULL AND anulado_at IS NULL
+		ORDER BY comprobante`, transferenciaID)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		m, err := lockMovimiento(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err = anularMovimiento(ctx, tx, m, motivo, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ============================================
+// ADJUNTOS
+// ============================================
+
+// AddAdjunto attaches a scanned receipt. Void movimientos still accept
+// attachments: the paperwork of a voided payment is part of the record.
+func (s *TesoreriaService) AddAdjunto(ctx context.Context, movimientoID string, a *models.AdjuntoMovimiento, userID string) (*models.AdjuntoMovimiento, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var comprobante int64
+	err = tx.QueryRow(ctx, `SELECT comprobante FROM movimientos_tesoreria WHERE id = $1`, movimientoID).Scan(&comprobante)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMovimientoNotFound
+		}
+		return nil, err
+	}
+
+	a.MovimientoID = movimientoID
+	a.Tamano = len(a.Contenido)
+	err = tx.QueryRow(ctx, `
+		INSERT INTO movimientos_adjuntos (movimiento_id, nombre_archivo, content_type, tamano, contenido, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_by, created_at`,
+		movimientoID, a.NombreArchivo, a.ContentType, a.Tamano, a.Contenido, userID).Scan(
+		&a.ID, &a.CreatedBy, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = registrarAuditoria(ctx, tx, "movimiento_tesoreria", movimientoID, "adjuntar", "", userID, map[string]interface{}{
+		"comprobante": comprobante,
+		"adjunto_id":  a.ID,
+		"archivo":     a.NombreArchivo,
+		"tamano":      a.Tamano,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// GetAdjunto returns an attachment with its content for download.
+func (s *TesoreriaService) GetAdjunto(ctx context.Context, movimientoID, adjuntoID string) (*models.AdjuntoMovimiento, error) {
+	var a models.AdjuntoMovimiento
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT id, movimiento_id, nombre_archivo, content_type, tamano, contenido, created_by, created_at
+		FROM movimientos_adjuntos
+		WHERE id = $1 AND movimiento_id = $2`, adjuntoID, movimientoID).Scan(
+		&a.ID, &a.MovimientoID, &a.NombreArchivo, &a.ContentType, &a.Tamano, &a.Contenido, &a.CreatedBy, &a.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAdjuntoNotFound
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+// DeleteAdjunto removes an attachment uploaded by mistake. The audit entry
+// keeps its name and size.
+func (s *TesoreriaService) DeleteAdjunto(ctx context.Context, movimientoID, adjuntoID, motivo, userID string) error {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var nombre string
+	var tamano int
+	err = tx.QueryRow(ctx, `
+		DELETE FROM movimientos_adjuntos
+		WHERE id = $1 AND movimiento_id = $2
+		RETURNING nombre_archivo, tamano`, adjuntoID, movimientoID).Scan(&nombre, &tamano)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAdjuntoNotFound
+		}
+		return err
+	}
+
+	err = registrarAuditoria(ctx, tx, "movimiento_tesoreria", movimientoID, "eliminar_adjunto", motivo, userID, map[string]interface{}{
+		"adjunto_id": adjuntoID,
+		"archivo":    nombre,
+		"tamano":     tamano,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
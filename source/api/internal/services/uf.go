@@ -0,0 +1,299 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/condominio/backend/internal/database"
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/money"
+)
+
+var (
+	ErrValorUFNotFound     = errors.New("UF value not found")
+	ErrInvalidValorUF      = errors.New("invalid UF value")
+	ErrValorUFNoDisponible = errors.New("no UF value loaded for the date")
+)
+
+type UFService struct {
+	db *database.DB
+}
+
+func NewUFService(db *database.DB) *UFService {
+	return &UFService{db: db}
+}
+
+// ============================================
+// VALORES UF
+// ============================================
+
+func (s *UFService) ListValoresUF(ctx context.Context, filter models.ValorUFFilter) ([]models.ValorUF, error) {
+	query := `SELECT fecha, valor, created_by, created_at, updated_at FROM valores_uf WHERE 1=1`
+	args := []interface{}{}
+	if filter.Desde != nil {
+		args = append(args, *filter.Desde)
+		query += ` AND fecha >= $` + strconv.Itoa(len(args))
+	}
+	if filter.Hasta != nil {
+		args = append(args, *filter.Hasta)
+		query += ` AND fecha <= $` + strconv.Itoa(len(args))
+	}
+	query += ` ORDER BY fecha DESC`
+	// Without a range, a year of values is plenty for the admin screen
+	if filter.Desde == nil && filter.Hasta == nil {
+		query += ` LIMIT 366`
+	}
+
+	rows, err := s.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	valores := []models.ValorUF{}
+	for rows.Next() {
+		var v models.ValorUF
+		if err := rows.Scan(&v.Fecha, &v.Valor, &v.CreatedBy, &v.CreatedAt, &v.UpdatedAt); err != nil {
+			return nil, err
+		}
+		valores = append(valores, v)
+	}
+	return valores, rows.Err()
+}
+
+func (s *UFService) GetValorUF(ctx context.Context, fecha time.Time) (*models.ValorUF, error) {
+	var v models.ValorUF
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT fecha, valor, created_by, created_at, updated_at FROM valores_uf WHERE fecha = $1`,
+		fecha).Scan(&v.Fecha, &v.Valor, &v.CreatedBy, &v.CreatedAt, &v.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrValorUFNotFound
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// SetValorUF creates or corrects the value of a day. Periodos already issued
+// keep the value they were converted with.
+func (s *UFService) SetValorUF(ctx context.Context, fecha time.Time, valor float64, userID string) (*models.ValorUF, error) {
+	if valor <= 0 {
+		return nil, ErrInvalidValorUF
+	}
+
+	_, err := s.db.Pool.Exec(ctx, `
+		INSERT INTO valores_uf (fecha, valor, created_by)
+		VALUES ($1, $2, NULLIF($3, '')::uuid)
+		ON CONFLICT (fecha) DO UPDATE SET valor = EXCLUDED.valor, updated_at = NOW()`,
+		fecha, valor, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = registrarAuditoria(ctx, s.db.Pool, "valor_uf", fecha.Format("2006-01-02"), "actualizar", "", userID,
+		map[string]interface{}{"valor": valor})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetValorUF(ctx, fecha)
+}
+
+func (s *UFService) DeleteValorUF(ctx context.Context, fecha time.Time, userID string) error {
+	result, err := s.db.Pool.Exec(ctx, `DELETE FROM valores_uf WHERE fecha = $1`, fecha)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrValorUFNotFound
+	}
+	return registrarAuditoria(ctx, s.db.Pool, "valor_uf", fecha.Format("2006-01-02"), "eliminar", "", userID, nil)
+}
+
+// ImportarValoresUF loads a CSV of fecha,valor lines such as the yearly tables
+// published by the SII. The separator (comma or semicolon) and the number
+// format are detected; a header line and unreadable lines are reported and
+// skipped, and the valid ones are saved in a single transaction.
+func (s *UFService) ImportarValoresUF(ctx context.Context, data []byte, userID string) (*models.ImportarUFResult, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = ','
+	if primera, _, _ := strings.Cut(string(data), "\n"); strings.Contains(primera, ";") {
+		r.Comma = ';'
+	}
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	result := &models.ImportarUFResult{Errores: []string{}}
+	type valor struct {
+		fecha time.Time
+		valor float64
+	}
+	var valores []valor
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrArchivoInvalido, err)
+		}
+		linea, _ := r.FieldPos(0)
+		if len(rec) < 2 || strings.TrimSpace(rec[0]) == "" {
+			continue
+		}
+		fecha, errFecha := parseFechaUF(rec[0])
+		v, errValor := parseValorUF(rec[1])
+		if errFecha != nil || errValor != nil || v <= 0 {
+			if linea > 1 || errFecha == nil { // the first line may be a header
+				result.Errores = append(result.Errores, fmt.Sprintf("Línea %d: %s;%s", linea, rec[0], rec[1]))
+			}
+			continue
+		}
+		valores = append(valores, valor{fecha, v})
+	}
+	if len(valores) == 0 {
+		return nil, fmt.Errorf("%w: no UF values found", ErrArchivoInvalido)
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, v := range valores {
+		var insertado bool
+		err = tx.QueryRow(ctx, `
+			INSERT INTO valores_uf (fecha, valor, created_by)
+			VALUES ($1, $2, NULLIF($3, '')::uuid)
+			ON CONFLICT (fecha) DO UPDATE SET valor = EXCLUDED.valor, updated_at = NOW()
+			RETURNING xmax = 0`, v.fecha, v.valor, userID).Scan(&insertado)
+		if err != nil {
+			return nil, err
+		}
+		if insertado {
+			result.Insertados++
+		} else {
+			result.Actualizados++
+		}
+	}
+
+	rango := valores[0].fecha.Format("2006-01-02") + "/" + valores[len(valores)-1].fecha.Format("2006-01-02")
+	err = registrarAuditoria(ctx, tx, "valor_uf", rango, "importar", "", userID, result)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func parseFechaUF(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	for _, layout := range []string{"2006-01-02", "02-01-2006", "02/01/2006"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, ErrInvalidFecha
+}
+
+// parseValorUF reads "39.485,65", "39485,65", "39,485.65" or "39485.65".
+// When only dots appear, a group of three digits after the last one means
+// thousands (UF values always carry two decimals).
+func parseValorUF(v string) (float64, error) {
+	v = strings.NewReplacer("$", "", " ", "").Replace(strings.TrimSpace(v))
+	coma, punto := strings.LastIndex(v, ","), strings.LastIndex(v, ".")
+	switch {
+	case coma >= 0 && coma > punto:
+		v = strings.Replace(strings.ReplaceAll(v, ".", ""), ",", ".", 1)
+	case punto >= 0 && coma >= 0:
+		v = strings.ReplaceAll(v, ",", "")
+	case punto >= 0 && len(v)-punto-1 == 3:
+		v = strings.ReplaceAll(v, ".", "")
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+// ============================================
+// CONVERSION
+// ============================================
+
+// fechaHoy is today's date as stored in DATE columns.
+func fechaHoy() time.Time {
+	y, m, d := time.Now().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// conversionUF is a UF amount expressed in CLP.
+type conversionUF struct {
+	montoUF float64
+	valor   float64
+	fecha   time.Time
+	clp     money.Amount
+}
+
+// convertirUF converts a UF amount to whole pesos. Issuing a periodo needs the
+// value of that exact day; a draft only shows a provisional amount, so it
+// uses the latest value known.
+func convertirUF(ctx context.Context, q querier, montoUF float64, fecha time.Time, exacta bool) (*conversionUF, error) {
+	c := &conversionUF{montoUF: montoUF}
+	query := `SELECT fecha, valor FROM valores_uf WHERE fecha = $1`
+	if !exacta {
+		query = `SELECT fecha, valor FROM valores_uf WHERE fecha <= $1 ORDER BY fecha DESC LIMIT 1`
+	}
+	err := q.QueryRow(ctx, query, fecha).Scan(&c.fecha, &c.valor)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", ErrValorUFNoDisponible, fecha.Format("02-01-2006"))
+		}
+		return nil, err
+	}
+	c.clp = money.FromPesos(int64(math.Round(montoUF * c.valor)))
+	return c, nil
+}
+
+// valorUFVigente returns the latest UF value up to fecha, or nil if the table
+// has none; reports show UF only when it is known.
+func valorUFVigente(ctx context.Context, q querier, fecha time.Time) (*float64, error) {
+	var v float64
+	err := q.QueryRow(ctx, `SELECT valor FROM valores_uf WHERE fecha <= $1 ORDER BY fecha DESC LIMIT 1`,
+		fecha).Scan(&v)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// enUF expresses a CLP amount in UF, rounded to four decimals.
+func enUF(clp money.Amount, valor float64) float64 {
+	return math.Round(clp.Float64()/valor*10000) / 10000
+}
+
+// completarUF fills the UF totals of a periodo issued in UF.
+func completarUF(p *models.PeriodoGasto) {
+	if p.ValorUF == nil || *p.ValorUF <= 0 {
+		return
+	}
+	recaudado := enUF(p.MontoRecaudado, *p.ValorUF)
+	pendiente := enUF(p.MontoPendiente, *p.ValorUF)
+	p.MontoRecaudadoUF = &recaudado
+	p.MontoPendienteUF = &pendiente
+}
@@ -6,25 +6,37 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
 	"github.com/condominio/backend/internal/database"
 	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/email"
 )
 
 var (
 	ErrVotacionNotFound    = errors.New("votacion not found")
 	ErrVotacionNotActive   = errors.New("votacion is not active")
-	ErrAlreadyVoted        = errors.New("user has already voted")
+	ErrAlreadyVoted        = errors.New("parcela has already voted")
 	ErrInvalidOpcion       = errors.New("invalid option for this votacion")
 	ErrAbstentionNotAllowed = errors.New("abstention is not allowed")
 	ErrUserNoParcela       = errors.New("user has no associated parcela")
+	ErrInvalidCoeficiente  = errors.New("coeficiente must be greater than 0 and less than 1000")
+	ErrCoeficienteEnUso    = errors.New("coeficientes cannot change while a weighted votacion is active")
+	ErrInvalidTipoVotacion = errors.New("tipo must be simple, multiple or preferencial and metodo_conteo irv or borda")
+	ErrInvalidSelecciones  = errors.New("min_selecciones and max_selecciones must satisfy 1 <= min <= max <= number of opciones")
+	ErrInvalidSeleccion    = errors.New("invalid selection of options")
+	ErrInvalidFechasVotacion = errors.New("end_date must be in the future and after start_date")
 )
 
 type VotacionService struct {
-	db *database.DB
+	db    *database.DB
+	email *email.Service
+	cfg   models.VotacionConfig
 }
 
-func NewVotacionService(db *database.DB) *VotacionService {
-	return &VotacionService{db: db}
+func NewVotacionService(db *database.DB, emailSvc *email.Service, cfg models.VotacionConfig) *VotacionService {
+	return &VotacionService{db: db, email: emailSvc, cfg: cfg}
 }
 
 func (s *VotacionService) List(ctx context.Context, filter models.VotacionFilter) (*models.VotacionListResponse, error) {
@@ -38,10 +50,12 @@ func (s *VotacionService) List(ctx context.Context, filter models.VotacionFilter
 
 	query := `
 		SELECT v.id, v.title, v.description, v.status, v.start_date, v.end_date,
-		       v.requires_quorum, v.quorum_percentage, v.allow_abstention,
+		       v.requires_quorum, v.quorum_percentage, v.allow_abstention, v.ponderada, v.evento_id, v.secreta,
+		       v.tipo, v.min_selecciones, v.max_selecciones,
+		       CASE WHEN v.tipo = 'preferencial' THEN v.metodo_conteo ELSE '' END, v.allow_vote_change,
 		       v.created_by, COALESCE(u.name, '') as creator_name,
 		       v.created_at, v.updated_at,
-		       (SELECT COUNT(*) FROM votos WHERE votacion_id = v.id) as total_votos
+		       (SELECT COUNT(*) FROM votos WHERE votacion_id = v.id AND parcela_id IS NOT NULL) as total_votos
 		FROM votaciones v
 		LEFT JOIN users u ON v.created_by = u.id
 		WHERE 1=1`
@@ -94,7 +108,8 @@ func (s *VotacionService) List(ctx context.Context, filter models.VotacionFilter
 		var v models.Votacion
 		err := rows.Scan(
 			&v.ID, &v.Title, &v.Description, &v.Status, &v.StartDate, &v.EndDate,
-			&v.RequiresQuorum, &v.QuorumPercentage, &v.AllowAbstention,
+			&v.RequiresQuorum, &v.QuorumPercentage, &v.AllowAbstention, &v.Ponderada, &v.EventoID, &v.Secreta,
+			&v.Tipo, &v.MinSelecciones, &v.MaxSelecciones, &v.MetodoConteo, &v.AllowVoteChange,
 			&v.CreatedBy, &v.CreatorName,
 			&v.CreatedAt, &v.UpdatedAt, &v.TotalVotos)
 		if err != nil {
@@ -115,14 +130,17 @@ func (s *VotacionService) GetByID(ctx context.Context, id string, userID *string
 	var v models.Votacion
 	err := s.db.Pool.QueryRow(ctx, `
 		SELECT v.id, v.title, v.description, v.status, v.start_date, v.end_date,
-		       v.requires_quorum, v.quorum_percentage, v.allow_abstention,
+		       v.requires_quorum, v.quorum_percentage, v.allow_abstention, v.ponderada, v.evento_id, v.secreta,
+		       v.tipo, v.min_selecciones, v.max_selecciones,
+		       CASE WHEN v.tipo = 'preferencial' THEN v.metodo_conteo ELSE '' END, v.allow_vote_change,
 		       v.created_by, COALESCE(u.name, '') as creator_name,
 		       v.created_at, v.updated_at
 		FROM votaciones v
 		LEFT JOIN users u ON v.created_by = u.id
 		WHERE v.id = $1`, id).Scan(
 		&v.ID, &v.Title, &v.Description, &v.Status, &v.StartDate, &v.EndDate,
-		&v.RequiresQuorum, &v.QuorumPercentage, &v.AllowAbstention,
+		&v.RequiresQuorum, &v.QuorumPercentage, &v.AllowAbstention, &v.Ponderada, &v.EventoID, &v.Secreta,
+		&v.Tipo, &v.MinSelecciones, &v.MaxSelecciones, &v.MetodoConteo, &v.AllowVoteChange,
 		&v.CreatedBy, &v.CreatorName,
 		&v.CreatedAt, &v.UpdatedAt)
 	if err != nil {
@@ -130,38 +148,60 @@ func (s *VotacionService) GetByID(ctx context.Context, id string, userID *string
 	}
 
 	// Get opciones
-	opciones, err := s.getOpciones(ctx, id)
+	opciones, err := s.getOpciones(ctx, id, v.Tipo)
 	if err != nil {
 		return nil, err
 	}
 	v.Opciones = opciones
 
 	// Get total votos
-	err = s.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM votos WHERE votacion_id = $1`, id).Scan(&v.TotalVotos)
+	err = s.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM votos WHERE votacion_id = $1 AND parcela_id IS NOT NULL`, id).Scan(&v.TotalVotos)
 	if err != nil {
 		return nil, err
 	}
 
-	// Check if user has voted
+	// Check if the user's parcela has voted
 	if userID != nil {
-		var count int
-		err = s.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM votos WHERE votacion_id = $1 AND user_id = $2`, id, *userID).Scan(&count)
+		v.HasVoted, err = s.parcelaHaVotado(ctx, id, *userID)
+		if err != nil {
+			return nil, err
+		}
+		v.Poderes, err = s.poderesVoto(ctx, &v, *userID)
 		if err != nil {
 			return nil, err
 		}
-		v.HasVoted = count > 0
 	}
 
 	return &v, nil
 }
 
-func (s *VotacionService) getOpciones(ctx context.Context, votacionID string) ([]models.VotacionOpcion, error) {
+// parcelaHaVotado tells whether the parcela of the user already has a ballot
+// in the votacion, cast by any of its residents.
+func (s *VotacionService) parcelaHaVotado(ctx context.Context, votacionID, userID string) (bool, error) {
+	var voted bool
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM votos vo
+			JOIN users u ON u.parcela_id = vo.parcela_id
+			WHERE vo.votacion_id = $1 AND u.id = $2)`, votacionID, userID).Scan(&voted)
+	return voted, err
+}
+
+// getOpciones counts for each opcion the ballots choosing it: in multiple
+// votaciones every ballot approving it, in preferencial ones those ranking it
+// first.
+func (s *VotacionService) getOpciones(ctx context.Context, votacionID string, tipo models.VotacionTipo) ([]models.VotacionOpcion, error) {
 	rows, err := s.db.Pool.Query(ctx, `
 		SELECT o.id, o.votacion_id, o.label, COALESCE(o.description, ''), o.order_index,
-		       (SELECT COUNT(*) FROM votos WHERE opcion_id = o.id) as votos_count
+		       COUNT(b.peso) as votos_count, COALESCE(SUM(b.peso), 0),
+		       COUNT(b.peso) FILTER (WHERE b.por_poder)
 		FROM votacion_opciones o
+		LEFT JOIN (`+boletasVotacion+`) b
+		       ON b.opcion_id = o.id
+		       OR o.id = ANY(CASE WHEN $2 THEN b.opciones[1:1] ELSE b.opciones END)
 		WHERE o.votacion_id = $1
-		ORDER BY o.order_index`, votacionID)
+		GROUP BY o.id
+		ORDER BY o.order_index`, votacionID, tipo == models.VotacionPreferencial)
 	if err != nil {
 		return nil, err
 	}
@@ -170,7 +210,7 @@ func (s *VotacionService) getOpciones(ctx context.Context, votacionID string) ([
 	opciones := []models.VotacionOpcion{}
 	for rows.Next() {
 		var o models.VotacionOpcion
-		err := rows.Scan(&o.ID, &o.VotacionID, &o.Label, &o.Description, &o.OrderIndex, &o.VotosCount)
+		err := rows.Scan(&o.ID, &o.VotacionID, &o.Label, &o.Description, &o.OrderIndex, &o.VotosCount, &o.Peso, &o.VotosPoder)
 		if err != nil {
 			return nil, err
 		}
@@ -182,10 +222,12 @@ func (s *VotacionService) getOpciones(ctx context.Context, votacionID string) ([
 func (s *VotacionService) GetActive(ctx context.Context, userID *string) ([]models.Votacion, error) {
 	rows, err := s.db.Pool.Query(ctx, `
 		SELECT v.id, v.title, v.description, v.status, v.start_date, v.end_date,
-		       v.requires_quorum, v.quorum_percentage, v.allow_abstention,
+		       v.requires_quorum, v.quorum_percentage, v.allow_abstention, v.ponderada, v.evento_id, v.secreta,
+		       v.tipo, v.min_selecciones, v.max_selecciones,
+		       CASE WHEN v.tipo = 'preferencial' THEN v.metodo_conteo ELSE '' END, v.allow_vote_change,
 		       v.created_by, COALESCE(u.name, '') as creator_name,
 		       v.created_at, v.updated_at,
-		       (SELECT COUNT(*) FROM votos WHERE votacion_id = v.id) as total_votos
+		       (SELECT COUNT(*) FROM votos WHERE votacion_id = v.id AND parcela_id IS NOT NULL) as total_votos
 		FROM votaciones v
 		LEFT JOIN users u ON v.created_by = u.id
 		WHERE v.status = 'active'
@@ -202,18 +244,17 @@ func (s *VotacionService) GetActive(ctx context.Context, userID *string) ([]mode
 		var v models.Votacion
 		err := rows.Scan(
 			&v.ID, &v.Title, &v.Description, &v.Status, &v.StartDate, &v.EndDate,
-			&v.RequiresQuorum, &v.QuorumPercentage, &v.AllowAbstention,
+			&v.RequiresQuorum, &v.QuorumPercentage, &v.AllowAbstention, &v.Ponderada, &v.EventoID, &v.Secreta,
+			&v.Tipo, &v.MinSelecciones, &v.MaxSelecciones, &v.MetodoConteo, &v.AllowVoteChange,
 			&v.CreatedBy, &v.CreatorName,
 			&v.CreatedAt, &v.UpdatedAt, &v.TotalVotos)
 		if err != nil {
 			return nil, err
 		}
 
-		// Check if user has voted
+		// Check if the user's parcela has voted
 		if userID != nil {
-			var count int
-			s.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM votos WHERE votacion_id = $1 AND user_id = $2`, v.ID, *userID).Scan(&count)
-			v.HasVoted = count > 0
+			v.HasVoted, _ = s.parcelaHaVotado(ctx, v.ID, *userID)
 		}
 
 		votaciones = append(votaciones, v)
@@ -225,6 +266,21 @@ func (s *VotacionService) Create(ctx context.Context, req *models.CreateVotacion
 	if req.QuorumPercentage <= 0 {
 		req.QuorumPercentage = 50
 	}
+	tipo := models.Votacion{
+		Tipo:           req.Tipo,
+		MinSelecciones: req.MinSelecciones,
+		MaxSelecciones: req.MaxSelecciones,
+		MetodoConteo:   req.MetodoConteo,
+		Opciones:       make([]models.VotacionOpcion, len(req.Opciones)),
+	}
+	if err := validarTipo(&tipo); err != nil {
+		return nil, err
+	}
+	if req.EventoID != nil {
+		if err := s.validarAsamblea(ctx, *req.EventoID); err != nil {
+			return nil, err
+		}
+	}
 
 	tx, err := s.db.Pool.Begin(ctx)
 	if err != nil {
@@ -234,10 +290,12 @@ func (s *VotacionService) Create(ctx context.Context, req *models.CreateVotacion
 
 	var votacionID string
 	err = tx.QueryRow(ctx, `
-		INSERT INTO votaciones (title, description, status, requires_quorum, quorum_percentage, allow_abstention, created_by)
-		VALUES ($1, $2, 'draft', $3, $4, $5, $6)
+		INSERT INTO votaciones (title, description, status, requires_quorum, quorum_percentage, allow_abstention, ponderada, secreta, evento_id,
+		                        tipo, min_selecciones, max_selecciones, metodo_conteo, allow_vote_change, created_by)
+		VALUES ($1, $2, 'draft', $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 		RETURNING id`,
-		req.Title, req.Description, req.RequiresQuorum, req.QuorumPercentage, req.AllowAbstention, createdBy).Scan(&votacionID)
+		req.Title, req.Description, req.RequiresQuorum, req.QuorumPercentage, req.AllowAbstention, req.Ponderada, req.Secreta, req.EventoID,
+		tipo.Tipo, tipo.MinSelecciones, tipo.MaxSelecciones, tipo.MetodoConteo, req.AllowVoteChange, createdBy).Scan(&votacionID)
 	if err != nil {
 		return nil, err
 	}
@@ -284,12 +342,47 @@ func (s *VotacionService) Update(ctx context.Context, id string, req *models.Upd
 	if req.AllowAbstention != nil {
 		current.AllowAbstention = *req.AllowAbstention
 	}
+	if req.Ponderada != nil {
+		current.Ponderada = *req.Ponderada
+	}
+	if req.Secreta != nil {
+		current.Secreta = *req.Secreta
+	}
+	if req.Tipo != nil {
+		current.Tipo = *req.Tipo
+	}
+	if req.MinSelecciones != nil {
+		current.MinSelecciones = *req.MinSelecciones
+	}
+	if req.MaxSelecciones != nil {
+		current.MaxSelecciones = *req.MaxSelecciones
+	}
+	if req.MetodoConteo != nil {
+		current.MetodoConteo = *req.MetodoConteo
+	}
+	if req.AllowVoteChange != nil {
+		current.AllowVoteChange = *req.AllowVoteChange
+	}
+	if err := validarTipo(current); err != nil {
+		return nil, err
+	}
+	if req.EventoID != nil {
+		current.EventoID = nil
+		if *req.EventoID != "" {
+			if err := s.validarAsamblea(ctx, *req.EventoID); err != nil {
+				return nil, err
+			}
+			current.EventoID = req.EventoID
+		}
+	}
 
 	_, err = s.db.Pool.Exec(ctx, `
 		UPDATE votaciones
-		SET title = $1, description = $2, requires_quorum = $3, quorum_percentage = $4, allow_abstention = $5, updated_at = NOW()
-		WHERE id = $6`,
-		current.Title, current.Description, current.RequiresQuorum, current.QuorumPercentage, current.AllowAbstention, id)
+		SET title = $1, description = $2, requires_quorum = $3, quorum_percentage = $4, allow_abstention = $5, ponderada = $6, secreta = $7, evento_id = $8,
+		    tipo = $9, min_selecciones = $10, max_selecciones = $11, metodo_conteo = $12, allow_vote_change = $13, updated_at = NOW()
+		WHERE id = $14`,
+		current.Title, current.Description, current.RequiresQuorum, current.QuorumPercentage, current.AllowAbstention, current.Ponderada, current.Secreta, current.EventoID,
+		current.Tipo, current.MinSelecciones, current.MaxSelecciones, current.MetodoConteo, current.AllowVoteChange, id)
 	if err != nil {
 		return nil, err
 	}
@@ -297,6 +390,8 @@ func (s *VotacionService) Update(ctx context.Context, id string, req *models.Upd
 	return s.GetByID(ctx, id, nil)
 }
 
+// Publish opens a draft votacion, or schedules its opening when start_date
+// is in the future.
 func (s *VotacionService) Publish(ctx context.Context, id string, startDate, endDate *time.Time) (*models.Votacion, error) {
 	current, err := s.GetByID(ctx, id, nil)
 	if err != nil {
@@ -310,11 +405,24 @@ func (s *VotacionService) Publish(ctx context.Context, id string, startDate, end
 	if len(current.Opciones) < 2 {
 		return nil, errors.New("votacion must have at least 2 options")
 	}
+	if err := validarTipo(current); err != nil {
+		return nil, err
+	}
+	if endDate != nil && (!endDate.After(time.Now()) || (startDate != nil && !endDate.After(*startDate))) {
+		return nil, ErrInvalidFechasVotacion
+	}
+
+	// A future start_date schedules the votacion: it stays in draft until the
+	// votaciones job opens it
+	status := models.VotacionStatusActive
+	if startDate != nil && startDate.After(time.Now()) {
+		status = models.VotacionStatusDraft
+	}
 
 	_, err = s.db.Pool.Exec(ctx, `
 		UPDATE votaciones
-		SET status = 'active', start_date = $1, end_date = $2, updated_at = NOW()
-		WHERE id = $3`, startDate, endDate, id)
+		SET status = $1, start_date = $2, end_date = $3, updated_at = NOW()
+		WHERE id = $4`, status, startDate, endDate, id)
 	if err != nil {
 		return nil, err
 	}
@@ -323,136 +431,376 @@ func (s *VotacionService) Publish(ctx context.Context, id string, startDate, end
 }
 
 func (s *VotacionService) Close(ctx context.Context, id string) (*models.Votacion, error) {
-	_, err := s.db.Pool.Exec(ctx, `
+	if _, err := s.cerrarVotacion(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(ctx, id, nil)
+}
+
+// cerrarVotacion closes an active votacion and publishes its secret ballots
+// in the same transaction. It returns false when the votacion was not active.
+func (s *VotacionService) cerrarVotacion(ctx context.Context, id string) (bool, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
 		UPDATE votaciones
 		SET status = 'closed', updated_at = NOW()
 		WHERE id = $1 AND status = 'active'`, id)
 	if err != nil {
-		return nil, err
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
 	}
 
-	return s.GetByID(ctx, id, nil)
+	if err := publicarBoletas(ctx, tx, id); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
+// Cancel discards the votacion; the ballots still in its urna are never
+// published.
 func (s *VotacionService) Cancel(ctx context.Context, id string) (*models.Votacion, error) {
-	_, err := s.db.Pool.Exec(ctx, `
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
 		UPDATE votaciones
 		SET status = 'cancelled', updated_at = NOW()
 		WHERE id = $1 AND status IN ('draft', 'active')`, id)
 	if err != nil {
 		return nil, err
 	}
+	if tag.RowsAffected() > 0 {
+		if _, err := tx.Exec(ctx, `DELETE FROM urna_boletas WHERE votacion_id = $1`, id); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
 
 	return s.GetByID(ctx, id, nil)
 }
 
-func (s *VotacionService) EmitirVoto(ctx context.Context, votacionID string, userID string, req *models.EmitirVotoRequest) error {
-	// Condition: user must have parcela to be allowed to vote
+// EmitirVoto casts the ballot of the user's parcela or, with req.ParcelaID,
+// of a parcela the user represents through a validated poder. The ballot is
+// weighted with the parcela coefficient at the time of voting, and each
+// parcela votes once, whoever casts the ballot, unless the votacion allows
+// vote changes. Secret votaciones return the voter's receipt.
+func (s *VotacionService) EmitirVoto(ctx context.Context, votacionID string, userID string, req *models.EmitirVotoRequest) (*models.ReciboVoto, error) {
 	var parcelaID *int
 	if err := s.db.Pool.QueryRow(ctx, `SELECT parcela_id FROM users WHERE id = $1`, userID).Scan(&parcelaID); err != nil {
-		return err
-	}
-	if parcelaID == nil {
-		return ErrUserNoParcela
+		return nil, err
 	}
 
 	votacion, err := s.GetByID(ctx, votacionID, &userID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if !votacion.CanVote() {
-		return ErrVotacionNotActive
+		return nil, ErrVotacionNotActive
 	}
 
-	if votacion.HasVoted {
-		return ErrAlreadyVoted
+	// Voting for another parcela needs a poder valid right now; otherwise the
+	// user must have a parcela to be allowed to vote
+	var poderID *string
+	if req.ParcelaID != nil && (parcelaID == nil || *req.ParcelaID != *parcelaID) {
+		id, err := s.poderVigente(ctx, votacion, userID, *req.ParcelaID)
+		if err != nil {
+			return nil, err
+		}
+		poderID = &id
+		parcelaID = req.ParcelaID
+	} else if parcelaID == nil {
+		return nil, ErrUserNoParcela
+	} else if votacion.HasVoted && !votacion.AllowVoteChange {
+		return nil, ErrAlreadyVoted
 	}
 
+	var opcionID *string
+	var opciones []string
 	if req.IsAbstention {
 		if !votacion.AllowAbstention {
-			return ErrAbstentionNotAllowed
+			return nil, ErrAbstentionNotAllowed
 		}
-		_, err = s.db.Pool.Exec(ctx, `
-			INSERT INTO votos (votacion_id, user_id, is_abstention, voted_at)
-			VALUES ($1, $2, true, NOW())`, votacionID, userID)
 	} else {
-		// Validate opcion
-		validOpcion := false
-		for _, o := range votacion.Opciones {
-			if o.ID == *req.OpcionID {
-				validOpcion = true
-				break
-			}
+		opcionID, opciones, err = seleccionVoto(votacion, req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if votacion.AllowVoteChange {
+		recibo, cambiado, err := s.cambiarVoto(ctx, votacion, userID, *parcelaID, poderID, opcionID, opciones, req)
+		if err != nil || cambiado {
+			return recibo, err
+		}
+	}
+
+	if votacion.Secreta {
+		return s.emitirVotoSecreto(ctx, votacionID, userID, *parcelaID, poderID, opcionID, opciones, req.IsAbstention)
+	}
+
+	_, err = s.db.Pool.Exec(ctx, `
+		INSERT INTO votos (votacion_id, user_id, parcela_id, peso, poder_id, opcion_id, opciones, is_abstention, voted_at)
+		VALUES ($1, $2, $3, (SELECT coeficiente FROM parcelas WHERE id = $3), $4, $5, $6::uuid[], $7, NOW())`,
+		votacionID, userID, *parcelaID, poderID, opcionID, opciones, req.IsAbstention)
+	return nil, votoError(err)
+}
+
+// seleccionVoto checks the ballot against the votacion tipo: one opcion in
+// simple votaciones, otherwise between MinSelecciones and LimiteSelecciones
+// distinct opciones.
+func seleccionVoto(votacion *models.Votacion, req *models.EmitirVotoRequest) (*string, []string, error) {
+	validas := make(map[string]bool, len(votacion.Opciones))
+	for _, o := range votacion.Opciones {
+		validas[o.ID] = true
+	}
+
+	if votacion.Tipo == models.VotacionSimple {
+		if req.OpcionID == nil || !validas[*req.OpcionID] {
+			return nil, nil, ErrInvalidOpcion
 		}
-		if !validOpcion {
-			return ErrInvalidOpcion
+		return req.OpcionID, nil, nil
+	}
+
+	limite := votacion.LimiteSelecciones()
+	if len(req.Opciones) < votacion.MinSelecciones || len(req.Opciones) > limite {
+		return nil, nil, fmt.Errorf("%w: select between %d and %d options", ErrInvalidSeleccion, votacion.MinSelecciones, limite)
+	}
+	elegidas := make(map[string]bool, len(req.Opciones))
+	for _, id := range req.Opciones {
+		if !validas[id] {
+			return nil, nil, ErrInvalidOpcion
 		}
+		if elegidas[id] {
+			return nil, nil, fmt.Errorf("%w: an option is selected twice", ErrInvalidSeleccion)
+		}
+		elegidas[id] = true
+	}
+	return nil, req.Opciones, nil
+}
+
+// validarTipo fills in the defaults of the votacion tipo and checks the
+// selection limits against its opciones.
+func validarTipo(v *models.Votacion) error {
+	if v.Tipo == "" {
+		v.Tipo = models.VotacionSimple
+	}
+	if v.MetodoConteo == "" {
+		v.MetodoConteo = models.ConteoIRV
+	}
+	if !v.Tipo.IsValid() || !v.MetodoConteo.IsValid() {
+		return ErrInvalidTipoVotacion
+	}
 
-		_, err = s.db.Pool.Exec(ctx, `
-			INSERT INTO votos (votacion_id, user_id, opcion_id, is_abstention, voted_at)
-			VALUES ($1, $2, $3, false, NOW())`, votacionID, userID, req.OpcionID)
+	if v.Tipo == models.VotacionSimple {
+		v.MinSelecciones, v.MaxSelecciones = 1, 1
+		return nil
 	}
+	if v.MinSelecciones == 0 {
+		v.MinSelecciones = 1
+	}
+	if v.MinSelecciones < 1 || v.MaxSelecciones < 0 ||
+		(v.MaxSelecciones > 0 && v.MaxSelecciones < v.MinSelecciones) ||
+		v.MinSelecciones > len(v.Opciones) || v.MaxSelecciones > len(v.Opciones) {
+		return ErrInvalidSelecciones
+	}
+	return nil
+}
 
+// votoError maps the unique violation of a second ballot for the parcela,
+// cast meanwhile by another resident or the apoderado.
+func votoError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+		return ErrAlreadyVoted
+	}
 	return err
 }
 
+// GetResultados counts one ballot per parcela over all the parcelas of the
+// community, by headcount and by coefficient. Turnout and quorum come from
+// votos in every mode; the tallies of a secret votacion stay empty until its
+// urna is published at closing.
 func (s *VotacionService) GetResultados(ctx context.Context, id string) (*models.VotacionResultado, error) {
 	votacion, err := s.GetByID(ctx, id, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get total vecinos (users with role vecino or directiva)
-	var totalVecinos int
-	err = s.db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE role IN ('vecino', 'directiva')`).Scan(&totalVecinos)
+	// Voting units: every parcela, with its current coefficient
+	var totalParcelas int
+	var pesoTotal float64
+	err = s.db.Pool.QueryRow(ctx, `SELECT COUNT(*), COALESCE(SUM(coeficiente), 0) FROM parcelas`).Scan(&totalParcelas, &pesoTotal)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get total votos and abstenciones
-	var totalVotos, totalAbstenciones int
+	// Parcelas that voted, secret or not
+	var totalVotos int
+	var pesoVotos float64
 	err = s.db.Pool.QueryRow(ctx, `
-		SELECT
-			COUNT(*) as total,
-			COUNT(*) FILTER (WHERE is_abstention = true) as abstenciones
-		FROM votos WHERE votacion_id = $1`, id).Scan(&totalVotos, &totalAbstenciones)
+		SELECT COUNT(*), COALESCE(SUM(peso), 0)
+		FROM votos
+		WHERE votacion_id = $1 AND parcela_id IS NOT NULL`, id).Scan(&totalVotos, &pesoVotos)
+	if err != nil {
+		return nil, err
+	}
+
+	// Abstenciones, from the counted ballots
+	var totalAbstenciones int
+	var pesoAbstenciones float64
+	err = s.db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(peso), 0)
+		FROM (`+boletasVotacion+`) b
+		WHERE is_abstention`, id).Scan(&totalAbstenciones, &pesoAbstenciones)
+	if err != nil {
+		return nil, err
+	}
+	pendiente := votacion.Secreta && votacion.Status != models.VotacionStatusClosed
+
+	// Ballots cast by apoderados, marked apart
+	votosPoder, pesoPoder, err := s.votosPorPoder(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	// Build resultados
+	// Build resultados. In multiple votaciones a ballot approves several
+	// opciones, so the percentages add up to more than 100
 	resultados := []models.OpcionResultado{}
 	votosEfectivos := totalVotos - totalAbstenciones
+	pesoEfectivo := pesoVotos - pesoAbstenciones
 	for _, o := range votacion.Opciones {
-		percentage := float64(0)
-		if votosEfectivos > 0 {
-			percentage = float64(o.VotosCount) / float64(votosEfectivos) * 100
+		if pendiente {
+			resultados = append(resultados, models.OpcionResultado{OpcionID: o.ID, Label: o.Label})
+			continue
 		}
 		resultados = append(resultados, models.OpcionResultado{
-			OpcionID:   o.ID,
-			Label:      o.Label,
-			Count:      o.VotosCount,
-			Percentage: percentage,
+			OpcionID:            o.ID,
+			Label:               o.Label,
+			Count:               o.VotosCount,
+			Percentage:          porcentajeVotos(float64(o.VotosCount), float64(votosEfectivos)),
+			Peso:                o.Peso,
+			PercentagePonderado: porcentajeVotos(o.Peso, pesoEfectivo),
+			CountPoder:          o.VotosPoder,
 		})
 	}
 
 	// Check quorum
-	participacion := float64(0)
-	if totalVecinos > 0 {
-		participacion = float64(totalVotos) / float64(totalVecinos) * 100
-	}
-	quorumAlcanzado := !votacion.RequiresQuorum || participacion >= float64(votacion.QuorumPercentage)
-
-	return &models.VotacionResultado{
-		Votacion:         *votacion,
-		TotalVotos:       totalVotos,
-		TotalAbstenciones: totalAbstenciones,
-		Resultados:       resultados,
-		QuorumAlcanzado:  quorumAlcanzado,
-		TotalVecinos:     totalVecinos,
-		Participacion:    participacion,
-	}, nil
+	participacion := porcentajeVotos(float64(totalVotos), float64(totalParcelas))
+	participacionPonderada := porcentajeVotos(pesoVotos, pesoTotal)
+	quorum := participacion
+	if votacion.Ponderada {
+		quorum = participacionPonderada
+	}
+	quorumAlcanzado := !votacion.RequiresQuorum || quorum >= float64(votacion.QuorumPercentage)
+
+	resultado := &models.VotacionResultado{
+		Votacion:               *votacion,
+		TotalVotos:             totalVotos,
+		TotalAbstenciones:      totalAbstenciones,
+		Resultados:             resultados,
+		QuorumAlcanzado:        quorumAlcanzado,
+		TotalVecinos:           totalParcelas,
+		Participacion:          participacion,
+		PesoTotal:              pesoTotal,
+		PesoVotos:              pesoVotos,
+		PesoAbstenciones:       pesoAbstenciones,
+		ParticipacionPonderada: participacionPonderada,
+		VotosPoder:             len(votosPoder),
+		PesoPoder:              pesoPoder,
+		VotosPorPoder:          votosPoder,
+		EscrutinioPendiente:    pendiente,
+	}
+	if votacion.Tipo == models.VotacionPreferencial && !pendiente {
+		if err := s.conteoPreferencial(ctx, votacion, resultado); err != nil {
+			return nil, err
+		}
+	}
+	return resultado, nil
+}
+
+// porcentajeVotos is parte as a percentage of total, 0 when nobody voted.
+func porcentajeVotos(parte, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return parte / total * 100
+}
+
+// ListCoeficientes returns the voting weight of every parcela.
+func (s *VotacionService) ListCoeficientes(ctx context.Context) ([]models.CoeficienteParcela, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT p.id, p.numero, p.coeficiente, COUNT(u.id)
+		FROM parcelas p
+		LEFT JOIN users u ON u.parcela_id = p.id AND u.role IN ('vecino', 'directiva')
+		GROUP BY p.id
+		ORDER BY p.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	coeficientes := []models.CoeficienteParcela{}
+	for rows.Next() {
+		var c models.CoeficienteParcela
+		if err := rows.Scan(&c.ParcelaID, &c.Numero, &c.Coeficiente, &c.Residentes); err != nil {
+			return nil, err
+		}
+		coeficientes = append(coeficientes, c)
+	}
+	return coeficientes, rows.Err()
+}
+
+// SetCoeficiente changes the voting weight of a parcela. It is refused while
+// a weighted votacion is active, so its ballots and totals use the same
+// coefficients.
+func (s *VotacionService) SetCoeficiente(ctx context.Context, parcelaID int, coeficiente float64) (*models.CoeficienteParcela, error) {
+	if coeficiente <= 0 || coeficiente >= 1000 {
+		return nil, ErrInvalidCoeficiente
+	}
+
+	var enUso bool
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM votaciones WHERE status = 'active' AND ponderada)`).Scan(&enUso)
+	if err != nil {
+		return nil, err
+	}
+	if enUso {
+		return nil, ErrCoeficienteEnUso
+	}
+
+	var c models.CoeficienteParcela
+	err = s.db.Pool.QueryRow(ctx, `
+		UPDATE parcelas SET coeficiente = $1, updated_at = NOW()
+		WHERE id = $2
+		RETURNING id, numero, coeficiente,
+		          (SELECT COUNT(*) FROM users WHERE parcela_id = $2 AND role IN ('vecino', 'directiva'))`,
+		coeficiente, parcelaID).Scan(&c.ParcelaID, &c.Numero, &c.Coeficiente, &c.Residentes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrParcelaNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
 }
 
 func (s *VotacionService) Delete(ctx context.Context, id string) error {
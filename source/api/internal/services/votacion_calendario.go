@@ -0,0 +1,361 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/condominio/backend/internal/models"
+	"github.com/condominio/backend/pkg/email"
+)
+
+// ProcesarVotaciones opens the scheduled votaciones whose start_date has
+// come, closes the active ones past their end_date, and sends the notices
+// that are due: the opening to every resident with a parcela, the reminder to
+// those whose parcela has not voted yet, and the results once closed,
+// whether by date or by hand. Each notice is recorded before it goes out so
+// it is never sent twice.
+func (s *VotacionService) ProcesarVotaciones(ctx context.Context) (*models.ProcesarVotacionesResult, error) {
+	result := &models.ProcesarVotacionesResult{}
+
+	abiertas, err := s.abrirProgramadas(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result.Abiertas = abiertas
+
+	vencidas, err := s.idsVotaciones(ctx, `
+		SELECT id FROM votaciones
+		WHERE status = 'active' AND end_date <= NOW()
+		ORDER BY end_date`)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range vencidas {
+		cerrada, err := s.cerrarVotacion(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if cerrada {
+			result.Cerradas++
+		}
+	}
+
+	var envios []email.Email
+	avisar := func(tipo models.AvisoVotacionTipo, contador *int, where string, args ...interface{}) error {
+		ids, err := s.votacionesSinAviso(ctx, tipo, where, args...)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			notificaciones, correos, enviado, err := s.registrarAviso(ctx, id, tipo)
+			if err != nil {
+				return err
+			}
+			if !enviado {
+				continue
+			}
+			*contador++
+			result.Notificaciones += notificaciones
+			result.Emails += len(correos)
+			envios = append(envios, correos...)
+		}
+		return nil
+	}
+
+	err = avisar(models.AvisoApertura, &result.Aperturas, `
+		v.status = 'active'
+		AND (v.start_date IS NULL OR v.start_date <= NOW())
+		AND (v.end_date IS NULL OR v.end_date > NOW())`)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only votaciones that were open before the reminder window get a
+	// reminder; otherwise the opening notice just told residents the same
+	if s.cfg.RecordatorioHoras > 0 {
+		err = avisar(models.AvisoRecordatorio, &result.Recordatorios, `
+			v.status = 'active'
+			AND v.end_date > NOW() AND v.end_date <= NOW() + make_interval(hours => $1)
+			AND EXISTS (SELECT 1 FROM avisos_votacion a
+			            WHERE a.votacion_id = v.id AND a.tipo = 'apertura'
+			              AND a.created_at < v.end_date - make_interval(hours => $1))`,
+			s.cfg.RecordatorioHoras)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := avisar(models.AvisoResultados, &result.Resultados, `v.status = 'closed'`); err != nil {
+		return nil, err
+	}
+
+	s.enviarCorreos(envios)
+	return result, nil
+}
+
+// abrirProgramadas opens the drafts published with a start_date that has
+// come. Drafts edited since into something that cannot be published stay
+// closed and are logged.
+func (s *VotacionService) abrirProgramadas(ctx context.Context) (int, error) {
+	ids, err := s.idsVotaciones(ctx, `
+		SELECT id FROM votaciones
+		WHERE status = 'draft' AND start_date IS NOT NULL AND start_date <= NOW()
+		ORDER BY start_date`)
+	if err != nil {
+		return 0, err
+	}
+
+	abiertas := 0
+	for _, id := range ids {
+		v, err := s.GetByID(ctx, id, nil)
+		if err != nil {
+			return abiertas, err
+		}
+		if len(v.Opciones) < 2 {
+			log.Printf("[VOTACIONES] %s not opened: it needs at least 2 options", id)
+			continue
+		}
+		if err := validarTipo(v); err != nil {
+			log.Printf("[VOTACIONES] %s not opened: %v", id, err)
+			continue
+		}
+
+		tag, err := s.db.Pool.Exec(ctx, `
+			UPDATE votaciones SET status = 'active', updated_at = NOW()
+			WHERE id = $1 AND status = 'draft'`, id)
+		if err != nil {
+			return abiertas, err
+		}
+		abiertas += int(tag.RowsAffected())
+	}
+	return abiertas, nil
+}
+
+func (s *VotacionService) votacionesSinAviso(ctx context.Context, tipo models.AvisoVotacionTipo, where string, args ...interface{}) ([]string, error) {
+	return s.idsVotaciones(ctx, `
+		SELECT v.id FROM votaciones v
+		WHERE `+where+`
+		  AND NOT EXISTS (SELECT 1 FROM avisos_votacion a WHERE a.votacion_id = v.id AND a.tipo = '`+string(tipo)+`')
+		ORDER BY v.created_at`, args...)
+}
+
+func (s *VotacionService) idsVotaciones(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := s.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// registrarAviso records the notice and creates the in-app notifications in
+// one transaction. It returns enviado=false when another run already sent
+// it. Emails are returned to be sent after the commit.
+func (s *VotacionService) registrarAviso(ctx context.Context, votacionID string, tipo models.AvisoVotacionTipo) (int, []email.Email, bool, error) {
+	titulo, cuerpo, err := s.mensajeAviso(ctx, votacionID, tipo)
+	if err != nil {
+		return 0, nil, false, err
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, nil, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var avisoID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO avisos_votacion (votacion_id, tipo)
+		VALUES ($1, $2)
+		ON CONFLICT (votacion_id, tipo) DO NOTHING
+		RETURNING id`, votacionID, tipo).Scan(&avisoID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil, false, nil
+		}
+		return 0, nil, false, err
+	}
+
+	// Residents who can vote; the reminder skips parcelas that already did
+	rows, err := tx.Query(ctx, `
+		SELECT u.id, u.name, u.email
+		FROM users u
+		WHERE u.parcela_id IS NOT NULL AND u.role IN ('vecino', 'directiva')
+		  AND (NOT $2 OR NOT EXISTS (
+		      SELECT 1 FROM votos vo WHERE vo.votacion_id = $1 AND vo.parcela_id = u.parcela_id))`,
+		votacionID, tipo == models.AvisoRecordatorio)
+	if err != nil {
+		return 0, nil, false, err
+	}
+	type destinatario struct {
+		id, nombre, correo string
+	}
+	var destinatarios []destinatario
+	for rows.Next() {
+		var d destinatario
+		if err := rows.Scan(&d.id, &d.nombre, &d.correo); err != nil {
+			rows.Close()
+			return 0, nil, false, err
+		}
+		destinatarios = append(destinatarios, d)
+	}
+	rows.Close()
+
+	url := strings.TrimSuffix(s.cfg.FrontendURL, "/") + "/votaciones/" + votacionID
+	var correos []email.Email
+	for _, d := range destinatarios {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO notificaciones (user_id, title, body, type, reference_id)
+			VALUES ($1, $2, $3, $4, $5)`,
+			d.id, titulo, cuerpo, models.NotificationTypeVotacion, votacionID)
+		if err != nil {
+			return 0, nil, false, err
+		}
+		if d.correo != "" {
+			correos = append(correos, email.Email{
+				To:       []string{d.correo},
+				Subject:  titulo + " - Comunidad Viña Pelvin",
+				Template: email.TemplateNotificacion,
+				Data: map[string]string{
+					"Title": titulo,
+					"Body":  cuerpo,
+					"URL":   url,
+				},
+			})
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE avisos_votacion SET notificaciones = $1, emails = $2 WHERE id = $3`,
+		len(destinatarios), len(correos), avisoID)
+	if err != nil {
+		return 0, nil, false, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, nil, false, err
+	}
+	return len(destinatarios), correos, true, nil
+}
+
+func (s *VotacionService) mensajeAviso(ctx context.Context, votacionID string, tipo models.AvisoVotacionTipo) (string, string, error) {
+	if tipo == models.AvisoResultados {
+		r, err := s.GetResultados(ctx, votacionID)
+		if err != nil {
+			return "", "", err
+		}
+		return "Resultados de la votación " + r.Votacion.Title, resumenResultados(r), nil
+	}
+
+	v, err := s.GetByID(ctx, votacionID, nil)
+	if err != nil {
+		return "", "", err
+	}
+	cierre := ""
+	if v.EndDate != nil {
+		cierre = " La votación cierra el " + v.EndDate.Format("02-01-2006 15:04") + "."
+	}
+	if tipo == models.AvisoRecordatorio {
+		return "Recordatorio: votación " + v.Title,
+			fmt.Sprintf("Su parcela aún no vota en «%s».%s", v.Title, cierre), nil
+	}
+	return "Votación abierta: " + v.Title,
+		fmt.Sprintf("Ya puede votar en «%s».%s", v.Title, cierre), nil
+}
+
+// resumenResultados is the results notice: participation, quorum and the
+// votes of each opcion, by coefficient when the votacion is Ponderada.
+func resumenResultados(r *models.VotacionResultado) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Votaron %d de %d parcelas (%.1f%%", r.TotalVotos, r.TotalVecinos, r.Participacion)
+	if r.Votacion.Ponderada {
+		fmt.Fprintf(&b, "; %.1f%% del coeficiente", r.ParticipacionPonderada)
+	}
+	b.WriteString(").")
+	if r.Votacion.RequiresQuorum {
+		if r.QuorumAlcanzado {
+			b.WriteString(" Se alcanzó el quórum.")
+		} else {
+			b.WriteString(" No se alcanzó el quórum.")
+		}
+	}
+
+	unidad := "votos"
+	if r.Votacion.Tipo == models.VotacionPreferencial {
+		unidad = "primeras preferencias"
+	}
+	for _, o := range r.Resultados {
+		porcentaje := o.Percentage
+		if r.Votacion.Ponderada {
+			porcentaje = o.PercentagePonderado
+		}
+		fmt.Fprintf(&b, " %s: %d %s (%.1f%%).", o.Label, o.Count, unidad, porcentaje)
+	}
+	if r.TotalAbstenciones > 0 {
+		fmt.Fprintf(&b, " Abstenciones: %d.", r.TotalAbstenciones)
+	}
+
+	if r.Votacion.Tipo == models.VotacionPreferencial {
+		switch {
+		case r.Ganador != nil:
+			for _, o := range r.Resultados {
+				if o.OpcionID == *r.Ganador {
+					fmt.Fprintf(&b, " Ganador: %s.", o.Label)
+				}
+			}
+		case r.Empate:
+			b.WriteString(" Resultado: empate.")
+		}
+	}
+	return b.String()
+}
+
+func (s *VotacionService) enviarCorreos(envios []email.Email) {
+	if s.email == nil || len(envios) == 0 {
+		return
+	}
+	go func() {
+		for _, e := range envios {
+			if err := s.email.Send(e); err != nil {
+				log.Printf("[EMAIL] Failed to send aviso de votacion to %v: %v", e.To, err)
+			}
+		}
+	}()
+}
+
+// ListAvisos returns the notices sent for a votacion.
+func (s *VotacionService) ListAvisos(ctx context.Context, votacionID string) ([]models.AvisoVotacion, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT id, votacion_id, tipo, notificaciones, emails, created_at
+		FROM avisos_votacion
+		WHERE votacion_id = $1
+		ORDER BY created_at`, votacionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	avisos := []models.AvisoVotacion{}
+	for rows.Next() {
+		var a models.AvisoVotacion
+		if err := rows.Scan(&a.ID, &a.VotacionID, &a.Tipo, &a.Notificaciones, &a.Emails, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		avisos = append(avisos, a)
+	}
+	return avisos, rows.Err()
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/condominio/backend/internal/models"
+)
+
+var ErrReciboRequerido = errors.New("the receipt code of the ballot is required to change a secret vote")
+
+// cambiarVoto replaces the ballot the parcela already cast, keeping the
+// replaced one in votos_historial. It returns cambiado=false when the parcela
+// has not voted yet. The ballot keeps the coefficient it was cast with. A
+// secret ballot can only be changed with the receipt code the parcela got,
+// which stays valid; it is rewritten in the urna and its contents are not
+// copied to the history. All of it happens in one transaction, with the
+// votacion locked open.
+func (s *VotacionService) cambiarVoto(ctx context.Context, votacion *models.Votacion, userID string, parcelaID int, poderID, opcionID *string, opciones []string, req *models.EmitirVotoRequest) (*models.ReciboVoto, bool, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := bloquearAbierta(ctx, tx, votacion.ID); err != nil {
+		return nil, false, err
+	}
+
+	var votoID string
+	var llave *string
+	err = tx.QueryRow(ctx, `
+		SELECT id, llave_recibo FROM votos
+		WHERE votacion_id = $1 AND parcela_id = $2
+		FOR UPDATE`, votacion.ID, parcelaID).Scan(&votoID, &llave)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var recibo *models.ReciboVoto
+	if votacion.Secreta {
+		codigo := strings.TrimSpace(req.Recibo)
+		if codigo == "" {
+			return nil, false, ErrReciboRequerido
+		}
+		// The code must be the one this parcela was given, not any valid one
+		if llave == nil || *llave != llaveRecibo(codigo) {
+			return nil, false, ErrReciboNotFound
+		}
+		recibo = &models.ReciboVoto{VotacionID: votacion.ID, Codigo: codigo, Huella: huellaRecibo(codigo)}
+		tag, err := tx.Exec(ctx, `
+			UPDATE urna_boletas SET opcion_id = $3, opciones = $4::uuid[], is_abstention = $5
+			WHERE votacion_id = $1 AND recibo = $2`,
+			votacion.ID, recibo.Huella, opcionID, opciones, req.IsAbstention)
+		if err != nil {
+			return nil, false, err
+		}
+		if tag.RowsAffected() == 0 {
+			return nil, false, ErrReciboNotFound
+		}
+		// votos only records who voted
+		opcionID, opciones = nil, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO votos_historial (votacion_id, parcela_id, user_id, poder_id, opcion_id, opciones,
+		                             is_abstention, peso, voted_at, cambiado_by)
+		SELECT votacion_id, parcela_id, user_id, poder_id, opcion_id, opciones,
+		       CASE WHEN $3 THEN NULL ELSE is_abstention END, peso, voted_at, $2
+		FROM votos
+		WHERE id = $1`, votoID, userID, votacion.Secreta)
+	if err != nil {
+		return nil, false, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE votos
+		SET user_id = $2, poder_id = $3, opcion_id = $4, opciones = $5::uuid[], is_abstention = $6, voted_at = NOW()
+		WHERE id = $1`,
+		votoID, userID, poderID, opcionID, opciones, req.IsAbstention && !votacion.Secreta)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, err
+	}
+	return recibo, true, nil
+}
+
+// ListHistorialVotos returns the ballots replaced in the votacion, by parcela
+// and from the oldest.
+func (s *VotacionService) ListHistorialVotos(ctx context.Context, votacionID string) ([]models.VotoHistorial, error) {
+	if _, err := s.GetByID(ctx, votacionID, nil); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT h.id, h.votacion_id, h.parcela_id, p.numero, h.user_id, COALESCE(u.name, ''), h.poder_id,
+		       h.opcion_id, COALESCE(h.opciones::text[], '{}'), h.is_abstention, h.peso, h.voted_at,
+		       h.cambiado_by, COALESCE(c.name, ''), h.cambiado_at
+		FROM votos_historial h
+		JOIN parcelas p ON p.id = h.parcela_id
+		LEFT JOIN users u ON u.id = h.user_id
+		LEFT JOIN users c ON c.id = h.cambiado_by
+		WHERE h.votacion_id = $1
+		ORDER BY h.parcela_id, h.cambiado_at`, votacionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	historial := []models.VotoHistorial{}
+	for rows.Next() {
+		var h models.VotoHistorial
+		err := rows.Scan(&h.ID, &h.VotacionID, &h.ParcelaID, &h.ParcelaNumero, &h.UserID, &h.UserName, &h.PoderID,
+			&h.OpcionID, &h.Opciones, &h.IsAbstention, &h.Peso, &h.VotedAt,
+			&h.CambiadoBy, &h.CambiadoByName, &h.CambiadoAt)
+		if err != nil {
+			return nil, err
+		}
+		historial = append(historial, h)
+	}
+	return historial, rows.Err()
+}
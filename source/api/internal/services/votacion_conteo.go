@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"math"
+
+	"github.com/condominio/backend/internal/models"
+)
+
+// Ballot weights are tallied as integers so that ties are exact: one unit per
+// parcela, or millionths of coefficient in weighted votaciones.
+const unidadesCoeficiente = 1e6
+
+// boletaPreferencial is a counted ranking, from most to least preferred.
+type boletaPreferencial struct {
+	opciones []string
+	peso     int64
+}
+
+// conteoPreferencial adds the instant-runoff rounds and the Borda points to
+// the results of a preferencial votacion, and the winner by its
+// MetodoConteo.
+func (s *VotacionService) conteoPreferencial(ctx context.Context, votacion *models.Votacion, resultado *models.VotacionResultado) error {
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT COALESCE(opciones::text[], '{}'), peso
+		FROM (`+boletasVotacion+`) b
+		WHERE NOT is_abstention`, votacion.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	escala := 1.0
+	if votacion.Ponderada {
+		escala = unidadesCoeficiente
+	}
+	boletas := []boletaPreferencial{}
+	for rows.Next() {
+		var b boletaPreferencial
+		var peso float64
+		if err := rows.Scan(&b.opciones, &peso); err != nil {
+			return err
+		}
+		b.peso = 1
+		if votacion.Ponderada {
+			b.peso = int64(math.Round(peso * unidadesCoeficiente))
+		}
+		boletas = append(boletas, b)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rondas, ganador, empate := conteoIRV(votacion.Opciones, boletas, escala)
+	puntos := conteoBorda(votacion.Opciones, boletas)
+	if votacion.MetodoConteo == models.ConteoBorda {
+		ganador, empate = ganadorBorda(votacion.Opciones, puntos)
+	}
+
+	for i := range resultado.Resultados {
+		p := float64(puntos[resultado.Resultados[i].OpcionID]) / escala
+		resultado.Resultados[i].Puntos = &p
+	}
+	resultado.Rondas = rondas
+	resultado.Ganador = ganador
+	resultado.Empate = empate
+	return nil
+}
+
+// conteoIRV runs the instant-runoff count: each round every ballot counts for
+// its most preferred opcion still in the race, and the opcion with the
+// fewest votes is dropped until one holds a majority of the ballots not
+// exhausted. Ties for last place drop the opcion with fewer first
+// preferences, then the later one in the ballot. It is a tie when every
+// remaining opcion has the same votes.
+func conteoIRV(opciones []models.VotacionOpcion, boletas []boletaPreferencial, escala float64) ([]models.RondaConteo, *string, bool) {
+	activas := make(map[string]bool, len(opciones))
+	for _, o := range opciones {
+		activas[o.ID] = true
+	}
+
+	rondas := []models.RondaConteo{}
+	var primeras map[string]int64
+	for n := 1; len(activas) > 0; n++ {
+		votos := map[string]int64{}
+		var total, agotadas int64
+		for _, b := range boletas {
+			if id, ok := preferida(b.opciones, activas); ok {
+				votos[id] += b.peso
+				total += b.peso
+			} else {
+				agotadas += b.peso
+			}
+		}
+		if primeras == nil {
+			primeras = votos
+		}
+
+		ronda := models.RondaConteo{Ronda: n, Agotadas: float64(agotadas) / escala}
+		for _, o := range opciones {
+			if activas[o.ID] {
+				ronda.Conteos = append(ronda.Conteos, models.ConteoOpcion{
+					OpcionID:   o.ID,
+					Label:      o.Label,
+					Votos:      float64(votos[o.ID]) / escala,
+					Percentage: porcentajeVotos(float64(votos[o.ID]), float64(total)),
+				})
+			}
+		}
+		if total == 0 {
+			return append(rondas, ronda), nil, false
+		}
+
+		for _, o := range opciones {
+			if activas[o.ID] && votos[o.ID]*2 > total {
+				id := o.ID
+				ronda.Ganador = &id
+				return append(rondas, ronda), &id, false
+			}
+		}
+
+		eliminada := ""
+		empate := true
+		for i := len(opciones) - 1; i >= 0; i-- {
+			id := opciones[i].ID
+			if !activas[id] {
+				continue
+			}
+			if eliminada == "" {
+				eliminada = id
+				continue
+			}
+			if votos[id] != votos[eliminada] {
+				empate = false
+			}
+			if votos[id] < votos[eliminada] ||
+				(votos[id] == votos[eliminada] && primeras[id] < primeras[eliminada]) {
+				eliminada = id
+			}
+		}
+		if empate {
+			return append(rondas, ronda), nil, true
+		}
+		ronda.Eliminada = &eliminada
+		delete(activas, eliminada)
+		rondas = append(rondas, ronda)
+	}
+	return rondas, nil, false
+}
+
+// preferida is the most preferred opcion of the ranking still in the race.
+func preferida(ranking []string, activas map[string]bool) (string, bool) {
+	for _, id := range ranking {
+		if activas[id] {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// conteoBorda gives each ranked opcion as many points as there are opciones
+// minus its position: n for the first of n, n-1 for the second, and so on.
+// Unranked opciones get none.
+func conteoBorda(opciones []models.VotacionOpcion, boletas []boletaPreferencial) map[string]int64 {
+	n := int64(len(opciones))
+	puntos := make(map[string]int64, len(opciones))
+	for _, b := range boletas {
+		for i, id := range b.opciones {
+			puntos[id] += (n - int64(i)) * b.peso
+		}
+	}
+	return puntos
+}
+
+// ganadorBorda is the opcion with the most points; a tie for first place
+// has no winner.
+func ganadorBorda(opciones []models.VotacionOpcion, puntos map[string]int64) (*string, bool) {
+	var ganador *string
+	empate := false
+	for i := range opciones {
+		id := opciones[i].ID
+		switch {
+		case puntos[id] == 0:
+		case ganador == nil || puntos[id] > puntos[*ganador]:
+			ganador = &id
+			empate = false
+		case puntos[id] == puntos[*ganador]:
+			empate = true
+		}
+	}
+	if empate {
+		return nil, true
+	}
+	return ganador, false
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/condominio/backend/internal/models"
+)
+
+func opcionesConteo(ids ...string) []models.VotacionOpcion {
+	opciones := make([]models.VotacionOpcion, len(ids))
+	for i, id := range ids {
+		opciones[i] = models.VotacionOpcion{ID: id, Label: id, OrderIndex: i}
+	}
+	return opciones
+}
+
+// boletasConteo repeats a ranking n times with weight 1.
+func boletasConteo(n int, ranking ...string) []boletaPreferencial {
+	boletas := make([]boletaPreferencial, n)
+	for i := range boletas {
+		boletas[i] = boletaPreferencial{opciones: ranking, peso: 1}
+	}
+	return boletas
+}
+
+func juntarBoletas(grupos ...[]boletaPreferencial) []boletaPreferencial {
+	var boletas []boletaPreferencial
+	for _, g := range grupos {
+		boletas = append(boletas, g...)
+	}
+	return boletas
+}
+
+func TestConteoIRV(t *testing.T) {
+	tests := []struct {
+		name       string
+		opciones   []string
+		boletas    []boletaPreferencial
+		escala     float64
+		eliminadas []string // opcion dropped after each round but the last
+		ganador    string   // "" for none
+		empate     bool
+		votos      map[string]float64 // of the last round
+		agotadas   float64            // of the last round
+	}{
+		{
+			name:     "majority in the first round",
+			opciones: []string{"a", "b", "c"},
+			boletas: juntarBoletas(
+				boletasConteo(3, "a"),
+				boletasConteo(2, "b", "a"),
+			),
+			escala:  1,
+			ganador: "a",
+			votos:   map[string]float64{"a": 3, "b": 2, "c": 0},
+		},
+		{
+			name:     "several eliminations with an exhausted ballot",
+			opciones: []string{"a", "b", "c", "d"},
+			boletas: juntarBoletas(
+				boletasConteo(4, "a"),
+				boletasConteo(3, "b"),
+				boletasConteo(2, "c", "b"),
+				boletasConteo(1, "d", "c"),
+			),
+			escala: 1,
+			// d first; then b and c tie at 3 and c has fewer first preferences
+			eliminadas: []string{"d", "c"},
+			ganador:    "b",
+			votos:      map[string]float64{"a": 4, "b": 5},
+			agotadas:   1,
+		},
+		{
+			name:     "tie for last place drops the later opcion",
+			opciones: []string{"a", "b", "c"},
+			boletas: juntarBoletas(
+				boletasConteo(2, "a"),
+				boletasConteo(1, "b"),
+				boletasConteo(1, "c", "a"),
+			),
+			escala:     1,
+			eliminadas: []string{"c"},
+			ganador:    "a",
+			votos:      map[string]float64{"a": 3, "b": 1},
+		},
+		{
+			name:     "tie for the win in the first round",
+			opciones: []string{"a", "b"},
+			boletas: juntarBoletas(
+				boletasConteo(2, "a"),
+				boletasConteo(2, "b"),
+			),
+			escala: 1,
+			empate: true,
+			votos:  map[string]float64{"a": 2, "b": 2},
+		},
+		{
+			name:     "tie for the win after exhausting ballots",
+			opciones: []string{"a", "b", "c"},
+			boletas: juntarBoletas(
+				boletasConteo(2, "a"),
+				boletasConteo(2, "b"),
+				boletasConteo(1, "c"),
+			),
+			escala:     1,
+			eliminadas: []string{"c"},
+			empate:     true,
+			votos:      map[string]float64{"a": 2, "b": 2},
+			agotadas:   1,
+		},
+		{
+			name:     "majority of the ballots not exhausted",
+			opciones: []string{"a", "b", "c"},
+			boletas: juntarBoletas(
+				boletasConteo(3, "a"),
+				boletasConteo(2, "b"),
+				boletasConteo(2, "c"),
+			),
+			escala: 1,
+			// c drops before b on the tie; its ballots are exhausted and 3 of
+			// the 5 left is a majority
+			eliminadas: []string{"c"},
+			ganador:    "a",
+			votos:      map[string]float64{"a": 3, "b": 2},
+			agotadas:   2,
+		},
+		{
+			name:     "no ballots",
+			opciones: []string{"a", "b"},
+			escala:   1,
+			votos:    map[string]float64{"a": 0, "b": 0},
+		},
+		{
+			name:     "weighted by coefficient, not by ballots",
+			opciones: []string{"a", "b", "c"},
+			boletas: []boletaPreferencial{
+				{opciones: []string{"a"}, peso: 400000},
+				{opciones: []string{"b"}, peso: 350000},
+				{opciones: []string{"c", "b"}, peso: 150000},
+				{opciones: []string{"c", "b"}, peso: 100000},
+			},
+			escala: unidadesCoeficiente,
+			// c has the most ballots but the least coefficient
+			eliminadas: []string{"c"},
+			ganador:    "b",
+			votos:      map[string]float64{"a": 0.4, "b": 0.6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rondas, ganador, empate := conteoIRV(opcionesConteo(tt.opciones...), tt.boletas, tt.escala)
+
+			if len(rondas) != len(tt.eliminadas)+1 {
+				t.Fatalf("got %d rondas, want %d", len(rondas), len(tt.eliminadas)+1)
+			}
+			for i, r := range rondas {
+				if r.Ronda != i+1 {
+					t.Errorf("ronda %d numbered %d", i+1, r.Ronda)
+				}
+				want := ""
+				if i < len(tt.eliminadas) {
+					want = tt.eliminadas[i]
+				}
+				got := ""
+				if r.Eliminada != nil {
+					got = *r.Eliminada
+				}
+				if got != want {
+					t.Errorf("ronda %d eliminated %q, want %q", i+1, got, want)
+				}
+			}
+
+			got := ""
+			if ganador != nil {
+				got = *ganador
+			}
+			if got != tt.ganador || empate != tt.empate {
+				t.Errorf("ganador %q empate %v, want %q %v", got, empate, tt.ganador, tt.empate)
+			}
+
+			ultima := rondas[len(rondas)-1]
+			if tt.ganador != "" && (ultima.Ganador == nil || *ultima.Ganador != tt.ganador) {
+				t.Errorf("last ronda does not name the winner")
+			}
+			votos := map[string]float64{}
+			for _, c := range ultima.Conteos {
+				votos[c.OpcionID] = c.Votos
+			}
+			if !reflect.DeepEqual(votos, tt.votos) {
+				t.Errorf("last ronda votos %v, want %v", votos, tt.votos)
+			}
+			if ultima.Agotadas != tt.agotadas {
+				t.Errorf("last ronda agotadas %v, want %v", ultima.Agotadas, tt.agotadas)
+			}
+		})
+	}
+}
+
+func TestConteoBorda(t *testing.T) {
+	tests := []struct {
+		name    string
+		boletas []boletaPreferencial
+		want    map[string]int64
+	}{
+		{
+			name: "full rankings",
+			boletas: juntarBoletas(
+				boletasConteo(2, "a", "b", "c"),
+				boletasConteo(1, "c", "b", "a"),
+			),
+			want: map[string]int64{"a": 7, "b": 6, "c": 5},
+		},
+		{
+			name: "partial rankings leave the rest without points",
+			boletas: juntarBoletas(
+				boletasConteo(1, "a", "b", "c"),
+				boletasConteo(1, "b"),
+				boletasConteo(1, "c", "a"),
+			),
+			want: map[string]int64{"a": 5, "b": 5, "c": 4},
+		},
+		{
+			name: "weighted ballots",
+			boletas: []boletaPreferencial{
+				{opciones: []string{"a", "b"}, peso: 250000},
+				{opciones: []string{"b"}, peso: 750000},
+			},
+			want: map[string]int64{"a": 750000, "b": 500000 + 2250000},
+		},
+		{
+			name: "no ballots",
+			want: map[string]int64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := conteoBorda(opcionesConteo("a", "b", "c"), tt.boletas)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("puntos %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGanadorBorda(t *testing.T) {
+	tests := []struct {
+		name    string
+		puntos  map[string]int64
+		ganador string
+		empate  bool
+	}{
+		{"most points wins", map[string]int64{"a": 7, "b": 6, "c": 5}, "a", false},
+		{"later opcion can win", map[string]int64{"a": 5, "b": 5, "c": 7}, "c", false},
+		{"tie for first place", map[string]int64{"a": 5, "b": 5, "c": 4}, "", true},
+		{"tie for second place does not matter", map[string]int64{"a": 4, "b": 4, "c": 9}, "c", false},
+		{"no points", map[string]int64{}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ganador, empate := ganadorBorda(opcionesConteo("a", "b", "c"), tt.puntos)
+			got := ""
+			if ganador != nil {
+				got = *ganador
+			}
+			if got != tt.ganador || empate != tt.empate {
+				t.Errorf("ganador %q empate %v, want %q %v", got, empate, tt.ganador, tt.empate)
+			}
+		})
+	}
+}
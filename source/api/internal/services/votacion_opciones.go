@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/condominio/backend/internal/models"
+)
+
+var (
+	ErrOpcionNotFound       = errors.New("opcion not found")
+	ErrOpcionesNoEditables  = errors.New("options can only change while the votacion is a draft")
+	ErrInvalidOpcionLabel   = errors.New("label is required")
+	ErrInvalidOrdenOpciones = errors.New("opciones must list every option of the votacion once")
+)
+
+const opcionColumns = `id, votacion_id, label, COALESCE(description, ''), order_index`
+
+func scanOpcion(row pgx.Row) (*models.VotacionOpcion, error) {
+	var o models.VotacionOpcion
+	if err := row.Scan(&o.ID, &o.VotacionID, &o.Label, &o.Description, &o.OrderIndex); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// bloquearBorrador locks the votacion while its opciones change, so it cannot
+// be published halfway.
+func bloquearBorrador(ctx context.Context, q querier, votacionID string) error {
+	var status models.VotacionStatus
+	err := q.QueryRow(ctx, `SELECT status FROM votaciones WHERE id::text = $1 FOR UPDATE`, votacionID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrVotacionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status != models.VotacionStatusDraft {
+		return ErrOpcionesNoEditables
+	}
+	return nil
+}
+
+// AddOpcion appends an opcion to a draft votacion.
+func (s *VotacionService) AddOpcion(ctx context.Context, votacionID string, req *models.AddOpcionRequest) (*models.VotacionOpcion, error) {
+	label := strings.TrimSpace(req.Label)
+	if label == "" {
+		return nil, ErrInvalidOpcionLabel
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := bloquearBorrador(ctx, tx, votacionID); err != nil {
+		return nil, err
+	}
+
+	opcion, err := scanOpcion(tx.QueryRow(ctx, `
+		INSERT INTO votacion_opciones (votacion_id, label, description, order_index)
+		VALUES ($1, $2, NULLIF($3, ''),
+		        (SELECT COALESCE(MAX(order_index) + 1, 0) FROM votacion_opciones WHERE votacion_id = $1))
+		RETURNING `+opcionColumns, votacionID, label, req.Description))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return opcion, nil
+}
+
+func (s *VotacionService) UpdateOpcion(ctx context.Context, votacionID, opcionID string, req *models.UpdateOpcionRequest) (*models.VotacionOpcion, error) {
+	if req.Label != nil && strings.TrimSpace(*req.Label) == "" {
+		return nil, ErrInvalidOpcionLabel
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := bloquearBorrador(ctx, tx, votacionID); err != nil {
+		return nil, err
+	}
+
+	var label *string
+	if req.Label != nil {
+		l := strings.TrimSpace(*req.Label)
+		label = &l
+	}
+	opcion, err := scanOpcion(tx.QueryRow(ctx, `
+		UPDATE votacion_opciones
+		SET label = COALESCE($3, label),
+		    description = CASE WHEN $4::text IS NULL THEN description ELSE NULLIF($4, '') END
+		WHERE id::text = $2 AND votacion_id = $1
+		RETURNING `+opcionColumns, votacionID, opcionID, label, req.Description))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrOpcionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return opcion, nil
+}
+
+// DeleteOpcion removes an opcion from a draft votacion and closes the gap in
+// the order.
+func (s *VotacionService) DeleteOpcion(ctx context.Context, votacionID, opcionID string) error {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := bloquearBorrador(ctx, tx, votacionID); err != nil {
+		return err
+	}
+
+	var orden int
+	err = tx.QueryRow(ctx, `
+		DELETE FROM votacion_opciones WHERE id::text = $2 AND votacion_id = $1
+		RETURNING order_index`, votacionID, opcionID).Scan(&orden)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrOpcionNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE votacion_opciones SET order_index = order_index - 1
+		WHERE votacion_id = $1 AND order_index > $2`, votacionID, orden)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ReordenarOpciones sets the order of the opciones of a draft votacion; ids
+// must list each of them once.
+func (s *VotacionService) ReordenarOpciones(ctx context.Context, votacionID string, ids []string) ([]models.VotacionOpcion, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := bloquearBorrador(ctx, tx, votacionID); err != nil {
+		return nil, err
+	}
+
+	var actuales []string
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(array_agg(id::text), '{}') FROM votacion_opciones WHERE votacion_id = $1`,
+		votacionID).Scan(&actuales)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) != len(actuales) {
+		return nil, ErrInvalidOrdenOpciones
+	}
+	pendientes := make(map[string]bool, len(actuales))
+	for _, id := range actuales {
+		pendientes[id] = true
+	}
+	for _, id := range ids {
+		if !pendientes[id] {
+			return nil, ErrInvalidOrdenOpciones
+		}
+		delete(pendientes, id)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE votacion_opciones o SET order_index = n.orden - 1
+		FROM unnest($2::uuid[]) WITH ORDINALITY AS n(id, orden)
+		WHERE o.id = n.id AND o.votacion_id = $1`, votacionID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return s.getOpciones(ctx, votacionID, models.VotacionSimple)
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/condominio/backend/internal/models"
+)
+
+var (
+	ErrVotacionNoSecreta = errors.New("votacion is not secret")
+	ErrReciboNotFound    = errors.New("no ballot matches the receipt")
+)
+
+// boletasVotacion yields the counted ballots of votacion $1 whatever its
+// mode: the votos themselves in public votaciones, the anonymous boletas in
+// secret ones. Secret ballots cannot tell whether an apoderado cast them, and
+// only count once published at closing; turnout comes from votos instead.
+const boletasVotacion = `
+	SELECT vo.opcion_id, vo.opciones, vo.is_abstention, vo.peso, vo.poder_id IS NOT NULL AS por_poder
+	FROM votos vo
+	JOIN votaciones v ON v.id = vo.votacion_id
+	WHERE vo.votacion_id = $1 AND vo.parcela_id IS NOT NULL AND NOT v.secreta
+	UNION ALL
+	SELECT b.opcion_id, b.opciones, b.is_abstention, b.peso, false
+	FROM boletas b
+	WHERE b.votacion_id = $1`
+
+// boletaColumns lists a published boleta; the opciones of multiple and
+// preferencial ballots by label, in the order they were ranked.
+const boletaColumns = `
+	b.recibo, b.opcion_id, COALESCE(o.label, ''),
+	ARRAY(SELECT so.label
+	      FROM unnest(b.opciones) WITH ORDINALITY AS sel(opcion_id, orden)
+	      JOIN votacion_opciones so ON so.id = sel.opcion_id
+	      ORDER BY sel.orden),
+	b.is_abstention, b.peso`
+
+// bloquearAbierta locks the votacion against closing while a ballot is cast
+// or changed, and confirms it still takes votes.
+func bloquearAbierta(ctx context.Context, q querier, votacionID string) error {
+	var abierta bool
+	err := q.QueryRow(ctx, `
+		SELECT status = 'active'
+		       AND (start_date IS NULL OR start_date <= NOW())
+		       AND (end_date IS NULL OR end_date > NOW())
+		FROM votaciones WHERE id = $1 FOR SHARE`, votacionID).Scan(&abierta)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrVotacionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !abierta {
+		return ErrVotacionNotActive
+	}
+	return nil
+}
+
+// emitirVotoSecreto records that the parcela voted and drops the ballot in
+// the urna of the votacion until closing, both in one transaction. The votos
+// row keeps only the llave of the receipt; the urna only its huella. Only the
+// voter gets the receipt code.
+func (s *VotacionService) emitirVotoSecreto(ctx context.Context, votacionID, userID string, parcelaID int, poderID, opcionID *string, opciones []string, abstencion bool) (*models.ReciboVoto, error) {
+	recibo, err := generarRecibo(votacionID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := bloquearAbierta(ctx, tx, votacionID); err != nil {
+		return nil, err
+	}
+
+	var peso float64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO votos (votacion_id, user_id, parcela_id, peso, poder_id, is_abstention, llave_recibo, voted_at)
+		VALUES ($1, $2, $3, (SELECT coeficiente FROM parcelas WHERE id = $3), $4, false, $5, NOW())
+		RETURNING peso`,
+		votacionID, userID, parcelaID, poderID, llaveRecibo(recibo.Codigo)).Scan(&peso)
+	if err != nil {
+		return nil, votoError(err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO urna_boletas (votacion_id, opcion_id, opciones, is_abstention, peso, recibo)
+		VALUES ($1, $2, $3::uuid[], $4, $5, $6)`,
+		votacionID, opcionID, opciones, abstencion, peso, recibo.Huella)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return recibo, nil
+}
+
+// publicarBoletas moves the ballots of the votacion from the urna to
+// boletas in a single shuffled batch, so the published boletas carry no
+// order of casting. While the votacion is open, whoever reads the database
+// directly can still pair each ballot with its votos row.
+func publicarBoletas(ctx context.Context, q querier, votacionID string) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO boletas (votacion_id, opcion_id, opciones, is_abstention, peso, recibo)
+		SELECT votacion_id, opcion_id, opciones, is_abstention, peso, recibo
+		FROM urna_boletas
+		WHERE votacion_id = $1
+		ORDER BY random()`, votacionID)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(ctx, `DELETE FROM urna_boletas WHERE votacion_id = $1`, votacionID)
+	return err
+}
+
+// generarRecibo draws a random receipt code; only its SHA-256 is stored.
+func generarRecibo(votacionID string) (*models.ReciboVoto, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	codigo := strings.ToUpper(hex.EncodeToString(b))
+	return &models.ReciboVoto{
+		VotacionID: votacionID,
+		Codigo:     codigo,
+		Huella:     huellaRecibo(codigo),
+	}, nil
+}
+
+// huellaRecibo is the published fingerprint of a receipt code, tolerant of
+// the case and spacing the voter types it with.
+func huellaRecibo(codigo string) string {
+	sum := sha256.Sum256([]byte(normalizarRecibo(codigo)))
+	return hex.EncodeToString(sum[:])
+}
+
+// llaveRecibo is the fingerprint kept with the votos row: it proves the
+// holder of the code cast that parcela's ballot, and cannot be matched with
+// the huella without the code.
+func llaveRecibo(codigo string) string {
+	sum := sha256.Sum256([]byte("cambio:" + normalizarRecibo(codigo)))
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizarRecibo(codigo string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(codigo), ""))
+}
+
+// ListBoletas publishes the ballots of a secret votacion, in fingerprint
+// order so the list says nothing about when each was cast. It is empty until
+// the votacion closes; then anyone can recount the results from it.
+func (s *VotacionService) ListBoletas(ctx context.Context, votacionID string) ([]models.Boleta, error) {
+	votacion, err := s.GetByID(ctx, votacionID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !votacion.Secreta {
+		return nil, ErrVotacionNoSecreta
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT `+boletaColumns+`
+		FROM boletas b
+		LEFT JOIN votacion_opciones o ON o.id = b.opcion_id
+		WHERE b.votacion_id = $1
+		ORDER BY b.recibo`, votacionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	boletas := []models.Boleta{}
+	for rows.Next() {
+		var b models.Boleta
+		if err := rows.Scan(&b.Huella, &b.OpcionID, &b.Opcion, &b.Opciones, &b.IsAbstention, &b.Peso); err != nil {
+			return nil, err
+		}
+		boletas = append(boletas, b)
+	}
+	return boletas, rows.Err()
+}
+
+// VerificarRecibo finds the ballot of a receipt code among the published
+// boletas of the votacion, once it has closed.
+func (s *VotacionService) VerificarRecibo(ctx context.Context, votacionID, codigo string) (*models.Boleta, error) {
+	var b models.Boleta
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT `+boletaColumns+`
+		FROM boletas b
+		LEFT JOIN votacion_opciones o ON o.id = b.opcion_id
+		WHERE b.votacion_id = $1 AND b.recibo = $2`,
+		votacionID, huellaRecibo(codigo)).Scan(&b.Huella, &b.OpcionID, &b.Opcion, &b.Opciones, &b.IsAbstention, &b.Peso)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrReciboNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
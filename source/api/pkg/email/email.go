@@ -2,7 +2,10 @@ package email
 
 import (
 	"bytes"
+	"crypto/rand"
 	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"html/template"
 	"log"
@@ -37,12 +40,20 @@ func NewService(cfg Config) *Service {
 
 // Email represents an email message
 type Email struct {
-	To       []string
-	Subject  string
-	Body     string
-	HTML     bool
-	Template string
-	Data     interface{}
+	To          []string
+	Subject     string
+	Body        string
+	HTML        bool
+	Template    string
+	Data        interface{}
+	Attachments []Attachment
+}
+
+// Attachment is a file sent along with an email
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
 }
 
 // Send sends an email
@@ -86,10 +97,17 @@ func (s *Service) Send(email Email) error {
 	headers["Subject"] = email.Subject
 	headers["MIME-Version"] = "1.0"
 
+	bodyContentType := "text/plain; charset=UTF-8"
 	if email.HTML {
-		headers["Content-Type"] = "text/html; charset=UTF-8"
+		bodyContentType = "text/html; charset=UTF-8"
+	}
+
+	var boundary string
+	if len(email.Attachments) > 0 {
+		boundary = randomBoundary()
+		headers["Content-Type"] = fmt.Sprintf("multipart/mixed; boundary=%q", boundary)
 	} else {
-		headers["Content-Type"] = "text/plain; charset=UTF-8"
+		headers["Content-Type"] = bodyContentType
 	}
 
 	var msg bytes.Buffer
@@ -97,7 +115,11 @@ func (s *Service) Send(email Email) error {
 		msg.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
 	}
 	msg.WriteString("\r\n")
-	msg.WriteString(body)
+	if boundary == "" {
+		msg.WriteString(body)
+	} else {
+		writeMultipart(&msg, boundary, bodyContentType, body, email.Attachments)
+	}
 
 	// Connect to SMTP server
 	addr := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
@@ -206,6 +228,37 @@ func (s *Service) sendSTARTTLS(addr string, auth smtp.Auth, to []string, msg []b
 	return w.Close()
 }
 
+// writeMultipart writes the body followed by base64-encoded attachments.
+func writeMultipart(msg *bytes.Buffer, boundary, bodyContentType, body string, attachments []Attachment) {
+	fmt.Fprintf(msg, "--%s\r\nContent-Type: %s\r\n\r\n%s\r\n", boundary, bodyContentType, body)
+
+	for _, a := range attachments {
+		contentType := a.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		fmt.Fprintf(msg, "--%s\r\n", boundary)
+		fmt.Fprintf(msg, "Content-Type: %s; name=%q\r\n", contentType, a.Filename)
+		fmt.Fprintf(msg, "Content-Disposition: attachment; filename=%q\r\n", a.Filename)
+		msg.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+		// RFC 2045: base64 lines of at most 76 characters
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			msg.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		msg.WriteString(encoded + "\r\n")
+	}
+	fmt.Fprintf(msg, "--%s--\r\n", boundary)
+}
+
+func randomBoundary() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return "condominio-" + hex.EncodeToString(b[:])
+}
+
 // RegisterTemplate registers an HTML template for emails
 func (s *Service) RegisterTemplate(name string, tmpl *template.Template) {
 	s.templates[name] = tmpl
@@ -356,6 +356,50 @@ const GastoComunTemplate = `
 </html>
 `
 
+// PagoRecibidoTemplate confirms a payment; the receipt PDF goes as attachment
+const PagoRecibidoTemplate = `
+<!DOCTYPE html>
+<html lang="es">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Pago Recibido - Recibo N° {{.Numero}}</title>
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px; background-color: #f5f5f5; }
+        .container { background-color: #ffffff; border-radius: 8px; padding: 30px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); }
+        .header { text-align: center; margin-bottom: 30px; padding-bottom: 20px; border-bottom: 2px solid #2D5016; }
+        .header h1 { color: #2D5016; margin: 0; font-size: 24px; }
+        .amount { font-size: 32px; font-weight: bold; color: #2D5016; text-align: center; margin: 20px 0; }
+        .info-box { background-color: #f0f7eb; border-left: 4px solid #2D5016; padding: 15px; margin: 20px 0; border-radius: 0 4px 4px 0; }
+        .footer { text-align: center; padding-top: 20px; border-top: 1px solid #eee; color: #666; font-size: 12px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>Comunidad Viña Pelvin</h1>
+        </div>
+        <div class="content">
+            <h2>Pago Recibido</h2>
+            <p>Hola <strong>{{.Nombre}}</strong>,</p>
+            <p>Hemos registrado su pago. Adjuntamos el recibo correspondiente.</p>
+            <div class="amount">{{.Monto}}</div>
+            <div class="info-box">
+                <p><strong>Recibo N°:</strong> {{.Numero}}</p>
+                <p><strong>Parcela:</strong> {{.Parcela}}</p>
+                <p><strong>Concepto:</strong> {{.Concepto}}</p>
+                <p><strong>Fecha de pago:</strong> {{.FechaPago}}</p>
+                <p><strong>Medio de pago:</strong> {{.Metodo}}</p>
+            </div>
+        </div>
+        <div class="footer">
+            <p>Comunidad Viña Pelvin - Parcelas de Agrado</p>
+        </div>
+    </div>
+</body>
+</html>
+`
+
 // BienvenidaTemplate is sent to new users
 const BienvenidaTemplate = `
 <!DOCTYPE html>
@@ -416,6 +460,7 @@ func RegisterAllTemplates(s *Service) error {
 		TemplateNotificacion:      NotificacionTemplate,
 		TemplateEmergencia:        EmergenciaTemplate,
 		TemplateGastoComun:        GastoComunTemplate,
+		TemplatePagoRecibido:      PagoRecibidoTemplate,
 		TemplateBienvenida:        BienvenidaTemplate,
 	}
 
@@ -0,0 +1,315 @@
+// Package money is an exact fixed-point amount for the peso columns stored
+// as DECIMAL(12,2). Amounts are kept as an integer number of centavos, so
+// sums and comparisons never drift the way float64 does, and they scan from
+// and encode to Postgres numeric through pgx.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Amount is a quantity of pesos with two decimals, stored in centavos. The
+// zero value is $0 and the usual integer operators (+, -, <, ==) are exact.
+type Amount int64
+
+const scale = 100
+
+var (
+	ErrInvalid  = errors.New("invalid amount")
+	ErrOverflow = errors.New("amount out of range")
+)
+
+// FromPesos returns a whole amount of pesos.
+func FromPesos(p int64) Amount {
+	return Amount(p * scale)
+}
+
+// FromFloat rounds f to the nearest centavo. Use it only at the edges
+// (UF conversions, legacy float inputs), never to carry totals.
+func FromFloat(f float64) Amount {
+	return Amount(math.Round(f * scale))
+}
+
+// Parse reads a decimal such as "45000", "-1250.5" or "12.34". More than two
+// decimals, exponents and thousands separators are rejected.
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	ent, dec, hasDec := strings.Cut(s, ".")
+	if ent == "" && dec == "" || len(dec) > 2 || hasDec && dec == "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+	for _, r := range ent + dec {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("%w: %q", ErrInvalid, s)
+		}
+	}
+	var pesos int64
+	if ent != "" {
+		var err error
+		if pesos, err = strconv.ParseInt(ent, 10, 64); err != nil || pesos > math.MaxInt64/scale {
+			return 0, fmt.Errorf("%w: %q", ErrOverflow, s)
+		}
+	}
+	var centavos int64
+	if dec != "" {
+		centavos, _ = strconv.ParseInt(dec, 10, 64)
+		if len(dec) == 1 {
+			centavos *= 10
+		}
+	}
+	if pesos*scale > math.MaxInt64-centavos {
+		return 0, fmt.Errorf("%w: %q", ErrOverflow, s)
+	}
+	a := Amount(pesos*scale + centavos)
+	if neg {
+		a = -a
+	}
+	return a, nil
+}
+
+// Float64 is the amount as a float, for display and ratios only.
+func (a Amount) Float64() float64 {
+	return float64(a) / scale
+}
+
+// Pesos returns the whole pesos, truncating the centavos.
+func (a Amount) Pesos() int64 {
+	return int64(a) / scale
+}
+
+// Mul multiplies by a count, e.g. the number of parcelas.
+func (a Amount) Mul(n int) Amount {
+	return a * Amount(n)
+}
+
+// Div splits the amount in n parts rounded half away from zero to the
+// centavo.
+func (a Amount) Div(n int) Amount {
+	if n == 0 {
+		return 0
+	}
+	q, r := int64(a)/int64(n), int64(a)%int64(n)
+	if r < 0 {
+		r = -r
+	}
+	if 2*r >= abs(int64(n)) {
+		if (a < 0) != (n < 0) {
+			q--
+		} else {
+			q++
+		}
+	}
+	return Amount(q)
+}
+
+// DivUp splits the amount in n parts rounding up to the centavo, so the
+// parts never add up to less than the whole.
+func (a Amount) DivUp(n int) Amount {
+	if n <= 0 {
+		return 0
+	}
+	q := int64(a) / int64(n)
+	if int64(a)%int64(n) > 0 {
+		q++
+	}
+	return Amount(q)
+}
+
+// RoundPesos rounds half away from zero to whole pesos.
+func (a Amount) RoundPesos() Amount {
+	return a.Div(scale) * scale
+}
+
+// CeilPesos rounds up to whole pesos.
+func (a Amount) CeilPesos() Amount {
+	return a.DivUp(scale) * scale
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// String formats with exactly two decimals: "45000.00", "-12.50".
+func (a Amount) String() string {
+	sign := ""
+	v := int64(a)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/scale, v%scale)
+}
+
+// ============================================
+// JSON
+// ============================================
+
+// JSONFormat selects how amounts are written to JSON. Both forms are always
+// accepted when reading.
+type JSONFormat int
+
+const (
+	// JSONNumber writes a plain number: 45000 for whole pesos, 45000.5 with
+	// centavos. It is what clients received when amounts were floats.
+	JSONNumber JSONFormat = iota
+	// JSONString writes a fixed-point string: "45000.00".
+	JSONString
+)
+
+var jsonFormat = JSONNumber
+
+// SetJSONFormat changes the output format for every Amount. Call it once at
+// startup, before serving requests.
+func SetJSONFormat(f JSONFormat) {
+	jsonFormat = f
+}
+
+// ParseJSONFormat reads "number" or "string".
+func ParseJSONFormat(s string) (JSONFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "number":
+		return JSONNumber, nil
+	case "string":
+		return JSONString, nil
+	}
+	return JSONNumber, fmt.Errorf("unknown money JSON format %q", s)
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	if jsonFormat == JSONString {
+		return []byte(`"` + a.String() + `"`), nil
+	}
+	s := a.String()
+	s = strings.TrimSuffix(s, ".00")
+	if strings.Contains(s, ".") {
+		s = strings.TrimSuffix(s, "0")
+	}
+	return []byte(s), nil
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	} else if strings.ContainsAny(s, "eE") {
+		// Exponent notation from float serializers: accept it only if it
+		// lands on a centavo.
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalid, s)
+		}
+		v := FromFloat(f)
+		if v.Float64() != f {
+			return fmt.Errorf("%w: %s", ErrInvalid, s)
+		}
+		*a = v
+		return nil
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// ============================================
+// PGX
+// ============================================
+
+// ScanNumeric implements pgtype.NumericScanner so numeric columns and
+// SUM() results scan without going through float64.
+func (a *Amount) ScanNumeric(n pgtype.Numeric) error {
+	if !n.Valid {
+		return errors.New("cannot scan NULL into *money.Amount")
+	}
+	if n.NaN || n.InfinityModifier != pgtype.Finite {
+		return fmt.Errorf("%w: not a finite number", ErrInvalid)
+	}
+	v := new(big.Int)
+	if n.Int != nil {
+		v.Set(n.Int)
+	}
+	exp := int(n.Exp) + 2 // to centavos
+	if exp >= 0 {
+		v.Mul(v, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil))
+	} else {
+		// More precision than centavos (e.g. AVG): round half away from zero
+		div := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-exp)), nil)
+		q, r := new(big.Int).QuoRem(v, div, new(big.Int))
+		if r.Abs(r).Mul(r, big.NewInt(2)).Cmp(div) >= 0 {
+			if v.Sign() < 0 {
+				q.Sub(q, big.NewInt(1))
+			} else {
+				q.Add(q, big.NewInt(1))
+			}
+		}
+		v = q
+	}
+	if !v.IsInt64() {
+		return ErrOverflow
+	}
+	*a = Amount(v.Int64())
+	return nil
+}
+
+// NumericValue implements pgtype.NumericValuer.
+func (a Amount) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: big.NewInt(int64(a)), Exp: -2, Valid: true}, nil
+}
+
+// ScanInt64 implements pgtype.Int64Scanner for integer expressions.
+func (a *Amount) ScanInt64(n pgtype.Int8) error {
+	if !n.Valid {
+		return errors.New("cannot scan NULL into *money.Amount")
+	}
+	*a = FromPesos(n.Int64)
+	return nil
+}
+
+// Int64Value implements pgtype.Int64Valuer for parameters Postgres types as
+// integers. Only whole pesos can be sent that way.
+func (a Amount) Int64Value() (pgtype.Int8, error) {
+	if a%scale != 0 {
+		return pgtype.Int8{}, fmt.Errorf("%w: %s is not a whole amount of pesos", ErrInvalid, a)
+	}
+	return pgtype.Int8{Int64: a.Pesos(), Valid: true}, nil
+}
+
+// ScanFloat64 implements pgtype.Float64Scanner for float expressions.
+func (a *Amount) ScanFloat64(n pgtype.Float8) error {
+	if !n.Valid {
+		return errors.New("cannot scan NULL into *money.Amount")
+	}
+	*a = FromFloat(n.Float64)
+	return nil
+}
+
+// Float64Value implements pgtype.Float64Valuer.
+func (a Amount) Float64Value() (pgtype.Float8, error) {
+	return pgtype.Float8{Float64: a.Float64(), Valid: true}, nil
+}
@@ -0,0 +1,291 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Amount
+		wantErr error
+	}{
+		{"45000", 4500000, nil},
+		{"12.34", 1234, nil},
+		{"-1250.5", -125050, nil},
+		{"+7.05", 705, nil},
+		{" 0.01 ", 1, nil},
+		{".5", 50, nil},
+		{"0", 0, nil},
+		{"1.234", 0, ErrInvalid},
+		{"1.", 0, ErrInvalid},
+		{"", 0, ErrInvalid},
+		{"-", 0, ErrInvalid},
+		{"1e3", 0, ErrInvalid},
+		{"1.000.000", 0, ErrInvalid},
+		{"12,50", 0, ErrInvalid},
+		{"abc", 0, ErrInvalid},
+		{"92233720368547758.07", 9223372036854775807, nil},
+		{"92233720368547758.08", 0, ErrOverflow},
+		{"92233720368547759", 0, ErrOverflow},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parse(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q) unexpected error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDiv(t *testing.T) {
+	tests := []struct {
+		a    Amount
+		n    int
+		want Amount
+	}{
+		{1000, 4, 250},
+		{1000, 3, 333}, // 333.33 rounds down
+		{2000, 3, 667}, // 666.67 rounds up
+		{5, 2, 3},      // half rounds away from zero
+		{-5, 2, -3},    // also when negative
+		{5, -2, -3},    // and with a negative divisor
+		{-1000, 3, -333},
+		{1000, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Div(tt.n); got != tt.want {
+			t.Errorf("%d.Div(%d) = %d, want %d", tt.a, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestDivUp(t *testing.T) {
+	tests := []struct {
+		a    Amount
+		n    int
+		want Amount
+	}{
+		{1000, 4, 250},
+		{1000, 3, 334},
+		{1, 3, 1},
+		{1000, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.a.DivUp(tt.n); got != tt.want {
+			t.Errorf("%d.DivUp(%d) = %d, want %d", tt.a, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestMul(t *testing.T) {
+	tests := []struct {
+		a    Amount
+		n    int
+		want Amount
+	}{
+		{333, 3, 999},
+		{FromPesos(45000), 12, FromPesos(540000)},
+		{-125, 4, -500},
+		{705, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Mul(tt.n); got != tt.want {
+			t.Errorf("%d.Mul(%d) = %d, want %d", tt.a, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestRoundPesos(t *testing.T) {
+	tests := []struct {
+		a, round, ceil Amount
+	}{
+		{4500049, 4500000, 4500100},
+		{4500050, 4500100, 4500100},
+		{4500000, 4500000, 4500000},
+		{-150, -200, -100},
+	}
+	for _, tt := range tests {
+		if got := tt.a.RoundPesos(); got != tt.round {
+			t.Errorf("%d.RoundPesos() = %d, want %d", tt.a, got, tt.round)
+		}
+		if got := tt.a.CeilPesos(); got != tt.ceil {
+			t.Errorf("%d.CeilPesos() = %d, want %d", tt.a, got, tt.ceil)
+		}
+	}
+}
+
+func TestScanNumeric(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      pgtype.Numeric
+		want    Amount
+		wantErr bool
+	}{
+		{"decimal(12,2)", pgtype.Numeric{Int: big.NewInt(123456), Exp: -2, Valid: true}, 123456, false},
+		{"integer exponent", pgtype.Numeric{Int: big.NewInt(45), Exp: 3, Valid: true}, 4500000, false},
+		{"negative", pgtype.Numeric{Int: big.NewInt(-1250), Exp: -1, Valid: true}, -12500, false},
+		{"extra decimals round down", pgtype.Numeric{Int: big.NewInt(123454), Exp: -4, Valid: true}, 1235, false},
+		{"extra decimals round half up", pgtype.Numeric{Int: big.NewInt(123450), Exp: -4, Valid: true}, 1235, false},
+		{"negative half rounds away from zero", pgtype.Numeric{Int: big.NewInt(-5), Exp: -3, Valid: true}, -1, false},
+		{"nil int is zero", pgtype.Numeric{Valid: true}, 0, false},
+		{"null", pgtype.Numeric{}, 0, true},
+		{"NaN", pgtype.Numeric{NaN: true, Valid: true}, 0, true},
+		{"infinity", pgtype.Numeric{InfinityModifier: pgtype.Infinity, Valid: true}, 0, true},
+		{"overflow", pgtype.Numeric{Int: big.NewInt(1), Exp: 30, Valid: true}, 0, true},
+	}
+	for _, tt := range tests {
+		var got Amount
+		err := got.ScanNumeric(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ScanNumeric error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("%s: ScanNumeric = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScanFloat64(t *testing.T) {
+	tests := []struct {
+		in      pgtype.Float8
+		want    Amount
+		wantErr bool
+	}{
+		{pgtype.Float8{Float64: 0.1 + 0.2, Valid: true}, 30, false},
+		{pgtype.Float8{Float64: 45000, Valid: true}, 4500000, false},
+		{pgtype.Float8{Float64: 1.005, Valid: true}, 100, false}, // 1.00499999... as a float
+		{pgtype.Float8{Float64: -12.345, Valid: true}, -1235, false},
+		{pgtype.Float8{}, 0, true},
+	}
+	for _, tt := range tests {
+		var got Amount
+		err := got.ScanFloat64(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ScanFloat64(%v) error = %v, wantErr %v", tt.in.Float64, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ScanFloat64(%v) = %d, want %d", tt.in.Float64, got, tt.want)
+		}
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	defer SetJSONFormat(JSONNumber)
+
+	tests := []struct {
+		a                  Amount
+		asNumber, asString string
+	}{
+		{4500000, `45000`, `"45000.00"`},
+		{4500050, `45000.5`, `"45000.50"`},
+		{1234, `12.34`, `"12.34"`},
+		{-1250, `-12.5`, `"-12.50"`},
+		{5, `0.05`, `"0.05"`},
+		{0, `0`, `"0.00"`},
+	}
+	for _, tt := range tests {
+		for _, f := range []struct {
+			format JSONFormat
+			want   string
+		}{{JSONNumber, tt.asNumber}, {JSONString, tt.asString}} {
+			SetJSONFormat(f.format)
+			got, err := json.Marshal(tt.a)
+			if err != nil {
+				t.Errorf("Marshal(%d) format %d: %v", tt.a, f.format, err)
+				continue
+			}
+			if string(got) != f.want {
+				t.Errorf("Marshal(%d) format %d = %s, want %s", tt.a, f.format, got, f.want)
+			}
+		}
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Amount
+		wantErr bool
+	}{
+		{`45000`, 4500000, false},
+		{`45000.5`, 4500050, false},
+		{`"45000.00"`, 4500000, false},
+		{`"-12.50"`, -1250, false},
+		{`4.5e4`, 4500000, false},
+		{`1.25E1`, 1250, false},
+		{`null`, 0, false},
+		{`1.234`, 0, true},
+		{`"1.234"`, 0, true},
+		{`1.2345e1`, 0, true},
+		{`"abc"`, 0, true},
+		{`true`, 0, true},
+	}
+	for _, tt := range tests {
+		var got Amount
+		err := json.Unmarshal([]byte(tt.in), &got)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Unmarshal(%s) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	defer SetJSONFormat(JSONNumber)
+
+	for _, format := range []JSONFormat{JSONNumber, JSONString} {
+		SetJSONFormat(format)
+		for _, a := range []Amount{0, 1, 99, 4500050, -1250, 123456789} {
+			data, err := json.Marshal(a)
+			if err != nil {
+				t.Fatalf("Marshal(%d): %v", a, err)
+			}
+			var got Amount
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Unmarshal(%s): %v", data, err)
+			}
+			if got != a {
+				t.Errorf("format %d: %d round-tripped to %d", format, a, got)
+			}
+		}
+	}
+}
+
+func TestParseJSONFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    JSONFormat
+		wantErr bool
+	}{
+		{"", JSONNumber, false},
+		{"number", JSONNumber, false},
+		{" String ", JSONString, false},
+		{"float", JSONNumber, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseJSONFormat(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseJSONFormat(%q) = %d, %v; want %d, wantErr %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Package ofx is a minimal reader for the bank statements (STMTRS) of OFX
+// files. It accepts both the SGML flavour of OFX 1.x, where leaf elements
+// have no closing tag, and the XML flavour of OFX 2.x. Only the fields needed
+// to reconcile a statement are read.
+package ofx
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrNoStatement = errors.New("ofx: no bank statement found")
+
+// Statement is the bank statement of a single account.
+type Statement struct {
+	AccountID    string
+	Currency     string
+	Start        time.Time
+	End          time.Time
+	Balance      *string // LEDGERBAL/BALAMT as written in the file
+	BalanceDate  time.Time
+	Transactions []Transaction
+}
+
+// Transaction is a STMTTRN entry. Amount keeps the text of TRNAMT: negative
+// for debits, positive for credits.
+type Transaction struct {
+	Type   string
+	Posted time.Time
+	Amount string
+	FITID  string
+	Ref    string // CHECKNUM or REFNUM
+	Name   string
+	Memo   string
+}
+
+// Parse reads the first bank or credit card statement of an OFX file.
+func Parse(data []byte) (*Statement, error) {
+	if i := bytes.Index(bytes.ToUpper(data), []byte("<OFX>")); i >= 0 {
+		data = data[i:]
+	} else {
+		return nil, ErrNoStatement
+	}
+
+	var st *Statement
+	var trn *Transaction
+	var stack []string
+	enStmt := false
+
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '<')
+		if i < 0 {
+			break
+		}
+		data = data[i+1:]
+		j := bytes.IndexByte(data, '>')
+		if j < 0 {
+			break
+		}
+		tag := strings.ToUpper(strings.TrimSpace(string(data[:j])))
+		data = data[j+1:]
+
+		if strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") {
+			continue
+		}
+		if strings.HasPrefix(tag, "/") {
+			tag = tag[1:]
+			// Pop up to the matching aggregate; leaf closing tags of
+			// OFX 2.x do not appear in the stack
+			for k := len(stack) - 1; k >= 0; k-- {
+				if stack[k] == tag {
+					stack = stack[:k]
+					break
+				}
+			}
+			switch tag {
+			case "STMTTRN":
+				if trn != nil && st != nil {
+					st.Transactions = append(st.Transactions, *trn)
+				}
+				trn = nil
+			case "STMTRS", "CCSTMTRS":
+				enStmt = false
+				if st != nil {
+					return st, nil
+				}
+			}
+			continue
+		}
+
+		// Element text runs up to the next tag
+		k := bytes.IndexByte(data, '<')
+		if k < 0 {
+			k = len(data)
+		}
+		valor := strings.TrimSpace(unescape(string(data[:k])))
+
+		if valor == "" {
+			// Aggregate
+			stack = append(stack, tag)
+			switch tag {
+			case "STMTRS", "CCSTMTRS":
+				if st == nil {
+					st = &Statement{}
+				}
+				enStmt = true
+			case "STMTTRN":
+				if enStmt {
+					trn = &Transaction{}
+				}
+			}
+			continue
+		}
+		if !enStmt || st == nil {
+			continue
+		}
+
+		padre := ""
+		if len(stack) > 0 {
+			padre = stack[len(stack)-1]
+		}
+		if trn != nil {
+			switch tag {
+			case "TRNTYPE":
+				trn.Type = valor
+			case "DTPOSTED":
+				trn.Posted, _ = parseDate(valor)
+			case "TRNAMT":
+				trn.Amount = valor
+			case "FITID":
+				trn.FITID = valor
+			case "CHECKNUM", "REFNUM":
+				if trn.Ref == "" {
+					trn.Ref = valor
+				}
+			case "NAME":
+				trn.Name = valor
+			case "MEMO":
+				trn.Memo = valor
+			}
+			continue
+		}
+		switch {
+		case tag == "CURDEF":
+			st.Currency = valor
+		case tag == "ACCTID":
+			st.AccountID = valor
+		case tag == "DTSTART" && padre == "BANKTRANLIST":
+			st.Start, _ = parseDate(valor)
+		case tag == "DTEND" && padre == "BANKTRANLIST":
+			st.End, _ = parseDate(valor)
+		case tag == "BALAMT" && padre == "LEDGERBAL":
+			v := valor
+			st.Balance = &v
+		case tag == "DTASOF" && padre == "LEDGERBAL":
+			st.BalanceDate, _ = parseDate(valor)
+		}
+	}
+
+	if st == nil {
+		return nil, ErrNoStatement
+	}
+	// SGML files may end without closing the statement
+	if trn != nil {
+		st.Transactions = append(st.Transactions, *trn)
+	}
+	return st, nil
+}
+
+// parseDate reads the date part of an OFX datetime
+// (YYYYMMDD[HHMMSS[.XXX]][[offset:TZ]]).
+func parseDate(v string) (time.Time, error) {
+	if len(v) > 8 {
+		v = v[:8]
+	}
+	return time.Parse("20060102", v)
+}
+
+func unescape(v string) string {
+	if !strings.Contains(v, "&") {
+		return v
+	}
+	return strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&", "&quot;", `"`, "&apos;", "'").Replace(v)
+}
@@ -0,0 +1,52 @@
+package pdf
+
+// Glyph widths (1/1000 em) of the standard Helvetica fonts for printable ASCII,
+// taken from the Adobe core font metrics.
+
+var helveticaWidths = map[byte]int{
+	32: 278, 33: 278, 34: 355, 35: 556, 36: 556, 37: 889, 38: 667, 39: 191,
+	40: 333, 41: 333, 42: 389, 43: 584, 44: 278, 45: 333, 46: 278, 47: 278,
+	48: 556, 49: 556, 50: 556, 51: 556, 52: 556, 53: 556, 54: 556, 55: 556,
+	56: 556, 57: 556, 58: 278, 59: 278, 60: 584, 61: 584, 62: 584, 63: 556,
+	64: 1015, 65: 667, 66: 667, 67: 722, 68: 722, 69: 667, 70: 611, 71: 778,
+	72: 722, 73: 278, 74: 500, 75: 667, 76: 556, 77: 833, 78: 722, 79: 778,
+	80: 667, 81: 778, 82: 722, 83: 667, 84: 611, 85: 722, 86: 667, 87: 944,
+	88: 667, 89: 667, 90: 611, 91: 278, 92: 278, 93: 278, 94: 469, 95: 556,
+	96: 333, 97: 556, 98: 556, 99: 500, 100: 556, 101: 556, 102: 278, 103: 556,
+	104: 556, 105: 222, 106: 222, 107: 500, 108: 222, 109: 833, 110: 556, 111: 556,
+	112: 556, 113: 556, 114: 333, 115: 500, 116: 278, 117: 556, 118: 500, 119: 722,
+	120: 500, 121: 500, 122: 500, 123: 334, 124: 260, 125: 334, 126: 584,
+	// Latin-1 extras
+	0xA0: 278, 0xB0: 400, 0xBA: 365, 0xAA: 370, 0xA1: 333, 0xBF: 611,
+}
+
+var helveticaBoldWidths = map[byte]int{
+	32: 278, 33: 333, 34: 474, 35: 556, 36: 556, 37: 889, 38: 722, 39: 238,
+	40: 333, 41: 333, 42: 389, 43: 584, 44: 278, 45: 333, 46: 278, 47: 278,
+	48: 556, 49: 556, 50: 556, 51: 556, 52: 556, 53: 556, 54: 556, 55: 556,
+	56: 556, 57: 556, 58: 333, 59: 333, 60: 584, 61: 584, 62: 584, 63: 611,
+	64: 975, 65: 722, 66: 722, 67: 722, 68: 722, 69: 667, 70: 611, 71: 778,
+	72: 722, 73: 278, 74: 556, 75: 722, 76: 611, 77: 833, 78: 722, 79: 778,
+	80: 667, 81: 778, 82: 722, 83: 667, 84: 611, 85: 722, 86: 667, 87: 944,
+	88: 667, 89: 667, 90: 611, 91: 333, 92: 278, 93: 333, 94: 584, 95: 556,
+	96: 333, 97: 556, 98: 611, 99: 556, 100: 611, 101: 556, 102: 333, 103: 611,
+	104: 611, 105: 278, 106: 278, 107: 556, 108: 278, 109: 889, 110: 611, 111: 611,
+	112: 611, 113: 611, 114: 389, 115: 556, 116: 333, 117: 611, 118: 556, 119: 778,
+	120: 556, 121: 556, 122: 500, 123: 389, 124: 280, 125: 389, 126: 584,
+	// Latin-1 extras
+	0xA0: 278, 0xB0: 400, 0xBA: 365, 0xAA: 370, 0xA1: 333, 0xBF: 611,
+}
+
+// accentBase maps accented Latin-1 letters to the unaccented letter whose
+// width is used for them.
+var accentBase = map[byte]byte{
+	0xC0: 'A', 0xC1: 'A', 0xC2: 'A', 0xC3: 'A', 0xC4: 'A', 0xC5: 'A',
+	0xC7: 'C', 0xC8: 'E', 0xC9: 'E', 0xCA: 'E', 0xCB: 'E', 0xCC: 'I',
+	0xCD: 'I', 0xCE: 'I', 0xCF: 'I', 0xD1: 'N', 0xD2: 'O', 0xD3: 'O',
+	0xD4: 'O', 0xD5: 'O', 0xD6: 'O', 0xD9: 'U', 0xDA: 'U', 0xDB: 'U',
+	0xDC: 'U', 0xDD: 'Y', 0xE0: 'a', 0xE1: 'a', 0xE2: 'a', 0xE3: 'a',
+	0xE4: 'a', 0xE5: 'a', 0xE7: 'c', 0xE8: 'e', 0xE9: 'e', 0xEA: 'e',
+	0xEB: 'e', 0xEC: 'i', 0xED: 'i', 0xEE: 'i', 0xEF: 'i', 0xF1: 'n',
+	0xF2: 'o', 0xF3: 'o', 0xF4: 'o', 0xF5: 'o', 0xF6: 'o', 0xF9: 'u',
+	0xFA: 'u', 0xFB: 'u', 0xFC: 'u', 0xFD: 'y', 0xFF: 'y',
+}
@@ -0,0 +1,366 @@
+// Package pdf is a minimal PDF 1.4 writer for reports and receipts. It only
+// supports the standard Helvetica fonts (no embedding), text, lines, filled
+// rectangles and simple paginated tables, which is all the API needs.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 page size and default margins, in points
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+	Margin     = 40.0
+)
+
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignRight
+	AlignCenter
+)
+
+type font struct {
+	name   string
+	widths map[byte]int
+}
+
+var (
+	helvetica     = font{name: "Helvetica", widths: helveticaWidths}
+	helveticaBold = font{name: "Helvetica-Bold", widths: helveticaBoldWidths}
+)
+
+type page struct {
+	content bytes.Buffer
+}
+
+// Document is a PDF being built in memory. Coordinates passed to its methods
+// are in points measured from the top-left corner of the page.
+type Document struct {
+	pages  []*page
+	cur    *page
+	font   *font
+	size   float64
+	y      float64
+	Title  string
+	Footer string // printed on every page next to "Pagina n de N"
+}
+
+// New creates an empty document with Helvetica 10pt selected.
+func New() *Document {
+	return &Document{font: &helvetica, size: 10}
+}
+
+// AddPage starts a new page and moves the cursor to the top margin.
+func (d *Document) AddPage() {
+	d.cur = &page{}
+	d.pages = append(d.pages, d.cur)
+	d.y = Margin
+}
+
+// SetFont selects Helvetica or Helvetica-Bold at the given size.
+func (d *Document) SetFont(bold bool, size float64) {
+	if bold {
+		d.font = &helveticaBold
+	} else {
+		d.font = &helvetica
+	}
+	d.size = size
+}
+
+// Y returns the vertical cursor.
+func (d *Document) Y() float64 { return d.y }
+
+// SetY moves the vertical cursor.
+func (d *Document) SetY(y float64) { d.y = y }
+
+// MoveDown advances the cursor, starting a new page when it would run past the
+// bottom margin.
+func (d *Document) MoveDown(h float64) {
+	d.y += h
+	if d.y > PageHeight-Margin-20 {
+		d.AddPage()
+	}
+}
+
+// EnsureSpace starts a new page when less than h points remain.
+func (d *Document) EnsureSpace(h float64) {
+	if d.y+h > PageHeight-Margin-20 {
+		d.AddPage()
+	}
+}
+
+// TextWidth returns the width of s in the current font.
+func (d *Document) TextWidth(s string) float64 {
+	return d.font.textWidth(s, d.size)
+}
+
+// Text writes s with its baseline at (x, y).
+func (d *Document) Text(x, y float64, s string) {
+	d.ensurePage()
+	fmt.Fprintf(&d.cur.content, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n",
+		d.fontKey(), d.size, x, PageHeight-y, escape(s))
+}
+
+// TextAligned writes s inside a box of width w starting at x.
+func (d *Document) TextAligned(x, y, w float64, s string, align Align) {
+	s = d.truncate(s, w)
+	switch align {
+	case AlignRight:
+		x += w - d.TextWidth(s)
+	case AlignCenter:
+		x += (w - d.TextWidth(s)) / 2
+	}
+	d.Text(x, y, s)
+}
+
+// Line draws a line between two points.
+func (d *Document) Line(x1, y1, x2, y2 float64) {
+	d.ensurePage()
+	fmt.Fprintf(&d.cur.content, "0.6 w %.2f %.2f m %.2f %.2f l S\n",
+		x1, PageHeight-y1, x2, PageHeight-y2)
+}
+
+// FillRect fills a rectangle with a gray level (0 black, 1 white).
+func (d *Document) FillRect(x, y, w, h, gray float64) {
+	d.ensurePage()
+	fmt.Fprintf(&d.cur.content, "q %.2f g %.2f %.2f %.2f %.2f re f Q\n",
+		gray, x, PageHeight-y-h, w, h)
+}
+
+// Heading writes a bold line at the cursor and advances it.
+func (d *Document) Heading(s string, size float64) {
+	d.ensurePage()
+	d.SetFont(true, size)
+	d.EnsureSpace(size + 6)
+	d.Text(Margin, d.y+size, s)
+	d.y += size + 8
+	d.SetFont(false, 10)
+}
+
+// Paragraph writes a line of regular text at the cursor and advances it.
+func (d *Document) Paragraph(s string) {
+	d.ensurePage()
+	d.EnsureSpace(14)
+	d.Text(Margin, d.y+10, s)
+	d.y += 14
+}
+
+// KeyValue writes "key: value" with a bold key at the cursor.
+func (d *Document) KeyValue(key, value string) {
+	d.ensurePage()
+	d.EnsureSpace(14)
+	d.SetFont(true, 10)
+	d.Text(Margin, d.y+10, key+":")
+	d.SetFont(false, 10)
+	d.Text(Margin+140, d.y+10, value)
+	d.y += 14
+}
+
+// Column describes a table column.
+type Column struct {
+	Header string
+	Width  float64
+	Align  Align
+}
+
+// Table draws rows at the cursor, repeating the header on every page. Rows
+// whose first cell starts with "**" are drawn in bold (the marker is removed).
+func (d *Document) Table(cols []Column, rows [][]string) {
+	d.ensurePage()
+	const rowHeight = 15.0
+
+	header := func() {
+		var w float64
+		for _, c := range cols {
+			w += c.Width
+		}
+		d.FillRect(Margin, d.y, w, rowHeight, 0.88)
+		d.SetFont(true, 9)
+		x := Margin
+		for _, c := range cols {
+			d.TextAligned(x+3, d.y+11, c.Width-6, c.Header, c.Align)
+			x += c.Width
+		}
+		d.y += rowHeight
+	}
+
+	d.EnsureSpace(rowHeight * 2)
+	header()
+	for _, row := range rows {
+		if d.y+rowHeight > PageHeight-Margin-20 {
+			d.AddPage()
+			header()
+		}
+		bold := len(row) > 0 && strings.HasPrefix(row[0], "**")
+		d.SetFont(bold, 9)
+		x := Margin
+		for i, c := range cols {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			if i == 0 && bold {
+				cell = strings.TrimPrefix(cell, "**")
+			}
+			d.TextAligned(x+3, d.y+11, c.Width-6, cell, c.Align)
+			x += c.Width
+		}
+		d.Line(Margin, d.y+rowHeight, x, d.y+rowHeight)
+		d.y += rowHeight
+	}
+	d.SetFont(false, 10)
+	d.y += 6
+}
+
+// WriteTo serializes the document.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	var buf bytes.Buffer
+	offsets := []int{}
+	obj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 1 catalog, 2 pages, 3-4 fonts, 5 info, then page/content pairs
+	n := len(d.pages)
+	kids := make([]string, n)
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+i*2)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), n))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	obj(fmt.Sprintf("<< /Title (%s) /Producer (Comunidad Vina Pelvin) >>", escape(d.Title)))
+
+	for i, p := range d.pages {
+		content := p.content.String() + d.footer(i+1, n)
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			PageWidth, PageHeight, 7+i*2))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets)+1, xref)
+
+	written, err := w.Write(buf.Bytes())
+	return int64(written), err
+}
+
+// Bytes serializes the document into a byte slice.
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (d *Document) footer(pageNum, total int) string {
+	label := fmt.Sprintf("Pagina %d de %d", pageNum, total)
+	var b bytes.Buffer
+	y := PageHeight - Margin + 15
+	if d.Footer != "" {
+		fmt.Fprintf(&b, "BT /F1 8 Tf %.2f %.2f Td (%s) Tj ET\n", Margin, PageHeight-y, escape(d.Footer))
+	}
+	x := PageWidth - Margin - helvetica.textWidth(label, 8)
+	fmt.Fprintf(&b, "BT /F1 8 Tf %.2f %.2f Td (%s) Tj ET\n", x, PageHeight-y, escape(label))
+	return b.String()
+}
+
+func (d *Document) ensurePage() {
+	if d.cur == nil {
+		d.AddPage()
+	}
+}
+
+func (d *Document) fontKey() string {
+	if d.font == &helveticaBold {
+		return "F2"
+	}
+	return "F1"
+}
+
+// truncate shortens s with "..." so it fits in width w.
+func (d *Document) truncate(s string, w float64) string {
+	if d.TextWidth(s) <= w {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && d.TextWidth(string(runes)+"...") > w {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+func (f *font) textWidth(s string, size float64) float64 {
+	var total int
+	for _, b := range toWinAnsi(s) {
+		if w, ok := f.widths[b]; ok {
+			total += w
+		} else if base, ok := accentBase[b]; ok {
+			total += f.widths[base]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// escape converts s to WinAnsi and escapes PDF string delimiters.
+func escape(s string) string {
+	var b strings.Builder
+	for _, c := range toWinAnsi(s) {
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n', '\r', '\t':
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// toWinAnsi maps UTF-8 text to WinAnsiEncoding; Latin-1 characters (accents,
+// ñ, °, º) map one to one and anything else becomes '?'.
+func toWinAnsi(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r < 0x80, r >= 0xA0 && r <= 0xFF:
+			out = append(out, byte(r))
+		case r == '€':
+			out = append(out, 0x80)
+		case r == '–', r == '—':
+			out = append(out, '-')
+		case r == '“', r == '”':
+			out = append(out, '"')
+		case r == '‘', r == '’':
+			out = append(out, '\'')
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
@@ -0,0 +1,328 @@
+// Package xlsx is a minimal XLSX (Office Open XML) writer for report exports.
+// It writes one or more sheets with inline strings, numbers and dates and a
+// fixed set of cell styles; there are no formulas or shared strings.
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Style selects one of the predefined cell formats.
+type Style int
+
+const (
+	StyleDefault Style = iota
+	StyleBold
+	StyleMoney   // #,##0
+	StyleDecimal // #,##0.00
+	StyleDate    // dd-mm-yyyy
+	StylePercent // 0.00%
+	StyleBoldMoney
+	StyleBoldDecimal
+)
+
+// Cell is a value with an explicit style. Plain values passed to AddRow use
+// StyleDefault, except time.Time which uses StyleDate.
+type Cell struct {
+	Value interface{}
+	Style Style
+}
+
+type Workbook struct {
+	sheets []*Sheet
+}
+
+type Sheet struct {
+	name   string
+	widths []float64
+	rows   [][]Cell
+}
+
+func New() *Workbook {
+	return &Workbook{}
+}
+
+// AddSheet appends a sheet. Names are truncated to 31 characters and characters
+// Excel does not allow are replaced.
+func (wb *Workbook) AddSheet(name string) *Sheet {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, name)
+	if r := []rune(name); len(r) > 31 {
+		name = string(r[:31])
+	}
+	if name == "" {
+		name = "Hoja" + strconv.Itoa(len(wb.sheets)+1)
+	}
+	s := &Sheet{name: name}
+	wb.sheets = append(wb.sheets, s)
+	return s
+}
+
+// SetWidths sets column widths in characters, starting at column A.
+func (s *Sheet) SetWidths(widths ...float64) {
+	s.widths = widths
+}
+
+// AddHeader appends a row of bold labels.
+func (s *Sheet) AddHeader(labels ...string) {
+	row := make([]Cell, len(labels))
+	for i, l := range labels {
+		row[i] = Cell{Value: l, Style: StyleBold}
+	}
+	s.rows = append(s.rows, row)
+}
+
+// AddRow appends a row. Values may be Cell, string, numbers, bool, time.Time,
+// *time.Time or nil (empty cell).
+func (s *Sheet) AddRow(values ...interface{}) {
+	row := make([]Cell, len(values))
+	for i, v := range values {
+		switch c := v.(type) {
+		case Cell:
+			row[i] = c
+		case time.Time:
+			row[i] = Cell{Value: c, Style: StyleDate}
+		case *time.Time:
+			if c != nil {
+				row[i] = Cell{Value: *c, Style: StyleDate}
+			}
+		default:
+			row[i] = Cell{Value: v}
+		}
+	}
+	s.rows = append(s.rows, row)
+}
+
+// WriteTo serializes the workbook as an .xlsx file.
+func (wb *Workbook) WriteTo(w io.Writer) (int64, error) {
+	if len(wb.sheets) == 0 {
+		wb.AddSheet("Hoja1")
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct {
+		name string
+		body string
+	}{
+		{"[Content_Types].xml", wb.contentTypes()},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", wb.workbook()},
+		{"xl/_rels/workbook.xml.rels", wb.workbookRels()},
+		{"xl/styles.xml", styles},
+	}
+	for i, s := range wb.sheets {
+		files = append(files, struct {
+			name string
+			body string
+		}{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), s.xml()})
+	}
+
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := io.WriteString(fw, f.body); err != nil {
+			return 0, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+	return buf.WriteTo(w)
+}
+
+// Bytes serializes the workbook into a byte slice.
+func (wb *Workbook) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := wb.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (wb *Workbook) contentTypes() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range wb.sheets {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+func (wb *Workbook) workbook() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, s := range wb.sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(s.name), i+1, i+1)
+	}
+	b.WriteString(`</sheets></workbook>`)
+	return b.String()
+}
+
+func (wb *Workbook) workbookRels() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := range wb.sheets {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(wb.sheets)+1)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+func (s *Sheet) xml() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(s.widths) > 0 {
+		b.WriteString(`<cols>`)
+		for i, w := range s.widths {
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%.1f" customWidth="1"/>`, i+1, i+1, w)
+		}
+		b.WriteString(`</cols>`)
+	}
+	b.WriteString(`<sheetData>`)
+	for r, row := range s.rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			writeCell(&b, columnName(c)+strconv.Itoa(r+1), cell)
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+func writeCell(b *strings.Builder, ref string, c Cell) {
+	style := ""
+	if c.Style != StyleDefault {
+		style = fmt.Sprintf(` s="%d"`, c.Style)
+	}
+
+	var num string
+	switch v := c.Value.(type) {
+	case nil:
+		if style != "" {
+			fmt.Fprintf(b, `<c r="%s"%s/>`, ref, style)
+		}
+		return
+	case string:
+		fmt.Fprintf(b, `<c r="%s" t="inlineStr"%s><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escape(v))
+		return
+	case bool:
+		val := "0"
+		if v {
+			val = "1"
+		}
+		fmt.Fprintf(b, `<c r="%s" t="b"%s><v>%s</v></c>`, ref, style, val)
+		return
+	case time.Time:
+		num = strconv.FormatFloat(excelDate(v), 'f', -1, 64)
+	case float64:
+		num = strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		num = strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case int:
+		num = strconv.Itoa(v)
+	case int64:
+		num = strconv.FormatInt(v, 10)
+	case int32:
+		num = strconv.FormatInt(int64(v), 10)
+	default:
+		fmt.Fprintf(b, `<c r="%s" t="inlineStr"%s><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escape(fmt.Sprint(v)))
+		return
+	}
+	fmt.Fprintf(b, `<c r="%s"%s><v>%s</v></c>`, ref, style, num)
+}
+
+// excelDate converts t to an Excel serial date (days since 1899-12-30).
+func excelDate(t time.Time) float64 {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	local := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	return local.Sub(epoch).Hours() / 24
+}
+
+// columnName converts a zero-based index to A, B, ..., Z, AA, AB...
+func columnName(i int) string {
+	name := ""
+	for i >= 0 {
+		name = string(rune('A'+i%26)) + name
+		i = i/26 - 1
+	}
+	return name
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		// Control characters are not allowed in XML 1.0
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			continue
+		}
+		switch r {
+		case '&':
+			b.WriteString("&amp;")
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		case '"':
+			b.WriteString("&quot;")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+const rootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// styles defines the cellXfs in the same order as the Style constants.
+const styles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="3">` +
+	`<numFmt numFmtId="164" formatCode="#,##0"/>` +
+	`<numFmt numFmtId="165" formatCode="#,##0.00"/>` +
+	`<numFmt numFmtId="166" formatCode="dd-mm-yyyy"/>` +
+	`</numFmts>` +
+	`<fonts count="2">` +
+	`<font><sz val="11"/><name val="Calibri"/></font>` +
+	`<font><b/><sz val="11"/><name val="Calibri"/></font>` +
+	`</fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="8">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="166" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="10" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="164" fontId="1" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyFont="1"/>` +
+	`<xf numFmtId="165" fontId="1" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyFont="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`